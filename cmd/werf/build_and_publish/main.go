@@ -16,7 +16,6 @@ import (
 	"github.com/flant/werf/cmd/werf/common"
 	"github.com/flant/werf/pkg/build"
 	"github.com/flant/werf/pkg/container_runtime"
-	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/logging"
 	"github.com/flant/werf/pkg/ssh_agent"
 	"github.com/flant/werf/pkg/tmp_manager"
@@ -89,14 +88,17 @@ If one or more IMAGE_NAME parameters specified, werf will build images stages an
 	common.SetupImagesRepoOptions(&commonCmdData, cmd)
 
 	common.SetupTag(&commonCmdData, cmd)
+	common.SetupContainerRuntime(&commonCmdData, cmd)
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified stages storage, to push images into the specified images repo, to pull base images")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupRegistryConcurrency(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 	common.SetupLogProjectDir(&commonCmdData, cmd)
 
 	common.SetupIntrospectStage(&commonCmdData, cmd)
+	common.SetupVulnerabilityScan(&commonCmdData, cmd)
 
 	common.SetupSynchronization(&commonCmdData, cmd)
 	common.SetupKubeConfig(&commonCmdData, cmd)
@@ -108,6 +110,7 @@ If one or more IMAGE_NAME parameters specified, werf will build images stages an
 	common.SetupVirtualMerge(&commonCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
+	common.SetupDev(&commonCmdData, cmd)
 
 	cmd.Flags().BoolVarP(&cmdData.IntrospectAfterError, "introspect-error", "", false, "Introspect failed stage in the state, right after running failed assembly instruction")
 	cmd.Flags().BoolVarP(&cmdData.IntrospectBeforeError, "introspect-before-error", "", false, "Introspect failed stage in the clean state, before running all assembly instructions of the stage")
@@ -124,7 +127,7 @@ func runBuildAndPublish(imagesToProcess []string) error {
 		return err
 	}
 
-	if err := true_git.Init(true_git.Options{Out: logboek.GetOutStream(), Err: logboek.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+	if err := true_git.Init(true_git.Options{Out: logging.GetOutStream(), Err: logging.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
 		return err
 	}
 
@@ -132,7 +135,8 @@ func runBuildAndPublish(imagesToProcess []string) error {
 		return err
 	}
 
-	if err := docker.Init(*commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+	containerRuntime, err := common.InitContainerRuntime(&commonCmdData)
+	if err != nil {
 		return err
 	}
 
@@ -162,8 +166,6 @@ func runBuildAndPublish(imagesToProcess []string) error {
 	}
 	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
 
-	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
-
 	stagesStorage, err := common.GetStagesStorage(containerRuntime, &commonCmdData)
 	if err != nil {
 		return err
@@ -187,7 +189,12 @@ func runBuildAndPublish(imagesToProcess []string) error {
 		return err
 	}
 
-	stagesManager := stages_manager.NewStagesManager(projectName, storageLockManager, stagesStorageCache)
+	conveyorOptions, err := common.GetConveyorOptions(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	stagesManager := stages_manager.NewStagesManager(common.GetStagesProjectName(projectName, &commonCmdData), storageLockManager, stagesStorageCache)
 	if err := stagesManager.UseStagesStorage(stagesStorage); err != nil {
 		return err
 	}
@@ -217,6 +224,11 @@ func runBuildAndPublish(imagesToProcess []string) error {
 		return err
 	}
 
+	vulnerabilityScanOptions, err := common.GetVulnerabilityScanOptions(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	publishReportFormat, err := common.GetPublishReportFormat(&commonCmdData)
 	if err != nil {
 		return err
@@ -228,7 +240,8 @@ func runBuildAndPublish(imagesToProcess []string) error {
 				IntrospectAfterError:  cmdData.IntrospectAfterError,
 				IntrospectBeforeError: cmdData.IntrospectBeforeError,
 			},
-			IntrospectOptions: introspectOptions,
+			IntrospectOptions:        introspectOptions,
+			VulnerabilityScanOptions: vulnerabilityScanOptions,
 		},
 		PublishImagesOptions: build.PublishImagesOptions{
 			ImagesToPublish:     imagesToProcess,
@@ -240,7 +253,7 @@ func runBuildAndPublish(imagesToProcess []string) error {
 
 	logboek.LogOptionalLn()
 
-	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, imagesToProcess, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, common.GetConveyorOptions(&commonCmdData))
+	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, imagesToProcess, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, conveyorOptions)
 	defer conveyorWithRetry.Terminate()
 
 	if err := conveyorWithRetry.WithRetryBlock(func(c *build.Conveyor) error {
@@ -29,6 +29,7 @@ var cmdData struct {
 	AsEnvFile       bool
 	OutputFilePath  string
 	Shell           string
+	MappingFile     string
 }
 
 var commonCmdData common.CmdData
@@ -40,7 +41,17 @@ func NewCmd() *cobra.Command {
 		Short:                 "Generate werf environment variables for specified CI system",
 		Long: `Generate werf environment variables for specified CI system.
 
-Currently supported only GitLab (gitlab) and GitHub (github) CI systems`,
+Supported CI systems: GitLab (gitlab), GitHub (github), Jenkins (jenkins), Bitbucket Pipelines (bitbucket), Azure DevOps (azure-devops) and Tekton (tekton).
+
+Any other CI system can be configured with the generic mode (generic) and the mapping file, which describes how werf environment variables are derived from the CI system environment variables:
+
+  imagesRepo: ${CI_REGISTRY}/${CI_PROJECT_NAME}
+  env: ${DEPLOY_ENVIRONMENT}
+  gitBranch: ${CI_BRANCH}
+  gitTag: ${CI_TAG}
+  gitCommit: ${CI_COMMIT}
+  annotations:
+    build-url: ${CI_BUILD_URL}`,
 		Example: `  # Load generated werf environment variables on GitLab job runner
   $ . $(werf ci-env gitlab --as-file)
 
@@ -50,7 +61,10 @@ Currently supported only GitLab (gitlab) and GitHub (github) CI systems`,
 
   # Load generated werf environment variables on GitLab job runner using cmd.exe
   $ FOR /F "tokens=*" %g IN ('werf ci-env gitlab --as-file --shell cmdexe') do (SET WERF_CI_ENV_SCRIPT_PATH=%g)
-  $ %WERF_CI_ENV_SCRIPT_PATH%`,
+  $ %WERF_CI_ENV_SCRIPT_PATH%
+
+  # Load generated werf environment variables on unsupported CI system using the mapping file
+  $ . $(werf ci-env generic --mapping-file .werf/ci-env.yaml --as-file)`,
 		RunE: runCIEnv,
 	}
 
@@ -69,6 +83,7 @@ Currently supported only GitLab (gitlab) and GitHub (github) CI systems`,
 	cmd.Flags().BoolVarP(&cmdData.AsEnvFile, "as-env-file", "", common.GetBoolEnvironmentDefaultFalse("WERF_AS_ENV_FILE"), "Create the .env file and print the path for sourcing (default $WERF_AS_ENV_FILE).")
 	cmd.Flags().StringVarP(&cmdData.OutputFilePath, "output-file-path", "o", os.Getenv("WERF_OUTPUT_FILE_PATH"), "Write to custom file (default $WERF_OUTPUT_FILE_PATH).")
 	cmd.Flags().StringVarP(&cmdData.Shell, "shell", "", os.Getenv("WERF_SHELL"), "Set to cmdexe, powershell or use the default behaviour that is compatible with any unix shell (default $WERF_SHELL).")
+	cmd.Flags().StringVarP(&cmdData.MappingFile, "mapping-file", "", os.Getenv("WERF_MAPPING_FILE"), "Path to the mapping file for the generic CI system (default $WERF_MAPPING_FILE).")
 
 	return cmd
 }
@@ -109,29 +124,36 @@ func runCIEnv(cmd *cobra.Command, args []string) error {
 		w = os.Stdout
 	}
 
+	var generateFunc func(w io.Writer, taggingStrategy string) error
+
 	ciSystem := args[0]
 	switch ciSystem {
 	case "github":
-		err := generateGithubEnvs(w, cmdData.TaggingStrategy)
-		if err != nil {
-			if !cmdData.AsFile && !cmdData.AsEnvFile {
-				writeError(w, err.Error())
-			}
-			return err
-		}
+		generateFunc = generateGithubEnvs
 	case "gitlab":
-		err := generateGitlabEnvs(w, cmdData.TaggingStrategy)
-		if err != nil {
-			if !cmdData.AsFile && !cmdData.AsEnvFile {
-				writeError(w, err.Error())
-			}
-			return err
-		}
+		generateFunc = generateGitlabEnvs
+	case "jenkins":
+		generateFunc = generateJenkinsEnvs
+	case "bitbucket":
+		generateFunc = generateBitbucketEnvs
+	case "azure-devops":
+		generateFunc = generateAzureDevOpsEnvs
+	case "tekton":
+		generateFunc = generateTektonEnvs
+	case "generic":
+		generateFunc = generateGenericEnvs
 	default:
 		common.PrintHelp(cmd)
 		return fmt.Errorf("provided ci system '%s' not supported", ciSystem)
 	}
 
+	if err := generateFunc(w, cmdData.TaggingStrategy); err != nil {
+		if !cmdData.AsFile && !cmdData.AsEnvFile {
+			writeError(w, err.Error())
+		}
+		return err
+	}
+
 	if cmdData.AsFile || cmdData.AsEnvFile {
 		sourceFilePath, err := createSourceFile(w.(*bytes.Buffer).Bytes())
 		if err != nil {
@@ -147,7 +169,7 @@ func runCIEnv(cmd *cobra.Command, args []string) error {
 }
 
 func generateGitlabEnvs(w io.Writer, taggingStrategy string) error {
-	dockerConfig, err := generateSessionDockerConfigDir()
+	dockerConfig, err := generateSessionDockerConfigDir(*commonCmdData.DockerConfig)
 	if err != nil {
 		return err
 	}
@@ -290,7 +312,7 @@ func generateGitlabEnvs(w io.Writer, taggingStrategy string) error {
 }
 
 func generateGithubEnvs(w io.Writer, taggingStrategy string) error {
-	dockerConfigDir, err := generateSessionDockerConfigDir()
+	dockerConfigDir, err := generateSessionDockerConfigDir(*commonCmdData.DockerConfig)
 	if err != nil {
 		return err
 	}
@@ -390,9 +412,8 @@ func generateGithubEnvs(w io.Writer, taggingStrategy string) error {
 	return nil
 }
 
-func generateSessionDockerConfigDir() (string, error) {
-	dockerConfigPath := *commonCmdData.DockerConfig
-	if *commonCmdData.DockerConfig == "" {
+func generateSessionDockerConfigDir(dockerConfigPath string) (string, error) {
+	if dockerConfigPath == "" {
 		dockerConfigPath = filepath.Join(os.Getenv("HOME"), ".docker")
 	}
 
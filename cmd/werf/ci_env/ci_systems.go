@@ -0,0 +1,240 @@
+package ci_env
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/slug"
+)
+
+const tektonDockerConfigDir = "/tekton/creds/.docker"
+
+var jenkinsGitRemoteBranchPrefixes = []string{"refs/remotes/origin/", "origin/"}
+
+type ciEnvs struct {
+	Registry         string
+	RegistryUsername string
+	RegistryPassword string
+
+	ImagesRepo    string
+	StagesStorage string
+
+	// DockerConfig is the docker config provided by the CI system, it is used if the docker config is not specified
+	DockerConfig string
+
+	Env string
+
+	GitTag        string
+	GitBranch     string
+	GitCommit     string
+	ProjectGitUrl string
+
+	Annotations []ciAnnotation
+}
+
+type ciAnnotation struct {
+	EnvName string
+	Key     string
+	Value   string
+}
+
+func generateJenkinsEnvs(w io.Writer, taggingStrategy string) error {
+	envs := ciEnvs{
+		GitTag:        os.Getenv("TAG_NAME"),
+		GitCommit:     os.Getenv("GIT_COMMIT"),
+		ProjectGitUrl: os.Getenv("GIT_URL"),
+	}
+
+	if envs.GitTag == "" {
+		if branchName := os.Getenv("BRANCH_NAME"); branchName != "" {
+			envs.GitBranch = branchName
+		} else if gitBranch := os.Getenv("GIT_BRANCH"); gitBranch != "" {
+			envs.GitBranch = jenkinsGitBranch(gitBranch)
+		}
+	}
+
+	if buildUrl := os.Getenv("BUILD_URL"); buildUrl != "" {
+		envs.Annotations = append(envs.Annotations, ciAnnotation{
+			EnvName: "WERF_ADD_ANNOTATION_JENKINS_BUILD_URL",
+			Key:     "jenkins.ci.werf.io/build-url",
+			Value:   buildUrl,
+		})
+	}
+
+	return generateCIEnvs(w, taggingStrategy, envs)
+}
+
+// jenkinsGitBranch trims the remote prefix of the branch name set by the git plugin,
+// the branch name itself may contain slashes (e.g. origin/feature/login)
+func jenkinsGitBranch(gitBranch string) string {
+	for _, prefix := range jenkinsGitRemoteBranchPrefixes {
+		if strings.HasPrefix(gitBranch, prefix) {
+			return strings.TrimPrefix(gitBranch, prefix)
+		}
+	}
+
+	return gitBranch
+}
+
+func generateBitbucketEnvs(w io.Writer, taggingStrategy string) error {
+	repoFullName := os.Getenv("BITBUCKET_REPO_FULL_NAME")
+
+	envs := ciEnvs{
+		Env:           os.Getenv("BITBUCKET_DEPLOYMENT_ENVIRONMENT"),
+		GitTag:        os.Getenv("BITBUCKET_TAG"),
+		GitBranch:     os.Getenv("BITBUCKET_BRANCH"),
+		GitCommit:     os.Getenv("BITBUCKET_COMMIT"),
+		ProjectGitUrl: os.Getenv("BITBUCKET_GIT_HTTP_ORIGIN"),
+	}
+
+	if buildNumber := os.Getenv("BITBUCKET_BUILD_NUMBER"); repoFullName != "" && buildNumber != "" {
+		envs.Annotations = append(envs.Annotations, ciAnnotation{
+			EnvName: "WERF_ADD_ANNOTATION_BITBUCKET_CI_PIPELINE_URL",
+			Key:     "bitbucket.ci.werf.io/pipeline-url",
+			Value:   fmt.Sprintf("https://bitbucket.org/%s/addon/pipelines/home#!/results/%s", repoFullName, buildNumber),
+		})
+	}
+
+	return generateCIEnvs(w, taggingStrategy, envs)
+}
+
+func generateAzureDevOpsEnvs(w io.Writer, taggingStrategy string) error {
+	envs := ciEnvs{
+		Env:           os.Getenv("RELEASE_ENVIRONMENTNAME"),
+		GitCommit:     os.Getenv("BUILD_SOURCEVERSION"),
+		ProjectGitUrl: os.Getenv("BUILD_REPOSITORY_URI"),
+	}
+
+	sourceBranch := os.Getenv("BUILD_SOURCEBRANCH")
+	switch {
+	case strings.HasPrefix(sourceBranch, "refs/tags/"):
+		envs.GitTag = strings.TrimPrefix(sourceBranch, "refs/tags/")
+	case strings.HasPrefix(sourceBranch, "refs/heads/"):
+		envs.GitBranch = strings.TrimPrefix(sourceBranch, "refs/heads/")
+	default:
+		envs.GitBranch = os.Getenv("BUILD_SOURCEBRANCHNAME")
+	}
+
+	collectionUri := os.Getenv("SYSTEM_TEAMFOUNDATIONCOLLECTIONURI")
+	teamProject := os.Getenv("SYSTEM_TEAMPROJECT")
+	buildId := os.Getenv("BUILD_BUILDID")
+	if collectionUri != "" && teamProject != "" && buildId != "" {
+		envs.Annotations = append(envs.Annotations, ciAnnotation{
+			EnvName: "WERF_ADD_ANNOTATION_AZURE_DEVOPS_BUILD_URL",
+			Key:     "azure-devops.ci.werf.io/build-url",
+			Value:   fmt.Sprintf("%s/%s/_build/results?buildId=%s", strings.TrimSuffix(collectionUri, "/"), teamProject, buildId),
+		})
+	}
+
+	return generateCIEnvs(w, taggingStrategy, envs)
+}
+
+func generateTektonEnvs(w io.Writer, taggingStrategy string) error {
+	// Tekton does not provide git related variables, the conventional ones are expected to be passed
+	// from the git-clone task results and the trigger bindings
+	envs := ciEnvs{
+		GitTag:        os.Getenv("GIT_TAG"),
+		GitBranch:     os.Getenv("GIT_BRANCH"),
+		GitCommit:     os.Getenv("GIT_COMMIT"),
+		ProjectGitUrl: os.Getenv("GIT_URL"),
+	}
+
+	// Tekton credentials initialization puts service account docker credentials into the special directory
+	if _, err := os.Stat(tektonDockerConfigDir); err == nil {
+		envs.DockerConfig = tektonDockerConfigDir
+	}
+
+	if pipelineRun := os.Getenv("TEKTON_PIPELINE_RUN"); pipelineRun != "" {
+		envs.Annotations = append(envs.Annotations, ciAnnotation{
+			EnvName: "WERF_ADD_ANNOTATION_TEKTON_PIPELINE_RUN",
+			Key:     "tekton.ci.werf.io/pipeline-run",
+			Value:   pipelineRun,
+		})
+	}
+
+	return generateCIEnvs(w, taggingStrategy, envs)
+}
+
+func generateCIEnvs(w io.Writer, taggingStrategy string, envs ciEnvs) error {
+	dockerConfig := *commonCmdData.DockerConfig
+	if dockerConfig == "" {
+		dockerConfig = envs.DockerConfig
+	}
+
+	dockerConfigDir, err := generateSessionDockerConfigDir(dockerConfig)
+	if err != nil {
+		return err
+	}
+
+	if envs.Registry != "" && envs.RegistryUsername != "" && envs.RegistryPassword != "" {
+		if err := docker.Login(envs.RegistryUsername, envs.RegistryPassword, envs.Registry); err != nil {
+			return fmt.Errorf("unable to login into docker repo %s: %s", envs.Registry, err)
+		}
+	}
+
+	// the stages storage is derived from the same images repo which is exported
+	imagesRepo := envs.ImagesRepo
+	if imagesRepo == "" {
+		imagesRepo = os.Getenv("WERF_IMAGES_REPO")
+	}
+
+	stagesStorage := envs.StagesStorage
+	if stagesStorage == "" && imagesRepo != "" {
+		stagesStorage = fmt.Sprintf("%s/stages", imagesRepo)
+	}
+
+	writeHeader(w, "DOCKER CONFIG", false)
+	writeEnv(w, "DOCKER_CONFIG", dockerConfigDir, true)
+
+	writeHeader(w, "STAGES_STORAGE", true)
+	writeEnv(w, "WERF_STAGES_STORAGE", stagesStorage, false)
+
+	writeHeader(w, "IMAGES REPO", true)
+	writeEnv(w, "WERF_IMAGES_REPO", imagesRepo, false)
+
+	writeHeader(w, "TAGGING", true)
+	switch taggingStrategy {
+	case "tag-or-branch":
+		if envs.GitTag != "" {
+			writeEnv(w, "WERF_TAG_GIT_TAG", slug.DockerTag(envs.GitTag), false)
+		} else if envs.GitBranch != "" {
+			writeEnv(w, "WERF_TAG_GIT_BRANCH", slug.DockerTag(envs.GitBranch), false)
+		} else {
+			return fmt.Errorf("neither git tag nor git branch for '%s' strategy are detected", taggingStrategy)
+		}
+	case "stages-signature":
+		writeEnv(w, "WERF_TAG_BY_STAGES_SIGNATURE", "true", false)
+	}
+
+	writeHeader(w, "DEPLOY", true)
+	writeEnv(w, "WERF_ENV", envs.Env, false)
+
+	var projectGit string
+	if envs.ProjectGitUrl != "" {
+		projectGit = fmt.Sprintf("project.werf.io/git=%s", envs.ProjectGitUrl)
+	}
+	writeEnv(w, "WERF_ADD_ANNOTATION_PROJECT_GIT", projectGit, false)
+
+	var ciCommit string
+	if envs.GitCommit != "" {
+		ciCommit = fmt.Sprintf("ci.werf.io/commit=%s", envs.GitCommit)
+	}
+	writeEnv(w, "WERF_ADD_ANNOTATION_CI_COMMIT", ciCommit, false)
+
+	for _, annotation := range envs.Annotations {
+		var value string
+		if annotation.Value != "" {
+			value = fmt.Sprintf("%s=%s", annotation.Key, annotation.Value)
+		}
+		writeEnv(w, annotation.EnvName, value, false)
+	}
+
+	if err := generateImageCleanupPolicies(w); err != nil {
+		return err
+	}
+
+	return generateOther(w)
+}
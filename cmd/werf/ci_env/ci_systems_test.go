@@ -0,0 +1,17 @@
+package ci_env
+
+import "testing"
+
+func TestJenkinsGitBranch(t *testing.T) {
+	for gitBranch, expected := range map[string]string{
+		"origin/master":                   "master",
+		"origin/feature/login":            "feature/login",
+		"refs/remotes/origin/release/1.1": "release/1.1",
+		"master":                          "master",
+		"feature/login":                   "feature/login",
+	} {
+		if branch := jenkinsGitBranch(gitBranch); branch != expected {
+			t.Errorf("%q: expected %q, got %q", gitBranch, expected, branch)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package ci_env
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// GenericMapping describes how werf environment variables are derived from the environment of an unsupported CI system.
+// Each value is expanded with the environment variables, e.g. "${CI_REGISTRY}/${CI_PROJECT}".
+type GenericMapping struct {
+	Registry         string `json:"registry"`
+	RegistryUsername string `json:"registryUsername"`
+	RegistryPassword string `json:"registryPassword"`
+
+	ImagesRepo    string `json:"imagesRepo"`
+	StagesStorage string `json:"stagesStorage"`
+
+	Env string `json:"env"`
+
+	GitTag        string `json:"gitTag"`
+	GitBranch     string `json:"gitBranch"`
+	GitCommit     string `json:"gitCommit"`
+	ProjectGitUrl string `json:"projectGitUrl"`
+
+	Annotations map[string]string `json:"annotations"`
+}
+
+var annotationEnvNameRegexp = regexp.MustCompile(`[^A-Z0-9]+`)
+
+func generateGenericEnvs(w io.Writer, taggingStrategy string) error {
+	if cmdData.MappingFile == "" {
+		return fmt.Errorf("mapping file should be specified with --mapping-file option for generic ci system")
+	}
+
+	mapping, err := readGenericMapping(cmdData.MappingFile)
+	if err != nil {
+		return err
+	}
+
+	return generateCIEnvs(w, taggingStrategy, genericCIEnvs(mapping))
+}
+
+// genericCIEnvs expands the mapping values with the current environment
+func genericCIEnvs(mapping *GenericMapping) ciEnvs {
+	envs := ciEnvs{
+		Registry:         os.ExpandEnv(mapping.Registry),
+		RegistryUsername: os.ExpandEnv(mapping.RegistryUsername),
+		RegistryPassword: os.ExpandEnv(mapping.RegistryPassword),
+		ImagesRepo:       os.ExpandEnv(mapping.ImagesRepo),
+		StagesStorage:    os.ExpandEnv(mapping.StagesStorage),
+		Env:              os.ExpandEnv(mapping.Env),
+		GitTag:           os.ExpandEnv(mapping.GitTag),
+		GitBranch:        os.ExpandEnv(mapping.GitBranch),
+		GitCommit:        os.ExpandEnv(mapping.GitCommit),
+		ProjectGitUrl:    os.ExpandEnv(mapping.ProjectGitUrl),
+	}
+
+	var annotationKeys []string
+	for key := range mapping.Annotations {
+		annotationKeys = append(annotationKeys, key)
+	}
+	sort.Strings(annotationKeys)
+
+	for _, key := range annotationKeys {
+		envs.Annotations = append(envs.Annotations, ciAnnotation{
+			EnvName: annotationEnvName(key),
+			Key:     key,
+			Value:   os.ExpandEnv(mapping.Annotations[key]),
+		})
+	}
+
+	return envs
+}
+
+func readGenericMapping(path string) (*GenericMapping, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading mapping file %s: %s", path, err)
+	}
+
+	mapping := &GenericMapping{}
+	if err := yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, fmt.Errorf("bad mapping file yaml %s: %s", path, err)
+	}
+
+	return mapping, nil
+}
+
+func annotationEnvName(key string) string {
+	name := annotationEnvNameRegexp.ReplaceAllString(strings.ToUpper(key), "_")
+	return fmt.Sprintf("WERF_ADD_ANNOTATION_%s", strings.Trim(name, "_"))
+}
@@ -0,0 +1,119 @@
+package ci_env
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMappingFile = `
+registry: ${TEST_CI_REGISTRY}
+registryUsername: ${TEST_CI_USER}
+registryPassword: ${TEST_CI_PASSWORD}
+imagesRepo: ${TEST_CI_REGISTRY}/${TEST_CI_PROJECT}
+env: production
+gitBranch: ${TEST_CI_BRANCH}
+gitTag: ${TEST_CI_TAG}
+gitCommit: ${TEST_CI_COMMIT}
+projectGitUrl: https://git.example.com/${TEST_CI_PROJECT}
+annotations:
+  ci.example.com/pipeline-url: https://ci.example.com/${TEST_CI_PROJECT}/${TEST_CI_PIPELINE}
+  ci.example.com/job: ${TEST_CI_JOB}
+`
+
+func setTestEnvs(t *testing.T, envs map[string]string) func() {
+	for key, value := range envs {
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return func() {
+		for key := range envs {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func writeTestMappingFile(t *testing.T, data string) (string, func()) {
+	dir, err := ioutil.TempDir("", "werf-ci-env-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "ci-env.yaml")
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestGenericCIEnvs(t *testing.T) {
+	defer setTestEnvs(t, map[string]string{
+		"TEST_CI_REGISTRY": "registry.example.com",
+		"TEST_CI_USER":     "user",
+		"TEST_CI_PASSWORD": "password",
+		"TEST_CI_PROJECT":  "group/app",
+		"TEST_CI_BRANCH":   "feature/x",
+		"TEST_CI_COMMIT":   "0123456789abcdef",
+		"TEST_CI_PIPELINE": "42",
+	})()
+
+	path, cleanup := writeTestMappingFile(t, testMappingFile)
+	defer cleanup()
+
+	mapping, err := readGenericMapping(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := ciEnvs{
+		Registry:         "registry.example.com",
+		RegistryUsername: "user",
+		RegistryPassword: "password",
+		ImagesRepo:       "registry.example.com/group/app",
+		Env:              "production",
+		GitBranch:        "feature/x",
+		GitCommit:        "0123456789abcdef",
+		ProjectGitUrl:    "https://git.example.com/group/app",
+		Annotations: []ciAnnotation{
+			// sorted by the key, unset variables are expanded to the empty value
+			{EnvName: "WERF_ADD_ANNOTATION_CI_EXAMPLE_COM_JOB", Key: "ci.example.com/job", Value: ""},
+			{EnvName: "WERF_ADD_ANNOTATION_CI_EXAMPLE_COM_PIPELINE_URL", Key: "ci.example.com/pipeline-url", Value: "https://ci.example.com/group/app/42"},
+		},
+	}
+
+	if envs := genericCIEnvs(mapping); !reflect.DeepEqual(envs, expected) {
+		t.Errorf("expected envs:\n%+v\ngot:\n%+v", expected, envs)
+	}
+}
+
+func TestReadGenericMapping_UnknownField(t *testing.T) {
+	path, cleanup := writeTestMappingFile(t, "imagesRepo: registry.example.com/app\nimageRepo: typo\n")
+	defer cleanup()
+
+	if _, err := readGenericMapping(path); err == nil || !strings.Contains(err.Error(), "bad mapping file yaml") {
+		t.Fatalf("expected bad mapping file error, got %v", err)
+	}
+}
+
+func TestAnnotationEnvName(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"ci.example.com/pipeline-url", "WERF_ADD_ANNOTATION_CI_EXAMPLE_COM_PIPELINE_URL"},
+		{"owner", "WERF_ADD_ANNOTATION_OWNER"},
+		{"-team--name-", "WERF_ADD_ANNOTATION_TEAM_NAME"},
+	}
+
+	for _, tt := range tests {
+		if name := annotationEnvName(tt.key); name != tt.expected {
+			t.Errorf("annotationEnvName(%q): expected %q, got %q", tt.key, tt.expected, name)
+		}
+	}
+}
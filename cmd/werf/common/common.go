@@ -1,9 +1,11 @@
 package common
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -18,10 +20,14 @@ import (
 	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/container_runtime"
 	"github.com/flant/werf/pkg/deploy/helm"
+	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/docker_registry"
 	"github.com/flant/werf/pkg/logging"
+	"github.com/flant/werf/pkg/podman"
 	"github.com/flant/werf/pkg/storage"
+	"github.com/flant/werf/pkg/tracing"
 	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/util/secretvalues"
 	"github.com/flant/werf/pkg/werf"
 )
 
@@ -58,6 +64,8 @@ type CmdData struct {
 	SetString       *[]string
 	Values          *[]string
 	SecretValues    *[]string
+	SecretSet       *[]string
+	SetFileSecret   *[]string
 	IgnoreSecretKey *bool
 
 	CommonRepoData *RepoData
@@ -72,8 +80,10 @@ type CmdData struct {
 	Synchronization *string
 
 	DockerConfig          *string
+	ContainerRuntime      *string
 	InsecureRegistry      *bool
 	SkipTlsVerifyRegistry *bool
+	RegistryConcurrency   *int
 	DryRun                *bool
 
 	GitTagStrategyLimit               *int64
@@ -94,20 +104,36 @@ type CmdData struct {
 	LogColorMode     *string
 	LogProjectDir    *bool
 	LogTerminalWidth *int64
+	LogFormat        *string
+
+	TraceFile     *string
+	TraceEndpoint *string
 
 	ThreeWayMergeMode *string
+	AutoRollback      *bool
 
 	PublishReportPath   *string
 	PublishReportFormat *string
 
+	StatsOutput *string
+
+	VulnerabilityDB                *string
+	VulnerabilityReportPath        *string
+	VulnerabilitySeverityThreshold *string
+
 	VirtualMerge           *bool
 	VirtualMergeFromCommit *string
 	VirtualMergeIntoCommit *string
+
+	Dev *bool
 }
 
 const (
 	CleaningCommandsForceOptionDescription = "First remove containers that use werf docker images which are going to be deleted"
 	StubImagesRepoAddress                  = "stub/repository"
+
+	DockerServerContainerRuntime = "docker-server"
+	PodmanContainerRuntime       = "podman"
 )
 
 func GetLongCommandDescription(text string) string {
@@ -172,6 +198,30 @@ func GetPublishReportFormat(cmdData *CmdData) (build.PublishReportFormat, error)
 	}
 }
 
+func SetupVulnerabilityScan(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.VulnerabilityDB = new(string)
+	cmdData.VulnerabilityReportPath = new(string)
+	cmdData.VulnerabilitySeverityThreshold = new(string)
+
+	cmd.Flags().StringVarP(cmdData.VulnerabilityDB, "vulnerability-db", "", os.Getenv("WERF_VULNERABILITY_DB"), "Scan built images for vulnerable OS packages and language dependencies using the local vulnerability database file (JSON or YAML). Scan is disabled by default ($WERF_VULNERABILITY_DB by default)")
+	cmd.Flags().StringVarP(cmdData.VulnerabilityReportPath, "vulnerability-report-path", "", os.Getenv("WERF_VULNERABILITY_REPORT_PATH"), "Write vulnerability scan report in json format to the specified file ($WERF_VULNERABILITY_REPORT_PATH by default)")
+	cmd.Flags().StringVarP(cmdData.VulnerabilitySeverityThreshold, "vulnerability-severity-threshold", "", os.Getenv("WERF_VULNERABILITY_SEVERITY_THRESHOLD"), "Fail before publishing the image that has vulnerabilities with the specified or higher severity: UNKNOWN, LOW, MEDIUM, HIGH or CRITICAL ($WERF_VULNERABILITY_SEVERITY_THRESHOLD by default)")
+}
+
+func GetVulnerabilityScanOptions(cmdData *CmdData) (build.VulnerabilityScanOptions, error) {
+	opts := build.VulnerabilityScanOptions{
+		DatabasePath:      *cmdData.VulnerabilityDB,
+		ReportPath:        *cmdData.VulnerabilityReportPath,
+		SeverityThreshold: *cmdData.VulnerabilitySeverityThreshold,
+	}
+
+	if opts.DatabasePath == "" && (opts.ReportPath != "" || opts.SeverityThreshold != "") {
+		return opts, fmt.Errorf("--vulnerability-db required to scan images for vulnerabilities")
+	}
+
+	return opts, nil
+}
+
 func SetupImagesCleanupPolicies(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.GitTagStrategyLimit = new(int64)
 	cmdData.GitTagStrategyExpiryDays = new(int64)
@@ -322,6 +372,14 @@ func SetupCommonRepoData(cmdData *CmdData, cmd *cobra.Command) {
 	SetupHarborUsernameForRepoData(cmdData.CommonRepoData, cmd, "repo-harbor-username", []string{"WERF_REPO_HARBOR_USERNAME"})
 	SetupHarborPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-harbor-password", []string{"WERF_REPO_HARBOR_PASSWORD"})
 	SetupQuayTokenForRepoData(cmdData.CommonRepoData, cmd, "repo-quay-token", []string{"WERF_REPO_QUAY_TOKEN"})
+	SetupNexusUsernameForRepoData(cmdData.CommonRepoData, cmd, "repo-nexus-username", []string{"WERF_REPO_NEXUS_USERNAME"})
+	SetupNexusPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-nexus-password", []string{"WERF_REPO_NEXUS_PASSWORD"})
+	SetupNexusApiUrlForRepoData(cmdData.CommonRepoData, cmd, "repo-nexus-api-url", []string{"WERF_REPO_NEXUS_API_URL"})
+	SetupNexusRepositoryForRepoData(cmdData.CommonRepoData, cmd, "repo-nexus-repository", []string{"WERF_REPO_NEXUS_REPOSITORY"})
+	SetupArtifactoryUsernameForRepoData(cmdData.CommonRepoData, cmd, "repo-artifactory-username", []string{"WERF_REPO_ARTIFACTORY_USERNAME"})
+	SetupArtifactoryPasswordForRepoData(cmdData.CommonRepoData, cmd, "repo-artifactory-password", []string{"WERF_REPO_ARTIFACTORY_PASSWORD"})
+	SetupAlibabaAccessKeyIdForRepoData(cmdData.CommonRepoData, cmd, "repo-alibaba-access-key-id", []string{"WERF_REPO_ALIBABA_ACCESS_KEY_ID"})
+	SetupAlibabaAccessKeySecretForRepoData(cmdData.CommonRepoData, cmd, "repo-alibaba-access-key-secret", []string{"WERF_REPO_ALIBABA_ACCESS_KEY_SECRET"})
 }
 
 func SetupStagesStorageOptions(cmdData *CmdData, cmd *cobra.Command) {
@@ -345,6 +403,14 @@ func SetupStagesStorageOptions(cmdData *CmdData, cmd *cobra.Command) {
 	SetupHarborUsernameForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-harbor-username", []string{"WERF_STAGES_STORAGE_REPO_HARBOR_USERNAME", "WERF_REPO_HARBOR_USERNAME"})
 	SetupHarborPasswordForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-harbor-password", []string{"WERF_STAGES_STORAGE_REPO_HARBOR_PASSWORD", "WERF_REPO_HARBOR_PASSWORD"})
 	SetupQuayTokenForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-quay-token", []string{"WERF_STAGES_STORAGE_REPO_QUAY_TOKEN", "WERF_REPO_QUAY_TOKEN"})
+	SetupNexusUsernameForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-nexus-username", []string{"WERF_STAGES_STORAGE_REPO_NEXUS_USERNAME", "WERF_REPO_NEXUS_USERNAME"})
+	SetupNexusPasswordForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-nexus-password", []string{"WERF_STAGES_STORAGE_REPO_NEXUS_PASSWORD", "WERF_REPO_NEXUS_PASSWORD"})
+	SetupNexusApiUrlForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-nexus-api-url", []string{"WERF_STAGES_STORAGE_REPO_NEXUS_API_URL", "WERF_REPO_NEXUS_API_URL"})
+	SetupNexusRepositoryForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-nexus-repository", []string{"WERF_STAGES_STORAGE_REPO_NEXUS_REPOSITORY", "WERF_REPO_NEXUS_REPOSITORY"})
+	SetupArtifactoryUsernameForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-artifactory-username", []string{"WERF_STAGES_STORAGE_REPO_ARTIFACTORY_USERNAME", "WERF_REPO_ARTIFACTORY_USERNAME"})
+	SetupArtifactoryPasswordForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-artifactory-password", []string{"WERF_STAGES_STORAGE_REPO_ARTIFACTORY_PASSWORD", "WERF_REPO_ARTIFACTORY_PASSWORD"})
+	SetupAlibabaAccessKeyIdForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-alibaba-access-key-id", []string{"WERF_STAGES_STORAGE_REPO_ALIBABA_ACCESS_KEY_ID", "WERF_REPO_ALIBABA_ACCESS_KEY_ID"})
+	SetupAlibabaAccessKeySecretForRepoData(cmdData.StagesStorageRepoData, cmd, "stages-storage-repo-alibaba-access-key-secret", []string{"WERF_STAGES_STORAGE_REPO_ALIBABA_ACCESS_KEY_SECRET", "WERF_REPO_ALIBABA_ACCESS_KEY_SECRET"})
 }
 
 func setupStagesStorage(cmdData *CmdData, cmd *cobra.Command) {
@@ -456,6 +522,14 @@ func SetupImagesRepoOptions(cmdData *CmdData, cmd *cobra.Command) {
 	SetupHarborUsernameForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-harbor-username", []string{"WERF_IMAGES_REPO_HARBOR_USERNAME", "WERF_REPO_HARBOR_USERNAME"})
 	SetupHarborPasswordForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-harbor-password", []string{"WERF_IMAGES_REPO_HARBOR_PASSWORD", "WERF_REPO_HARBOR_PASSWORD"})
 	SetupQuayTokenForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-quay-token", []string{"WERF_IMAGES_REPO_QUAY_TOKEN", "WERF_REPO_QUAY_TOKEN"})
+	SetupNexusUsernameForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-nexus-username", []string{"WERF_IMAGES_REPO_NEXUS_USERNAME", "WERF_REPO_NEXUS_USERNAME"})
+	SetupNexusPasswordForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-nexus-password", []string{"WERF_IMAGES_REPO_NEXUS_PASSWORD", "WERF_REPO_NEXUS_PASSWORD"})
+	SetupNexusApiUrlForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-nexus-api-url", []string{"WERF_IMAGES_REPO_NEXUS_API_URL", "WERF_REPO_NEXUS_API_URL"})
+	SetupNexusRepositoryForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-nexus-repository", []string{"WERF_IMAGES_REPO_NEXUS_REPOSITORY", "WERF_REPO_NEXUS_REPOSITORY"})
+	SetupArtifactoryUsernameForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-artifactory-username", []string{"WERF_IMAGES_REPO_ARTIFACTORY_USERNAME", "WERF_REPO_ARTIFACTORY_USERNAME"})
+	SetupArtifactoryPasswordForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-artifactory-password", []string{"WERF_IMAGES_REPO_ARTIFACTORY_PASSWORD", "WERF_REPO_ARTIFACTORY_PASSWORD"})
+	SetupAlibabaAccessKeyIdForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-alibaba-access-key-id", []string{"WERF_IMAGES_REPO_ALIBABA_ACCESS_KEY_ID", "WERF_REPO_ALIBABA_ACCESS_KEY_ID"})
+	SetupAlibabaAccessKeySecretForRepoData(cmdData.ImagesRepoData, cmd, "images-repo-alibaba-access-key-secret", []string{"WERF_IMAGES_REPO_ALIBABA_ACCESS_KEY_SECRET", "WERF_REPO_ALIBABA_ACCESS_KEY_SECRET"})
 }
 
 func setupImagesRepo(cmdData *CmdData, cmd *cobra.Command) {
@@ -484,6 +558,25 @@ func SetupInsecureRegistry(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.InsecureRegistry, "insecure-registry", "", GetBoolEnvironmentDefaultFalse("WERF_INSECURE_REGISTRY"), "Use plain HTTP requests when accessing a registry (default $WERF_INSECURE_REGISTRY)")
 }
 
+func SetupRegistryConcurrency(cmdData *CmdData, cmd *cobra.Command) {
+	if cmdData.RegistryConcurrency != nil {
+		return
+	}
+
+	defaultValueP, err := getIntEnvVar("WERF_REGISTRY_CONCURRENCY")
+	if err != nil {
+		TerminateWithError(fmt.Sprintf("bad WERF_REGISTRY_CONCURRENCY value: %s", err), 1)
+	}
+
+	defaultValue := docker_registry.DefaultConcurrency
+	if defaultValueP != nil {
+		defaultValue = int(*defaultValueP)
+	}
+
+	cmdData.RegistryConcurrency = new(int)
+	cmd.Flags().IntVarP(cmdData.RegistryConcurrency, "registry-concurrency", "", defaultValue, "Max number of simultaneous requests to a registry when listing and fetching images info (default $WERF_REGISTRY_CONCURRENCY or 10)")
+}
+
 func SetupSkipTlsVerifyRegistry(cmdData *CmdData, cmd *cobra.Command) {
 	if cmdData.SkipTlsVerifyRegistry != nil {
 		return
@@ -516,6 +609,16 @@ func SetupDockerConfig(cmdData *CmdData, cmd *cobra.Command, extraDesc string) {
 	cmd.Flags().StringVarP(cmdData.DockerConfig, "docker-config", "", defaultValue, desc)
 }
 
+func SetupContainerRuntime(cmdData *CmdData, cmd *cobra.Command) {
+	defaultValue := os.Getenv("WERF_CONTAINER_RUNTIME")
+	if defaultValue == "" {
+		defaultValue = DockerServerContainerRuntime
+	}
+
+	cmdData.ContainerRuntime = new(string)
+	cmd.Flags().StringVarP(cmdData.ContainerRuntime, "container-runtime", "", defaultValue, fmt.Sprintf("Container runtime to build stapel images: '%[1]s' or '%[2]s' (default $WERF_CONTAINER_RUNTIME or '%[1]s').\n'%[2]s' runtime does not require docker daemon, podman binary is used to build, pull and push images", DockerServerContainerRuntime, PodmanContainerRuntime))
+}
+
 func SetupLogOptions(cmdData *CmdData, cmd *cobra.Command) {
 	setupLogDebug(cmdData, cmd)
 	setupLogVerbose(cmdData, cmd)
@@ -523,6 +626,8 @@ func SetupLogOptions(cmdData *CmdData, cmd *cobra.Command) {
 	setupLogColor(cmdData, cmd)
 	setupLogPretty(cmdData, cmd)
 	setupTerminalWidth(cmdData, cmd)
+	setupLogFormat(cmdData, cmd)
+	setupTrace(cmdData, cmd)
 }
 
 func setupLogDebug(cmdData *CmdData, cmd *cobra.Command) {
@@ -657,8 +762,28 @@ Defaults to:
 * interactive terminal width or %d`, logboek.DefaultWidth))
 }
 
+func setupLogFormat(cmdData *CmdData, cmd *cobra.Command) {
+	defaultValue := os.Getenv("WERF_LOG_FORMAT")
+	if defaultValue == "" {
+		defaultValue = "text"
+	}
+
+	cmdData.LogFormat = new(string)
+	cmd.Flags().StringVarP(cmdData.LogFormat, "log-format", "", defaultValue, `Log format: 'text' or 'json' (default $WERF_LOG_FORMAT or 'text').
+'json' format prints one event per line for every log line and every start, end or fail of the log process with image, stage, phase and duration fields`)
+}
+
+func setupTrace(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.TraceFile = new(string)
+	cmd.Flags().StringVarP(cmdData.TraceFile, "trace-file", "", os.Getenv("WERF_TRACE_FILE"), `Append trace spans of conveyor phases, stage builds, registry requests, lock waits, helm deploy and kubedog tracking into the file in the OTLP/JSON format (default $WERF_TRACE_FILE)`)
+
+	cmdData.TraceEndpoint = new(string)
+	cmd.Flags().StringVarP(cmdData.TraceEndpoint, "trace-endpoint", "", os.Getenv("WERF_TRACE_ENDPOINT"), `Send trace spans to the OpenTelemetry collector OTLP/HTTP endpoint, e.g. http://localhost:4318 (default $WERF_TRACE_ENDPOINT).
+The trace is attached to the parent span from $TRACEPARENT if it is set`)
+}
+
 func SetupSet(cmdData *CmdData, cmd *cobra.Command) {
-	set := predefinedValuesByEnvNamePrefix("WERF_SET", "WERF_SET_STRING")
+	set := predefinedValuesByEnvNamePrefix("WERF_SET", "WERF_SET_STRING", "WERF_SET_FILE_SECRET")
 
 	cmdData.Set = &set
 	cmd.Flags().StringArrayVarP(cmdData.Set, "set", "", set, `Set helm values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2).
@@ -689,6 +814,22 @@ func SetupSecretValues(cmdData *CmdData, cmd *cobra.Command) {
 Also, can be defined with $WERF_SECRET_VALUES* (e.g. $WERF_SECRET_VALUES_ENV=.helm/secret_values_test.yaml, $WERF_SECRET_VALUES=.helm/secret_values_db.yaml)`)
 }
 
+func SetupSecretSet(cmdData *CmdData, cmd *cobra.Command) {
+	secretSet := predefinedValuesByEnvNamePrefix("WERF_SECRET_SET")
+
+	cmdData.SecretSet = &secretSet
+	cmd.Flags().StringArrayVarP(cmdData.SecretSet, "secret-set", "", secretSet, `Set secret STRING helm values on the command line, the values are masked in the werf output (can specify multiple or separate values with commas: key1=val1,key2=val2).
+Also, can be defined with $WERF_SECRET_SET* (e.g. $WERF_SECRET_SET_1=key1=val1, $WERF_SECRET_SET_2=key2=val2)`)
+}
+
+func SetupSetFileSecret(cmdData *CmdData, cmd *cobra.Command) {
+	setFileSecret := predefinedValuesByEnvNamePrefix("WERF_SET_FILE_SECRET")
+
+	cmdData.SetFileSecret = &setFileSecret
+	cmd.Flags().StringArrayVarP(cmdData.SetFileSecret, "set-file-secret", "", setFileSecret, `Set secret helm values from the files on the command line, the values are masked in the werf output (can specify multiple or separate values with commas: key1=path1,key2=path2).
+Also, can be defined with $WERF_SET_FILE_SECRET* (e.g. $WERF_SET_FILE_SECRET_1=key1=path1, $WERF_SET_FILE_SECRET_2=key2=path2)`)
+}
+
 func SetupIgnoreSecretKey(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.IgnoreSecretKey = new(bool)
 	cmd.Flags().BoolVarP(cmdData.IgnoreSecretKey, "ignore-secret-key", "", GetBoolEnvironmentDefaultFalse("WERF_IGNORE_SECRET_KEY"), "Disable secrets decryption (default $WERF_IGNORE_SECRET_KEY)")
@@ -734,6 +875,11 @@ func SetupThreeWayMergeMode(cmdData *CmdData, cmd *cobra.Command) {
 Supported 'enabled', 'disabled' and 'onlyNewReleases', see docs for more info https://werf.io/documentation/reference/deploy_process/experimental_three_way_merge.html`)
 }
 
+func SetupAutoRollback(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.AutoRollback = new(bool)
+	cmd.Flags().BoolVarP(cmdData.AutoRollback, "auto-rollback", "", GetBoolEnvironmentDefaultFalse("WERF_AUTO_ROLLBACK"), "Rollback release to the latest successfully deployed revision and wait for the resources readiness when the release upgrade fails ($WERF_AUTO_ROLLBACK by default)")
+}
+
 func GetThreeWayMergeMode(threeWayMergeModeParam string) (helm.ThreeWayMergeModeType, error) {
 	switch threeWayMergeModeParam {
 	case "enabled", "disabled", "onlyNewReleases", "":
@@ -931,9 +1077,27 @@ func GetStagesStorageAddress(cmdData *CmdData) (string, error) {
 	if *cmdData.StagesStorage == "" {
 		return "", fmt.Errorf("--stages-storage=ADDRESS param required")
 	}
+
+	if isDevMode(cmdData) {
+		return storage.DevStagesStorageAddress(*cmdData.StagesStorage), nil
+	}
+
 	return *cmdData.StagesStorage, nil
 }
 
+// GetStagesProjectName returns the project name for the stages manager: the development mode stages are stored and cached separately
+func GetStagesProjectName(projectName string, cmdData *CmdData) string {
+	if isDevMode(cmdData) {
+		return storage.DevProjectName(projectName)
+	}
+
+	return projectName
+}
+
+func isDevMode(cmdData *CmdData) bool {
+	return cmdData.Dev != nil && *cmdData.Dev
+}
+
 func GetOptionalStagesStorageAddress(cmdData *CmdData) string {
 	return *cmdData.StagesStorage
 }
@@ -967,6 +1131,7 @@ func getImagesRepo(projectName string, cmdData *CmdData, optionalStubRepoAddress
 	}
 
 	repoData := MergeRepoData(cmdData.ImagesRepoData, cmdData.CommonRepoData)
+	RegisterRepoDataSecretValues(repoData)
 
 	if err := ValidateRepoImplementation(*repoData.Implementation); err != nil {
 		return nil, err
@@ -980,20 +1145,46 @@ func getImagesRepo(projectName string, cmdData *CmdData, optionalStubRepoAddress
 			DockerImagesRepoOptions: storage.DockerImagesRepoOptions{
 				Implementation: *repoData.Implementation,
 				DockerRegistryOptions: docker_registry.DockerRegistryOptions{
-					InsecureRegistry:      *cmdData.InsecureRegistry,
-					SkipTlsVerifyRegistry: *cmdData.SkipTlsVerifyRegistry,
-					DockerHubUsername:     *repoData.DockerHubUsername,
-					DockerHubPassword:     *repoData.DockerHubPassword,
-					GitHubToken:           *repoData.GitHubToken,
-					HarborUsername:        *repoData.HarborUsername,
-					HarborPassword:        *repoData.HarborPassword,
-					QuayToken:             *repoData.QuayToken,
+					InsecureRegistry:       *cmdData.InsecureRegistry,
+					SkipTlsVerifyRegistry:  *cmdData.SkipTlsVerifyRegistry,
+					DockerHubUsername:      *repoData.DockerHubUsername,
+					DockerHubPassword:      *repoData.DockerHubPassword,
+					GitHubToken:            *repoData.GitHubToken,
+					HarborUsername:         *repoData.HarborUsername,
+					HarborPassword:         *repoData.HarborPassword,
+					QuayToken:              *repoData.QuayToken,
+					NexusUsername:          *repoData.NexusUsername,
+					NexusPassword:          *repoData.NexusPassword,
+					NexusApiUrl:            *repoData.NexusApiUrl,
+					NexusRepository:        *repoData.NexusRepository,
+					ArtifactoryUsername:    *repoData.ArtifactoryUsername,
+					ArtifactoryPassword:    *repoData.ArtifactoryPassword,
+					AlibabaAccessKeyId:     *repoData.AlibabaAccessKeyId,
+					AlibabaAccessKeySecret: *repoData.AlibabaAccessKeySecret,
 				},
 			},
 		},
 	)
 }
 
+// InitContainerRuntime initializes the cli of the selected container runtime, docker config is used for the registries authorization
+func InitContainerRuntime(cmdData *CmdData) (container_runtime.LocalRuntime, error) {
+	switch *cmdData.ContainerRuntime {
+	case DockerServerContainerRuntime:
+		if err := docker.Init(*cmdData.DockerConfig, *cmdData.LogVerbose, *cmdData.LogDebug); err != nil {
+			return nil, err
+		}
+		return container_runtime.NewLocalDockerServerRuntime(), nil
+	case PodmanContainerRuntime:
+		if err := podman.Init(*cmdData.DockerConfig, *cmdData.LogVerbose, *cmdData.LogDebug); err != nil {
+			return nil, err
+		}
+		return container_runtime.NewLocalPodmanRuntime(), nil
+	default:
+		return nil, fmt.Errorf("bad --container-runtime value %q: '%s' or '%s' expected", *cmdData.ContainerRuntime, DockerServerContainerRuntime, PodmanContainerRuntime)
+	}
+}
+
 func GetStagesStorage(containerRuntime container_runtime.ContainerRuntime, cmdData *CmdData) (storage.StagesStorage, error) {
 	stagesStorageAddress, err := GetStagesStorageAddress(cmdData)
 	if err != nil {
@@ -1001,6 +1192,7 @@ func GetStagesStorage(containerRuntime container_runtime.ContainerRuntime, cmdDa
 	}
 
 	repoData := MergeRepoData(cmdData.StagesStorageRepoData, cmdData.CommonRepoData)
+	RegisterRepoDataSecretValues(repoData)
 
 	if err := ValidateRepoImplementation(*repoData.Implementation); err != nil {
 		return nil, err
@@ -1013,15 +1205,23 @@ func GetStagesStorage(containerRuntime container_runtime.ContainerRuntime, cmdDa
 			RepoStagesStorageOptions: storage.RepoStagesStorageOptions{
 				Implementation: *repoData.Implementation,
 				DockerRegistryOptions: docker_registry.DockerRegistryOptions{
-					InsecureRegistry:      *cmdData.InsecureRegistry,
-					SkipTlsVerifyRegistry: *cmdData.SkipTlsVerifyRegistry,
-					DockerHubUsername:     *repoData.DockerHubUsername,
-					DockerHubPassword:     *repoData.DockerHubPassword,
-					DockerHubToken:        *repoData.DockerHubToken,
-					GitHubToken:           *repoData.GitHubToken,
-					HarborUsername:        *repoData.HarborUsername,
-					HarborPassword:        *repoData.HarborPassword,
-					QuayToken:             *repoData.QuayToken,
+					InsecureRegistry:       *cmdData.InsecureRegistry,
+					SkipTlsVerifyRegistry:  *cmdData.SkipTlsVerifyRegistry,
+					DockerHubUsername:      *repoData.DockerHubUsername,
+					DockerHubPassword:      *repoData.DockerHubPassword,
+					DockerHubToken:         *repoData.DockerHubToken,
+					GitHubToken:            *repoData.GitHubToken,
+					HarborUsername:         *repoData.HarborUsername,
+					HarborPassword:         *repoData.HarborPassword,
+					QuayToken:              *repoData.QuayToken,
+					NexusUsername:          *repoData.NexusUsername,
+					NexusPassword:          *repoData.NexusPassword,
+					NexusApiUrl:            *repoData.NexusApiUrl,
+					NexusRepository:        *repoData.NexusRepository,
+					ArtifactoryUsername:    *repoData.ArtifactoryUsername,
+					ArtifactoryPassword:    *repoData.ArtifactoryPassword,
+					AlibabaAccessKeyId:     *repoData.AlibabaAccessKeyId,
+					AlibabaAccessKeySecret: *repoData.AlibabaAccessKeySecret,
 				},
 			},
 		},
@@ -1259,17 +1459,61 @@ func ProcessLogOptions(cmdData *CmdData) error {
 		logging.EnableLogVerbose()
 	}
 
-	if !*cmdData.LogPretty {
-		logging.DisablePrettyLog()
+	secretvalues.RegisterEnvironSecretValues()
+
+	switch *cmdData.LogFormat {
+	case "text":
+		if !*cmdData.LogPretty {
+			logging.DisablePrettyLog()
+		}
+
+		if err := ProcessLogTerminalWidth(cmdData); err != nil {
+			return err
+		}
+	case "json":
+		if err := logging.EnableJSONLogFormat(); err != nil {
+			return fmt.Errorf("unable to enable json log format: %s", err)
+		}
+	default:
+		return fmt.Errorf("bad log format '%s': text and json formats are supported", *cmdData.LogFormat)
 	}
 
-	if err := ProcessLogTerminalWidth(cmdData); err != nil {
+	if err := tracing.Init(tracing.InitOptions{
+		File:           *cmdData.TraceFile,
+		Endpoint:       *cmdData.TraceEndpoint,
+		ServiceVersion: werf.Version,
+	}); err != nil {
 		return err
 	}
+	tracing.StartSpan(commandSpanName(), nil)
 
 	return nil
 }
 
+// commandSpanName returns the werf command without flags and arguments, e.g. werf helm deploy-chart
+func commandSpanName() string {
+	parts := []string{"werf"}
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "-") || !commandNameRegexp.MatchString(arg) {
+			break
+		}
+		parts = append(parts, arg)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+var commandNameRegexp = regexp.MustCompile(`^[a-z][a-z-]*$`)
+
+// Shutdown exports the trace spans and flushes the log output, should be called before the process exit
+func Shutdown(err error) {
+	if traceErr := tracing.Shutdown(err); traceErr != nil {
+		logboek.LogWarnF("WARNING: %s\n", traceErr)
+	}
+
+	logging.Shutdown()
+}
+
 func ProcessLogColorMode(cmdData *CmdData) error {
 	logColorMode := *cmdData.LogColorMode
 
@@ -1316,7 +1560,7 @@ func ProcessLogTerminalWidth(cmdData *CmdData) error {
 }
 
 func DockerRegistryInit(cmdData *CmdData) error {
-	return docker_registry.Init(*cmdData.InsecureRegistry, *cmdData.SkipTlsVerifyRegistry)
+	return docker_registry.Init(*cmdData.InsecureRegistry, *cmdData.SkipTlsVerifyRegistry, *cmdData.RegistryConcurrency)
 }
 
 func ValidateRepoImplementation(implementation string) error {
@@ -1382,6 +1626,7 @@ func TerminateWithError(errMsg string, exitCode int) {
 	msg = strings.TrimSuffix(msg, "\n")
 
 	logboek.LogErrorLn(msg)
+	Shutdown(errors.New(errMsg))
 	os.Exit(exitCode)
 }
 
@@ -1399,3 +1644,8 @@ func SetupVirtualMergeIntoCommit(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.VirtualMergeIntoCommit = new(string)
 	cmd.Flags().StringVarP(cmdData.VirtualMergeIntoCommit, "virtual-merge-into-commit", "", os.Getenv("WERF_VIRTUAL_MERGE_INTO_COMMIT"), "Commit hash for virtual/ephemeral merge commit which is base for changes introduced in the pull request ($WERF_VIRTUAL_MERGE_INTO_COMMIT by default)")
 }
+
+func SetupDev(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.Dev = new(bool)
+	cmd.Flags().BoolVarP(cmdData.Dev, "dev", "", GetBoolEnvironmentDefaultFalse("WERF_DEV"), "Enable development mode: build local git mappings from the current worktree state including uncommitted and untracked files. Dev stages are stored separately from the regular ones: in the stages storage repo with the -dev suffix or under the project name with the -dev suffix for :local stages storage. Cannot be used with --virtual-merge ($WERF_DEV by default)")
+}
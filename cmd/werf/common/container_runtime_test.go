@@ -0,0 +1,79 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flant/werf/pkg/container_runtime"
+)
+
+func newContainerRuntimeCmdData(containerRuntime, dockerConfig string) *CmdData {
+	logVerbose, logDebug := false, false
+	return &CmdData{
+		ContainerRuntime: &containerRuntime,
+		DockerConfig:     &dockerConfig,
+		LogVerbose:       &logVerbose,
+		LogDebug:         &logDebug,
+	}
+}
+
+func TestInitContainerRuntime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-container-runtime-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	podmanBinPath := filepath.Join(dir, "podman")
+	if err := ioutil.WriteFile(podmanBinPath, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, env := range []string{"WERF_PODMAN_BIN", "DOCKER_CONFIG", "REGISTRY_AUTH_FILE"} {
+		defer os.Setenv(env, os.Getenv(env))
+	}
+
+	tests := []struct {
+		name                     string
+		containerRuntime         string
+		podmanBin                string
+		expectedContainerRuntime string
+		expectedErr              string
+	}{
+		{name: "podman", containerRuntime: PodmanContainerRuntime, podmanBin: podmanBinPath, expectedContainerRuntime: "local-podman"},
+		{name: "podman without binary", containerRuntime: PodmanContainerRuntime, podmanBin: filepath.Join(dir, "no-podman"), expectedErr: "podman is required for the podman container runtime"},
+		{name: "unknown", containerRuntime: "kaniko", expectedErr: "bad --container-runtime value \"kaniko\""},
+	}
+
+	// docker server container runtime is not covered: docker client initialization requires the running docker daemon
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("WERF_PODMAN_BIN", tt.podmanBin)
+
+			containerRuntime, err := InitContainerRuntime(newContainerRuntimeCmdData(tt.containerRuntime, dir))
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if containerRuntime.String() != tt.expectedContainerRuntime {
+				t.Errorf("expected %s container runtime, got %s", tt.expectedContainerRuntime, containerRuntime.String())
+			}
+
+			if _, isPodman := containerRuntime.(*container_runtime.LocalPodmanRuntime); isPodman {
+				if authFile := os.Getenv("REGISTRY_AUTH_FILE"); authFile != filepath.Join(dir, "config.json") {
+					t.Errorf("expected podman auth file in the docker config dir, got %q", authFile)
+				}
+			}
+		})
+	}
+}
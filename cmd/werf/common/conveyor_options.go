@@ -1,16 +1,23 @@
 package common
 
 import (
+	"fmt"
+
 	"github.com/flant/werf/pkg/build"
 	"github.com/flant/werf/pkg/build/stage"
 )
 
-func GetConveyorOptions(commonCmdData *CmdData) build.ConveyorOptions {
+func GetConveyorOptions(commonCmdData *CmdData) (build.ConveyorOptions, error) {
+	if isDevMode(commonCmdData) && *commonCmdData.VirtualMerge {
+		return build.ConveyorOptions{}, fmt.Errorf("--dev cannot be used with --virtual-merge: dev mode builds the current worktree state instead of the merge commit")
+	}
+
 	return build.ConveyorOptions{
 		LocalGitRepoVirtualMergeOptions: stage.VirtualMergeOptions{
 			VirtualMerge:           *commonCmdData.VirtualMerge,
 			VirtualMergeFromCommit: *commonCmdData.VirtualMergeFromCommit,
 			VirtualMergeIntoCommit: *commonCmdData.VirtualMergeIntoCommit,
 		},
-	}
+		DevMode: isDevMode(commonCmdData),
+	}, nil
 }
@@ -0,0 +1,117 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func newDevModeCmdData(dev *bool, virtualMerge bool, stagesStorage string) *CmdData {
+	return &CmdData{
+		Dev:                    dev,
+		VirtualMerge:           &virtualMerge,
+		VirtualMergeFromCommit: new(string),
+		VirtualMergeIntoCommit: new(string),
+		StagesStorage:          &stagesStorage,
+	}
+}
+
+func TestGetConveyorOptions_DevMode(t *testing.T) {
+	enabled, disabled := true, false
+
+	tests := []struct {
+		name            string
+		dev             *bool
+		virtualMerge    bool
+		expectedDevMode bool
+		expectedErr     string
+	}{
+		{name: "dev mode", dev: &enabled, expectedDevMode: true},
+		{name: "virtual merge", dev: &disabled, virtualMerge: true},
+		{name: "command without dev option", dev: nil, virtualMerge: true},
+		{name: "dev mode with virtual merge", dev: &enabled, virtualMerge: true, expectedErr: "--dev cannot be used with --virtual-merge"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := GetConveyorOptions(newDevModeCmdData(tt.dev, tt.virtualMerge, ":local"))
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if opts.DevMode != tt.expectedDevMode {
+				t.Errorf("expected dev mode %v, got %v", tt.expectedDevMode, opts.DevMode)
+			}
+
+			if opts.LocalGitRepoVirtualMergeOptions.VirtualMerge != tt.virtualMerge {
+				t.Errorf("expected virtual merge %v, got %v", tt.virtualMerge, opts.LocalGitRepoVirtualMergeOptions.VirtualMerge)
+			}
+		})
+	}
+}
+
+func TestDevModeStagesNamespace(t *testing.T) {
+	enabled, disabled := true, false
+
+	tests := []struct {
+		name                         string
+		dev                          *bool
+		stagesStorage                string
+		expectedStagesStorageAddress string
+		expectedStagesProjectName    string
+	}{
+		{
+			name:                         "repo stages storage",
+			dev:                          &disabled,
+			stagesStorage:                "registry.example.com/app/stages",
+			expectedStagesStorageAddress: "registry.example.com/app/stages",
+			expectedStagesProjectName:    "app",
+		},
+		{
+			name:                         "dev mode with repo stages storage",
+			dev:                          &enabled,
+			stagesStorage:                "registry.example.com/app/stages",
+			expectedStagesStorageAddress: "registry.example.com/app/stages-dev",
+			expectedStagesProjectName:    "app-dev",
+		},
+		{
+			name:                         "dev mode with local stages storage",
+			dev:                          &enabled,
+			stagesStorage:                ":local",
+			expectedStagesStorageAddress: ":local",
+			expectedStagesProjectName:    "app-dev",
+		},
+		{
+			name:                         "command without dev option",
+			dev:                          nil,
+			stagesStorage:                ":local",
+			expectedStagesStorageAddress: ":local",
+			expectedStagesProjectName:    "app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmdData := newDevModeCmdData(tt.dev, false, tt.stagesStorage)
+
+			address, err := GetStagesStorageAddress(cmdData)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if address != tt.expectedStagesStorageAddress {
+				t.Errorf("expected stages storage address %q, got %q", tt.expectedStagesStorageAddress, address)
+			}
+
+			if projectName := GetStagesProjectName("app", cmdData); projectName != tt.expectedStagesProjectName {
+				t.Errorf("expected stages project name %q, got %q", tt.expectedStagesProjectName, projectName)
+			}
+		})
+	}
+}
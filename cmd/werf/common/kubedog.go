@@ -5,8 +5,9 @@ import (
 	"io/ioutil"
 
 	"github.com/flant/kubedog/pkg/display"
-	"github.com/flant/logboek"
 	"k8s.io/klog"
+
+	"github.com/flant/werf/pkg/logging"
 )
 
 func InitKubedog() error {
@@ -16,11 +17,11 @@ func InitKubedog() error {
 	// Suppress info and warnings from client-go reflector
 	klog.SetOutputBySeverity("INFO", ioutil.Discard)
 	klog.SetOutputBySeverity("WARNING", ioutil.Discard)
-	klog.SetOutputBySeverity("ERROR", logboek.GetErrStream())
-	klog.SetOutputBySeverity("FATAL", logboek.GetErrStream())
+	klog.SetOutputBySeverity("ERROR", logging.GetErrStream())
+	klog.SetOutputBySeverity("FATAL", logging.GetErrStream())
 
-	display.SetOut(logboek.GetOutStream())
-	display.SetErr(logboek.GetErrStream())
+	display.SetOut(logging.GetOutStream())
+	display.SetErr(logging.GetErrStream())
 
 	return nil
 }
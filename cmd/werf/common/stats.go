@@ -0,0 +1,86 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/docker/go-units"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/cleaning"
+	"github.com/flant/werf/pkg/logging"
+)
+
+func SetupStatsOutput(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.StatsOutput = new(string)
+
+	defaultValue := os.Getenv("WERF_STATS_OUTPUT")
+	if defaultValue == "" {
+		defaultValue = "table"
+	}
+
+	cmd.Flags().StringVarP(cmdData.StatsOutput, "output", "o", defaultValue, "Output the statistics in the specified format: table or json (default $WERF_STATS_OUTPUT or table)")
+}
+
+// ProcessStatsOutput validates the output format, json output disables the log to keep stdout parseable
+func ProcessStatsOutput(cmdData *CmdData) error {
+	switch *cmdData.StatsOutput {
+	case "table":
+	case "json":
+		logging.EnableLogQuiet()
+	default:
+		return fmt.Errorf("bad --output value %q: table or json expected", *cmdData.StatsOutput)
+	}
+
+	return nil
+}
+
+func PrintStatsJSON(stats interface{}) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
+
+// PrintSizeStatsTable prints the table with the groupTitle column, rows are sorted by name if sortRows is set
+func PrintSizeStatsTable(groupTitle string, statsByName map[string]*cleaning.SizeStats, sortRows bool, names ...string) {
+	if sortRows {
+		for name := range statsByName {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	t := uitable.New()
+	t.MaxColWidth = uint(logboek.ContentWidth())
+	t.AddRow(groupTitle, "COUNT", "TOTAL SIZE", "UNIQUE SIZE")
+
+	for _, name := range names {
+		stats := statsByName[name]
+		t.AddRow(name, strconv.Itoa(stats.Count), units.HumanSize(float64(stats.TotalSize)), units.HumanSize(float64(stats.UniqueSize)))
+	}
+
+	fmt.Println(t.String())
+	fmt.Println()
+}
+
+func PrintAgeGroupsStatsTable(ageGroups []*cleaning.AgeGroupStats) {
+	statsByName := map[string]*cleaning.SizeStats{}
+	var names []string
+	for _, group := range ageGroups {
+		statsByName[group.Name] = &group.SizeStats
+		names = append(names, group.Name)
+	}
+
+	PrintSizeStatsTable("AGE", statsByName, false, names...)
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/flant/werf/pkg/docker_registry"
+	"github.com/flant/werf/pkg/util/secretvalues"
 	"github.com/spf13/cobra"
 )
 
@@ -13,14 +14,22 @@ type RepoData struct {
 	IsCommon               bool
 	DesignationStorageName string
 
-	Implementation    *string
-	DockerHubUsername *string
-	DockerHubPassword *string
-	DockerHubToken    *string
-	GitHubToken       *string
-	HarborUsername    *string
-	HarborPassword    *string
-	QuayToken         *string
+	Implementation         *string
+	DockerHubUsername      *string
+	DockerHubPassword      *string
+	DockerHubToken         *string
+	GitHubToken            *string
+	HarborUsername         *string
+	HarborPassword         *string
+	QuayToken              *string
+	NexusUsername          *string
+	NexusPassword          *string
+	NexusApiUrl            *string
+	NexusRepository        *string
+	ArtifactoryUsername    *string
+	ArtifactoryPassword    *string
+	AlibabaAccessKeyId     *string
+	AlibabaAccessKeySecret *string
 }
 
 func MergeRepoData(repoDataArr ...*RepoData) *RepoData {
@@ -51,11 +60,53 @@ func MergeRepoData(repoDataArr ...*RepoData) *RepoData {
 		if res.QuayToken == nil || *res.QuayToken == "" {
 			res.QuayToken = repoData.QuayToken
 		}
+		if res.NexusUsername == nil || *res.NexusUsername == "" {
+			res.NexusUsername = repoData.NexusUsername
+		}
+		if res.NexusPassword == nil || *res.NexusPassword == "" {
+			res.NexusPassword = repoData.NexusPassword
+		}
+		if res.NexusApiUrl == nil || *res.NexusApiUrl == "" {
+			res.NexusApiUrl = repoData.NexusApiUrl
+		}
+		if res.NexusRepository == nil || *res.NexusRepository == "" {
+			res.NexusRepository = repoData.NexusRepository
+		}
+		if res.ArtifactoryUsername == nil || *res.ArtifactoryUsername == "" {
+			res.ArtifactoryUsername = repoData.ArtifactoryUsername
+		}
+		if res.ArtifactoryPassword == nil || *res.ArtifactoryPassword == "" {
+			res.ArtifactoryPassword = repoData.ArtifactoryPassword
+		}
+		if res.AlibabaAccessKeyId == nil || *res.AlibabaAccessKeyId == "" {
+			res.AlibabaAccessKeyId = repoData.AlibabaAccessKeyId
+		}
+		if res.AlibabaAccessKeySecret == nil || *res.AlibabaAccessKeySecret == "" {
+			res.AlibabaAccessKeySecret = repoData.AlibabaAccessKeySecret
+		}
 	}
 
 	return res
 }
 
+// RegisterRepoDataSecretValues registers passwords and tokens to mask them in the registry client output and errors
+func RegisterRepoDataSecretValues(repoData *RepoData) {
+	for _, value := range []*string{
+		repoData.DockerHubPassword,
+		repoData.DockerHubToken,
+		repoData.GitHubToken,
+		repoData.HarborPassword,
+		repoData.QuayToken,
+		repoData.NexusPassword,
+		repoData.ArtifactoryPassword,
+		repoData.AlibabaAccessKeySecret,
+	} {
+		if value != nil {
+			secretvalues.RegisterSecretValues(*value)
+		}
+	}
+}
+
 func SetupImplementationForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
 	var usageTitle string
 	if repoData.IsCommon {
@@ -212,6 +263,166 @@ func SetupQuayTokenForRepoData(repoData *RepoData, cmd *cobra.Command, paramName
 	_ = cmd.Flags().MarkHidden(paramName)
 }
 
+func SetupNexusUsernameForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Common Nexus username for any stages storage or images repo specified for the command (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Nexus username for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.NexusUsername = new(string)
+	cmd.Flags().StringVarP(
+		repoData.NexusUsername,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+
+	_ = cmd.Flags().MarkHidden(paramName)
+}
+
+func SetupNexusPasswordForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Common Nexus password for any stages storage or images repo specified for the command (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Nexus password for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.NexusPassword = new(string)
+	cmd.Flags().StringVarP(
+		repoData.NexusPassword,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+
+	_ = cmd.Flags().MarkHidden(paramName)
+}
+
+func SetupNexusApiUrlForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Common Nexus REST API url (e.g. https://nexus.example.com) for any stages storage or images repo specified for the command, required if the docker repository is served on a separate host or port (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Nexus REST API url (e.g. https://nexus.example.com) for %s, required if the docker repository is served on a separate host or port (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.NexusApiUrl = new(string)
+	cmd.Flags().StringVarP(
+		repoData.NexusApiUrl,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+
+	_ = cmd.Flags().MarkHidden(paramName)
+}
+
+func SetupNexusRepositoryForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Common Nexus repository name for any stages storage or images repo specified for the command, required to list and delete images (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Nexus repository name for %s, required to list and delete images (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.NexusRepository = new(string)
+	cmd.Flags().StringVarP(
+		repoData.NexusRepository,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+
+	_ = cmd.Flags().MarkHidden(paramName)
+}
+
+func SetupArtifactoryUsernameForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Common Artifactory username for any stages storage or images repo specified for the command (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Artifactory username for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.ArtifactoryUsername = new(string)
+	cmd.Flags().StringVarP(
+		repoData.ArtifactoryUsername,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+
+	_ = cmd.Flags().MarkHidden(paramName)
+}
+
+func SetupArtifactoryPasswordForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Common Artifactory password or access token for any stages storage or images repo specified for the command (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Artifactory password or access token for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.ArtifactoryPassword = new(string)
+	cmd.Flags().StringVarP(
+		repoData.ArtifactoryPassword,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+
+	_ = cmd.Flags().MarkHidden(paramName)
+}
+
+func SetupAlibabaAccessKeyIdForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Common Alibaba Cloud access key id for any stages storage or images repo specified for the command (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Alibaba Cloud access key id for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.AlibabaAccessKeyId = new(string)
+	cmd.Flags().StringVarP(
+		repoData.AlibabaAccessKeyId,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+
+	_ = cmd.Flags().MarkHidden(paramName)
+}
+
+func SetupAlibabaAccessKeySecretForRepoData(repoData *RepoData, cmd *cobra.Command, paramName string, paramEnvNames []string) {
+	var usage string
+	if repoData.IsCommon {
+		usage = fmt.Sprintf("Common Alibaba Cloud access key secret for any stages storage or images repo specified for the command (default %s)", strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	} else {
+		usage = fmt.Sprintf("Alibaba Cloud access key secret for %s (default %s)", repoData.DesignationStorageName, strings.Join(getParamEnvNamesForUsageDescription(paramEnvNames), ", "))
+	}
+
+	repoData.AlibabaAccessKeySecret = new(string)
+	cmd.Flags().StringVarP(
+		repoData.AlibabaAccessKeySecret,
+		paramName,
+		"",
+		getDefaultValueByParamEnvNames(paramEnvNames),
+		usage,
+	)
+
+	_ = cmd.Flags().MarkHidden(paramName)
+}
+
 func getDefaultValueByParamEnvNames(paramEnvNames []string) string {
 	var defaultValue string
 	for _, paramEnvName := range paramEnvNames {
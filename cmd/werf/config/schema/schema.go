@@ -0,0 +1,45 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "schema",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print JSON Schema of werf.yaml",
+		Long: common.GetLongCommandDescription(`Print JSON Schema of werf.yaml.
+
+The schema describes a single werf.yaml document: meta, stapel image, artifact or dockerfile image config section. It can be used by editors and linters for autocompletion and validation of the config.`),
+		Example: `  # Save schema for the editor
+  $ werf config schema > werf-schema.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			data, err := json.MarshalIndent(config.GetWerfConfigJSONSchema(), "", "  ")
+			if err != nil {
+				return fmt.Errorf("unable to marshal json schema: %s", err)
+			}
+
+			fmt.Println(string(data))
+
+			return nil
+		},
+	}
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
@@ -0,0 +1,80 @@
+package validate
+
+import (
+	"fmt"
+
+	"github.com/flant/logboek"
+	"github.com/spf13/cobra"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/tmp_manager"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "validate",
+		DisableFlagsInUseLine: true,
+		Short:                 "Validate werf.yaml",
+		Long: common.GetLongCommandDescription(`Validate werf.yaml.
+
+Config is rendered and each document is checked against JSON Schema (see werf config schema) and werf config directives requirements. All found problems are reported with line and column in the rendered config, command exits with non-zero code if there are any problems.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+				return fmt.Errorf("initialization error: %s", err)
+			}
+
+			tmp_manager.AutoGCEnabled = false
+
+			projectDir, err := common.GetProjectDir(&commonCmdData)
+			if err != nil {
+				return fmt.Errorf("getting project dir failed: %s", err)
+			}
+
+			werfConfigPath, err := common.GetWerfConfigPath(projectDir, &commonCmdData, true)
+			if err != nil {
+				return err
+			}
+
+			werfConfigTemplatesDir := common.GetWerfConfigTemplatesDir(projectDir, &commonCmdData)
+
+			renderPath, problems, err := config.ValidateWerfConfig(werfConfigPath, werfConfigTemplatesDir)
+			if err != nil {
+				return err
+			}
+
+			if len(problems) == 0 {
+				logboek.LogLn("Config is valid")
+				return nil
+			}
+
+			if renderPath != "" {
+				logboek.LogF("Rendered config: %s\n", renderPath)
+			}
+
+			for _, problem := range problems {
+				logboek.LogErrorLn(problem.Error())
+			}
+
+			return fmt.Errorf("werf config is not valid: %d problems found", len(problems))
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
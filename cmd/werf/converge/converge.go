@@ -23,7 +23,7 @@ import (
 	"github.com/flant/werf/cmd/werf/common"
 	"github.com/flant/werf/pkg/build"
 	"github.com/flant/werf/pkg/container_runtime"
-	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/logging"
 	"github.com/flant/werf/pkg/ssh_agent"
 	"github.com/flant/werf/pkg/tmp_manager"
 	"github.com/flant/werf/pkg/true_git"
@@ -81,15 +81,19 @@ werf converge --stages-storage registry.mydomain.com/web/back/stages --images-re
 	common.SetupStagesStorageOptions(&commonCmdData, cmd)
 	common.SetupImagesRepoOptions(&commonCmdData, cmd)
 
+	common.SetupContainerRuntime(&commonCmdData, cmd)
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified stages storage, to push images into the specified images repo, to pull base images")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupRegistryConcurrency(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 	common.SetupLogProjectDir(&commonCmdData, cmd)
 
 	common.SetupSynchronization(&commonCmdData, cmd)
 
+	common.SetupVulnerabilityScan(&commonCmdData, cmd)
+
 	common.SetupKubeConfig(&commonCmdData, cmd)
 	common.SetupKubeContext(&commonCmdData, cmd)
 	common.SetupHelmChartDir(&commonCmdData, cmd)
@@ -109,11 +113,16 @@ werf converge --stages-storage registry.mydomain.com/web/back/stages --images-re
 	common.SetupSetString(&commonCmdData, cmd)
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
+	common.SetupSecretSet(&commonCmdData, cmd)
+	common.SetupSetFileSecret(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
 
+	common.SetupAutoRollback(&commonCmdData, cmd)
+
 	common.SetupVirtualMerge(&commonCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
+	common.SetupDev(&commonCmdData, cmd)
 
 	cmd.Flags().IntVarP(&cmdData.Timeout, "timeout", "t", 0, "Resources tracking timeout in seconds")
 
@@ -129,7 +138,7 @@ func runConverge() error {
 		return err
 	}
 
-	if err := true_git.Init(true_git.Options{Out: logboek.GetOutStream(), Err: logboek.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+	if err := true_git.Init(true_git.Options{Out: logging.GetOutStream(), Err: logging.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
 		return err
 	}
 
@@ -137,7 +146,8 @@ func runConverge() error {
 		return err
 	}
 
-	if err := docker.Init(*commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+	containerRuntime, err := common.InitContainerRuntime(&commonCmdData)
+	if err != nil {
 		return err
 	}
 
@@ -161,8 +171,6 @@ func runConverge() error {
 	}
 	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
 
-	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
-
 	stagesStorage, err := common.GetStagesStorage(containerRuntime, &commonCmdData)
 	if err != nil {
 		return err
@@ -181,7 +189,12 @@ func runConverge() error {
 		return err
 	}
 
-	stagesManager := stages_manager.NewStagesManager(projectName, storageLockManager, stagesStorageCache)
+	conveyorOptions, err := common.GetConveyorOptions(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	stagesManager := stages_manager.NewStagesManager(common.GetStagesProjectName(projectName, &commonCmdData), storageLockManager, stagesStorageCache)
 	if err := stagesManager.UseStagesStorage(stagesStorage); err != nil {
 		return err
 	}
@@ -255,9 +268,15 @@ func runConverge() error {
 		return fmt.Errorf("cannot init kubedog: %s", err)
 	}
 
+	vulnerabilityScanOptions, err := common.GetVulnerabilityScanOptions(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	opts := build.BuildAndPublishOptions{
 		BuildStagesOptions: build.BuildStagesOptions{
-			ImageBuildOptions: container_runtime.BuildOptions{},
+			ImageBuildOptions:        container_runtime.BuildOptions{},
+			VulnerabilityScanOptions: vulnerabilityScanOptions,
 		},
 		PublishImagesOptions: build.PublishImagesOptions{
 			TagOptions: build.TagOptions{TagByStagesSignature: true}, // always content based tagging
@@ -266,7 +285,7 @@ func runConverge() error {
 
 	logboek.LogOptionalLn()
 
-	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, nil, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, common.GetConveyorOptions(&commonCmdData))
+	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, nil, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, conveyorOptions)
 	defer conveyorWithRetry.Terminate()
 
 	var imagesInfoGetters []images_manager.ImageInfoGetter
@@ -287,6 +306,8 @@ func runConverge() error {
 	return deploy.Deploy(projectName, projectDir, helmChartDir, imagesRepo.String(), imagesInfoGetters, release, namespace, "", tag_strategy.StagesSignature, werfConfig, *commonCmdData.HelmReleaseStorageNamespace, helmReleaseStorageType, storageLockManager, deploy.DeployOptions{
 		Set:                  *commonCmdData.Set,
 		SetString:            *commonCmdData.SetString,
+		SecretSet:            *commonCmdData.SecretSet,
+		SetFileSecret:        *commonCmdData.SetFileSecret,
 		Values:               *commonCmdData.Values,
 		SecretValues:         *commonCmdData.SecretValues,
 		Timeout:              time.Duration(cmdData.Timeout) * time.Second,
@@ -295,6 +316,7 @@ func runConverge() error {
 		UserExtraLabels:      userExtraLabels,
 		IgnoreSecretKey:      *commonCmdData.IgnoreSecretKey,
 		ThreeWayMergeMode:    helm.ThreeWayMergeEnabled,
+		AutoRollback:         *commonCmdData.AutoRollback,
 	})
 
 	return nil
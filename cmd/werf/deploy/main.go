@@ -22,6 +22,7 @@ import (
 	"github.com/flant/werf/pkg/deploy/helm"
 	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/images_manager"
+	"github.com/flant/werf/pkg/logging"
 	"github.com/flant/werf/pkg/ssh_agent"
 	"github.com/flant/werf/pkg/tag_strategy"
 	"github.com/flant/werf/pkg/tmp_manager"
@@ -102,6 +103,7 @@ Read more info about Helm chart structure, Helm Release name, Kubernetes Namespa
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read and pull images from the specified stages storage and images repo")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupRegistryConcurrency(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 	common.SetupLogProjectDir(&commonCmdData, cmd)
@@ -110,13 +112,17 @@ Read more info about Helm chart structure, Helm Release name, Kubernetes Namespa
 	common.SetupSetString(&commonCmdData, cmd)
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
+	common.SetupSecretSet(&commonCmdData, cmd)
+	common.SetupSetFileSecret(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
 
 	common.SetupThreeWayMergeMode(&commonCmdData, cmd)
+	common.SetupAutoRollback(&commonCmdData, cmd)
 
 	common.SetupVirtualMerge(&commonCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
 	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
+	common.SetupDev(&commonCmdData, cmd)
 
 	cmd.Flags().IntVarP(&cmdData.Timeout, "timeout", "t", 0, "Resources tracking timeout in seconds")
 
@@ -132,7 +138,7 @@ func runDeploy() error {
 		return err
 	}
 
-	if err := true_git.Init(true_git.Options{Out: logboek.GetOutStream(), Err: logboek.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+	if err := true_git.Init(true_git.Options{Out: logging.GetOutStream(), Err: logging.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
 		return err
 	}
 
@@ -210,7 +216,7 @@ func runDeploy() error {
 	projectName := werfConfig.Meta.Project
 
 	if len(werfConfig.StapelImages) != 0 || len(werfConfig.ImagesFromDockerfile) != 0 {
-		containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+		containerRuntime := container_runtime.NewLocalDockerServerRuntime() // TODO
 
 		stagesStorage, err := common.GetStagesStorage(containerRuntime, &commonCmdData)
 		if err != nil {
@@ -229,7 +235,12 @@ func runDeploy() error {
 			return err
 		}
 
-		stagesManager := stages_manager.NewStagesManager(projectName, storageLockManager, stagesStorageCache)
+		conveyorOptions, err := common.GetConveyorOptions(&commonCmdData)
+		if err != nil {
+			return err
+		}
+
+		stagesManager := stages_manager.NewStagesManager(common.GetStagesProjectName(projectName, &commonCmdData), storageLockManager, stagesStorageCache)
 		if err := stagesManager.UseStagesStorage(stagesStorage); err != nil {
 			return err
 		}
@@ -258,7 +269,7 @@ func runDeploy() error {
 
 		logboek.LogOptionalLn()
 
-		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, common.GetConveyorOptions(&commonCmdData))
+		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, conveyorOptions)
 		defer conveyorWithRetry.Terminate()
 
 		if err := conveyorWithRetry.WithRetryBlock(func(c *build.Conveyor) error {
@@ -310,6 +321,8 @@ func runDeploy() error {
 	return deploy.Deploy(projectName, projectDir, helmChartDir, imagesRepository, imagesInfoGetters, release, namespace, tag, tagStrategy, werfConfig, *commonCmdData.HelmReleaseStorageNamespace, helmReleaseStorageType, storageLockManager, deploy.DeployOptions{
 		Set:                  *commonCmdData.Set,
 		SetString:            *commonCmdData.SetString,
+		SecretSet:            *commonCmdData.SecretSet,
+		SetFileSecret:        *commonCmdData.SetFileSecret,
 		Values:               *commonCmdData.Values,
 		SecretValues:         *commonCmdData.SecretValues,
 		Timeout:              time.Duration(cmdData.Timeout) * time.Second,
@@ -318,5 +331,6 @@ func runDeploy() error {
 		UserExtraLabels:      userExtraLabels,
 		IgnoreSecretKey:      *commonCmdData.IgnoreSecretKey,
 		ThreeWayMergeMode:    threeWayMergeMode,
+		AutoRollback:         *commonCmdData.AutoRollback,
 	})
 }
@@ -0,0 +1,258 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/logging"
+	"github.com/flant/werf/pkg/ssh_agent"
+	"github.com/flant/werf/pkg/stages_manager"
+	"github.com/flant/werf/pkg/tmp_manager"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var cmdData struct {
+	To        string
+	Repo      string
+	Tag       string
+	IndexPath string
+
+	IntrospectBeforeError bool
+	IntrospectAfterError  bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [IMAGE_NAME...]",
+		Short: "Build and export images to the local tarball or OCI directory",
+		Example: `  # Export all images from werf.yaml into the OCI image layout directory
+  $ werf export --stages-storage :local --to oci:./out
+
+  # Export image 'backend' into the docker-archive tarball (can be loaded by docker load)
+  $ werf export --stages-storage :local --to docker-archive:backend.tar backend
+
+  # Export images with the custom names: registry.example.com/app/IMAGE_NAME:v1.0.0
+  $ werf export --stages-storage :local --to oci:./out --repo registry.example.com/app --tag v1.0.0`,
+		Long: common.GetLongCommandDescription(`Build and export images described in the werf.yaml to files.
+
+Stages of images are built or fetched from the specified stages storage, then the final images are written to the destination:
+* oci:DIR — OCI image layout directory, images are added to the existing layout replacing the images with the same names;
+* docker-archive:FILE — tarball in the docker save format.
+
+Images are named REPO/IMAGE_NAME:TAG (REPO:TAG for the nameless image), project name and stages signature are used by default, the platform is added to the tag of each platform variant of the image.
+
+The JSON index with the name, platform, image id and digest of each exported image is written to DIR/werf-export.json or FILE.json (or to the path specified by --index-path).
+
+If one or more IMAGE_NAME parameters specified, werf will export only these images`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfDebugAnsibleArgs),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if cmdData.To == "" {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("--to DESTINATION required")
+			} else if _, _, err := build.ParseExportDestination(cmdData.To); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runExport(args)
+			})
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+
+	common.SetupContainerRuntime(&commonCmdData, cmd)
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified stages storage, to pull base images")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupRegistryConcurrency(&commonCmdData, cmd)
+
+	common.SetupIntrospectStage(&commonCmdData, cmd)
+	common.SetupVulnerabilityScan(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	common.SetupVirtualMerge(&commonCmdData, cmd)
+	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
+	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
+	common.SetupDev(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.To, "to", "", os.Getenv("WERF_EXPORT_TO"), "Export destination: oci:DIR or docker-archive:FILE ($WERF_EXPORT_TO by default)")
+	cmd.Flags().StringVarP(&cmdData.Repo, "repo", "", os.Getenv("WERF_EXPORT_REPO"), "Repository of the exported images names (project name by default or $WERF_EXPORT_REPO)")
+	cmd.Flags().StringVarP(&cmdData.Tag, "tag", "", os.Getenv("WERF_EXPORT_TAG"), "Tag of the exported images names (stages signature by default or $WERF_EXPORT_TAG)")
+	cmd.Flags().StringVarP(&cmdData.IndexPath, "index-path", "", os.Getenv("WERF_EXPORT_INDEX_PATH"), "Path of the JSON index of the exported images (DIR/werf-export.json or FILE.json by default or $WERF_EXPORT_INDEX_PATH)")
+
+	cmd.Flags().BoolVarP(&cmdData.IntrospectAfterError, "introspect-error", "", false, "Introspect failed stage in the state, right after running failed assembly instruction")
+	cmd.Flags().BoolVarP(&cmdData.IntrospectBeforeError, "introspect-before-error", "", false, "Introspect failed stage in the clean state, before running all assembly instructions of the stage")
+
+	return cmd
+}
+
+func runExport(imagesToProcess []string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{Out: logging.GetOutStream(), Err: logging.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	containerRuntime, err := common.InitContainerRuntime(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(projectDir, &commonCmdData, true)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	for _, imageToProcess := range imagesToProcess {
+		if !werfConfig.HasImage(imageToProcess) {
+			return fmt.Errorf("specified image %s is not defined in werf.yaml", logging.ImageLogName(imageToProcess, false))
+		}
+	}
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir()
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	stagesStorage, err := common.GetStagesStorage(containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(&commonCmdData, stagesStorage.Address())
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(synchronization, "kubernetes://") {
+		if err := kube.Init(kube.InitOptions{KubeContext: *commonCmdData.KubeContext, KubeConfig: *commonCmdData.KubeConfig}); err != nil {
+			return fmt.Errorf("cannot initialize kube: %s", err)
+		}
+	}
+	stagesStorageCache, err := common.GetStagesStorageCache(synchronization)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(synchronization)
+	if err != nil {
+		return err
+	}
+
+	conveyorOptions, err := common.GetConveyorOptions(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	stagesManager := stages_manager.NewStagesManager(common.GetStagesProjectName(projectName, &commonCmdData), storageLockManager, stagesStorageCache)
+	if err := stagesManager.UseStagesStorage(stagesStorage); err != nil {
+		return err
+	}
+
+	if err := ssh_agent.Init(*commonCmdData.SSHKeys); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logboek.LogWarnF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	introspectOptions, err := common.GetIntrospectOptions(&commonCmdData, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	vulnerabilityScanOptions, err := common.GetVulnerabilityScanOptions(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	opts := build.BuildAndExportOptions{
+		BuildStagesOptions: build.BuildStagesOptions{
+			ImageBuildOptions: container_runtime.BuildOptions{
+				IntrospectAfterError:  cmdData.IntrospectAfterError,
+				IntrospectBeforeError: cmdData.IntrospectBeforeError,
+			},
+			IntrospectOptions:        introspectOptions,
+			VulnerabilityScanOptions: vulnerabilityScanOptions,
+		},
+		ExportImagesOptions: build.ExportImagesOptions{
+			ImagesToExport: imagesToProcess,
+			Destination:    cmdData.To,
+			Repo:           cmdData.Repo,
+			Tag:            cmdData.Tag,
+			IndexPath:      cmdData.IndexPath,
+		},
+	}
+
+	logboek.LogOptionalLn()
+
+	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, imagesToProcess, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, nil, storageLockManager, conveyorOptions)
+	defer conveyorWithRetry.Terminate()
+
+	if err := conveyorWithRetry.WithRetryBlock(func(c *build.Conveyor) error {
+		return c.BuildAndExport(opts)
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
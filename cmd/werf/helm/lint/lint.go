@@ -5,13 +5,12 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/flant/logboek"
-
 	"github.com/flant/werf/cmd/werf/common"
 	"github.com/flant/werf/pkg/deploy"
 	"github.com/flant/werf/pkg/deploy/helm"
 	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/images_manager"
+	"github.com/flant/werf/pkg/logging"
 	"github.com/flant/werf/pkg/storage"
 	"github.com/flant/werf/pkg/tag_strategy"
 	"github.com/flant/werf/pkg/true_git"
@@ -52,6 +51,8 @@ func NewCmd() *cobra.Command {
 	common.SetupSetString(&commonCmdData, cmd)
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
+	common.SetupSecretSet(&commonCmdData, cmd)
+	common.SetupSetFileSecret(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
@@ -64,7 +65,7 @@ func runLint() error {
 		return fmt.Errorf("initialization error: %s", err)
 	}
 
-	if err := true_git.Init(true_git.Options{Out: logboek.GetOutStream(), Err: logboek.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+	if err := true_git.Init(true_git.Options{Out: logging.GetOutStream(), Err: logging.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
 		return err
 	}
 
@@ -130,6 +131,8 @@ func runLint() error {
 		SecretValues:    *commonCmdData.SecretValues,
 		Set:             *commonCmdData.Set,
 		SetString:       *commonCmdData.SetString,
+		SecretSet:       *commonCmdData.SecretSet,
+		SetFileSecret:   *commonCmdData.SetFileSecret,
 		Env:             *commonCmdData.Environment,
 		IgnoreSecretKey: *commonCmdData.IgnoreSecretKey,
 	})
@@ -9,16 +9,16 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/flant/logboek"
-
 	"github.com/flant/werf/cmd/werf/common"
 	helm_common "github.com/flant/werf/cmd/werf/helm/common"
 	"github.com/flant/werf/pkg/deploy"
 	"github.com/flant/werf/pkg/deploy/helm"
 	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/images_manager"
+	"github.com/flant/werf/pkg/logging"
 	"github.com/flant/werf/pkg/tmp_manager"
 	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/util/secretvalues"
 	"github.com/flant/werf/pkg/werf"
 )
 
@@ -62,6 +62,8 @@ func NewCmd() *cobra.Command {
 	common.SetupSetString(&commonCmdData, cmd)
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
+	common.SetupSecretSet(&commonCmdData, cmd)
+	common.SetupSetFileSecret(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
 
 	common.SetupImagesRepoOptions(&commonCmdData, cmd)
@@ -82,7 +84,7 @@ func runRender(outputFilePath string) error {
 		return fmt.Errorf("initialization error: %s", err)
 	}
 
-	if err := true_git.Init(true_git.Options{Out: logboek.GetOutStream(), Err: logboek.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+	if err := true_git.Init(true_git.Options{Out: logging.GetOutStream(), Err: logging.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
 		return err
 	}
 
@@ -180,6 +182,8 @@ func runRender(outputFilePath string) error {
 		SecretValues:         *commonCmdData.SecretValues,
 		Set:                  *commonCmdData.Set,
 		SetString:            *commonCmdData.SetString,
+		SecretSet:            *commonCmdData.SecretSet,
+		SetFileSecret:        *commonCmdData.SetFileSecret,
 		Env:                  env,
 		UserExtraAnnotations: userExtraAnnotations,
 		UserExtraLabels:      userExtraLabels,
@@ -193,7 +197,7 @@ func runRender(outputFilePath string) error {
 			return err
 		}
 	} else {
-		fmt.Printf("%s", buf.String())
+		fmt.Printf("%s", secretvalues.MaskRegisteredSecretValues(buf.String()))
 	}
 
 	return nil
@@ -81,7 +81,7 @@ func run(projectNames ...string) error {
 		return err
 	}
 
-	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+	containerRuntime := container_runtime.NewLocalDockerServerRuntime() // TODO
 
 	stagesStorage, err := common.GetStagesStorage(containerRuntime, &commonCmdData)
 	if err != nil {
@@ -15,8 +15,6 @@ import (
 
 	"github.com/flant/werf/cmd/werf/common"
 	"github.com/flant/werf/pkg/build"
-	"github.com/flant/werf/pkg/container_runtime"
-	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/logging"
 	"github.com/flant/werf/pkg/ssh_agent"
 	"github.com/flant/werf/pkg/tmp_manager"
@@ -62,9 +60,11 @@ If one or more IMAGE_NAME parameters specified, werf will publish only these ima
 	common.SetupStagesStorageOptions(commonCmdData, cmd)
 	common.SetupImagesRepoOptions(commonCmdData, cmd)
 
+	common.SetupContainerRuntime(commonCmdData, cmd)
 	common.SetupDockerConfig(commonCmdData, cmd, "Command needs granted permissions to read and pull images from the specified stages storage and push images into images repo")
 	common.SetupInsecureRegistry(commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(commonCmdData, cmd)
+	common.SetupRegistryConcurrency(commonCmdData, cmd)
 
 	common.SetupLogOptions(commonCmdData, cmd)
 	common.SetupLogProjectDir(commonCmdData, cmd)
@@ -79,6 +79,7 @@ If one or more IMAGE_NAME parameters specified, werf will publish only these ima
 	common.SetupVirtualMerge(commonCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(commonCmdData, cmd)
 	common.SetupVirtualMergeIntoCommit(commonCmdData, cmd)
+	common.SetupDev(commonCmdData, cmd)
 
 	return cmd
 }
@@ -92,7 +93,7 @@ func runImagesPublish(commonCmdData *common.CmdData, imagesToProcess []string) e
 		return err
 	}
 
-	if err := true_git.Init(true_git.Options{Out: logboek.GetOutStream(), Err: logboek.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+	if err := true_git.Init(true_git.Options{Out: logging.GetOutStream(), Err: logging.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
 		return err
 	}
 
@@ -100,7 +101,8 @@ func runImagesPublish(commonCmdData *common.CmdData, imagesToProcess []string) e
 		return err
 	}
 
-	if err := docker.Init(*commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+	containerRuntime, err := common.InitContainerRuntime(commonCmdData)
+	if err != nil {
 		return err
 	}
 
@@ -132,8 +134,6 @@ func runImagesPublish(commonCmdData *common.CmdData, imagesToProcess []string) e
 	}
 	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
 
-	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
-
 	stagesStorage, err := common.GetStagesStorage(containerRuntime, commonCmdData)
 	if err != nil {
 		return err
@@ -157,7 +157,12 @@ func runImagesPublish(commonCmdData *common.CmdData, imagesToProcess []string) e
 		return err
 	}
 
-	stagesManager := stages_manager.NewStagesManager(projectName, storageLockManager, stagesStorageCache)
+	conveyorOptions, err := common.GetConveyorOptions(commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	stagesManager := stages_manager.NewStagesManager(common.GetStagesProjectName(projectName, commonCmdData), storageLockManager, stagesStorageCache)
 	if err := stagesManager.UseStagesStorage(stagesStorage); err != nil {
 		return err
 	}
@@ -194,7 +199,7 @@ func runImagesPublish(commonCmdData *common.CmdData, imagesToProcess []string) e
 		PublishReportFormat: publishReportFormat,
 	}
 
-	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, imagesToProcess, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, common.GetConveyorOptions(commonCmdData))
+	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, imagesToProcess, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, conveyorOptions)
 	defer conveyorWithRetry.Terminate()
 
 	if err := conveyorWithRetry.WithRetryBlock(func(c *build.Conveyor) error {
@@ -0,0 +1,151 @@
+package list
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "list",
+		DisableFlagsInUseLine: true,
+		Short:                 "List project locks held in the synchronization backend",
+		Long: common.GetLongCommandDescription(`List project locks held in the synchronization backend (--synchronization).
+
+Prints lock name, owner host, PID and CI job, acquisition time and the lease expiration time (only for kubernetes synchronization, the running owner renews the lease until the lock is released). Stale locks have no live owners and could be released with werf locks release command.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runList()
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runList() error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(projectDir, &commonCmdData, false)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	synchronization, err := common.GetSynchronization(&commonCmdData, common.GetOptionalStagesStorageAddress(&commonCmdData))
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(synchronization, "kubernetes://") {
+		if err := kube.Init(kube.InitOptions{KubeContext: *commonCmdData.KubeContext, KubeConfig: *commonCmdData.KubeConfig}); err != nil {
+			return fmt.Errorf("cannot initialize kube: %s", err)
+		}
+	}
+	storageLockManager, err := common.GetStorageLockManager(synchronization)
+	if err != nil {
+		return err
+	}
+
+	locks, err := storageLockManager.ListLocks(projectName)
+	if err != nil {
+		return fmt.Errorf("unable to list locks of project %q: %s", projectName, err)
+	}
+
+	if len(locks) == 0 {
+		logboek.LogLn("No locks found")
+		return nil
+	}
+
+	t := uitable.New()
+	t.MaxColWidth = uint(logboek.ContentWidth())
+	t.AddRow("NAME", "STATUS", "HOST", "PID", "CI JOB", "ACQUIRED", "EXPIRES")
+
+	for _, lock := range locks {
+		status := "active"
+		if lock.Stale {
+			status = "stale"
+		}
+		if lock.Shared {
+			status += ", shared"
+			if lock.HoldersCount > 0 {
+				status += fmt.Sprintf(" by %d", lock.HoldersCount)
+			}
+		}
+
+		expires := "-"
+		if !lock.ExpireAt.IsZero() {
+			expires = humanTime(lock.ExpireAt)
+		}
+
+		if len(lock.Owners) == 0 {
+			t.AddRow(lock.Name, status, "unknown", "-", "-", "-", expires)
+			continue
+		}
+
+		for _, owner := range lock.Owners {
+			ciJob := owner.CIJob
+			if ciJob == "" {
+				ciJob = "-"
+			}
+
+			t.AddRow(lock.Name, status, owner.Host, strconv.Itoa(owner.PID), ciJob, humanTimeAgo(owner.AcquiredAt), expires)
+		}
+	}
+
+	fmt.Println(t.String())
+
+	return nil
+}
+
+func humanTimeAgo(t time.Time) string {
+	return units.HumanDuration(time.Since(t)) + " ago"
+}
+
+func humanTime(t time.Time) string {
+	if time.Now().After(t) {
+		return humanTimeAgo(t)
+	}
+	return "in " + units.HumanDuration(time.Until(t))
+}
@@ -0,0 +1,105 @@
+package release
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var cmdData struct {
+	Force bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "release LOCK_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 "Release stuck project lock in the synchronization backend",
+		Long: common.GetLongCommandDescription(`Release stuck project lock in the synchronization backend (--synchronization).
+
+Lock names are printed by werf locks list command. Only stale locks without live owners are released by default. For kubernetes synchronization --force option allows to release the lock with active lease, the werf process which holds the lock will crash on the lost lease. Local host locks are released by the OS when the owner process exits, so only the records of the dead owners are removed.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if len(args) != 1 {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("accepts 1 position argument, received %d", len(args))
+			}
+
+			return runRelease(args[0])
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&cmdData.Force, "force", "", false, "Release the lock even if its owner may be still running")
+
+	return cmd
+}
+
+func runRelease(lockName string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(projectDir, &commonCmdData, false)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	synchronization, err := common.GetSynchronization(&commonCmdData, common.GetOptionalStagesStorageAddress(&commonCmdData))
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(synchronization, "kubernetes://") {
+		if err := kube.Init(kube.InitOptions{KubeContext: *commonCmdData.KubeContext, KubeConfig: *commonCmdData.KubeConfig}); err != nil {
+			return fmt.Errorf("cannot initialize kube: %s", err)
+		}
+	}
+	storageLockManager, err := common.GetStorageLockManager(synchronization)
+	if err != nil {
+		return err
+	}
+
+	if err := storageLockManager.ReleaseLock(projectName, lockName, cmdData.Force); err != nil {
+		return fmt.Errorf("unable to release lock %q of project %q: %s", lockName, projectName, err)
+	}
+
+	logboek.LogF("Lock %q has been released\n", lockName)
+
+	return nil
+}
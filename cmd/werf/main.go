@@ -14,8 +14,10 @@ import (
 	"github.com/flant/werf/cmd/werf/cleanup"
 	"github.com/flant/werf/cmd/werf/deploy"
 	"github.com/flant/werf/cmd/werf/dismiss"
+	"github.com/flant/werf/cmd/werf/export"
 	"github.com/flant/werf/cmd/werf/publish"
 	"github.com/flant/werf/cmd/werf/purge"
+	"github.com/flant/werf/cmd/werf/rollback"
 	"github.com/flant/werf/cmd/werf/run"
 
 	helm_secret_decrypt "github.com/flant/werf/cmd/werf/helm/secret/decrypt"
@@ -39,12 +41,15 @@ import (
 	images_cleanup "github.com/flant/werf/cmd/werf/images/cleanup"
 	images_publish "github.com/flant/werf/cmd/werf/images/publish"
 	images_purge "github.com/flant/werf/cmd/werf/images/purge"
+	images_stats "github.com/flant/werf/cmd/werf/images/stats"
 
 	stages_build "github.com/flant/werf/cmd/werf/stages/build"
 	stages_cleanup "github.com/flant/werf/cmd/werf/stages/cleanup"
 	stages_purge "github.com/flant/werf/cmd/werf/stages/purge"
+	stages_stats "github.com/flant/werf/cmd/werf/stages/stats"
 	stages_switch "github.com/flant/werf/cmd/werf/stages/switch_from_local"
 	stages_sync "github.com/flant/werf/cmd/werf/stages/sync"
+	stages_verify "github.com/flant/werf/cmd/werf/stages/verify"
 
 	stage_image "github.com/flant/werf/cmd/werf/stage/image"
 
@@ -53,6 +58,12 @@ import (
 	host_project_purge "github.com/flant/werf/cmd/werf/host/project/purge"
 	host_purge "github.com/flant/werf/cmd/werf/host/purge"
 
+	locks_list "github.com/flant/werf/cmd/werf/locks/list"
+	locks_release "github.com/flant/werf/cmd/werf/locks/release"
+
+	preview_gc "github.com/flant/werf/cmd/werf/preview/gc"
+	preview_up "github.com/flant/werf/cmd/werf/preview/up"
+
 	helm_delete "github.com/flant/werf/cmd/werf/helm/delete"
 	helm_dependency "github.com/flant/werf/cmd/werf/helm/dependency"
 	helm_deploy_chart "github.com/flant/werf/cmd/werf/helm/deploy_chart"
@@ -69,6 +80,8 @@ import (
 
 	config_list "github.com/flant/werf/cmd/werf/config/list"
 	config_render "github.com/flant/werf/cmd/werf/config/render"
+	config_schema "github.com/flant/werf/cmd/werf/config/schema"
+	config_validate "github.com/flant/werf/cmd/werf/config/validate"
 
 	"github.com/flant/werf/cmd/werf/completion"
 	"github.com/flant/werf/cmd/werf/docs"
@@ -110,9 +123,12 @@ Find more information at https://werf.io`),
 				build.NewCmd(),
 				publish.NewCmd(),
 				build_and_publish.NewCmd(),
+				export.NewCmd(),
 				run.NewCmd(),
 				deploy.NewCmd(),
 				dismiss.NewCmd(),
+				rollback.NewCmd(),
+				previewCmd(),
 				cleanup.NewCmd(),
 				purge.NewCmd(),
 			},
@@ -133,6 +149,7 @@ Find more information at https://werf.io`),
 				managedImagesCmd(),
 				helmCmd(),
 				hostCmd(),
+				locksCmd(),
 			},
 		},
 	}
@@ -150,6 +167,8 @@ Find more information at https://werf.io`),
 	if err := rootCmd.Execute(); err != nil {
 		common.TerminateWithError(err.Error(), 1)
 	}
+
+	common.Shutdown(nil)
 }
 
 func configCmd() *cobra.Command {
@@ -160,6 +179,8 @@ func configCmd() *cobra.Command {
 	cmd.AddCommand(
 		config_render.NewCmd(),
 		config_list.NewCmd(),
+		config_validate.NewCmd(),
+		config_schema.NewCmd(),
 	)
 
 	return cmd
@@ -188,6 +209,7 @@ func imagesCmd() *cobra.Command {
 		images_publish.NewCmd(),
 		images_cleanup.NewCmd(),
 		images_purge.NewCmd(),
+		images_stats.NewCmd(),
 	)
 
 	return cmd
@@ -202,8 +224,10 @@ func stagesCmd() *cobra.Command {
 		stages_build.NewCmd(),
 		stages_cleanup.NewCmd(),
 		stages_purge.NewCmd(),
+		stages_stats.NewCmd(),
 		stages_switch.NewCmd(),
 		stages_sync.NewCmd(),
+		stages_verify.NewCmd(),
 	)
 
 	return cmd
@@ -246,6 +270,34 @@ func helmCmd() *cobra.Command {
 	return cmd
 }
 
+func locksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "locks",
+		Short: "Work with project locks of the synchronization backend",
+	}
+
+	cmd.AddCommand(
+		locks_list.NewCmd(),
+		locks_release.NewCmd(),
+	)
+
+	return cmd
+}
+
+func previewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Work with preview environments of the application",
+	}
+
+	cmd.AddCommand(
+		preview_up.NewCmd(),
+		preview_gc.NewCmd(),
+	)
+
+	return cmd
+}
+
 func hostCmd() *cobra.Command {
 	hostCmd := &cobra.Command{
 		Use:   "host",
@@ -0,0 +1,184 @@
+package gc
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/flant/werf/pkg/storage"
+
+	"github.com/flant/werf/pkg/image"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/deploy/helm"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var cmdData struct {
+	WithHooks bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Dismiss stale preview environments of the application",
+		Long: common.GetLongCommandDescription(`Dismiss stale preview environments of the application.
+
+Previews deployed by werf preview up are found by the Kubernetes Namespace labels. The preview is dismissed (Helm Release is purged and Kubernetes Namespace is deleted) when its ttl elapsed or its git branch no longer exists.
+
+Git branches are taken from the remote-tracking branches of origin in the project git repository, so the repository should be fetched with pruning before gc. The branch check is skipped when the project directory is not a git repository.
+
+The failure to dismiss one preview does not stop gc: the rest previews are processed and the command fails at the end.
+
+Read more info about preview environments: https://werf.io/documentation/reference/deploy_process/deploy_into_kubernetes.html`),
+		Example: `  # Dismiss stale previews of the project
+  $ git fetch --prune origin
+  $ werf preview gc
+
+  # Show which previews would be dismissed
+  $ werf preview gc --dry-run`,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runPreviewGC()
+			})
+		},
+	}
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+	common.SetupDir(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupSynchronization(&commonCmdData, cmd)
+
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+	common.SetupReleasesHistoryMax(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "")
+
+	common.SetupDryRun(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&cmdData.WithHooks, "with-hooks", "", true, "Delete Helm Release hooks getting from existing revisions")
+
+	return cmd
+}
+
+func runPreviewGC() error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	helmReleaseStorageType, err := common.GetHelmReleaseStorageType(*commonCmdData.HelmReleaseStorageType)
+	if err != nil {
+		return err
+	}
+
+	deployInitOptions := deploy.InitOptions{
+		HelmInitOptions: helm.InitOptions{
+			KubeConfig:                  *commonCmdData.KubeConfig,
+			KubeContext:                 *commonCmdData.KubeContext,
+			HelmReleaseStorageNamespace: *commonCmdData.HelmReleaseStorageNamespace,
+			HelmReleaseStorageType:      helmReleaseStorageType,
+			ReleasesMaxHistory:          *commonCmdData.ReleasesHistoryMax,
+		},
+	}
+	if err := deploy.Init(deployInitOptions); err != nil {
+		return err
+	}
+
+	common.LogKubeContext(kube.Context)
+
+	if err := docker.Init(*commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(projectDir, &commonCmdData, true)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+	logboek.LogOptionalLn()
+
+	projectName := werfConfig.Meta.Project
+
+	err = kube.Init(kube.InitOptions{KubeContext: *commonCmdData.KubeContext, KubeConfig: *commonCmdData.KubeConfig})
+	if err != nil {
+		return fmt.Errorf("cannot initialize kube: %s", err)
+	}
+
+	if err := common.InitKubedog(); err != nil {
+		return fmt.Errorf("cannot init kubedog: %s", err)
+	}
+
+	stagesStorageAddress := common.GetOptionalStagesStorageAddress(&commonCmdData)
+	if stagesStorageAddress == "" {
+		stagesStorageAddress = storage.LocalStorageAddress
+	}
+	synchronization, err := common.GetSynchronization(&commonCmdData, stagesStorageAddress)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(synchronization)
+	if err != nil {
+		return err
+	}
+
+	var gitBranches []string
+	gitDir := filepath.Join(projectDir, ".git")
+	if exist, err := util.DirExists(gitDir); err != nil {
+		return err
+	} else if exist {
+		localGitRepo := &git_repo.Local{Path: projectDir, GitDir: gitDir}
+		if gitBranches, err = localGitRepo.RemoteBranchesList(); err != nil {
+			return fmt.Errorf("cannot get local git branches list: %s", err)
+		}
+
+		if len(gitBranches) == 0 {
+			logboek.LogWarnF("WARNING: No remote-tracking branches of origin found: git branches of previews will not be checked\n")
+			gitBranches = nil
+		}
+	} else {
+		logboek.LogWarnF("WARNING: Project directory is not a git repository: git branches of previews will not be checked\n")
+	}
+
+	return deploy.RunPreviewGC(projectName, *commonCmdData.KubeContext, storageLockManager, deploy.PreviewGCOptions{
+		GitBranches: gitBranches,
+		WithHooks:   cmdData.WithHooks,
+		DryRun:      *commonCmdData.DryRun,
+	})
+}
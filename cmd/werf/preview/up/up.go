@@ -0,0 +1,356 @@
+package up
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/flant/werf/pkg/storage"
+
+	"github.com/flant/werf/pkg/image"
+
+	"github.com/flant/werf/pkg/stages_manager"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/build"
+	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/deploy/helm"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/images_manager"
+	"github.com/flant/werf/pkg/logging"
+	"github.com/flant/werf/pkg/ssh_agent"
+	"github.com/flant/werf/pkg/tag_strategy"
+	"github.com/flant/werf/pkg/tmp_manager"
+	"github.com/flant/werf/pkg/true_git"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var cmdData struct {
+	Timeout int
+	ID      string
+	Branch  string
+	TTL     string
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Deploy preview environment of the application into Kubernetes",
+		Long: common.GetLongCommandDescription(`Deploy preview environment of the application into Kubernetes.
+
+Preview environment is identified by --id, for example merge request number. Helm Release name and Kubernetes Namespace are constructed from the project name and the preview ID as [[ project ]]-preview-[[ id ]] and slugified if needed.
+
+Kubernetes Namespace and all release resources are labeled with the preview ID and the expiry time (now + --ttl). Git branch of the preview is stored in the Namespace annotation. werf preview gc dismisses previews which git branch no longer exists or which ttl elapsed.
+
+Deploy needs the same parameters as werf deploy to construct image names: repo and tags.
+
+Read more info about preview environments: https://werf.io/documentation/reference/deploy_process/deploy_into_kubernetes.html`),
+		Example: `  # Deploy preview of merge request 42 from branch 'feature-x' which could be dismissed after 3 days
+  $ werf preview up --stages-storage :local --images-repo registry.mydomain.com/myproject --tag-git-branch feature-x --id 42 --branch feature-x --ttl 72h`,
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runPreviewUp()
+			})
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupTag(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+	common.SetupAddAnnotations(&commonCmdData, cmd)
+	common.SetupAddLabels(&commonCmdData, cmd)
+
+	common.SetupHelmChartDir(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+	common.SetupStatusProgressPeriod(&commonCmdData, cmd)
+	common.SetupHooksStatusProgressPeriod(&commonCmdData, cmd)
+	common.SetupReleasesHistoryMax(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupImagesRepoOptions(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read and pull images from the specified stages storage and images repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupRegistryConcurrency(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSet(&commonCmdData, cmd)
+	common.SetupSetString(&commonCmdData, cmd)
+	common.SetupValues(&commonCmdData, cmd)
+	common.SetupSecretValues(&commonCmdData, cmd)
+	common.SetupSecretSet(&commonCmdData, cmd)
+	common.SetupSetFileSecret(&commonCmdData, cmd)
+	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
+
+	common.SetupThreeWayMergeMode(&commonCmdData, cmd)
+	common.SetupAutoRollback(&commonCmdData, cmd)
+
+	common.SetupVirtualMerge(&commonCmdData, cmd)
+	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
+	common.SetupVirtualMergeIntoCommit(&commonCmdData, cmd)
+	common.SetupDev(&commonCmdData, cmd)
+
+	cmd.Flags().IntVarP(&cmdData.Timeout, "timeout", "t", 0, "Resources tracking timeout in seconds")
+
+	cmd.Flags().StringVarP(&cmdData.ID, "id", "", os.Getenv("WERF_PREVIEW_ID"), "Preview ID, for example merge request number (default $WERF_PREVIEW_ID)")
+	cmd.Flags().StringVarP(&cmdData.Branch, "branch", "", os.Getenv("WERF_PREVIEW_BRANCH"), "Git branch of the preview, werf preview gc dismisses the preview when the branch no longer exists (default $WERF_PREVIEW_BRANCH)")
+
+	defaultTTL := os.Getenv("WERF_PREVIEW_TTL")
+	if defaultTTL == "" {
+		defaultTTL = "72h"
+	}
+	cmd.Flags().StringVarP(&cmdData.TTL, "ttl", "", defaultTTL, "Preview time to live since the last werf preview up, 0 disables expiry (default $WERF_PREVIEW_TTL or 72h)")
+
+	return cmd
+}
+
+func runPreviewUp() error {
+	if cmdData.ID == "" {
+		return fmt.Errorf("--id option or $WERF_PREVIEW_ID variable required")
+	}
+
+	ttl, err := time.ParseDuration(cmdData.TTL)
+	if err != nil {
+		return fmt.Errorf("bad --ttl value '%s': %s", cmdData.TTL, err)
+	}
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(true_git.Options{Out: logging.GetOutStream(), Err: logging.GetErrStream(), LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	helmReleaseStorageType, err := common.GetHelmReleaseStorageType(*commonCmdData.HelmReleaseStorageType)
+	if err != nil {
+		return err
+	}
+
+	threeWayMergeMode, err := common.GetThreeWayMergeMode(*commonCmdData.ThreeWayMergeMode)
+	if err != nil {
+		return err
+	}
+
+	deployInitOptions := deploy.InitOptions{
+		HelmInitOptions: helm.InitOptions{
+			KubeConfig:                  *commonCmdData.KubeConfig,
+			KubeContext:                 *commonCmdData.KubeContext,
+			HelmReleaseStorageNamespace: *commonCmdData.HelmReleaseStorageNamespace,
+			HelmReleaseStorageType:      helmReleaseStorageType,
+			StatusProgressPeriod:        common.GetStatusProgressPeriod(&commonCmdData),
+			HooksStatusProgressPeriod:   common.GetHooksStatusProgressPeriod(&commonCmdData),
+			ReleasesMaxHistory:          *commonCmdData.ReleasesHistoryMax,
+			InitNamespace:               true,
+		},
+	}
+	if err := deploy.Init(deployInitOptions); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := docker.Init(*commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	if err := kube.Init(kube.InitOptions{KubeContext: *commonCmdData.KubeContext, KubeConfig: *commonCmdData.KubeConfig}); err != nil {
+		return fmt.Errorf("cannot initialize kube: %s", err)
+	}
+
+	if err := common.InitKubedog(); err != nil {
+		return fmt.Errorf("cannot init kubedog: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	helmChartDir, err := common.GetHelmChartDir(projectDir, &commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting helm chart dir failed: %s", err)
+	}
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir()
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(projectDir, &commonCmdData, true)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	var imagesRepository string
+	var tag string
+	var tagStrategy tag_strategy.TagStrategy
+	var imagesInfoGetters []images_manager.ImageInfoGetter
+	var storageLockManager storage.LockManager
+
+	projectName := werfConfig.Meta.Project
+
+	if len(werfConfig.StapelImages) != 0 || len(werfConfig.ImagesFromDockerfile) != 0 {
+		containerRuntime := container_runtime.NewLocalDockerServerRuntime() // TODO
+
+		stagesStorage, err := common.GetStagesStorage(containerRuntime, &commonCmdData)
+		if err != nil {
+			return err
+		}
+		synchronization, err := common.GetSynchronization(&commonCmdData, stagesStorage.Address())
+		if err != nil {
+			return err
+		}
+		storageLockManager, err = common.GetStorageLockManager(synchronization)
+		if err != nil {
+			return err
+		}
+		stagesStorageCache, err := common.GetStagesStorageCache(synchronization)
+		if err != nil {
+			return err
+		}
+
+		conveyorOptions, err := common.GetConveyorOptions(&commonCmdData)
+		if err != nil {
+			return err
+		}
+
+		stagesManager := stages_manager.NewStagesManager(common.GetStagesProjectName(projectName, &commonCmdData), storageLockManager, stagesStorageCache)
+		if err := stagesManager.UseStagesStorage(stagesStorage); err != nil {
+			return err
+		}
+
+		imagesRepo, err := common.GetImagesRepo(projectName, &commonCmdData)
+		if err != nil {
+			return err
+		}
+
+		imagesRepository = imagesRepo.String()
+
+		tag, tagStrategy, err = common.GetDeployTag(&commonCmdData, common.TagOptionsGetterOptions{})
+		if err != nil {
+			return err
+		}
+
+		if err := ssh_agent.Init(*commonCmdData.SSHKeys); err != nil {
+			return fmt.Errorf("cannot initialize ssh agent: %s", err)
+		}
+		defer func() {
+			err := ssh_agent.Terminate()
+			if err != nil {
+				logboek.LogWarnF("WARNING: ssh agent termination failed: %s\n", err)
+			}
+		}()
+
+		logboek.LogOptionalLn()
+
+		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, []string{}, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, conveyorOptions)
+		defer conveyorWithRetry.Terminate()
+
+		if err := conveyorWithRetry.WithRetryBlock(func(c *build.Conveyor) error {
+			if err := c.ShouldBeBuilt(build.ShouldBeBuiltOptions{}); err != nil {
+				return err
+			}
+
+			imagesInfoGetters = c.GetImageInfoGetters(werfConfig.StapelImages, werfConfig.ImagesFromDockerfile, tag, tagStrategy, false)
+			return nil
+		}); err != nil {
+			return err
+		}
+	} else {
+		stagesStorageAddress := common.GetOptionalStagesStorageAddress(&commonCmdData)
+		if stagesStorageAddress == "" {
+			stagesStorageAddress = storage.LocalStorageAddress
+		}
+		synchronization, err := common.GetSynchronization(&commonCmdData, stagesStorageAddress)
+		if err != nil {
+			return err
+		}
+		storageLockManager, err = common.GetStorageLockManager(synchronization)
+		if err != nil {
+			return err
+		}
+	}
+
+	preview := deploy.NewPreview(projectName, cmdData.ID, cmdData.Branch, ttl)
+
+	env := *commonCmdData.Environment
+	if env == "" {
+		env = fmt.Sprintf("preview-%s", preview.ID)
+	}
+
+	userExtraAnnotations, err := common.GetUserExtraAnnotations(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	userExtraLabels, err := common.GetUserExtraLabels(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range preview.Labels(projectName) {
+		userExtraLabels[k] = v
+	}
+
+	if err := deploy.ApplyPreviewNamespace(projectName, preview); err != nil {
+		return err
+	}
+
+	logboek.LogOptionalLn()
+	return deploy.Deploy(projectName, projectDir, helmChartDir, imagesRepository, imagesInfoGetters, preview.Release, preview.Namespace, tag, tagStrategy, werfConfig, *commonCmdData.HelmReleaseStorageNamespace, helmReleaseStorageType, storageLockManager, deploy.DeployOptions{
+		Set:                  *commonCmdData.Set,
+		SetString:            *commonCmdData.SetString,
+		SecretSet:            *commonCmdData.SecretSet,
+		SetFileSecret:        *commonCmdData.SetFileSecret,
+		Values:               *commonCmdData.Values,
+		SecretValues:         *commonCmdData.SecretValues,
+		Timeout:              time.Duration(cmdData.Timeout) * time.Second,
+		Env:                  env,
+		UserExtraAnnotations: userExtraAnnotations,
+		UserExtraLabels:      userExtraLabels,
+		IgnoreSecretKey:      *commonCmdData.IgnoreSecretKey,
+		ThreeWayMergeMode:    threeWayMergeMode,
+		AutoRollback:         *commonCmdData.AutoRollback,
+	})
+}
@@ -0,0 +1,185 @@
+package rollback
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/deploy"
+	"github.com/flant/werf/pkg/deploy/helm"
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/storage"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var cmdData struct {
+	ToCommit             string
+	ToPreviousSuccessful bool
+	Timeout              int
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Rollback application in Kubernetes to the previously deployed version",
+		Long: common.GetLongCommandDescription(`Rollback application in Kubernetes to the previously deployed version.
+
+The target Helm Release revision is resolved by the git commit or as the previous successfully deployed revision. werf deploy records the git commit, tag and images used for each release revision, so only revisions deployed by werf with this info could be found by the commit.
+
+Command shows the changes of images and resources between the current and the target revisions, rolls back the release and waits until all resources of the release are become ready.
+
+Environment is a required param for the rollback by default, because it is needed to construct Helm Release name and Kubernetes Namespace. Either --env or $WERF_ENV should be specified for command.
+
+Read more info about Helm Release name, Kubernetes Namespace and how to change it: https://werf.io/documentation/reference/deploy_process/deploy_into_kubernetes.html`),
+		Example: `  # Rollback project deployed into 'dev' environment to the revision deployed from the commit 4a5e3b1
+  $ werf rollback --env dev --to-commit 4a5e3b1
+
+  # Rollback project deployed into 'production' environment to the previous successfully deployed revision
+  $ werf rollback --env production --to-previous-successful`,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if (cmdData.ToCommit == "") == !cmdData.ToPreviousSuccessful {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("either --to-commit or --to-previous-successful should be specified")
+			}
+
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runRollback()
+			})
+		},
+	}
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+	common.SetupDir(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupSynchronization(&commonCmdData, cmd)
+
+	common.SetupEnvironment(&commonCmdData, cmd)
+	common.SetupRelease(&commonCmdData, cmd)
+	common.SetupNamespace(&commonCmdData, cmd)
+
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+	common.SetupStatusProgressPeriod(&commonCmdData, cmd)
+	common.SetupHooksStatusProgressPeriod(&commonCmdData, cmd)
+	common.SetupReleasesHistoryMax(&commonCmdData, cmd)
+
+	common.SetupThreeWayMergeMode(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.ToCommit, "to-commit", "", "", "Rollback to the latest successfully deployed release revision from the specified git commit (full or abbreviated)")
+	cmd.Flags().BoolVarP(&cmdData.ToPreviousSuccessful, "to-previous-successful", "", false, "Rollback to the successfully deployed release revision preceding the current one")
+	cmd.Flags().IntVarP(&cmdData.Timeout, "timeout", "t", 0, "Resources tracking timeout in seconds")
+
+	return cmd
+}
+
+func runRollback() error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	helmReleaseStorageType, err := common.GetHelmReleaseStorageType(*commonCmdData.HelmReleaseStorageType)
+	if err != nil {
+		return err
+	}
+
+	threeWayMergeMode, err := common.GetThreeWayMergeMode(*commonCmdData.ThreeWayMergeMode)
+	if err != nil {
+		return err
+	}
+
+	deployInitOptions := deploy.InitOptions{
+		HelmInitOptions: helm.InitOptions{
+			KubeConfig:                  *commonCmdData.KubeConfig,
+			KubeContext:                 *commonCmdData.KubeContext,
+			HelmReleaseStorageNamespace: *commonCmdData.HelmReleaseStorageNamespace,
+			HelmReleaseStorageType:      helmReleaseStorageType,
+			StatusProgressPeriod:        common.GetStatusProgressPeriod(&commonCmdData),
+			HooksStatusProgressPeriod:   common.GetHooksStatusProgressPeriod(&commonCmdData),
+			ReleasesMaxHistory:          *commonCmdData.ReleasesHistoryMax,
+		},
+	}
+	if err := deploy.Init(deployInitOptions); err != nil {
+		return err
+	}
+
+	if err := kube.Init(kube.InitOptions{KubeContext: *commonCmdData.KubeContext, KubeConfig: *commonCmdData.KubeConfig}); err != nil {
+		return fmt.Errorf("cannot initialize kube: %s", err)
+	}
+
+	if err := common.InitKubedog(); err != nil {
+		return fmt.Errorf("cannot init kubedog: %s", err)
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(projectDir, &commonCmdData, true)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+	logboek.LogOptionalLn()
+
+	projectName := werfConfig.Meta.Project
+
+	release, err := common.GetHelmRelease(*commonCmdData.Release, *commonCmdData.Environment, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := common.GetKubernetesNamespace(*commonCmdData.Namespace, *commonCmdData.Environment, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	stagesStorageAddress := common.GetOptionalStagesStorageAddress(&commonCmdData)
+	if stagesStorageAddress == "" {
+		stagesStorageAddress = storage.LocalStorageAddress
+	}
+	synchronization, err := common.GetSynchronization(&commonCmdData, stagesStorageAddress)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(synchronization)
+	if err != nil {
+		return err
+	}
+
+	return deploy.RunRollback(projectName, release, namespace, storageLockManager, deploy.RollbackOptions{
+		ToCommit:             cmdData.ToCommit,
+		ToPreviousSuccessful: cmdData.ToPreviousSuccessful,
+		Timeout:              time.Duration(cmdData.Timeout) * time.Second,
+		ThreeWayMergeMode:    threeWayMergeMode,
+	})
+}
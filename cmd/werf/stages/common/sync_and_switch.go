@@ -51,6 +51,14 @@ func SetupFromStagesStorage(commonCmdData *common.CmdData, cmdData *SyncCmdData,
 	common.SetupHarborUsernameForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-harbor-username", []string{"WERF_FROM_REPO_HARBOR_USERNAME", "WERF_REPO_HARBOR_USERNAME"})
 	common.SetupHarborPasswordForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-harbor-password", []string{"WERF_FROM_REPO_HARBOR_PASSWORD", "WERF_REPO_HARBOR_PASSWORD"})
 	common.SetupQuayTokenForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-quay-token", []string{"WERF_FROM_REPO_QUAY_TOKEN", "WERF_REPO_QUAY_TOKEN"})
+	common.SetupNexusUsernameForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-nexus-username", []string{"WERF_FROM_REPO_NEXUS_USERNAME", "WERF_REPO_NEXUS_USERNAME"})
+	common.SetupNexusPasswordForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-nexus-password", []string{"WERF_FROM_REPO_NEXUS_PASSWORD", "WERF_REPO_NEXUS_PASSWORD"})
+	common.SetupNexusApiUrlForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-nexus-api-url", []string{"WERF_FROM_REPO_NEXUS_API_URL", "WERF_REPO_NEXUS_API_URL"})
+	common.SetupNexusRepositoryForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-nexus-repository", []string{"WERF_FROM_REPO_NEXUS_REPOSITORY", "WERF_REPO_NEXUS_REPOSITORY"})
+	common.SetupArtifactoryUsernameForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-artifactory-username", []string{"WERF_FROM_REPO_ARTIFACTORY_USERNAME", "WERF_REPO_ARTIFACTORY_USERNAME"})
+	common.SetupArtifactoryPasswordForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-artifactory-password", []string{"WERF_FROM_REPO_ARTIFACTORY_PASSWORD", "WERF_REPO_ARTIFACTORY_PASSWORD"})
+	common.SetupAlibabaAccessKeyIdForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-alibaba-access-key-id", []string{"WERF_FROM_REPO_ALIBABA_ACCESS_KEY_ID", "WERF_REPO_ALIBABA_ACCESS_KEY_ID"})
+	common.SetupAlibabaAccessKeySecretForRepoData(cmdData.FromStagesStorageRepoData, cmd, "from-repo-alibaba-access-key-secret", []string{"WERF_FROM_REPO_ALIBABA_ACCESS_KEY_SECRET", "WERF_REPO_ALIBABA_ACCESS_KEY_SECRET"})
 }
 
 func SetupToStagesStorage(commonCmdData *common.CmdData, cmdData *SyncCmdData, cmd *cobra.Command) {
@@ -71,6 +79,14 @@ func SetupToStagesStorage(commonCmdData *common.CmdData, cmdData *SyncCmdData, c
 	common.SetupHarborUsernameForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-harbor-username", []string{"WERF_TO_REPO_HARBOR_USERNAME", "WERF_REPO_HARBOR_USERNAME"})
 	common.SetupHarborPasswordForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-harbor-password", []string{"WERF_TO_REPO_HARBOR_PASSWORD", "WERF_REPO_HARBOR_PASSWORD"})
 	common.SetupQuayTokenForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-quay-token", []string{"WERF_TO_REPO_QUAY_TOKEN", "WERF_REPO_QUAY_TOKEN"})
+	common.SetupNexusUsernameForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-nexus-username", []string{"WERF_TO_REPO_NEXUS_USERNAME", "WERF_REPO_NEXUS_USERNAME"})
+	common.SetupNexusPasswordForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-nexus-password", []string{"WERF_TO_REPO_NEXUS_PASSWORD", "WERF_REPO_NEXUS_PASSWORD"})
+	common.SetupNexusApiUrlForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-nexus-api-url", []string{"WERF_TO_REPO_NEXUS_API_URL", "WERF_REPO_NEXUS_API_URL"})
+	common.SetupNexusRepositoryForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-nexus-repository", []string{"WERF_TO_REPO_NEXUS_REPOSITORY", "WERF_REPO_NEXUS_REPOSITORY"})
+	common.SetupArtifactoryUsernameForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-artifactory-username", []string{"WERF_TO_REPO_ARTIFACTORY_USERNAME", "WERF_REPO_ARTIFACTORY_USERNAME"})
+	common.SetupArtifactoryPasswordForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-artifactory-password", []string{"WERF_TO_REPO_ARTIFACTORY_PASSWORD", "WERF_REPO_ARTIFACTORY_PASSWORD"})
+	common.SetupAlibabaAccessKeyIdForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-alibaba-access-key-id", []string{"WERF_TO_REPO_ALIBABA_ACCESS_KEY_ID", "WERF_REPO_ALIBABA_ACCESS_KEY_ID"})
+	common.SetupAlibabaAccessKeySecretForRepoData(cmdData.ToStagesStorageRepoData, cmd, "to-repo-alibaba-access-key-secret", []string{"WERF_TO_REPO_ALIBABA_ACCESS_KEY_SECRET", "WERF_REPO_ALIBABA_ACCESS_KEY_SECRET"})
 }
 
 func NewFromStagesStorage(commonCmdData *common.CmdData, cmdData *SyncCmdData, containerRuntime container_runtime.ContainerRuntime, defaultAddress string) (storage.StagesStorage, error) {
@@ -85,6 +101,7 @@ func NewFromStagesStorage(commonCmdData *common.CmdData, cmdData *SyncCmdData, c
 	}
 
 	repoData := common.MergeRepoData(cmdData.FromStagesStorageRepoData, commonCmdData.CommonRepoData)
+	common.RegisterRepoDataSecretValues(repoData)
 
 	if err := common.ValidateRepoImplementation(*repoData.Implementation); err != nil {
 		return nil, err
@@ -99,6 +116,7 @@ func NewToStagesStorage(commonCmdData *common.CmdData, cmdData *SyncCmdData, con
 	}
 
 	repoData := common.MergeRepoData(cmdData.ToStagesStorageRepoData, commonCmdData.CommonRepoData)
+	common.RegisterRepoDataSecretValues(repoData)
 
 	if err := common.ValidateRepoImplementation(*repoData.Implementation); err != nil {
 		return nil, err
@@ -115,15 +133,23 @@ func NewStagesStorage(commonCmdData *common.CmdData, stagesStorageAddress string
 			RepoStagesStorageOptions: storage.RepoStagesStorageOptions{
 				Implementation: *repoData.Implementation,
 				DockerRegistryOptions: docker_registry.DockerRegistryOptions{
-					InsecureRegistry:      *commonCmdData.InsecureRegistry,
-					SkipTlsVerifyRegistry: *commonCmdData.SkipTlsVerifyRegistry,
-					DockerHubUsername:     *repoData.DockerHubUsername,
-					DockerHubPassword:     *repoData.DockerHubPassword,
-					DockerHubToken:        *repoData.DockerHubToken,
-					GitHubToken:           *repoData.GitHubToken,
-					HarborUsername:        *repoData.HarborUsername,
-					HarborPassword:        *repoData.HarborPassword,
-					QuayToken:             *repoData.QuayToken,
+					InsecureRegistry:       *commonCmdData.InsecureRegistry,
+					SkipTlsVerifyRegistry:  *commonCmdData.SkipTlsVerifyRegistry,
+					DockerHubUsername:      *repoData.DockerHubUsername,
+					DockerHubPassword:      *repoData.DockerHubPassword,
+					DockerHubToken:         *repoData.DockerHubToken,
+					GitHubToken:            *repoData.GitHubToken,
+					HarborUsername:         *repoData.HarborUsername,
+					HarborPassword:         *repoData.HarborPassword,
+					QuayToken:              *repoData.QuayToken,
+					NexusUsername:          *repoData.NexusUsername,
+					NexusPassword:          *repoData.NexusPassword,
+					NexusApiUrl:            *repoData.NexusApiUrl,
+					NexusRepository:        *repoData.NexusRepository,
+					ArtifactoryUsername:    *repoData.ArtifactoryUsername,
+					ArtifactoryPassword:    *repoData.ArtifactoryPassword,
+					AlibabaAccessKeyId:     *repoData.AlibabaAccessKeyId,
+					AlibabaAccessKeySecret: *repoData.AlibabaAccessKeySecret,
 				},
 			},
 		},
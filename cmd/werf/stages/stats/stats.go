@@ -0,0 +1,199 @@
+package stats
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/cmd/werf/common"
+	"github.com/flant/werf/pkg/cleaning"
+	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/stages_manager"
+	"github.com/flant/werf/pkg/util"
+	"github.com/flant/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "stats",
+		DisableFlagsInUseLine: true,
+		Short:                 "Print project stages usage statistics",
+		Long: common.GetLongCommandDescription(`Print project stages usage statistics: count, total and unique size of stages per werf image, per stage and per age.
+
+Total size is the sum of stages sizes including parent stages layers, unique size counts only own layers of each stage. Reclaimable stages are the stages which would be removed by werf cleanup command: werf images cleanup is run in the dry run mode with the same options and policies and the stages which are not used by the remaining images repo images are selected as werf stages cleanup does.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if err := common.ProcessStatsOutput(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runStats()
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupImagesRepoOptions(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read images from the specified stages storage and images repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupRegistryConcurrency(&commonCmdData, cmd)
+	common.SetupImagesCleanupPolicies(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+	common.SetupWithoutKube(&commonCmdData, cmd)
+
+	common.SetupStatsOutput(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runStats() error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := docker.Init(*commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(projectDir, &commonCmdData, true)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	logboek.LogOptionalLn()
+
+	projectName := werfConfig.Meta.Project
+
+	containerRuntime := container_runtime.NewLocalDockerServerRuntime() // TODO
+
+	stagesStorage, err := common.GetStagesStorage(containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(&commonCmdData, stagesStorage.Address())
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(synchronization, "kubernetes://") || !*commonCmdData.WithoutKube {
+		if err := kube.Init(kube.InitOptions{KubeContext: *commonCmdData.KubeContext, KubeConfig: *commonCmdData.KubeConfig}); err != nil {
+			return fmt.Errorf("cannot initialize kube: %s", err)
+		}
+	}
+	stagesStorageCache, err := common.GetStagesStorageCache(synchronization)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(synchronization)
+	if err != nil {
+		return err
+	}
+
+	stagesManager := stages_manager.NewStagesManager(projectName, storageLockManager, stagesStorageCache)
+	if err := stagesManager.UseStagesStorage(stagesStorage); err != nil {
+		return err
+	}
+
+	imagesRepo, err := common.GetImagesRepo(projectName, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	imagesNames, err := common.GetManagedImagesNames(projectName, stagesStorage, werfConfig)
+	if err != nil {
+		return err
+	}
+	logboek.Debug.LogF("Managed images names: %v\n", imagesNames)
+
+	var localRepo cleaning.GitRepo
+	gitDir := filepath.Join(projectDir, ".git")
+	if exist, err := util.DirExists(gitDir); err != nil {
+		return err
+	} else if exist {
+		localRepo = &git_repo.Local{
+			Path:   projectDir,
+			GitDir: gitDir,
+		}
+	}
+
+	policies, err := common.GetImagesCleanupPolicies(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	var kubernetesContextsClients map[string]kubernetes.Interface
+	if !*commonCmdData.WithoutKube {
+		kubernetesContextsClients, err = kube.GetAllContextsClients(kube.GetAllContextsClientsOptions{KubeConfig: *commonCmdData.KubeConfig})
+		if err != nil {
+			return fmt.Errorf("unable to get Kubernetes clusters connections: %s", err)
+		}
+	}
+
+	stats, err := cleaning.GetStagesStats(imagesRepo, stagesManager, cleaning.StagesStatsOptions{
+		ImagesCleanupOptions: cleaning.ImagesCleanupOptions{
+			ImageNameList:             imagesNames,
+			LocalGit:                  localRepo,
+			KubernetesContextsClients: kubernetesContextsClients,
+			WithoutKube:               *commonCmdData.WithoutKube,
+			Policies:                  policies,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if *commonCmdData.StatsOutput == "json" {
+		return common.PrintStatsJSON(stats)
+	}
+
+	common.PrintSizeStatsTable("STAGES", map[string]*cleaning.SizeStats{"total": &stats.Total, "reclaimable": &stats.Reclaimable}, false, "total", "reclaimable")
+	common.PrintSizeStatsTable("IMAGE", stats.ByImage, true)
+	common.PrintSizeStatsTable("STAGE", stats.ByStage, true)
+	common.PrintAgeGroupsStatsTable(stats.ByAge)
+
+	return nil
+}
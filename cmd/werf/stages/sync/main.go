@@ -12,8 +12,6 @@ import (
 	"github.com/flant/logboek"
 	"github.com/flant/werf/cmd/werf/common"
 	stages_common "github.com/flant/werf/cmd/werf/stages/common"
-	"github.com/flant/werf/pkg/container_runtime"
-	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/werf"
 	"github.com/spf13/cobra"
 )
@@ -47,9 +45,11 @@ func NewCmd() *cobra.Command {
 	common.SetupTmpDir(&commonCmdData, cmd)
 	common.SetupHomeDir(&commonCmdData, cmd)
 
+	common.SetupContainerRuntime(&commonCmdData, cmd)
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and delete images from the specified stages storages")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+	common.SetupRegistryConcurrency(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 	common.SetupLogProjectDir(&commonCmdData, cmd)
@@ -79,7 +79,8 @@ func runSync() error {
 		return err
 	}
 
-	if err := docker.Init(*commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+	containerRuntime, err := common.InitContainerRuntime(&commonCmdData)
+	if err != nil {
 		return err
 	}
 
@@ -99,8 +100,6 @@ func runSync() error {
 
 	projectName := werfConfig.Meta.Project
 
-	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
-
 	fromStagesStorage, err := stages_common.NewFromStagesStorage(&commonCmdData, &cmdData, containerRuntime, "")
 	if err != nil {
 		return err
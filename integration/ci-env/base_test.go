@@ -23,6 +23,10 @@ var _ = Describe("base", func() {
 	ciSystems := []string{
 		"gitlab",
 		"github",
+		"jenkins",
+		"bitbucket",
+		"azure-devops",
+		"tekton",
 	}
 
 	for i := range ciSystems {
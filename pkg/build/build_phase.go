@@ -19,6 +19,7 @@ import (
 	"github.com/flant/werf/pkg/image"
 	imagePkg "github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/stapel"
+	"github.com/flant/werf/pkg/tracing"
 	"github.com/flant/werf/pkg/werf"
 )
 
@@ -31,6 +32,7 @@ type BuildPhaseOptions struct {
 type BuildStagesOptions struct {
 	ImageBuildOptions container_runtime.BuildOptions
 	IntrospectOptions
+	VulnerabilityScanOptions VulnerabilityScanOptions
 }
 
 type IntrospectOptions struct {
@@ -101,8 +103,8 @@ func (phase *BuildPhase) AfterImageStages(img *Image) error {
 	img.SetContentSignature(stagesSig)
 
 	if phase.ShouldAddManagedImageRecord {
-		if err := phase.Conveyor.StagesManager.StagesStorage.AddManagedImage(phase.Conveyor.projectName(), img.GetName()); err != nil {
-			return fmt.Errorf("unable to add image %q to the managed images of project %q: %s", img.GetName(), phase.Conveyor.projectName(), err)
+		if err := phase.Conveyor.StagesManager.StagesStorage.AddManagedImage(phase.Conveyor.stagesProjectName(), img.GetName()); err != nil {
+			return fmt.Errorf("unable to add image %q to the managed images of project %q: %s", img.GetName(), phase.Conveyor.stagesProjectName(), err)
 		}
 	}
 
@@ -219,6 +221,8 @@ func (phase *BuildPhase) calculateStage(img *Image, stg stage.Interface, shouldB
 	if err != nil {
 		return err
 	}
+	// platform variants of the stage share the logical signature and are stored separately
+	stageSig = image.PlatformSignature(stageSig, img.platform)
 	stg.SetSignature(stageSig)
 
 	if stages, err := phase.Conveyor.StagesManager.GetStagesBySignature(stg.LogDetailedName(), stageSig); err != nil {
@@ -258,11 +262,16 @@ func (phase *BuildPhase) prepareStageInstructions(img *Image, stg stage.Interfac
 
 	serviceLabels := map[string]string{
 		imagePkg.WerfDockerImageName:     stageImage.Name(),
-		imagePkg.WerfLabel:               phase.Conveyor.projectName(),
+		imagePkg.WerfLabel:               phase.Conveyor.stagesProjectName(),
 		imagePkg.WerfVersionLabel:        werf.Version,
 		imagePkg.WerfCacheVersionLabel:   imagePkg.BuildCacheVersion,
 		imagePkg.WerfImageLabel:          "false",
 		imagePkg.WerfStageSignatureLabel: stg.GetSignature(),
+		imagePkg.WerfStageNameLabel:      string(stg.Name()),
+	}
+
+	if img.platform != "" {
+		serviceLabels[imagePkg.WerfStagePlatformLabel] = img.platform
 	}
 
 	switch stg.(type) {
@@ -273,6 +282,15 @@ func (phase *BuildPhase) prepareStageInstructions(img *Image, stg stage.Interfac
 			buildArgs = append(buildArgs, fmt.Sprintf("--label=%s=%s", key, value))
 		}
 
+		if img.platform != "" {
+			buildArgs = append(buildArgs, fmt.Sprintf("--platform=%s", img.platform))
+		}
+
+		// inline cache metadata is required by buildkit to use the image as the cache source
+		if os.Getenv("DOCKER_BUILDKIT") == "1" {
+			buildArgs = append(buildArgs, "--build-arg=BUILDKIT_INLINE_CACHE=1")
+		}
+
 		stageImage.DockerfileImageBuilder().AppendBuildArgs(buildArgs...)
 
 		phase.Conveyor.AppendOnTerminateFunc(func() error {
@@ -298,6 +316,103 @@ func (phase *BuildPhase) prepareStageInstructions(img *Image, stg stage.Interfac
 	return nil
 }
 
+type dockerfileCacheImage struct {
+	cacheName string
+	imageName string
+}
+
+// dockerfileCacheName returns the layers cache name of the Dockerfile image target stage or the named intermediate stage
+func dockerfileCacheName(img *Image, dockerStageName string) string {
+	name := img.GetName()
+	if img.platform != "" {
+		name = fmt.Sprintf("%s/%s", name, img.platform)
+	}
+	if dockerStageName != "" {
+		name = fmt.Sprintf("%s/stage/%s", name, dockerStageName)
+	}
+	return name
+}
+
+// prepareDockerfileLayersCache makes layers of the previously built Dockerfile image available as the build cache,
+// so that only instructions since the first changed one are executed on the runner without local docker cache.
+// Named intermediate stages are built separately to be stored as cache images along with the target stage.
+// Cache sources are passed only if the cache has been fetched, otherwise the builder would ignore the local layers cache
+func (phase *BuildPhase) prepareDockerfileLayersCache(img *Image, stg *stage.DockerfileStage) ([]dockerfileCacheImage, error) {
+	dockerfileImageBuilder := stg.GetImage().DockerfileImageBuilder()
+	intermediateStagesNames := stg.DockerIntermediateStagesNames()
+
+	var cacheFrom []string
+	for _, dockerStageName := range append(intermediateStagesNames, "") {
+		if cacheImageName := phase.fetchDockerfileCache(dockerfileCacheName(img, dockerStageName)); cacheImageName != "" {
+			cacheFrom = append(cacheFrom, cacheImageName)
+		}
+	}
+	isCacheFetched := len(cacheFrom) != 0
+
+	var cacheImages []dockerfileCacheImage
+	for _, dockerStageName := range intermediateStagesNames {
+		var cacheFromArgs []string
+		if isCacheFetched {
+			for _, imageName := range cacheFrom {
+				cacheFromArgs = append(cacheFromArgs, fmt.Sprintf("--cache-from=%s", imageName))
+			}
+		}
+
+		var builtId string
+		if err := logboek.Info.LogProcess(fmt.Sprintf("Building Dockerfile stage %s", dockerStageName), logboek.LevelLogProcessOptions{}, func() error {
+			var err error
+			builtId, err = dockerfileImageBuilder.BuildStage(dockerStageName, cacheFromArgs...)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("unable to build Dockerfile stage %s: %s", dockerStageName, err)
+		}
+
+		cacheFrom = append(cacheFrom, builtId)
+		cacheImages = append(cacheImages, dockerfileCacheImage{cacheName: dockerfileCacheName(img, dockerStageName), imageName: builtId})
+	}
+
+	if isCacheFetched {
+		for _, imageName := range cacheFrom {
+			dockerfileImageBuilder.AppendBuildArgs(fmt.Sprintf("--cache-from=%s", imageName))
+		}
+	}
+
+	return cacheImages, nil
+}
+
+// fetchDockerfileCache returns the local name of the fetched cache image, empty if there is no cache
+func (phase *BuildPhase) fetchDockerfileCache(cacheName string) string {
+	var cacheImageName string
+	if err := logboek.Info.LogProcess(fmt.Sprintf("Fetching dockerfile layers cache %s", cacheName), logboek.LevelLogProcessOptions{}, func() error {
+		var err error
+		cacheImageName, err = phase.Conveyor.StagesManager.StagesStorage.FetchDockerfileCache(phase.Conveyor.stagesProjectName(), cacheName)
+		return err
+	}); err != nil {
+		logboek.LogWarnF("WARNING: unable to fetch dockerfile layers cache %s from the stages storage %s: %s\n", cacheName, phase.Conveyor.StagesManager.StagesStorage.String(), err)
+		return ""
+	}
+
+	if cacheImageName != "" {
+		phase.Conveyor.AppendOnTerminateFunc(func() error {
+			cacheImg := &container_runtime.DockerImage{Image: container_runtime.NewStageImage(nil, cacheImageName, phase.Conveyor.ContainerRuntime.(container_runtime.LocalRuntime))}
+			return phase.Conveyor.ContainerRuntime.RemoveImage(cacheImg)
+		})
+	}
+
+	return cacheImageName
+}
+
+func (phase *BuildPhase) storeDockerfileCache(cacheImages []dockerfileCacheImage) {
+	for _, cacheImage := range cacheImages {
+		img := &container_runtime.DockerImage{Image: container_runtime.NewStageImage(nil, cacheImage.imageName, phase.Conveyor.ContainerRuntime.(container_runtime.LocalRuntime))}
+		if err := logboek.Info.LogProcess(fmt.Sprintf("Storing dockerfile layers cache %s", cacheImage.cacheName), logboek.LevelLogProcessOptions{}, func() error {
+			return phase.Conveyor.StagesManager.StagesStorage.StoreDockerfileCache(phase.Conveyor.stagesProjectName(), cacheImage.cacheName, img)
+		}); err != nil {
+			logboek.LogWarnF("WARNING: unable to store dockerfile layers cache %s into the stages storage %s: %s\n", cacheImage.cacheName, phase.Conveyor.StagesManager.StagesStorage.String(), err)
+		}
+	}
+}
+
 func (phase *BuildPhase) buildStage(img *Image, stg stage.Interface) error {
 	_, err := stapel.GetOrCreateContainer()
 	if err != nil {
@@ -322,6 +437,13 @@ func (phase *BuildPhase) buildStage(img *Image, stg stage.Interface) error {
 			Style:           logboek.HighlightStyle(),
 		},
 		func() (err error) {
+			span := tracing.StartSpan(fmt.Sprintf("stage %s", stg.LogDetailedName()), map[string]string{
+				"image":     img.GetName(),
+				"stage":     string(stg.Name()),
+				"signature": stg.GetSignature(),
+			})
+			defer func() { span.End(err) }()
+
 			if err := stg.PreRunHook(phase.Conveyor); err != nil {
 				return fmt.Errorf("%s preRunHook failed: %s", stg.LogDetailedName(), err)
 			}
@@ -351,7 +473,15 @@ func (phase *BuildPhase) atomicBuildStageImage(img *Image, stg stage.Interface)
 		time.Sleep(time.Duration(seconds) * time.Second)
 	}
 
+	var dockerfileCacheImages []dockerfileCacheImage
 	if err := logboek.WithTag(fmt.Sprintf("%s/%s", img.LogName(), stg.Name()), img.LogTagStyle(), func() error {
+		if dockerfileStage, ok := stg.(*stage.DockerfileStage); ok {
+			var err error
+			if dockerfileCacheImages, err = phase.prepareDockerfileLayersCache(img, dockerfileStage); err != nil {
+				return err
+			}
+		}
+
 		if err := stageImage.Build(phase.ImageBuildOptions); err != nil {
 			return err
 		}
@@ -367,17 +497,34 @@ func (phase *BuildPhase) atomicBuildStageImage(img *Image, stg stage.Interface)
 		time.Sleep(time.Duration(seconds) * time.Second)
 	}
 
-	if lock, err := phase.Conveyor.StorageLockManager.LockStage(phase.Conveyor.projectName(), stg.GetSignature()); err != nil {
-		return fmt.Errorf("unable to lock project %s signature %s: %s", phase.Conveyor.projectName(), stg.GetSignature(), err)
+	isStored, err := phase.lockAndStoreStageImage(img, stg)
+	if err != nil {
+		return err
+	}
+
+	// the layers cache is optional and stored after the stage lock is released to not block other builders of the stage
+	if isStored && img.isDockerfileImage {
+		phase.storeDockerfileCache(append(dockerfileCacheImages, dockerfileCacheImage{cacheName: dockerfileCacheName(img, ""), imageName: stg.GetImage().Name()}))
+	}
+
+	return nil
+}
+
+// lockAndStoreStageImage stores the newly built stage image into the stages storage unless the suitable stage has been already stored by another builder
+func (phase *BuildPhase) lockAndStoreStageImage(img *Image, stg stage.Interface) (bool, error) {
+	stageImage := stg.GetImage()
+
+	if lock, err := phase.Conveyor.StorageLockManager.LockStage(phase.Conveyor.stagesProjectName(), stg.GetSignature()); err != nil {
+		return false, fmt.Errorf("unable to lock project %s signature %s: %s", phase.Conveyor.stagesProjectName(), stg.GetSignature(), err)
 	} else {
 		defer phase.Conveyor.StorageLockManager.Unlock(lock)
 	}
 
 	if stages, err := phase.Conveyor.StagesManager.GetStagesBySignature(stg.LogDetailedName(), stg.GetSignature()); err != nil {
-		return err
+		return false, err
 	} else {
 		if stageDesc, err := phase.Conveyor.StagesManager.SelectSuitableStage(phase.Conveyor, stg, stages); err != nil {
-			return err
+			return false, err
 		} else if stageDesc != nil {
 			logboek.Default.LogF(
 				"Discarding newly built image for stage %s by signature %s: detected already existing image %s in the stages storage\n",
@@ -386,7 +533,7 @@ func (phase *BuildPhase) atomicBuildStageImage(img *Image, stg stage.Interface)
 			i := phase.Conveyor.GetOrCreateStageImage(phase.StagesIterator.GetPrevImage(img, stg).(*container_runtime.StageImage), stageDesc.Info.Name)
 			i.SetStageDescription(stageDesc)
 			stg.SetImage(i)
-			return nil
+			return false, nil
 		} else {
 			newStageImageName, uniqueID := phase.Conveyor.StagesManager.GenerateStageUniqueID(stg.GetSignature(), stages)
 			repository, tag := image.ParseRepositoryAndTag(newStageImageName)
@@ -412,7 +559,7 @@ func (phase *BuildPhase) atomicBuildStageImage(img *Image, stg stage.Interface)
 					return nil
 				},
 			); err != nil {
-				return err
+				return false, err
 			}
 
 			var stageIDs []image.StageID
@@ -421,7 +568,11 @@ func (phase *BuildPhase) atomicBuildStageImage(img *Image, stg stage.Interface)
 			}
 			stageIDs = append(stageIDs, *stageImage.GetStageDescription().StageID)
 
-			return phase.Conveyor.StagesManager.AtomicStoreStagesBySignatureToCache(string(stg.Name()), stg.GetSignature(), stageIDs)
+			if err := phase.Conveyor.StagesManager.AtomicStoreStagesBySignatureToCache(string(stg.Name()), stg.GetSignature(), stageIDs); err != nil {
+				return false, err
+			}
+
+			return true, nil
 		}
 	}
 }
@@ -500,7 +651,7 @@ func calculateSignature(stageName, stageDependencies string, prevNonEmptyStage s
 			return "", fmt.Errorf("unable to get prev stage %s dependencies for the stage %s: %s", prevNonEmptyStage.Name(), stageName, err)
 		}
 
-		checksumArgs = append(checksumArgs, prevNonEmptyStage.GetSignature(), prevStageDependencies)
+		checksumArgs = append(checksumArgs, image.LogicalSignature(prevNonEmptyStage.GetSignature()), prevStageDependencies)
 	}
 
 	signature := util.Sha3_224Hash(checksumArgs...)
@@ -4,6 +4,7 @@ package builder
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -28,6 +29,7 @@ type Ansible struct {
 type Extra struct {
 	ContainerWerfPath string
 	TmpPath           string
+	ProjectDir        string
 }
 
 func NewAnsibleBuilder(config *config.Ansible, extra *Extra) *Ansible {
@@ -44,13 +46,13 @@ func (b *Ansible) Install(container Container) error       { return b.stage("Ins
 func (b *Ansible) BeforeSetup(container Container) error   { return b.stage("BeforeSetup", container) }
 func (b *Ansible) Setup(container Container) error         { return b.stage("Setup", container) }
 
-func (b *Ansible) BeforeInstallChecksum() string { return b.stageChecksum("BeforeInstall") }
-func (b *Ansible) InstallChecksum() string       { return b.stageChecksum("Install") }
-func (b *Ansible) BeforeSetupChecksum() string   { return b.stageChecksum("BeforeSetup") }
-func (b *Ansible) SetupChecksum() string         { return b.stageChecksum("Setup") }
+func (b *Ansible) BeforeInstallChecksum() (string, error) { return b.stageChecksum("BeforeInstall") }
+func (b *Ansible) InstallChecksum() (string, error)       { return b.stageChecksum("Install") }
+func (b *Ansible) BeforeSetupChecksum() (string, error)   { return b.stageChecksum("BeforeSetup") }
+func (b *Ansible) SetupChecksum() (string, error)         { return b.stageChecksum("Setup") }
 
 func (b *Ansible) isEmptyStage(userStageName string) bool {
-	return b.stageChecksum(userStageName) == ""
+	return len(b.stageTasks(userStageName)) == 0 && b.stageVersionChecksum(userStageName) == ""
 }
 
 func (b *Ansible) stage(userStageName string, container Container) error {
@@ -91,6 +93,14 @@ func (b *Ansible) stage(userStageName string, container Container) error {
 		fmt.Sprintf("%s:%s:rw", stageHostTmpDir, b.containerTmpDir()),
 	)
 
+	if b.config.Roles != "" {
+		container.AddVolume(fmt.Sprintf("%s:%s:ro", filepath.Join(b.extra.ProjectDir, b.config.Roles), b.containerRolesDir()))
+	}
+
+	if b.config.Library != "" {
+		container.AddVolume(fmt.Sprintf("%s:%s:ro", filepath.Join(b.extra.ProjectDir, b.config.Library), b.containerLibraryDir()))
+	}
+
 	containerName, err := stapel.GetOrCreateContainer()
 	if err != nil {
 		return err
@@ -112,7 +122,7 @@ func (b *Ansible) stage(userStageName string, container Container) error {
 	return nil
 }
 
-func (b *Ansible) stageChecksum(userStageName string) string {
+func (b *Ansible) stageChecksum(userStageName string) (string, error) {
 	var checksumArgs []string
 
 	for _, task := range b.stageTasks(userStageName) {
@@ -132,6 +142,20 @@ func (b *Ansible) stageChecksum(userStageName string) string {
 		logboek.Debug.LogFHighlight("DEBUG: %s stage tasks checksum dependencies %v\n", userStageName, checksumArgs)
 	}
 
+	// roles, library and templates are mounted or copied from the project directory as is, uncommitted changes included
+	projectFilesChecksum, err := b.projectFilesChecksum(userStageName)
+	if err != nil {
+		return "", fmt.Errorf("unable to calculate checksum of %s stage project files: %s", userStageName, err)
+	}
+
+	if projectFilesChecksum != "" {
+		if debugUserStageChecksum() {
+			logboek.Debug.LogFHighlight("DEBUG: %s stage project files checksum %v\n", userStageName, projectFilesChecksum)
+		}
+
+		checksumArgs = append(checksumArgs, projectFilesChecksum)
+	}
+
 	if stageVersionChecksum := b.stageVersionChecksum(userStageName); stageVersionChecksum != "" {
 		if debugUserStageChecksum() {
 			logboek.Debug.LogFHighlight("DEBUG: %s stage version checksum %v\n", userStageName, stageVersionChecksum)
@@ -141,9 +165,9 @@ func (b *Ansible) stageChecksum(userStageName string) string {
 	}
 
 	if len(checksumArgs) != 0 {
-		return util.Sha256Hash(checksumArgs...)
+		return util.Sha256Hash(checksumArgs...), nil
 	} else {
-		return ""
+		return "", nil
 	}
 }
 
@@ -178,6 +202,96 @@ func (b *Ansible) stageVersionChecksum(userStageName string) string {
 	}
 }
 
+func (b *Ansible) projectFilesChecksum(userStageName string) (string, error) {
+	tasks := b.stageTasks(userStageName)
+	if len(tasks) == 0 {
+		return "", nil
+	}
+
+	var projectPaths []string
+	for _, p := range []string{b.config.Roles, b.config.Library} {
+		if p != "" {
+			projectPaths = append(projectPaths, p)
+		}
+	}
+
+	for _, task := range tasks {
+		projectPaths = append(projectPaths, task.TemplateSources...)
+	}
+
+	var checksumArgs []string
+	for _, projectPath := range projectPaths {
+		args, err := b.projectPathChecksumArgs(projectPath)
+		if err != nil {
+			return "", err
+		}
+
+		checksumArgs = append(checksumArgs, args...)
+	}
+
+	if len(checksumArgs) != 0 {
+		return util.Sha256Hash(checksumArgs...), nil
+	}
+
+	return "", nil
+}
+
+// projectPathChecksumArgs returns paths, executable bits and contents of the project file or the directory files,
+// the project path itself is followed if it is a symlink as docker does for the mounted directory.
+// Other permission bits are not taken into account as git does not keep them and they depend on the umask of the host
+func (b *Ansible) projectPathChecksumArgs(projectPath string) ([]string, error) {
+	hostPath, err := filepath.EvalSymlinks(filepath.Join(b.extra.ProjectDir, filepath.FromSlash(projectPath)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("eval symlinks %s failed: %s", projectPath, err)
+	}
+
+	var args []string
+	err = filepath.Walk(hostPath, func(fp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(hostPath, fp)
+		if err != nil {
+			return err
+		}
+
+		args = append(args, path.Join(projectPath, filepath.ToSlash(relPath)))
+
+		if fi.Mode()&os.ModeSymlink != 0 {
+			linkTo, err := os.Readlink(fp)
+			if err != nil {
+				return fmt.Errorf("read link %s failed: %s", fp, err)
+			}
+
+			args = append(args, "symlink", linkTo)
+		} else {
+			data, err := ioutil.ReadFile(fp)
+			if err != nil {
+				return fmt.Errorf("read file %s failed: %s", fp, err)
+			}
+
+			args = append(args, fmt.Sprintf("executable=%t", fi.Mode()&0111 != 0), util.Sha256Hash(string(data)))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("filepath walk %s failed: %s", projectPath, err)
+	}
+
+	return args, nil
+}
+
 func (b *Ansible) stageTasks(userStageName string) []*config.AnsibleTask {
 	value := b.configFieldValue(userStageName)
 	ansibleTasks, ok := value.([]*config.AnsibleTask)
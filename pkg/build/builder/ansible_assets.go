@@ -37,6 +37,11 @@ func (b *Ansible) createStageWorkDirStructure(userStageName string) error {
 	// generate ansible config for solo mode
 	writeFile(filepath.Join(stageWorkDir, "ansible.cfg"), b.assetsAnsibleCfg())
 
+	// template module looks for relative src in the templates dir next to the playbook
+	if err := b.copyStageTemplates(userStageName, filepath.Join(stageWorkDir, "templates")); err != nil {
+		return err
+	}
+
 	// save config dump for pretty errors
 	stageConfig, err := b.stageConfig(userStageName)
 	if err != nil {
@@ -128,6 +133,28 @@ execfile("%s")
 	return nil
 }
 
+func (b *Ansible) copyStageTemplates(userStageName, templatesDir string) error {
+	for _, task := range b.stageTasks(userStageName) {
+		for _, src := range task.TemplateSources {
+			data, err := ioutil.ReadFile(filepath.Join(b.extra.ProjectDir, filepath.FromSlash(src)))
+			if err != nil {
+				return fmt.Errorf("unable to read template %s: %s", src, err)
+			}
+
+			dest := filepath.Join(templatesDir, filepath.FromSlash(src))
+			if err := mkdirP(filepath.Dir(dest)); err != nil {
+				return err
+			}
+
+			if err := ioutil.WriteFile(dest, data, os.FileMode(0664)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (b *Ansible) stagePlaybook(userStageName string) ([]map[string]interface{}, error) {
 	playbook := map[string]interface{}{
 		"hosts":        "all",
@@ -210,6 +237,14 @@ func (b *Ansible) containerTmpDir() string {
 	return path.Join(b.extra.ContainerWerfPath, "ansible-tmpdir")
 }
 
+func (b *Ansible) containerRolesDir() string {
+	return path.Join(b.extra.ContainerWerfPath, "ansible-roles")
+}
+
+func (b *Ansible) containerLibraryDir() string {
+	return path.Join(b.extra.ContainerWerfPath, "ansible-library")
+}
+
 func mkdirP(path string) error {
 	return os.MkdirAll(path, os.FileMode(0775))
 }
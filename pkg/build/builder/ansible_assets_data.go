@@ -15,6 +15,15 @@ func (b *Ansible) assetsAnsibleCfg() string {
 	localTmpDirPath := path.Join(b.containerTmpDir(), "local")
 	remoteTmpDirPath := path.Join(b.containerTmpDir(), "remote")
 
+	// project roles and modules are mounted from the project directory
+	var projectDefaults string
+	if b.config.Roles != "" {
+		projectDefaults += fmt.Sprintf("roles_path = %s\n", b.containerRolesDir())
+	}
+	if b.config.Library != "" {
+		projectDefaults += fmt.Sprintf("library = %s\n", b.containerLibraryDir())
+	}
+
 	format := `[defaults]
 inventory = %[1]s
 transport = local
@@ -31,13 +40,13 @@ local_tmp = %[3]s
 remote_tmp = %[4]s
 ; keep ansiballz for debug
 ;keep_remote_files = 1
-[privilege_escalation]
+%[6]s[privilege_escalation]
 become = yes
 become_method = sudo
 become_exe = %[5]s
 become_flags = -E -H`
 
-	return fmt.Sprintf(format, hostsPath, callbackPluginsPath, localTmpDirPath, remoteTmpDirPath, sudoBinPath)
+	return fmt.Sprintf(format, hostsPath, callbackPluginsPath, localTmpDirPath, remoteTmpDirPath, sudoBinPath, projectDefaults)
 }
 
 func (b *Ansible) assetsHosts() string {
@@ -0,0 +1,111 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flant/werf/pkg/config"
+)
+
+func writeTestProjectFile(t *testing.T, projectDir, relPath, content string) {
+	p := filepath.Join(projectDir, relPath)
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnsibleStageChecksum_ProjectFiles(t *testing.T) {
+	projectDir, err := ioutil.TempDir("", "werf-ansible-builder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(projectDir)
+
+	writeTestProjectFile(t, projectDir, "roles/app/tasks/main.yml", "- shell: echo\n")
+	writeTestProjectFile(t, projectDir, "library/my_module.py", "print()\n")
+	writeTestProjectFile(t, projectDir, "conf/nginx.conf.j2", "server {}\n")
+
+	b := NewAnsibleBuilder(&config.Ansible{
+		Install: []*config.AnsibleTask{{
+			Config:          map[string]interface{}{"template": map[string]interface{}{"src": "conf/nginx.conf.j2", "dest": "/etc/nginx/nginx.conf"}},
+			TemplateSources: []string{"conf/nginx.conf.j2"},
+		}},
+		Roles:   "roles",
+		Library: "library",
+	}, &Extra{ProjectDir: projectDir})
+
+	if b.IsInstallEmpty() || !b.IsSetupEmpty() {
+		t.Fatalf("unexpected empty stages: install %v, setup %v", b.IsInstallEmpty(), b.IsSetupEmpty())
+	}
+
+	if checksum, err := b.SetupChecksum(); err != nil || checksum != "" {
+		t.Errorf("expected empty setup checksum, got %q, %v", checksum, err)
+	}
+
+	checksum := testInstallChecksum(t, b)
+
+	chmod := func(relPath string, mode os.FileMode) func(t *testing.T) {
+		return func(t *testing.T) {
+			if err := os.Chmod(filepath.Join(projectDir, relPath), mode); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	write := func(relPath, content string) func(t *testing.T) {
+		return func(t *testing.T) {
+			writeTestProjectFile(t, projectDir, relPath, content)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		modify          func(t *testing.T)
+		expectedChanged bool
+	}{
+		{name: "changed role", modify: write("roles/app/tasks/main.yml", "- shell: echo changed\n"), expectedChanged: true},
+		{name: "new role file", modify: write("roles/app/defaults/main.yml", "key: value\n"), expectedChanged: true},
+		{name: "changed module", modify: write("library/my_module.py", "print('changed')\n"), expectedChanged: true},
+		{name: "executable module", modify: chmod("library/my_module.py", 0755), expectedChanged: true},
+		{name: "changed template", modify: write("conf/nginx.conf.j2", "server { listen 80; }\n"), expectedChanged: true},
+		{name: "changed template permissions", modify: chmod("conf/nginx.conf.j2", 0600)},
+		{name: "unused project file", modify: write("conf/unused.j2", "unused\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.modify(t)
+
+			newChecksum := testInstallChecksum(t, b)
+			if isChanged := newChecksum != checksum; isChanged != tt.expectedChanged {
+				t.Errorf("expected checksum changed %v, got %v", tt.expectedChanged, isChanged)
+			}
+			checksum = newChecksum
+		})
+	}
+
+	if err := os.RemoveAll(filepath.Join(projectDir, "roles")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("roles", filepath.Join(projectDir, "roles")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.InstallChecksum(); err == nil {
+		t.Errorf("expected error for the roles symlinks loop")
+	}
+}
+
+func testInstallChecksum(t *testing.T, b *Ansible) string {
+	checksum, err := b.InstallChecksum()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return checksum
+}
@@ -36,10 +36,10 @@ func (b *Shell) Install(container Container) error       { return b.stage("Insta
 func (b *Shell) BeforeSetup(container Container) error   { return b.stage("BeforeSetup", container) }
 func (b *Shell) Setup(container Container) error         { return b.stage("Setup", container) }
 
-func (b *Shell) BeforeInstallChecksum() string { return b.stageChecksum("BeforeInstall") }
-func (b *Shell) InstallChecksum() string       { return b.stageChecksum("Install") }
-func (b *Shell) BeforeSetupChecksum() string   { return b.stageChecksum("BeforeSetup") }
-func (b *Shell) SetupChecksum() string         { return b.stageChecksum("Setup") }
+func (b *Shell) BeforeInstallChecksum() (string, error) { return b.stageChecksum("BeforeInstall"), nil }
+func (b *Shell) InstallChecksum() (string, error)       { return b.stageChecksum("Install"), nil }
+func (b *Shell) BeforeSetupChecksum() (string, error)   { return b.stageChecksum("BeforeSetup"), nil }
+func (b *Shell) SetupChecksum() (string, error)         { return b.stageChecksum("Setup"), nil }
 
 func (b *Shell) isEmptyStage(userStageName string) bool {
 	return b.stageChecksum(userStageName) == ""
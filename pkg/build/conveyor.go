@@ -26,12 +26,16 @@ import (
 	"github.com/flant/werf/pkg/build/stage"
 	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/docker_registry"
 	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/images_manager"
 	"github.com/flant/werf/pkg/logging"
 	"github.com/flant/werf/pkg/path_matcher"
+	"github.com/flant/werf/pkg/slug"
 	"github.com/flant/werf/pkg/storage"
 	"github.com/flant/werf/pkg/tag_strategy"
+	"github.com/flant/werf/pkg/tracing"
 	"github.com/flant/werf/pkg/util"
 )
 
@@ -46,6 +50,8 @@ type Conveyor struct {
 	baseImagesRepoIdsCache map[string]string
 	baseImagesRepoErrCache map[string]error
 
+	externalImagesPlatformNamesCache map[string]string
+
 	sshAuthSock string
 
 	gitReposCaches map[string]*stage.GitRepoCache
@@ -72,6 +78,7 @@ type Conveyor struct {
 
 type ConveyorOptions struct {
 	LocalGitRepoVirtualMergeOptions stage.VirtualMergeOptions
+	DevMode                         bool
 }
 
 func NewConveyor(werfConfig *config.WerfConfig, imageNamesToProcess []string, projectDir, baseTmpDir, sshAuthSock string, containerRuntime container_runtime.ContainerRuntime, stagesManager *stages_manager.StagesManager, imagesRepo storage.ImagesRepo, storageLockManager storage.LockManager, opts ConveyorOptions) *Conveyor {
@@ -85,14 +92,15 @@ func NewConveyor(werfConfig *config.WerfConfig, imageNamesToProcess []string, pr
 
 		sshAuthSock: sshAuthSock,
 
-		stageImages:            make(map[string]*container_runtime.StageImage),
-		gitReposCaches:         make(map[string]*stage.GitRepoCache),
-		baseImagesRepoIdsCache: make(map[string]string),
-		baseImagesRepoErrCache: make(map[string]error),
-		imagesInOrder:          []*Image{},
-		remoteGitRepos:         make(map[string]*git_repo.Remote),
-		tmpDir:                 filepath.Join(baseTmpDir, util.GenerateConsistentRandomString(10)),
-		importServers:          make(map[string]import_server.ImportServer),
+		stageImages:                      make(map[string]*container_runtime.StageImage),
+		gitReposCaches:                   make(map[string]*stage.GitRepoCache),
+		baseImagesRepoIdsCache:           make(map[string]string),
+		baseImagesRepoErrCache:           make(map[string]error),
+		externalImagesPlatformNamesCache: make(map[string]string),
+		imagesInOrder:                    []*Image{},
+		remoteGitRepos:                   make(map[string]*git_repo.Remote),
+		tmpDir:                           filepath.Join(baseTmpDir, util.GenerateConsistentRandomString(10)),
+		importServers:                    make(map[string]import_server.ImportServer),
 
 		ContainerRuntime:   containerRuntime,
 		ImagesRepo:         imagesRepo,
@@ -109,38 +117,94 @@ func (c *Conveyor) GetLocalGitRepoVirtualMergeOptions() stage.VirtualMergeOption
 	return c.ConveyorOptions.LocalGitRepoVirtualMergeOptions
 }
 
-func (c *Conveyor) GetImportServer(imageName, stageName string) (import_server.ImportServer, error) {
-	importServerName := imageName
+func (c *Conveyor) GetImportServer(imageName, stageName, platform, method string) (import_server.ImportServer, error) {
+	importServerName := fmt.Sprintf("%s/%s", method, imageName)
 	if stageName != "" {
 		importServerName += "/" + stageName
 	}
+	if platform != "" {
+		importServerName += "/" + platform
+	}
 	if srv, hasKey := c.importServers[importServerName]; hasKey {
 		return srv, nil
 	}
 
-	var srv *import_server.RsyncServer
+	localRuntime, ok := c.ContainerRuntime.(container_runtime.LocalRuntime)
+	if !ok {
+		return nil, fmt.Errorf("import from image %s is not supported by %s container runtime yet", imageName, c.ContainerRuntime.String())
+	}
 
-	if err := logboek.Info.LogProcess(fmt.Sprintf("Firing up import rsync server for image %s", imageName), logboek.LevelLogProcessOptions{}, func() error {
-		var tmpDir string
-		if stageName == "" {
-			tmpDir = filepath.Join(c.tmpDir, "import-server", imageName)
-		} else {
-			tmpDir = filepath.Join(c.tmpDir, "import-server", fmt.Sprintf("%s-%s", imageName, stageName))
+	var tmpDirName, dockerImageName string
+	if stageName == "" {
+		tmpDirName = imageName
+		dockerImageName = c.GetImageVariant(imageName, platform).GetLastNonEmptyStage().GetImage().Name()
+	} else {
+		tmpDirName = fmt.Sprintf("%s-%s", imageName, stageName)
+		dockerImageName = c.getImageVariantStage(imageName, stageName, platform).GetImage().Name()
+	}
+	if platform != "" {
+		tmpDirName = fmt.Sprintf("%s-%s", tmpDirName, slug.Slug(platform))
+	}
+
+	return c.runImportServer(localRuntime, importServerName, method, imageName, tmpDirName, dockerImageName)
+}
+
+// GetExternalImageImportServer pulls the external image if the local one does not match the image in the registry
+func (c *Conveyor) GetExternalImageImportServer(externalImageName, platform, method string) (import_server.ImportServer, error) {
+	importServerName := fmt.Sprintf("%s/external/%s", method, externalImageName)
+	if platform != "" {
+		importServerName += "/" + platform
+	}
+	if srv, hasKey := c.importServers[importServerName]; hasKey {
+		return srv, nil
+	}
+
+	localRuntime, ok := c.ContainerRuntime.(container_runtime.LocalRuntime)
+	if !ok {
+		return nil, fmt.Errorf("import from external image %s is not supported by %s container runtime yet", externalImageName, c.ContainerRuntime.String())
+	}
+
+	if platform != "" {
+		var err error
+		if externalImageName, err = c.GetExternalImagePlatformName(externalImageName, platform); err != nil {
+			return nil, err
 		}
+	}
 
-		if err := os.MkdirAll(tmpDir, os.ModePerm); err != nil {
-			return fmt.Errorf("unable to create dir %s: %s", tmpDir, err)
+	repoImageID, err := c.GetExternalImageRepoID(externalImageName)
+	if err != nil {
+		return nil, err
+	}
+
+	if inspect, err := localRuntime.GetImageInspect(externalImageName); err != nil {
+		return nil, fmt.Errorf("unable to inspect local image %s: %s", externalImageName, err)
+	} else if inspect == nil || inspect.ID != repoImageID {
+		logProcessOptions := logboek.LevelLogProcessOptions{Style: logboek.HighlightStyle()}
+		if err := logboek.Default.LogProcess(fmt.Sprintf("Pulling import image %s", externalImageName), logProcessOptions, func() error {
+			return localRuntime.PullImageFromRegistry(&container_runtime.DockerImage{Image: c.GetOrCreateStageImage(nil, externalImageName)})
+		}); err != nil {
+			return nil, err
 		}
+	}
 
-		var dockerImageName string
-		if stageName == "" {
-			dockerImageName = c.GetImageNameForLastImageStage(imageName)
-		} else {
-			dockerImageName = c.GetImageNameForImageStage(imageName, stageName)
+	return c.runImportServer(localRuntime, importServerName, method, externalImageName, fmt.Sprintf("external-%s", slug.Slug(externalImageName)), externalImageName)
+}
+
+func (c *Conveyor) runImportServer(localRuntime container_runtime.LocalRuntime, importServerName, method, imageName, tmpDirName, dockerImageName string) (import_server.ImportServer, error) {
+	if method == config.ImportMethodTar {
+		return c.runTarImporter(localRuntime, importServerName, imageName, tmpDirName, dockerImageName)
+	}
+
+	var srv *import_server.RsyncServer
+
+	if err := logboek.Info.LogProcess(fmt.Sprintf("Firing up import rsync server for image %s", imageName), logboek.LevelLogProcessOptions{}, func() error {
+		tmpDir := filepath.Join(c.tmpDir, "import-server", tmpDirName)
+		if err := os.MkdirAll(tmpDir, os.ModePerm); err != nil {
+			return fmt.Errorf("unable to create dir %s: %s", tmpDir, err)
 		}
 
 		var err error
-		srv, err = import_server.RunRsyncServer(dockerImageName, tmpDir)
+		srv, err = import_server.RunRsyncServer(localRuntime, dockerImageName, tmpDir)
 		if srv != nil {
 			c.AppendOnTerminateFunc(func() error {
 				if err := srv.Shutdown(); err != nil {
@@ -162,6 +226,61 @@ func (c *Conveyor) GetImportServer(imageName, stageName string) (import_server.I
 	return srv, nil
 }
 
+func (c *Conveyor) runTarImporter(localRuntime container_runtime.LocalRuntime, importServerName, imageName, tmpDirName, dockerImageName string) (import_server.ImportServer, error) {
+	var imp *import_server.TarImporter
+
+	if err := logboek.Info.LogProcess(fmt.Sprintf("Preparing tar importer for image %s", imageName), logboek.LevelLogProcessOptions{}, func() error {
+		var err error
+		imp, err = import_server.NewTarImporter(localRuntime, dockerImageName, filepath.Join(c.tmpDir, "import-tar", tmpDirName))
+		if err != nil {
+			return fmt.Errorf("unable to create tar importer: %s", err)
+		}
+
+		c.AppendOnTerminateFunc(func() error {
+			if err := imp.Shutdown(); err != nil {
+				return fmt.Errorf("unable to shutdown tar importer %s: %s", imp.DockerContainerName, err)
+			}
+			return nil
+		})
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	c.importServers[importServerName] = imp
+
+	return imp, nil
+}
+
+// GetExternalImageRepoID returns id of the image in the registry, ids are cached for the conveyor run
+func (c *Conveyor) GetExternalImageRepoID(externalImageName string) (string, error) {
+	if cachedRepoID, exist := c.baseImagesRepoIdsCache[externalImageName]; exist {
+		return cachedRepoID, nil
+	} else if cachedRepoErr, exist := c.baseImagesRepoErrCache[externalImageName]; exist {
+		return "", cachedRepoErr
+	}
+
+	var fetchedRepoImage *image.Info
+	processMsg := fmt.Sprintf("Trying to get image id from registry (%s)", externalImageName)
+	if err := logboek.Info.LogProcessInline(processMsg, logboek.LevelLogProcessInlineOptions{}, func() error {
+		var fetchImageIdErr error
+		fetchedRepoImage, fetchImageIdErr = docker_registry.API().GetRepoImage(externalImageName)
+		if fetchImageIdErr != nil {
+			c.baseImagesRepoErrCache[externalImageName] = fetchImageIdErr
+			return fmt.Errorf("can not get image id from registry (%s): %s", externalImageName, fetchImageIdErr)
+		}
+
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	c.baseImagesRepoIdsCache[externalImageName] = fetchedRepoImage.ID
+
+	return fetchedRepoImage.ID, nil
+}
+
 func (c *Conveyor) AppendOnTerminateFunc(f func() error) {
 	c.onTerminateFuncs = append(c.onTerminateFuncs, f)
 }
@@ -224,6 +343,33 @@ type TagOptions struct {
 	TagByStagesSignature bool
 }
 
+// GetExternalImagePlatformName returns the name of the external image pinned by the digest of the specified platform variant
+func (c *Conveyor) GetExternalImagePlatformName(externalImageName, platform string) (string, error) {
+	cacheKey := fmt.Sprintf("%s/%s", externalImageName, platform)
+	if name, exist := c.externalImagesPlatformNamesCache[cacheKey]; exist {
+		return name, nil
+	}
+
+	var repoImage *image.Info
+	processMsg := fmt.Sprintf("Trying to get image digest from registry (%s, %s)", externalImageName, platform)
+	if err := logboek.Info.LogProcessInline(processMsg, logboek.LevelLogProcessInlineOptions{}, func() error {
+		var err error
+		repoImage, err = docker_registry.API().GetRepoImageByPlatform(externalImageName, platform)
+		if err != nil {
+			return fmt.Errorf("can not get image digest from registry (%s, %s): %s", externalImageName, platform, err)
+		}
+
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s@%s", repoImage.Repository, repoImage.RepoDigest)
+	c.externalImagesPlatformNamesCache[cacheKey] = name
+
+	return name, nil
+}
+
 type ShouldBeBuiltOptions struct {
 	FetchLastStage bool
 }
@@ -243,9 +389,10 @@ func (c *Conveyor) ShouldBeBuilt(opts ShouldBeBuiltOptions) error {
 
 	if opts.FetchLastStage {
 		for _, imageName := range c.imageNamesToProcess {
-			lastImageStage := c.GetImage(imageName).GetLastNonEmptyStage()
-			if err := c.StagesManager.FetchStage(lastImageStage); err != nil {
-				return err
+			for _, img := range c.GetImageVariants(imageName) {
+				if err := c.StagesManager.FetchStage(img.GetLastNonEmptyStage()); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -300,6 +447,7 @@ func (c *Conveyor) BuildStages(opts BuildStagesOptions) error {
 			ImageBuildOptions: opts.ImageBuildOptions,
 		}),
 	}
+	phases = appendVulnerabilityScanPhase(c, phases, opts)
 
 	return c.runPhases(phases, true)
 }
@@ -337,10 +485,9 @@ func (c *Conveyor) BuildAndPublish(opts BuildAndPublishOptions) error {
 		return err
 	}
 
-	phases := []Phase{
-		NewBuildPhase(c, BuildPhaseOptions{ImageBuildOptions: opts.ImageBuildOptions, IntrospectOptions: opts.IntrospectOptions}),
-		NewPublishImagesPhase(c, c.ImagesRepo, opts.PublishImagesOptions),
-	}
+	phases := []Phase{NewBuildPhase(c, BuildPhaseOptions{ImageBuildOptions: opts.ImageBuildOptions, IntrospectOptions: opts.IntrospectOptions})}
+	phases = appendVulnerabilityScanPhase(c, phases, opts.BuildStagesOptions)
+	phases = append(phases, NewPublishImagesPhase(c, c.ImagesRepo, opts.PublishImagesOptions))
 
 	if opts.DryRun {
 		fmt.Printf("BuildAndPublish DryRun\n")
@@ -350,6 +497,31 @@ func (c *Conveyor) BuildAndPublish(opts BuildAndPublishOptions) error {
 	return c.runPhases(phases, true)
 }
 
+type BuildAndExportOptions struct {
+	BuildStagesOptions
+	ExportImagesOptions
+}
+
+func (c *Conveyor) BuildAndExport(opts BuildAndExportOptions) error {
+	if err := c.determineStages(); err != nil {
+		return err
+	}
+
+	phases := []Phase{NewBuildPhase(c, BuildPhaseOptions{ImageBuildOptions: opts.ImageBuildOptions, IntrospectOptions: opts.IntrospectOptions})}
+	phases = appendVulnerabilityScanPhase(c, phases, opts.BuildStagesOptions)
+	phases = append(phases, NewExportImagesPhase(c, opts.ExportImagesOptions))
+
+	return c.runPhases(phases, true)
+}
+
+// appendVulnerabilityScanPhase adds the scan right after the build phase, so images are checked before publish or export
+func appendVulnerabilityScanPhase(c *Conveyor, phases []Phase, opts BuildStagesOptions) []Phase {
+	if opts.VulnerabilityScanOptions.DatabasePath == "" {
+		return phases
+	}
+	return append(phases, NewVulnerabilityScanPhase(c, opts.VulnerabilityScanOptions))
+}
+
 func (c *Conveyor) determineStages() error {
 	return logboek.Info.LogProcess(
 		"Determining of stages",
@@ -376,27 +548,35 @@ func (c *Conveyor) doDetermineStages() error {
 			style = ImageLogProcessStyle(false)
 		}
 
-		err := logboek.Info.LogProcess(imageLogName, logboek.LevelLogProcessOptions{Style: style}, func() error {
-			var err error
-
-			switch imageConfig := imageInterfaceConfig.(type) {
-			case config.StapelImageInterface:
-				img, err = prepareImageBasedOnStapelImageConfig(imageConfig, c)
-			case *config.ImageFromDockerfile:
-				img, err = prepareImageBasedOnImageFromDockerfile(imageConfig, c)
+		platforms := getImagePlatforms(imageInterfaceConfig)
+		for _, platform := range platforms {
+			processName := imageLogName
+			if platform != "" {
+				processName = fmt.Sprintf("%s (%s)", imageLogName, platform)
 			}
 
-			if err != nil {
-				return err
-			}
+			err := logboek.Info.LogProcess(processName, logboek.LevelLogProcessOptions{Style: style}, func() error {
+				var err error
 
-			c.imagesInOrder = append(c.imagesInOrder, img)
+				switch imageConfig := imageInterfaceConfig.(type) {
+				case config.StapelImageInterface:
+					img, err = prepareImageBasedOnStapelImageConfig(imageConfig, platform, c)
+				case *config.ImageFromDockerfile:
+					img, err = prepareImageBasedOnImageFromDockerfile(imageConfig, platform, c)
+				}
 
-			return nil
-		})
+				if err != nil {
+					return err
+				}
 
-		if err != nil {
-			return err
+				c.imagesInOrder = append(c.imagesInOrder, img)
+
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -445,52 +625,65 @@ func (c *Conveyor) runPhases(phases []Phase, logImages bool) error {
 	}
 
 	for _, phase := range phases {
-		logProcessMsg := fmt.Sprintf("Phase %s -- BeforeImages()", phase.Name())
-		logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
-		if err := phase.BeforeImages(); err != nil {
-			logboek.Debug.LogProcessFail(logboek.LevelLogProcessFailOptions{})
-			return fmt.Errorf("phase %s before images handler failed: %s", phase.Name(), err)
+		if err := withPhase(phase, nil, func() error {
+			logProcessMsg := fmt.Sprintf("Phase %s -- BeforeImages()", phase.Name())
+			logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
+			if err := phase.BeforeImages(); err != nil {
+				logboek.Debug.LogProcessFail(logboek.LevelLogProcessFailOptions{})
+				return fmt.Errorf("phase %s before images handler failed: %s", phase.Name(), err)
+			}
+			logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
+			return nil
+		}); err != nil {
+			return err
 		}
-		logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
 	}
 
 	for _, img := range c.imagesInOrder {
 		if err := imagesLogger.LogProcess(img.LogDetailedName(), logboek.LevelLogProcessOptions{Style: img.LogProcessStyle()}, func() error {
 			for _, phase := range phases {
-				logProcessMsg := fmt.Sprintf("Phase %s -- BeforeImageStages()", phase.Name())
-				logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
-				if err := phase.BeforeImageStages(img); err != nil {
-					logboek.Debug.LogProcessFail(logboek.LevelLogProcessFailOptions{})
-					return fmt.Errorf("phase %s before image %s stages handler failed: %s", phase.Name(), img.GetLogName(), err)
-				}
-				logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
+				var imageProcessingShouldBeStopped bool
+				if err := withPhase(phase, img, func() error {
+					logProcessMsg := fmt.Sprintf("Phase %s -- BeforeImageStages()", phase.Name())
+					logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
+					if err := phase.BeforeImageStages(img); err != nil {
+						logboek.Debug.LogProcessFail(logboek.LevelLogProcessFailOptions{})
+						return fmt.Errorf("phase %s before image %s stages handler failed: %s", phase.Name(), img.GetLogName(), err)
+					}
+					logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
+
+					logProcessMsg = fmt.Sprintf("Phase %s -- OnImageStage()", phase.Name())
+					logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
+					for _, stg := range img.GetStages() {
+						logboek.Debug.LogF("Phase %s -- OnImageStage() %s %s\n", phase.Name(), img.GetLogName(), stg.LogDetailedName())
+						if err := phase.OnImageStage(img, stg); err != nil {
+							logboek.Debug.LogProcessFail(logboek.LevelLogProcessFailOptions{})
+							return fmt.Errorf("phase %s on image %s stage %s handler failed: %s", phase.Name(), img.GetLogName(), stg.Name(), err)
+						}
+					}
+					logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
 
-				logProcessMsg = fmt.Sprintf("Phase %s -- OnImageStage()", phase.Name())
-				logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
-				for _, stg := range img.GetStages() {
-					logboek.Debug.LogF("Phase %s -- OnImageStage() %s %s\n", phase.Name(), img.GetLogName(), stg.LogDetailedName())
-					if err := phase.OnImageStage(img, stg); err != nil {
+					logProcessMsg = fmt.Sprintf("Phase %s -- AfterImageStages()", phase.Name())
+					logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
+					if err := phase.AfterImageStages(img); err != nil {
 						logboek.Debug.LogProcessFail(logboek.LevelLogProcessFailOptions{})
-						return fmt.Errorf("phase %s on image %s stage %s handler failed: %s", phase.Name(), img.GetLogName(), stg.Name(), err)
+						return fmt.Errorf("phase %s after image %s stages handler failed: %s", phase.Name(), img.GetLogName(), err)
 					}
-				}
-				logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
+					logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
+
+					logProcessMsg = fmt.Sprintf("Phase %s -- ImageProcessingShouldBeStopped()", phase.Name())
+					logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
+					imageProcessingShouldBeStopped = phase.ImageProcessingShouldBeStopped(img)
+					logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
 
-				logProcessMsg = fmt.Sprintf("Phase %s -- AfterImageStages()", phase.Name())
-				logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
-				if err := phase.AfterImageStages(img); err != nil {
-					logboek.Debug.LogProcessFail(logboek.LevelLogProcessFailOptions{})
-					return fmt.Errorf("phase %s after image %s stages handler failed: %s", phase.Name(), img.GetLogName(), err)
+					return nil
+				}); err != nil {
+					return err
 				}
-				logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
 
-				logProcessMsg = fmt.Sprintf("Phase %s -- ImageProcessingShouldBeStopped()", phase.Name())
-				logboek.Debug.LogProcessStart(logProcessMsg, logboek.LevelLogProcessStartOptions{})
-				if phase.ImageProcessingShouldBeStopped(img) {
-					logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
+				if imageProcessingShouldBeStopped {
 					return nil
 				}
-				logboek.Debug.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
 			}
 
 			return nil
@@ -500,12 +693,14 @@ func (c *Conveyor) runPhases(phases []Phase, logImages bool) error {
 	}
 
 	for _, phase := range phases {
-		if err := logboek.Debug.LogProcess(fmt.Sprintf("Phase %s -- AfterImages()", phase.Name()), logboek.LevelLogProcessOptions{}, func() error {
-			if err := phase.AfterImages(); err != nil {
-				return fmt.Errorf("phase %s after images handler failed: %s", phase.Name(), err)
-			}
+		if err := withPhase(phase, nil, func() error {
+			return logboek.Debug.LogProcess(fmt.Sprintf("Phase %s -- AfterImages()", phase.Name()), logboek.LevelLogProcessOptions{}, func() error {
+				if err := phase.AfterImages(); err != nil {
+					return fmt.Errorf("phase %s after images handler failed: %s", phase.Name(), err)
+				}
 
-			return nil
+				return nil
+			})
 		}); err != nil {
 			return err
 		}
@@ -514,10 +709,25 @@ func (c *Conveyor) runPhases(phases []Phase, logImages bool) error {
 	return nil
 }
 
+// withPhase traces the phase handlers call for the image (or for all images), the span marks the json log events with the phase name
+func withPhase(phase Phase, img *Image, f func() error) error {
+	attributes := map[string]string{"phase": phase.Name()}
+	if img != nil {
+		attributes["image"] = img.GetName()
+	}
+
+	return tracing.Trace(fmt.Sprintf("phase %s", phase.Name()), attributes, f)
+}
+
 func (c *Conveyor) projectName() string {
 	return c.werfConfig.Meta.Project
 }
 
+// stagesProjectName is the project name under which the stages are stored, it differs from the project name in the development mode
+func (c *Conveyor) stagesProjectName() string {
+	return c.StagesManager.ProjectName
+}
+
 func (c *Conveyor) GetStageImage(name string) *container_runtime.StageImage {
 	return c.stageImages[name]
 }
@@ -535,19 +745,60 @@ func (c *Conveyor) GetOrCreateStageImage(fromImage *container_runtime.StageImage
 		return img
 	}
 
-	img := container_runtime.NewStageImage(fromImage, name, c.ContainerRuntime.(*container_runtime.LocalDockerServerRuntime))
+	img := container_runtime.NewStageImage(fromImage, name, c.ContainerRuntime.(container_runtime.LocalRuntime))
 	c.stageImages[name] = img
 	return img
 }
 
+func getImagePlatforms(imageInterfaceConfig config.ImageInterface) []string {
+	var platforms []string
+	switch imageConfig := imageInterfaceConfig.(type) {
+	case config.StapelImageInterface:
+		platforms = imageConfig.ImageBaseConfig().Platform
+	case *config.ImageFromDockerfile:
+		platforms = imageConfig.Platform
+	}
+
+	if len(platforms) == 0 {
+		return []string{""}
+	}
+
+	return platforms
+}
+
+// GetImage returns the image built for the host platform (or the first platform variant of the image)
 func (c *Conveyor) GetImage(name string) *Image {
+	return c.GetImageVariant(name, "")
+}
+
+// GetImageVariant returns the platform variant of the image,
+// the image without platforms is used for any platform
+func (c *Conveyor) GetImageVariant(name, platform string) *Image {
+	variants := c.GetImageVariants(name)
+	if len(variants) == 0 {
+		panic(fmt.Sprintf("Image '%s' not found!", name))
+	}
+
+	for _, p := range []string{platform, "", image.HostPlatform()} {
+		for _, img := range variants {
+			if img.platform == p {
+				return img
+			}
+		}
+	}
+
+	return variants[0]
+}
+
+func (c *Conveyor) GetImageVariants(name string) []*Image {
+	var variants []*Image
 	for _, img := range c.imagesInOrder {
 		if img.GetName() == name {
-			return img
+			variants = append(variants, img)
 		}
 	}
 
-	panic(fmt.Sprintf("Image '%s' not found!", name))
+	return variants
 }
 
 func (c *Conveyor) GetImageStageContentSignature(imageName, stageName string) string {
@@ -559,11 +810,16 @@ func (c *Conveyor) GetImageContentSignature(imageName string) string {
 }
 
 func (c *Conveyor) getImageStage(imageName, stageName string) stage.Interface {
-	if stg := c.GetImage(imageName).GetStage(stage.StageName(stageName)); stg != nil {
+	return c.getImageVariantStage(imageName, stageName, "")
+}
+
+func (c *Conveyor) getImageVariantStage(imageName, stageName, platform string) stage.Interface {
+	img := c.GetImageVariant(imageName, platform)
+	if stg := img.GetStage(stage.StageName(stageName)); stg != nil {
 		return stg
 	} else {
 		// FIXME: find first existing stage after specified unexisting
-		return c.GetImage(imageName).GetLastNonEmptyStage()
+		return img.GetLastNonEmptyStage()
 	}
 }
 
@@ -575,19 +831,19 @@ func (c *Conveyor) GetImageNameForImageStage(imageName, stageName string) string
 	return c.getImageStage(imageName, stageName).GetImage().Name()
 }
 
-func (c *Conveyor) GetImageIDForLastImageStage(imageName string) string {
-	return c.GetImage(imageName).GetLastNonEmptyStage().GetImage().GetStageDescription().Info.ID
+func (c *Conveyor) GetImageIDForLastImageStage(imageName, platform string) string {
+	return c.GetImageVariant(imageName, platform).GetLastNonEmptyStage().GetImage().GetStageDescription().Info.ID
 }
 
-func (c *Conveyor) GetImageIDForImageStage(imageName, stageName string) string {
-	return c.getImageStage(imageName, stageName).GetImage().GetStageDescription().Info.ID
+func (c *Conveyor) GetImageIDForImageStage(imageName, stageName, platform string) string {
+	return c.getImageVariantStage(imageName, stageName, platform).GetImage().GetStageDescription().Info.ID
 }
 
 func (c *Conveyor) GetImageTmpDir(imageName string) string {
 	return filepath.Join(c.tmpDir, "image", imageName)
 }
 
-func prepareImageBasedOnStapelImageConfig(imageInterfaceConfig config.StapelImageInterface, c *Conveyor) (*Image, error) {
+func prepareImageBasedOnStapelImageConfig(imageInterfaceConfig config.StapelImageInterface, platform string, c *Conveyor) (*Image, error) {
 	image := &Image{}
 
 	imageBaseConfig := imageInterfaceConfig.ImageBaseConfig()
@@ -597,11 +853,20 @@ func prepareImageBasedOnStapelImageConfig(imageInterfaceConfig config.StapelImag
 	from, fromImageName, fromLatest := getFromFields(imageBaseConfig)
 
 	image.name = imageName
+	image.platform = platform
 
 	if from != "" {
 		if err := handleImageFromName(from, fromLatest, image, c); err != nil {
 			return nil, err
 		}
+
+		if platform != "" {
+			platformName, err := c.GetExternalImagePlatformName(from, platform)
+			if err != nil {
+				return nil, err
+			}
+			image.baseImageName = platformName
+		}
 	} else {
 		image.baseImageImageName = fromImageName
 	}
@@ -706,12 +971,20 @@ func initStages(image *Image, imageInterfaceConfig config.StapelImageInterface,
 	imageName := imageBaseConfig.Name
 	imageArtifact := imageInterfaceConfig.IsArtifact()
 
+	dependenciesGitMapping, err := generateDependenciesGitMapping(imageBaseConfig, c)
+	if err != nil {
+		return err
+	}
+
 	baseStageOptions := &stage.NewBaseStageOptions{
-		ImageName:        imageName,
-		ConfigMounts:     imageBaseConfig.Mount,
-		ImageTmpDir:      c.GetImageTmpDir(imageBaseConfig.Name),
-		ContainerWerfDir: c.containerWerfDir,
-		ProjectName:      c.werfConfig.Meta.Project,
+		ImageName:              imageName,
+		ConfigMounts:           imageBaseConfig.Mount,
+		ImageTmpDir:            c.GetImageTmpDir(imageBaseConfig.Name),
+		ContainerWerfDir:       c.containerWerfDir,
+		ProjectName:            c.werfConfig.Meta.Project,
+		ProjectDir:             c.projectDir,
+		Platform:               image.platform,
+		DependenciesGitMapping: dependenciesGitMapping,
 	}
 
 	gitArchiveStageOptions := &stage.NewGitArchiveStageOptions{
@@ -778,18 +1051,8 @@ func generateGitMappings(imageBaseConfig *config.StapelImageBase, c *Conveyor) (
 	var gitMappings []*stage.GitMapping
 
 	if len(imageBaseConfig.Git.Local) != 0 {
-		localGitRepo := c.GetLocalGitRepo()
-		if localGitRepo == nil {
-			localGitRepo, err := git_repo.OpenLocalRepo("own", c.projectDir)
-			if err != nil {
-				return nil, fmt.Errorf("unable to open local repo %s: %s", c.projectDir, err)
-			}
-
-			if localGitRepo == nil {
-				return nil, errors.New("local git mapping is used but project git repository is not found")
-			}
-
-			c.SetLocalGitRepo(localGitRepo)
+		if err := initLocalGitRepo(c, "local git mapping is used but project git repository is not found"); err != nil {
+			return nil, err
 		}
 	}
 
@@ -840,6 +1103,48 @@ func generateGitMappings(imageBaseConfig *config.StapelImageBase, c *Conveyor) (
 	return res, nil
 }
 
+func initLocalGitRepo(c *Conveyor, notFoundErrMsg string) error {
+	if c.GetLocalGitRepo() != nil {
+		return nil
+	}
+
+	localGitRepo, err := git_repo.OpenLocalRepo("own", c.projectDir)
+	if err != nil {
+		return fmt.Errorf("unable to open local repo %s: %s", c.projectDir, err)
+	}
+
+	if localGitRepo == nil {
+		return errors.New(notFoundErrMsg)
+	}
+
+	c.SetLocalGitRepo(localGitRepo)
+
+	return nil
+}
+
+// generateDependenciesGitMapping returns git mapping of the project git repository root which is not added to the image,
+// the mapping is only used to calculate checksums of the project dependencies of user stages
+func generateDependenciesGitMapping(imageBaseConfig *config.StapelImageBase, c *Conveyor) (*stage.GitMapping, error) {
+	if imageBaseConfig.Dependencies == nil {
+		return nil, nil
+	}
+
+	if err := initLocalGitRepo(c, "project dependencies are used but project git repository is not found"); err != nil {
+		return nil, err
+	}
+
+	localGitRepo := c.GetLocalGitRepo()
+
+	return &stage.GitMapping{
+		GitRepoInterface:               localGitRepo,
+		GitRepoCache:                   c.GetGitRepoCache(localGitRepo.GetName()),
+		Name:                           "own",
+		StagesDependencies:             stageDependenciesToMap(imageBaseConfig.GitMappingDependencies()),
+		DevMode:                        c.DevMode,
+		BaseCommitByPrevBuiltImageName: make(map[string]string),
+	}, nil
+}
+
 func filterAndLogGitMappings(c *Conveyor, gitMappings []*stage.GitMapping) ([]*stage.GitMapping, error) {
 	var res []*stage.GitMapping
 
@@ -889,6 +1194,10 @@ func filterAndLogGitMappings(c *Conveyor, gitMappings []*stage.GitMapping) ([]*s
 					logboek.Info.LogFDetails("group: %s\n", gitMapping.Group)
 				}
 
+				if gitMapping.LFS {
+					logboek.Info.LogLnDetails("lfs: true")
+				}
+
 				if len(gitMapping.StagesDependencies) != 0 {
 					logboek.Info.LogLnDetails("stageDependencies:")
 
@@ -947,6 +1256,7 @@ func gitLocalPathInit(localGitMappingConfig *config.GitLocal, localGitRepo *git_
 	gitMapping.Name = "own"
 
 	gitMapping.GitRepoInterface = localGitRepo
+	gitMapping.DevMode = c.DevMode
 
 	gitMapping.GitRepoCache = c.GetGitRepoCache(localGitRepo.GetName())
 
@@ -974,6 +1284,7 @@ func baseGitMappingInit(local *config.GitLocalExport, imageName string, c *Conve
 		Owner:              local.Owner,
 		Group:              local.Group,
 		StagesDependencies: stageDependencies,
+		LFS:                local.LFS,
 
 		BaseCommitByPrevBuiltImageName: make(map[string]string),
 	}
@@ -1007,9 +1318,10 @@ func getImageScriptsContainerDir(c *Conveyor) string {
 
 func stageDependenciesToMap(sd *config.StageDependencies) map[stage.StageName][]string {
 	result := map[stage.StageName][]string{
-		stage.Install:     sd.Install,
-		stage.BeforeSetup: sd.BeforeSetup,
-		stage.Setup:       sd.Setup,
+		stage.BeforeInstall: sd.BeforeInstall,
+		stage.Install:       sd.Install,
+		stage.BeforeSetup:   sd.BeforeSetup,
+		stage.Setup:         sd.Setup,
 	}
 
 	return result
@@ -1024,9 +1336,10 @@ func appendIfExist(stages []stage.Interface, stage stage.Interface) []stage.Inte
 	return stages
 }
 
-func prepareImageBasedOnImageFromDockerfile(imageFromDockerfileConfig *config.ImageFromDockerfile, c *Conveyor) (*Image, error) {
+func prepareImageBasedOnImageFromDockerfile(imageFromDockerfileConfig *config.ImageFromDockerfile, platform string, c *Conveyor) (*Image, error) {
 	img := &Image{}
 	img.name = imageFromDockerfileConfig.Name
+	img.platform = platform
 	img.isDockerfileImage = true
 
 	contextDir := filepath.Join(c.projectDir, imageFromDockerfileConfig.Context)
@@ -1151,6 +1464,7 @@ func prepareImageBasedOnImageFromDockerfile(imageFromDockerfileConfig *config.Im
 	baseStageOptions := &stage.NewBaseStageOptions{
 		ImageName:   imageFromDockerfileConfig.Name,
 		ProjectName: c.werfConfig.Meta.Project,
+		Platform:    platform,
 	}
 
 	dockerfileStage := stage.GenerateDockerfileStage(
@@ -1163,6 +1477,7 @@ func prepareImageBasedOnImageFromDockerfile(imageFromDockerfileConfig *config.Im
 		),
 		stage.NewDockerStages(dockerStages, dockerArgsHash, dockerTargetIndex),
 		stage.NewContextChecksum(c.projectDir, dockerignorePathMatcher, localGitRepo),
+		imageFromDockerfileConfig.ConfigImportsChecksum,
 		baseStageOptions,
 	)
 
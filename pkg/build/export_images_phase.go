@@ -0,0 +1,339 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/util"
+)
+
+type ExportFormat string
+
+const (
+	ExportDockerArchive ExportFormat = "docker-archive"
+	ExportOCI           ExportFormat = "oci"
+
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+)
+
+// ParseExportDestination parses destination in the format FORMAT:PATH (oci:./out, docker-archive:app.tar)
+func ParseExportDestination(destination string) (ExportFormat, string, error) {
+	parts := strings.SplitN(destination, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid destination %q: expected FORMAT:PATH", destination)
+	}
+
+	switch format := ExportFormat(parts[0]); format {
+	case ExportDockerArchive, ExportOCI:
+		return format, parts[1], nil
+	default:
+		return "", "", fmt.Errorf("invalid destination %q: unsupported format %q, %s or %s expected", destination, parts[0], ExportOCI, ExportDockerArchive)
+	}
+}
+
+type ExportImagesOptions struct {
+	ImagesToExport []string
+	Destination    string
+	Repo           string
+	Tag            string
+	IndexPath      string
+}
+
+type ExportIndex struct {
+	Destination string
+	Images      []ExportIndexImageRecord
+}
+
+type ExportIndexImageRecord struct {
+	WerfImageName string
+	Platform      string `json:",omitempty"`
+	Name          string
+	DockerImageID string
+	Digest        string `json:",omitempty"`
+}
+
+func NewExportImagesPhase(c *Conveyor, opts ExportImagesOptions) *ExportImagesPhase {
+	return &ExportImagesPhase{
+		BasePhase:           BasePhase{c},
+		ExportImagesOptions: opts,
+		ExportIndex:         &ExportIndex{Destination: opts.Destination},
+	}
+}
+
+type ExportImagesPhase struct {
+	BasePhase
+	ExportImagesOptions
+
+	ExportIndex *ExportIndex
+
+	format ExportFormat
+	path   string
+}
+
+func (phase *ExportImagesPhase) Name() string {
+	return "export"
+}
+
+func (phase *ExportImagesPhase) BeforeImages() error {
+	format, path, err := ParseExportDestination(phase.Destination)
+	if err != nil {
+		return err
+	}
+
+	phase.format = format
+	phase.path = path
+
+	return nil
+}
+
+func (phase *ExportImagesPhase) AfterImages() error {
+	if len(phase.ExportIndex.Images) == 0 {
+		logboek.LogWarnF("WARNING: no images to export\n")
+		return nil
+	}
+
+	var refs []string
+	for _, record := range phase.ExportIndex.Images {
+		refs = append(refs, record.Name)
+	}
+
+	if err := logboek.Default.LogProcess(fmt.Sprintf("Exporting images to %s", phase.Destination), logboek.LevelLogProcessOptions{Style: logboek.HighlightStyle()}, func() error {
+		switch phase.format {
+		case ExportDockerArchive:
+			return phase.exportDockerArchive(refs)
+		case ExportOCI:
+			return phase.exportOCI()
+		default:
+			return fmt.Errorf("unsupported export format %q", phase.format)
+		}
+	}); err != nil {
+		return err
+	}
+
+	return phase.writeIndex()
+}
+
+func (phase *ExportImagesPhase) exportDockerArchive(refs []string) error {
+	if err := os.MkdirAll(filepath.Dir(phase.path), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create dir %s: %s", filepath.Dir(phase.path), err)
+	}
+
+	return phase.Conveyor.ContainerRuntime.(container_runtime.LocalRuntime).SaveImages(phase.path, refs...)
+}
+
+func (phase *ExportImagesPhase) exportOCI() error {
+	layoutPath, err := layout.FromPath(phase.path)
+	if err != nil {
+		if layoutPath, err = layout.Write(phase.path, empty.Index); err != nil {
+			return fmt.Errorf("unable to create oci layout %s: %s", phase.path, err)
+		}
+	}
+
+	tmpDir := filepath.Join(phase.Conveyor.tmpDir, "export")
+	if err := os.MkdirAll(tmpDir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create dir %s: %s", tmpDir, err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for ind := range phase.ExportIndex.Images {
+		record := &phase.ExportIndex.Images[ind]
+
+		tag, err := name.NewTag(record.Name)
+		if err != nil {
+			return fmt.Errorf("invalid image name %q: %s", record.Name, err)
+		}
+
+		archivePath := filepath.Join(tmpDir, fmt.Sprintf("%d.tar", ind))
+		if err := phase.Conveyor.ContainerRuntime.(container_runtime.LocalRuntime).SaveImages(archivePath, record.Name); err != nil {
+			return err
+		}
+
+		img, err := tarball.ImageFromPath(archivePath, &tag)
+		if err != nil {
+			return fmt.Errorf("unable to read image %s from %s: %s", record.Name, archivePath, err)
+		}
+
+		if err := appendOCIImage(layoutPath, img, record.Name, record.Platform); err != nil {
+			return fmt.Errorf("unable to write image %s into oci layout %s: %s", record.Name, phase.path, err)
+		}
+
+		digest, err := img.Digest()
+		if err != nil {
+			return fmt.Errorf("unable to get image %s digest: %s", record.Name, err)
+		}
+		record.Digest = digest.String()
+	}
+
+	return nil
+}
+
+// appendOCIImage appends the image to the layout index replacing the descriptors with the same ref name,
+// so repeated exports into the same layout do not duplicate the refs
+func appendOCIImage(layoutPath layout.Path, img v1.Image, refName, platform string) error {
+	index, err := layoutPath.ImageIndex()
+	if err != nil {
+		return err
+	}
+
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return err
+	}
+
+	var manifests []v1.Descriptor
+	for _, desc := range indexManifest.Manifests {
+		if desc.Annotations[ociRefNameAnnotation] != refName {
+			manifests = append(manifests, desc)
+		}
+	}
+
+	if len(manifests) != len(indexManifest.Manifests) {
+		indexManifest.Manifests = manifests
+
+		rawIndex, err := json.MarshalIndent(indexManifest, "", "   ")
+		if err != nil {
+			return err
+		}
+
+		if err := layoutPath.WriteFile("index.json", rawIndex, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	options := []layout.Option{layout.WithAnnotations(map[string]string{ociRefNameAnnotation: refName})}
+	if platform != "" {
+		platformOS, platformArch, platformVariant, err := image.ParsePlatform(platform)
+		if err != nil {
+			return err
+		}
+		options = append(options, layout.WithPlatform(v1.Platform{OS: platformOS, Architecture: platformArch, Variant: platformVariant}))
+	}
+
+	return layoutPath.AppendImage(img, options...)
+}
+
+func (phase *ExportImagesPhase) writeIndex() error {
+	indexPath := phase.IndexPath
+	if indexPath == "" {
+		switch phase.format {
+		case ExportOCI:
+			indexPath = filepath.Join(phase.path, "werf-export.json")
+		default:
+			indexPath = phase.path + ".json"
+		}
+	}
+
+	data, err := json.MarshalIndent(phase.ExportIndex, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to prepare export index: %s", err)
+	}
+
+	if err := ioutil.WriteFile(indexPath, append(data, []byte("\n")...), 0644); err != nil {
+		return fmt.Errorf("unable to write export index to %s: %s", indexPath, err)
+	}
+
+	logboek.Default.LogFDetails("Export index: %s\n", indexPath)
+
+	return nil
+}
+
+func (phase *ExportImagesPhase) BeforeImageStages(img *Image) error {
+	return nil
+}
+
+func (phase *ExportImagesPhase) OnImageStage(img *Image, stg stage.Interface) error {
+	return nil
+}
+
+func (phase *ExportImagesPhase) AfterImageStages(img *Image) error {
+	if img.isArtifact {
+		return nil
+	}
+
+	if len(phase.ImagesToExport) != 0 && !util.IsStringsContainValue(phase.ImagesToExport, img.GetName()) {
+		return nil
+	}
+
+	lastStage := img.GetLastNonEmptyStage()
+	if err := phase.Conveyor.StagesManager.FetchStage(lastStage); err != nil {
+		return err
+	}
+
+	exportImageName := phase.exportImageName(img)
+	if _, err := name.NewTag(exportImageName); err != nil {
+		return fmt.Errorf("invalid export image name %q: %s", exportImageName, err)
+	}
+
+	localRuntime := phase.Conveyor.ContainerRuntime.(container_runtime.LocalRuntime)
+	existingInspect, err := localRuntime.GetImageInspect(exportImageName)
+	if err != nil {
+		return fmt.Errorf("unable to inspect image %s: %s", exportImageName, err)
+	}
+
+	stageImage := phase.Conveyor.GetStageImage(lastStage.GetImage().Name())
+	if err := logboek.Info.LogProcess(fmt.Sprintf("Tagging %s", exportImageName), logboek.LevelLogProcessOptions{}, func() error {
+		return stageImage.Tag(exportImageName)
+	}); err != nil {
+		return fmt.Errorf("unable to tag image %s by name %s: %s", stageImage.Name(), exportImageName, err)
+	}
+
+	// the name that has already existed locally is kept after export
+	if existingInspect == nil {
+		phase.Conveyor.AppendOnTerminateFunc(func() error {
+			exportImage := &container_runtime.DockerImage{Image: container_runtime.NewStageImage(nil, exportImageName, localRuntime)}
+			return phase.Conveyor.ContainerRuntime.RemoveImage(exportImage)
+		})
+	}
+
+	phase.ExportIndex.Images = append(phase.ExportIndex.Images, ExportIndexImageRecord{
+		WerfImageName: img.GetName(),
+		Platform:      img.platform,
+		Name:          exportImageName,
+		DockerImageID: lastStage.GetImage().GetStageDescription().Info.ID,
+	})
+
+	logboek.Default.LogFDetails("  name: %s\n", exportImageName)
+
+	return nil
+}
+
+func (phase *ExportImagesPhase) ImageProcessingShouldBeStopped(img *Image) bool {
+	return false
+}
+
+// exportImageName returns REPO/IMAGE:TAG (REPO:TAG for the nameless image),
+// the stages signature is used by default and the platform is added to the tag of the platform variant
+func (phase *ExportImagesPhase) exportImageName(img *Image) string {
+	repo := phase.Repo
+	if repo == "" {
+		repo = phase.Conveyor.projectName()
+	}
+	if img.GetName() != "" {
+		repo = fmt.Sprintf("%s/%s", repo, img.GetName())
+	}
+
+	tag := phase.Tag
+	if tag == "" {
+		tag = img.GetContentSignature()
+	}
+	if img.platform != "" {
+		tag = fmt.Sprintf("%s-%s", tag, strings.ReplaceAll(img.platform, "/", "-"))
+	}
+
+	return fmt.Sprintf("%s:%s", repo, tag)
+}
@@ -0,0 +1,152 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+
+	"github.com/flant/werf/pkg/config"
+)
+
+func TestParseExportDestination(t *testing.T) {
+	tests := []struct {
+		destination string
+		format      ExportFormat
+		path        string
+		wantErr     bool
+	}{
+		{destination: "oci:./out", format: ExportOCI, path: "./out"},
+		{destination: "docker-archive:app.tar", format: ExportDockerArchive, path: "app.tar"},
+		{destination: "docker-archive:/tmp/c:d.tar", format: ExportDockerArchive, path: "/tmp/c:d.tar"},
+		{destination: "oci:", wantErr: true},
+		{destination: "./out", wantErr: true},
+		{destination: "tar:app.tar", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		format, path, err := ParseExportDestination(tt.destination)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.destination)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.destination, err)
+		} else if format != tt.format || path != tt.path {
+			t.Errorf("%q: got %q %q, expected %q %q", tt.destination, format, path, tt.format, tt.path)
+		}
+	}
+}
+
+func TestExportImagesPhase_UnsupportedFormat(t *testing.T) {
+	phase := &ExportImagesPhase{
+		ExportImagesOptions: ExportImagesOptions{Destination: "tar:app.tar"},
+		ExportIndex:         &ExportIndex{Images: []ExportIndexImageRecord{{Name: "app:latest"}}},
+		format:              "tar",
+		path:                "app.tar",
+	}
+
+	if err := phase.AfterImages(); err == nil || !strings.Contains(err.Error(), "unsupported export format \"tar\"") {
+		t.Fatalf("expected unsupported export format error, got %v", err)
+	}
+}
+
+func TestAppendOCIImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-export-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	layoutPath, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exports := []struct {
+		refName  string
+		platform string
+	}{
+		{refName: "app/backend:v1-linux-amd64", platform: "linux/amd64"},
+		{refName: "app/backend:v1-linux-arm64-v8", platform: "linux/arm64/v8"},
+		{refName: "app/frontend:v1"},
+		// repeated export replaces the images with the same names
+		{refName: "app/backend:v1-linux-amd64", platform: "linux/amd64"},
+		{refName: "app/frontend:v1"},
+	}
+
+	expectedDigests := map[string]v1.Hash{}
+	for _, export := range exports {
+		img, err := random.Image(256, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := appendOCIImage(layoutPath, img, export.refName, export.platform); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if expectedDigests[export.refName], err = img.Digest(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index, err := layoutPath.ImageIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(indexManifest.Manifests) != len(expectedDigests) {
+		t.Fatalf("expected %d manifests, got %d", len(expectedDigests), len(indexManifest.Manifests))
+	}
+
+	for _, desc := range indexManifest.Manifests {
+		refName := desc.Annotations[ociRefNameAnnotation]
+		if desc.Digest != expectedDigests[refName] {
+			t.Errorf("%s: expected digest %s, got %s", refName, expectedDigests[refName], desc.Digest)
+		}
+
+		if refName == "app/backend:v1-linux-arm64-v8" && (desc.Platform == nil || desc.Platform.Architecture != "arm64" || desc.Platform.Variant != "v8") {
+			t.Errorf("%s: unexpected platform %+v", refName, desc.Platform)
+		}
+	}
+}
+
+func TestExportImagesPhase_ExportImageName(t *testing.T) {
+	conveyor := &Conveyor{werfConfig: &config.WerfConfig{Meta: &config.Meta{Project: "app"}}}
+
+	tests := []struct {
+		name     string
+		repo     string
+		tag      string
+		image    *Image
+		expected string
+	}{
+		{name: "defaults", image: &Image{name: "backend", contentSignature: "abc"}, expected: "app/backend:abc"},
+		{name: "nameless image", image: &Image{contentSignature: "abc"}, expected: "app:abc"},
+		{name: "repo and tag", repo: "registry.example.com/app", tag: "v1.0.0", image: &Image{name: "backend", contentSignature: "abc"}, expected: "registry.example.com/app/backend:v1.0.0"},
+		{name: "platform variant", tag: "v1.0.0", image: &Image{name: "backend", platform: "linux/arm64/v8"}, expected: "app/backend:v1.0.0-linux-arm64-v8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phase := NewExportImagesPhase(conveyor, ExportImagesOptions{Repo: tt.repo, Tag: tt.tag})
+			if name := phase.exportImageName(tt.image); name != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, name)
+			}
+		})
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/flant/logboek"
 
 	"github.com/flant/werf/pkg/build/stage"
-	"github.com/flant/werf/pkg/docker_registry"
 	"github.com/flant/werf/pkg/image"
 	"github.com/flant/werf/pkg/logging"
 )
@@ -23,7 +22,8 @@ const (
 )
 
 type Image struct {
-	name string
+	name     string
+	platform string
 
 	baseImageName      string
 	baseImageImageName string
@@ -41,11 +41,18 @@ type Image struct {
 }
 
 func (i *Image) LogName() string {
-	return logging.ImageLogName(i.name, i.isArtifact)
+	return i.withPlatformLogSuffix(logging.ImageLogName(i.name, i.isArtifact))
 }
 
 func (i *Image) LogDetailedName() string {
-	return logging.ImageLogProcessName(i.name, i.isArtifact)
+	return i.withPlatformLogSuffix(logging.ImageLogProcessName(i.name, i.isArtifact))
+}
+
+func (i *Image) withPlatformLogSuffix(logName string) string {
+	if i.platform == "" {
+		return logName
+	}
+	return fmt.Sprintf("%s (%s)", logName, i.platform)
 }
 
 func (i *Image) LogProcessStyle() *logboek.Style {
@@ -113,6 +120,10 @@ func (i *Image) GetName() string {
 	return i.name
 }
 
+func (i *Image) GetPlatform() string {
+	return i.platform
+}
+
 func (i *Image) GetLogName() string {
 	return i.LogName()
 }
@@ -120,7 +131,7 @@ func (i *Image) GetLogName() string {
 func (i *Image) SetupBaseImage(c *Conveyor) {
 	if i.baseImageImageName != "" {
 		i.baseImageType = StageAsBaseImage
-		i.stageAsBaseImage = c.GetImage(i.baseImageImageName).GetLastNonEmptyStage()
+		i.stageAsBaseImage = c.GetImageVariant(i.baseImageImageName, i.platform).GetLastNonEmptyStage()
 		i.baseImage = c.GetOrCreateStageImage(nil, i.stageAsBaseImage.GetImage().Name())
 	} else {
 		i.baseImageType = ImageFromRegistryAsBaseImage
@@ -135,7 +146,7 @@ func (i *Image) GetBaseImage() *container_runtime.StageImage {
 func (i *Image) FetchBaseImage(c *Conveyor) error {
 	switch i.baseImageType {
 	case ImageFromRegistryAsBaseImage:
-		containerRuntime := c.ContainerRuntime.(*container_runtime.LocalDockerServerRuntime)
+		containerRuntime := c.ContainerRuntime.(container_runtime.LocalRuntime)
 
 		if inspect, err := containerRuntime.GetImageInspect(i.baseImage.Name()); err != nil {
 			return fmt.Errorf("unable to inspect local image %s: %s", i.baseImage.Name(), err)
@@ -146,6 +157,11 @@ func (i *Image) FetchBaseImage(c *Conveyor) error {
 				Info:    image.NewInfoFromInspect(i.baseImage.Name(), inspect),
 			})
 
+			// base image of the platform variant is pinned by digest
+			if i.platform != "" {
+				return nil
+			}
+
 			baseImageRepoId, err := i.getFromBaseImageIdFromRegistry(c, i.baseImage.Name())
 			if baseImageRepoId == inspect.ID || err != nil {
 				if err != nil {
@@ -192,30 +208,13 @@ func (i *Image) FetchBaseImage(c *Conveyor) error {
 func (i *Image) getFromBaseImageIdFromRegistry(c *Conveyor, baseImageName string) (string, error) {
 	if i.baseImageRepoId != "" {
 		return i.baseImageRepoId, nil
-	} else if cachedBaseImageRepoId, exist := c.baseImagesRepoIdsCache[baseImageName]; exist {
-		i.baseImageRepoId = cachedBaseImageRepoId
-		return cachedBaseImageRepoId, nil
-	} else if cachedBaseImagesRepoErr, exist := c.baseImagesRepoErrCache[baseImageName]; exist {
-		return "", cachedBaseImagesRepoErr
 	}
 
-	var fetchedBaseRepoImage *image.Info
-	processMsg := fmt.Sprintf("Trying to get from base image id from registry (%s)", baseImageName)
-	if err := logboek.Info.LogProcessInline(processMsg, logboek.LevelLogProcessInlineOptions{}, func() error {
-		var fetchImageIdErr error
-		fetchedBaseRepoImage, fetchImageIdErr = docker_registry.API().GetRepoImage(baseImageName)
-		if fetchImageIdErr != nil {
-			c.baseImagesRepoErrCache[baseImageName] = fetchImageIdErr
-			return fmt.Errorf("can not get base image id from registry (%s): %s", baseImageName, fetchImageIdErr)
-		}
-
-		return nil
-	}); err != nil {
+	baseImageRepoId, err := c.GetExternalImageRepoID(baseImageName)
+	if err != nil {
 		return "", err
 	}
-
-	i.baseImageRepoId = fetchedBaseRepoImage.ID
-	c.baseImagesRepoIdsCache[baseImageName] = i.baseImageRepoId
+	i.baseImageRepoId = baseImageRepoId
 
 	return i.baseImageRepoId, nil
 }
@@ -3,5 +3,7 @@ package import_server
 import "github.com/flant/werf/pkg/config"
 
 type ImportServer interface {
-	GetCopyCommand(importConfig *config.Import) string
+	GetCopyCommand(importConfig *config.Import) (string, error)
+	// GetVolumes returns volumes that should be mounted into the container running copy commands
+	GetVolumes() []string
 }
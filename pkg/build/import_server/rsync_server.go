@@ -11,10 +11,9 @@ import (
 
 	"github.com/google/uuid"
 
-	"github.com/flant/werf/pkg/docker"
-
 	"github.com/flant/logboek"
 	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/container_runtime"
 	"github.com/flant/werf/pkg/stapel"
 )
 
@@ -26,19 +25,22 @@ type RsyncServer struct {
 	DockerContainerName    string
 	DockerImageName        string
 	AuthUser, AuthPassword string
+
+	localRuntime container_runtime.LocalRuntime
 }
 
-func RunRsyncServer(dockerImageName string, tmpDir string) (*RsyncServer, error) {
+func RunRsyncServer(localRuntime container_runtime.LocalRuntime, dockerImageName string, tmpDir string) (*RsyncServer, error) {
 	logboek.Debug.LogF("RunRsyncServer for docker image %q\n", dockerImageName)
 
 	srv := &RsyncServer{
+		localRuntime:        localRuntime,
 		Port:                rsyncServerPort,
 		DockerContainerName: fmt.Sprintf("import-server-%s", uuid.New().String()),
 		AuthUser:            fmt.Sprintf("werf-%s", generateSecureRandomString(4)),
 		AuthPassword:        generateSecureRandomString(16),
 	}
 
-	stapelContainerName, err := stapel.GetOrCreateContainer()
+	stapelContainerName, err := localRuntime.GetOrCreateStapelContainer()
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +71,6 @@ strict modes = false
 	}
 
 	runArgs := []string{
-		"--detach",
 		"--rm",
 		"--user=0:0",
 		"--workdir=/",
@@ -84,35 +85,37 @@ strict modes = false
 		"--no-detach",
 		"--config=/.werf/rsyncd.conf",
 	}
-	logboek.Debug.LogF("Run rsync server command: %q\n", fmt.Sprintf("docker run %s", strings.Join(runArgs, " ")))
-	if output, err := docker.CliRun_RecordedOutput(runArgs...); err != nil {
-		logboek.LogErrorF("%s", output)
+	logboek.Debug.LogF("Run rsync server command: %q\n", fmt.Sprintf("%s run --detach %s", localRuntime.String(), strings.Join(runArgs, " ")))
+	if err := localRuntime.RunDetachedContainer(runArgs...); err != nil {
 		return nil, err
 	}
 
 	logboek.Debug.LogF("Inspect container %s\n", srv.DockerContainerName)
 
-	if inspect, err := docker.ContainerInspect(srv.DockerContainerName); err != nil {
-		return nil, fmt.Errorf("unable to inspect import server container %s: %s", srv.DockerContainerName, err)
+	if ipAddress, err := localRuntime.GetContainerIPAddress(srv.DockerContainerName); err != nil {
+		return srv, fmt.Errorf("unable to get import server container %s ip address: %s", srv.DockerContainerName, err)
+	} else if ipAddress == "" {
+		// rootless podman containers do not get ip addresses reachable from other containers
+		return srv, fmt.Errorf("import server container %s has no ip address in %s container runtime, tar import method should be used", srv.DockerContainerName, localRuntime.String())
 	} else {
-		if inspect.NetworkSettings == nil {
-			return nil, fmt.Errorf("unable to get import server container %s ip address: no network settings available in inspect", srv.DockerContainerName)
-		}
-		srv.IPAddress = inspect.NetworkSettings.IPAddress
+		srv.IPAddress = ipAddress
 	}
 
 	return srv, nil
 }
 
 func (srv *RsyncServer) Shutdown() error {
-	if output, err := docker.CliRm_RecordedOutput("--force", srv.DockerContainerName); err != nil {
-		logboek.LogErrorF("%s", output)
+	if err := srv.localRuntime.RemoveContainer(srv.DockerContainerName); err != nil {
 		return fmt.Errorf("unable to remove container %s: %s", srv.DockerContainerName, err)
 	}
 	return nil
 }
 
-func (srv *RsyncServer) GetCopyCommand(importConfig *config.Import) string {
+func (srv *RsyncServer) GetVolumes() []string {
+	return nil
+}
+
+func (srv *RsyncServer) GetCopyCommand(importConfig *config.Import) (string, error) {
 	var args []string
 
 	rsyncImportPathSpec := fmt.Sprintf("rsync://%s@%s:%s/import/%s", srv.AuthUser, srv.IPAddress, srv.Port, importConfig.Add)
@@ -187,7 +190,7 @@ func (srv *RsyncServer) GetCopyCommand(importConfig *config.Import) string {
 
 	logboek.Debug.LogF("Rsync server copy commands for import: artifact=%q image=%q add=%s to=%s includePaths=%v excludePaths=%v: %q\n", importConfig.ArtifactName, importConfig.ImageName, importConfig.Add, importConfig.To, importConfig.IncludePaths, importConfig.ExcludePaths, command)
 
-	return command
+	return command, nil
 }
 
 func descentPath(filePath string) []string {
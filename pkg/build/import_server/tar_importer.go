@@ -0,0 +1,234 @@
+package import_server
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/config"
+	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/path_matcher"
+	"github.com/flant/werf/pkg/stapel"
+	"github.com/flant/werf/pkg/util"
+)
+
+// TarImporter exports import paths from the source image as tar archives without running any server:
+// archives are mounted into the build container and unpacked there
+type TarImporter struct {
+	DockerImageName      string
+	DockerContainerName  string
+	ArchivesDir          string
+	ContainerArchivesDir string
+
+	localRuntime container_runtime.LocalRuntime
+}
+
+func NewTarImporter(localRuntime container_runtime.LocalRuntime, dockerImageName, tmpDir string) (*TarImporter, error) {
+	logboek.Debug.LogF("NewTarImporter for docker image %q\n", dockerImageName)
+
+	imp := &TarImporter{
+		localRuntime:         localRuntime,
+		DockerImageName:      dockerImageName,
+		DockerContainerName:  fmt.Sprintf("import-tar-%s", uuid.New().String()),
+		ArchivesDir:          filepath.Join(tmpDir, "archives"),
+		ContainerArchivesDir: path.Join("/.werf/imports", uuid.New().String()),
+	}
+
+	if err := os.MkdirAll(imp.ArchivesDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create dir %s: %s", imp.ArchivesDir, err)
+	}
+
+	// the container is never started, it is only needed to read files of the image
+	createArgs := []string{
+		fmt.Sprintf("--name=%s", imp.DockerContainerName),
+		fmt.Sprintf("--entrypoint=%s", stapel.TrueBinPath()),
+		dockerImageName,
+	}
+	if err := localRuntime.CreateContainer(createArgs...); err != nil {
+		return nil, err
+	}
+
+	return imp, nil
+}
+
+func (imp *TarImporter) Shutdown() error {
+	if err := imp.localRuntime.RemoveContainer(imp.DockerContainerName); err != nil {
+		return fmt.Errorf("unable to remove container %s: %s", imp.DockerContainerName, err)
+	}
+	return nil
+}
+
+func (imp *TarImporter) GetVolumes() []string {
+	return []string{fmt.Sprintf("%s:%s:ro", imp.ArchivesDir, imp.ContainerArchivesDir)}
+}
+
+func (imp *TarImporter) GetCopyCommand(importConfig *config.Import) (string, error) {
+	archiveName := util.Sha256Hash(append(append([]string{importConfig.Add, importConfig.To, ":::"}, importConfig.IncludePaths...), append([]string{":::"}, importConfig.ExcludePaths...)...)...) + ".tar"
+	archivePath := filepath.Join(imp.ArchivesDir, archiveName)
+
+	isDir, err := imp.exportArchive(importConfig, archivePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to export %s from image %s: %s", importConfig.Add, imp.DockerImageName, err)
+	}
+
+	unpackDirectory := importConfig.To
+	if !isDir {
+		unpackDirectory = path.Dir(importConfig.To)
+	}
+
+	var credentialsOpts []string
+	if importConfig.Owner != "" {
+		credentialsOpts = append(credentialsOpts, fmt.Sprintf("--owner=%s", importConfig.Owner))
+	}
+	if importConfig.Group != "" {
+		credentialsOpts = append(credentialsOpts, fmt.Sprintf("--group=%s", importConfig.Group))
+	}
+
+	args := []string{
+		strings.Join(append(append([]string{stapel.InstallBinPath()}, credentialsOpts...), "-d", unpackDirectory), " "),
+		strings.TrimLeft(fmt.Sprintf("%s %s -xf %s -C %s", stapel.OptionalSudoCommand(importConfig.Owner, importConfig.Group), stapel.TarBinPath(), path.Join(imp.ContainerArchivesDir, archiveName), unpackDirectory), " "),
+	}
+
+	command := strings.Join(args, " && ")
+
+	logboek.Debug.LogF("Tar importer copy commands for import: artifact=%q image=%q from=%q add=%s to=%s includePaths=%v excludePaths=%v: %q\n", importConfig.ArtifactName, importConfig.ImageName, importConfig.From, importConfig.Add, importConfig.To, importConfig.IncludePaths, importConfig.ExcludePaths, command)
+
+	return command, nil
+}
+
+// exportArchive writes files of the import path matched by include and exclude globs into the archive,
+// archive paths are relative to the destination path for the directory and to the destination parent directory for the file
+func (imp *TarImporter) exportArchive(importConfig *config.Import, archivePath string) (bool, error) {
+	srcPath := importConfig.Add
+
+	stat, err := imp.localRuntime.GetContainerPathStat(imp.DockerContainerName, srcPath)
+	if err != nil {
+		return false, err
+	}
+
+	// follow symlink like rsync -L does
+	if stat.LinkTarget != "" {
+		srcPath = stat.LinkTarget
+		if stat, err = imp.localRuntime.GetContainerPathStat(imp.DockerContainerName, srcPath); err != nil {
+			return false, err
+		}
+	}
+
+	isDir := stat.Mode.IsDir()
+
+	if exist, err := util.FileExists(archivePath); err != nil {
+		return false, err
+	} else if exist {
+		return isDir, nil
+	}
+
+	reader, err := imp.localRuntime.CopyFromContainer(imp.DockerContainerName, srcPath)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	tmpArchivePath := archivePath + ".tmp"
+	f, err := os.Create(tmpArchivePath)
+	if err != nil {
+		return false, fmt.Errorf("unable to create %s: %s", tmpArchivePath, err)
+	}
+
+	if err := writeImportArchive(tar.NewReader(reader), tar.NewWriter(f), path.Base(srcPath), isDir, path.Base(importConfig.To), importConfig.IncludePaths, importConfig.ExcludePaths); err != nil {
+		_ = f.Close()
+		return false, err
+	}
+
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("unable to close %s: %s", tmpArchivePath, err)
+	}
+
+	// cli based runtime reports copy errors only when the archive is closed
+	if err := reader.Close(); err != nil {
+		return false, err
+	}
+
+	if err := os.Rename(tmpArchivePath, archivePath); err != nil {
+		return false, fmt.Errorf("unable to rename %s to %s: %s", tmpArchivePath, archivePath, err)
+	}
+
+	return isDir, nil
+}
+
+func writeImportArchive(tr *tar.Reader, tw *tar.Writer, srcBaseName string, isDir bool, toBaseName string, includePaths, excludePaths []string) error {
+	pathMatcher := path_matcher.NewGitMappingPathMatcher("", includePaths, excludePaths, false)
+	// directory that is not fully excluded might still be matched by exclude paths itself
+	excludePathMatcher := path_matcher.NewGitMappingPathMatcher("", nil, excludePaths, false)
+	writtenFiles := map[string]bool{}
+
+	relPath := func(name string) (string, bool) {
+		name = strings.TrimSuffix(name, "/")
+		if name == srcBaseName {
+			return "", true
+		} else if strings.HasPrefix(name, srcBaseName+"/") {
+			return strings.TrimPrefix(name, srcBaseName+"/"), true
+		}
+		return "", false
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("unable to read archive: %s", err)
+		}
+
+		rel, ok := relPath(hdr.Name)
+		if !ok {
+			return fmt.Errorf("unexpected archive entry %q", hdr.Name)
+		}
+
+		if !isDir {
+			hdr.Name = toBaseName
+		} else if rel == "" {
+			// destination directory is created by the copy command
+			continue
+		} else if hdr.Typeflag == tar.TypeDir {
+			if isMatched, shouldGoThrough := pathMatcher.ProcessDirOrSubmodulePath(rel); !isMatched && !shouldGoThrough {
+				continue
+			} else if !excludePathMatcher.MatchPath(rel) {
+				continue
+			}
+			hdr.Name = rel + "/"
+		} else {
+			if !pathMatcher.MatchPath(rel) {
+				continue
+			}
+			hdr.Name = rel
+		}
+
+		if hdr.Typeflag == tar.TypeLink {
+			linkRel, ok := relPath(hdr.Linkname)
+			if !ok || !writtenFiles[linkRel] {
+				return fmt.Errorf("unable to import hard link %s: link target %s is not imported", rel, hdr.Linkname)
+			}
+			hdr.Linkname = linkRel
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("unable to write archive entry %q: %s", hdr.Name, err)
+		}
+
+		if _, err := io.Copy(tw, tr); err != nil {
+			return fmt.Errorf("unable to write archive entry %q: %s", hdr.Name, err)
+		}
+
+		writtenFiles[rel] = true
+	}
+
+	return tw.Close()
+}
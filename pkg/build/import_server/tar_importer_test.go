@@ -0,0 +1,117 @@
+package import_server
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestWriteImportArchive(t *testing.T) {
+	entries := []*tar.Header{
+		{Name: "app/", Typeflag: tar.TypeDir},
+		{Name: "app/bin/", Typeflag: tar.TypeDir},
+		{Name: "app/bin/server", Typeflag: tar.TypeReg},
+		{Name: "app/bin/server-link", Typeflag: tar.TypeLink, Linkname: "app/bin/server"},
+		{Name: "app/public/", Typeflag: tar.TypeDir},
+		{Name: "app/public/index.html", Typeflag: tar.TypeReg},
+		{Name: "app/tmp/", Typeflag: tar.TypeDir},
+		{Name: "app/tmp/cache", Typeflag: tar.TypeReg},
+	}
+
+	for _, test := range []struct {
+		name                       string
+		isDir                      bool
+		includePaths, excludePaths []string
+		expected                   []string
+	}{
+		{
+			name:     "directory",
+			isDir:    true,
+			expected: []string{"bin/", "bin/server", "bin/server-link", "public/", "public/index.html", "tmp/", "tmp/cache"},
+		},
+		{
+			name:         "include paths",
+			isDir:        true,
+			includePaths: []string{"bin"},
+			expected:     []string{"bin/", "bin/server", "bin/server-link"},
+		},
+		{
+			name:         "exclude paths",
+			isDir:        true,
+			excludePaths: []string{"tmp", "**/*.html"},
+			expected:     []string{"bin/", "bin/server", "bin/server-link", "public/"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			names, err := writeTestImportArchive(entries, "app", test.isDir, "app", test.includePaths, test.excludePaths)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, names)
+			}
+		})
+	}
+
+	t.Run("file", func(t *testing.T) {
+		names, err := writeTestImportArchive([]*tar.Header{{Name: "server", Typeflag: tar.TypeReg}}, "server", false, "app-server", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := []string{"app-server"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("expected %v, got %v", expected, names)
+		}
+	})
+
+	t.Run("excluded hard link target", func(t *testing.T) {
+		if _, err := writeTestImportArchive(entries, "app", true, "app", []string{"bin/server-link"}, nil); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func writeTestImportArchive(entries []*tar.Header, srcBaseName string, isDir bool, toBaseName string, includePaths, excludePaths []string) ([]string, error) {
+	src := bytes.NewBuffer(nil)
+	srcWriter := tar.NewWriter(src)
+	for _, entry := range entries {
+		hdr := *entry
+		hdr.Mode = 0644
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(hdr.Name))
+		}
+		if err := srcWriter.WriteHeader(&hdr); err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := srcWriter.Write([]byte(hdr.Name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := srcWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	dst := bytes.NewBuffer(nil)
+	if err := writeImportArchive(tar.NewReader(src), tar.NewWriter(dst), srcBaseName, isDir, toBaseName, includePaths, excludePaths); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	dstReader := tar.NewReader(dst)
+	for {
+		hdr, err := dstReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		names = append(names, hdr.Name)
+	}
+
+	return names, nil
+}
@@ -4,13 +4,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
 	"github.com/flant/logboek"
 
 	"github.com/flant/werf/pkg/build/stage"
 	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/docker_registry"
 	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/logging"
+	"github.com/flant/werf/pkg/slug"
 	"github.com/flant/werf/pkg/storage"
 	"github.com/flant/werf/pkg/tag_strategy"
 	"github.com/flant/werf/pkg/util"
@@ -101,6 +108,14 @@ func (phase *PublishImagesPhase) AfterImageStages(img *Image) error {
 		return nil
 	}
 
+	// manifest list is published once all platform variants of the image are built
+	if img.platform != "" {
+		variants := phase.Conveyor.GetImageVariants(img.GetName())
+		if variants[len(variants)-1] != img {
+			return nil
+		}
+	}
+
 	if len(phase.ImagesToPublish) == 0 {
 		return phase.publishImage(img)
 	}
@@ -194,6 +209,10 @@ type publishImageByTagOptions struct {
 }
 
 func (phase *PublishImagesPhase) publishImageByTag(img *Image, imageMetaTag string, tagStrategy tag_strategy.TagStrategy, opts publishImageByTagOptions) error {
+	if img.platform != "" {
+		return phase.publishManifestListByTag(img, imageMetaTag, tagStrategy, opts)
+	}
+
 	imageRepository := phase.ImagesRepo.ImageRepositoryName(img.GetName())
 	lastStageImage := img.GetLastNonEmptyStage().GetImage()
 	imageName := phase.ImagesRepo.ImageRepositoryNameWithTag(img.GetName(), imageMetaTag)
@@ -225,7 +244,7 @@ func (phase *PublishImagesPhase) publishImageByTag(img *Image, imageMetaTag stri
 		return nil
 	}
 
-	publishImage := container_runtime.NewWerfImage(phase.Conveyor.GetStageImage(lastStageImage.Name()), imageName, phase.Conveyor.ContainerRuntime.(*container_runtime.LocalDockerServerRuntime))
+	publishImage := container_runtime.NewWerfImage(phase.Conveyor.GetStageImage(lastStageImage.Name()), imageName, phase.Conveyor.ContainerRuntime.(container_runtime.LocalRuntime))
 
 	publishImage.Container().ServiceCommitChangeOptions().AddLabel(map[string]string{
 		image.WerfDockerImageName:  imageName,
@@ -349,3 +368,184 @@ func (phase *PublishImagesPhase) checkImageAlreadyExists(existingTags []string,
 
 	return lastStageImage.GetStageDescription().Info.ID == repoImageParentID, repoImageID, nil
 }
+
+// publishManifestListByTag publishes each platform variant of the image by the digest and then creates the tag with the manifest list
+func (phase *PublishImagesPhase) publishManifestListByTag(img *Image, imageMetaTag string, tagStrategy tag_strategy.TagStrategy, opts publishImageByTagOptions) error {
+	variants := phase.Conveyor.GetImageVariants(img.GetName())
+	imageRepository := phase.ImagesRepo.ImageRepositoryName(img.GetName())
+	imageName := phase.ImagesRepo.ImageRepositoryNameWithTag(img.GetName(), imageMetaTag)
+	imageActualTag := phase.ImagesRepo.ImageRepositoryTag(img.GetName(), imageMetaTag)
+
+	logUpToDate := func() {
+		logboek.Default.LogFHighlight("%s tag %s is up-to-date\n", strings.Title(string(tagStrategy)), imageActualTag)
+
+		_ = logboek.WithIndent(func() error {
+			logboek.Default.LogFDetails("images-repo: %s\n", imageRepository)
+			logboek.Default.LogFDetails("      image: %s\n", imageName)
+			return nil
+		})
+
+		logboek.LogOptionalLn()
+
+		phase.PublishReport.Images[img.GetName()] = PublishReportImageRecord{
+			WerfImageName: img.GetName(),
+			DockerRepo:    imageRepository,
+			DockerTag:     imageActualTag,
+		}
+	}
+
+	if alreadyExists := phase.checkManifestListAlreadyExists(opts.ExistingTagsList, variants, imageMetaTag, opts.CheckAlreadyExistingTagByDockerImageID); alreadyExists {
+		logUpToDate()
+		return nil
+	}
+
+	successInfoSectionFunc := func() {
+		_ = logboek.WithIndent(func() error {
+			logboek.Default.LogFDetails("images-repo: %s\n", imageRepository)
+			logboek.Default.LogFDetails("      image: %s\n", imageName)
+			return nil
+		})
+	}
+
+	publishingFunc := func() error {
+		if lock, err := phase.Conveyor.StorageLockManager.LockImage(phase.Conveyor.projectName(), imageName); err != nil {
+			return fmt.Errorf("error locking image %s: %s", imageName, err)
+		} else {
+			defer phase.Conveyor.StorageLockManager.Unlock(lock)
+		}
+
+		existingTags, err := phase.fetchExistingTags(img.GetName())
+		if err != nil {
+			return err
+		}
+
+		if alreadyExists := phase.checkManifestListAlreadyExists(existingTags, variants, imageMetaTag, opts.CheckAlreadyExistingTagByDockerImageID); alreadyExists {
+			logUpToDate()
+			return nil
+		}
+
+		tmpDir := filepath.Join(phase.Conveyor.tmpDir, "publish-manifest-list")
+		if err := os.MkdirAll(tmpDir, os.ModePerm); err != nil {
+			return fmt.Errorf("unable to create dir %s: %s", tmpDir, err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		// variants are published by digests, so the tag is changed only once by the manifest list and never refers to a single platform image
+		var entries []docker_registry.ManifestListEntry
+		var firstPublishedImageID string
+		for _, variant := range variants {
+			lastStageImage := variant.GetLastNonEmptyStage().GetImage()
+			if err := phase.Conveyor.StagesManager.FetchStage(variant.GetLastNonEmptyStage()); err != nil {
+				return err
+			}
+
+			publishImage := container_runtime.NewWerfImage(phase.Conveyor.GetStageImage(lastStageImage.Name()), imageName, phase.Conveyor.ContainerRuntime.(container_runtime.LocalRuntime))
+			publishImage.Container().ServiceCommitChangeOptions().AddLabel(map[string]string{
+				image.WerfDockerImageName:  imageName,
+				image.WerfTagStrategyLabel: string(tagStrategy),
+				image.WerfImageLabel:       "true",
+				image.WerfImageNameLabel:   img.GetName(),
+				image.WerfImageTagLabel:    imageMetaTag,
+			})
+
+			if err := logboek.Info.LogProcess(fmt.Sprintf("Building final image with meta information (%s)", variant.platform), logboek.LevelLogProcessOptions{}, func() error {
+				if err := publishImage.Build(container_runtime.BuildOptions{}); err != nil {
+					return fmt.Errorf("error building %s with tagging strategy '%s': %s", imageName, tagStrategy, err)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			var digest string
+			if err := logboek.Info.LogProcess(fmt.Sprintf("Publishing image by digest (%s)", variant.platform), logboek.LevelLogProcessOptions{}, func() error {
+				var err error
+				digest, err = phase.publishImageByDigest(img.GetName(), publishImage.MustGetBuiltId(), filepath.Join(tmpDir, slug.Slug(variant.platform)+".tar"))
+				return err
+			}); err != nil {
+				return fmt.Errorf("unable to publish image %s (%s): %s", imageName, variant.platform, err)
+			}
+
+			entries = append(entries, docker_registry.ManifestListEntry{Platform: variant.platform, RepoDigest: digest})
+			if firstPublishedImageID == "" {
+				firstPublishedImageID = publishImage.MustGetBuiltId()
+			}
+		}
+
+		if err := logboek.Info.LogProcess("Publishing manifest list", logboek.LevelLogProcessOptions{}, func() error {
+			return phase.ImagesRepo.PublishManifestList(img.GetName(), imageMetaTag, entries)
+		}); err != nil {
+			return fmt.Errorf("unable to publish manifest list %s: %s", imageName, err)
+		}
+
+		phase.PublishReport.Images[img.GetName()] = PublishReportImageRecord{
+			WerfImageName: img.GetName(),
+			DockerRepo:    imageRepository,
+			DockerTag:     imageActualTag,
+			DockerImageID: firstPublishedImageID,
+		}
+
+		return nil
+	}
+
+	return logboek.Default.LogProcess(
+		fmt.Sprintf("Publishing image %s by %s tag %s", logging.ImageLogName(img.GetName(), false), tagStrategy, imageMetaTag),
+		logboek.LevelLogProcessOptions{
+			SuccessInfoSectionFunc: successInfoSectionFunc,
+			Style:                  logboek.HighlightStyle(),
+		},
+		publishingFunc)
+}
+
+// publishImageByDigest pushes the local image into the images repo without a tag using the image archive
+func (phase *PublishImagesPhase) publishImageByDigest(werfImageName, localImageID, archivePath string) (string, error) {
+	if err := phase.Conveyor.ContainerRuntime.(container_runtime.LocalRuntime).SaveImages(archivePath, localImageID); err != nil {
+		return "", err
+	}
+	defer os.Remove(archivePath)
+
+	archiveImage, err := tarball.ImageFromPath(archivePath, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to read image %s from %s: %s", localImageID, archivePath, err)
+	}
+
+	digest, err := phase.ImagesRepo.PublishImageByDigest(werfImageName, archiveImage)
+	if err != nil {
+		return "", err
+	}
+	logboek.Info.LogFDetails("digest: %s\n", digest)
+
+	return digest, nil
+}
+
+// checkManifestListAlreadyExists checks that the tag contains each platform variant of the image built from the current stages
+func (phase *PublishImagesPhase) checkManifestListAlreadyExists(existingTags []string, variants []*Image, imageMetaTag string, checkAlreadyExistingTagByDockerImageID bool) bool {
+	werfImageName := variants[0].GetName()
+	imageActualTag := phase.ImagesRepo.ImageRepositoryTag(werfImageName, imageMetaTag)
+
+	if !util.IsStringsContainValue(existingTags, imageActualTag) {
+		return false
+	} else if !checkAlreadyExistingTagByDockerImageID {
+		return true
+	}
+
+	for _, variant := range variants {
+		var repoImage *image.Info
+		logProcessMsg := fmt.Sprintf("Getting existing tag %s parent id (%s)", imageActualTag, variant.platform)
+		if err := logboek.Info.LogProcessInline(logProcessMsg, logboek.LevelLogProcessInlineOptions{}, func() error {
+			var err error
+			repoImage, err = phase.ImagesRepo.GetRepoImageByPlatform(werfImageName, imageMetaTag, variant.platform)
+			return err
+		}); err != nil {
+			// the tag may contain an image without the required platform
+			logboek.Info.LogF("Tag %s will be republished: %s\n", imageActualTag, err)
+			return false
+		}
+
+		if variant.GetLastNonEmptyStage().GetImage().GetStageDescription().Info.ID != repoImage.ParentID {
+			return false
+		}
+	}
+
+	return true
+}
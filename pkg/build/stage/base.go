@@ -66,6 +66,11 @@ type NewBaseStageOptions struct {
 	ImageTmpDir      string
 	ContainerWerfDir string
 	ProjectName      string
+	ProjectDir       string
+	Platform         string
+
+	// DependenciesGitMapping is used only to calculate checksums of the project dependencies of user stages
+	DependenciesGitMapping *GitMapping
 }
 
 func newBaseStage(name StageName, options *NewBaseStageOptions) *BaseStage {
@@ -76,6 +81,7 @@ func newBaseStage(name StageName, options *NewBaseStageOptions) *BaseStage {
 	s.imageTmpDir = options.ImageTmpDir
 	s.containerWerfDir = options.ContainerWerfDir
 	s.projectName = options.ProjectName
+	s.platform = options.Platform
 	return s
 }
 
@@ -90,6 +96,7 @@ type BaseStage struct {
 	containerWerfDir string
 	configMounts     []*config.Mount
 	projectName      string
+	platform         string
 }
 
 func (s *BaseStage) LogDetailedName() string {
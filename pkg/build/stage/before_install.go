@@ -4,6 +4,7 @@ import (
 	"github.com/flant/werf/pkg/build/builder"
 	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/util"
 )
 
 func GenerateBeforeInstallStage(imageBaseConfig *config.StapelImageBase, baseStageOptions *NewBaseStageOptions) *BeforeInstallStage {
@@ -25,8 +26,22 @@ type BeforeInstallStage struct {
 	*UserStage
 }
 
-func (s *BeforeInstallStage) GetDependencies(_ Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
-	return s.builder.BeforeInstallChecksum(), nil
+func (s *BeforeInstallStage) GetDependencies(c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
+	builderChecksum, err := s.builder.BeforeInstallChecksum()
+	if err != nil {
+		return "", err
+	}
+
+	if !s.hasStageDependencies(BeforeInstall) {
+		return builderChecksum, nil
+	}
+
+	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(c, BeforeInstall)
+	if err != nil {
+		return "", err
+	}
+
+	return util.Sha256Hash(builderChecksum, stageDependenciesChecksum), nil
 }
 
 func (s *BeforeInstallStage) PrepareImage(c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
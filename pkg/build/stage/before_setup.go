@@ -27,12 +27,17 @@ type BeforeSetupStage struct {
 }
 
 func (s *BeforeSetupStage) GetDependencies(c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
+	builderChecksum, err := s.builder.BeforeSetupChecksum()
+	if err != nil {
+		return "", err
+	}
+
 	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(c, BeforeSetup)
 	if err != nil {
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.BeforeSetupChecksum(), stageDependenciesChecksum), nil
+	return util.Sha256Hash(builderChecksum, stageDependenciesChecksum), nil
 }
 
 func (s *BeforeSetupStage) PrepareImage(c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
@@ -7,12 +7,14 @@ type Conveyor interface {
 	GetImageContentSignature(imageName string) string
 
 	GetImageNameForLastImageStage(imageName string) string
-	GetImageIDForLastImageStage(imageName string) string
+	GetImageIDForLastImageStage(imageName, platform string) string
 
 	GetImageNameForImageStage(imageName, stageName string) string
-	GetImageIDForImageStage(imageName, stageName string) string
+	GetImageIDForImageStage(imageName, stageName, platform string) string
 
-	GetImportServer(imageName, stageName string) (import_server.ImportServer, error)
+	GetImportServer(imageName, stageName, platform, method string) (import_server.ImportServer, error)
+	GetExternalImageImportServer(externalImageName, platform, method string) (import_server.ImportServer, error)
+	GetExternalImageRepoID(externalImageName string) (string, error)
 	GetLocalGitRepoVirtualMergeOptions() VirtualMergeOptions
 }
 
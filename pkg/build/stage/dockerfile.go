@@ -25,15 +25,16 @@ import (
 	"github.com/flant/werf/pkg/util"
 )
 
-func GenerateDockerfileStage(dockerRunArgs *DockerRunArgs, dockerStages *DockerStages, contextChecksum *ContextChecksum, baseStageOptions *NewBaseStageOptions) *DockerfileStage {
-	return newDockerfileStage(dockerRunArgs, dockerStages, contextChecksum, baseStageOptions)
+func GenerateDockerfileStage(dockerRunArgs *DockerRunArgs, dockerStages *DockerStages, contextChecksum *ContextChecksum, configImportsChecksum string, baseStageOptions *NewBaseStageOptions) *DockerfileStage {
+	return newDockerfileStage(dockerRunArgs, dockerStages, contextChecksum, configImportsChecksum, baseStageOptions)
 }
 
-func newDockerfileStage(dockerRunArgs *DockerRunArgs, dockerStages *DockerStages, contextChecksum *ContextChecksum, baseStageOptions *NewBaseStageOptions) *DockerfileStage {
+func newDockerfileStage(dockerRunArgs *DockerRunArgs, dockerStages *DockerStages, contextChecksum *ContextChecksum, configImportsChecksum string, baseStageOptions *NewBaseStageOptions) *DockerfileStage {
 	s := &DockerfileStage{}
 	s.DockerRunArgs = dockerRunArgs
 	s.DockerStages = dockerStages
 	s.ContextChecksum = contextChecksum
+	s.configImportsChecksum = configImportsChecksum
 	s.BaseStage = newBaseStage(Dockerfile, baseStageOptions)
 
 	return s
@@ -44,6 +45,8 @@ type DockerfileStage struct {
 	*DockerStages
 	*ContextChecksum
 	*BaseStage
+
+	configImportsChecksum string
 }
 
 func NewDockerRunArgs(dockerfilePath, target, context string, buildArgs map[string]interface{}, addHost []string) *DockerRunArgs {
@@ -78,6 +81,19 @@ type DockerStages struct {
 	dockerTargetStageIndex int
 }
 
+// DockerIntermediateStagesNames returns names of the Dockerfile stages preceding the target one,
+// unnamed stages cannot be built separately with the --target option
+func (s *DockerStages) DockerIntermediateStagesNames() []string {
+	var names []string
+	for _, stage := range s.dockerStages[:s.dockerTargetStageIndex] {
+		if stage.Name != "" {
+			names = append(names, stage.Name)
+		}
+	}
+
+	return names
+}
+
 func NewContextChecksum(projectPath string, dockerignorePathMatcher *path_matcher.DockerfileIgnorePathMatcher, localGitRepo *git_repo.Local) *ContextChecksum {
 	return &ContextChecksum{
 		projectPath:             projectPath,
@@ -163,7 +179,12 @@ func (s *DockerfileStage) GetDependencies(_ Conveyor, _, _ container_runtime.Ima
 		}
 	}
 
-	return util.Sha256Hash(stagesDependencies[s.dockerTargetStageIndex]...), nil
+	dependencies := stagesDependencies[s.dockerTargetStageIndex]
+	if s.configImportsChecksum != "" {
+		dependencies = append(dependencies, s.configImportsChecksum)
+	}
+
+	return util.Sha256Hash(dependencies...), nil
 }
 
 func (s *DockerfileStage) dockerfileInstructionDependencies(cmd interface{}) ([]string, []string, error) {
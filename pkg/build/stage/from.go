@@ -25,12 +25,14 @@ func GenerateFromStage(imageBaseConfig *config.StapelImageBase, baseImageRepoId
 		fromImageOrArtifactImageName = imageBaseConfig.FromImageArtifactName
 	}
 
-	return newFromStage(fromImageOrArtifactImageName, baseImageRepoIdOrNone, imageBaseConfig.FromCacheVersion, baseStageOptions)
+	return newFromStage(imageBaseConfig.From, fromImageOrArtifactImageName, baseImageRepoIdOrNone, imageBaseConfig.FromCacheVersion, imageBaseConfig.ConfigImportsChecksum, baseStageOptions)
 }
 
-func newFromStage(fromImageOrArtifactImageName, baseImageRepoIdOrNone, cacheVersion string, baseStageOptions *NewBaseStageOptions) *FromStage {
+func newFromStage(from, fromImageOrArtifactImageName, baseImageRepoIdOrNone, cacheVersion, configImportsChecksum string, baseStageOptions *NewBaseStageOptions) *FromStage {
 	s := &FromStage{}
 	s.cacheVersion = cacheVersion
+	s.configImportsChecksum = configImportsChecksum
+	s.from = from
 	s.fromImageOrArtifactImageName = fromImageOrArtifactImageName
 	s.baseImageRepoIdOrNone = baseImageRepoIdOrNone
 	s.BaseStage = newBaseStage(From, baseStageOptions)
@@ -40,9 +42,11 @@ func newFromStage(fromImageOrArtifactImageName, baseImageRepoIdOrNone, cacheVers
 type FromStage struct {
 	*BaseStage
 
+	from                         string
 	fromImageOrArtifactImageName string
 	baseImageRepoIdOrNone        string
 	cacheVersion                 string
+	configImportsChecksum        string
 }
 
 func (s *FromStage) GetDependencies(c Conveyor, prevImage, _ container_runtime.ImageInterface) (string, error) {
@@ -56,6 +60,10 @@ func (s *FromStage) GetDependencies(c Conveyor, prevImage, _ container_runtime.I
 		args = append(args, s.baseImageRepoIdOrNone)
 	}
 
+	if s.configImportsChecksum != "" {
+		args = append(args, s.configImportsChecksum)
+	}
+
 	for _, mount := range s.configMounts {
 		args = append(args, filepath.ToSlash(filepath.Clean(mount.From)), path.Clean(mount.To), mount.Type)
 	}
@@ -63,7 +71,8 @@ func (s *FromStage) GetDependencies(c Conveyor, prevImage, _ container_runtime.I
 	if s.fromImageOrArtifactImageName != "" {
 		args = append(args, c.GetImageContentSignature(s.fromImageOrArtifactImageName))
 	} else {
-		args = append(args, prevImage.Name())
+		// base image of the platform variant is pinned by digest, the signature depends on the configured name only
+		args = append(args, s.from)
 	}
 
 	return util.Sha256Hash(args...), nil
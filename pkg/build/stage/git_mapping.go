@@ -62,6 +62,8 @@ type GitMapping struct {
 	IncludePaths       []string
 	ExcludePaths       []string
 	StagesDependencies map[StageName][]string
+	DevMode            bool
+	LFS                bool
 
 	PatchesDir           string
 	ContainerPatchesDir  string
@@ -209,6 +211,10 @@ func (gm *GitMapping) getLatestCommit() (string, error) {
 		return gm.GitRepo().LatestBranchCommit(gm.Branch)
 	}
 
+	if localGitRepo, isLocal := gm.GitRepo().(*git_repo.Local); isLocal && gm.DevMode {
+		return localGitRepo.DevHeadCommit()
+	}
+
 	commit, err := gm.GitRepo().HeadCommit()
 	if err != nil {
 		return "", err
@@ -421,6 +427,7 @@ func (gm *GitMapping) baseApplyPatchCommand(fromCommit, toCommit string, prevBui
 		FilterOptions: gm.getRepoFilterOptions(),
 		FromCommit:    fromCommit,
 		ToCommit:      toCommit,
+		LFS:           gm.LFS,
 	}
 
 	patch, err := gm.getOrCreatePatch(patchOpts)
@@ -497,6 +504,7 @@ func (gm *GitMapping) baseApplyPatchCommand(fromCommit, toCommit string, prevBui
 		archiveOpts := git_repo.ArchiveOptions{
 			FilterOptions: gm.getRepoFilterOptions(),
 			Commit:        toCommit,
+			LFS:           gm.LFS,
 		}
 
 		archive, err := gm.getOrCreateArchive(archiveOpts)
@@ -618,6 +626,7 @@ func (gm *GitMapping) baseApplyArchiveCommand(commit string, image container_run
 	archiveOpts := git_repo.ArchiveOptions{
 		FilterOptions: gm.getRepoFilterOptions(),
 		Commit:        commit,
+		LFS:           gm.LFS,
 	}
 
 	archive, err := gm.getOrCreateArchive(archiveOpts)
@@ -661,6 +670,7 @@ func (gm *GitMapping) StageDependenciesChecksum(c Conveyor, stageName StageName)
 		FilterOptions: gm.getRepoFilterOptions(),
 		Paths:         depsPaths,
 		Commit:        commitInfo.Commit,
+		LFS:           gm.LFS,
 	}
 
 	checksum, err := gm.getOrCreateChecksum(checksumOpts)
@@ -694,6 +704,7 @@ func (gm *GitMapping) PatchSize(c Conveyor, fromCommit string) (int64, error) {
 		ToCommit:              toCommitInfo.Commit,
 		WithEntireFileContext: true,
 		WithBinary:            true,
+		LFS:                   gm.LFS,
 	}
 
 	patch, err := gm.getOrCreatePatch(patchOpts)
@@ -742,6 +753,12 @@ func (gm *GitMapping) GetParamshash() string {
 	parts = append(parts, ":::")
 	parts = append(parts, gm.Commit)
 
+	// archives and patches with resolved LFS objects differ from the regular ones
+	if gm.LFS {
+		parts = append(parts, ":::")
+		parts = append(parts, "lfs")
+	}
+
 	for _, part := range parts {
 		_, err = hash.Write([]byte(part))
 		if err != nil {
@@ -780,6 +797,7 @@ func (gm *GitMapping) GetPatchContent(c Conveyor, prevBuiltImage container_runti
 		FilterOptions: gm.getRepoFilterOptions(),
 		FromCommit:    fromCommit,
 		ToCommit:      toCommitInfo.Commit,
+		LFS:           gm.LFS,
 	}
 	patch, err := gm.getOrCreatePatch(patchOpts)
 	if err != nil {
@@ -816,6 +834,7 @@ func (gm *GitMapping) baseIsPatchEmpty(fromCommit, toCommit string) (bool, error
 		FilterOptions: gm.getRepoFilterOptions(),
 		FromCommit:    fromCommit,
 		ToCommit:      toCommit,
+		LFS:           gm.LFS,
 	}
 
 	patch, err := gm.getOrCreatePatch(patchOpts)
@@ -835,6 +854,7 @@ func (gm *GitMapping) IsEmpty(c Conveyor) (bool, error) {
 	archiveOpts := git_repo.ArchiveOptions{
 		FilterOptions: gm.getRepoFilterOptions(),
 		Commit:        commitInfo.Commit,
+		LFS:           gm.LFS,
 	}
 
 	archive, err := gm.getOrCreateArchive(archiveOpts)
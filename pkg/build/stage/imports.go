@@ -3,6 +3,7 @@ package stage
 import (
 	"fmt"
 
+	"github.com/flant/werf/pkg/build/import_server"
 	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/container_runtime"
 	imagePkg "github.com/flant/werf/pkg/image"
@@ -45,17 +46,25 @@ func (s *ImportsStage) GetDependencies(c Conveyor, _, _ container_runtime.ImageI
 	var args []string
 
 	for _, elm := range s.imports {
-		var imgName string
-		if elm.ImageName != "" {
-			imgName = elm.ImageName
-		} else {
-			imgName = elm.ArtifactName
-		}
-
-		if elm.Stage == "" {
-			args = append(args, c.GetImageContentSignature(imgName))
+		if elm.From != "" {
+			repoImageID, err := c.GetExternalImageRepoID(elm.From)
+			if err != nil {
+				return "", fmt.Errorf("unable to get import image %s id: %s", elm.From, err)
+			}
+			args = append(args, repoImageID)
 		} else {
-			args = append(args, c.GetImageStageContentSignature(imgName, elm.Stage))
+			var imgName string
+			if elm.ImageName != "" {
+				imgName = elm.ImageName
+			} else {
+				imgName = elm.ArtifactName
+			}
+
+			if elm.Stage == "" {
+				args = append(args, c.GetImageContentSignature(imgName))
+			} else {
+				args = append(args, c.GetImageStageContentSignature(imgName, elm.Stage))
+			}
 		}
 
 		args = append(args, elm.Add, elm.To)
@@ -66,37 +75,65 @@ func (s *ImportsStage) GetDependencies(c Conveyor, _, _ container_runtime.ImageI
 		if elm.Stage != "" {
 			args = append(args, elm.Stage)
 		}
+
+		if method := elm.GetMethod(); method != config.ImportMethodRsync {
+			args = append(args, method)
+		}
 	}
 
 	return util.Sha256Hash(args...), nil
 }
 
 func (s *ImportsStage) PrepareImage(c Conveyor, _, image container_runtime.ImageInterface) error {
+	addedVolumes := map[string]bool{}
+
 	for _, elm := range s.imports {
 		var importImage string
 		if elm.ImageName != "" {
 			importImage = elm.ImageName
-		} else {
+		} else if elm.ArtifactName != "" {
 			importImage = elm.ArtifactName
+		} else {
+			importImage = elm.From
 		}
 
-		srv, err := c.GetImportServer(importImage, elm.Stage)
+		var srv import_server.ImportServer
+		var err error
+		if elm.From != "" {
+			srv, err = c.GetExternalImageImportServer(elm.From, s.platform, elm.GetMethod())
+		} else {
+			srv, err = c.GetImportServer(importImage, elm.Stage, s.platform, elm.GetMethod())
+		}
 		if err != nil {
 			return fmt.Errorf("unable to get import server for image %q: %s", importImage, err)
 		}
 
-		command := srv.GetCopyCommand(elm)
+		command, err := srv.GetCopyCommand(elm)
+		if err != nil {
+			return fmt.Errorf("unable to get copy command for image %q: %s", importImage, err)
+		}
 		image.Container().AddServiceRunCommands(command)
 
+		for _, volume := range srv.GetVolumes() {
+			if !addedVolumes[volume] {
+				image.Container().RunOptions().AddVolume(volume)
+				addedVolumes[volume] = true
+			}
+		}
+
 		imageServiceCommitChangeOptions := image.Container().ServiceCommitChangeOptions()
 
 		labelKey := imagePkg.WerfImportLabelPrefix + slug.Slug(importImage)
 
 		var labelValue string
-		if elm.Stage == "" {
-			labelValue = c.GetImageIDForLastImageStage(importImage)
+		if elm.From != "" {
+			if labelValue, err = c.GetExternalImageRepoID(elm.From); err != nil {
+				return err
+			}
+		} else if elm.Stage == "" {
+			labelValue = c.GetImageIDForLastImageStage(importImage, s.platform)
 		} else {
-			labelValue = c.GetImageIDForImageStage(importImage, elm.Stage)
+			labelValue = c.GetImageIDForImageStage(importImage, elm.Stage, s.platform)
 		}
 
 		imageServiceCommitChangeOptions.AddLabel(map[string]string{labelKey: labelValue})
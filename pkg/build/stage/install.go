@@ -27,12 +27,17 @@ type InstallStage struct {
 }
 
 func (s *InstallStage) GetDependencies(c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
+	builderChecksum, err := s.builder.InstallChecksum()
+	if err != nil {
+		return "", err
+	}
+
 	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(c, Install)
 	if err != nil {
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.InstallChecksum(), stageDependenciesChecksum), nil
+	return util.Sha256Hash(builderChecksum, stageDependenciesChecksum), nil
 }
 
 func (s *InstallStage) PrepareImage(c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
@@ -27,12 +27,17 @@ type SetupStage struct {
 }
 
 func (s *SetupStage) GetDependencies(c Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
+	builderChecksum, err := s.builder.SetupChecksum()
+	if err != nil {
+		return "", err
+	}
+
 	stageDependenciesChecksum, err := s.getStageDependenciesChecksum(c, Setup)
 	if err != nil {
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.SetupChecksum(), stageDependenciesChecksum), nil
+	return util.Sha256Hash(builderChecksum, stageDependenciesChecksum), nil
 }
 
 func (s *SetupStage) PrepareImage(c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
@@ -12,7 +12,7 @@ import (
 
 func getBuilder(imageBaseConfig *config.StapelImageBase, baseStageOptions *NewBaseStageOptions) builder.Builder {
 	var b builder.Builder
-	extra := &builder.Extra{ContainerWerfPath: baseStageOptions.ContainerWerfDir, TmpPath: baseStageOptions.ImageTmpDir}
+	extra := &builder.Extra{ContainerWerfPath: baseStageOptions.ContainerWerfDir, TmpPath: baseStageOptions.ImageTmpDir, ProjectDir: baseStageOptions.ProjectDir}
 	if imageBaseConfig.Shell != nil {
 		b = builder.NewShellBuilder(imageBaseConfig.Shell, extra)
 	} else if imageBaseConfig.Ansible != nil {
@@ -25,6 +25,7 @@ func getBuilder(imageBaseConfig *config.StapelImageBase, baseStageOptions *NewBa
 func newUserStage(builder builder.Builder, name StageName, baseStageOptions *NewBaseStageOptions) *UserStage {
 	s := &UserStage{}
 	s.builder = builder
+	s.dependenciesGitMapping = baseStageOptions.DependenciesGitMapping
 	s.BaseStage = newBaseStage(name, baseStageOptions)
 	return s
 }
@@ -32,7 +33,18 @@ func newUserStage(builder builder.Builder, name StageName, baseStageOptions *New
 type UserStage struct {
 	*BaseStage
 
-	builder builder.Builder
+	builder                builder.Builder
+	dependenciesGitMapping *GitMapping
+}
+
+func (s *UserStage) hasStageDependencies(name StageName) bool {
+	for _, gitMapping := range s.gitMappings {
+		if len(gitMapping.StagesDependencies[name]) != 0 {
+			return true
+		}
+	}
+
+	return s.dependenciesGitMapping != nil && len(s.dependenciesGitMapping.StagesDependencies[name]) != 0
 }
 
 func (s *UserStage) getStageDependenciesChecksum(c Conveyor, name StageName) (string, error) {
@@ -53,6 +65,22 @@ func (s *UserStage) getStageDependenciesChecksum(c Conveyor, name StageName) (st
 		args = append(args, checksum)
 	}
 
+	// project dependencies checksum is added only if specified to keep signatures of the existing stages
+	if s.dependenciesGitMapping != nil {
+		checksum, err := s.dependenciesGitMapping.StageDependenciesChecksum(c, name)
+		if err != nil {
+			return "", err
+		}
+
+		if debugUserStageChecksum() {
+			logboek.Debug.LogFHighlight("DEBUG: %s stage project dependencies checksum %v\n", name, checksum)
+		}
+
+		if checksum != "" {
+			args = append(args, checksum)
+		}
+	}
+
 	return util.Sha256Hash(args...), nil
 }
 
@@ -0,0 +1,204 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/build/stage"
+	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/vulnerability"
+)
+
+type VulnerabilityScanOptions struct {
+	DatabasePath      string
+	ReportPath        string
+	SeverityThreshold string
+}
+
+type VulnerabilityReport struct {
+	Images []VulnerabilityReportImageRecord
+}
+
+type VulnerabilityReportImageRecord struct {
+	WerfImageName string
+	Platform      string `json:",omitempty"`
+	DockerImageID string
+	Packages      int
+	Findings      []vulnerability.Finding
+}
+
+func NewVulnerabilityScanPhase(c *Conveyor, opts VulnerabilityScanOptions) *VulnerabilityScanPhase {
+	return &VulnerabilityScanPhase{
+		BasePhase:                BasePhase{c},
+		VulnerabilityScanOptions: opts,
+		VulnerabilityReport:      &VulnerabilityReport{},
+	}
+}
+
+type VulnerabilityScanPhase struct {
+	BasePhase
+	VulnerabilityScanOptions
+
+	VulnerabilityReport *VulnerabilityReport
+
+	db        *vulnerability.Database
+	threshold vulnerability.Severity
+}
+
+func (phase *VulnerabilityScanPhase) Name() string {
+	return "vulnerabilityScan"
+}
+
+func (phase *VulnerabilityScanPhase) BeforeImages() error {
+	if phase.SeverityThreshold != "" {
+		threshold, err := vulnerability.ParseSeverity(phase.SeverityThreshold)
+		if err != nil {
+			return fmt.Errorf("bad severity threshold: %s", err)
+		}
+		phase.threshold = threshold
+	}
+
+	db, err := vulnerability.LoadDatabase(phase.DatabasePath)
+	if err != nil {
+		return err
+	}
+	phase.db = db
+
+	return nil
+}
+
+func (phase *VulnerabilityScanPhase) AfterImages() error {
+	return phase.writeReport()
+}
+
+func (phase *VulnerabilityScanPhase) BeforeImageStages(img *Image) error {
+	return nil
+}
+
+func (phase *VulnerabilityScanPhase) OnImageStage(img *Image, stg stage.Interface) error {
+	return nil
+}
+
+// AfterImageStages scans the built image before the following phases (publish or export) process it
+func (phase *VulnerabilityScanPhase) AfterImageStages(img *Image) error {
+	if img.isArtifact {
+		return nil
+	}
+
+	lastStage := img.GetLastNonEmptyStage()
+	if err := phase.Conveyor.StagesManager.FetchStage(lastStage); err != nil {
+		return err
+	}
+
+	record := VulnerabilityReportImageRecord{
+		WerfImageName: img.GetName(),
+		Platform:      img.platform,
+		DockerImageID: lastStage.GetImage().GetStageDescription().Info.ID,
+	}
+
+	if err := logboek.Default.LogProcess("Scanning image for vulnerabilities", logboek.LevelLogProcessOptions{}, func() error {
+		packages, err := phase.scanImage(lastStage.GetImage().Name())
+		if err != nil {
+			return err
+		}
+
+		record.Packages = len(packages)
+		for _, pkg := range packages {
+			record.Findings = append(record.Findings, phase.db.Match(pkg)...)
+		}
+
+		phase.logFindings(record)
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	phase.VulnerabilityReport.Images = append(phase.VulnerabilityReport.Images, record)
+
+	if phase.threshold == "" {
+		return nil
+	}
+
+	var blocking int
+	for _, finding := range record.Findings {
+		if finding.Severity.AtLeast(phase.threshold) {
+			blocking++
+		}
+	}
+
+	if blocking > 0 {
+		if err := phase.writeReport(); err != nil {
+			logboek.LogWarnF("WARNING: %s\n", err)
+		}
+		return fmt.Errorf("image %s has %d vulnerabilities with severity %s or higher", img.LogName(), blocking, phase.threshold)
+	}
+
+	return nil
+}
+
+func (phase *VulnerabilityScanPhase) ImageProcessingShouldBeStopped(img *Image) bool {
+	return false
+}
+
+func (phase *VulnerabilityScanPhase) scanImage(imageName string) ([]vulnerability.Package, error) {
+	tmpDir := filepath.Join(phase.Conveyor.tmpDir, "vulnerability-scan")
+	if err := os.MkdirAll(tmpDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create dir %s: %s", tmpDir, err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, "image.tar")
+	if err := phase.Conveyor.ContainerRuntime.(container_runtime.LocalRuntime).SaveImages(archivePath, imageName); err != nil {
+		return nil, err
+	}
+
+	img, err := tarball.ImageFromPath(archivePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read image %s from %s: %s", imageName, archivePath, err)
+	}
+
+	fs := mutate.Extract(img)
+	defer fs.Close()
+
+	// packages of the unreadable database are not checked at all, so the image cannot be considered as passed the severity threshold
+	return vulnerability.ScanFilesystem(fs, vulnerability.ScanFilesystemOptions{FailOnPackageDatabaseError: phase.threshold != ""})
+}
+
+func (phase *VulnerabilityScanPhase) logFindings(record VulnerabilityReportImageRecord) {
+	logboek.Default.LogFDetails("packages: %d\n", record.Packages)
+	logboek.Default.LogFDetails("vulnerabilities: %d\n", len(record.Findings))
+
+	for _, finding := range record.Findings {
+		fixed := "no fix"
+		if finding.FixedVersion != "" {
+			fixed = fmt.Sprintf("fixed in %s", finding.FixedVersion)
+		}
+		logboek.Info.LogF("%s %s %s %s (%s, %s)\n", finding.Severity, finding.VulnerabilityID, finding.Package, finding.InstalledVersion, fixed, finding.Path)
+	}
+}
+
+func (phase *VulnerabilityScanPhase) writeReport() error {
+	if phase.ReportPath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(phase.VulnerabilityReport, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to prepare vulnerability report: %s", err)
+	}
+
+	if err := ioutil.WriteFile(phase.ReportPath, append(data, []byte("\n")...), 0644); err != nil {
+		return fmt.Errorf("unable to write vulnerability report to %s: %s", phase.ReportPath, err)
+	}
+
+	return nil
+}
@@ -60,6 +60,8 @@ func newImagesCleanupManager(imagesRepo storage.ImagesRepo, options ImagesCleanu
 
 type imagesCleanupManager struct {
 	imagesRepoImages *map[string][]*image.Info
+	// deletedRepoImageList contains images removed by the cleanup (or which would be removed in the dry run mode)
+	deletedRepoImageList []*image.Info
 
 	ImagesRepo                storage.ImagesRepo
 	ImageNameList             []string
@@ -118,8 +120,10 @@ func (m *imagesCleanupManager) setImagesRepoImages(repoImages map[string][]*imag
 func (m *imagesCleanupManager) run() error {
 	imagesCleanupLockName := fmt.Sprintf("images-cleanup.%s", m.ImagesRepo.String())
 	return werf.WithHostLock(imagesCleanupLockName, lockgate.AcquireOptions{Timeout: time.Second * 600}, func() error {
-		if err := m.initRepoImages(); err != nil {
-			return err
+		if m.imagesRepoImages == nil {
+			if err := m.initRepoImages(); err != nil {
+				return err
+			}
 		}
 
 		repoImagesToCleanup := m.getImagesRepoImages()
@@ -292,7 +296,7 @@ Loop:
 			"Removed tags by nonexistent git-tag policy",
 			logboek.LevelLogBlockOptions{},
 			func() error {
-				return deleteRepoImageInImagesRepo(m.ImagesRepo, m.DryRun, nonexistentGitTagRepoImages...)
+				return m.deleteRepoImages(nonexistentGitTagRepoImages...)
 			},
 		); err != nil {
 			return nil, err
@@ -306,7 +310,7 @@ Loop:
 			"Removed tags by nonexistent git-branch policy",
 			logboek.LevelLogBlockOptions{},
 			func() error {
-				return deleteRepoImageInImagesRepo(m.ImagesRepo, m.DryRun, nonexistentGitBranchRepoImages...)
+				return m.deleteRepoImages(nonexistentGitBranchRepoImages...)
 			},
 		); err != nil {
 			return nil, err
@@ -320,7 +324,7 @@ Loop:
 			"Removed tags by nonexistent git-commit policy",
 			logboek.LevelLogBlockOptions{},
 			func() error {
-				return deleteRepoImageInImagesRepo(m.ImagesRepo, m.DryRun, nonexistentGitCommitRepoImages...)
+				return m.deleteRepoImages(nonexistentGitCommitRepoImages...)
 			},
 		); err != nil {
 			return nil, err
@@ -332,6 +336,16 @@ Loop:
 	return repoImages, nil
 }
 
+func (m *imagesCleanupManager) deleteRepoImages(repoImageList ...*image.Info) error {
+	if err := deleteRepoImageInImagesRepo(m.ImagesRepo, m.DryRun, repoImageList...); err != nil {
+		return err
+	}
+
+	m.deletedRepoImageList = append(m.deletedRepoImageList, repoImageList...)
+
+	return nil
+}
+
 func repoImageMetaTagMatch(imageMetaTag string, matches ...string) bool {
 	for _, match := range matches {
 		if imageMetaTag == slug.DockerTag(match) {
@@ -439,7 +453,7 @@ func (m *imagesCleanupManager) repoImagesCleanupByPolicy(repoImages, repoImagesW
 			logBlockMessage,
 			logboek.LevelLogBlockOptions{},
 			func() error {
-				return deleteRepoImageInImagesRepo(m.ImagesRepo, m.DryRun, expiredRepoImages...)
+				return m.deleteRepoImages(expiredRepoImages...)
 			},
 		); err != nil {
 			return nil, err
@@ -456,7 +470,7 @@ func (m *imagesCleanupManager) repoImagesCleanupByPolicy(repoImages, repoImagesW
 			logBlockMessage,
 			logboek.LevelLogBlockOptions{},
 			func() error {
-				return deleteRepoImageInImagesRepo(m.ImagesRepo, m.DryRun, excessImagesByLimit...)
+				return m.deleteRepoImages(excessImagesByLimit...)
 			},
 		); err != nil {
 			return nil, err
@@ -3,6 +3,7 @@ package cleaning
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -100,47 +101,145 @@ func (m *stagesCleanupManager) run() error {
 			return err
 		}
 
-		var stagesImageList []*image.Info
-		stagesByImageName := map[string]*image.StageDescription{}
-		for _, stageDesc := range stages {
-			stagesImageList = append(stagesImageList, stageDesc.Info)
-			stagesByImageName[stageDesc.Info.Name] = stageDesc
+		repoImageList, err := m.getOrInitImagesRepoImageList()
+		if err != nil {
+			return err
 		}
 
-		repoImageList, err := m.getOrInitImagesRepoImageList()
+		stagesToDeleteList := selectStagesToCleanup(stages, repoImageList)
+
+		if err := deleteStageInStagesStorage(m.StagesManager, deleteImageOptions, m.DryRun, stagesToDeleteList...); err != nil {
+			return err
+		}
+
+		return m.deleteUnusedDockerfileCaches(stages, stagesToDeleteList)
+	})
+}
+
+func (m *stagesCleanupManager) deleteUnusedDockerfileCaches(stages, deletedStages []*image.StageDescription) error {
+	dockerfileCaches, err := m.StagesManager.StagesStorage.GetDockerfileCaches(m.ProjectName)
+	if err != nil {
+		return err
+	}
+
+	cacheSignatures := map[string]string{}
+	for _, imageName := range dockerfileCaches {
+		signature, err := m.StagesManager.StagesStorage.GetDockerfileCacheStageSignature(m.ProjectName, imageName)
 		if err != nil {
 			return err
 		}
+		cacheSignatures[imageName] = signature
+	}
+
+	for _, imageName := range selectDockerfileCachesToCleanup(cacheSignatures, stages, deletedStages) {
+		if !m.DryRun {
+			if err := m.StagesManager.StagesStorage.RmDockerfileCache(m.ProjectName, imageName); err != nil {
+				return err
+			}
+		}
 
-		for _, repoImage := range repoImageList {
-			stagesImageList = exceptRepoImageAndRelativesByImageID(stagesImageList, repoImage.ParentID)
+		logTag := imageName
+		if logTag == "" {
+			logTag = storage.NamelessImageRecordTag
 		}
 
-		var repoImageListToExcept []*image.Info
-		if os.Getenv("WERF_DISABLE_STAGES_CLEANUP_DATE_PERIOD_POLICY") == "" {
-			for _, repoImage := range stagesImageList {
-				if time.Now().Unix()-repoImage.GetCreatedAt().Unix() < stagesCleanupDefaultIgnorePeriodPolicy {
-					repoImageListToExcept = append(repoImageListToExcept, repoImage)
-				}
+		logboek.Default.LogFDetails("  dockerfile layers cache: %s\n", logTag)
+		logboek.LogOptionalLn()
+	}
+
+	return nil
+}
+
+// selectDockerfileCachesToCleanup returns the dockerfile layers caches stored by the stages which no longer exist in the stages storage
+func selectDockerfileCachesToCleanup(cacheSignatures map[string]string, stages, deletedStages []*image.StageDescription) []string {
+	deletedStagesImageNames := map[string]bool{}
+	for _, stageDesc := range deletedStages {
+		deletedStagesImageNames[stageDesc.Info.Name] = true
+	}
+
+	existingSignatures := map[string]bool{}
+	for _, stageDesc := range stages {
+		if !deletedStagesImageNames[stageDesc.Info.Name] {
+			existingSignatures[stageDesc.StageID.Signature] = true
+		}
+	}
+
+	var res []string
+	for imageName, signature := range cacheSignatures {
+		if !existingSignatures[signature] {
+			res = append(res, imageName)
+		}
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// selectStagesToCleanup returns stages which are not used by the images repo images and are older than the ignore period
+func selectStagesToCleanup(stages []*image.StageDescription, repoImageList []*image.Info) []*image.StageDescription {
+	var stagesImageList []*image.Info
+	stagesByImageName := map[string]*image.StageDescription{}
+	for _, stageDesc := range stages {
+		stagesImageList = append(stagesImageList, stageDesc.Info)
+		stagesByImageName[stageDesc.Info.Name] = stageDesc
+	}
+
+	for _, repoImage := range repoImageList {
+		stagesImageList = exceptRepoImageAndRelativesByImageID(stagesImageList, repoImage.ParentID)
+	}
+
+	stagesImageList = exceptPlatformVariantsOfUsedStages(stages, stagesImageList)
+
+	var repoImageListToExcept []*image.Info
+	if os.Getenv("WERF_DISABLE_STAGES_CLEANUP_DATE_PERIOD_POLICY") == "" {
+		for _, repoImage := range stagesImageList {
+			if time.Now().Unix()-repoImage.GetCreatedAt().Unix() < stagesCleanupDefaultIgnorePeriodPolicy {
+				repoImageListToExcept = append(repoImageListToExcept, repoImage)
 			}
 		}
+	}
 
-		stagesImageList = exceptRepoImageList(stagesImageList, repoImageListToExcept...)
+	stagesImageList = exceptRepoImageList(stagesImageList, repoImageListToExcept...)
 
-		var stagesToDeleteList []*image.StageDescription
-		for _, imgInfo := range stagesImageList {
-			if stagesByImageName[imgInfo.Name] == nil || stagesByImageName[imgInfo.Name].Info != imgInfo {
-				panic(fmt.Sprintf("inconsistent state detected: %#v != %#v", stagesByImageName[imgInfo.Name].Info, imgInfo))
+	var stagesToDeleteList []*image.StageDescription
+	for _, imgInfo := range stagesImageList {
+		if stagesByImageName[imgInfo.Name] == nil || stagesByImageName[imgInfo.Name].Info != imgInfo {
+			panic(fmt.Sprintf("inconsistent state detected: %#v != %#v", stagesByImageName[imgInfo.Name].Info, imgInfo))
+		}
+		stagesToDeleteList = append(stagesToDeleteList, stagesByImageName[imgInfo.Name])
+	}
+
+	return stagesToDeleteList
+}
+
+// exceptPlatformVariantsOfUsedStages keeps all platform variants of the stage if any of them is used,
+// because the manifest list in the images repo refers to the variants not covered by the repo image parent
+func exceptPlatformVariantsOfUsedStages(stages []*image.StageDescription, stagesImageList []*image.Info) []*image.Info {
+	for {
+		usedLogicalSignatures := map[string]bool{}
+		for _, stageDesc := range stages {
+			logicalSignature, platform := image.ParsePlatformSignature(stageDesc.StageID.Signature)
+			if platform != "" && findRepoImageByImageID(stagesImageList, stageDesc.Info.ID) == nil {
+				usedLogicalSignatures[logicalSignature] = true
 			}
-			stagesToDeleteList = append(stagesToDeleteList, stagesByImageName[imgInfo.Name])
 		}
 
-		if err := deleteStageInStagesStorage(m.StagesManager, deleteImageOptions, m.DryRun, stagesToDeleteList...); err != nil {
-			return err
+		var variantsToExcept []*image.Info
+		for _, stageDesc := range stages {
+			logicalSignature, platform := image.ParsePlatformSignature(stageDesc.StageID.Signature)
+			if platform != "" && usedLogicalSignatures[logicalSignature] && findRepoImageByImageID(stagesImageList, stageDesc.Info.ID) != nil {
+				variantsToExcept = append(variantsToExcept, stageDesc.Info)
+			}
 		}
 
-		return nil
-	})
+		if len(variantsToExcept) == 0 {
+			return stagesImageList
+		}
+
+		for _, variant := range variantsToExcept {
+			stagesImageList = exceptRepoImageAndRelativesByRepoImage(stagesImageList, variant)
+		}
+	}
 }
 
 func exceptRepoImageAndRelativesByImageID(repoImageList []*image.Info, imageID string) []*image.Info {
@@ -98,6 +98,30 @@ func (m *stagesPurgeManager) run() error {
 		}
 		logboek.Default.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
 
+		logboek.Default.LogProcessStart("Deleting dockerfile layers caches", logboek.LevelLogProcessStartOptions{})
+		dockerfileCaches, err := m.StagesManager.StagesStorage.GetDockerfileCaches(m.ProjectName)
+		if err != nil {
+			logboek.Default.LogProcessFail(logboek.LevelLogProcessFailOptions{})
+			return err
+		}
+
+		for _, imageName := range dockerfileCaches {
+			if !m.DryRun {
+				if err := m.StagesManager.StagesStorage.RmDockerfileCache(m.ProjectName, imageName); err != nil {
+					return err
+				}
+			}
+
+			logTag := imageName
+			if logTag == "" {
+				logTag = storage.NamelessImageRecordTag
+			}
+
+			logboek.Default.LogFDetails("  image: %s\n", logTag)
+			logboek.LogOptionalLn()
+		}
+		logboek.Default.LogProcessEnd(logboek.LevelLogProcessEndOptions{})
+
 		return nil
 	})
 }
@@ -0,0 +1,271 @@
+package cleaning
+
+import (
+	"time"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/stages_manager"
+	"github.com/flant/werf/pkg/storage"
+)
+
+const (
+	// UnusedStagesGroup contains stages which are not used by any image in the images repo
+	UnusedStagesGroup = "<unused>"
+	// UnknownStageName is used for stages built by werf versions which do not set werf-stage-name label
+	UnknownStageName = "<unknown>"
+)
+
+var ageGroups = []struct {
+	Name   string
+	MaxAge time.Duration
+}{
+	{Name: "< 1 day", MaxAge: 24 * time.Hour},
+	{Name: "1-7 days", MaxAge: 7 * 24 * time.Hour},
+	{Name: "7-30 days", MaxAge: 30 * 24 * time.Hour},
+	{Name: "30-90 days", MaxAge: 90 * 24 * time.Hour},
+	{Name: "> 90 days"},
+}
+
+// SizeStats contains the number of images and their size in bytes.
+// TotalSize is the sum of the image sizes including parent layers, UniqueSize counts every layer once:
+// stage size without its parent stage for stages and size of distinct images (tags of the same image are counted once) for images.
+type SizeStats struct {
+	Count      int   `json:"count"`
+	TotalSize  int64 `json:"totalSize"`
+	UniqueSize int64 `json:"uniqueSize"`
+}
+
+type AgeGroupStats struct {
+	Name string `json:"name"`
+	SizeStats
+}
+
+type StagesStats struct {
+	Total SizeStats `json:"total"`
+	// ByImage contains stages used by images repo images of each werf image (including imports), UnusedStagesGroup contains the rest
+	ByImage map[string]*SizeStats `json:"byImage"`
+	// ByStage contains stages grouped by the stage name (from, install, setup, etc.)
+	ByStage map[string]*SizeStats `json:"byStage"`
+	ByAge   []*AgeGroupStats      `json:"byAge"`
+	// Reclaimable contains stages which would be removed by the stages cleanup after the images cleanup under the current policies
+	Reclaimable SizeStats `json:"reclaimable"`
+}
+
+type ImagesStats struct {
+	Total   SizeStats             `json:"total"`
+	ByImage map[string]*SizeStats `json:"byImage"`
+	ByAge   []*AgeGroupStats      `json:"byAge"`
+	// Reclaimable contains tags which would be removed by the images cleanup under the current policies
+	Reclaimable SizeStats `json:"reclaimable"`
+}
+
+// StagesStatsOptions contains the images cleanup options which are used to estimate the images repo images remaining after the cleanup
+type StagesStatsOptions struct {
+	ImagesCleanupOptions
+}
+
+func GetStagesStats(imagesRepo storage.ImagesRepo, stagesManager *stages_manager.StagesManager, options StagesStatsOptions) (*StagesStats, error) {
+	stages, err := stagesManager.GetAllStages()
+	if err != nil {
+		return nil, err
+	}
+
+	repoImages, err := selectRepoImagesFromImagesRepo(imagesRepo, options.ImageNameList)
+	if err != nil {
+		return nil, err
+	}
+
+	var stagesImageList []*image.Info
+	for _, stageDesc := range stages {
+		stagesImageList = append(stagesImageList, stageDesc.Info)
+	}
+
+	ownSize := stagesOwnSizeFunc(stagesImageList)
+
+	stats := &StagesStats{
+		Total:   newSizeStats(stagesImageList, ownSize),
+		ByImage: map[string]*SizeStats{},
+		ByStage: map[string]*SizeStats{},
+		ByAge:   newAgeGroupsStats(stagesImageList, ownSize),
+	}
+
+	unusedStagesImageList := stagesImageList
+	for imageName, repoImageList := range repoImages {
+		var usedStagesImageList []*image.Info
+		for _, repoImage := range repoImageList {
+			for _, info := range repoImageRelatives(stagesImageList, repoImage) {
+				if findRepoImageByImageID(usedStagesImageList, info.ID) == nil {
+					usedStagesImageList = append(usedStagesImageList, info)
+				}
+			}
+			unusedStagesImageList = exceptRepoImageAndRelativesByImageID(unusedStagesImageList, repoImage.ParentID)
+		}
+
+		imageStats := newSizeStats(usedStagesImageList, ownSize)
+		stats.ByImage[imageName] = &imageStats
+	}
+
+	if len(unusedStagesImageList) != 0 {
+		unusedStats := newSizeStats(unusedStagesImageList, ownSize)
+		stats.ByImage[UnusedStagesGroup] = &unusedStats
+	}
+
+	stagesImageListByName := map[string][]*image.Info{}
+	for _, info := range stagesImageList {
+		stageName := info.Labels[image.WerfStageNameLabel]
+		if stageName == "" {
+			stageName = UnknownStageName
+		}
+		stagesImageListByName[stageName] = append(stagesImageListByName[stageName], info)
+	}
+
+	for stageName, infos := range stagesImageListByName {
+		stageStats := newSizeStats(infos, ownSize)
+		stats.ByStage[stageName] = &stageStats
+	}
+
+	var reclaimableImageList []*image.Info
+	m, err := estimateImagesCleanup(imagesRepo, repoImages, options.ImagesCleanupOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stageDesc := range selectStagesToCleanup(stages, flattenRepoImages(m.getImagesRepoImages())) {
+		reclaimableImageList = append(reclaimableImageList, stageDesc.Info)
+	}
+	stats.Reclaimable = newSizeStats(reclaimableImageList, ownSize)
+
+	return stats, nil
+}
+
+// GetImagesStats estimates the images cleanup in the dry run mode, options.DryRun is ignored
+func GetImagesStats(imagesRepo storage.ImagesRepo, options ImagesCleanupOptions) (*ImagesStats, error) {
+	repoImages, err := selectRepoImagesFromImagesRepo(imagesRepo, options.ImageNameList)
+	if err != nil {
+		return nil, err
+	}
+
+	repoImageList := flattenRepoImages(repoImages)
+
+	stats := &ImagesStats{
+		Total:   newSizeStats(repoImageList, imageSize),
+		ByImage: map[string]*SizeStats{},
+		ByAge:   newAgeGroupsStats(repoImageList, imageSize),
+	}
+
+	for imageName, imageRepoImageList := range repoImages {
+		imageStats := newSizeStats(imageRepoImageList, imageSize)
+		stats.ByImage[imageName] = &imageStats
+	}
+
+	m, err := estimateImagesCleanup(imagesRepo, repoImages, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// image is reclaimed only when all its tags are removed
+	keptIDs := map[string]bool{}
+	for _, repoImage := range exceptRepoImageList(repoImageList, m.deletedRepoImageList...) {
+		keptIDs[repoImage.ID] = true
+	}
+
+	stats.Reclaimable = newSizeStats(m.deletedRepoImageList, func(info *image.Info) int64 {
+		if keptIDs[info.ID] {
+			return 0
+		}
+		return info.Size
+	})
+
+	return stats, nil
+}
+
+// estimateImagesCleanup runs the images cleanup in the dry run mode,
+// the manager contains the removed images and the images which would remain in the images repo
+func estimateImagesCleanup(imagesRepo storage.ImagesRepo, repoImages map[string][]*image.Info, options ImagesCleanupOptions) (*imagesCleanupManager, error) {
+	// cleanup changes the lists of the passed images
+	repoImagesToCleanup := map[string][]*image.Info{}
+	for imageName, imageRepoImageList := range repoImages {
+		repoImagesToCleanup[imageName] = append([]*image.Info{}, imageRepoImageList...)
+	}
+
+	options.DryRun = true
+	m := newImagesCleanupManager(imagesRepo, options)
+	m.setImagesRepoImages(repoImagesToCleanup)
+
+	if err := logboek.Default.LogProcess("Estimating images cleanup", logboek.LevelLogProcessOptions{}, m.run); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func newSizeStats(infos []*image.Info, uniqueSize func(info *image.Info) int64) SizeStats {
+	stats := SizeStats{}
+	countedIDs := map[string]bool{}
+	for _, info := range infos {
+		stats.Count++
+		stats.TotalSize += info.Size
+
+		if !countedIDs[info.ID] {
+			countedIDs[info.ID] = true
+			stats.UniqueSize += uniqueSize(info)
+		}
+	}
+
+	return stats
+}
+
+func newAgeGroupsStats(infos []*image.Info, uniqueSize func(info *image.Info) int64) []*AgeGroupStats {
+	infosByGroup := make([][]*image.Info, len(ageGroups))
+	for _, info := range infos {
+		age := time.Since(info.GetCreatedAt())
+		for ind, group := range ageGroups {
+			if group.MaxAge == 0 || age < group.MaxAge {
+				infosByGroup[ind] = append(infosByGroup[ind], info)
+				break
+			}
+		}
+	}
+
+	var res []*AgeGroupStats
+	for ind, group := range ageGroups {
+		res = append(res, &AgeGroupStats{Name: group.Name, SizeStats: newSizeStats(infosByGroup[ind], uniqueSize)})
+	}
+
+	return res
+}
+
+func imageSize(info *image.Info) int64 {
+	return info.Size
+}
+
+// stagesOwnSizeFunc returns the size of the stage without the size of its parent stage
+func stagesOwnSizeFunc(stagesImageList []*image.Info) func(info *image.Info) int64 {
+	stagesByID := map[string]*image.Info{}
+	for _, info := range stagesImageList {
+		stagesByID[info.ID] = info
+	}
+
+	return func(info *image.Info) int64 {
+		if parent, hasKey := stagesByID[info.ParentID]; hasKey && info.ParentID != "" && parent.Size <= info.Size {
+			return info.Size - parent.Size
+		}
+		return info.Size
+	}
+}
+
+// repoImageRelatives returns stages used by the repo image: parent stages chain and imported stages
+func repoImageRelatives(stagesImageList []*image.Info, repoImage *image.Info) []*image.Info {
+	unrelatedImageList := exceptRepoImageAndRelativesByImageID(stagesImageList, repoImage.ParentID)
+
+	var relatives []*image.Info
+	for _, info := range stagesImageList {
+		if findRepoImageByImageID(unrelatedImageList, info.ID) == nil {
+			relatives = append(relatives, info)
+		}
+	}
+
+	return relatives
+}
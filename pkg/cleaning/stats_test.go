@@ -0,0 +1,381 @@
+package cleaning
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/stages_manager"
+	"github.com/flant/werf/pkg/storage"
+	"github.com/flant/werf/pkg/tag_strategy"
+	"github.com/flant/werf/pkg/werf"
+)
+
+type imagesRepoStub struct {
+	storage.ImagesRepo
+
+	repoImages map[string][]*image.Info
+}
+
+func (r *imagesRepoStub) SelectRepoImages(_ []string, _ func(string, *image.Info, error) (bool, error)) (map[string][]*image.Info, error) {
+	return r.repoImages, nil
+}
+
+func (r *imagesRepoStub) String() string {
+	return "stub"
+}
+
+type stagesStorageStub struct {
+	storage.StagesStorage
+
+	stages []*image.StageDescription
+}
+
+func (s *stagesStorageStub) GetAllStages(_ string) ([]image.StageID, error) {
+	var stageIDs []image.StageID
+	for _, stageDesc := range s.stages {
+		stageIDs = append(stageIDs, *stageDesc.StageID)
+	}
+	return stageIDs, nil
+}
+
+func (s *stagesStorageStub) GetStageDescription(_, signature string, uniqueID int64) (*image.StageDescription, error) {
+	for _, stageDesc := range s.stages {
+		if stageDesc.StageID.Signature == signature && stageDesc.StageID.UniqueID == uniqueID {
+			return stageDesc, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *stagesStorageStub) ConstructStageImageName(projectName, signature string, uniqueID int64) string {
+	return fmt.Sprintf("%s:%s-%d", projectName, signature, uniqueID)
+}
+
+func (s *stagesStorageStub) String() string {
+	return "stub"
+}
+
+type gitRepoStub struct {
+	commits []string
+}
+
+func (r gitRepoStub) IsCommitExists(commit string) (bool, error) {
+	for _, c := range r.commits {
+		if c == commit {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r gitRepoStub) TagsList() ([]string, error) {
+	return nil, nil
+}
+
+func (r gitRepoStub) RemoteBranchesList() ([]string, error) {
+	return nil, nil
+}
+
+var statsTestCreatedAt = time.Now().Add(-10 * 24 * time.Hour)
+
+func newStageDescription(signature, id, parentID, stageName string, size int64, createdAt time.Time, labels map[string]string) *image.StageDescription {
+	info := &image.Info{
+		Name:              fmt.Sprintf("project:%s-1", signature),
+		ID:                id,
+		ParentID:          parentID,
+		Labels:            map[string]string{image.WerfStageNameLabel: stageName},
+		Size:              size,
+		CreatedAtUnixNano: createdAt.UnixNano(),
+	}
+	for k, v := range labels {
+		info.Labels[k] = v
+	}
+
+	return &image.StageDescription{StageID: &image.StageID{Signature: signature, UniqueID: 1}, Info: info}
+}
+
+func newRepoImage(tag, id, parentID, commit string, size int64, labels map[string]string) *image.Info {
+	info := &image.Info{
+		Name:     "images-repo/app:" + tag,
+		Tag:      tag,
+		ID:       id,
+		ParentID: parentID,
+		Labels: map[string]string{
+			image.WerfTagStrategyLabel: string(tag_strategy.GitCommit),
+			image.WerfImageTagLabel:    commit,
+		},
+		Size:              size,
+		CreatedAtUnixNano: statsTestCreatedAt.UnixNano(),
+	}
+	for k, v := range labels {
+		info.Labels[k] = v
+	}
+
+	return info
+}
+
+// newStatsTestStages returns the stages tree:
+// from <- install <- setup (imports artifact), from <- install2 and the orphan stage
+func newStatsTestStages() []*image.StageDescription {
+	return []*image.StageDescription{
+		newStageDescription("from", "from", "", "from", 100, statsTestCreatedAt, nil),
+		newStageDescription("install", "install", "from", "install", 150, statsTestCreatedAt, nil),
+		newStageDescription("setup", "setup", "install", "setup", 180, statsTestCreatedAt, map[string]string{image.WerfImportLabelPrefix + "artifact": "artifact"}),
+		newStageDescription("install2", "install2", "from", "install", 160, statsTestCreatedAt, nil),
+		newStageDescription("artifact", "artifact", "", "setup", 70, statsTestCreatedAt, nil),
+		newStageDescription("orphan", "orphan", "", "from", 50, statsTestCreatedAt, nil),
+	}
+}
+
+func newStatsTestRepoImages() map[string][]*image.Info {
+	return map[string][]*image.Info{
+		"app": {
+			newRepoImage("v1", "app-v1", "setup", "existing", 180, nil),
+			newRepoImage("v1-removed", "app-v1", "setup", "removed", 180, nil),
+			newRepoImage("old", "app-old", "install2", "old", 160, nil),
+		},
+	}
+}
+
+func initStatsTestWerf(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "werf-cleaning-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := image.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	return func() { os.RemoveAll(dir) }
+}
+
+func stageSignatures(stages []*image.StageDescription) []string {
+	var signatures []string
+	for _, stageDesc := range stages {
+		signatures = append(signatures, stageDesc.StageID.Signature)
+	}
+	sort.Strings(signatures)
+	return signatures
+}
+
+func TestSelectStagesToCleanup(t *testing.T) {
+	recentCreatedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name          string
+		stages        []*image.StageDescription
+		repoImageList []*image.Info
+		expected      []string
+	}{
+		{
+			name:     "without repo images all stages are removed",
+			stages:   newStatsTestStages(),
+			expected: []string{"artifact", "from", "install", "install2", "orphan", "setup"},
+		},
+		{
+			name:          "parents chain and imports of the repo image are kept",
+			stages:        newStatsTestStages(),
+			repoImageList: []*image.Info{newRepoImage("v1", "app-v1", "setup", "existing", 180, nil)},
+			expected:      []string{"install2", "orphan"},
+		},
+		{
+			name:          "shared parent stage is kept while any child is used",
+			stages:        newStatsTestStages(),
+			repoImageList: []*image.Info{newRepoImage("old", "app-old", "install2", "old", 160, nil)},
+			expected:      []string{"artifact", "install", "orphan", "setup"},
+		},
+		{
+			name: "recent stages are kept",
+			stages: []*image.StageDescription{
+				newStageDescription("old", "old", "", "from", 100, statsTestCreatedAt, nil),
+				newStageDescription("recent", "recent", "", "from", 100, recentCreatedAt, nil),
+			},
+			expected: []string{"old"},
+		},
+		{
+			name: "all platform variants of the used stage are kept",
+			stages: []*image.StageDescription{
+				newStageDescription(image.PlatformSignature("sig", "linux/amd64"), "amd64", "", "from", 100, statsTestCreatedAt, nil),
+				newStageDescription(image.PlatformSignature("sig", "linux/arm64"), "arm64", "", "from", 100, statsTestCreatedAt, nil),
+				newStageDescription(image.PlatformSignature("unused", "linux/arm64"), "unused", "", "from", 100, statsTestCreatedAt, nil),
+			},
+			repoImageList: []*image.Info{newRepoImage("v1", "app-v1", "amd64", "existing", 100, nil)},
+			expected:      []string{image.PlatformSignature("unused", "linux/arm64")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := stageSignatures(selectStagesToCleanup(tt.stages, tt.repoImageList))
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected stages %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetImagesStats(t *testing.T) {
+	defer initStatsTestWerf(t)()
+
+	tests := []struct {
+		name                string
+		commits             []string
+		policies            ImagesCleanupPolicies
+		expectedTotal       SizeStats
+		expectedReclaimable SizeStats
+	}{
+		{
+			name:                "all commits exist",
+			commits:             []string{"existing", "removed", "old"},
+			expectedTotal:       SizeStats{Count: 3, TotalSize: 520, UniqueSize: 340},
+			expectedReclaimable: SizeStats{},
+		},
+		{
+			name:          "tags of nonexistent commits are removed, the size of the kept image is not reclaimable",
+			commits:       []string{"existing"},
+			expectedTotal: SizeStats{Count: 3, TotalSize: 520, UniqueSize: 340},
+			// v1-removed tag shares the image with the kept v1 tag
+			expectedReclaimable: SizeStats{Count: 2, TotalSize: 340, UniqueSize: 160},
+		},
+		{
+			name:                "tags are removed by the limit policy",
+			commits:             []string{"existing", "removed", "old"},
+			policies:            ImagesCleanupPolicies{GitCommitStrategyHasLimit: true, GitCommitStrategyLimit: 0},
+			expectedTotal:       SizeStats{Count: 3, TotalSize: 520, UniqueSize: 340},
+			expectedReclaimable: SizeStats{Count: 3, TotalSize: 520, UniqueSize: 340},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imagesRepo := &imagesRepoStub{repoImages: newStatsTestRepoImages()}
+
+			stats, err := GetImagesStats(imagesRepo, ImagesCleanupOptions{
+				ImageNameList: []string{"app"},
+				LocalGit:      gitRepoStub{commits: tt.commits},
+				WithoutKube:   true,
+				Policies:      tt.policies,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if stats.Total != tt.expectedTotal {
+				t.Errorf("expected total %+v, got %+v", tt.expectedTotal, stats.Total)
+			}
+
+			if stats.Reclaimable != tt.expectedReclaimable {
+				t.Errorf("expected reclaimable %+v, got %+v", tt.expectedReclaimable, stats.Reclaimable)
+			}
+
+			if expected := tt.expectedTotal; *stats.ByImage["app"] != expected {
+				t.Errorf("expected app image stats %+v, got %+v", expected, *stats.ByImage["app"])
+			}
+		})
+	}
+}
+
+func TestGetStagesStats(t *testing.T) {
+	defer initStatsTestWerf(t)()
+
+	tests := []struct {
+		name                string
+		commits             []string
+		expectedByImage     map[string]SizeStats
+		expectedReclaimable SizeStats
+	}{
+		{
+			name:    "stages of all repo images are used",
+			commits: []string{"existing", "removed", "old"},
+			expectedByImage: map[string]SizeStats{
+				// from, install, setup, artifact and install2
+				"app":             {Count: 5, TotalSize: 660, UniqueSize: 310},
+				UnusedStagesGroup: {Count: 1, TotalSize: 50, UniqueSize: 50},
+			},
+			expectedReclaimable: SizeStats{Count: 1, TotalSize: 50, UniqueSize: 50},
+		},
+		{
+			name:    "stages used only by the tags removed by the images cleanup are reclaimable",
+			commits: []string{"existing"},
+			expectedByImage: map[string]SizeStats{
+				"app":             {Count: 5, TotalSize: 660, UniqueSize: 310},
+				UnusedStagesGroup: {Count: 1, TotalSize: 50, UniqueSize: 50},
+			},
+			// install2 (own size 60) and orphan
+			expectedReclaimable: SizeStats{Count: 2, TotalSize: 210, UniqueSize: 110},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imagesRepo := &imagesRepoStub{repoImages: newStatsTestRepoImages()}
+			stagesManager := &stages_manager.StagesManager{
+				ProjectName:   "project",
+				StagesStorage: &stagesStorageStub{stages: newStatsTestStages()},
+			}
+
+			stats, err := GetStagesStats(imagesRepo, stagesManager, StagesStatsOptions{
+				ImagesCleanupOptions: ImagesCleanupOptions{
+					ImageNameList: []string{"app"},
+					LocalGit:      gitRepoStub{commits: tt.commits},
+					WithoutKube:   true,
+				},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if expected := (SizeStats{Count: 6, TotalSize: 710, UniqueSize: 360}); stats.Total != expected {
+				t.Errorf("expected total %+v, got %+v", expected, stats.Total)
+			}
+
+			byImage := map[string]SizeStats{}
+			for imageName, imageStats := range stats.ByImage {
+				byImage[imageName] = *imageStats
+			}
+			if !reflect.DeepEqual(byImage, tt.expectedByImage) {
+				t.Errorf("expected stats by image %+v, got %+v", tt.expectedByImage, byImage)
+			}
+
+			if expected := (SizeStats{Count: 2, TotalSize: 310, UniqueSize: 110}); *stats.ByStage["install"] != expected {
+				t.Errorf("expected install stage stats %+v, got %+v", expected, *stats.ByStage["install"])
+			}
+
+			if stats.Reclaimable != tt.expectedReclaimable {
+				t.Errorf("expected reclaimable %+v, got %+v", tt.expectedReclaimable, stats.Reclaimable)
+			}
+		})
+	}
+}
+
+func TestSelectDockerfileCachesToCleanup(t *testing.T) {
+	stages := newStatsTestStages()
+	deletedStages := []*image.StageDescription{stages[3], stages[5]}
+
+	cacheSignatures := map[string]string{
+		"":                        "from",
+		"backend":                 "setup",
+		"backend/stage/builder":   "setup",
+		"worker":                  "install2",
+		"worker/linux/arm64":      "orphan",
+		"frontend":                "removed",
+		"without-signature-label": "",
+	}
+
+	expected := []string{"frontend", "without-signature-label", "worker", "worker/linux/arm64"}
+	if res := selectDockerfileCachesToCleanup(cacheSignatures, stages, deletedStages); !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+}
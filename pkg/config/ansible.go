@@ -10,6 +10,9 @@ type Ansible struct {
 	InstallCacheVersion       string
 	BeforeSetupCacheVersion   string
 	SetupCacheVersion         string
+	Roles                     string
+	Library                   string
+	AllowUnsupportedModules   []string
 
 	raw *rawAnsible
 }
@@ -19,5 +22,13 @@ func (c *Ansible) GetDumpConfigSection() string {
 }
 
 func (c *Ansible) validate() error {
+	if c.Roles != "" && !isProjectRelativePath(c.Roles) {
+		return newDetailedConfigError("`roles: PATH` should be a path relative to the project directory!", c.raw, c.raw.rawImage.doc)
+	}
+
+	if c.Library != "" && !isProjectRelativePath(c.Library) {
+		return newDetailedConfigError("`library: PATH` should be a path relative to the project directory!", c.raw, c.raw.rawImage.doc)
+	}
+
 	return nil
 }
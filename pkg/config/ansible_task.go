@@ -2,6 +2,8 @@ package config
 
 type AnsibleTask struct {
 	Config interface{}
+	// TemplateSources are src paths of the template module relative to the project directory
+	TemplateSources []string
 
 	raw *rawAnsibleTask
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/flant/werf/pkg/util"
@@ -17,6 +18,9 @@ type doc struct {
 	Content        []byte
 	Line           int
 	RenderFilePath string
+
+	// ConfigImportsChecksum is the checksum of the config imports included into the document
+	ConfigImportsChecksum string
 }
 
 func checkOverflow(m map[string]interface{}, configSection interface{}, doc *doc) error {
@@ -53,6 +57,16 @@ func isAbsolutePath(p string) bool {
 	return path.IsAbs(p)
 }
 
+// isProjectRelativePath returns true for the relative path that does not point outside the project directory
+func isProjectRelativePath(p string) bool {
+	if !isRelativePath(p) {
+		return false
+	}
+
+	relPath, err := filepath.Rel(".", filepath.FromSlash(p))
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
 func oneOrNone(conditions []bool) bool {
 	if len(conditions) == 0 {
 		return true
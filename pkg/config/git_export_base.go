@@ -30,11 +30,7 @@ func (c *ExportBase) GitMappingExcludePath() []string {
 }
 
 func (c *GitExportBase) GitMappingStageDependencies() *StageDependencies {
-	s := &StageDependencies{}
-	s.Install = gitMappingPaths(c.StageDependencies.Install)
-	s.BeforeSetup = gitMappingPaths(c.StageDependencies.BeforeSetup)
-	s.Setup = gitMappingPaths(c.StageDependencies.Setup)
-	return s
+	return c.StageDependencies.gitMappingStageDependencies()
 }
 
 func gitMappingPaths(paths []string) []string {
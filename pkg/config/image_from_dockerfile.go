@@ -7,6 +7,10 @@ type ImageFromDockerfile struct {
 	Target     string
 	Args       map[string]interface{}
 	AddHost    []string
+	Platform   []string
+
+	// ConfigImportsChecksum is the checksum of the config imports included into the image document
+	ConfigImportsChecksum string
 
 	raw *rawImageFromDockerfile
 }
@@ -4,17 +4,32 @@ import (
 	"fmt"
 )
 
+const (
+	ImportMethodRsync = "rsync"
+	ImportMethodTar   = "tar"
+)
+
 type Import struct {
 	*ArtifactExport
 	ImageName    string
 	ArtifactName string
+	From         string
 	Before       string
 	After        string
 	Stage        string
+	Method       string
 
 	raw *rawImport
 }
 
+// GetMethod returns the method of files copying, rsync server is used if method is not specified
+func (c *Import) GetMethod() string {
+	if c.Method == "" {
+		return ImportMethodRsync
+	}
+	return c.Method
+}
+
 func (c *Import) GetRaw() interface{} {
 	return c.raw
 }
@@ -24,10 +39,12 @@ func (c *Import) validate() error {
 		return err
 	}
 
-	if c.ArtifactName == "" && c.ImageName == "" {
-		return newDetailedConfigError("artifact name `artifact: NAME` or image name `image: NAME` required for import!", c.raw, c.raw.rawStapelImage.doc)
-	} else if c.ArtifactName != "" && c.ImageName != "" {
-		return newDetailedConfigError("specify only one artifact name using `artifact: NAME` or image name using `image: NAME` for import!", c.raw, c.raw.rawStapelImage.doc)
+	if c.ArtifactName == "" && c.ImageName == "" && c.From == "" {
+		return newDetailedConfigError("artifact name `artifact: NAME`, image name `image: NAME` or external image `from: IMAGE[:TAG][@DIGEST]` required for import!", c.raw, c.raw.rawStapelImage.doc)
+	} else if !oneOrNone([]bool{c.ArtifactName != "", c.ImageName != "", c.From != ""}) {
+		return newDetailedConfigError("specify only one artifact name using `artifact: NAME`, image name using `image: NAME` or external image using `from: IMAGE[:TAG][@DIGEST]` for import!", c.raw, c.raw.rawStapelImage.doc)
+	} else if c.From != "" && c.Stage != "" {
+		return newDetailedConfigError("`stage: NAME` cannot be used for import from external image `from: IMAGE[:TAG][@DIGEST]`!", c.raw, c.raw.rawStapelImage.doc)
 	} else if c.Before != "" && c.After != "" {
 		return newDetailedConfigError("specify only one artifact stage using `before: install|setup` or `after: install|setup` for import!", c.raw, c.raw.rawStapelImage.doc)
 	} else if c.Before == "" && c.After == "" {
@@ -36,6 +53,8 @@ func (c *Import) validate() error {
 		return newDetailedConfigError(fmt.Sprintf("invalid artifact stage `before: %s` for import: expected install or setup!", c.Before), c.raw, c.raw.rawStapelImage.doc)
 	} else if c.After != "" && checkInvalidRelation(c.After) {
 		return newDetailedConfigError(fmt.Sprintf("invalid artifact stage `after: %s` for import: expected install or setup!", c.After), c.raw, c.raw.rawStapelImage.doc)
+	} else if c.Method != "" && c.Method != ImportMethodRsync && c.Method != ImportMethodTar {
+		return newDetailedConfigError(fmt.Sprintf("invalid method `method: %s` for import: expected rsync or tar", c.Method), c.raw, c.raw.rawStapelImage.doc)
 	} else if c.Stage != "" && checkInvalidStage(c.Stage) {
 		return newDetailedConfigError(fmt.Sprintf("invalid stage `stage: %s` for import: expected beforeInstall, install, beforeSetup or setup", c.Stage), c.raw, c.raw.rawStapelImage.doc)
 	}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/werf/pkg/util"
+)
+
+type importEntry struct {
+	content        string
+	expectedFrom   string
+	expectedMethod string
+	expectedErr    string
+}
+
+var _ = DescribeTable("parsing import", func(e importEntry) {
+	parentStack = util.NewStack()
+
+	_, rawStapelImage, _, err := parseDoc(&doc{Content: []byte(e.content), RenderFilePath: "werf.yaml"})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	images, err := rawStapelImage.toStapelImageDirectives()
+	if e.expectedErr != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedErr))
+		return
+	}
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(images).Should(HaveLen(1))
+	Ω(images[0].Import).Should(HaveLen(1))
+	Ω(images[0].Import[0].From).Should(Equal(e.expectedFrom))
+	Ω(images[0].Import[0].GetMethod()).Should(Equal(e.expectedMethod))
+},
+	Entry("external image", importEntry{
+		content:        "image: app\nfrom: alpine\nimport:\n- from: golang:1.15\n  add: /usr/local/go\n  after: install\n",
+		expectedFrom:   "golang:1.15",
+		expectedMethod: "rsync",
+	}),
+	Entry("external image with tar method", importEntry{
+		content:        "image: app\nfrom: alpine\nimport:\n- from: golang:1.15\n  method: tar\n  add: /usr/local/go\n  after: install\n",
+		expectedFrom:   "golang:1.15",
+		expectedMethod: "tar",
+	}),
+	Entry("external image pinned by digest", importEntry{
+		content:        "image: app\nfrom: alpine\nimport:\n- from: golang:1.15@sha256:4f9a9fb4b5ac3e5c6e4ef0b2b0fd6dc0ee57d1f2f70d6df65e2e4f6a8fb4f7f3\n  add: /usr/local/go\n  after: install\n",
+		expectedFrom:   "golang:1.15@sha256:4f9a9fb4b5ac3e5c6e4ef0b2b0fd6dc0ee57d1f2f70d6df65e2e4f6a8fb4f7f3",
+		expectedMethod: "rsync",
+	}),
+	Entry("external image and artifact", importEntry{
+		content:     "image: app\nfrom: alpine\nimport:\n- from: golang:1.15\n  artifact: builder\n  add: /usr/local/go\n  after: install\n",
+		expectedErr: "specify only one artifact name using `artifact: NAME`, image name using `image: NAME` or external image using `from: IMAGE[:TAG][@DIGEST]` for import!",
+	}),
+	Entry("unknown method", importEntry{
+		content:     "image: app\nfrom: alpine\nimport:\n- artifact: builder\n  method: scp\n  add: /app\n  after: install\n",
+		expectedErr: "invalid method `method: scp` for import: expected rsync or tar",
+	}),
+	Entry("external image with stage", importEntry{
+		content:     "image: app\nfrom: alpine\nimport:\n- from: golang:1.15\n  stage: install\n  add: /usr/local/go\n  after: install\n",
+		expectedErr: "`stage: NAME` cannot be used for import from external image",
+	}),
+)
@@ -0,0 +1,366 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/util"
+)
+
+const werfConfigImportsFileName = "imports.yaml"
+
+type rawConfigImport struct {
+	Name string              `yaml:"name,omitempty"`
+	Git  *rawConfigImportGit `yaml:"git,omitempty"`
+	Path string              `yaml:"path,omitempty"`
+	Dir  string              `yaml:"dir,omitempty"`
+}
+
+type rawConfigImportGit struct {
+	Url    string `yaml:"url,omitempty"`
+	Commit string `yaml:"commit,omitempty"`
+	Tag    string `yaml:"tag,omitempty"`
+}
+
+// configImport is a set of templates shared between projects,
+// each template is available by the name "<import name>/<template path relative to import dir>"
+type configImport struct {
+	Name      string
+	Templates map[string][]byte
+}
+
+// importUsage is the position in the werf config render where the imported template has been included
+type importUsage struct {
+	Offset           int
+	TemplateChecksum string
+}
+
+func getWerfConfigImports(werfConfigPath, werfConfigTemplatesDir string) ([]*configImport, error) {
+	importsFilePath := filepath.Join(werfConfigTemplatesDir, werfConfigImportsFileName)
+	if exist, err := util.FileExists(importsFilePath); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(importsFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", importsFilePath, err)
+	}
+
+	var rawImports []*rawConfigImport
+	if err := yaml.UnmarshalStrict(data, &rawImports); err != nil {
+		return nil, fmt.Errorf("bad config imports yaml %s: %s", importsFilePath, err)
+	}
+
+	var imports []*configImport
+	names := map[string]bool{}
+	for _, rawImport := range rawImports {
+		if err := rawImport.validate(); err != nil {
+			return nil, fmt.Errorf("bad config import in %s: %s", importsFilePath, err)
+		}
+
+		if names[rawImport.Name] {
+			return nil, fmt.Errorf("bad config import in %s: duplicate import name %q", importsFilePath, rawImport.Name)
+		}
+		names[rawImport.Name] = true
+
+		var files map[string][]byte
+		if rawImport.Git != nil {
+			files, err = rawImport.readGitFiles()
+		} else {
+			files, err = rawImport.readLocalFiles(filepath.Dir(werfConfigPath))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to import %q: %s", rawImport.Name, err)
+		}
+
+		imports = append(imports, newConfigImport(rawImport.Name, files))
+	}
+
+	return imports, nil
+}
+
+func (c *rawConfigImport) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name required")
+	}
+
+	if (c.Git == nil) == (c.Path == "") {
+		return fmt.Errorf("import %q: one of `git` or `path` required", c.Name)
+	}
+
+	if c.Git != nil {
+		if c.Git.Url == "" {
+			return fmt.Errorf("import %q: `git.url` required", c.Name)
+		}
+
+		if (c.Git.Commit == "") == (c.Git.Tag == "") {
+			return fmt.Errorf("import %q: git import should be pinned with one of `git.commit` or `git.tag`", c.Name)
+		}
+	}
+
+	if filepath.IsAbs(c.Dir) || strings.HasPrefix(filepath.Clean(c.Dir), "..") {
+		return fmt.Errorf("import %q: `dir` should be relative path inside imported repository or path", c.Name)
+	}
+
+	return nil
+}
+
+func (c *rawConfigImport) readGitFiles() (map[string][]byte, error) {
+	remoteRepo, err := git_repo.OpenRemoteRepo(slug.Slug(c.Name), c.Git.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	var commit string
+	if err := logboek.Info.LogProcess(fmt.Sprintf("Fetching config import %q from %s", c.Name, c.Git.Url), logboek.LevelLogProcessOptions{}, func() error {
+		isCloned, err := remoteRepo.Clone()
+		if err != nil {
+			return err
+		}
+
+		commit, err = c.resolveGitCommit(remoteRepo)
+		if err == nil || isCloned {
+			return err
+		}
+
+		// pinned commit or tag could be missing in the existing clone
+		if err := remoteRepo.Fetch(); err != nil {
+			return err
+		}
+
+		commit, err = c.resolveGitCommit(remoteRepo)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	files, err := remoteRepo.ReadCommitFiles(commit, c.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterTemplateFiles(files), nil
+}
+
+func (c *rawConfigImport) resolveGitCommit(remoteRepo *git_repo.Remote) (string, error) {
+	if c.Git.Tag != "" {
+		return remoteRepo.TagCommit(c.Git.Tag)
+	}
+
+	if exist, err := remoteRepo.IsCommitExists(c.Git.Commit); err != nil {
+		return "", err
+	} else if !exist {
+		return "", fmt.Errorf("commit %s not found in repo %s", c.Git.Commit, c.Git.Url)
+	}
+
+	return c.Git.Commit, nil
+}
+
+func (c *rawConfigImport) readLocalFiles(projectDir string) (map[string][]byte, error) {
+	importDir := c.Path
+	if !filepath.IsAbs(importDir) {
+		importDir = filepath.Join(projectDir, importDir)
+	}
+	importDir = filepath.Join(importDir, c.Dir)
+
+	if exist, err := util.DirExists(importDir); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, fmt.Errorf("directory %s not found", importDir)
+	}
+
+	templatesPaths, err := getWerfConfigTemplates(importDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	for _, templatePath := range templatesPaths {
+		relPath, err := filepath.Rel(importDir, templatePath)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadFile(templatePath)
+		if err != nil {
+			return nil, err
+		}
+
+		files[filepath.ToSlash(relPath)] = data
+	}
+
+	return files, nil
+}
+
+func filterTemplateFiles(files map[string][]byte) map[string][]byte {
+	res := map[string][]byte{}
+	for path, data := range files {
+		if strings.HasSuffix(path, ".tmpl") {
+			res[path] = data
+		}
+	}
+	return res
+}
+
+func newConfigImport(name string, files map[string][]byte) *configImport {
+	imp := &configImport{Name: name, Templates: map[string][]byte{}}
+
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		imp.Templates[fmt.Sprintf("%s/%s", name, path)] = files[path]
+	}
+
+	return imp
+}
+
+// importedTemplateChecksum is the checksum of the parsed template without comments,
+// other templates of the import do not affect the checksum
+func importedTemplateChecksum(tmpl *template.Template, templateName string) string {
+	t := tmpl.Lookup(templateName)
+	if t == nil || t.Tree == nil {
+		return ""
+	}
+
+	return util.Sha256Hash(templateName, t.Tree.Root.String())
+}
+
+// addConfigImportsTemplates adds templates of imports and returns mapping of all template names defined in the imports
+func addConfigImportsTemplates(tmpl *template.Template, imports []*configImport) (map[string]*configImport, error) {
+	importByTemplateName := map[string]*configImport{}
+
+	for _, imp := range imports {
+		var templateNames []string
+		for templateName := range imp.Templates {
+			templateNames = append(templateNames, templateName)
+		}
+		sort.Strings(templateNames)
+
+		for _, templateName := range templateNames {
+			if tmpl.Lookup(templateName) != nil {
+				return nil, fmt.Errorf("template %q of import %q is already defined", templateName, imp.Name)
+			}
+
+			definedTemplates := map[string]bool{}
+			for _, t := range tmpl.Templates() {
+				definedTemplates[t.Name()] = true
+			}
+
+			if err := addTemplate(tmpl, templateName, string(imp.Templates[templateName])); err != nil {
+				return nil, err
+			}
+
+			// template file itself and all templates defined in it with `define` action
+			for _, t := range tmpl.Templates() {
+				if !definedTemplates[t.Name()] {
+					importByTemplateName[t.Name()] = imp
+				}
+			}
+		}
+	}
+
+	return importByTemplateName, nil
+}
+
+// replaceTemplateActionsWithInclude replaces {{ template "name" pipeline }} actions with the include function call
+// to track usages of the imported templates, the template action is executed by text/template without any hooks
+func replaceTemplateActionsWithInclude(tmpl *template.Template) {
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			replaceListTemplateActionsWithInclude(t.Tree.Root)
+		}
+	}
+}
+
+func replaceListTemplateActionsWithInclude(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+
+	for ind, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.TemplateNode:
+			list.Nodes[ind] = newIncludeActionNode(n)
+		case *parse.IfNode:
+			replaceListTemplateActionsWithInclude(n.List)
+			replaceListTemplateActionsWithInclude(n.ElseList)
+		case *parse.RangeNode:
+			replaceListTemplateActionsWithInclude(n.List)
+			replaceListTemplateActionsWithInclude(n.ElseList)
+		case *parse.WithNode:
+			replaceListTemplateActionsWithInclude(n.List)
+			replaceListTemplateActionsWithInclude(n.ElseList)
+		}
+	}
+}
+
+func newIncludeActionNode(n *parse.TemplateNode) *parse.ActionNode {
+	var data parse.Node = &parse.NilNode{NodeType: parse.NodeNil, Pos: n.Pos}
+	if n.Pipe != nil {
+		data = n.Pipe
+	}
+
+	cmd := &parse.CommandNode{
+		NodeType: parse.NodeCommand,
+		Pos:      n.Pos,
+		Args: []parse.Node{
+			parse.NewIdentifier("include").SetPos(n.Pos),
+			&parse.StringNode{NodeType: parse.NodeString, Pos: n.Pos, Quoted: strconv.Quote(n.Name), Text: n.Name},
+			data,
+		},
+	}
+
+	return &parse.ActionNode{
+		NodeType: parse.NodeAction,
+		Pos:      n.Pos,
+		Line:     n.Line,
+		Pipe:     &parse.PipeNode{NodeType: parse.NodePipe, Pos: n.Pos, Line: n.Line, Cmds: []*parse.CommandNode{cmd}},
+	}
+}
+
+// setDocsImportsChecksum sets checksum of the imported templates included into the document content
+func setDocsImportsChecksum(docs []*doc, werfConfigRenderContent string, usages []*importUsage) {
+	checksumsByDoc := map[*doc][]string{}
+
+	for _, usage := range usages {
+		line := strings.Count(werfConfigRenderContent[:usage.Offset], "\n") + 1
+
+		var usageDoc *doc
+		for _, d := range docs {
+			if d.Line < line {
+				usageDoc = d
+			}
+		}
+
+		if usageDoc == nil {
+			continue
+		}
+
+		if !util.IsStringsContainValue(checksumsByDoc[usageDoc], usage.TemplateChecksum) {
+			checksumsByDoc[usageDoc] = append(checksumsByDoc[usageDoc], usage.TemplateChecksum)
+		}
+	}
+
+	for d, checksums := range checksumsByDoc {
+		sort.Strings(checksums)
+		d.ConfigImportsChecksum = util.Sha256Hash(checksums...)
+	}
+}
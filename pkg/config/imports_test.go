@@ -0,0 +1,129 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/werf/pkg/util"
+)
+
+const testImportedTemplate = `{{ define "base" }}from: alpine
+{{ end }}`
+
+var _ = Describe("config imports", func() {
+	var projectDir string
+
+	BeforeEach(func() {
+		parentStack = util.NewStack()
+
+		var err error
+		projectDir, err = ioutil.TempDir("", "werf-config-imports-test")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		writeProjectFile(projectDir, ".werf/imports.yaml", "- name: shared\n  path: shared\n")
+		writeProjectFile(projectDir, "shared/base.tmpl", testImportedTemplate)
+	})
+
+	AfterEach(func() {
+		Ω(os.RemoveAll(projectDir)).Should(Succeed())
+	})
+
+	renderDocs := func(werfConfig string) []*doc {
+		werfConfigPath := writeProjectFile(projectDir, "werf.yaml", werfConfig)
+
+		content, usages, err := parseWerfConfigYaml(werfConfigPath, filepath.Join(projectDir, ".werf"))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		docs, err := splitByDocs(content, "werf.yaml")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		setDocsImportsChecksum(docs, content, usages)
+
+		return docs
+	}
+
+	It("sets the checksum only for documents using the imported templates", func() {
+		docs := renderDocs(`project: app
+configVersion: 1
+---
+image: include
+{{ include "base" . }}
+---
+image: template
+{{ template "base" . }}
+---
+image: without-data
+{{- if true }}
+{{ template "base" }}
+{{- end }}
+---
+image: not-imported
+from: alpine
+`)
+		Ω(docs).Should(HaveLen(5))
+
+		for _, d := range docs[1:] {
+			_, rawStapelImage, _, err := parseDoc(d)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rawStapelImage.From).Should(Equal("alpine"))
+		}
+
+		Ω(docs[0].ConfigImportsChecksum).Should(BeEmpty())
+		Ω(docs[1].ConfigImportsChecksum).ShouldNot(BeEmpty())
+		Ω(docs[2].ConfigImportsChecksum).Should(Equal(docs[1].ConfigImportsChecksum))
+		Ω(docs[3].ConfigImportsChecksum).Should(Equal(docs[1].ConfigImportsChecksum))
+		Ω(docs[4].ConfigImportsChecksum).Should(BeEmpty())
+	})
+
+	It("changes the checksum when the imported templates are changed", func() {
+		werfConfig := "project: app\nconfigVersion: 1\n---\nimage: app\n{{ template \"base\" . }}\n"
+
+		checksum := renderDocs(werfConfig)[1].ConfigImportsChecksum
+
+		writeProjectFile(projectDir, "shared/base.tmpl", strings.Replace(testImportedTemplate, "alpine", "alpine:3.12", 1))
+		Ω(renderDocs(werfConfig)[1].ConfigImportsChecksum).ShouldNot(Equal(checksum))
+	})
+
+	It("does not change the checksum when other templates of the import are changed", func() {
+		werfConfig := "project: app\nconfigVersion: 1\n---\nimage: app\n{{ template \"base\" . }}\n"
+		checksum := renderDocs(werfConfig)[1].ConfigImportsChecksum
+
+		writeProjectFile(projectDir, "shared/base.tmpl", testImportedTemplate+"\n{{/* changed */}}\n{{ define \"other\" }}from: ubuntu\n{{ end }}")
+		writeProjectFile(projectDir, "shared/other.tmpl", `{{ define "unused" }}from: debian{{ end }}`)
+		Ω(renderDocs(werfConfig)[1].ConfigImportsChecksum).Should(Equal(checksum))
+	})
+
+	It("sets the checksum for dockerfile images", func() {
+		docs := renderDocs(`project: app
+configVersion: 1
+---
+image: app
+dockerfile: Dockerfile
+args:
+  BASE: {{ include "base" . | trim | trimPrefix "from: " }}
+`)
+		Ω(docs).Should(HaveLen(2))
+
+		_, _, rawImageFromDockerfile, err := parseDoc(docs[1])
+		Ω(err).ShouldNot(HaveOccurred())
+
+		images, err := rawImageFromDockerfile.toImageFromDockerfileDirectives()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(images).Should(HaveLen(1))
+		Ω(images[0].Args).Should(HaveKeyWithValue("BASE", "alpine"))
+		Ω(images[0].ConfigImportsChecksum).ShouldNot(BeEmpty())
+		Ω(images[0].ConfigImportsChecksum).Should(Equal(docs[1].ConfigImportsChecksum))
+	})
+})
+
+func writeProjectFile(projectDir, relPath, content string) string {
+	path := filepath.Join(projectDir, relPath)
+	Ω(os.MkdirAll(filepath.Dir(path), os.ModePerm)).Should(Succeed())
+	Ω(ioutil.WriteFile(path, []byte(content), 0644)).Should(Succeed())
+	return path
+}
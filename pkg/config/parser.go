@@ -32,7 +32,7 @@ func RenderWerfConfig(werfConfigPath, werfConfigTemplatesDir string, imagesToPro
 	}
 
 	if len(imagesToProcess) == 0 {
-		werfConfigRenderContent, err := parseWerfConfigYaml(werfConfigPath, werfConfigTemplatesDir)
+		werfConfigRenderContent, _, err := parseWerfConfigYaml(werfConfigPath, werfConfigTemplatesDir)
 		if err != nil {
 			return fmt.Errorf("cannot parse config: %s", err)
 		}
@@ -62,7 +62,7 @@ func RenderWerfConfig(werfConfigPath, werfConfigTemplatesDir string, imagesToPro
 }
 
 func GetWerfConfig(werfConfigPath, werfConfigTemplatesDir string, logRenderedFilePath bool) (*WerfConfig, error) {
-	werfConfigRenderContent, err := parseWerfConfigYaml(werfConfigPath, werfConfigTemplatesDir)
+	werfConfigRenderContent, importUsages, err := parseWerfConfigYaml(werfConfigPath, werfConfigTemplatesDir)
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse config: %s", err)
 	}
@@ -86,6 +86,8 @@ func GetWerfConfig(werfConfigPath, werfConfigTemplatesDir string, logRenderedFil
 		return nil, err
 	}
 
+	setDocsImportsChecksum(docs, werfConfigRenderContent, importUsages)
+
 	meta, rawStapelImages, rawImagesFromDockerfile, err := splitByMetaAndRawImages(docs)
 	if err != nil {
 		return nil, err
@@ -202,46 +204,70 @@ func splitByDocs(werfConfigRenderContent string, werfConfigRenderPath string) ([
 	return docs, nil
 }
 
-func parseWerfConfigYaml(werfConfigPath, werfConfigTemplatesDir string) (string, error) {
+func parseWerfConfigYaml(werfConfigPath, werfConfigTemplatesDir string) (string, []*importUsage, error) {
 	data, err := ioutil.ReadFile(werfConfigPath)
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+
+	configImports, err := getWerfConfigImports(werfConfigPath, werfConfigTemplatesDir)
+	if err != nil {
+		return "", nil, err
 	}
 
+	// imported templates usages are tracked by the offset in the config render to bind them to the config documents
+	renderBuf := bytes.NewBuffer(nil)
+	var importUsages []*importUsage
+	var importByTemplateName map[string]*configImport
+
 	tmpl := template.New("werfConfig")
-	tmpl.Funcs(funcMap(tmpl))
+	tmpl.Funcs(funcMap(tmpl, func(name string) {
+		if _, ok := importByTemplateName[name]; ok {
+			importUsages = append(importUsages, &importUsage{Offset: renderBuf.Len(), TemplateChecksum: importedTemplateChecksum(tmpl, name)})
+		}
+	}))
 
 	werfConfigsTemplates, err := getWerfConfigTemplates(werfConfigTemplatesDir)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	if len(werfConfigsTemplates) != 0 {
 		for _, templatePath := range werfConfigsTemplates {
 			templateName, err := filepath.Rel(werfConfigTemplatesDir, templatePath)
 			if err != nil {
-				return "", err
+				return "", nil, err
 			}
 
 			var templateData []byte
 			if templateData, err = ioutil.ReadFile(templatePath); err != nil {
-				return "", err
+				return "", nil, err
 			}
 
 			if err := addTemplate(tmpl, templateName, string(templateData)); err != nil {
-				return "", err
+				return "", nil, err
 			}
 		}
 	}
 
+	if importByTemplateName, err = addConfigImportsTemplates(tmpl, configImports); err != nil {
+		return "", nil, err
+	}
+
 	if _, err := tmpl.Parse(string(data)); err != nil {
-		return "", err
+		return "", nil, err
+	}
+
+	if len(configImports) != 0 {
+		replaceTemplateActionsWithInclude(tmpl)
 	}
 
 	files := files{filepath.Dir(werfConfigPath)}
-	config, err := executeTemplate(tmpl, "werfConfig", map[string]interface{}{"Files": files})
+	if err := tmpl.ExecuteTemplate(renderBuf, "werfConfig", map[string]interface{}{"Files": files}); err != nil {
+		return "", nil, err
+	}
 
-	return config, err
+	return renderBuf.String(), importUsages, nil
 }
 
 func addTemplate(tmpl *template.Template, templateName string, templateContent string) error {
@@ -284,9 +310,10 @@ func getWerfConfigTemplates(path string) ([]string, error) {
 	return templates, nil
 }
 
-func funcMap(tmpl *template.Template) template.FuncMap {
+func funcMap(tmpl *template.Template, onInclude func(name string)) template.FuncMap {
 	funcMap := sprig.TxtFuncMap()
 	funcMap["include"] = func(name string, data interface{}) (string, error) {
+		onInclude(name)
 		return executeTemplate(tmpl, name, data)
 	}
 	funcMap["tpl"] = func(templateContent string, data interface{}) (string, error) {
@@ -560,6 +587,10 @@ func prepareWerfConfig(rawImages []*rawStapelImage, rawImagesFromDockerfile []*r
 		return nil, err
 	}
 
+	if err := werfConfig.validateImagesPlatforms(); err != nil {
+		return nil, err
+	}
+
 	return werfConfig, nil
 }
 
@@ -570,48 +601,64 @@ func splitByMetaAndRawImages(docs []*doc) (*Meta, []*rawStapelImage, []*rawImage
 
 	parentStack = util.NewStack()
 	for _, doc := range docs {
-		var raw map[string]interface{}
-		err := yaml.UnmarshalStrict(doc.Content, &raw)
+		rawMeta, rawStapelImage, rawImageFromDockerfile, err := parseDoc(doc)
 		if err != nil {
-			return nil, nil, nil, newYamlUnmarshalError(err, doc)
+			return nil, nil, nil, err
 		}
 
-		if isMetaDoc(raw) {
+		switch {
+		case rawMeta != nil:
 			if resultMeta != nil {
 				return nil, nil, nil, newYamlUnmarshalError(errors.New("duplicate meta config section definition"), doc)
 			}
 
-			rawMeta := &rawMeta{doc: doc}
-			err := yaml.UnmarshalStrict(doc.Content, &rawMeta)
-			if err != nil {
-				return nil, nil, nil, newYamlUnmarshalError(err, doc)
-			}
-
 			resultMeta = rawMeta.toMeta()
-		} else if isImageFromDockerfileDoc(raw) {
-			imageFromDockerfile := &rawImageFromDockerfile{doc: doc}
-			err := yaml.UnmarshalStrict(doc.Content, &imageFromDockerfile)
-			if err != nil {
-				return nil, nil, nil, newYamlUnmarshalError(err, doc)
-			}
-
-			rawImagesFromDockerfile = append(rawImagesFromDockerfile, imageFromDockerfile)
-		} else if isImageDoc(raw) {
-			image := &rawStapelImage{doc: doc}
-			err := yaml.UnmarshalStrict(doc.Content, &image)
-			if err != nil {
-				return nil, nil, nil, newYamlUnmarshalError(err, doc)
-			}
-
-			rawStapelImages = append(rawStapelImages, image)
-		} else {
-			return nil, nil, nil, newYamlUnmarshalError(errors.New("cannot recognize type of config section (part of YAML stream separated by three hyphens, https://yaml.org/spec/1.2/spec.html#id2800132):\n * 'configVersion' required for meta config section;\n * 'image' required for the image config sections;\n * 'artifact' required for the artifact config sections;"), doc)
+		case rawImageFromDockerfile != nil:
+			rawImagesFromDockerfile = append(rawImagesFromDockerfile, rawImageFromDockerfile)
+		case rawStapelImage != nil:
+			rawStapelImages = append(rawStapelImages, rawStapelImage)
 		}
 	}
 
 	return resultMeta, rawStapelImages, rawImagesFromDockerfile, nil
 }
 
+func parseDoc(doc *doc) (*rawMeta, *rawStapelImage, *rawImageFromDockerfile, error) {
+	var raw map[string]interface{}
+	err := yaml.UnmarshalStrict(doc.Content, &raw)
+	if err != nil {
+		return nil, nil, nil, newYamlUnmarshalError(err, doc)
+	}
+
+	if isMetaDoc(raw) {
+		rawMeta := &rawMeta{doc: doc}
+		err := yaml.UnmarshalStrict(doc.Content, &rawMeta)
+		if err != nil {
+			return nil, nil, nil, newYamlUnmarshalError(err, doc)
+		}
+
+		return rawMeta, nil, nil, nil
+	} else if isImageFromDockerfileDoc(raw) {
+		imageFromDockerfile := &rawImageFromDockerfile{doc: doc}
+		err := yaml.UnmarshalStrict(doc.Content, &imageFromDockerfile)
+		if err != nil {
+			return nil, nil, nil, newYamlUnmarshalError(err, doc)
+		}
+
+		return nil, nil, imageFromDockerfile, nil
+	} else if isImageDoc(raw) {
+		image := &rawStapelImage{doc: doc}
+		err := yaml.UnmarshalStrict(doc.Content, &image)
+		if err != nil {
+			return nil, nil, nil, newYamlUnmarshalError(err, doc)
+		}
+
+		return nil, image, nil, nil
+	} else {
+		return nil, nil, nil, newYamlUnmarshalError(errors.New("cannot recognize type of config section (part of YAML stream separated by three hyphens, https://yaml.org/spec/1.2/spec.html#id2800132):\n * 'configVersion' required for meta config section;\n * 'image' required for the image config sections;\n * 'artifact' required for the artifact config sections;"), doc)
+	}
+}
+
 func isMetaDoc(h map[string]interface{}) bool {
 	if _, ok := h["configVersion"]; ok {
 		return true
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/flant/werf/pkg/image"
+)
+
+func toPlatformDirective(platform interface{}, configSection interface{}, doc *doc) ([]string, error) {
+	platforms, err := InterfaceToStringArray(platform, configSection, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	isAdded := map[string]bool{}
+	for _, p := range platforms {
+		if _, _, _, err := image.ParsePlatform(p); err != nil {
+			return nil, newDetailedConfigError(fmt.Sprintf("invalid platform `%s`: expected `OS/ARCH[/VARIANT]`!", p), configSection, doc)
+		}
+
+		if isAdded[p] {
+			return nil, newDetailedConfigError(fmt.Sprintf("duplicated platform `%s`!", p), configSection, doc)
+		}
+		isAdded[p] = true
+	}
+
+	return platforms, nil
+}
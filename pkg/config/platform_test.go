@@ -0,0 +1,101 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/werf/pkg/util"
+)
+
+type platformEntry struct {
+	contents          []string
+	expectedPlatforms []string
+	expectedErr       string
+}
+
+var _ = DescribeTable("parsing platform", func(e platformEntry) {
+	parentStack = util.NewStack()
+
+	werfConfig := &WerfConfig{}
+	for _, content := range e.contents {
+		_, rawStapelImage, _, err := parseDoc(&doc{Content: []byte(content), RenderFilePath: "werf.yaml"})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		if rawStapelImage.stapelImageType() == "artifact" {
+			artifacts, err := rawStapelImage.toStapelImageArtifactDirectives()
+			if err == nil {
+				werfConfig.Artifacts = append(werfConfig.Artifacts, artifacts...)
+				continue
+			}
+
+			Ω(e.expectedErr).ShouldNot(BeEmpty())
+			Ω(err.Error()).Should(ContainSubstring(e.expectedErr))
+			return
+		}
+
+		images, err := rawStapelImage.toStapelImageDirectives()
+		if err == nil {
+			werfConfig.StapelImages = append(werfConfig.StapelImages, images...)
+			continue
+		}
+
+		Ω(e.expectedErr).ShouldNot(BeEmpty())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedErr))
+		return
+	}
+
+	err := werfConfig.validateImagesPlatforms()
+	if e.expectedErr != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedErr))
+		return
+	}
+	Ω(err).ShouldNot(HaveOccurred())
+
+	Ω(werfConfig.StapelImages[len(werfConfig.StapelImages)-1].Platform).Should(Equal(e.expectedPlatforms))
+},
+	Entry("single platform", platformEntry{
+		contents:          []string{"image: app\nfrom: alpine\nplatform: linux/arm64\n"},
+		expectedPlatforms: []string{"linux/arm64"},
+	}),
+	Entry("platforms list", platformEntry{
+		contents:          []string{"image: app\nfrom: alpine\nplatform:\n- linux/amd64\n- linux/arm/v7\n"},
+		expectedPlatforms: []string{"linux/amd64", "linux/arm/v7"},
+	}),
+	Entry("invalid platform", platformEntry{
+		contents:    []string{"image: app\nfrom: alpine\nplatform: arm64\n"},
+		expectedErr: "invalid platform `arm64`: expected `OS/ARCH[/VARIANT]`!",
+	}),
+	Entry("duplicated platform", platformEntry{
+		contents:    []string{"image: app\nfrom: alpine\nplatform: [linux/amd64, linux/amd64]\n"},
+		expectedErr: "duplicated platform `linux/amd64`!",
+	}),
+	Entry("base image with the same platforms", platformEntry{
+		contents: []string{
+			"image: base\nfrom: alpine\nplatform: [linux/amd64, linux/arm64]\n",
+			"image: app\nfromImage: base\nplatform: [linux/arm64, linux/amd64]\n",
+		},
+		expectedPlatforms: []string{"linux/arm64", "linux/amd64"},
+	}),
+	Entry("base image with other platforms", platformEntry{
+		contents: []string{
+			"image: base\nfrom: alpine\nplatform: [linux/amd64]\n",
+			"image: app\nfromImage: base\nplatform: [linux/amd64, linux/arm64]\n",
+		},
+		expectedErr: "platforms of the base image `base` should be the same as platforms of the image!",
+	}),
+	Entry("import from artifact without platforms", platformEntry{
+		contents: []string{
+			"artifact: builder\nfrom: golang\n",
+			"image: app\nfrom: alpine\nplatform: [linux/amd64, linux/arm64]\nimport:\n- artifact: builder\n  add: /app\n  after: install\n",
+		},
+		expectedPlatforms: []string{"linux/amd64", "linux/arm64"},
+	}),
+	Entry("import from artifact built for other platforms", platformEntry{
+		contents: []string{
+			"artifact: builder\nfrom: golang\nplatform: linux/amd64\n",
+			"image: app\nfrom: alpine\nplatform: [linux/amd64, linux/arm64]\nimport:\n- artifact: builder\n  add: /app\n  after: install\n",
+		},
+		expectedErr: "import source is not built for platform `linux/arm64` of the image!",
+	}),
+)
@@ -10,6 +10,9 @@ type rawAnsible struct {
 	InstallCacheVersion       string           `yaml:"installCacheVersion,omitempty"`
 	BeforeSetupCacheVersion   string           `yaml:"beforeSetupCacheVersion,omitempty"`
 	SetupCacheVersion         string           `yaml:"setupCacheVersion,omitempty"`
+	Roles                     string           `yaml:"roles,omitempty"`
+	Library                   string           `yaml:"library,omitempty"`
+	AllowUnsupportedModules   []string         `yaml:"allowUnsupportedModules,omitempty"`
 
 	rawImage *rawStapelImage `yaml:"-"` // parent
 
@@ -33,6 +36,16 @@ func (c *rawAnsible) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	// tasks are checked after the whole section is parsed to take allowUnsupportedModules into account
+	modules := append(supportedModules(), c.AllowUnsupportedModules...)
+	for _, tasks := range [][]rawAnsibleTask{c.BeforeInstall, c.Install, c.BeforeSetup, c.Setup} {
+		for ind := range tasks {
+			if err := tasks[ind].validateModule(modules); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -44,6 +57,9 @@ func (c *rawAnsible) toDirective() (ansible *Ansible, err error) {
 	ansible.InstallCacheVersion = c.InstallCacheVersion
 	ansible.BeforeSetupCacheVersion = c.BeforeSetupCacheVersion
 	ansible.SetupCacheVersion = c.SetupCacheVersion
+	ansible.Roles = c.Roles
+	ansible.Library = c.Library
+	ansible.AllowUnsupportedModules = c.AllowUnsupportedModules
 
 	for ind := range c.BeforeInstall {
 		if ansibleTask, err := c.BeforeInstall[ind].toDirective(); err != nil {
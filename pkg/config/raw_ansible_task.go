@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"path"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
@@ -30,30 +32,83 @@ func (c *rawAnsibleTask) UnmarshalYAML(unmarshal func(interface{}) error) error
 		return err
 	}
 
-	if !c.blockDefined() {
-		check := false
-		for _, supportedModule := range supportedModules() {
-			if c.Fields[supportedModule] != nil {
-				if check {
-					return newDetailedConfigError("invalid ansible task!", c, c.rawAnsible.rawImage.doc)
-				} else {
-					check = true
+	return nil
+}
+
+func (c *rawAnsibleTask) validateModule(modules []string) error {
+	if c.blockDefined() {
+		for _, tasks := range [][]rawAnsibleTask{c.Block, c.Rescue, c.Always} {
+			for ind := range tasks {
+				if err := tasks[ind].validateModule(modules); err != nil {
+					return err
 				}
 			}
 		}
 
-		if !check {
-			var supportedModulesString string
-			for _, supportedModule := range supportedModules() {
-				supportedModulesString += fmt.Sprintf("* %s\n", supportedModule)
+		return nil
+	}
+
+	check := false
+	for _, module := range modules {
+		if c.Fields[module] != nil {
+			if check {
+				return newDetailedConfigError("invalid ansible task!", c, c.rawAnsible.rawImage.doc)
+			} else {
+				check = true
 			}
-			return newConfigError(fmt.Sprintf("unsupported ansible task!\n\n%s\nSupported modules list:\n%s\n%s", dumpConfigSection(c), supportedModulesString, dumpConfigDoc(c.rawAnsible.rawImage.doc)))
 		}
 	}
 
+	if !check {
+		var supportedModulesString string
+		for _, module := range modules {
+			supportedModulesString += fmt.Sprintf("* %s\n", module)
+		}
+		return newConfigError(fmt.Sprintf("unsupported ansible task!\n\n%s\nSupported modules list:\n%s\nOther modules can be enabled with the `ansible.allowUnsupportedModules` directive at your own risk.\n\n%s", dumpConfigSection(c), supportedModulesString, dumpConfigDoc(c.rawAnsible.rawImage.doc)))
+	}
+
 	return nil
 }
 
+// templateSources returns src paths of the template module tasks including the tasks of blocks
+func (c *rawAnsibleTask) templateSources() ([]string, error) {
+	if c.blockDefined() {
+		var sources []string
+		for _, tasks := range [][]rawAnsibleTask{c.Block, c.Rescue, c.Always} {
+			for ind := range tasks {
+				taskSources, err := tasks[ind].templateSources()
+				if err != nil {
+					return nil, err
+				}
+				sources = append(sources, taskSources...)
+			}
+		}
+
+		return sources, nil
+	}
+
+	var src string
+	switch args := c.Fields["template"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		// free-form arguments: src=PATH dest=PATH
+		for _, arg := range strings.Fields(args) {
+			if strings.HasPrefix(arg, "src=") {
+				src = strings.Trim(strings.TrimPrefix(arg, "src="), `"'`)
+			}
+		}
+	case map[interface{}]interface{}:
+		src, _ = args["src"].(string)
+	}
+
+	if src == "" || strings.Contains(src, "{{") || !isProjectRelativePath(src) {
+		return nil, newDetailedConfigError("`template.src: PATH` should be a static path relative to the project directory!", c, c.rawAnsible.rawImage.doc)
+	}
+
+	return []string{path.Clean(src)}, nil
+}
+
 func (c *rawAnsibleTask) blockDefined() bool {
 	return c.Block != nil || c.Rescue != nil || c.Always != nil
 }
@@ -67,7 +122,7 @@ func supportedModules() []string {
 	// Crypto Modules
 	modules = append(modules, []string{"openssl_certificate", "openssl_csr", "openssl_privatekey", "openssl_publickey"}...)
 	// No Databases modules
-	// Files Modules (no fetch, patch, replace, synchronize, template, xml
+	// Files Modules (no fetch, patch, synchronize, xml)
 	modules = append(modules, []string{
 		"acl",
 		"archive",
@@ -82,6 +137,7 @@ func supportedModules() []string {
 		"lineinfile",
 		"stat",
 		"tempfile",
+		"template",
 		"unarchive",
 		"xattr",
 	}...)
@@ -144,7 +200,7 @@ func supportedModules() []string {
 	// System Modules (only passwd management and locales)
 	modules = append(modules, []string{"cron", "user", "group", "getent", "locale_gen", "timezone"}...)
 	// Utilities Modules
-	modules = append(modules, []string{"meta", "assert", "debug", "fail", "set_fact", "wait_for"}...)
+	modules = append(modules, []string{"meta", "assert", "debug", "fail", "set_fact", "wait_for", "include_role", "import_role"}...)
 	// No Web Infrastructure modules
 	// No Windows modules
 
@@ -168,5 +224,9 @@ func (c *rawAnsibleTask) toDirective() (*AnsibleTask, error) {
 	ansibleTask.Config = unmarshal
 	ansibleTask.raw = c
 
+	if ansibleTask.TemplateSources, err = c.templateSources(); err != nil {
+		return nil, err
+	}
+
 	return ansibleTask, nil
 }
@@ -0,0 +1,89 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/werf/pkg/util"
+)
+
+type ansibleEntry struct {
+	content                 string
+	expectedRoles           string
+	expectedLibrary         string
+	expectedTemplateSources []string
+	expectedErr             string
+}
+
+var _ = DescribeTable("parsing ansible", func(e ansibleEntry) {
+	parentStack = util.NewStack()
+
+	_, rawStapelImage, _, err := parseDoc(&doc{Content: []byte(e.content), RenderFilePath: "werf.yaml"})
+	if err == nil {
+		var images []*StapelImage
+		images, err = rawStapelImage.toStapelImageDirectives()
+		if err == nil {
+			Ω(images).Should(HaveLen(1))
+
+			ansible := images[0].Ansible
+			Ω(ansible.Roles).Should(Equal(e.expectedRoles))
+			Ω(ansible.Library).Should(Equal(e.expectedLibrary))
+
+			var templateSources []string
+			for _, tasks := range [][]*AnsibleTask{ansible.BeforeInstall, ansible.Install, ansible.BeforeSetup, ansible.Setup} {
+				for _, task := range tasks {
+					templateSources = append(templateSources, task.TemplateSources...)
+				}
+			}
+			Ω(templateSources).Should(Equal(e.expectedTemplateSources))
+		}
+	}
+
+	if e.expectedErr != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedErr))
+	} else {
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+},
+	Entry("supported modules only", ansibleEntry{
+		content: "image: app\nfrom: alpine\nansible:\n  install:\n  - shell: echo\n",
+	}),
+	Entry("unsupported module", ansibleEntry{
+		content:     "image: app\nfrom: alpine\nansible:\n  install:\n  - synchronize:\n      src: a\n      dest: b\n",
+		expectedErr: "unsupported ansible task!",
+	}),
+	Entry("unsupported module in block", ansibleEntry{
+		content:     "image: app\nfrom: alpine\nansible:\n  install:\n  - block:\n    - my_module: {}\n",
+		expectedErr: "unsupported ansible task!",
+	}),
+	Entry("allowed unsupported modules", ansibleEntry{
+		content: "image: app\nfrom: alpine\nansible:\n  install:\n  - block:\n    - my_module: {}\n  - synchronize:\n      src: a\n      dest: b\n  allowUnsupportedModules: [synchronize, my_module]\n",
+	}),
+	Entry("templates, roles and library", ansibleEntry{
+		content:                 "image: app\nfrom: alpine\nansible:\n  roles: .werf/ansible/roles\n  library: .werf/ansible/library/\n  install:\n  - template:\n      src: conf/nginx.conf.j2\n      dest: /etc/nginx/nginx.conf\n  setup:\n  - block:\n    - template: src=conf/app.ini dest=/app/app.ini\n",
+		expectedRoles:           ".werf/ansible/roles",
+		expectedLibrary:         ".werf/ansible/library/",
+		expectedTemplateSources: []string{"conf/nginx.conf.j2", "conf/app.ini"},
+	}),
+	Entry("template with not normalized src", ansibleEntry{
+		content:                 "image: app\nfrom: alpine\nansible:\n  install:\n  - template:\n      src: ./conf//nginx.conf\n      dest: /etc/nginx/nginx.conf\n",
+		expectedTemplateSources: []string{"conf/nginx.conf"},
+	}),
+	Entry("template with jinja src", ansibleEntry{
+		content:     "image: app\nfrom: alpine\nansible:\n  install:\n  - template:\n      src: \"{{ item }}\"\n      dest: /etc\n",
+		expectedErr: "`template.src: PATH` should be a static path relative to the project directory!",
+	}),
+	Entry("roles outside of the project directory", ansibleEntry{
+		content:     "image: app\nfrom: alpine\nansible:\n  roles: ../roles\n  install:\n  - shell: echo\n",
+		expectedErr: "`roles: PATH` should be a path relative to the project directory!",
+	}),
+	Entry("library with the name starting with dots", ansibleEntry{
+		content:         "image: app\nfrom: alpine\nansible:\n  library: ..library\n  install:\n  - shell: echo\n",
+		expectedLibrary: "..library",
+	}),
+	Entry("library outside of the project directory after cleaning", ansibleEntry{
+		content:     "image: app\nfrom: alpine\nansible:\n  library: library/../..\n  install:\n  - shell: echo\n",
+		expectedErr: "`library: PATH` should be a path relative to the project directory!",
+	}),
+)
@@ -11,6 +11,7 @@ type rawGit struct {
 	Tag                                             string                `yaml:"tag,omitempty"`
 	Commit                                          string                `yaml:"commit,omitempty"`
 	RawStageDependencies                            *rawStageDependencies `yaml:"stageDependencies,omitempty"`
+	LFS                                             bool                  `yaml:"lfs,omitempty"`
 	HerebyIAdmitThatBranchMightBreakReproducibility bool                  `yaml:"herebyIAdmitThatBranchMightBreakReproducibility,omitempty"`
 
 	rawStapelImage *rawStapelImage `yaml:"-"` // parent
@@ -104,6 +105,8 @@ func (c *rawGit) toGitLocalExportDirective() (gitLocalExport *GitLocalExport, er
 		}
 	}
 
+	gitLocalExport.LFS = c.LFS
+
 	gitLocalExport.raw = c
 
 	if err := c.validateGitLocalExportDirective(gitLocalExport); err != nil {
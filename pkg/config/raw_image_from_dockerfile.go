@@ -12,6 +12,7 @@ type rawImageFromDockerfile struct {
 	Target     string                 `yaml:"target,omitempty"`
 	Args       map[string]interface{} `yaml:"args,omitempty"`
 	AddHost    interface{}            `yaml:"addHost,omitempty"`
+	Platform   interface{}            `yaml:"platform,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -88,6 +89,12 @@ func (c *rawImageFromDockerfile) toImageFromDockerfileDirective(imageName string
 		image.AddHost = addHost
 	}
 
+	if image.Platform, err = toPlatformDirective(c.Platform, c, c.doc); err != nil {
+		return nil, err
+	}
+
+	image.ConfigImportsChecksum = c.doc.ConfigImportsChecksum
+
 	image.raw = c
 
 	return image, nil
@@ -1,18 +1,30 @@
 package config
 
 type rawStageDependencies struct {
-	Install     interface{} `yaml:"install,omitempty"`
-	Setup       interface{} `yaml:"setup,omitempty"`
-	BeforeSetup interface{} `yaml:"beforeSetup,omitempty"`
+	BeforeInstall interface{} `yaml:"beforeInstall,omitempty"`
+	Install       interface{} `yaml:"install,omitempty"`
+	Setup         interface{} `yaml:"setup,omitempty"`
+	BeforeSetup   interface{} `yaml:"beforeSetup,omitempty"`
 
-	rawGit *rawGit `yaml:"-"` // parent
+	rawGit         *rawGit         `yaml:"-"` // parent
+	rawStapelImage *rawStapelImage `yaml:"-"` // parent of the image dependencies
 
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
 }
 
+func (c *rawStageDependencies) doc() *doc {
+	if c.rawGit != nil {
+		return c.rawGit.rawStapelImage.doc
+	}
+	return c.rawStapelImage.doc
+}
+
 func (c *rawStageDependencies) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	if parent, ok := parentStack.Peek().(*rawGit); ok {
+	switch parent := parentStack.Peek().(type) {
+	case *rawGit:
 		c.rawGit = parent
+	case *rawStapelImage:
+		c.rawStapelImage = parent
 	}
 
 	type plain rawStageDependencies
@@ -20,7 +32,7 @@ func (c *rawStageDependencies) UnmarshalYAML(unmarshal func(interface{}) error)
 		return err
 	}
 
-	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawGit.rawStapelImage.doc); err != nil {
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.doc()); err != nil {
 		return err
 	}
 
@@ -30,19 +42,25 @@ func (c *rawStageDependencies) UnmarshalYAML(unmarshal func(interface{}) error)
 func (c *rawStageDependencies) toDirective() (stageDependencies *StageDependencies, err error) {
 	stageDependencies = &StageDependencies{}
 
-	if install, err := InterfaceToStringArray(c.Install, c, c.rawGit.rawStapelImage.doc); err != nil {
+	if beforeInstall, err := InterfaceToStringArray(c.BeforeInstall, c, c.doc()); err != nil {
+		return nil, err
+	} else {
+		stageDependencies.BeforeInstall = beforeInstall
+	}
+
+	if install, err := InterfaceToStringArray(c.Install, c, c.doc()); err != nil {
 		return nil, err
 	} else {
 		stageDependencies.Install = install
 	}
 
-	if beforeSetup, err := InterfaceToStringArray(c.BeforeSetup, c, c.rawGit.rawStapelImage.doc); err != nil {
+	if beforeSetup, err := InterfaceToStringArray(c.BeforeSetup, c, c.doc()); err != nil {
 		return nil, err
 	} else {
 		stageDependencies.BeforeSetup = beforeSetup
 	}
 
-	if setup, err := InterfaceToStringArray(c.Setup, c, c.rawGit.rawStapelImage.doc); err != nil {
+	if setup, err := InterfaceToStringArray(c.Setup, c, c.doc()); err != nil {
 		return nil, err
 	} else {
 		stageDependencies.Setup = setup
@@ -0,0 +1,63 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/werf/pkg/util"
+)
+
+type stageDependenciesEntry struct {
+	content                   string
+	expectedGitDependencies   *StageDependencies
+	expectedImageDependencies *StageDependencies
+	expectedErr               string
+}
+
+var _ = DescribeTable("parsing stage dependencies", func(e stageDependenciesEntry) {
+	parentStack = util.NewStack()
+
+	_, rawStapelImage, _, err := parseDoc(&doc{Content: []byte(e.content), RenderFilePath: "werf.yaml"})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	images, err := rawStapelImage.toStapelImageDirectives()
+	if e.expectedErr != "" {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(e.expectedErr))
+		return
+	}
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(images).Should(HaveLen(1))
+
+	imageBase := images[0].StapelImageBase
+
+	if e.expectedGitDependencies != nil {
+		Ω(imageBase.Git.Local).Should(HaveLen(1))
+		Ω(imageBase.Git.Local[0].GitMappingStageDependencies()).Should(Equal(e.expectedGitDependencies))
+	}
+
+	if e.expectedImageDependencies != nil {
+		Ω(imageBase.GitMappingDependencies()).Should(Equal(e.expectedImageDependencies))
+	} else {
+		Ω(imageBase.Dependencies).Should(BeNil())
+	}
+},
+	Entry("git mapping beforeInstall dependencies", stageDependenciesEntry{
+		content: "image: app\nfrom: alpine\ngit:\n- add: /\n  to: /app\n  stageDependencies:\n    beforeInstall: apt-packages.txt\n    install:\n    - go.mod\n    - go.sum\n",
+		expectedGitDependencies: &StageDependencies{
+			BeforeInstall: []string{"apt-packages.txt"},
+			Install:       []string{"go.mod", "go.sum"},
+		},
+	}),
+	Entry("image dependencies", stageDependenciesEntry{
+		content: "image: app\nfrom: alpine\ndependencies:\n  beforeInstall:\n  - apt-packages.txt\n  setup: '**/*.conf'\n",
+		expectedImageDependencies: &StageDependencies{
+			BeforeInstall: []string{"apt-packages.txt"},
+			Setup:         []string{"**/*.conf"},
+		},
+	}),
+	Entry("image dependencies with absolute path", stageDependenciesEntry{
+		content:     "image: app\nfrom: alpine\ndependencies:\n  beforeInstall: /apt-packages.txt\n",
+		expectedErr: "`beforeInstall: [PATH, ...]|PATH` should be relative paths!",
+	}),
+)
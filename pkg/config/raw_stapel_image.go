@@ -6,21 +6,23 @@ import (
 )
 
 type rawStapelImage struct {
-	Images                                              []string     `yaml:"-"`
-	Artifact                                            string       `yaml:"artifact,omitempty"`
-	From                                                string       `yaml:"from,omitempty"`
-	FromLatest                                          bool         `yaml:"fromLatest,omitempty"`
-	HerebyIAdmitThatFromLatestMightBreakReproducibility bool         `yaml:"herebyIAdmitThatFromLatestMightBreakReproducibility,omitempty"`
-	FromCacheVersion                                    string       `yaml:"fromCacheVersion,omitempty"`
-	FromImage                                           string       `yaml:"fromImage,omitempty"`
-	FromImageArtifact                                   string       `yaml:"fromImageArtifact,omitempty"`
-	RawGit                                              []*rawGit    `yaml:"git,omitempty"`
-	RawShell                                            *rawShell    `yaml:"shell,omitempty"`
-	RawAnsible                                          *rawAnsible  `yaml:"ansible,omitempty"`
-	RawMount                                            []*rawMount  `yaml:"mount,omitempty"`
-	RawDocker                                           *rawDocker   `yaml:"docker,omitempty"`
-	RawImport                                           []*rawImport `yaml:"import,omitempty"`
-	AsLayers                                            bool         `yaml:"asLayers,omitempty"`
+	Images                                              []string              `yaml:"-"`
+	Artifact                                            string                `yaml:"artifact,omitempty"`
+	From                                                string                `yaml:"from,omitempty"`
+	FromLatest                                          bool                  `yaml:"fromLatest,omitempty"`
+	HerebyIAdmitThatFromLatestMightBreakReproducibility bool                  `yaml:"herebyIAdmitThatFromLatestMightBreakReproducibility,omitempty"`
+	FromCacheVersion                                    string                `yaml:"fromCacheVersion,omitempty"`
+	FromImage                                           string                `yaml:"fromImage,omitempty"`
+	FromImageArtifact                                   string                `yaml:"fromImageArtifact,omitempty"`
+	RawGit                                              []*rawGit             `yaml:"git,omitempty"`
+	RawShell                                            *rawShell             `yaml:"shell,omitempty"`
+	RawAnsible                                          *rawAnsible           `yaml:"ansible,omitempty"`
+	RawMount                                            []*rawMount           `yaml:"mount,omitempty"`
+	RawDocker                                           *rawDocker            `yaml:"docker,omitempty"`
+	RawImport                                           []*rawImport          `yaml:"import,omitempty"`
+	RawDependencies                                     *rawStageDependencies `yaml:"dependencies,omitempty"`
+	AsLayers                                            bool                  `yaml:"asLayers,omitempty"`
+	Platform                                            interface{}           `yaml:"platform,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -578,6 +580,9 @@ func (c *rawStapelImage) toAnsibleWithTaskByStage(task *AnsibleTask, stage strin
 	case "setup":
 		ansible.Setup = []*AnsibleTask{task}
 	}
+	ansible.Roles = c.RawAnsible.Roles
+	ansible.Library = c.RawAnsible.Library
+	ansible.AllowUnsupportedModules = c.RawAnsible.AllowUnsupportedModules
 	ansible.raw = c.RawAnsible
 	return
 }
@@ -646,6 +651,14 @@ func (c *rawStapelImage) toStapelImageBaseDirective(name string) (imageBase *Sta
 		}
 	}
 
+	if c.RawDependencies != nil {
+		if dependencies, err := c.RawDependencies.toDirective(); err != nil {
+			return nil, err
+		} else {
+			imageBase.Dependencies = dependencies
+		}
+	}
+
 	if err := c.validateStapelImageBaseDirective(imageBase); err != nil {
 		return nil, err
 	}
@@ -673,7 +686,12 @@ func (c *rawStapelImage) toBaseStapelImageBaseDirective(name string) (imageBase
 		}
 	}
 
+	if imageBase.Platform, err = toPlatformDirective(c.Platform, c, c.doc); err != nil {
+		return nil, err
+	}
+
 	imageBase.Git = &GitManager{}
+	imageBase.ConfigImportsChecksum = c.doc.ConfigImportsChecksum
 
 	imageBase.raw = c
 
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	schemaMetaDefinition            = "Meta"
+	schemaStapelImageDefinition     = "StapelImage"
+	schemaArtifactDefinition        = "Artifact"
+	schemaDockerfileImageDefinition = "DockerfileImage"
+)
+
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+	Definitions          map[string]*JSONSchema `json:"definitions,omitempty"`
+}
+
+// GetWerfConfigJSONSchema generates JSON Schema of the werf.yaml document by the raw config structures:
+// each document is either meta, stapel image, artifact or dockerfile image
+func GetWerfConfigJSONSchema() *JSONSchema {
+	definitions := map[string]*JSONSchema{}
+	g := &schemaGenerator{definitions: definitions}
+
+	meta := g.structSchema(reflect.TypeOf(rawMeta{}))
+	meta.Title = "Meta config section"
+	meta.Required = []string{"configVersion", "project"}
+	definitions[schemaMetaDefinition] = meta
+
+	imageNameSchema := &JSONSchema{
+		Description: "Image name, list of image names or ~ for nameless image",
+		AnyOf: []*JSONSchema{
+			{Type: "string"},
+			{Type: "null"},
+			{Type: "array", Items: &JSONSchema{Type: "string"}},
+		},
+	}
+
+	stapelImage := g.structSchema(reflect.TypeOf(rawStapelImage{}))
+	stapelImage.Title = "Stapel image config section"
+	delete(stapelImage.Properties, "artifact")
+	stapelImage.Properties["image"] = imageNameSchema
+	stapelImage.Required = []string{"image"}
+	definitions[schemaStapelImageDefinition] = stapelImage
+
+	artifact := g.structSchema(reflect.TypeOf(rawStapelImage{}))
+	artifact.Title = "Stapel artifact config section"
+	artifact.Required = []string{"artifact"}
+	definitions[schemaArtifactDefinition] = artifact
+
+	dockerfileImage := g.structSchema(reflect.TypeOf(rawImageFromDockerfile{}))
+	dockerfileImage.Title = "Dockerfile image config section"
+	dockerfileImage.Properties["image"] = imageNameSchema
+	dockerfileImage.Required = []string{"image", "dockerfile"}
+	definitions[schemaDockerfileImageDefinition] = dockerfileImage
+
+	return &JSONSchema{
+		Schema:      "http://json-schema.org/draft-04/schema#",
+		Title:       "werf.yaml",
+		Description: "werf config document (part of YAML stream separated by three hyphens)",
+		OneOf: []*JSONSchema{
+			schemaDefinitionRef(schemaMetaDefinition),
+			schemaDefinitionRef(schemaStapelImageDefinition),
+			schemaDefinitionRef(schemaArtifactDefinition),
+			schemaDefinitionRef(schemaDockerfileImageDefinition),
+		},
+		Definitions: definitions,
+	}
+}
+
+func getWerfConfigDocumentJSONSchema(definition string) *JSONSchema {
+	schema := GetWerfConfigJSONSchema()
+	schema.OneOf = nil
+	schema.Ref = schemaDefinitionRef(definition).Ref
+	return schema
+}
+
+func schemaDefinitionRef(definition string) *JSONSchema {
+	return &JSONSchema{Ref: "#/definitions/" + definition}
+}
+
+type schemaGenerator struct {
+	definitions map[string]*JSONSchema
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) *JSONSchema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: "array", Items: g.typeSchema(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &JSONSchema{Type: "object"}
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: g.typeSchema(t.Elem())}
+	case reflect.Interface:
+		// raw config interface fields always accept single string or array of strings
+		return &JSONSchema{
+			AnyOf: []*JSONSchema{
+				{Type: "string"},
+				{Type: "array", Items: &JSONSchema{Type: "string"}},
+			},
+		}
+	case reflect.Struct:
+		name := schemaDefinitionName(t)
+		if _, exists := g.definitions[name]; !exists {
+			g.definitions[name] = nil // break recursion
+			g.definitions[name] = g.structSchema(t)
+		}
+		return schemaDefinitionRef(name)
+	default:
+		return &JSONSchema{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *JSONSchema {
+	schema := &JSONSchema{
+		Type:                 "object",
+		Properties:           map[string]*JSONSchema{},
+		AdditionalProperties: false,
+	}
+
+	g.addStructFields(schema, t)
+
+	return schema
+}
+
+func (g *schemaGenerator) addStructFields(schema *JSONSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("yaml")
+		if tag == "-" || tag == "" {
+			continue
+		}
+
+		tagParts := strings.Split(tag, ",")
+		name := tagParts[0]
+		isInline := false
+		for _, opt := range tagParts[1:] {
+			if opt == "inline" {
+				isInline = true
+			}
+		}
+
+		if isInline {
+			switch field.Type.Kind() {
+			case reflect.Struct:
+				g.addStructFields(schema, field.Type)
+			case reflect.Map:
+				// UnsupportedAttributes collects unknown fields to report them, other inline maps accept arbitrary fields
+				if field.Name != "UnsupportedAttributes" {
+					schema.AdditionalProperties = true
+				}
+			}
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		schema.Properties[name] = g.typeSchema(field.Type)
+	}
+}
+
+func schemaDefinitionName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "raw")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
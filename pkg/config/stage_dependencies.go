@@ -1,20 +1,32 @@
 package config
 
 type StageDependencies struct {
-	Install     []string
-	Setup       []string
-	BeforeSetup []string
+	BeforeInstall []string
+	Install       []string
+	Setup         []string
+	BeforeSetup   []string
 
 	raw *rawStageDependencies
 }
 
 func (c *StageDependencies) validate() error {
-	if !allRelativePaths(c.Install) {
-		return newDetailedConfigError("`install: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.rawGit.rawStapelImage.doc)
+	if !allRelativePaths(c.BeforeInstall) {
+		return newDetailedConfigError("`beforeInstall: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.doc())
+	} else if !allRelativePaths(c.Install) {
+		return newDetailedConfigError("`install: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.doc())
 	} else if !allRelativePaths(c.Setup) {
-		return newDetailedConfigError("`setup: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.rawGit.rawStapelImage.doc)
+		return newDetailedConfigError("`setup: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.doc())
 	} else if !allRelativePaths(c.BeforeSetup) {
-		return newDetailedConfigError("`beforeSetup: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.rawGit.rawStapelImage.doc)
+		return newDetailedConfigError("`beforeSetup: [PATH, ...]|PATH` should be relative paths!", c.raw, c.raw.doc())
 	}
 	return nil
 }
+
+func (c *StageDependencies) gitMappingStageDependencies() *StageDependencies {
+	s := &StageDependencies{}
+	s.BeforeInstall = gitMappingPaths(c.BeforeInstall)
+	s.Install = gitMappingPaths(c.Install)
+	s.BeforeSetup = gitMappingPaths(c.BeforeSetup)
+	s.Setup = gitMappingPaths(c.Setup)
+	return s
+}
@@ -17,6 +17,9 @@ type StapelImageBase struct {
 	Ansible                                             *Ansible
 	Mount                                               []*Mount
 	Import                                              []*Import
+	Dependencies                                        *StageDependencies
+	ConfigImportsChecksum                               string
+	Platform                                            []string
 
 	raw *rawStapelImage
 }
@@ -25,6 +28,11 @@ func (c *StapelImageBase) GetName() string {
 	return c.Name
 }
 
+// GitMappingDependencies returns project dependencies, the paths are relative to the project git repository root
+func (c *StapelImageBase) GitMappingDependencies() *StageDependencies {
+	return c.Dependencies.gitMappingStageDependencies()
+}
+
 func (c *StapelImageBase) imports() []*Import {
 	return c.Import
 }
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	yaml_v3 "gopkg.in/yaml.v3"
+
+	"github.com/flant/werf/pkg/tmp_manager"
+	"github.com/flant/werf/pkg/util"
+)
+
+type ValidationError struct {
+	FilePath string
+	Line     int
+	Column   int
+	Message  string
+}
+
+func (e *ValidationError) Error() string {
+	switch {
+	case e.Line == 0:
+		return fmt.Sprintf("%s: %s", e.FilePath, e.Message)
+	case e.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", e.FilePath, e.Line, e.Message)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", e.FilePath, e.Line, e.Column, e.Message)
+	}
+}
+
+// ValidateWerfConfig renders werf config and reports all found problems with positions in the rendered document,
+// in contrast to GetWerfConfig, which fails on the first problem
+func ValidateWerfConfig(werfConfigPath, werfConfigTemplatesDir string) (string, []*ValidationError, error) {
+	werfConfigRenderContent, _, err := parseWerfConfigYaml(werfConfigPath, werfConfigTemplatesDir)
+	if err != nil {
+		return "", []*ValidationError{{FilePath: werfConfigPath, Message: fmt.Sprintf("cannot parse config: %s", err)}}, nil
+	}
+
+	werfConfigRenderPath, err := tmp_manager.CreateWerfConfigRender()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := writeWerfConfigRender(werfConfigRenderContent, werfConfigRenderPath); err != nil {
+		return "", nil, fmt.Errorf("unable to write rendered config to %s: %s", werfConfigRenderPath, err)
+	}
+
+	docs, err := splitByDocs(werfConfigRenderContent, werfConfigRenderPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	v := &validator{renderPath: werfConfigRenderPath, schemas: map[string]*gojsonschema.Schema{}}
+
+	var meta *Meta
+	var rawStapelImages []*rawStapelImage
+	var rawImagesFromDockerfile []*rawImageFromDockerfile
+
+	parentStack = util.NewStack()
+	for _, doc := range docs {
+		if !v.validateDocSchema(doc) {
+			continue
+		}
+
+		rawMeta, rawStapelImage, rawImageFromDockerfile, err := parseDoc(doc)
+		if err != nil {
+			v.addDocError(doc, err)
+			continue
+		}
+
+		switch {
+		case rawMeta != nil:
+			if meta != nil {
+				v.addDocError(doc, errors.New("duplicate meta config section definition"))
+				continue
+			}
+			meta = rawMeta.toMeta()
+		case rawImageFromDockerfile != nil:
+			if _, err := rawImageFromDockerfile.toImageFromDockerfileDirectives(); err != nil {
+				v.addDocError(doc, err)
+				continue
+			}
+			rawImagesFromDockerfile = append(rawImagesFromDockerfile, rawImageFromDockerfile)
+		case rawStapelImage != nil:
+			if rawStapelImage.stapelImageType() == "images" {
+				_, err = rawStapelImage.toStapelImageDirectives()
+			} else {
+				_, err = rawStapelImage.toStapelImageArtifactDirectives()
+			}
+			if err != nil {
+				v.addDocError(doc, err)
+				continue
+			}
+			rawStapelImages = append(rawStapelImages, rawStapelImage)
+		}
+	}
+
+	if len(v.errors) == 0 {
+		if meta == nil {
+			v.errors = append(v.errors, &ValidationError{FilePath: werfConfigRenderPath, Message: "meta config section is not defined"})
+		} else if _, err := prepareWerfConfig(rawStapelImages, rawImagesFromDockerfile, meta); err != nil {
+			v.errors = append(v.errors, &ValidationError{FilePath: werfConfigRenderPath, Message: errorSummary(err)})
+		}
+	}
+
+	return werfConfigRenderPath, v.errors, nil
+}
+
+type validator struct {
+	renderPath string
+	schemas    map[string]*gojsonschema.Schema
+	errors     []*ValidationError
+}
+
+var yamlErrorLineRegexp = regexp.MustCompile(`line ([0-9]+)`)
+
+// validateDocSchema returns true if the document matches JSON Schema
+func (v *validator) validateDocSchema(doc *doc) bool {
+	var node yaml_v3.Node
+	if err := yaml_v3.Unmarshal(doc.Content, &node); err != nil {
+		line := doc.Line + 1
+		if res := yamlErrorLineRegexp.FindStringSubmatch(err.Error()); len(res) == 2 {
+			if errLine, err := strconv.Atoi(res[1]); err == nil {
+				line = doc.Line + errLine
+			}
+		}
+		v.errors = append(v.errors, &ValidationError{FilePath: v.renderPath, Line: line, Message: err.Error()})
+		return false
+	}
+
+	if len(node.Content) == 0 || node.Content[0].Kind != yaml_v3.MappingNode {
+		v.errors = append(v.errors, &ValidationError{FilePath: v.renderPath, Line: doc.Line + 1, Message: "config section should be a map"})
+		return false
+	}
+	root := node.Content[0]
+
+	var raw map[string]interface{}
+	if err := root.Decode(&raw); err != nil {
+		v.errors = append(v.errors, &ValidationError{FilePath: v.renderPath, Line: doc.Line + root.Line, Column: root.Column, Message: err.Error()})
+		return false
+	}
+
+	var definition string
+	switch {
+	case isMetaDoc(raw):
+		definition = schemaMetaDefinition
+	case isImageFromDockerfileDoc(raw):
+		definition = schemaDockerfileImageDefinition
+	case isImageDoc(raw):
+		if _, ok := raw["artifact"]; ok {
+			definition = schemaArtifactDefinition
+		} else {
+			definition = schemaStapelImageDefinition
+		}
+	default:
+		v.errors = append(v.errors, &ValidationError{FilePath: v.renderPath, Line: doc.Line + root.Line, Column: root.Column, Message: "cannot recognize type of config section: 'configVersion' required for meta config section, 'image' required for the image config sections, 'artifact' required for the artifact config sections"})
+		return false
+	}
+
+	// empty directives are allowed in the config and treated as not specified, except nameless image
+	for key, value := range raw {
+		if value == nil {
+			if key != "image" {
+				delete(raw, key)
+			}
+			continue
+		}
+		raw[key] = removeNullValues(value)
+	}
+
+	schema, err := v.getSchema(definition)
+	if err != nil {
+		panic(fmt.Sprintf("invalid werf config json schema: %s", err))
+	}
+
+	result, err := schema.Validate(gojsonschema.NewGoLoader(raw))
+	if err != nil {
+		v.errors = append(v.errors, &ValidationError{FilePath: v.renderPath, Line: doc.Line + root.Line, Column: root.Column, Message: err.Error()})
+		return false
+	}
+
+	var docErrors []*ValidationError
+	for _, resultErr := range result.Errors() {
+		path := strings.Split(resultErr.Context().String("\000"), "\000")[1:]
+		if property, ok := resultErr.Details()["property"].(string); ok && resultErr.Type() == "additional_property_not_allowed" {
+			path = append(path, property)
+		}
+
+		// the reasons of anyOf mismatch are reported separately with the same position
+		if resultErr.Type() == "number_any_of" && len(result.Errors()) > 1 {
+			continue
+		}
+
+		node := findYamlNode(root, path)
+		docErrors = append(docErrors, &ValidationError{
+			FilePath: v.renderPath,
+			Line:     doc.Line + node.Line,
+			Column:   node.Column,
+			Message:  resultErr.Description(),
+		})
+	}
+
+	sort.SliceStable(docErrors, func(i, j int) bool {
+		if docErrors[i].Line != docErrors[j].Line {
+			return docErrors[i].Line < docErrors[j].Line
+		}
+		return docErrors[i].Column < docErrors[j].Column
+	})
+	v.errors = append(v.errors, docErrors...)
+
+	return result.Valid()
+}
+
+func (v *validator) getSchema(definition string) (*gojsonschema.Schema, error) {
+	if schema, ok := v.schemas[definition]; ok {
+		return schema, nil
+	}
+
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(getWerfConfigDocumentJSONSchema(definition)))
+	if err != nil {
+		return nil, err
+	}
+	v.schemas[definition] = schema
+
+	return schema, nil
+}
+
+func (v *validator) addDocError(doc *doc, err error) {
+	line := doc.Line + 1
+	message := errorSummary(err)
+
+	if res := yamlErrorLineRegexp.FindStringSubmatch(message); len(res) == 2 {
+		if errLine, err := strconv.Atoi(res[1]); err == nil {
+			line = errLine
+		}
+	}
+
+	v.errors = append(v.errors, &ValidationError{FilePath: v.renderPath, Line: line, Message: message})
+}
+
+// findYamlNode returns the deepest existing node by the path of map keys and sequence indexes
+func findYamlNode(node *yaml_v3.Node, path []string) *yaml_v3.Node {
+	for _, elm := range path {
+		switch node.Kind {
+		case yaml_v3.MappingNode:
+			var found *yaml_v3.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == elm {
+					found = node.Content[i+1]
+					if found.Kind == yaml_v3.ScalarNode {
+						// point to the key of the scalar value
+						found = node.Content[i]
+					}
+					break
+				}
+			}
+			if found == nil {
+				return node
+			}
+			node = found
+		case yaml_v3.SequenceNode:
+			ind, err := strconv.Atoi(elm)
+			if err != nil || ind >= len(node.Content) {
+				return node
+			}
+			node = node.Content[ind]
+		default:
+			return node
+		}
+	}
+
+	return node
+}
+
+func removeNullValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elm := range v {
+			if elm == nil {
+				delete(v, key)
+			} else {
+				v[key] = removeNullValues(elm)
+			}
+		}
+	case []interface{}:
+		for ind, elm := range v {
+			v[ind] = removeNullValues(elm)
+		}
+	}
+	return value
+}
+
+// errorSummary cuts off the config dump of the detailed config errors
+func errorSummary(err error) string {
+	return strings.SplitN(strings.TrimSpace(err.Error()), "\n\n", 2)[0]
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"github.com/xeipuuv/gojsonschema"
+
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type validateDocSchemaEntry struct {
+	content        string
+	expectedErrors []string
+}
+
+var _ = DescribeTable("validating config document by json schema", func(e validateDocSchemaEntry) {
+	v := &validator{renderPath: "werf.yaml", schemas: map[string]*gojsonschema.Schema{}}
+	v.validateDocSchema(&doc{Content: []byte(e.content), Line: 10, RenderFilePath: "werf.yaml"})
+
+	var errors []string
+	for _, err := range v.errors {
+		errors = append(errors, err.Error())
+	}
+
+	Ω(errors).Should(Equal(e.expectedErrors))
+},
+	Entry("valid meta", validateDocSchemaEntry{
+		content: "project: name\nconfigVersion: 1\n",
+	}),
+	Entry("valid nameless image with empty directives", validateDocSchemaEntry{
+		content: "image: ~\nfrom: alpine\nshell:\n  install:\n",
+	}),
+	Entry("unknown directive", validateDocSchemaEntry{
+		content: "image: app\nfrom: alpine\nshell:\n  install: true\n  unknown: value\n",
+		expectedErrors: []string{
+			"werf.yaml:14:3: Invalid type. Expected: string, given: boolean",
+			"werf.yaml:15:3: Additional property unknown is not allowed",
+		},
+	}),
+	Entry("invalid git mapping", validateDocSchemaEntry{
+		content: "artifact: art\nfrom: alpine\ngit:\n- add: /\n  to: 1\n",
+		expectedErrors: []string{
+			"werf.yaml:15:3: Invalid type. Expected: string, given: integer",
+		},
+	}),
+	Entry("unknown document", validateDocSchemaEntry{
+		content: "from: alpine\n",
+		expectedErrors: []string{
+			"werf.yaml:11:1: cannot recognize type of config section: 'configVersion' required for meta config section, 'image' required for the image config sections, 'artifact' required for the artifact config sections",
+		},
+	}),
+)
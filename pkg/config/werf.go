@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/flant/werf/pkg/util"
 )
 
 type WerfConfig struct {
@@ -257,6 +259,85 @@ func (c *WerfConfig) validateImageFrom(i *StapelImageBase) error {
 	return nil
 }
 
+func (c *WerfConfig) validateImagesPlatforms() error {
+	var images []StapelImageInterface
+	for _, image := range c.StapelImages {
+		images = append(images, image)
+	}
+	for _, artifact := range c.Artifacts {
+		images = append(images, artifact)
+	}
+
+	for _, image := range images {
+		imageBaseConfig := image.ImageBaseConfig()
+
+		// the image is built on top of the same platform variant of the base image
+		for _, fromName := range []string{imageBaseConfig.FromImageName, imageBaseConfig.FromImageArtifactName} {
+			if fromName == "" {
+				continue
+			}
+
+			var fromPlatforms []string
+			if fromImage := c.GetImage(fromName); fromImage != nil {
+				fromPlatforms = imagePlatforms(fromImage)
+			} else if fromArtifact := c.GetArtifact(fromName); fromArtifact != nil {
+				fromPlatforms = imagePlatforms(fromArtifact)
+			}
+
+			if !isStringsSetsEqual(fromPlatforms, imageBaseConfig.Platform) {
+				return newDetailedConfigError(fmt.Sprintf("platforms of the base image `%s` should be the same as platforms of the image!", fromName), nil, imageBaseConfig.raw.doc)
+			}
+		}
+
+		// files are imported from the same platform variant or from the only variant of the image without platforms
+		for _, imp := range image.imports() {
+			var importPlatforms []string
+			if imp.ImageName != "" {
+				importPlatforms = imagePlatforms(c.GetImage(imp.ImageName))
+			} else if imp.ArtifactName != "" {
+				importPlatforms = imagePlatforms(c.GetArtifact(imp.ArtifactName))
+			}
+
+			if len(importPlatforms) == 0 {
+				continue
+			}
+
+			for _, platform := range imageBaseConfig.Platform {
+				if !util.IsStringsContainValue(importPlatforms, platform) {
+					return newDetailedConfigError(fmt.Sprintf("import source is not built for platform `%s` of the image!", platform), imp.raw, imageBaseConfig.raw.doc)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func imagePlatforms(interf ImageInterface) []string {
+	switch i := interf.(type) {
+	case StapelImageInterface:
+		return i.ImageBaseConfig().Platform
+	case *ImageFromDockerfile:
+		return i.Platform
+	}
+
+	return nil
+}
+
+func isStringsSetsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, value := range a {
+		if !util.IsStringsContainValue(b, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (c *WerfConfig) validateInfiniteLoopBetweenRelatedImages() error {
 	var imageAndArtifactNames []string
 
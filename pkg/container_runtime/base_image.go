@@ -6,21 +6,20 @@ import (
 	"github.com/flant/werf/pkg/image"
 
 	"github.com/docker/docker/api/types"
-	"github.com/flant/werf/pkg/docker"
 )
 
 type baseImage struct {
-	name             string
-	inspect          *types.ImageInspect
+	name      string
+	inspect   *types.ImageInspect
 	stageDesc *image.StageDescription
 
-	LocalDockerServerRuntime *LocalDockerServerRuntime
+	LocalRuntime LocalRuntime
 }
 
-func newBaseImage(name string, localDockerServerRuntime *LocalDockerServerRuntime) *baseImage {
+func newBaseImage(name string, localRuntime LocalRuntime) *baseImage {
 	image := &baseImage{}
 	image.name = name
-	image.LocalDockerServerRuntime = localDockerServerRuntime
+	image.LocalRuntime = localRuntime
 	return image
 }
 
@@ -33,7 +32,7 @@ func (i *baseImage) SetName(name string) {
 }
 
 func (i *baseImage) MustResetInspect() error {
-	if inspect, err := i.LocalDockerServerRuntime.GetImageInspect(i.Name()); err != nil {
+	if inspect, err := i.LocalRuntime.GetImageInspect(i.Name()); err != nil {
 		return fmt.Errorf("unable to get inspect for image %s: %s", i.Name(), err)
 	} else {
 		i.SetInspect(inspect)
@@ -58,7 +57,7 @@ func (i *baseImage) UnsetInspect() {
 }
 
 func (i *baseImage) Untag() error {
-	if err := docker.CliRmi(i.name, "--force"); err != nil {
+	if err := i.LocalRuntime.localCli().Rmi(i.name, "--force"); err != nil {
 		return err
 	}
 
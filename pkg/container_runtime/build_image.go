@@ -4,8 +4,8 @@ type buildImage struct {
 	*baseImage
 }
 
-func newBuildImage(id string, localDockerServerRuntime *LocalDockerServerRuntime) *buildImage {
+func newBuildImage(id string, localRuntime LocalRuntime) *buildImage {
 	image := &buildImage{}
-	image.baseImage = newBaseImage(id, localDockerServerRuntime)
+	image.baseImage = newBaseImage(id, localRuntime)
 	return image
 }
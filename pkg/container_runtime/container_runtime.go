@@ -2,13 +2,12 @@ package container_runtime
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/flant/logboek"
 
 	"github.com/docker/docker/api/types"
-	"github.com/flant/werf/pkg/docker"
-
-	"github.com/docker/docker/client"
 )
 
 type ContainerRuntime interface {
@@ -19,18 +18,72 @@ type ContainerRuntime interface {
 	String() string
 }
 
-type LocalDockerServerRuntime struct{}
+// LocalRuntime keeps images locally and builds stapel stages by running and committing containers:
+// LocalDockerServerRuntime uses docker server, LocalPodmanRuntime uses daemonless podman
+type LocalRuntime interface {
+	ContainerRuntime
+
+	GetImageInspect(ref string) (*types.ImageInspect, error)
+	CreateEmptyImage(ref string) error
+	PushImage(img Image) error
+	PushBuiltImage(img Image) error
+	TagBuiltImageByName(img Image) error
+	SaveImages(path string, refs ...string) error
+
+	// auxiliary containers, e.g. import servers, are created in the same runtime as the build containers
+	GetOrCreateStapelContainer() (string, error)
+	CreateContainer(args ...string) error
+	RunDetachedContainer(args ...string) error
+	RemoveContainer(containerName string) error
+	GetContainerIPAddress(containerName string) (string, error)
+	GetContainerPathStat(containerName, path string) (types.ContainerPathStat, error)
+	CopyFromContainer(containerName, path string) (io.ReadCloser, error)
+
+	localCli() localCli
+}
 
-// GetImageInspect only avaiable for LocalDockerServerRuntime
-func (runtime *LocalDockerServerRuntime) GetImageInspect(ref string) (*types.ImageInspect, error) {
-	inspect, err := docker.ImageInspect(ref)
-	if client.IsErrNotFound(err) {
-		return nil, nil
-	}
-	return inspect, err
+func NewLocalDockerServerRuntime() *LocalDockerServerRuntime {
+	return &LocalDockerServerRuntime{localRuntime: localRuntime{cli: dockerServerCli{}}}
+}
+
+type LocalDockerServerRuntime struct {
+	localRuntime
+}
+
+func (runtime *LocalDockerServerRuntime) String() string {
+	return "local-docker-server"
+}
+
+func NewLocalPodmanRuntime() *LocalPodmanRuntime {
+	return &LocalPodmanRuntime{localRuntime: localRuntime{cli: podmanCli{}}}
+}
+
+type LocalPodmanRuntime struct {
+	localRuntime
+}
+
+func (runtime *LocalPodmanRuntime) String() string {
+	return "local-podman"
+}
+
+type localRuntime struct {
+	cli localCli
+}
+
+func (runtime *localRuntime) localCli() localCli {
+	return runtime.cli
+}
+
+// GetImageInspect returns nil if image does not exist locally
+func (runtime *localRuntime) GetImageInspect(ref string) (*types.ImageInspect, error) {
+	return runtime.cli.ImageInspect(ref)
 }
 
-func (runtime *LocalDockerServerRuntime) RefreshImageObject(img Image) error {
+func (runtime *localRuntime) CreateEmptyImage(ref string) error {
+	return runtime.cli.CreateEmptyImage(ref)
+}
+
+func (runtime *localRuntime) RefreshImageObject(img Image) error {
 	dockerImage := img.(*DockerImage)
 
 	if inspect, err := runtime.GetImageInspect(dockerImage.Image.Name()); err != nil {
@@ -41,11 +94,11 @@ func (runtime *LocalDockerServerRuntime) RefreshImageObject(img Image) error {
 	return nil
 }
 
-func (runtime *LocalDockerServerRuntime) RenameImage(img Image, newImageName string, removeOldName bool) error {
+func (runtime *localRuntime) RenameImage(img Image, newImageName string, removeOldName bool) error {
 	dockerImage := img.(*DockerImage)
 
 	if err := logboek.Info.LogProcess(fmt.Sprintf("Tagging image %s by name %s", dockerImage.Image.Name(), newImageName), logboek.LevelLogProcessOptions{}, func() error {
-		if err := docker.CliTag(dockerImage.Image.Name(), newImageName); err != nil {
+		if err := runtime.cli.Tag(dockerImage.Image.Name(), newImageName); err != nil {
 			return fmt.Errorf("unable to tag image %s by name %s: %s", dockerImage.Image.Name(), newImageName, err)
 		}
 		return nil
@@ -55,7 +108,7 @@ func (runtime *LocalDockerServerRuntime) RenameImage(img Image, newImageName str
 
 	if removeOldName {
 		if err := logboek.Info.LogProcess(fmt.Sprintf("Removing old image tag %s", dockerImage.Image.Name()), logboek.LevelLogProcessOptions{}, func() error {
-			if err := docker.CliRmi(dockerImage.Image.Name()); err != nil {
+			if err := runtime.cli.Rmi(dockerImage.Image.Name()); err != nil {
 				return err
 			}
 			return nil
@@ -69,11 +122,20 @@ func (runtime *LocalDockerServerRuntime) RenameImage(img Image, newImageName str
 	return nil
 }
 
-func (runtime *LocalDockerServerRuntime) RemoveImage(img Image) error {
+func (runtime *localRuntime) SaveImages(path string, refs ...string) error {
+	return logboek.Info.LogProcess(fmt.Sprintf("Saving images into %s", path), logboek.LevelLogProcessOptions{}, func() error {
+		if err := runtime.cli.Save(path, refs...); err != nil {
+			return fmt.Errorf("unable to save images %s: %s", strings.Join(refs, ", "), err)
+		}
+		return nil
+	})
+}
+
+func (runtime *localRuntime) RemoveImage(img Image) error {
 	dockerImage := img.(*DockerImage)
 
 	if err := logboek.Info.LogProcess(fmt.Sprintf("Removing image tag %s", dockerImage.Image.Name()), logboek.LevelLogProcessOptions{}, func() error {
-		if err := docker.CliRmi(dockerImage.Image.Name()); err != nil {
+		if err := runtime.cli.Rmi(dockerImage.Image.Name()); err != nil {
 			return err
 		}
 		return nil
@@ -84,7 +146,7 @@ func (runtime *LocalDockerServerRuntime) RemoveImage(img Image) error {
 	return nil
 }
 
-func (runtime *LocalDockerServerRuntime) PullImageFromRegistry(img Image) error {
+func (runtime *localRuntime) PullImageFromRegistry(img Image) error {
 	dockerImage := img.(*DockerImage)
 
 	if err := dockerImage.Image.Pull(); err != nil {
@@ -100,11 +162,11 @@ func (runtime *LocalDockerServerRuntime) PullImageFromRegistry(img Image) error
 	return nil
 }
 
-func (runtime *LocalDockerServerRuntime) PushImage(img Image) error {
+func (runtime *localRuntime) PushImage(img Image) error {
 	dockerImage := img.(*DockerImage)
 
 	if err := logboek.Info.LogProcess(fmt.Sprintf("Pushing %s", dockerImage.Image.Name()), logboek.LevelLogProcessOptions{}, func() error {
-		return docker.CliPushWithRetries(dockerImage.Image.Name())
+		return runtime.cli.Push(dockerImage.Image.Name())
 	}); err != nil {
 		return err
 	}
@@ -112,8 +174,7 @@ func (runtime *LocalDockerServerRuntime) PushImage(img Image) error {
 	return nil
 }
 
-// PushBuiltImage is only available for LocalDockerServerRuntime
-func (runtime *LocalDockerServerRuntime) PushBuiltImage(img Image) error {
+func (runtime *localRuntime) PushBuiltImage(img Image) error {
 	dockerImage := img.(*DockerImage)
 
 	if err := logboek.Info.LogProcess(fmt.Sprintf("Tagging built image by name %s", dockerImage.Image.Name()), logboek.LevelLogProcessOptions{}, func() error {
@@ -126,7 +187,7 @@ func (runtime *LocalDockerServerRuntime) PushBuiltImage(img Image) error {
 	}
 
 	if err := logboek.Info.LogProcess(fmt.Sprintf("Pushing %s", dockerImage.Image.Name()), logboek.LevelLogProcessOptions{}, func() error {
-		return docker.CliPushWithRetries(dockerImage.Image.Name())
+		return runtime.cli.Push(dockerImage.Image.Name())
 	}); err != nil {
 		return err
 	}
@@ -134,8 +195,7 @@ func (runtime *LocalDockerServerRuntime) PushBuiltImage(img Image) error {
 	return nil
 }
 
-// TagBuiltImageByName is only available for LocalDockerServerRuntime
-func (runtime *LocalDockerServerRuntime) TagBuiltImageByName(img Image) error {
+func (runtime *localRuntime) TagBuiltImageByName(img Image) error {
 	dockerImage := img.(*DockerImage)
 
 	if err := dockerImage.Image.TagBuiltImage(dockerImage.Image.Name()); err != nil {
@@ -144,8 +204,35 @@ func (runtime *LocalDockerServerRuntime) TagBuiltImageByName(img Image) error {
 	return nil
 }
 
-func (runtime *LocalDockerServerRuntime) String() string {
-	return "local-docker-server"
+func (runtime *localRuntime) GetOrCreateStapelContainer() (string, error) {
+	return runtime.cli.GetOrCreateStapelContainer()
+}
+
+func (runtime *localRuntime) CreateContainer(args ...string) error {
+	return runtime.cli.CreateContainer(args...)
+}
+
+func (runtime *localRuntime) RunDetachedContainer(args ...string) error {
+	return runtime.cli.RunDetached(args...)
+}
+
+// RemoveContainer removes the container even if it is running
+func (runtime *localRuntime) RemoveContainer(containerName string) error {
+	return runtime.cli.ForceContainerRemove(containerName)
+}
+
+// GetContainerIPAddress returns empty address if the container is not reachable from other containers by ip
+func (runtime *localRuntime) GetContainerIPAddress(containerName string) (string, error) {
+	return runtime.cli.ContainerIPAddress(containerName)
+}
+
+func (runtime *localRuntime) GetContainerPathStat(containerName, path string) (types.ContainerPathStat, error) {
+	return runtime.cli.ContainerStatPath(containerName, path)
+}
+
+// CopyFromContainer returns tar archive of the path, the archive should be closed by the caller
+func (runtime *localRuntime) CopyFromContainer(containerName, path string) (io.ReadCloser, error) {
+	return runtime.cli.CopyFromContainer(containerName, path)
 }
 
 type LocalHostRuntime struct {
@@ -4,18 +4,19 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
-
-	"github.com/flant/werf/pkg/docker"
 )
 
 type DockerfileImageBuilder struct {
-	temporalId string
-	isBuilt    bool
-	BuildArgs  []string
+	localRuntime LocalRuntime
+	temporalId   string
+	isBuilt      bool
+	BuildArgs    []string
+
+	stagesTemporalIds []string
 }
 
-func NewDockerfileImageBuilder() *DockerfileImageBuilder {
-	return &DockerfileImageBuilder{temporalId: uuid.New().String()}
+func NewDockerfileImageBuilder(localRuntime LocalRuntime) *DockerfileImageBuilder {
+	return &DockerfileImageBuilder{localRuntime: localRuntime, temporalId: uuid.New().String()}
 }
 
 func (b *DockerfileImageBuilder) GetBuiltId() string {
@@ -32,7 +33,7 @@ func (b *DockerfileImageBuilder) AppendBuildArgs(buildArgs ...string) {
 func (b *DockerfileImageBuilder) Build() error {
 	buildArgs := append(b.BuildArgs, fmt.Sprintf("--tag=%s", b.temporalId))
 
-	if err := docker.CliBuild_LiveOutput(buildArgs...); err != nil {
+	if err := b.localRuntime.localCli().Build(buildArgs...); err != nil {
 		return err
 	}
 
@@ -41,9 +42,29 @@ func (b *DockerfileImageBuilder) Build() error {
 	return nil
 }
 
+// BuildStage builds the Dockerfile stage with the same build args and returns the temporal image name, the image is removed by Cleanup
+func (b *DockerfileImageBuilder) BuildStage(target string, extraBuildArgs ...string) (string, error) {
+	temporalId := uuid.New().String()
+
+	var buildArgs []string
+	buildArgs = append(buildArgs, b.BuildArgs...)
+	buildArgs = append(buildArgs, extraBuildArgs...)
+	buildArgs = append(buildArgs, fmt.Sprintf("--target=%s", target), fmt.Sprintf("--tag=%s", temporalId))
+
+	if err := b.localRuntime.localCli().Build(buildArgs...); err != nil {
+		return "", err
+	}
+
+	b.stagesTemporalIds = append(b.stagesTemporalIds, temporalId)
+
+	return temporalId, nil
+}
+
 func (b *DockerfileImageBuilder) Cleanup() error {
-	if err := docker.CliRmi(b.temporalId, "--force"); err != nil {
-		return fmt.Errorf("unable to remove temporal dockerfile image %q: %s", b.temporalId, err)
+	for _, temporalId := range append([]string{b.temporalId}, b.stagesTemporalIds...) {
+		if err := b.localRuntime.localCli().Rmi(temporalId, "--force"); err != nil {
+			return fmt.Errorf("unable to remove temporal dockerfile image %q: %s", temporalId, err)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,249 @@
+package container_runtime
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/hashicorp/go-version"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/docker"
+	"github.com/flant/werf/pkg/podman"
+	"github.com/flant/werf/pkg/stapel"
+)
+
+// localCli performs low-level operations with the local images and containers of the runtime
+type localCli interface {
+	// ImageInspect returns nil if image does not exist
+	ImageInspect(ref string) (*types.ImageInspect, error)
+	CreateEmptyImage(ref string) error
+	Tag(args ...string) error
+	Rmi(args ...string) error
+	Pull(ref string) error
+	Push(ref string) error
+	Build(args ...string) error
+	// Save writes the images into the docker-archive tarball
+	Save(path string, refs ...string) error
+
+	Run(args ...string) error
+	Commit(containerName string, changes []string) (string, error)
+	ContainerRemove(containerName string) error
+
+	CreateContainer(args ...string) error
+	RunDetached(args ...string) error
+	ForceContainerRemove(containerName string) error
+	ContainerIPAddress(containerName string) (string, error)
+	ContainerStatPath(containerName, path string) (types.ContainerPathStat, error)
+	CopyFromContainer(containerName, path string) (io.ReadCloser, error)
+
+	GetOrCreateStapelContainer() (string, error)
+	EmptyEntrypointInstructionValue() (string, error)
+}
+
+type dockerServerCli struct{}
+
+func (dockerServerCli) ImageInspect(ref string) (*types.ImageInspect, error) {
+	inspect, err := docker.ImageInspect(ref)
+	if client.IsErrNotFound(err) {
+		return nil, nil
+	}
+	return inspect, err
+}
+
+func (dockerServerCli) CreateEmptyImage(ref string) error {
+	return docker.CreateImage(ref)
+}
+
+func (dockerServerCli) Tag(args ...string) error {
+	return docker.CliTag(args...)
+}
+
+func (dockerServerCli) Rmi(args ...string) error {
+	return docker.CliRmi(args...)
+}
+
+func (dockerServerCli) Pull(ref string) error {
+	return docker.CliPullWithRetries(ref)
+}
+
+func (dockerServerCli) Push(ref string) error {
+	return docker.CliPushWithRetries(ref)
+}
+
+func (dockerServerCli) Build(args ...string) error {
+	return docker.CliBuild_LiveOutput(args...)
+}
+
+func (dockerServerCli) Save(path string, refs ...string) error {
+	return docker.ImageSaveToFile(path, refs...)
+}
+
+func (dockerServerCli) Run(args ...string) error {
+	return docker.CliRun_LiveOutput(args...)
+}
+
+func (dockerServerCli) Commit(containerName string, changes []string) (string, error) {
+	return docker.ContainerCommit(containerName, types.ContainerCommitOptions{Changes: changes})
+}
+
+func (dockerServerCli) ContainerRemove(containerName string) error {
+	return docker.ContainerRemove(containerName, types.ContainerRemoveOptions{})
+}
+
+func (dockerServerCli) CreateContainer(args ...string) error {
+	if output, err := docker.CliCreate_RecordedOutput(args...); err != nil {
+		logboek.LogErrorF("%s", output)
+		return err
+	}
+	return nil
+}
+
+func (dockerServerCli) RunDetached(args ...string) error {
+	if output, err := docker.CliRun_RecordedOutput(append([]string{"--detach"}, args...)...); err != nil {
+		logboek.LogErrorF("%s", output)
+		return err
+	}
+	return nil
+}
+
+func (dockerServerCli) ForceContainerRemove(containerName string) error {
+	if output, err := docker.CliRm_RecordedOutput("--force", containerName); err != nil {
+		logboek.LogErrorF("%s", output)
+		return err
+	}
+	return nil
+}
+
+func (dockerServerCli) ContainerIPAddress(containerName string) (string, error) {
+	inspect, err := docker.ContainerInspect(containerName)
+	if err != nil {
+		return "", err
+	}
+
+	if inspect.NetworkSettings == nil {
+		return "", fmt.Errorf("no network settings available in inspect")
+	}
+
+	return inspect.NetworkSettings.IPAddress, nil
+}
+
+func (dockerServerCli) ContainerStatPath(containerName, path string) (types.ContainerPathStat, error) {
+	return docker.ContainerStatPath(containerName, path)
+}
+
+func (dockerServerCli) CopyFromContainer(containerName, path string) (io.ReadCloser, error) {
+	reader, _, err := docker.CopyFromContainer(containerName, path)
+	return reader, err
+}
+
+func (dockerServerCli) GetOrCreateStapelContainer() (string, error) {
+	return stapel.GetOrCreateContainer()
+}
+
+func (dockerServerCli) EmptyEntrypointInstructionValue() (string, error) {
+	v, err := docker.ServerVersion()
+	if err != nil {
+		return "", err
+	}
+
+	serverVersion, err := version.NewVersion(v.Version)
+	if err != nil {
+		return "", err
+	}
+
+	serverVersionMajor := serverVersion.Segments()[0]
+	if serverVersionMajor >= 17 {
+		serverVersionMinor := serverVersion.Segments()[1]
+		isOldValueFormat := serverVersionMajor == 17 && serverVersionMinor < 10
+		if isOldValueFormat {
+			return "[]", nil
+		}
+	}
+
+	return "[\"\"]", nil
+}
+
+type podmanCli struct{}
+
+func (podmanCli) ImageInspect(ref string) (*types.ImageInspect, error) {
+	return podman.ImageInspect(ref)
+}
+
+func (podmanCli) CreateEmptyImage(ref string) error {
+	return podman.CreateImage(ref)
+}
+
+func (podmanCli) Tag(args ...string) error {
+	return podman.CliTag(args...)
+}
+
+func (podmanCli) Rmi(args ...string) error {
+	return podman.CliRmi(args...)
+}
+
+func (podmanCli) Pull(ref string) error {
+	return podman.CliPullWithRetries(ref)
+}
+
+func (podmanCli) Push(ref string) error {
+	return podman.CliPushWithRetries(ref)
+}
+
+func (podmanCli) Build(args ...string) error {
+	return podman.CliBuild_LiveOutput(args...)
+}
+
+func (podmanCli) Save(path string, refs ...string) error {
+	args := []string{"--format", "docker-archive", "--output", path}
+	if len(refs) > 1 {
+		args = append(args, "--multi-image-archive")
+	}
+	return podman.CliSave(append(args, refs...)...)
+}
+
+func (podmanCli) Run(args ...string) error {
+	return podman.CliRun_LiveOutput(args...)
+}
+
+func (podmanCli) Commit(containerName string, changes []string) (string, error) {
+	return podman.ContainerCommit(containerName, changes)
+}
+
+func (podmanCli) ContainerRemove(containerName string) error {
+	return podman.ContainerRemove(containerName)
+}
+
+func (podmanCli) CreateContainer(args ...string) error {
+	return podman.CliCreate(args...)
+}
+
+func (podmanCli) RunDetached(args ...string) error {
+	return podman.CliRun(append([]string{"--detach"}, args...)...)
+}
+
+func (podmanCli) ForceContainerRemove(containerName string) error {
+	return podman.CliRm("--force", containerName)
+}
+
+func (podmanCli) ContainerIPAddress(containerName string) (string, error) {
+	return podman.ContainerIPAddress(containerName)
+}
+
+func (podmanCli) ContainerStatPath(containerName, path string) (types.ContainerPathStat, error) {
+	return podman.ContainerStatPath(containerName, path)
+}
+
+func (podmanCli) CopyFromContainer(containerName, path string) (io.ReadCloser, error) {
+	return podman.CopyFromContainer(containerName, path)
+}
+
+func (podmanCli) GetOrCreateStapelContainer() (string, error) {
+	return stapel.GetOrCreatePodmanContainer()
+}
+
+func (podmanCli) EmptyEntrypointInstructionValue() (string, error) {
+	return "[\"\"]", nil
+}
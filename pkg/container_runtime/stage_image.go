@@ -12,7 +12,6 @@ import (
 
 	"github.com/flant/logboek"
 
-	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/image"
 )
 
@@ -24,9 +23,9 @@ type StageImage struct {
 	dockerfileImageBuilder *DockerfileImageBuilder
 }
 
-func NewStageImage(fromImage *StageImage, name string, localDockerServerRuntime *LocalDockerServerRuntime) *StageImage {
+func NewStageImage(fromImage *StageImage, name string, localRuntime LocalRuntime) *StageImage {
 	stage := &StageImage{}
-	stage.baseImage = newBaseImage(name, localDockerServerRuntime)
+	stage.baseImage = newBaseImage(name, localRuntime)
 	stage.fromImage = fromImage
 	stage.container = newStageImageContainer(stage)
 	return stage
@@ -115,7 +114,7 @@ func (i *StageImage) Build(options BuildOptions) error {
 		}
 	}
 
-	if inspect, err := i.LocalDockerServerRuntime.GetImageInspect(i.MustGetBuiltId()); err != nil {
+	if inspect, err := i.LocalRuntime.GetImageInspect(i.MustGetBuiltId()); err != nil {
 		return err
 	} else {
 		i.SetInspect(inspect)
@@ -134,7 +133,7 @@ func (i *StageImage) Commit() error {
 		return err
 	}
 
-	i.buildImage = newBuildImage(builtId, i.LocalDockerServerRuntime)
+	i.buildImage = newBuildImage(builtId, i.LocalRuntime)
 
 	return nil
 }
@@ -191,15 +190,15 @@ func (i *StageImage) GetBuiltId() string {
 }
 
 func (i *StageImage) TagBuiltImage(name string) error {
-	return docker.CliTag(i.MustGetBuiltId(), i.name)
+	return i.LocalRuntime.localCli().Tag(i.MustGetBuiltId(), i.name)
 }
 
 func (i *StageImage) Tag(name string) error {
-	return docker.CliTag(i.GetID(), name)
+	return i.LocalRuntime.localCli().Tag(i.GetID(), name)
 }
 
 func (i *StageImage) Pull() error {
-	if err := docker.CliPullWithRetries(i.name); err != nil {
+	if err := i.LocalRuntime.localCli().Pull(i.name); err != nil {
 		return err
 	}
 
@@ -209,23 +208,23 @@ func (i *StageImage) Pull() error {
 }
 
 func (i *StageImage) Push() error {
-	return docker.CliPushWithRetries(i.name)
+	return i.LocalRuntime.localCli().Push(i.name)
 }
 
 func (i *StageImage) Import(name string) error {
-	importedImage := newBaseImage(name, i.LocalDockerServerRuntime)
+	importedImage := newBaseImage(name, i.LocalRuntime)
 
-	if err := docker.CliPullWithRetries(name); err != nil {
+	if err := i.LocalRuntime.localCli().Pull(name); err != nil {
 		return err
 	}
 
 	importedImageId := importedImage.GetStageDescription().Info.ID
 
-	if err := docker.CliTag(importedImageId, i.name); err != nil {
+	if err := i.LocalRuntime.localCli().Tag(importedImageId, i.name); err != nil {
 		return err
 	}
 
-	if err := docker.CliRmi(name); err != nil {
+	if err := i.LocalRuntime.localCli().Rmi(name); err != nil {
 		return err
 	}
 
@@ -241,7 +240,7 @@ func (i *StageImage) Export(name string) error {
 
 	defer func() {
 		if err := logboek.Info.LogProcess(fmt.Sprintf("Untagging %s", name), logboek.LevelLogProcessOptions{}, func() error {
-			return docker.CliRmi(name)
+			return i.LocalRuntime.localCli().Rmi(name)
 		}); err != nil {
 			// TODO: errored image state
 			logboek.Error.LogF("Unable to remote temporary image %q: %s", name, err)
@@ -249,7 +248,7 @@ func (i *StageImage) Export(name string) error {
 	}()
 
 	if err := logboek.Info.LogProcess(fmt.Sprintf("Pushing %s", name), logboek.LevelLogProcessOptions{}, func() error {
-		return docker.CliPushWithRetries(name)
+		return i.LocalRuntime.localCli().Push(name)
 	}); err != nil {
 		return err
 	}
@@ -259,7 +258,7 @@ func (i *StageImage) Export(name string) error {
 
 func (i *StageImage) DockerfileImageBuilder() *DockerfileImageBuilder {
 	if i.dockerfileImageBuilder == nil {
-		i.dockerfileImageBuilder = NewDockerfileImageBuilder()
+		i.dockerfileImageBuilder = NewDockerfileImageBuilder(i.LocalRuntime)
 	}
 	return i.dockerfileImageBuilder
 }
@@ -7,10 +7,7 @@ import (
 
 	"github.com/flant/werf/pkg/image"
 
-	"github.com/docker/docker/api/types"
-
 	"github.com/flant/logboek"
-	"github.com/flant/werf/pkg/docker"
 	"github.com/flant/werf/pkg/stapel"
 	"github.com/flant/werf/pkg/util"
 )
@@ -187,7 +184,7 @@ func (c *StageImageContainer) prepareServiceRunOptions() (*StageImageContainerOp
 	serviceRunOptions.Entrypoint = stapel.BashBinPath()
 	serviceRunOptions.User = "0:0"
 
-	stapelContainerName, err := stapel.GetOrCreateContainer()
+	stapelContainerName, err := c.image.LocalRuntime.localCli().GetOrCreateStapelContainer()
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +204,7 @@ func (c *StageImageContainer) prepareCommitChanges() ([]string, error) {
 		return nil, err
 	}
 
-	commitChanges, err := commitOptions.prepareCommitChanges()
+	commitChanges, err := commitOptions.prepareCommitChanges(c.image.LocalRuntime.localCli())
 	if err != nil {
 		return nil, err
 	}
@@ -259,7 +256,7 @@ func (c *StageImageContainer) run() error {
 		return err
 	}
 
-	if err := docker.CliRun_LiveOutput(runArgs...); err != nil {
+	if err := c.image.LocalRuntime.localCli().Run(runArgs...); err != nil {
 		return fmt.Errorf("container run failed: %s", err.Error())
 	}
 
@@ -272,7 +269,7 @@ func (c *StageImageContainer) introspect() error {
 		return err
 	}
 
-	if err := docker.CliRun_LiveOutput(runArgs...); err != nil {
+	if err := c.image.LocalRuntime.localCli().Run(runArgs...); err != nil {
 		if !strings.Contains(err.Error(), "Code: ") || IsStartContainerErr(err) {
 			return err
 		}
@@ -287,7 +284,7 @@ func (c *StageImageContainer) introspectBefore() error {
 		return err
 	}
 
-	if err := docker.CliRun_LiveOutput(runArgs...); err != nil {
+	if err := c.image.LocalRuntime.localCli().Run(runArgs...); err != nil {
 		if !strings.Contains(err.Error(), "Code: ") || IsStartContainerErr(err) {
 			return err
 		}
@@ -313,8 +310,7 @@ func (c *StageImageContainer) commit() (string, error) {
 		return "", err
 	}
 
-	commitOptions := types.ContainerCommitOptions{Changes: commitChanges}
-	id, err := docker.ContainerCommit(c.name, commitOptions)
+	id, err := c.image.LocalRuntime.localCli().Commit(c.name, commitChanges)
 	if err != nil {
 		return "", err
 	}
@@ -323,5 +319,5 @@ func (c *StageImageContainer) commit() (string, error) {
 }
 
 func (c *StageImageContainer) rm() error {
-	return docker.ContainerRemove(c.name, types.ContainerRemoveOptions{})
+	return c.image.LocalRuntime.localCli().ContainerRemove(c.name)
 }
@@ -2,10 +2,6 @@ package container_runtime
 
 import (
 	"fmt"
-
-	"github.com/hashicorp/go-version"
-
-	"github.com/flant/werf/pkg/docker"
 )
 
 type StageImageContainerOptions struct {
@@ -201,7 +197,7 @@ func (co *StageImageContainerOptions) toCommitChanges() []string {
 	return args
 }
 
-func (co *StageImageContainerOptions) prepareCommitChanges() ([]string, error) {
+func (co *StageImageContainerOptions) prepareCommitChanges(cli localCli) ([]string, error) {
 	var args []string
 
 	for _, volume := range co.Volume {
@@ -233,7 +229,7 @@ func (co *StageImageContainerOptions) prepareCommitChanges() ([]string, error) {
 	if co.Entrypoint != "" {
 		entrypoint = co.Entrypoint
 	} else {
-		entrypoint, err = getEmptyEntrypointInstructionValue()
+		entrypoint, err = cli.EmptyEntrypointInstructionValue()
 		if err != nil {
 			return nil, fmt.Errorf("container options preparing failed: %s", err.Error())
 		}
@@ -253,26 +249,3 @@ func (co *StageImageContainerOptions) prepareCommitChanges() ([]string, error) {
 
 	return args, nil
 }
-
-func getEmptyEntrypointInstructionValue() (string, error) {
-	v, err := docker.ServerVersion()
-	if err != nil {
-		return "", err
-	}
-
-	serverVersion, err := version.NewVersion(v.Version)
-	if err != nil {
-		return "", err
-	}
-
-	serverVersionMajor := serverVersion.Segments()[0]
-	if serverVersionMajor >= 17 {
-		serverVersionMinor := serverVersion.Segments()[1]
-		isOldValueFormat := serverVersionMajor == 17 && serverVersionMinor < 10
-		if isOldValueFormat {
-			return "[]", nil
-		}
-	}
-
-	return "[\"\"]", nil
-}
@@ -4,8 +4,8 @@ type WerfImage struct {
 	*StageImage
 }
 
-func NewWerfImage(fromImage *StageImage, name string, localDockerServerRuntime *LocalDockerServerRuntime) *WerfImage {
-	return &WerfImage{StageImage: NewStageImage(fromImage, name, localDockerServerRuntime)}
+func NewWerfImage(fromImage *StageImage, name string, localRuntime LocalRuntime) *WerfImage {
+	return &WerfImage{StageImage: NewStageImage(fromImage, name, localRuntime)}
 }
 
 func (i *WerfImage) Tag() error {
@@ -17,6 +17,7 @@ import (
 
 	"github.com/flant/werf/pkg/config"
 	"github.com/flant/werf/pkg/deploy/helm"
+	"github.com/flant/werf/pkg/deploy/strategy"
 	"github.com/flant/werf/pkg/deploy/werf_chart"
 	"github.com/flant/werf/pkg/images_manager"
 	"github.com/flant/werf/pkg/tag_strategy"
@@ -28,16 +29,21 @@ type DeployOptions struct {
 	SecretValues         []string
 	Set                  []string
 	SetString            []string
+	SecretSet            []string
+	SetFileSecret        []string
 	Timeout              time.Duration
 	Env                  string
 	UserExtraAnnotations map[string]string
 	UserExtraLabels      map[string]string
 	IgnoreSecretKey      bool
 	ThreeWayMergeMode    helm.ThreeWayMergeModeType
+	AutoRollback         bool
 	DryRun               bool
 }
 
 func Deploy(projectName, projectDir, helmChartDir string, imagesRepository string, images []images_manager.ImageInfoGetter, release, namespace, commonTag string, tagStrategy tag_strategy.TagStrategy, werfConfig *config.WerfConfig, helmReleaseStorageNamespace, helmReleaseStorageType string, storageLockManager storage.LockManager, opts DeployOptions) error {
+	registerSetSecretValues(opts.Set, opts.SetString, opts.SecretSet)
+
 	if opts.DryRun {
 		fmt.Printf("Deploy DryRun\n")
 		return nil
@@ -49,6 +55,7 @@ func Deploy(projectName, projectDir, helmChartDir string, imagesRepository strin
 	}
 
 	var werfChart *werf_chart.WerfChart
+	var releaseAnnotations map[string]string
 
 	if err := logboek.Default.LogBlock("Deploy options", logboek.LevelLogBlockOptions{}, func() error {
 		if kube.Context != "" {
@@ -76,7 +83,7 @@ func Deploy(projectName, projectDir, helmChartDir string, imagesRepository strin
 			return nil
 		})
 
-		werfChart, err = PrepareWerfChart(werfConfig.Meta.Project, helmChartDir, opts.Env, m, opts.SecretValues, serviceValues)
+		werfChart, err = PrepareWerfChart(werfConfig.Meta.Project, helmChartDir, opts.Env, m, opts.SecretValues, opts.SecretSet, opts.SetFileSecret, serviceValues)
 		if err != nil {
 			return err
 		}
@@ -87,6 +94,11 @@ func Deploy(projectName, projectDir, helmChartDir string, imagesRepository strin
 		werfChart.LogExtraAnnotations()
 		werfChart.LogExtraLabels()
 
+		releaseAnnotations, err = GetReleaseAnnotations(projectDir, commonTag, tagStrategy, images)
+		if err != nil {
+			return fmt.Errorf("unable to get release annotations: %s", err)
+		}
+
 		return nil
 	}); err != nil {
 		logboek.LogOptionalLn()
@@ -96,10 +108,10 @@ func Deploy(projectName, projectDir, helmChartDir string, imagesRepository strin
 	logboek.LogOptionalLn()
 
 	helm.WerfTemplateEngine.InitWerfEngineExtraTemplatesFunctions(werfChart.DecodedSecretFilesData)
-	patchLoadChartfile(werfChart.Name)
+	patchLoadChartfile(werfChart.Name, releaseAnnotations)
 
 	err := helm.WerfTemplateEngineWithExtraAnnotationsAndLabels(werfChart.ExtraAnnotations, werfChart.ExtraLabels, func() error {
-		return werfChart.Deploy(release, namespace, helm.ChartOptions{
+		return deployWithStrategies(werfChart, release, namespace, helm.ChartOptions{
 			Timeout: opts.Timeout,
 			ChartValuesOptions: helm.ChartValuesOptions{
 				Set:       opts.Set,
@@ -107,6 +119,7 @@ func Deploy(projectName, projectDir, helmChartDir string, imagesRepository strin
 				Values:    opts.Values,
 			},
 			ThreeWayMergeMode: opts.ThreeWayMergeMode,
+			AutoRollback:      opts.AutoRollback,
 		})
 	})
 
@@ -117,7 +130,33 @@ func Deploy(projectName, projectDir, helmChartDir string, imagesRepository strin
 	return nil
 }
 
-func patchLoadChartfile(chartName string) {
+// deployWithStrategies runs canary and blue-green strategies for the chart deployments annotated with werf.io/strategy
+func deployWithStrategies(werfChart *werf_chart.WerfChart, release, namespace string, opts helm.ChartOptions) error {
+	deployFunc := func() error {
+		return werfChart.Deploy(release, namespace, opts)
+	}
+
+	templates, err := werfChart.GetTemplates(release, namespace, opts.ChartValuesOptions)
+	if err != nil {
+		return err
+	}
+
+	deployments, err := strategy.GetDeployments(namespace, templates)
+	if err != nil {
+		return err
+	} else if len(deployments) == 0 {
+		return deployFunc()
+	}
+
+	deployedTemplates, err := helm.GetLatestDeployedReleaseTemplates(release)
+	if err != nil {
+		return fmt.Errorf("unable to get deployed release templates: %s", err)
+	}
+
+	return strategy.Run(kube.Kubernetes, deployments, deployedTemplates, strategy.Options{Timeout: opts.Timeout}, deployFunc)
+}
+
+func patchLoadChartfile(chartName string, annotations map[string]string) {
 	boundedFunc := helm.LoadChartfileFunc
 	helm.LoadChartfileFunc = func(chartPath string) (*chart.Chart, error) {
 		var c *chart.Chart
@@ -134,9 +173,10 @@ func patchLoadChartfile(chartName string) {
 		}
 
 		c.Metadata = &chart.Metadata{
-			Name:    chartName,
-			Version: "0.1.0",
-			Engine:  helm.WerfTemplateEngineName,
+			Name:        chartName,
+			Version:     "0.1.0",
+			Engine:      helm.WerfTemplateEngineName,
+			Annotations: annotations,
 		}
 
 		return c, nil
@@ -0,0 +1,238 @@
+package helm
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/helm/pkg/kube"
+	"k8s.io/helm/pkg/proto/hapi/release"
+	"k8s.io/helm/pkg/storage"
+	"k8s.io/helm/pkg/storage/driver"
+	storageerrors "k8s.io/helm/pkg/storage/errors"
+	"k8s.io/helm/pkg/tiller"
+	tiller_env "k8s.io/helm/pkg/tiller/environment"
+
+	"github.com/flant/werf/pkg/werf"
+)
+
+const testChartTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  version: {{ .Values.version | quote }}
+`
+
+// fakeKubeClient applies nothing and fails resources tracking of the manifests with the failing version
+type fakeKubeClient struct {
+	tiller_env.PrintingKubeClient
+
+	failingVersions map[string]bool
+}
+
+func (c *fakeKubeClient) track(reader io.Reader) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	for version := range c.failingVersions {
+		if strings.Contains(string(data), fmt.Sprintf("version: %q", version)) {
+			return fmt.Errorf("configmap/config tracking failed: version %s is broken", version)
+		}
+	}
+
+	return nil
+}
+
+func (c *fakeKubeClient) CreateWithOptions(_ string, reader io.Reader, _ kube.CreateOptions) error {
+	return c.track(reader)
+}
+
+func (c *fakeKubeClient) UpdateWithOptions(_ string, _, modifiedReader io.Reader, _ kube.UpdateOptions) error {
+	return c.track(modifiedReader)
+}
+
+func (c *fakeKubeClient) DeleteWithOptions(_ string, _ io.Reader, _ kube.DeleteOptions) error {
+	return nil
+}
+
+// fakeReleaseStorageDriver keeps releases in memory and returns not found error for the missing release as configmaps and secrets drivers do
+type fakeReleaseStorageDriver struct {
+	*driver.Memory
+}
+
+func (d *fakeReleaseStorageDriver) Query(keyvals map[string]string) ([]*release.Release, error) {
+	releases, err := d.Memory.Query(keyvals)
+	if err == nil && len(releases) == 0 {
+		return nil, storageerrors.ErrReleaseNotFound(keyvals["NAME"])
+	}
+
+	return releases, err
+}
+
+// setupFakeTiller initializes the release server with the memory release storage and the fake kube client
+func setupFakeTiller(t *testing.T) (*fakeKubeClient, string) {
+	dir, err := ioutil.TempDir("", "werf-auto-rollback-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	chartDir := filepath.Join(dir, "chart")
+	if err := os.MkdirAll(filepath.Join(chartDir, "templates"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("name: test\nversion: 0.1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(chartDir, "templates", "config.yaml"), []byte(testChartTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	kubeClient := &fakeKubeClient{
+		PrintingKubeClient: tiller_env.PrintingKubeClient{Out: ioutil.Discard},
+		failingVersions:    map[string]bool{},
+	}
+
+	env := tiller_env.New()
+	env.KubeClient = kubeClient
+	env.Releases = storage.Init(&fakeReleaseStorageDriver{Memory: driver.NewMemory()})
+	tillerReleaseServer = tiller.NewReleaseServer(env, fake.NewSimpleClientset(), false)
+	resourcesWaiter = &ResourcesWaiter{}
+
+	return kubeClient, chartDir
+}
+
+func deployTestChart(chartDir, version string, autoRollback bool) error {
+	return DeployHelmChart(chartDir, "test", "test", ChartOptions{
+		ThreeWayMergeMode:  ThreeWayMergeEnabled,
+		AutoRollback:       autoRollback,
+		ChartValuesOptions: ChartValuesOptions{Set: []string{fmt.Sprintf("version=%s", version)}},
+	})
+}
+
+func assertReleaseRevisions(t *testing.T, expected ...string) {
+	t.Helper()
+
+	revisions, err := GetReleaseRevisions("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []string
+	for _, r := range revisions {
+		statuses = append(statuses, fmt.Sprintf("%d:%s", r.Revision, r.Status))
+	}
+
+	if strings.Join(statuses, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected revisions %v, got %v", expected, statuses)
+	}
+}
+
+func TestDeployHelmChart_AutoRollback(t *testing.T) {
+	kubeClient, chartDir := setupFakeTiller(t)
+	defer os.RemoveAll(filepath.Dir(chartDir))
+
+	if err := deployTestChart(chartDir, "1", true); err != nil {
+		t.Fatalf("unexpected deploy error: %s", err)
+	}
+
+	kubeClient.failingVersions["2"] = true
+
+	err := deployTestChart(chartDir, "2", true)
+	if err == nil {
+		t.Fatalf("expected deploy error")
+	}
+
+	if !strings.Contains(err.Error(), "version 2 is broken") {
+		t.Errorf("expected the original failure in the error, got: %s", err)
+	}
+
+	if !strings.Contains(err.Error(), "release has been rolled back to revision 1") {
+		t.Errorf("expected the rollback result in the error, got: %s", err)
+	}
+
+	assertReleaseRevisions(t, "3:DEPLOYED", "2:SUPERSEDED", "1:SUPERSEDED")
+
+	revisions, err := GetReleaseRevisions("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(revisions[0].Manifest, `version: "1"`) {
+		t.Errorf("expected manifest of revision 1 after rollback, got:\n%s", revisions[0].Manifest)
+	}
+}
+
+func TestDeployHelmChart_AutoRollbackFailed(t *testing.T) {
+	kubeClient, chartDir := setupFakeTiller(t)
+	defer os.RemoveAll(filepath.Dir(chartDir))
+
+	if err := deployTestChart(chartDir, "1", true); err != nil {
+		t.Fatalf("unexpected deploy error: %s", err)
+	}
+
+	kubeClient.failingVersions["1"] = true
+	kubeClient.failingVersions["2"] = true
+
+	err := deployTestChart(chartDir, "2", true)
+	if err == nil {
+		t.Fatalf("expected deploy error")
+	}
+
+	if !strings.Contains(err.Error(), "version 2 is broken") || !strings.Contains(err.Error(), "auto rollback to revision 1 failed") || !strings.Contains(err.Error(), "version 1 is broken") {
+		t.Errorf("expected both the original and the rollback failures in the error, got: %s", err)
+	}
+
+	assertReleaseRevisions(t, "3:FAILED", "2:SUPERSEDED", "1:DEPLOYED")
+}
+
+func TestDeployHelmChart_AutoRollbackWithoutSuccessfulRevision(t *testing.T) {
+	kubeClient, chartDir := setupFakeTiller(t)
+	defer os.RemoveAll(filepath.Dir(chartDir))
+
+	kubeClient.failingVersions["1"] = true
+
+	err := deployTestChart(chartDir, "1", true)
+	if err == nil {
+		t.Fatalf("expected deploy error")
+	}
+
+	if strings.Contains(err.Error(), "rolled back") {
+		t.Errorf("unexpected rollback of the first release revision: %s", err)
+	}
+
+	assertReleaseRevisions(t, "1:FAILED")
+}
+
+func TestDeployHelmChart_WithoutAutoRollback(t *testing.T) {
+	kubeClient, chartDir := setupFakeTiller(t)
+	defer os.RemoveAll(filepath.Dir(chartDir))
+
+	if err := deployTestChart(chartDir, "1", false); err != nil {
+		t.Fatalf("unexpected deploy error: %s", err)
+	}
+
+	kubeClient.failingVersions["2"] = true
+
+	err := deployTestChart(chartDir, "2", false)
+	if err == nil {
+		t.Fatalf("expected deploy error")
+	}
+
+	if strings.Contains(err.Error(), "rolled back") {
+		t.Errorf("unexpected rollback: %s", err)
+	}
+
+	assertReleaseRevisions(t, "2:FAILED", "1:DEPLOYED")
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/flant/kubedog/pkg/kube"
 	"github.com/flant/logboek"
+	"github.com/flant/werf/pkg/tracing"
 	"github.com/flant/werf/pkg/util"
 	"github.com/flant/werf/pkg/werf"
 )
@@ -162,11 +163,16 @@ type ChartOptions struct {
 	DryRun            bool
 	Debug             bool
 	ThreeWayMergeMode ThreeWayMergeModeType
+	// AutoRollback enables rollback to the latest successfully deployed revision when the release upgrade fails
+	AutoRollback bool
 
 	ChartValuesOptions
 }
 
 func DeployHelmChart(chartPath, releaseName, namespace string, opts ChartOptions) (err error) {
+	span := tracing.StartSpan("helm deploy", map[string]string{"release": releaseName, "namespace": namespace})
+	defer func() { span.End(err) }()
+
 	var isReleaseExists bool
 
 	preDeployFunc := func() error {
@@ -470,7 +476,48 @@ func DeployHelmChart(chartPath, releaseName, namespace string, opts ChartOptions
 		return err
 	}
 
-	return runDeployProcess(releaseName, namespace, opts, templatesFromChart, deployFunc)
+	if err := runDeployProcess(releaseName, namespace, opts, templatesFromChart, deployFunc); err != nil {
+		if opts.AutoRollback && !opts.DryRun && isReleaseExists {
+			return autoRollbackRelease(releaseName, namespace, opts, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// autoRollbackRelease rolls back the failed release revision and returns the deploy error with the rollback result
+func autoRollbackRelease(releaseName, namespace string, opts ChartOptions, deployErr error) error {
+	resp, err := releaseHistory(releaseName, releaseHistoryOptions{Max: 1})
+	if err != nil {
+		return fmt.Errorf("%s\nauto rollback failed: unable to get release history: %s", deployErr, err)
+	}
+
+	// release revision has not been created: there is nothing to roll back
+	if len(resp.Releases) == 0 || resp.Releases[0].Info == nil || resp.Releases[0].Info.GetStatus().GetCode().String() != "FAILED" {
+		return deployErr
+	}
+	failedRevision := resp.Releases[0].Version
+
+	revision, err := latestSuccessfullyDeployedReleaseRevision(releaseName)
+	if err == ErrNoSuccessfullyDeployedReleaseRevisionFound {
+		return fmt.Errorf("%s\nauto rollback skipped: %s", deployErr, err)
+	} else if err != nil {
+		return fmt.Errorf("%s\nauto rollback failed: %s", deployErr, err)
+	}
+
+	logboek.LogOptionalLn()
+	logboek.LogWarnF("WARNING: Release revision %d has failed: %s\n", failedRevision, deployErr)
+
+	logProcessMsg := fmt.Sprintf("Rolling back release to the latest successfully deployed revision %d", revision)
+	if err := logboek.Default.LogProcess(logProcessMsg, logboek.LevelLogProcessOptions{}, func() error {
+		return RollbackReleaseToRevision(releaseName, namespace, revision, opts)
+	}); err != nil {
+		return fmt.Errorf("%s\nauto rollback to revision %d failed: %s", deployErr, revision, err)
+	}
+
+	return fmt.Errorf("%s\nrelease has been rolled back to revision %d", deployErr, revision)
 }
 
 func latestSuccessfullyDeployedReleaseRevision(releaseName string) (int32, error) {
@@ -480,7 +527,7 @@ func latestSuccessfullyDeployedReleaseRevision(releaseName string) (int32, error
 	}
 
 	for _, r := range resp.Releases {
-		if r.Info.Status.Code.String() == "DEPLOYED" {
+		if r.Info != nil && r.Info.GetStatus().GetCode().String() == "DEPLOYED" {
 			return r.Version, nil
 		}
 	}
@@ -0,0 +1,334 @@
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flant/logboek"
+
+	"k8s.io/helm/pkg/proto/hapi/release"
+	"k8s.io/helm/pkg/releaseutil"
+	"k8s.io/helm/pkg/timeconv"
+)
+
+// Release annotations are stored in the chart metadata of each release revision,
+// so the rolled back revision gets the annotations of the target revision
+const (
+	ReleaseGitCommitAnnotation   = "werf.io/git-commit"
+	ReleaseTagAnnotation         = "werf.io/tag"
+	ReleaseTagStrategyAnnotation = "werf.io/tag-strategy"
+	ReleaseImagesAnnotation      = "werf.io/images"
+)
+
+type ReleaseRevision struct {
+	Revision    int32
+	Status      string
+	Updated     time.Time
+	Namespace   string
+	Description string
+
+	GitCommit   string
+	Tag         string
+	TagStrategy string
+	// Images contains werf image name and the image reference used by the revision, nameless image has an empty name
+	Images map[string]string
+
+	Manifest string
+}
+
+func (r *ReleaseRevision) IsSuccessful() bool {
+	return r.Status == release.Status_DEPLOYED.String() || r.Status == release.Status_SUPERSEDED.String()
+}
+
+func newReleaseRevision(r *release.Release) *ReleaseRevision {
+	revision := &ReleaseRevision{
+		Revision:  r.Version,
+		Namespace: r.Namespace,
+		Manifest:  r.Manifest,
+		Images:    map[string]string{},
+	}
+
+	if r.Info != nil {
+		revision.Status = r.Info.Status.Code.String()
+		revision.Updated = timeconv.Time(r.Info.LastDeployed)
+		revision.Description = r.Info.Description
+	}
+
+	if r.Chart != nil && r.Chart.Metadata != nil {
+		annotations := r.Chart.Metadata.Annotations
+		revision.GitCommit = annotations[ReleaseGitCommitAnnotation]
+		revision.Tag = annotations[ReleaseTagAnnotation]
+		revision.TagStrategy = annotations[ReleaseTagStrategyAnnotation]
+
+		if data, hasKey := annotations[ReleaseImagesAnnotation]; hasKey {
+			if err := json.Unmarshal([]byte(data), &revision.Images); err != nil {
+				logboek.LogWarnF("WARNING: Cannot parse release revision %d annotation %s: %s\n", r.Version, ReleaseImagesAnnotation, err)
+			}
+		}
+	}
+
+	return revision
+}
+
+// GetReleaseRevisions returns release revisions sorted from the newest to the oldest
+func GetReleaseRevisions(releaseName string) ([]*ReleaseRevision, error) {
+	resp, err := releaseHistory(releaseName, releaseHistoryOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get release history: %s", err)
+	}
+
+	var revisions []*ReleaseRevision
+	for _, r := range resp.Releases {
+		revisions = append(revisions, newReleaseRevision(r))
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+
+	return revisions, nil
+}
+
+type RollbackTarget struct {
+	// ToCommit is a full or abbreviated git commit
+	ToCommit             string
+	ToPreviousSuccessful bool
+}
+
+// ResolveRollbackRevision selects the successfully deployed revision for the rollback, revisions should be sorted from the newest to the oldest
+func ResolveRollbackRevision(revisions []*ReleaseRevision, target RollbackTarget) (*ReleaseRevision, error) {
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("release has no revisions")
+	}
+
+	current := revisions[0]
+
+	switch {
+	case target.ToPreviousSuccessful:
+		for _, r := range revisions[1:] {
+			if r.IsSuccessful() {
+				return r, nil
+			}
+		}
+
+		return nil, fmt.Errorf("successfully deployed revision before the current revision %d not found", current.Revision)
+
+	case target.ToCommit != "":
+		commit := strings.ToLower(target.ToCommit)
+
+		var matched []*ReleaseRevision
+		matchedCommits := map[string]bool{}
+		for _, r := range revisions {
+			if r.GitCommit != "" && strings.HasPrefix(r.GitCommit, commit) {
+				matched = append(matched, r)
+				matchedCommits[r.GitCommit] = true
+			}
+		}
+
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("revision deployed from commit %s not found: only revisions deployed by werf with git commit info could be used", target.ToCommit)
+		}
+
+		if len(matchedCommits) > 1 {
+			var commits []string
+			for c := range matchedCommits {
+				commits = append(commits, c)
+			}
+			sort.Strings(commits)
+
+			return nil, fmt.Errorf("commit %s is ambiguous: %s", target.ToCommit, strings.Join(commits, ", "))
+		}
+
+		if matched[0] == current && current.Status == release.Status_DEPLOYED.String() {
+			return nil, fmt.Errorf("commit %s is already deployed in the current revision %d", current.GitCommit, current.Revision)
+		}
+
+		for _, r := range matched {
+			if r != current && r.IsSuccessful() {
+				return r, nil
+			}
+		}
+
+		return nil, fmt.Errorf("no successfully deployed revision from commit %s found", matched[0].GitCommit)
+
+	default:
+		return nil, fmt.Errorf("rollback target is not specified")
+	}
+}
+
+type ReleaseRevisionsChanges struct {
+	GitCommit [2]string
+	// Images contains the changed images references: [from, to]
+	Images map[string][2]string
+
+	AddedResources   []string
+	RemovedResources []string
+	ChangedResources []string
+}
+
+func GetReleaseRevisionsChanges(from, to *ReleaseRevision) (*ReleaseRevisionsChanges, error) {
+	changes := &ReleaseRevisionsChanges{
+		GitCommit: [2]string{from.GitCommit, to.GitCommit},
+		Images:    map[string][2]string{},
+	}
+
+	for name, ref := range from.Images {
+		if to.Images[name] != ref {
+			changes.Images[name] = [2]string{ref, to.Images[name]}
+		}
+	}
+
+	for name, ref := range to.Images {
+		if _, hasKey := from.Images[name]; !hasKey {
+			changes.Images[name] = [2]string{"", ref}
+		}
+	}
+
+	fromResources, err := manifestResources(from.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse revision %d manifest: %s", from.Revision, err)
+	}
+
+	toResources, err := manifestResources(to.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse revision %d manifest: %s", to.Revision, err)
+	}
+
+	for id, doc := range toResources {
+		if fromDoc, hasKey := fromResources[id]; !hasKey {
+			changes.AddedResources = append(changes.AddedResources, id)
+		} else if fromDoc != doc {
+			changes.ChangedResources = append(changes.ChangedResources, id)
+		}
+	}
+
+	for id := range fromResources {
+		if _, hasKey := toResources[id]; !hasKey {
+			changes.RemovedResources = append(changes.RemovedResources, id)
+		}
+	}
+
+	sort.Strings(changes.AddedResources)
+	sort.Strings(changes.RemovedResources)
+	sort.Strings(changes.ChangedResources)
+
+	return changes, nil
+}
+
+func manifestResources(manifest string) (map[string]string, error) {
+	res := map[string]string{}
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		t, err := parseTemplate(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Metadata.Name == "" {
+			continue
+		}
+
+		id := fmt.Sprintf("%s/%s", t.Kind, t.Metadata.Name)
+		if t.Metadata.Namespace != "" {
+			id = fmt.Sprintf("%s/%s", t.Metadata.Namespace, id)
+		}
+
+		res[id] = strings.TrimSpace(doc)
+	}
+
+	return res, nil
+}
+
+func (changes *ReleaseRevisionsChanges) Log() {
+	if changes.GitCommit[0] != changes.GitCommit[1] {
+		logboek.LogF("Git commit: %s -> %s\n", revisionValue(changes.GitCommit[0]), revisionValue(changes.GitCommit[1]))
+	}
+
+	if len(changes.Images) != 0 {
+		var names []string
+		for name := range changes.Images {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		logboek.LogLn("Images:")
+		for _, name := range names {
+			imageName := name
+			if imageName == "" {
+				imageName = "~"
+			}
+
+			logboek.LogF("  %s: %s -> %s\n", imageName, revisionValue(changes.Images[name][0]), revisionValue(changes.Images[name][1]))
+		}
+	}
+
+	if len(changes.AddedResources)+len(changes.RemovedResources)+len(changes.ChangedResources) == 0 {
+		logboek.LogLn("Resources: no changes")
+		return
+	}
+
+	logboek.LogLn("Resources:")
+	for _, id := range changes.AddedResources {
+		logboek.LogF("  + %s\n", id)
+	}
+	for _, id := range changes.RemovedResources {
+		logboek.LogF("  - %s\n", id)
+	}
+	for _, id := range changes.ChangedResources {
+		logboek.LogF("  ~ %s\n", id)
+	}
+}
+
+func revisionValue(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+// RollbackReleaseToRevision rolls back the release and tracks resources until they are ready
+func RollbackReleaseToRevision(releaseName, namespace string, revision int32, opts ChartOptions) error {
+	var templates ChartTemplates
+	logProcessMsg := fmt.Sprintf("Getting templates from release revision %d", revision)
+	if err := logboek.Info.LogProcessInline(logProcessMsg, logboek.LevelLogProcessInlineOptions{}, func() error {
+		var err error
+		templates, err = GetTemplatesFromReleaseRevision(releaseName, revision)
+		return err
+	}); err != nil {
+		return fmt.Errorf("get templates from release revision failed: %s", err)
+	}
+
+	return runDeployProcess(releaseName, namespace, opts, templates, func() error {
+		logboek.Info.LogF("Running helm rollback...\n")
+
+		if err := ReleaseRollback(releaseName, revision, opts.ThreeWayMergeMode, ReleaseRollbackOptions{
+			releaseRollbackOptions: releaseRollbackOptions{
+				Timeout:       int64(opts.Timeout / time.Second),
+				CleanupOnFail: true,
+				Wait:          true,
+				DryRun:        opts.DryRun,
+			},
+		}); err != nil {
+			return fmt.Errorf("release rollback to revision %d failed: %s", revision, err)
+		}
+
+		return nil
+	})
+}
+
+// GetLatestDeployedReleaseTemplates returns templates of the latest successfully deployed release revision or nil when there is no such revision
+func GetLatestDeployedReleaseTemplates(releaseName string) (ChartTemplates, error) {
+	revision, err := latestSuccessfullyDeployedReleaseRevision(releaseName)
+	if err == ErrNoSuccessfullyDeployedReleaseRevisionFound {
+		return nil, nil
+	} else if err != nil {
+		if isReleaseNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return GetTemplatesFromReleaseRevision(releaseName, revision)
+}
@@ -0,0 +1,126 @@
+package helm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveRollbackRevision(t *testing.T) {
+	revisions := []*ReleaseRevision{
+		{Revision: 5, Status: "FAILED", GitCommit: "e5f6a7b8"},
+		{Revision: 4, Status: "DEPLOYED", GitCommit: "c3d4e5f6"},
+		{Revision: 3, Status: "SUPERSEDED", GitCommit: "a1b2c3d4"},
+		{Revision: 2, Status: "FAILED", GitCommit: "a1b2ffff"},
+		{Revision: 1, Status: "SUPERSEDED"},
+	}
+
+	tests := []struct {
+		name             string
+		target           RollbackTarget
+		expectedRevision int32
+		expectedErr      bool
+	}{
+		{name: "previous successful after failed", target: RollbackTarget{ToPreviousSuccessful: true}, expectedRevision: 4},
+		{name: "full commit", target: RollbackTarget{ToCommit: "a1b2c3d4"}, expectedRevision: 3},
+		{name: "abbreviated commit", target: RollbackTarget{ToCommit: "C3D4"}, expectedRevision: 4},
+		{name: "ambiguous commit", target: RollbackTarget{ToCommit: "a1b2"}, expectedErr: true},
+		{name: "failed only commit", target: RollbackTarget{ToCommit: "a1b2ff"}, expectedErr: true},
+		{name: "failed current commit", target: RollbackTarget{ToCommit: "e5f6"}, expectedErr: true},
+		{name: "unknown commit", target: RollbackTarget{ToCommit: "0000"}, expectedErr: true},
+		{name: "no target", target: RollbackTarget{}, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ResolveRollbackRevision(revisions, tt.target)
+			if tt.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got revision %d", r.Revision)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if r.Revision != tt.expectedRevision {
+				t.Errorf("expected revision %d, got %d", tt.expectedRevision, r.Revision)
+			}
+		})
+	}
+
+	deployed := []*ReleaseRevision{
+		{Revision: 2, Status: "DEPLOYED", GitCommit: "c3d4e5f6"},
+		{Revision: 1, Status: "SUPERSEDED", GitCommit: "a1b2c3d4"},
+	}
+
+	if r, err := ResolveRollbackRevision(deployed, RollbackTarget{ToPreviousSuccessful: true}); err != nil || r.Revision != 1 {
+		t.Errorf("expected revision 1, got %v (err: %v)", r, err)
+	}
+
+	if _, err := ResolveRollbackRevision(deployed, RollbackTarget{ToCommit: "c3d4"}); err == nil {
+		t.Errorf("expected error for the already deployed commit")
+	}
+}
+
+func TestGetReleaseRevisionsChanges(t *testing.T) {
+	from := &ReleaseRevision{
+		Revision:  2,
+		GitCommit: "c3d4e5f6",
+		Images:    map[string]string{"backend": "registry/backend:new", "frontend": "registry/frontend:same"},
+		Manifest: `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+spec:
+  replicas: 2
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: new-config
+`,
+	}
+
+	to := &ReleaseRevision{
+		Revision:  1,
+		GitCommit: "a1b2c3d4",
+		Images:    map[string]string{"backend": "registry/backend:old", "frontend": "registry/frontend:same", "worker": "registry/worker:old"},
+		Manifest: `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+spec:
+  replicas: 1
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: old-secret
+  namespace: other
+`,
+	}
+
+	changes, err := GetReleaseRevisionsChanges(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &ReleaseRevisionsChanges{
+		GitCommit: [2]string{"c3d4e5f6", "a1b2c3d4"},
+		Images: map[string][2]string{
+			"backend": {"registry/backend:new", "registry/backend:old"},
+			"worker":  {"", "registry/worker:old"},
+		},
+		AddedResources:   []string{"other/Secret/old-secret"},
+		RemovedResources: []string{"ConfigMap/new-config"},
+		ChangedResources: []string{"Deployment/backend"},
+	}
+
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected changes %+v, got %+v", expected, changes)
+	}
+}
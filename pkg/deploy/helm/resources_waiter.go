@@ -24,6 +24,8 @@ import (
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes/scheme"
 	helmKube "k8s.io/helm/pkg/kube"
+
+	"github.com/flant/werf/pkg/tracing"
 )
 
 type ResourcesWaiter struct {
@@ -150,12 +152,14 @@ func (waiter *ResourcesWaiter) WaitForResources(timeout time.Duration, created h
 
 	logboek.LogOptionalLn()
 	return logboek.LogProcess("Waiting for release resources to become ready", logboek.LogProcessOptions{}, func() error {
-		return multitrack.Multitrack(kube.Kubernetes, specs, multitrack.MultitrackOptions{
-			StatusProgressPeriod: waiter.StatusProgressPeriod,
-			Options: tracker.Options{
-				Timeout:      timeout,
-				LogsFromTime: waiter.LogsFromTime,
-			},
+		return tracing.Trace("kubedog track", nil, func() error {
+			return multitrack.Multitrack(kube.Kubernetes, specs, multitrack.MultitrackOptions{
+				StatusProgressPeriod: waiter.StatusProgressPeriod,
+				Options: tracker.Options{
+					Timeout:      timeout,
+					LogsFromTime: waiter.LogsFromTime,
+				},
+			})
 		})
 	})
 }
@@ -329,12 +333,14 @@ func (waiter *ResourcesWaiter) WatchUntilReady(namespace string, reader io.Reade
 			}
 
 			return logboek.LogProcess(fmt.Sprintf("Waiting for helm hook job/%s termination", name), logboek.LogProcessOptions{}, func() error {
-				return multitrack.Multitrack(kube.Kubernetes, specs, multitrack.MultitrackOptions{
-					StatusProgressPeriod: waiter.HooksStatusProgressPeriod,
-					Options: tracker.Options{
-						Timeout:      timeout,
-						LogsFromTime: waiter.LogsFromTime,
-					},
+				return tracing.Trace("kubedog track", map[string]string{"namespace": namespace, "job": name}, func() error {
+					return multitrack.Multitrack(kube.Kubernetes, specs, multitrack.MultitrackOptions{
+						StatusProgressPeriod: waiter.HooksStatusProgressPeriod,
+						Options: tracker.Options{
+							Timeout:      timeout,
+							LogsFromTime: waiter.LogsFromTime,
+						},
+					})
 				})
 			})
 
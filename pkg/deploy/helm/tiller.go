@@ -9,6 +9,7 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/flant/werf/pkg/logging"
 	"github.com/flant/werf/pkg/util/secretvalues"
 
 	"github.com/gosuri/uitable"
@@ -334,7 +335,7 @@ func ReleaseInstall(chartPath, releaseName, namespace string, values []string, s
 	}
 
 	return logboek.LogBlock(fmt.Sprintf("Deployed release info"), logboek.LogBlockOptions{}, func() error {
-		return fprintReleaseStatus(logboek.GetOutStream(), releaseName)
+		return fprintReleaseStatus(logging.GetOutStream(), releaseName)
 	})
 }
 
@@ -370,7 +371,7 @@ func ReleaseUpdate(chartPath, releaseName string, values []string, secretValues
 	}
 
 	return logboek.LogBlock(fmt.Sprintf("Deployed release info"), logboek.LogBlockOptions{}, func() error {
-		return fprintReleaseStatus(logboek.GetOutStream(), releaseName)
+		return fprintReleaseStatus(logging.GetOutStream(), releaseName)
 	})
 }
 
@@ -537,7 +538,7 @@ func displayReleaseLogMessages() {
 	logboek.LogOptionalLn()
 	_ = logboek.Default.LogBlock("Debug info", logboek.LevelLogBlockOptions{}, func() error {
 		for _, msg := range releaseLogMessages {
-			_, _ = logboek.OutF("%s\n", logboek.DetailsStyle().Colorize(secretvalues.MaskSecretValuesInString(releaseLogSecretValuesToMask, msg)))
+			_, _ = fmt.Fprintf(logging.GetOutStream(), "%s\n", logboek.DetailsStyle().Colorize(secretvalues.MaskSecretValuesInString(releaseLogSecretValuesToMask, msg)))
 		}
 
 		return nil
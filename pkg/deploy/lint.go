@@ -18,11 +18,14 @@ type LintOptions struct {
 	SecretValues    []string
 	Set             []string
 	SetString       []string
+	SecretSet       []string
+	SetFileSecret   []string
 	Env             string
 	IgnoreSecretKey bool
 }
 
 func RunLint(projectDir, helmChartDir string, werfConfig *config.WerfConfig, imagesRepository string, images []images_manager.ImageInfoGetter, commonTag string, tagStrategy tag_strategy.TagStrategy, opts LintOptions) error {
+	registerSetSecretValues(opts.Set, opts.SetString, opts.SecretSet)
 	logboek.Debug.LogF("Lint options: %#v\n", opts)
 
 	m, err := GetSafeSecretManager(projectDir, helmChartDir, opts.SecretValues, opts.IgnoreSecretKey)
@@ -37,17 +40,20 @@ func RunLint(projectDir, helmChartDir string, werfConfig *config.WerfConfig, ima
 		return fmt.Errorf("error creating service values: %s", err)
 	}
 
-	werfChart, err := PrepareWerfChart(werfConfig.Meta.Project, helmChartDir, opts.Env, m, opts.SecretValues, serviceValues)
+	werfChart, err := PrepareWerfChart(werfConfig.Meta.Project, helmChartDir, opts.Env, m, opts.SecretValues, opts.SecretSet, opts.SetFileSecret, serviceValues)
 	if err != nil {
 		return err
 	}
 	helm.SetReleaseLogSecretValuesToMask(werfChart.SecretValuesToMask)
 
 	helm.WerfTemplateEngine.InitWerfEngineExtraTemplatesFunctions(werfChart.DecodedSecretFilesData)
-	patchLoadChartfile(werfChart.Name)
+	patchLoadChartfile(werfChart.Name, nil)
+
+	out := secretvalues.NewMaskingWriter(os.Stdout)
+	defer out.Flush()
 
 	if err := helm.Lint(
-		os.Stdout,
+		out,
 		werfChart.ChartDir,
 		namespace,
 		append(werfChart.Values, opts.Values...),
@@ -0,0 +1,247 @@
+package deploy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/logboek"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/storage"
+)
+
+const (
+	PreviewProjectLabel = "werf.io/preview-project"
+	PreviewIDLabel      = "werf.io/preview-id"
+	// PreviewExpiresLabel contains unix time after which the preview could be dismissed by gc
+	PreviewExpiresLabel = "werf.io/preview-expires"
+
+	PreviewReleaseAnnotation = "werf.io/preview-release"
+	PreviewBranchAnnotation  = "werf.io/preview-branch"
+)
+
+type Preview struct {
+	ID        string
+	Release   string
+	Namespace string
+	Branch    string
+	Expires   time.Time
+}
+
+func NewPreview(projectName, id, branch string, ttl time.Duration) *Preview {
+	name := fmt.Sprintf("%s-preview-%s", projectName, id)
+
+	p := &Preview{
+		ID:        slug.KubernetesNamespace(id),
+		Release:   slug.HelmRelease(name),
+		Namespace: slug.KubernetesNamespace(name),
+		Branch:    branch,
+	}
+
+	if ttl != 0 {
+		p.Expires = time.Now().Add(ttl)
+	}
+
+	return p
+}
+
+// Labels returns labels of the preview namespace and all preview release resources
+func (p *Preview) Labels(projectName string) map[string]string {
+	labels := map[string]string{
+		PreviewProjectLabel: projectName,
+		PreviewIDLabel:      p.ID,
+	}
+
+	if !p.Expires.IsZero() {
+		labels[PreviewExpiresLabel] = strconv.FormatInt(p.Expires.Unix(), 10)
+	}
+
+	return labels
+}
+
+func (p *Preview) Annotations() map[string]string {
+	annotations := map[string]string{PreviewReleaseAnnotation: p.Release}
+
+	if p.Branch != "" {
+		annotations[PreviewBranchAnnotation] = p.Branch
+	}
+
+	return annotations
+}
+
+// DismissReason returns the reason to dismiss the preview or empty string if the preview is still needed.
+// Branch is checked only when the list of existing git branches is specified.
+func (p *Preview) DismissReason(gitBranches []string, now time.Time) string {
+	if !p.Expires.IsZero() && now.After(p.Expires) {
+		return fmt.Sprintf("ttl elapsed at %s", p.Expires.Format(time.RFC3339))
+	}
+
+	if gitBranches != nil && p.Branch != "" {
+		for _, branch := range gitBranches {
+			if branch == p.Branch {
+				return ""
+			}
+		}
+
+		return fmt.Sprintf("git branch %s no longer exists", p.Branch)
+	}
+
+	return ""
+}
+
+// ApplyPreviewNamespace creates the preview namespace or updates its labels and annotations.
+// The namespace is the source of the previews list for gc, so it is marked before the release deploy.
+func ApplyPreviewNamespace(projectName string, preview *Preview) error {
+	namespaces := kube.Kubernetes.CoreV1().Namespaces()
+
+	isExist := true
+	ns, err := namespaces.Get(preview.Namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		isExist = false
+		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: preview.Namespace}}
+	} else if err != nil {
+		return fmt.Errorf("get Namespace %s error: %s", preview.Namespace, err)
+	}
+
+	if ns.Labels == nil {
+		ns.Labels = map[string]string{}
+	}
+	delete(ns.Labels, PreviewExpiresLabel)
+	for k, v := range preview.Labels(projectName) {
+		ns.Labels[k] = v
+	}
+
+	if ns.Annotations == nil {
+		ns.Annotations = map[string]string{}
+	}
+	delete(ns.Annotations, PreviewBranchAnnotation)
+	for k, v := range preview.Annotations() {
+		ns.Annotations[k] = v
+	}
+
+	if isExist {
+		if _, err := namespaces.Update(ns); err != nil {
+			return fmt.Errorf("update Namespace %s error: %s", preview.Namespace, err)
+		}
+	} else if _, err := namespaces.Create(ns); err != nil {
+		return fmt.Errorf("create Namespace %s error: %s", preview.Namespace, err)
+	}
+
+	return nil
+}
+
+// GetPreviews returns project previews found by the preview namespaces labels
+func GetPreviews(projectName string) ([]*Preview, error) {
+	list, err := kube.Kubernetes.CoreV1().Namespaces().List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", PreviewProjectLabel, projectName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list Namespaces error: %s", err)
+	}
+
+	var previews []*Preview
+	for _, ns := range list.Items {
+		p := &Preview{
+			ID:        ns.Labels[PreviewIDLabel],
+			Release:   ns.Annotations[PreviewReleaseAnnotation],
+			Namespace: ns.Name,
+			Branch:    ns.Annotations[PreviewBranchAnnotation],
+		}
+
+		if p.Release == "" {
+			logboek.LogWarnF("WARNING: Namespace %s has no %s annotation: skipping\n", ns.Name, PreviewReleaseAnnotation)
+			continue
+		}
+
+		if value, hasKey := ns.Labels[PreviewExpiresLabel]; hasKey {
+			expires, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				logboek.LogWarnF("WARNING: Namespace %s has bad %s label value %q: %s\n", ns.Name, PreviewExpiresLabel, value, err)
+			} else {
+				p.Expires = time.Unix(expires, 0)
+			}
+		}
+
+		previews = append(previews, p)
+	}
+
+	return previews, nil
+}
+
+type PreviewGCOptions struct {
+	// GitBranches are existing git branches, nil disables the branch check
+	GitBranches []string
+	WithHooks   bool
+	DryRun      bool
+}
+
+func RunPreviewGC(projectName, kubeContext string, storageLockManager storage.LockManager, opts PreviewGCOptions) error {
+	previews, err := GetPreviews(projectName)
+	if err != nil {
+		return err
+	}
+
+	var failedPreviews []string
+	now := time.Now()
+	for _, p := range previews {
+		reason := p.DismissReason(opts.GitBranches, now)
+		if reason == "" {
+			logboek.Info.LogF("Preview %s is kept\n", p.ID)
+			continue
+		}
+
+		if err := logboek.LogProcess(fmt.Sprintf("Dismissing preview %s (%s)", p.ID, reason), logboek.LogProcessOptions{}, func() error {
+			if opts.DryRun {
+				logboek.LogF("Helm release %s and namespace %s would be deleted\n", p.Release, p.Namespace)
+				return nil
+			}
+
+			if err := RunDismiss(projectName, p.Release, p.Namespace, kubeContext, storageLockManager, DismissOptions{
+				WithNamespace: true,
+				WithHooks:     opts.WithHooks,
+			}); err != nil {
+				return err
+			}
+
+			// the namespace is left when the release has not been deployed
+			return deletePreviewNamespace(p.Namespace)
+		}); err != nil {
+			// the rest previews are processed anyway, one broken release should not keep all stale previews
+			logboek.LogWarnF("WARNING: Dismiss preview %s failed: %s\n", p.ID, err)
+			failedPreviews = append(failedPreviews, p.ID)
+		}
+	}
+
+	if len(failedPreviews) != 0 {
+		return fmt.Errorf("dismiss previews %s failed", strings.Join(failedPreviews, ", "))
+	}
+
+	return nil
+}
+
+func deletePreviewNamespace(namespace string) error {
+	namespaces := kube.Kubernetes.CoreV1().Namespaces()
+
+	ns, err := namespaces.Get(namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get Namespace %s error: %s", namespace, err)
+	}
+
+	if ns.DeletionTimestamp != nil {
+		return nil
+	}
+
+	if err := namespaces.Delete(namespace, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete Namespace %s error: %s", namespace, err)
+	}
+
+	return nil
+}
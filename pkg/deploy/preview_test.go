@@ -0,0 +1,161 @@
+package deploy
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flant/kubedog/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flant/werf/pkg/slug"
+	"github.com/flant/werf/pkg/storage"
+)
+
+func TestNewPreview(t *testing.T) {
+	p := NewPreview("myproject", "Feature/X_42", "feature/x", 0)
+
+	if err := slug.ValidateKubernetesNamespace(p.ID); err != nil || p.ID == "Feature/X_42" {
+		t.Errorf("preview id %q should be slugified", p.ID)
+	}
+
+	if !strings.HasPrefix(p.Release, "myproject-preview-feature-x-42") || !strings.HasPrefix(p.Namespace, "myproject-preview-feature-x-42") {
+		t.Errorf("unexpected preview release %q and namespace %q", p.Release, p.Namespace)
+	}
+
+	if !p.Expires.IsZero() {
+		t.Errorf("preview without ttl should not expire")
+	}
+
+	if _, hasKey := p.Labels("myproject")[PreviewExpiresLabel]; hasKey {
+		t.Errorf("preview without ttl should not have %s label", PreviewExpiresLabel)
+	}
+
+	p = NewPreview("myproject", "42", "", time.Hour)
+	if p.Release != "myproject-preview-42" || p.Namespace != "myproject-preview-42" || p.ID != "42" {
+		t.Errorf("unexpected preview %+v", p)
+	}
+
+	if p.Expires.Before(time.Now().Add(59 * time.Minute)) {
+		t.Errorf("unexpected preview expiry %s", p.Expires)
+	}
+
+	if _, hasKey := p.Annotations()[PreviewBranchAnnotation]; hasKey {
+		t.Errorf("preview without branch should not have %s annotation", PreviewBranchAnnotation)
+	}
+}
+
+func TestPreview_DismissReason(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		preview     Preview
+		gitBranches []string
+		dismiss     bool
+	}{
+		{name: "alive", preview: Preview{Branch: "feature-x", Expires: now.Add(time.Hour)}, gitBranches: []string{"master", "feature-x"}},
+		{name: "expired", preview: Preview{Branch: "feature-x", Expires: now.Add(-time.Hour)}, gitBranches: []string{"feature-x"}, dismiss: true},
+		{name: "no expiry", preview: Preview{Branch: "feature-x"}, gitBranches: []string{"feature-x"}},
+		{name: "branch removed", preview: Preview{Branch: "feature-x", Expires: now.Add(time.Hour)}, gitBranches: []string{"master"}, dismiss: true},
+		{name: "branch check disabled", preview: Preview{Branch: "feature-x"}},
+		{name: "no branch", preview: Preview{}, gitBranches: []string{"master"}},
+	}
+
+	for _, tt := range tests {
+		if reason := tt.preview.DismissReason(tt.gitBranches, now); (reason != "") != tt.dismiss {
+			t.Errorf("%s: unexpected dismiss reason %q", tt.name, reason)
+		}
+	}
+}
+
+func TestGetPreviews(t *testing.T) {
+	kube.Kubernetes = fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+	}, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "other-preview-1",
+			Labels: map[string]string{PreviewProjectLabel: "other", PreviewIDLabel: "1"},
+		},
+	})
+
+	expires := time.Now().Add(time.Hour)
+	preview := NewPreview("myproject", "42", "feature-x", time.Hour)
+	if err := ApplyPreviewNamespace("myproject", preview); err != nil {
+		t.Fatal(err)
+	}
+
+	preview = NewPreview("myproject", "42", "feature-y", 0)
+	if err := ApplyPreviewNamespace("myproject", preview); err != nil {
+		t.Fatal(err)
+	}
+
+	previews, err := GetPreviews("myproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(previews) != 1 {
+		t.Fatalf("expected 1 preview, got %d", len(previews))
+	}
+
+	p := previews[0]
+	if p.ID != "42" || p.Release != "myproject-preview-42" || p.Namespace != "myproject-preview-42" || p.Branch != "feature-y" {
+		t.Errorf("unexpected preview %+v", p)
+	}
+
+	if !p.Expires.IsZero() {
+		t.Errorf("preview expiry %s should be removed by the last up without ttl", p.Expires)
+	}
+
+	preview = NewPreview("myproject", "42", "feature-y", time.Hour)
+	if err := ApplyPreviewNamespace("myproject", preview); err != nil {
+		t.Fatal(err)
+	}
+
+	if previews, err = GetPreviews("myproject"); err != nil {
+		t.Fatal(err)
+	} else if previews[0].Expires.Unix() < expires.Unix() {
+		t.Errorf("unexpected preview expiry %s", previews[0].Expires)
+	}
+}
+
+type previewLockManagerStub struct {
+	storage.LockManager
+
+	lockedReleases []string
+}
+
+func (m *previewLockManagerStub) LockDeployProcess(_, releaseName, _ string) (storage.LockHandle, error) {
+	m.lockedReleases = append(m.lockedReleases, releaseName)
+	return storage.LockHandle{}, fmt.Errorf("lock %s failed", releaseName)
+}
+
+func TestRunPreviewGC_ContinuesAfterFailedDismiss(t *testing.T) {
+	kube.Kubernetes = fake.NewSimpleClientset()
+
+	for _, id := range []string{"1", "2"} {
+		if err := ApplyPreviewNamespace("myproject", NewPreview("myproject", id, "", -time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ApplyPreviewNamespace("myproject", NewPreview("myproject", "3", "", time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	lockManager := &previewLockManagerStub{}
+	err := RunPreviewGC("myproject", "", lockManager, PreviewGCOptions{})
+	if err == nil || !strings.Contains(err.Error(), "1, 2") {
+		t.Errorf("expected error for previews 1 and 2, got %v", err)
+	}
+
+	expected := []string{"myproject-preview-1", "myproject-preview-2"}
+	if !reflect.DeepEqual(lockManager.lockedReleases, expected) {
+		t.Errorf("expected dismiss of %v, got %v", expected, lockManager.lockedReleases)
+	}
+}
@@ -0,0 +1,54 @@
+package deploy
+
+import (
+	"encoding/json"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/deploy/helm"
+	"github.com/flant/werf/pkg/git_repo"
+	"github.com/flant/werf/pkg/images_manager"
+	"github.com/flant/werf/pkg/tag_strategy"
+)
+
+// GetReleaseAnnotations returns the info which is recorded in each release revision and used by werf rollback
+func GetReleaseAnnotations(projectDir, commonTag string, tagStrategy tag_strategy.TagStrategy, images []images_manager.ImageInfoGetter) (map[string]string, error) {
+	annotations := map[string]string{}
+
+	localGitRepo, err := git_repo.OpenLocalRepo("own", projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if localGitRepo != nil {
+		if commit, err := localGitRepo.HeadCommit(); err != nil {
+			logboek.LogWarnF("WARNING: Unable to get git head commit of the project: %s\n", err)
+		} else {
+			annotations[helm.ReleaseGitCommitAnnotation] = commit
+		}
+	}
+
+	if commonTag != "" {
+		annotations[helm.ReleaseTagAnnotation] = commonTag
+	}
+
+	if tagStrategy != "" {
+		annotations[helm.ReleaseTagStrategyAnnotation] = string(tagStrategy)
+	}
+
+	if len(images) != 0 {
+		imagesRefs := map[string]string{}
+		for _, image := range images {
+			imagesRefs[image.GetName()] = image.GetImageName()
+		}
+
+		data, err := json.Marshal(imagesRefs)
+		if err != nil {
+			return nil, err
+		}
+
+		annotations[helm.ReleaseImagesAnnotation] = string(data)
+	}
+
+	return annotations, nil
+}
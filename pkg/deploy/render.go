@@ -19,6 +19,8 @@ type RenderOptions struct {
 	SecretValues         []string
 	Set                  []string
 	SetString            []string
+	SecretSet            []string
+	SetFileSecret        []string
 	Env                  string
 	UserExtraAnnotations map[string]string
 	UserExtraLabels      map[string]string
@@ -26,6 +28,7 @@ type RenderOptions struct {
 }
 
 func RunRender(out io.Writer, projectDir, helmChartDir string, werfConfig *config.WerfConfig, imagesRepository string, images []images_manager.ImageInfoGetter, commonTag string, tagStrategy tag_strategy.TagStrategy, opts RenderOptions) error {
+	registerSetSecretValues(opts.Set, opts.SetString, opts.SecretSet)
 	logboek.Debug.LogF("Render options: %#v\n", opts)
 
 	m, err := GetSafeSecretManager(projectDir, helmChartDir, opts.SecretValues, opts.IgnoreSecretKey)
@@ -38,7 +41,7 @@ func RunRender(out io.Writer, projectDir, helmChartDir string, werfConfig *confi
 		return err
 	}
 
-	werfChart, err := PrepareWerfChart(werfConfig.Meta.Project, helmChartDir, opts.Env, m, opts.SecretValues, serviceValues)
+	werfChart, err := PrepareWerfChart(werfConfig.Meta.Project, helmChartDir, opts.Env, m, opts.SecretValues, opts.SecretSet, opts.SetFileSecret, serviceValues)
 	if err != nil {
 		return err
 	}
@@ -53,7 +56,7 @@ func RunRender(out io.Writer, projectDir, helmChartDir string, werfConfig *confi
 	}
 
 	helm.WerfTemplateEngine.InitWerfEngineExtraTemplatesFunctions(werfChart.DecodedSecretFilesData)
-	patchLoadChartfile(werfChart.Name)
+	patchLoadChartfile(werfChart.Name, nil)
 
 	return helm.WerfTemplateEngineWithExtraAnnotationsAndLabels(werfChart.ExtraAnnotations, werfChart.ExtraLabels, func() error {
 		return helm.Render(
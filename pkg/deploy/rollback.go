@@ -0,0 +1,78 @@
+package deploy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/flant/kubedog/pkg/kube"
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/deploy/helm"
+	"github.com/flant/werf/pkg/storage"
+)
+
+type RollbackOptions struct {
+	ToCommit             string
+	ToPreviousSuccessful bool
+	Timeout              time.Duration
+	ThreeWayMergeMode    helm.ThreeWayMergeModeType
+}
+
+func RunRollback(projectName, release, namespace string, storageLockManager storage.LockManager, opts RollbackOptions) error {
+	if lock, err := storageLockManager.LockDeployProcess(projectName, release, kube.Context); err != nil {
+		return err
+	} else {
+		defer storageLockManager.Unlock(lock)
+	}
+
+	if err := logboek.Default.LogBlock("Rollback options", logboek.LevelLogBlockOptions{}, func() error {
+		if kube.Context != "" {
+			logboek.LogF("Kube-config context: %s\n", kube.Context)
+		}
+		logboek.LogF("Kubernetes namespace: %s\n", namespace)
+		logboek.LogF("Helm release storage namespace: %s\n", helm.HelmReleaseStorageNamespace)
+		logboek.LogF("Helm release storage type: %s\n", helm.HelmReleaseStorageType)
+		logboek.LogF("Helm release name: %s\n", release)
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	logboek.LogOptionalLn()
+
+	revisions, err := helm.GetReleaseRevisions(release)
+	if err != nil {
+		return err
+	}
+
+	if len(revisions) != 0 && revisions[0].Namespace != namespace {
+		return fmt.Errorf("existing release has been deployed in namespace %s (not in specified %s): check --namespace option value", revisions[0].Namespace, namespace)
+	}
+
+	target, err := helm.ResolveRollbackRevision(revisions, helm.RollbackTarget{
+		ToCommit:             opts.ToCommit,
+		ToPreviousSuccessful: opts.ToPreviousSuccessful,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to resolve rollback revision: %s", err)
+	}
+
+	changes, err := helm.GetReleaseRevisionsChanges(revisions[0], target)
+	if err != nil {
+		return err
+	}
+
+	blockMsg := fmt.Sprintf("Rollback from revision %d (%s) to revision %d (%s)", revisions[0].Revision, revisions[0].Status, target.Revision, target.Status)
+	_ = logboek.Default.LogBlock(blockMsg, logboek.LevelLogBlockOptions{}, func() error {
+		changes.Log()
+		return nil
+	})
+
+	logboek.LogOptionalLn()
+
+	return helm.RollbackReleaseToRevision(release, namespace, target.Revision, helm.ChartOptions{
+		Timeout:           opts.Timeout,
+		ThreeWayMergeMode: opts.ThreeWayMergeMode,
+	})
+}
@@ -0,0 +1,58 @@
+package strategy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// AnalysisRequest is sent to the canary analysis endpoint as a JSON POST request body.
+// The endpoint could query any metrics system and decides whether the canary is healthy.
+type AnalysisRequest struct {
+	Namespace       string `json:"namespace"`
+	Deployment      string `json:"deployment"`
+	Canary          string `json:"canary"`
+	Step            int    `json:"step"`
+	ReplicasPercent int    `json:"replicasPercent"`
+}
+
+// AnalysisResult is expected in the JSON response body of the canary analysis endpoint
+type AnalysisResult struct {
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+var AnalysisTimeout = 30 * time.Second
+
+func RunAnalysis(url string, request AnalysisRequest) (*AnalysisResult, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: AnalysisTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s response: %s", url, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with status %s: %s", url, resp.Status, bytes.TrimSpace(data))
+	}
+
+	result := &AnalysisResult{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("unable to parse %s response: %s", url, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/flant/logboek"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const GreenTrack = "green"
+
+// blueGreenRollout runs the full copy of the new deployment version and switches services to it when it is ready.
+// Services are switched back after the release deploy has updated the original deployment.
+//
+// The track label is added to the live service selector only, so the release deploy keeps it with and without helm three-way merge:
+// the patch changes only the fields which differ in the previous and the new release manifests, which both have no track label.
+// The label is lost only if the service is recreated by the release deploy, Finish warns about it.
+type blueGreenRollout struct {
+	client     kubernetes.Interface
+	deployment *Deployment
+	replicas   int32
+	opts       Options
+}
+
+func newBlueGreenRollout(client kubernetes.Interface, deployment *Deployment, replicas int32, opts Options) *blueGreenRollout {
+	return &blueGreenRollout{client: client, deployment: deployment, replicas: replicas, opts: opts}
+}
+
+func (r *blueGreenRollout) Start() error {
+	green := newDeploymentCopy(r.deployment.Deployment, GreenTrack, r.replicas)
+
+	if err := applyDeployment(r.client, green); err != nil {
+		return fmt.Errorf("unable to apply deployment/%s: %s", green.Name, err)
+	}
+
+	if err := trackDeployment(r.client, green, r.opts); err != nil {
+		return err
+	}
+
+	for _, name := range r.deployment.BlueGreenServices {
+		if err := SwitchServiceSelector(r.client, green.Namespace, name, GreenTrack); err != nil {
+			return err
+		}
+
+		logboek.LogF("service/%s switched to deployment/%s\n", name, green.Name)
+	}
+
+	return nil
+}
+
+func (r *blueGreenRollout) Finish() error {
+	namespace := r.deployment.Deployment.Namespace
+
+	for _, name := range r.deployment.BlueGreenServices {
+		service, err := r.client.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get service/%s: %s", name, err)
+		}
+
+		if service.Spec.Selector[TrackLabelName] != GreenTrack {
+			logboek.LogWarnF("WARNING: service/%s selector has been reset by the release deploy, the service has been switched to deployment/%s before the release deploy finished\n", name, r.deployment.Deployment.Name)
+		}
+	}
+
+	return r.restore()
+}
+
+func (r *blueGreenRollout) Abort() error {
+	return r.restore()
+}
+
+func (r *blueGreenRollout) restore() error {
+	namespace := r.deployment.Deployment.Namespace
+
+	for _, name := range r.deployment.BlueGreenServices {
+		if err := SwitchServiceSelector(r.client, namespace, name, ""); err != nil {
+			return err
+		}
+
+		logboek.LogF("service/%s switched to deployment/%s\n", name, r.deployment.Deployment.Name)
+	}
+
+	return deleteDeployment(r.client, namespace, fmt.Sprintf("%s-%s", r.deployment.Deployment.Name, GreenTrack))
+}
+
+// SwitchServiceSelector adds the track label to the service selector or removes it when the track is empty
+func SwitchServiceSelector(client kubernetes.Interface, namespace, name, track string) error {
+	services := client.CoreV1().Services(namespace)
+
+	service, err := services.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("service/%s not found in namespace %s", name, namespace)
+		}
+		return fmt.Errorf("unable to get service/%s: %s", name, err)
+	}
+
+	if track == "" {
+		if _, hasKey := service.Spec.Selector[TrackLabelName]; !hasKey {
+			return nil
+		}
+		delete(service.Spec.Selector, TrackLabelName)
+	} else {
+		if service.Spec.Selector == nil {
+			service.Spec.Selector = map[string]string{}
+		}
+		service.Spec.Selector[TrackLabelName] = track
+	}
+
+	if _, err := services.Update(service); err != nil {
+		return fmt.Errorf("unable to update service/%s selector: %s", name, err)
+	}
+
+	return nil
+}
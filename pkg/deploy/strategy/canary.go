@@ -0,0 +1,93 @@
+package strategy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/flant/logboek"
+	"k8s.io/client-go/kubernetes"
+)
+
+const CanaryTrack = "canary"
+
+// canaryRollout runs the canary copy of the new deployment version alongside the current one increasing replicas step by step
+type canaryRollout struct {
+	client         kubernetes.Interface
+	deployment     *Deployment
+	stableReplicas int32
+	opts           Options
+}
+
+func newCanaryRollout(client kubernetes.Interface, deployment *Deployment, stableReplicas int32, opts Options) *canaryRollout {
+	return &canaryRollout{client: client, deployment: deployment, stableReplicas: stableReplicas, opts: opts}
+}
+
+func (r *canaryRollout) Start() error {
+	steps := r.deployment.CanarySteps
+	for ind, step := range steps {
+		replicas := CanaryReplicas(r.stableReplicas, step.ReplicasPercent)
+		canary := newDeploymentCopy(r.deployment.Deployment, CanaryTrack, replicas)
+
+		logProcessMsg := fmt.Sprintf("Step %d/%d: %d%% (%d of %d replicas)", ind+1, len(steps), step.ReplicasPercent, replicas, r.stableReplicas)
+		if err := logboek.LogProcess(logProcessMsg, logboek.LogProcessOptions{}, func() error {
+			if err := applyDeployment(r.client, canary); err != nil {
+				return fmt.Errorf("unable to apply deployment/%s: %s", canary.Name, err)
+			}
+
+			if err := trackDeployment(r.client, canary, r.opts); err != nil {
+				return err
+			}
+
+			if step.Pause > 0 {
+				logboek.LogF("Pausing for %s\n", step.Pause)
+				time.Sleep(step.Pause)
+			}
+
+			if r.deployment.CanaryAnalysisURL != "" {
+				result, err := RunAnalysis(r.deployment.CanaryAnalysisURL, AnalysisRequest{
+					Namespace:       canary.Namespace,
+					Deployment:      r.deployment.Deployment.Name,
+					Canary:          canary.Name,
+					Step:            ind + 1,
+					ReplicasPercent: step.ReplicasPercent,
+				})
+				if err != nil {
+					return fmt.Errorf("canary analysis failed: %s", err)
+				}
+
+				if !result.Passed {
+					return fmt.Errorf("canary analysis has not passed: %s", result.Message)
+				}
+
+				logboek.LogF("Canary analysis passed: %s\n", result.Message)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *canaryRollout) Finish() error {
+	return deleteDeployment(r.client, r.deployment.Deployment.Namespace, r.canaryName())
+}
+
+func (r *canaryRollout) Abort() error {
+	return deleteDeployment(r.client, r.deployment.Deployment.Namespace, r.canaryName())
+}
+
+func (r *canaryRollout) canaryName() string {
+	return fmt.Sprintf("%s-%s", r.deployment.Deployment.Name, CanaryTrack)
+}
+
+// CanaryReplicas returns the percent of the stable replicas rounded up, at least one replica
+func CanaryReplicas(stableReplicas int32, percent int) int32 {
+	replicas := (int64(stableReplicas)*int64(percent) + 99) / 100
+	if replicas < 1 {
+		return 1
+	}
+	return int32(replicas)
+}
@@ -0,0 +1,342 @@
+package strategy
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	yaml_v2 "gopkg.in/yaml.v2"
+
+	"github.com/flant/kubedog/pkg/tracker"
+	"github.com/flant/kubedog/pkg/trackers/rollout/multitrack"
+	"github.com/flant/logboek"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/flant/werf/pkg/deploy/helm"
+)
+
+const (
+	StrategyAnnoName          = "werf.io/strategy"
+	CanaryStepsAnnoName       = "werf.io/canary-steps"
+	CanaryAnalysisURLAnnoName = "werf.io/canary-analysis-url"
+	BlueGreenServicesAnnoName = "werf.io/blue-green-services"
+
+	// TrackLabelName distinguishes pods of the deployment copy created by the strategy
+	TrackLabelName = "werf.io/track"
+)
+
+type StrategyType string
+
+const (
+	CanaryStrategy    StrategyType = "canary"
+	BlueGreenStrategy StrategyType = "blue-green"
+)
+
+var DefaultCanarySteps = []CanaryStep{{ReplicasPercent: 20, Pause: time.Minute}}
+
+type CanaryStep struct {
+	ReplicasPercent int
+	Pause           time.Duration
+}
+
+// Deployment is the chart Deployment with the strategy configured by annotations
+type Deployment struct {
+	Strategy   StrategyType
+	Deployment *appsv1.Deployment
+
+	CanarySteps       []CanaryStep
+	CanaryAnalysisURL string
+
+	BlueGreenServices []string
+
+	template helm.Template
+}
+
+type Options struct {
+	Timeout time.Duration
+}
+
+type rollout interface {
+	// Start rolls out the new version of the deployment alongside the current one
+	Start() error
+	// Finish removes the strategy resources after the release has been deployed
+	Finish() error
+	// Abort returns the deployment to the state before Start
+	Abort() error
+}
+
+// GetDeployments returns chart deployments annotated with werf.io/strategy
+func GetDeployments(namespace string, templates helm.ChartTemplates) ([]*Deployment, error) {
+	var res []*Deployment
+	for _, t := range templates.Deployments() {
+		if _, hasKey := t.Metadata.Annotations[StrategyAnnoName]; !hasKey {
+			continue
+		}
+
+		d, err := newDeployment(namespace, t)
+		if err != nil {
+			return nil, fmt.Errorf("bad deployment/%s strategy: %s", t.Metadata.Name, err)
+		}
+
+		res = append(res, d)
+	}
+
+	return res, nil
+}
+
+func newDeployment(namespace string, t helm.Template) (*Deployment, error) {
+	data, err := yaml_v2.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := yaml.Unmarshal(data, deployment); err != nil {
+		return nil, fmt.Errorf("unable to parse deployment: %s", err)
+	}
+	deployment.Namespace = t.Namespace(namespace)
+
+	d := &Deployment{
+		Strategy:   StrategyType(t.Metadata.Annotations[StrategyAnnoName]),
+		Deployment: deployment,
+		template:   t,
+	}
+
+	switch d.Strategy {
+	case CanaryStrategy:
+		d.CanarySteps = DefaultCanarySteps
+		if value, hasKey := t.Metadata.Annotations[CanaryStepsAnnoName]; hasKey {
+			if d.CanarySteps, err = ParseCanarySteps(value); err != nil {
+				return nil, fmt.Errorf("bad %s annotation value %q: %s", CanaryStepsAnnoName, value, err)
+			}
+		}
+
+		d.CanaryAnalysisURL = t.Metadata.Annotations[CanaryAnalysisURLAnnoName]
+
+	case BlueGreenStrategy:
+		for _, name := range strings.Split(t.Metadata.Annotations[BlueGreenServicesAnnoName], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				d.BlueGreenServices = append(d.BlueGreenServices, name)
+			}
+		}
+
+		if len(d.BlueGreenServices) == 0 {
+			return nil, fmt.Errorf("%s annotation with the services to switch is required", BlueGreenServicesAnnoName)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown %s annotation value %q: %s or %s expected", StrategyAnnoName, d.Strategy, CanaryStrategy, BlueGreenStrategy)
+	}
+
+	return d, nil
+}
+
+// ParseCanarySteps parses comma-separated steps in the format PERCENT%[:PAUSE], e.g. 10%:1m,50%:5m
+func ParseCanarySteps(value string) ([]CanaryStep, error) {
+	var steps []CanaryStep
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		fields := strings.SplitN(part, ":", 2)
+
+		percent, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(fields[0]), "%"))
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("bad step %q: replicas percent in range 1-100 expected", part)
+		}
+
+		step := CanaryStep{ReplicasPercent: percent}
+		if len(fields) == 2 {
+			if step.Pause, err = time.ParseDuration(strings.TrimSpace(fields[1])); err != nil {
+				return nil, fmt.Errorf("bad step %q pause: %s", part, err)
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no steps specified")
+	}
+
+	return steps, nil
+}
+
+// Run rolls out the changed strategy deployments before deployFunc and removes the strategy resources after
+func Run(client kubernetes.Interface, deployments []*Deployment, deployedTemplates helm.ChartTemplates, opts Options, deployFunc func() error) error {
+	var rollouts []rollout
+	var names []string
+	for _, d := range deployments {
+		if !isDeploymentChanged(d, deployedTemplates) {
+			continue
+		}
+
+		// the strategy is not needed for the first rollout
+		current, err := client.AppsV1().Deployments(d.Deployment.Namespace).Get(d.Deployment.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("unable to get deployment/%s: %s", d.Deployment.Name, err)
+		}
+
+		switch d.Strategy {
+		case CanaryStrategy:
+			rollouts = append(rollouts, newCanaryRollout(client, d, specReplicas(current), opts))
+		case BlueGreenStrategy:
+			rollouts = append(rollouts, newBlueGreenRollout(client, d, specReplicas(current), opts))
+		}
+		names = append(names, fmt.Sprintf("deployment/%s (%s)", d.Deployment.Name, d.Strategy))
+	}
+
+	if len(rollouts) == 0 {
+		return deployFunc()
+	}
+
+	var started []rollout
+	abort := func() {
+		_ = logboek.LogProcess("Aborting strategies", logboek.LogProcessOptions{}, func() error {
+			for i := len(started) - 1; i >= 0; i-- {
+				if err := started[i].Abort(); err != nil {
+					logboek.LogWarnF("WARNING: %s\n", err)
+				}
+			}
+			return nil
+		})
+	}
+
+	for ind, r := range rollouts {
+		started = append(started, r)
+		if err := logboek.LogProcess(fmt.Sprintf("Rolling out %s", names[ind]), logboek.LogProcessOptions{}, r.Start); err != nil {
+			abort()
+			return fmt.Errorf("%s rollout aborted: %s", names[ind], err)
+		}
+	}
+
+	if err := deployFunc(); err != nil {
+		abort()
+		return err
+	}
+
+	for ind, r := range rollouts {
+		if err := logboek.LogProcess(fmt.Sprintf("Finishing %s", names[ind]), logboek.LogProcessOptions{}, r.Finish); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isDeploymentChanged(d *Deployment, deployedTemplates helm.ChartTemplates) bool {
+	for _, t := range deployedTemplates.Deployments() {
+		if t.Metadata.Name == d.template.Metadata.Name {
+			return !reflect.DeepEqual(podTemplate(t), podTemplate(d.template))
+		}
+	}
+
+	return false
+}
+
+func podTemplate(t helm.Template) interface{} {
+	if spec, ok := t.OtherFields["spec"].(map[interface{}]interface{}); ok {
+		return spec["template"]
+	}
+	return nil
+}
+
+// newDeploymentCopy returns the copy of the deployment with the track label added to the selector and the pod template
+func newDeploymentCopy(deployment *appsv1.Deployment, track string, replicas int32) *appsv1.Deployment {
+	c := deployment.DeepCopy()
+
+	c.ObjectMeta = metav1.ObjectMeta{
+		Name:        fmt.Sprintf("%s-%s", deployment.Name, track),
+		Namespace:   deployment.Namespace,
+		Labels:      map[string]string{TrackLabelName: track},
+		Annotations: map[string]string{},
+	}
+
+	for k, v := range deployment.Labels {
+		c.Labels[k] = v
+	}
+
+	for k, v := range deployment.Annotations {
+		switch k {
+		case StrategyAnnoName, CanaryStepsAnnoName, CanaryAnalysisURLAnnoName, BlueGreenServicesAnnoName:
+		default:
+			c.Annotations[k] = v
+		}
+	}
+
+	c.Spec.Replicas = &replicas
+
+	if c.Spec.Selector == nil {
+		c.Spec.Selector = &metav1.LabelSelector{}
+	}
+	if c.Spec.Selector.MatchLabels == nil {
+		c.Spec.Selector.MatchLabels = map[string]string{}
+	}
+	c.Spec.Selector.MatchLabels[TrackLabelName] = track
+
+	if c.Spec.Template.Labels == nil {
+		c.Spec.Template.Labels = map[string]string{}
+	}
+	c.Spec.Template.Labels[TrackLabelName] = track
+
+	return c
+}
+
+func applyDeployment(client kubernetes.Interface, deployment *appsv1.Deployment) error {
+	deployments := client.AppsV1().Deployments(deployment.Namespace)
+
+	existing, err := deployments.Get(deployment.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = deployments.Create(deployment)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	deployment.ResourceVersion = existing.ResourceVersion
+	_, err = deployments.Update(deployment)
+	return err
+}
+
+func deleteDeployment(client kubernetes.Interface, namespace, name string) error {
+	deletePropagation := metav1.DeletePropagationForeground
+	err := client.AppsV1().Deployments(namespace).Delete(name, &metav1.DeleteOptions{PropagationPolicy: &deletePropagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete deployment/%s: %s", name, err)
+	}
+
+	logboek.LogF("deployment/%s deleted\n", name)
+
+	return nil
+}
+
+// trackDeployment waits for the deployment readiness, it is replaced in tests because there are no controllers in the fake clientset
+var trackDeployment = trackDeploymentTillReady
+
+func trackDeploymentTillReady(client kubernetes.Interface, deployment *appsv1.Deployment, opts Options) error {
+	return multitrack.Multitrack(client, multitrack.MultitrackSpecs{
+		Deployments: []multitrack.MultitrackSpec{{ResourceName: deployment.Name, Namespace: deployment.Namespace}},
+	}, multitrack.MultitrackOptions{
+		Options: tracker.Options{
+			Timeout:      opts.Timeout,
+			LogsFromTime: time.Now(),
+		},
+	})
+}
+
+func specReplicas(deployment *appsv1.Deployment) int32 {
+	if deployment.Spec.Replicas != nil {
+		return *deployment.Spec.Replicas
+	}
+	return 1
+}
@@ -0,0 +1,456 @@
+package strategy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flant/werf/pkg/deploy/helm"
+)
+
+const testDeploymentTemplate = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+  labels:
+    app: backend
+  annotations:
+    werf.io/strategy: canary
+    werf.io/canary-steps: 10%:30s, 50%
+    werf.io/canary-analysis-url: http://metrics/analysis
+    werf.io/fail-mode: HopeUntilEndOfDeployProcess
+spec:
+  replicas: 4
+  selector:
+    matchLabels:
+      app: backend
+  template:
+    metadata:
+      labels:
+        app: backend
+    spec:
+      containers:
+      - name: backend
+        image: registry/backend:2
+`
+
+func parseTestTemplate(t *testing.T, data string) helm.Template {
+	var template helm.Template
+	if err := yaml.Unmarshal([]byte(data), &template); err != nil {
+		t.Fatal(err)
+	}
+	return template
+}
+
+func TestParseCanarySteps(t *testing.T) {
+	steps, err := ParseCanarySteps("10%:30s, 50%:2m,100")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []CanaryStep{{ReplicasPercent: 10, Pause: 30 * time.Second}, {ReplicasPercent: 50, Pause: 2 * time.Minute}, {ReplicasPercent: 100}}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected %v, got %v", expected, steps)
+	}
+
+	for _, value := range []string{"", "0%", "150%", "ten%", "10%:soon"} {
+		if _, err := ParseCanarySteps(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestCanaryReplicas(t *testing.T) {
+	tests := []struct {
+		stable   int32
+		percent  int
+		expected int32
+	}{
+		{stable: 10, percent: 10, expected: 1},
+		{stable: 10, percent: 25, expected: 3},
+		{stable: 4, percent: 50, expected: 2},
+		{stable: 1, percent: 1, expected: 1},
+		{stable: 0, percent: 50, expected: 1},
+		{stable: 3, percent: 100, expected: 3},
+	}
+
+	for _, tt := range tests {
+		if replicas := CanaryReplicas(tt.stable, tt.percent); replicas != tt.expected {
+			t.Errorf("CanaryReplicas(%d, %d): expected %d, got %d", tt.stable, tt.percent, tt.expected, replicas)
+		}
+	}
+}
+
+func TestGetDeployments(t *testing.T) {
+	templates := helm.ChartTemplates{
+		parseTestTemplate(t, testDeploymentTemplate),
+		parseTestTemplate(t, "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: worker\n"),
+	}
+
+	deployments, err := GetDeployments("production", templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deployments) != 1 {
+		t.Fatalf("expected 1 deployment, got %d", len(deployments))
+	}
+
+	d := deployments[0]
+	if d.Strategy != CanaryStrategy || d.Deployment.Name != "backend" || d.Deployment.Namespace != "production" || *d.Deployment.Spec.Replicas != 4 {
+		t.Errorf("unexpected deployment %+v", d)
+	}
+
+	if !reflect.DeepEqual(d.CanarySteps, []CanaryStep{{ReplicasPercent: 10, Pause: 30 * time.Second}, {ReplicasPercent: 50}}) {
+		t.Errorf("unexpected canary steps %v", d.CanarySteps)
+	}
+
+	if d.CanaryAnalysisURL != "http://metrics/analysis" {
+		t.Errorf("unexpected canary analysis url %q", d.CanaryAnalysisURL)
+	}
+
+	blueGreen := parseTestTemplate(t, testDeploymentTemplate)
+	blueGreen.Metadata.Annotations = map[string]string{StrategyAnnoName: "blue-green"}
+	if _, err := GetDeployments("production", helm.ChartTemplates{blueGreen}); err == nil {
+		t.Errorf("expected error for blue-green deployment without services")
+	}
+
+	blueGreen.Metadata.Annotations[BlueGreenServicesAnnoName] = "backend, backend-internal"
+	if deployments, err := GetDeployments("production", helm.ChartTemplates{blueGreen}); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(deployments[0].BlueGreenServices, []string{"backend", "backend-internal"}) {
+		t.Errorf("unexpected blue-green services %v", deployments[0].BlueGreenServices)
+	}
+
+	unknown := parseTestTemplate(t, testDeploymentTemplate)
+	unknown.Metadata.Annotations[StrategyAnnoName] = "recreate"
+	if _, err := GetDeployments("production", helm.ChartTemplates{unknown}); err == nil {
+		t.Errorf("expected error for unknown strategy")
+	}
+}
+
+func TestNewDeploymentCopy(t *testing.T) {
+	deployments, err := GetDeployments("production", helm.ChartTemplates{parseTestTemplate(t, testDeploymentTemplate)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := deployments[0].Deployment
+
+	c := newDeploymentCopy(deployment, CanaryTrack, 1)
+
+	if c.Name != "backend-canary" || c.Namespace != "production" || *c.Spec.Replicas != 1 {
+		t.Errorf("unexpected copy %s/%s with %d replicas", c.Namespace, c.Name, *c.Spec.Replicas)
+	}
+
+	expectedLabels := map[string]string{"app": "backend", TrackLabelName: CanaryTrack}
+	if !reflect.DeepEqual(c.Spec.Selector.MatchLabels, expectedLabels) || !reflect.DeepEqual(c.Spec.Template.Labels, expectedLabels) {
+		t.Errorf("unexpected copy selector %v and pod labels %v", c.Spec.Selector.MatchLabels, c.Spec.Template.Labels)
+	}
+
+	if _, hasKey := c.Annotations[StrategyAnnoName]; hasKey {
+		t.Errorf("strategy annotation should not be copied")
+	}
+
+	if c.Annotations["werf.io/fail-mode"] != "HopeUntilEndOfDeployProcess" {
+		t.Errorf("tracking annotations should be copied, got %v", c.Annotations)
+	}
+
+	if _, hasKey := deployment.Spec.Template.Labels[TrackLabelName]; hasKey || *deployment.Spec.Replicas != 4 {
+		t.Errorf("original deployment should not be changed")
+	}
+}
+
+func TestIsDeploymentChanged(t *testing.T) {
+	deployments, err := GetDeployments("production", helm.ChartTemplates{parseTestTemplate(t, testDeploymentTemplate)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := deployments[0]
+
+	if isDeploymentChanged(d, nil) {
+		t.Errorf("deployment without deployed revision should not be changed")
+	}
+
+	deployed := parseTestTemplate(t, testDeploymentTemplate)
+	deployed.OtherFields["spec"].(map[interface{}]interface{})["replicas"] = 2
+	if isDeploymentChanged(d, helm.ChartTemplates{deployed}) {
+		t.Errorf("replicas change should not be rolled out by the strategy")
+	}
+
+	deployed = parseTestTemplate(t, testDeploymentTemplate)
+	deployed.OtherFields["spec"].(map[interface{}]interface{})["template"] = map[interface{}]interface{}{}
+	if !isDeploymentChanged(d, helm.ChartTemplates{deployed}) {
+		t.Errorf("pod template change should be rolled out by the strategy")
+	}
+}
+
+func TestSwitchServiceSelector(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "production"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "backend"}},
+	})
+
+	getSelector := func() map[string]string {
+		service, err := client.CoreV1().Services("production").Get("backend", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return service.Spec.Selector
+	}
+
+	if err := SwitchServiceSelector(client, "production", "backend", GreenTrack); err != nil {
+		t.Fatal(err)
+	}
+
+	if selector := getSelector(); !reflect.DeepEqual(selector, map[string]string{"app": "backend", TrackLabelName: GreenTrack}) {
+		t.Errorf("unexpected switched selector %v", selector)
+	}
+
+	if err := SwitchServiceSelector(client, "production", "backend", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if selector := getSelector(); !reflect.DeepEqual(selector, map[string]string{"app": "backend"}) {
+		t.Errorf("unexpected restored selector %v", selector)
+	}
+
+	if err := SwitchServiceSelector(client, "production", "frontend", GreenTrack); err == nil {
+		t.Errorf("expected error for missing service")
+	}
+}
+
+func TestRun_UnchangedDeployments(t *testing.T) {
+	deployments, err := GetDeployments("production", helm.ChartTemplates{parseTestTemplate(t, testDeploymentTemplate)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := fake.NewSimpleClientset()
+
+	var deployed bool
+	if err := Run(client, deployments, helm.ChartTemplates{parseTestTemplate(t, testDeploymentTemplate)}, Options{}, func() error {
+		deployed = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !deployed {
+		t.Errorf("expected release deploy")
+	}
+
+	if list, err := client.AppsV1().Deployments("production").List(metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	} else if len(list.Items) != 0 {
+		t.Errorf("unexpected strategy deployments %v", list.Items)
+	}
+}
+
+func TestRunAnalysis(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request AnalysisRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch request.Step {
+		case 1:
+			_ = json.NewEncoder(w).Encode(AnalysisResult{Passed: true, Message: "error rate 0.1%"})
+		case 2:
+			_ = json.NewEncoder(w).Encode(AnalysisResult{Passed: false, Message: "error rate 5%"})
+		default:
+			http.Error(w, "no metrics", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	request := AnalysisRequest{Namespace: "production", Deployment: "backend", Canary: "backend-canary", ReplicasPercent: 10}
+
+	request.Step = 1
+	if result, err := RunAnalysis(server.URL, request); err != nil || !result.Passed || result.Message != "error rate 0.1%" {
+		t.Errorf("expected passed analysis, got %+v (err: %v)", result, err)
+	}
+
+	request.Step = 2
+	if result, err := RunAnalysis(server.URL, request); err != nil || result.Passed {
+		t.Errorf("expected failed analysis, got %+v (err: %v)", result, err)
+	}
+
+	request.Step = 3
+	if _, err := RunAnalysis(server.URL, request); err == nil {
+		t.Errorf("expected error for unavailable endpoint")
+	}
+}
+
+func newTestRunDeployment(t *testing.T, strategy StrategyType) (*Deployment, helm.ChartTemplates) {
+	template := parseTestTemplate(t, testDeploymentTemplate)
+	template.Metadata.Annotations[StrategyAnnoName] = string(strategy)
+	template.Metadata.Annotations[BlueGreenServicesAnnoName] = "backend"
+
+	deployments, err := GetDeployments("production", helm.ChartTemplates{template})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployed := parseTestTemplate(t, testDeploymentTemplate)
+	deployed.OtherFields["spec"].(map[interface{}]interface{})["template"] = map[interface{}]interface{}{}
+
+	return deployments[0], helm.ChartTemplates{deployed}
+}
+
+func newTestRunClientset(d *Deployment) *fake.Clientset {
+	current := d.Deployment.DeepCopy()
+	current.Spec.Template.Spec.Containers[0].Image = "registry/backend:1"
+
+	return fake.NewSimpleClientset(current, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "production"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "backend"}},
+	})
+}
+
+func stubTrackDeployment() (*[]string, func()) {
+	var tracked []string
+	trackDeployment = func(_ kubernetes.Interface, deployment *appsv1.Deployment, _ Options) error {
+		tracked = append(tracked, deployment.Name)
+		return nil
+	}
+	return &tracked, func() { trackDeployment = trackDeploymentTillReady }
+}
+
+func assertDeploymentNotFound(t *testing.T, client *fake.Clientset, name string) {
+	if _, err := client.AppsV1().Deployments("production").Get(name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected deployment/%s to be deleted, got %v", name, err)
+	}
+}
+
+func TestRun_CanaryAnalysisAbort(t *testing.T) {
+	d, deployedTemplates := newTestRunDeployment(t, CanaryStrategy)
+	client := newTestRunClientset(d)
+
+	tracked, restore := stubTrackDeployment()
+	defer restore()
+
+	var analysisRequests []AnalysisRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request AnalysisRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		analysisRequests = append(analysisRequests, request)
+
+		if canary, err := client.AppsV1().Deployments("production").Get(request.Canary, metav1.GetOptions{}); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if *canary.Spec.Replicas != 1 {
+			http.Error(w, "unexpected canary replicas", http.StatusBadRequest)
+		} else {
+			_ = json.NewEncoder(w).Encode(AnalysisResult{Passed: false, Message: "error rate 5%"})
+		}
+	}))
+	defer server.Close()
+
+	d.CanarySteps = []CanaryStep{{ReplicasPercent: 10}, {ReplicasPercent: 50}}
+	d.CanaryAnalysisURL = server.URL
+
+	var deployed bool
+	err := Run(client, []*Deployment{d}, deployedTemplates, Options{}, func() error {
+		deployed = true
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "canary analysis has not passed: error rate 5%") {
+		t.Fatalf("expected canary analysis error, got %v", err)
+	}
+
+	if deployed {
+		t.Errorf("release should not be deployed after the aborted strategy")
+	}
+
+	if expected := []string{"backend-canary"}; !reflect.DeepEqual(*tracked, expected) {
+		t.Errorf("expected tracked deployments %v, got %v", expected, *tracked)
+	}
+
+	if expected := []AnalysisRequest{{Namespace: "production", Deployment: "backend", Canary: "backend-canary", Step: 1, ReplicasPercent: 10}}; !reflect.DeepEqual(analysisRequests, expected) {
+		t.Errorf("expected analysis requests %+v, got %+v", expected, analysisRequests)
+	}
+
+	assertDeploymentNotFound(t, client, "backend-canary")
+
+	if current, err := client.AppsV1().Deployments("production").Get("backend", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	} else if image := current.Spec.Template.Spec.Containers[0].Image; image != "registry/backend:1" {
+		t.Errorf("current deployment should not be changed, got image %s", image)
+	}
+}
+
+func TestRun_BlueGreen(t *testing.T) {
+	getSelector := func(client *fake.Clientset) map[string]string {
+		service, err := client.CoreV1().Services("production").Get("backend", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return service.Spec.Selector
+	}
+
+	greenSelector := map[string]string{"app": "backend", TrackLabelName: GreenTrack}
+	originalSelector := map[string]string{"app": "backend"}
+
+	t.Run("deploy", func(t *testing.T) {
+		d, deployedTemplates := newTestRunDeployment(t, BlueGreenStrategy)
+		client := newTestRunClientset(d)
+
+		_, restore := stubTrackDeployment()
+		defer restore()
+
+		if err := Run(client, []*Deployment{d}, deployedTemplates, Options{}, func() error {
+			// the release deploy updates the original deployment while the traffic goes to the green copy
+			if selector := getSelector(client); !reflect.DeepEqual(selector, greenSelector) {
+				t.Errorf("expected service switched to the green copy during the release deploy, got %v", selector)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if selector := getSelector(client); !reflect.DeepEqual(selector, originalSelector) {
+			t.Errorf("expected service switched back after the release deploy, got %v", selector)
+		}
+
+		assertDeploymentNotFound(t, client, "backend-green")
+	})
+
+	t.Run("release deploy failure", func(t *testing.T) {
+		d, deployedTemplates := newTestRunDeployment(t, BlueGreenStrategy)
+		client := newTestRunClientset(d)
+
+		_, restore := stubTrackDeployment()
+		defer restore()
+
+		err := Run(client, []*Deployment{d}, deployedTemplates, Options{}, func() error {
+			return errors.New("upgrade failed")
+		})
+		if err == nil || err.Error() != "upgrade failed" {
+			t.Fatalf("expected release deploy error, got %v", err)
+		}
+
+		if selector := getSelector(client); !reflect.DeepEqual(selector, originalSelector) {
+			t.Errorf("expected service switched back on abort, got %v", selector)
+		}
+
+		assertDeploymentNotFound(t, client, "backend-green")
+	})
+}
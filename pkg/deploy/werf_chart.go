@@ -2,12 +2,14 @@ package deploy
 
 import (
 	"github.com/flant/logboek"
+	"k8s.io/helm/pkg/strvals"
 
 	"github.com/flant/werf/pkg/deploy/secret"
 	"github.com/flant/werf/pkg/deploy/werf_chart"
+	"github.com/flant/werf/pkg/util/secretvalues"
 )
 
-func PrepareWerfChart(projectName, helmChartDir, env string, m secret.Manager, secretValues []string, serviceValues map[string]interface{}) (*werf_chart.WerfChart, error) {
+func PrepareWerfChart(projectName, helmChartDir, env string, m secret.Manager, secretValues, secretSet, setFileSecret []string, serviceValues map[string]interface{}) (*werf_chart.WerfChart, error) {
 	werfChart, err := werf_chart.InitWerfChart(projectName, helmChartDir, env, m)
 	if err != nil {
 		return nil, err
@@ -19,6 +21,14 @@ func PrepareWerfChart(projectName, helmChartDir, env string, m secret.Manager, s
 		}
 	}
 
+	if err = werfChart.SetSecretSet(secretSet); err != nil {
+		return nil, err
+	}
+
+	if err = werfChart.SetSecretSetFile(setFileSecret); err != nil {
+		return nil, err
+	}
+
 	if serviceValues != nil {
 		if err = werfChart.SetServiceValues(serviceValues); err != nil {
 			return nil, err
@@ -29,3 +39,25 @@ func PrepareWerfChart(projectName, helmChartDir, env string, m secret.Manager, s
 
 	return werfChart, nil
 }
+
+// registerSetSecretValues registers --secret-set values and values of the --set and --set-string options with sensitive keys
+// before any options are logged. Parse errors are reported later by the chart preparation and helm.
+func registerSetSecretValues(set, setString, secretSet []string) {
+	for _, value := range secretSet {
+		if values, err := strvals.ParseString(value); err == nil {
+			secretvalues.RegisterSecretValues(secretvalues.ExtractSecretValuesFromMap(values)...)
+		}
+	}
+
+	for _, value := range set {
+		if values, err := strvals.Parse(value); err == nil {
+			secretvalues.RegisterSensitiveValuesFromMap(values)
+		}
+	}
+
+	for _, value := range setString {
+		if values, err := strvals.ParseString(value); err == nil {
+			secretvalues.RegisterSensitiveValuesFromMap(values)
+		}
+	}
+}
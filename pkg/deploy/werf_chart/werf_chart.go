@@ -9,6 +9,7 @@ import (
 	"unicode"
 
 	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/strvals"
 
 	"github.com/flant/logboek"
 	"github.com/flant/werf/pkg/deploy/helm"
@@ -63,11 +64,57 @@ func (chart *WerfChart) SetSecretValuesFile(path string, m secret.Manager) error
 		return fmt.Errorf("cannot unmarshal secret values file %s: %s", path, err)
 	}
 	chart.SecretValues = append(chart.SecretValues, values)
-	chart.SecretValuesToMask = secretvalues.ExtractSecretValuesFromMap(values)
+	chart.addSecretValuesToMask(secretvalues.ExtractSecretValuesFromMap(values)...)
 
 	return nil
 }
 
+// SetSecretSet sets values specified in the --set-string format and marks them as secret
+func (chart *WerfChart) SetSecretSet(secretSet []string) error {
+	for _, value := range secretSet {
+		values := map[string]interface{}{}
+		if err := strvals.ParseIntoString(value, values); err != nil {
+			return fmt.Errorf("failed parsing --secret-set data: %s", err)
+		}
+
+		chart.SecretValues = append(chart.SecretValues, values)
+		chart.addSecretValuesToMask(secretvalues.ExtractSecretValuesFromMap(values)...)
+	}
+
+	return nil
+}
+
+// SetSecretSetFile sets values from the files specified in the KEY=PATH format and marks them as secret
+func (chart *WerfChart) SetSecretSetFile(setFileSecret []string) error {
+	for _, value := range setFileSecret {
+		values := map[string]interface{}{}
+		if err := strvals.ParseIntoFile(value, values, func(rs []rune) (interface{}, error) {
+			data, err := ioutil.ReadFile(string(rs))
+			return string(data), err
+		}); err != nil {
+			return fmt.Errorf("failed parsing --set-file-secret data: %s", err)
+		}
+
+		chart.SecretValues = append(chart.SecretValues, values)
+		chart.addSecretValuesToMask(secretvalues.ExtractSecretValuesFromMap(values)...)
+	}
+
+	return nil
+}
+
+// addSecretValuesToMask masks secret values in the deploy output, booleans, numbers and paths are skipped to keep the output readable
+func (chart *WerfChart) addSecretValuesToMask(values ...string) {
+	var maskableValues []string
+	for _, value := range values {
+		if secretvalues.IsMaskableValue(value) {
+			maskableValues = append(maskableValues, value)
+		}
+	}
+
+	chart.SecretValuesToMask = append(chart.SecretValuesToMask, maskableValues...)
+	secretvalues.RegisterSecretValues(maskableValues...)
+}
+
 func (chart *WerfChart) Deploy(releaseName string, namespace string, opts helm.ChartOptions) error {
 	opts.SecretValues = append(chart.SecretValues, opts.SecretValues...)
 	opts.Set = append(chart.Set, opts.Set...)
@@ -77,6 +124,18 @@ func (chart *WerfChart) Deploy(releaseName string, namespace string, opts helm.C
 	return helm.DeployHelmChart(chart.ChartDir, releaseName, namespace, opts)
 }
 
+func (chart *WerfChart) GetTemplates(releaseName, namespace string, opts helm.ChartValuesOptions) (helm.ChartTemplates, error) {
+	return helm.GetTemplatesFromChart(
+		chart.ChartDir,
+		releaseName,
+		namespace,
+		append(chart.Values, opts.Values...),
+		append(chart.SecretValues, opts.SecretValues...),
+		append(chart.Set, opts.Set...),
+		append(chart.SetString, opts.SetString...),
+	)
+}
+
 func (chart *WerfChart) MergeExtraAnnotations(extraAnnotations map[string]string) {
 	for annoName, annoValue := range extraAnnotations {
 		chart.ExtraAnnotations[annoName] = annoValue
@@ -178,7 +237,7 @@ func InitWerfChart(projectName, chartDir string, env string, m secret.Manager) (
 			}
 
 			werfChart.DecodedSecretFilesData[filepath.ToSlash(relativePath)] = string(decodedData)
-			werfChart.SecretValuesToMask = append(werfChart.SecretValuesToMask, string(decodedData))
+			werfChart.addSecretValuesToMask(string(decodedData))
 
 			return nil
 		}); err != nil {
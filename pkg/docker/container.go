@@ -1,6 +1,8 @@
 package docker
 
 import (
+	"io"
+
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/cli/cli/command/container"
 	"github.com/docker/docker/api/types"
@@ -48,6 +50,17 @@ func ContainerRemove(ref string, options types.ContainerRemoveOptions) error {
 	return nil
 }
 
+func ContainerStatPath(ref, path string) (types.ContainerPathStat, error) {
+	ctx := context.Background()
+	return apiClient.ContainerStatPath(ctx, ref, path)
+}
+
+// CopyFromContainer returns tar archive of the path, the archive should be closed by the caller
+func CopyFromContainer(ref, srcPath string) (io.ReadCloser, types.ContainerPathStat, error) {
+	ctx := context.Background()
+	return apiClient.CopyFromContainer(ctx, ref, srcPath)
+}
+
 func doCliCreate(c *command.DockerCli, args ...string) error {
 	return prepareCliCmd(container.NewCreateCommand(c), args...).Execute()
 }
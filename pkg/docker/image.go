@@ -1,7 +1,9 @@
 package docker
 
 import (
+	"io"
 	"math/rand"
+	"os"
 	"strings"
 	"time"
 
@@ -50,6 +52,31 @@ func ImageInspect(ref string) (*types.ImageInspect, error) {
 	return &inspect, nil
 }
 
+func ImageSave(refs ...string) (io.ReadCloser, error) {
+	ctx := context.Background()
+	return apiClient.ImageSave(ctx, refs)
+}
+
+func ImageSaveToFile(path string, refs ...string) error {
+	reader, err := ImageSave(refs...)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, reader); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
 func doCliPull(c *command.DockerCli, args ...string) error {
 	return prepareCliCmd(image.NewPullCommand(c), args...).Execute()
 }
@@ -7,10 +7,14 @@ import (
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/cli/cli/command/registry"
 	"github.com/docker/cli/cli/flags"
-	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/logging"
+	"github.com/flant/werf/pkg/util/secretvalues"
 )
 
 func Login(username, password, repo string) error {
+	secretvalues.RegisterSecretValues(password)
+
 	var outb, errb bytes.Buffer
 
 	cliOpts := []command.DockerCliOption{
@@ -37,7 +41,7 @@ func Login(username, password, repo string) error {
 
 	err = cmd.Execute()
 	if Debug() {
-		fmt.Fprintf(logboek.GetOutStream(), "Docker login stdout:\n%s\nDocker login stderr:\n%s\n", outb.String(), errb.String())
+		fmt.Fprintf(logging.GetOutStream(), "Docker login stdout:\n%s\nDocker login stderr:\n%s\n", outb.String(), errb.String())
 	}
 
 	if err != nil {
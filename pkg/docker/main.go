@@ -19,6 +19,8 @@ import (
 	"github.com/docker/cli/cli/flags"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+
+	"github.com/flant/werf/pkg/logging"
 )
 
 var (
@@ -47,7 +49,7 @@ func Init(dockerConfigDir string, verbose, debug bool) error {
 		return err
 	}
 
-	logrus.StandardLogger().SetOutput(logboek.GetOutStream())
+	logrus.StandardLogger().SetOutput(logging.GetOutStream())
 
 	isDebug = debug
 	isVerbose = verbose
@@ -87,8 +89,8 @@ func newDockerCli(opts []command.DockerCliOption) (*command.DockerCli, error) {
 
 func setDockerClient() error {
 	if c, err := newDockerCli([]command.DockerCliOption{
-		command.WithOutputStream(logboek.GetOutStream()),
-		command.WithErrorStream(logboek.GetErrStream()),
+		command.WithOutputStream(logging.GetOutStream()),
+		command.WithErrorStream(logging.GetErrStream()),
 		command.WithContentTrust(false),
 	}); err != nil {
 		return fmt.Errorf("unable to create live output docker cli: %s", err)
@@ -0,0 +1,205 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/flant/werf/pkg/image"
+)
+
+const AlibabaCrImplementationName = "alibaba-acr"
+
+type AlibabaCrNotFoundError apiError
+
+var (
+	alibabaCrPatternRegexp = regexp.MustCompile(`^registry(-intl)?(-vpc)?\.([a-z0-9-]+)\.aliyuncs\.com$`)
+	alibabaCrPatterns      = []string{alibabaCrPatternRegexp.String()}
+)
+
+// alibabaCr supports the personal edition instances: REGISTRY/NAMESPACE/REPOSITORY
+type alibabaCr struct {
+	*defaultImplementation
+	alibabaCrApi
+	alibabaCrCredentials
+}
+
+type alibabaCrOptions struct {
+	defaultImplementationOptions
+	alibabaCrCredentials
+}
+
+type alibabaCrCredentials struct {
+	accessKeyId     string
+	accessKeySecret string
+}
+
+func newAlibabaCr(options alibabaCrOptions) (*alibabaCr, error) {
+	d, err := newDefaultImplementation(options.defaultImplementationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	alibabaCr := &alibabaCr{
+		defaultImplementation: d,
+		alibabaCrApi:          newAlibabaCrApi(),
+		alibabaCrCredentials:  options.alibabaCrCredentials,
+	}
+
+	return alibabaCr, nil
+}
+
+func (r *alibabaCr) ResolveRepoMode(registryOrRepositoryAddress, repoMode string) (string, error) {
+	_, _, repository, err := r.parseReference(registryOrRepositoryAddress)
+	if err != nil {
+		return "", err
+	}
+
+	switch repoMode {
+	case MonorepoRepoMode:
+		if repository != "" {
+			return MonorepoRepoMode, nil
+		}
+
+		return "", fmt.Errorf("docker registry implementation %[1]s and repo mode %[2]s cannot be used with %[4]s (add repository to address or use %[3]s repo mode)", r.String(), MonorepoRepoMode, MultirepoRepoMode, registryOrRepositoryAddress)
+	case MultirepoRepoMode:
+		if repository == "" {
+			return MultirepoRepoMode, nil
+		}
+
+		return "", fmt.Errorf("docker registry implementation %[1]s and repo mode %[3]s cannot be used with %[4]s (exclude repository from address or use %[2]s repo mode)", r.String(), MonorepoRepoMode, MultirepoRepoMode, registryOrRepositoryAddress)
+	case "auto", "":
+		if repository == "" {
+			return MultirepoRepoMode, nil
+		} else {
+			return MonorepoRepoMode, nil
+		}
+	default:
+		return "", fmt.Errorf("docker registry implementation %s does not support repo mode %s", r.String(), repoMode)
+	}
+}
+
+func (r *alibabaCr) Tags(reference string) ([]string, error) {
+	region, namespace, repository, err := r.parseRepositoryReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, resp, err := r.alibabaCrApi.getRepoTags(region, namespace, repository, r.alibabaCrCredentials)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	return tags, err
+}
+
+func (r *alibabaCr) SelectRepoImageList(reference string, f func(string, *image.Info, error) (bool, error)) ([]*image.Info, error) {
+	tags, err := r.Tags(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.defaultImplementation.selectRepoImageListByTags(reference, tags, f)
+}
+
+func (r *alibabaCr) GetRepoImageList(reference string) ([]*image.Info, error) {
+	return r.SelectRepoImageList(reference, nil)
+}
+
+func (r *alibabaCr) CreateRepo(reference string) error {
+	region, namespace, repository, err := r.parseRepositoryReference(reference)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.alibabaCrApi.createRepo(region, namespace, repository, r.alibabaCrCredentials)
+	return err
+}
+
+func (r *alibabaCr) DeleteRepo(reference string) error {
+	region, namespace, repository, err := r.parseRepositoryReference(reference)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.alibabaCrApi.deleteRepo(region, namespace, repository, r.alibabaCrCredentials)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return AlibabaCrNotFoundError{error: err}
+	}
+
+	return err
+}
+
+func (r *alibabaCr) DeleteRepoImage(repoImageList ...*image.Info) error {
+	for _, repoImage := range repoImageList {
+		if err := r.deleteRepoImage(repoImage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *alibabaCr) deleteRepoImage(repoImage *image.Info) error {
+	region, namespace, repository, err := r.parseRepositoryReference(repoImage.Repository)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.alibabaCrApi.deleteRepoTag(region, namespace, repository, repoImage.Tag, r.alibabaCrCredentials)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return AlibabaCrNotFoundError{error: err}
+	}
+
+	return err
+}
+
+func (r *alibabaCr) String() string {
+	return AlibabaCrImplementationName
+}
+
+func (r *alibabaCr) parseRepositoryReference(reference string) (string, string, string, error) {
+	region, namespace, repository, err := r.parseReference(reference)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if repository == "" {
+		return "", "", "", fmt.Errorf("unexpected reference %s: repository expected after namespace", reference)
+	}
+
+	if r.alibabaCrCredentials.accessKeyId == "" || r.alibabaCrCredentials.accessKeySecret == "" {
+		return "", "", "", fmt.Errorf("access key id and access key secret required for %s docker registry implementation", r.String())
+	}
+
+	return region, namespace, repository, nil
+}
+
+func (r *alibabaCr) parseReference(reference string) (string, string, string, error) {
+	parsedReference, err := name.NewRepository(reference)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	match := alibabaCrPatternRegexp.FindStringSubmatch(parsedReference.RegistryStr())
+	if len(match) == 0 {
+		return "", "", "", fmt.Errorf("reference %s is not compatible with %s docker registry implementation", reference, r.String())
+	}
+	region := match[3]
+
+	var namespace, repository string
+	parts := strings.Split(parsedReference.RepositoryStr(), "/")
+	switch len(parts) {
+	case 1:
+		namespace = parts[0]
+	case 2:
+		namespace, repository = parts[0], parts[1]
+	default:
+		return "", "", "", fmt.Errorf("unexpected reference %s", reference)
+	}
+
+	return region, namespace, repository, nil
+}
@@ -0,0 +1,182 @@
+package docker_registry
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const alibabaCrApiVersion = "2016-06-07"
+
+type alibabaCrApi struct {
+	apiUrl func(region string) string
+}
+
+func newAlibabaCrApi() alibabaCrApi {
+	return alibabaCrApi{
+		apiUrl: func(region string) string {
+			return fmt.Sprintf("https://cr.%s.aliyuncs.com", region)
+		},
+	}
+}
+
+func (api *alibabaCrApi) getRepoTags(region, namespace, repository string, credentials alibabaCrCredentials) ([]string, *http.Response, error) {
+	var tags []string
+
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("Page", strconv.Itoa(page))
+		query.Set("PageSize", "100")
+
+		resp, respBody, err := api.doRequest(http.MethodGet, region, fmt.Sprintf("/repos/%s/%s/tags", namespace, repository), query, nil, credentials, http.StatusOK)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		respJson := &struct {
+			Data struct {
+				Tags []struct {
+					Tag string `json:"tag"`
+				} `json:"tags"`
+				Total    int `json:"total"`
+				PageSize int `json:"pageSize"`
+			} `json:"data"`
+		}{}
+
+		if err := json.Unmarshal(respBody, respJson); err != nil {
+			return nil, resp, fmt.Errorf("unexpected body %s", string(respBody))
+		}
+
+		for _, tag := range respJson.Data.Tags {
+			tags = append(tags, tag.Tag)
+		}
+
+		if len(respJson.Data.Tags) == 0 || len(tags) >= respJson.Data.Total {
+			return tags, resp, nil
+		}
+	}
+}
+
+func (api *alibabaCrApi) deleteRepoTag(region, namespace, repository, tag string, credentials alibabaCrCredentials) (*http.Response, error) {
+	resp, _, err := api.doRequest(http.MethodDelete, region, fmt.Sprintf("/repos/%s/%s/tags/%s", namespace, repository, tag), nil, nil, credentials, http.StatusOK)
+	return resp, err
+}
+
+func (api *alibabaCrApi) createRepo(region, namespace, repository string, credentials alibabaCrCredentials) (*http.Response, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"repo": map[string]string{
+			"RepoNamespace": namespace,
+			"RepoName":      repository,
+			"RepoType":      "PRIVATE",
+			"Summary":       repository,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, _, err := api.doRequest(http.MethodPut, region, "/repos", nil, body, credentials, http.StatusOK)
+	return resp, err
+}
+
+func (api *alibabaCrApi) deleteRepo(region, namespace, repository string, credentials alibabaCrCredentials) (*http.Response, error) {
+	resp, _, err := api.doRequest(http.MethodDelete, region, fmt.Sprintf("/repos/%s/%s", namespace, repository), nil, nil, credentials, http.StatusOK)
+	return resp, err
+}
+
+// doRequest performs the request signed with the access key (ROA signature version 1.0)
+func (api *alibabaCrApi) doRequest(method, region, resourcePath string, query url.Values, body []byte, credentials alibabaCrCredentials, acceptedCodes ...int) (*http.Response, []byte, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	headers := map[string]string{
+		"Accept":                  "application/json",
+		"Date":                    time.Now().UTC().Format(http.TimeFormat),
+		"x-acs-signature-method":  "HMAC-SHA1",
+		"x-acs-signature-nonce":   hex.EncodeToString(nonce),
+		"x-acs-signature-version": "1.0",
+		"x-acs-version":           alibabaCrApiVersion,
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		md5Sum := md5.Sum(body)
+		headers["Content-MD5"] = base64.StdEncoding.EncodeToString(md5Sum[:])
+		headers["Content-Type"] = "application/json;charset=utf-8"
+		reqBody = bytes.NewBuffer(body)
+	}
+
+	headers["Authorization"] = fmt.Sprintf("acs %s:%s", credentials.accessKeyId, alibabaCrSignature(method, resourcePath, query, headers, credentials.accessKeySecret))
+
+	reqUrl := api.apiUrl(region) + resourcePath
+	if len(query) != 0 {
+		reqUrl += "?" + query.Encode()
+	}
+
+	return doRequest(method, reqUrl, reqBody, doRequestOptions{
+		Headers:       headers,
+		AcceptedCodes: acceptedCodes,
+	})
+}
+
+func alibabaCrSignature(method, resourcePath string, query url.Values, headers map[string]string, accessKeySecret string) string {
+	var acsHeaders []string
+	for key, value := range headers {
+		if strings.HasPrefix(strings.ToLower(key), "x-acs-") {
+			acsHeaders = append(acsHeaders, fmt.Sprintf("%s:%s", strings.ToLower(key), value))
+		}
+	}
+	sort.Strings(acsHeaders)
+
+	resource := resourcePath
+	if len(query) != 0 {
+		var keys []string
+		for key := range query {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var params []string
+		for _, key := range keys {
+			params = append(params, fmt.Sprintf("%s=%s", key, query.Get(key)))
+		}
+		resource += "?" + strings.Join(params, "&")
+	}
+
+	header := func(key string) string {
+		for k, v := range headers {
+			if strings.EqualFold(k, key) {
+				return v
+			}
+		}
+		return ""
+	}
+
+	stringToSign := strings.Join([]string{
+		method,
+		header("Accept"),
+		header("Content-MD5"),
+		header("Content-Type"),
+		header("Date"),
+	}, "\n") + "\n" + strings.Join(acsHeaders, "\n") + "\n" + resource
+
+	mac := hmac.New(sha1.New, []byte(accessKeySecret))
+	mac.Write([]byte(stringToSign))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,89 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/werf/pkg/image"
+)
+
+var _ = Describe("alibaba cloud container registry", func() {
+	var server *httptest.Server
+	var registry *alibabaCr
+	var requests []string
+
+	BeforeEach(func() {
+		requests = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+
+			headers := map[string]string{}
+			for key := range req.Header {
+				headers[key] = req.Header.Get(key)
+			}
+			expectedSignature := alibabaCrSignature(req.Method, req.URL.Path, req.URL.Query(), headers, "secret")
+			Ω(req.Header.Get("Authorization")).Should(Equal("acs id:" + expectedSignature))
+			Ω(req.Header.Get("X-Acs-Version")).Should(Equal(alibabaCrApiVersion))
+
+			requests = append(requests, fmt.Sprintf("%s %s", req.Method, req.URL.Path))
+
+			switch {
+			case req.Method == http.MethodGet && req.URL.Query().Get("Page") == "1":
+				fmt.Fprintf(w, `{"data":{"tags":[{"tag":"v1"},{"tag":"v2"}],"total":3,"page":1,"pageSize":2}}`)
+			case req.Method == http.MethodGet:
+				fmt.Fprintf(w, `{"data":{"tags":[{"tag":"v3"}],"total":3,"page":2,"pageSize":2}}`)
+			case strings.HasSuffix(req.URL.Path, "/missing"):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				fmt.Fprint(w, `{"data":{}}`)
+			}
+		}))
+
+		var err error
+		registry, err = newAlibabaCr(alibabaCrOptions{alibabaCrCredentials: alibabaCrCredentials{accessKeyId: "id", accessKeySecret: "secret"}})
+		Ω(err).ShouldNot(HaveOccurred())
+		registry.alibabaCrApi.apiUrl = func(region string) string {
+			Ω(region).Should(Equal("cn-hangzhou"))
+			return server.URL
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists tags with all pages", func() {
+		tags, err := registry.Tags("registry.cn-hangzhou.aliyuncs.com/namespace/app")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"v1", "v2", "v3"}))
+	})
+
+	It("creates the repository, deletes the tag and the repository with signed requests", func() {
+		Ω(registry.CreateRepo("registry-vpc.cn-hangzhou.aliyuncs.com/namespace/app")).Should(Succeed())
+		Ω(registry.DeleteRepoImage(&image.Info{Repository: "registry.cn-hangzhou.aliyuncs.com/namespace/app", Tag: "v1"})).Should(Succeed())
+		Ω(registry.DeleteRepo("registry.cn-hangzhou.aliyuncs.com/namespace/app")).Should(Succeed())
+
+		Ω(requests).Should(Equal([]string{
+			"PUT /repos",
+			"DELETE /repos/namespace/app/tags/v1",
+			"DELETE /repos/namespace/app",
+		}))
+	})
+
+	It("returns not found error if the repository does not exist", func() {
+		err := registry.DeleteRepo("registry.cn-hangzhou.aliyuncs.com/namespace/missing")
+		Ω(err).Should(BeAssignableToTypeOf(AlibabaCrNotFoundError{}))
+	})
+
+	It("requires the access key", func() {
+		registry.alibabaCrCredentials = alibabaCrCredentials{}
+		_, err := registry.Tags("registry.cn-hangzhou.aliyuncs.com/namespace/app")
+		Ω(err).Should(HaveOccurred())
+	})
+})
@@ -4,12 +4,18 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 
 	"github.com/flant/werf/pkg/image"
 )
@@ -17,6 +23,10 @@ import (
 type api struct {
 	InsecureRegistry      bool
 	SkipTlsVerifyRegistry bool
+
+	// authorized transports are reused by the concurrent manifest requests to the same repository
+	pullTransports    map[string]http.RoundTripper
+	pullTransportsMux sync.Mutex
 }
 
 type apiOptions struct {
@@ -28,6 +38,7 @@ func newAPI(options apiOptions) *api {
 	return &api{
 		InsecureRegistry:      options.InsecureRegistry,
 		SkipTlsVerifyRegistry: options.SkipTlsVerifyRegistry,
+		pullTransports:        map[string]http.RoundTripper{},
 	}
 }
 
@@ -63,7 +74,13 @@ func (api *api) TryGetRepoImage(reference string) (*image.Info, error) {
 }
 
 func (api *api) GetRepoImage(reference string) (*image.Info, error) {
-	imageInfo, _, err := api.image(reference)
+	return api.GetRepoImageByPlatform(reference, "")
+}
+
+// GetRepoImageByPlatform returns the image of the platform from the manifest list or checks the platform of the image,
+// the image of the host platform is selected from the manifest list if the platform is not specified
+func (api *api) GetRepoImageByPlatform(reference, platform string) (*image.Info, error) {
+	imageInfo, ref, err := api.image(reference, platform)
 	if err != nil {
 		return nil, err
 	}
@@ -83,19 +100,36 @@ func (api *api) GetRepoImage(reference string) (*image.Info, error) {
 		return nil, err
 	}
 
-	parsedReference, err := name.NewTag(reference, api.parseReferenceOptions()...)
-	if err != nil {
-		return nil, err
+	if platform != "" {
+		// variant is not checked: it is not available in the image config
+		if os, arch, _, err := image.ParsePlatform(platform); err != nil {
+			return nil, err
+		} else if configFile.OS != os || configFile.Architecture != arch {
+			return nil, fmt.Errorf("image %q is built for platform %s/%s, platform %s required", reference, configFile.OS, configFile.Architecture, platform)
+		}
+	}
+
+	// reference pinned by digest has no tag
+	var tag string
+	if parsedTag, ok := ref.(name.Tag); ok {
+		tag = parsedTag.TagStr()
+	}
+
+	// compressed size of all image layers including the layers of the parent
+	var size int64
+	for _, layer := range manifest.Layers {
+		size += layer.Size
 	}
 
 	repoImage := &image.Info{
 		Name:       reference,
-		Repository: strings.Join([]string{parsedReference.RegistryStr(), parsedReference.RepositoryStr()}, "/"),
+		Repository: strings.Join([]string{ref.Context().RegistryStr(), ref.Context().RepositoryStr()}, "/"),
 		ID:         manifest.Config.Digest.String(),
-		Tag:        parsedReference.TagStr(),
+		Tag:        tag,
 		RepoDigest: digest.String(),
 		ParentID:   configFile.Config.Image,
 		Labels:     configFile.Config.Labels,
+		Size:       size,
 	}
 
 	repoImage.SetCreatedAtUnix(configFile.Created.Unix())
@@ -103,6 +137,165 @@ func (api *api) GetRepoImage(reference string) (*image.Info, error) {
 	return repoImage, nil
 }
 
+// getRepoImageWithCache returns the image info from the manifest cache if the manifest digest of the reference has not been changed,
+// the manifest digest is requested with HEAD request which is cheaper than fetching the manifest and the config
+func (api *api) getRepoImageWithCache(reference string) (*image.Info, error) {
+	if image.CommonManifestCache == nil {
+		return api.GetRepoImage(reference)
+	}
+
+	manifestDigest, err := api.manifestDigest(reference)
+	if err != nil {
+		// the error of the image request is more informative (MANIFEST_UNKNOWN, NAME_UNKNOWN)
+		return api.GetRepoImage(reference)
+	}
+
+	if imgInfo, err := image.CommonManifestCache.GetImageInfoByManifestDigest(reference, manifestDigest); err != nil {
+		return nil, fmt.Errorf("error getting image %s info from manifest cache: %s", reference, err)
+	} else if imgInfo != nil {
+		return imgInfo, nil
+	}
+
+	imgInfo, err := api.GetRepoImage(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := image.CommonManifestCache.StoreImageInfoWithManifestDigest(imgInfo, manifestDigest); err != nil {
+		return nil, fmt.Errorf("error storing image %s info into manifest cache: %s", reference, err)
+	}
+
+	return imgInfo, nil
+}
+
+func (api *api) manifestDigest(reference string) (string, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return "", fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	tr, err := api.pullTransport(ref.Context())
+	if err != nil {
+		return "", err
+	}
+
+	u := url.URL{
+		Scheme: ref.Context().Registry.Scheme(),
+		Host:   ref.Context().RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/manifests/%s", ref.Context().RepositoryStr(), ref.Identifier()),
+	}
+
+	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", strings.Join([]string{
+		string(types.DockerManifestSchema2),
+		string(types.DockerManifestList),
+		string(types.OCIManifestSchema1),
+		string(types.OCIImageIndex),
+	}, ","))
+
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return "", err
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("no manifest digest in the registry response for %q", reference)
+	}
+
+	return digest, nil
+}
+
+func (api *api) pullTransport(repo name.Repository) (http.RoundTripper, error) {
+	api.pullTransportsMux.Lock()
+	defer api.pullTransportsMux.Unlock()
+
+	if tr, ok := api.pullTransports[repo.String()]; ok {
+		return tr, nil
+	}
+
+	auth, err := authn.DefaultKeychain.Resolve(repo.Registry)
+	if err != nil {
+		return nil, fmt.Errorf("getting creds for %q: %v", repo, err)
+	}
+
+	tr, err := transport.New(repo.Registry, auth, api.getHttpTransport(), []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, err
+	}
+	api.pullTransports[repo.String()] = tr
+
+	return tr, nil
+}
+
+type ManifestListEntry struct {
+	Platform   string
+	RepoDigest string
+}
+
+// PutImageByDigest pushes the image into the repository without a tag and returns the digest of the image manifest
+func (api *api) PutImageByDigest(repository string, img v1.Image) (string, error) {
+	repo, err := name.NewRepository(repository, api.newRepositoryOptions()...)
+	if err != nil {
+		return "", fmt.Errorf("parsing repo %q: %v", repository, err)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return "", fmt.Errorf("getting image digest: %v", err)
+	}
+
+	ref := repo.Digest(digest.String())
+	if err := remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport())); err != nil {
+		return "", fmt.Errorf("writing image %q: %v", ref, err)
+	}
+
+	return digest.String(), nil
+}
+
+// PutManifestList creates the manifest list by the reference from the images of the same repository pinned by digests
+func (api *api) PutManifestList(reference string, entries []ManifestListEntry) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", reference, err)
+	}
+
+	var addenda []mutate.IndexAddendum
+	for _, entry := range entries {
+		os, arch, variant, err := image.ParsePlatform(entry.Platform)
+		if err != nil {
+			return err
+		}
+
+		img, _, err := api.image(ref.Context().Digest(entry.RepoDigest).String(), "")
+		if err != nil {
+			return err
+		}
+
+		addenda = append(addenda, mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: os, Architecture: arch, Variant: variant},
+			},
+		})
+	}
+
+	index := mutate.IndexMediaType(mutate.AppendManifests(empty.Index, addenda...), types.DockerManifestList)
+	if err := remote.WriteIndex(ref, index, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport())); err != nil {
+		return fmt.Errorf("writing manifest list %q: %v", ref, err)
+	}
+
+	return nil
+}
+
 func (api *api) list(reference string) ([]string, error) {
 	repo, err := name.NewRepository(reference, api.newRepositoryOptions()...)
 	if err != nil {
@@ -130,27 +323,84 @@ func (api *api) deleteImageByReference(reference string) error {
 	return nil
 }
 
-func (api *api) image(reference string) (v1.Image, name.Reference, error) {
+func (api *api) image(reference, platform string) (v1.Image, name.Reference, error) {
 	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("parsing reference %q: %v", reference, err)
 	}
 
-	// FIXME: Hack for the go-containerregistry library,
-	// FIXME: that uses default transport without options to change transport to custom.
-	// FIXME: Needed for the insecure https registry to work.
-	oldDefaultTransport := http.DefaultTransport
-	http.DefaultTransport = api.getHttpTransport()
-	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
-	http.DefaultTransport = oldDefaultTransport
-
+	// the transport is passed explicitly instead of replacing http.DefaultTransport, which is not safe for the concurrent requests
+	desc, err := remote.Get(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport()))
 	if err != nil {
 		return nil, nil, fmt.Errorf("reading image %q: %v", ref, err)
 	}
 
+	var img v1.Image
+	switch desc.MediaType {
+	case types.OCIImageIndex, types.DockerManifestList:
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading manifest list %q: %v", ref, err)
+		}
+
+		indexManifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading manifest list %q: %v", ref, err)
+		}
+
+		child, err := selectManifestListChild(indexManifest.Manifests, platform)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading manifest list %q: %v", ref, err)
+		}
+
+		if img, err = index.Image(child.Digest); err != nil {
+			return nil, nil, fmt.Errorf("reading image %q: %v", ref, err)
+		}
+	default:
+		if img, err = desc.Image(); err != nil {
+			return nil, nil, fmt.Errorf("reading image %q: %v", ref, err)
+		}
+	}
+
 	return img, ref, nil
 }
 
+func selectManifestListChild(manifests []v1.Descriptor, platform string) (v1.Descriptor, error) {
+	if len(manifests) == 0 {
+		return v1.Descriptor{}, fmt.Errorf("empty manifest list")
+	}
+
+	platforms := []string{platform}
+	if platform == "" {
+		platforms = []string{image.HostPlatform(), "linux/amd64"}
+	}
+
+	for _, p := range platforms {
+		os, arch, variant, err := image.ParsePlatform(p)
+		if err != nil {
+			return v1.Descriptor{}, err
+		}
+
+		for _, child := range manifests {
+			// child without platform is considered as linux/amd64
+			childPlatform := v1.Platform{OS: "linux", Architecture: "amd64"}
+			if child.Platform != nil {
+				childPlatform = *child.Platform
+			}
+
+			if childPlatform.OS == os && childPlatform.Architecture == arch && (variant == "" || childPlatform.Variant == variant) {
+				return child, nil
+			}
+		}
+	}
+
+	if platform == "" {
+		return manifests[0], nil
+	}
+
+	return v1.Descriptor{}, fmt.Errorf("no image for platform %s", platform)
+}
+
 func (api *api) newRepositoryOptions() []name.Option {
 	return api.parseReferenceOptions()
 }
@@ -166,8 +416,8 @@ func (api *api) parseReferenceOptions() []name.Option {
 	return options
 }
 
-func (api *api) getHttpTransport() (transport http.RoundTripper) {
-	transport = http.DefaultTransport
+func (api *api) getHttpTransport() http.RoundTripper {
+	var httpTransport http.RoundTripper = http.DefaultTransport
 
 	if api.SkipTlsVerifyRegistry {
 		defaultTransport := http.DefaultTransport.(*http.Transport)
@@ -183,8 +433,8 @@ func (api *api) getHttpTransport() (transport http.RoundTripper) {
 			TLSNextProto:          make(map[string]func(authority string, c *tls.Conn) http.RoundTripper),
 		}
 
-		transport = newTransport
+		httpTransport = newTransport
 	}
 
-	return
+	return newRetryTransport(httpTransport)
 }
@@ -0,0 +1,66 @@
+package docker_registry
+
+import (
+	"net/http/httptest"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("api", func() {
+	var server *httptest.Server
+	var registryAPI *api
+
+	BeforeEach(func() {
+		server = httptest.NewServer(registry.New())
+		registryAPI = newAPI(apiOptions{})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("publishes platform images by digests and the manifest list by the single tag", func() {
+		repository := registryHost(server) + "/group/app"
+
+		var entries []ManifestListEntry
+		for _, platform := range []string{"linux/amd64", "linux/arm64"} {
+			img, err := random.Image(256, 1)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			digest, err := registryAPI.PutImageByDigest(repository, img)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			expectedDigest, err := img.Digest()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(digest).Should(Equal(expectedDigest.String()))
+
+			entries = append(entries, ManifestListEntry{Platform: platform, RepoDigest: digest})
+		}
+
+		ref, err := name.ParseReference(repository+":v1", name.WeakValidation)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		// the tag does not exist until the manifest list is published
+		_, err = remote.Get(ref)
+		Ω(err).Should(HaveOccurred())
+
+		Ω(registryAPI.PutManifestList(repository+":v1", entries)).Should(Succeed())
+
+		index, err := remote.Index(ref)
+		Ω(err).ShouldNot(HaveOccurred())
+		manifest, err := index.IndexManifest()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(manifest.Manifests).Should(HaveLen(2))
+		for ind, desc := range manifest.Manifests {
+			Ω(desc.Digest.String()).Should(Equal(entries[ind].RepoDigest))
+			Ω(desc.Platform.OS + "/" + desc.Platform.Architecture).Should(Equal(entries[ind].Platform))
+		}
+	})
+})
@@ -0,0 +1,201 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/flant/werf/pkg/image"
+)
+
+const ArtifactoryImplementationName = "artifactory"
+
+type ArtifactoryNotFoundError apiError
+
+var artifactoryPatterns = []string{"^.*\\.jfrog\\.io", "^artifactory\\..*"}
+
+// artifactory supports the repository path method only: REGISTRY/REPOSITORY_KEY/IMAGE
+type artifactory struct {
+	*defaultImplementation
+	artifactoryApi
+	artifactoryCredentials
+}
+
+type artifactoryOptions struct {
+	defaultImplementationOptions
+	artifactoryCredentials
+}
+
+type artifactoryCredentials struct {
+	username string
+	password string
+}
+
+func newArtifactory(options artifactoryOptions) (*artifactory, error) {
+	d, err := newDefaultImplementation(options.defaultImplementationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	artifactory := &artifactory{
+		defaultImplementation:  d,
+		artifactoryApi:         newArtifactoryApi(),
+		artifactoryCredentials: options.artifactoryCredentials,
+	}
+
+	return artifactory, nil
+}
+
+func (r *artifactory) ResolveRepoMode(registryOrRepositoryAddress, repoMode string) (string, error) {
+	_, _, imageName, err := r.parseReference(registryOrRepositoryAddress)
+	if err != nil {
+		return "", err
+	}
+
+	switch repoMode {
+	case MonorepoRepoMode:
+		if imageName != "" {
+			return MonorepoRepoMode, nil
+		}
+
+		return "", fmt.Errorf("docker registry implementation %[1]s and repo mode %[2]s cannot be used with %[4]s (add image name to address or use %[3]s repo mode)", r.String(), MonorepoRepoMode, MultirepoRepoMode, registryOrRepositoryAddress)
+	case MultirepoRepoMode:
+		return MultirepoRepoMode, nil
+	case "auto", "":
+		if imageName == "" {
+			return MultirepoRepoMode, nil
+		} else {
+			return MonorepoRepoMode, nil
+		}
+	default:
+		return "", fmt.Errorf("docker registry implementation %s does not support repo mode %s", r.String(), repoMode)
+	}
+}
+
+func (r *artifactory) Tags(reference string) ([]string, error) {
+	apiUrl, repositoryKey, imageName, credentials, err := r.parseImageReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, resp, err := r.artifactoryApi.getTags(apiUrl, repositoryKey, imageName, credentials)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	return tags, err
+}
+
+func (r *artifactory) SelectRepoImageList(reference string, f func(string, *image.Info, error) (bool, error)) ([]*image.Info, error) {
+	tags, err := r.Tags(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.defaultImplementation.selectRepoImageListByTags(reference, tags, f)
+}
+
+func (r *artifactory) GetRepoImageList(reference string) ([]*image.Info, error) {
+	return r.SelectRepoImageList(reference, nil)
+}
+
+func (r *artifactory) CreateRepo(reference string) error {
+	apiUrl, repositoryKey, imageName, credentials, err := r.parseImageReference(reference)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.artifactoryApi.createFolder(apiUrl, repositoryKey, imageName, credentials)
+	return err
+}
+
+func (r *artifactory) DeleteRepo(reference string) error {
+	apiUrl, repositoryKey, imageName, credentials, err := r.parseImageReference(reference)
+	if err != nil {
+		return err
+	}
+
+	return r.deleteItem(apiUrl, repositoryKey, imageName, credentials)
+}
+
+func (r *artifactory) DeleteRepoImage(repoImageList ...*image.Info) error {
+	for _, repoImage := range repoImageList {
+		if err := r.deleteRepoImage(repoImage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteRepoImage deletes the tag folder, the manifest digest cannot be used because Artifactory deletes all tags with the same manifest
+func (r *artifactory) deleteRepoImage(repoImage *image.Info) error {
+	apiUrl, repositoryKey, imageName, credentials, err := r.parseImageReference(repoImage.Repository)
+	if err != nil {
+		return err
+	}
+
+	return r.deleteItem(apiUrl, repositoryKey, path.Join(imageName, repoImage.Tag), credentials)
+}
+
+func (r *artifactory) deleteItem(apiUrl, repositoryKey, itemPath string, credentials artifactoryCredentials) error {
+	resp, err := r.artifactoryApi.deleteItem(apiUrl, repositoryKey, itemPath, credentials)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return ArtifactoryNotFoundError{error: err}
+	}
+
+	return err
+}
+
+func (r *artifactory) String() string {
+	return ArtifactoryImplementationName
+}
+
+// parseImageReference returns the Artifactory API url, the repository key, the image name and the credentials (the docker config credentials are used by default)
+func (r *artifactory) parseImageReference(reference string) (string, string, string, artifactoryCredentials, error) {
+	parsedReference, err := name.NewRepository(reference, r.api.parseReferenceOptions()...)
+	if err != nil {
+		return "", "", "", artifactoryCredentials{}, err
+	}
+
+	apiUrl, repositoryKey, imageName, err := r.parseReference(reference)
+	if err != nil {
+		return "", "", "", artifactoryCredentials{}, err
+	}
+
+	if imageName == "" {
+		return "", "", "", artifactoryCredentials{}, fmt.Errorf("unexpected reference %s: image name expected after repository key", reference)
+	}
+
+	credentials := r.artifactoryCredentials
+	if credentials.username == "" && credentials.password == "" {
+		credentials.username, credentials.password, err = getKeychainCredentials(parsedReference.Registry)
+		if err != nil {
+			return "", "", "", artifactoryCredentials{}, err
+		}
+	}
+
+	return apiUrl, repositoryKey, imageName, credentials, nil
+}
+
+func (r *artifactory) parseReference(reference string) (string, string, string, error) {
+	parsedReference, err := name.NewRepository(reference, r.api.parseReferenceOptions()...)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	parts := strings.SplitN(parsedReference.RepositoryStr(), "/", 2)
+	repositoryKey := parts[0]
+
+	var imageName string
+	if len(parts) == 2 {
+		imageName = parts[1]
+	}
+
+	apiUrl := fmt.Sprintf("%s://%s/artifactory", parsedReference.Registry.Scheme(), parsedReference.RegistryStr())
+
+	return apiUrl, repositoryKey, imageName, nil
+}
@@ -0,0 +1,75 @@
+package docker_registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type artifactoryApi struct{}
+
+func newArtifactoryApi() artifactoryApi {
+	return artifactoryApi{}
+}
+
+func (api *artifactoryApi) getTags(apiUrl, repositoryKey, imageName string, credentials artifactoryCredentials) ([]string, *http.Response, error) {
+	reqUrl := fmt.Sprintf("%s/api/docker/%s/v2/%s/tags/list", apiUrl, url.PathEscape(repositoryKey), escapePath(imageName))
+	resp, respBody, err := doRequest(http.MethodGet, reqUrl, nil, api.requestOptions(credentials, http.StatusOK))
+	if err != nil {
+		return nil, resp, err
+	}
+
+	respJson := &struct {
+		Tags []string `json:"tags"`
+	}{}
+
+	if err := json.Unmarshal(respBody, respJson); err != nil {
+		return nil, resp, fmt.Errorf("unexpected body %s", string(respBody))
+	}
+
+	return respJson.Tags, resp, nil
+}
+
+// createFolder creates the folder of the image in the repository, the folder is created with all parents
+func (api *artifactoryApi) createFolder(apiUrl, repositoryKey, folderPath string, credentials artifactoryCredentials) (*http.Response, error) {
+	reqUrl := fmt.Sprintf("%s/%s/%s/", apiUrl, url.PathEscape(repositoryKey), escapePath(folderPath))
+	resp, _, err := doRequest(http.MethodPut, reqUrl, nil, api.requestOptions(credentials, http.StatusOK, http.StatusCreated))
+	return resp, err
+}
+
+// deleteItem deletes the folder of the image or the folder of the tag with the manifest and the layers
+func (api *artifactoryApi) deleteItem(apiUrl, repositoryKey, itemPath string, credentials artifactoryCredentials) (*http.Response, error) {
+	reqUrl := fmt.Sprintf("%s/%s/%s", apiUrl, url.PathEscape(repositoryKey), escapePath(itemPath))
+	resp, _, err := doRequest(http.MethodDelete, reqUrl, nil, api.requestOptions(credentials, http.StatusOK, http.StatusNoContent))
+	return resp, err
+}
+
+func (api *artifactoryApi) requestOptions(credentials artifactoryCredentials, acceptedCodes ...int) doRequestOptions {
+	options := doRequestOptions{
+		Headers:       map[string]string{},
+		AcceptedCodes: acceptedCodes,
+	}
+
+	// an access token can be used without username
+	if credentials.username == "" && credentials.password != "" {
+		options.Headers["Authorization"] = fmt.Sprintf("Bearer %s", credentials.password)
+	} else {
+		options.BasicAuth = doRequestBasicAuth{
+			username: credentials.username,
+			password: credentials.password,
+		}
+	}
+
+	return options
+}
+
+func escapePath(p string) string {
+	var parts []string
+	for _, part := range strings.Split(p, "/") {
+		parts = append(parts, url.PathEscape(part))
+	}
+
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,84 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/werf/pkg/image"
+)
+
+var _ = Describe("artifactory", func() {
+	var server *httptest.Server
+	var registry *artifactory
+	var requests []string
+
+	BeforeEach(func() {
+		requests = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+
+			Ω(req.Header.Get("Authorization")).Should(Equal("Bearer token"))
+			requests = append(requests, fmt.Sprintf("%s %s", req.Method, req.URL.Path))
+
+			switch {
+			case req.Method == http.MethodGet && req.URL.Path == "/artifactory/api/docker/docker-local/v2/group/app/tags/list":
+				fmt.Fprint(w, `{"name":"group/app","tags":["v1","v2"]}`)
+			case req.Method == http.MethodGet:
+				w.WriteHeader(http.StatusNotFound)
+			case req.Method == http.MethodPut:
+				w.WriteHeader(http.StatusCreated)
+			case req.Method == http.MethodDelete && req.URL.Path == "/artifactory/docker-local/group/missing":
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}))
+
+		var err error
+		registry, err = newArtifactory(artifactoryOptions{artifactoryCredentials: artifactoryCredentials{password: "token"}})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists tags with the Docker API of the repository", func() {
+		tags, err := registry.Tags(registryHost(server) + "/docker-local/group/app")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"v1", "v2"}))
+	})
+
+	It("returns no tags if the image does not exist", func() {
+		tags, err := registry.Tags(registryHost(server) + "/docker-local/group/missing")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(BeEmpty())
+	})
+
+	It("creates the image folder, deletes the tag folder and the image folder", func() {
+		Ω(registry.CreateRepo(registryHost(server) + "/docker-local/group/app")).Should(Succeed())
+		Ω(registry.DeleteRepoImage(&image.Info{Repository: registryHost(server) + "/docker-local/group/app", Tag: "v1", RepoDigest: "sha256:aaa"})).Should(Succeed())
+		Ω(registry.DeleteRepo(registryHost(server) + "/docker-local/group/app")).Should(Succeed())
+
+		Ω(requests).Should(Equal([]string{
+			"PUT /artifactory/docker-local/group/app/",
+			"DELETE /artifactory/docker-local/group/app/v1",
+			"DELETE /artifactory/docker-local/group/app",
+		}))
+	})
+
+	It("returns not found error if the image does not exist", func() {
+		err := registry.DeleteRepo(registryHost(server) + "/docker-local/group/missing")
+		Ω(err).Should(BeAssignableToTypeOf(ArtifactoryNotFoundError{}))
+	})
+
+	It("resolves repo mode by the image name after the repository key", func() {
+		Ω(registry.ResolveRepoMode("company.jfrog.io/docker-local", "auto")).Should(Equal(MultirepoRepoMode))
+		Ω(registry.ResolveRepoMode("company.jfrog.io/docker-local/app", "auto")).Should(Equal(MonorepoRepoMode))
+	})
+})
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 
 	"github.com/flant/logboek"
@@ -66,3 +68,18 @@ func doRequest(method, url string, body io.Reader, options doRequestOptions) (*h
 
 	return resp, respBody, nil
 }
+
+// getKeychainCredentials returns username and password of the registry from the docker config
+func getKeychainCredentials(registry name.Registry) (string, string, error) {
+	authenticator, err := authn.DefaultKeychain.Resolve(registry)
+	if err != nil {
+		return "", "", fmt.Errorf("getting creds for %q: %s", registry.RegistryStr(), err)
+	}
+
+	authConfig, err := authenticator.Authorization()
+	if err != nil {
+		return "", "", fmt.Errorf("getting creds for %q: %s", registry.RegistryStr(), err)
+	}
+
+	return authConfig.Username, authConfig.Password, nil
+}
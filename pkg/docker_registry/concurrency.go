@@ -0,0 +1,67 @@
+package docker_registry
+
+import "sync"
+
+const DefaultConcurrency = 10
+
+var concurrency = DefaultConcurrency
+
+// Concurrency returns the max number of the simultaneous registry requests of the listing operations
+func Concurrency() int {
+	return concurrency
+}
+
+// ParallelFetch calls fetch for every index in range [0, n) in the bounded pool of workers,
+// new jobs are not started after the first error, which is returned
+func ParallelFetch(n int, fetch func(ind int) error) error {
+	workers := concurrency
+	if workers > n {
+		workers = n
+	}
+
+	if workers <= 1 {
+		for ind := 0; ind < n; ind++ {
+			if err := fetch(ind); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	jobs := make(chan int, n)
+	for ind := 0; ind < n; ind++ {
+		jobs <- ind
+	}
+	close(jobs)
+
+	var firstErr error
+	var errOnce sync.Once
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for ind := range jobs {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if err := fetch(ind); err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						close(done)
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,42 @@
+package docker_registry_test
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/flant/werf/pkg/docker_registry"
+
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("parallel fetch", func(n, failAt int) {
+	var fetched int32
+	results := make([]int, n)
+
+	err := docker_registry.ParallelFetch(n, func(ind int) error {
+		atomic.AddInt32(&fetched, 1)
+		if ind == failAt {
+			return errors.New("fetch failed")
+		}
+		results[ind] = ind * ind
+		return nil
+	})
+
+	if failAt >= 0 {
+		Ω(err).Should(MatchError("fetch failed"))
+		Ω(int(atomic.LoadInt32(&fetched))).Should(BeNumerically("<=", n))
+		return
+	}
+
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(int(fetched)).Should(Equal(n))
+	for ind := range results {
+		Ω(results[ind]).Should(Equal(ind * ind))
+	}
+},
+	Entry("no jobs", 0, -1),
+	Entry("fewer jobs than workers", 3, -1),
+	Entry("more jobs than workers", 100, -1),
+	Entry("first error is returned", 100, 42),
+)
@@ -36,11 +36,21 @@ func (r *defaultImplementation) SelectRepoImageList(reference string, f func(str
 	return r.selectRepoImageListByTags(reference, tags, f)
 }
 
+// selectRepoImageListByTags fetches images concurrently, the selection function is called sequentially in the order of tags
 func (r *defaultImplementation) selectRepoImageListByTags(reference string, tags []string, f func(string, *image.Info, error) (bool, error)) ([]*image.Info, error) {
+	repoImages := make([]*image.Info, len(tags))
+	repoImageErrors := make([]error, len(tags))
+	if err := ParallelFetch(len(tags), func(ind int) error {
+		repoImages[ind], repoImageErrors[ind] = r.getRepoImageWithCache(strings.Join([]string{reference, tags[ind]}, ":"))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
 	var repoImageList []*image.Info
-	for _, tag := range tags {
+	for ind, tag := range tags {
 		ref := strings.Join([]string{reference, tag}, ":")
-		repoImage, err := r.GetRepoImage(ref)
+		repoImage, err := repoImages[ind], repoImageErrors[ind]
 
 		if f != nil {
 			ok, err := f(ref, repoImage, err)
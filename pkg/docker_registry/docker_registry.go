@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 
 	"github.com/flant/werf/pkg/image"
 )
@@ -21,26 +22,57 @@ type DockerRegistry interface {
 	DeleteRepo(reference string) error
 	Tags(reference string) ([]string, error)
 	GetRepoImage(reference string) (*image.Info, error)
+	GetRepoImageByPlatform(reference, platform string) (*image.Info, error)
 	TryGetRepoImage(reference string) (*image.Info, error)
 	IsRepoImageExists(reference string) (bool, error)
 	GetRepoImageList(reference string) ([]*image.Info, error)
 	SelectRepoImageList(reference string, f func(string, *image.Info, error) (bool, error)) ([]*image.Info, error)
 	DeleteRepoImage(repoImageList ...*image.Info) error
+	PutImageByDigest(repository string, img v1.Image) (string, error)
+	PutManifestList(reference string, entries []ManifestListEntry) error
 
 	ResolveRepoMode(registryOrRepositoryAddress, repoMode string) (string, error)
 	String() string
 }
 
 type DockerRegistryOptions struct {
-	InsecureRegistry      bool
-	SkipTlsVerifyRegistry bool
-	DockerHubToken        string
-	DockerHubUsername     string
-	DockerHubPassword     string
-	GitHubToken           string
-	HarborUsername        string
-	HarborPassword        string
-	QuayToken             string
+	InsecureRegistry       bool
+	SkipTlsVerifyRegistry  bool
+	DockerHubToken         string
+	DockerHubUsername      string
+	DockerHubPassword      string
+	GitHubToken            string
+	HarborUsername         string
+	HarborPassword         string
+	QuayToken              string
+	NexusUsername          string
+	NexusPassword          string
+	NexusApiUrl            string
+	NexusRepository        string
+	ArtifactoryUsername    string
+	ArtifactoryPassword    string
+	AlibabaAccessKeyId     string
+	AlibabaAccessKeySecret string
+}
+
+func (o *DockerRegistryOptions) alibabaCrOptions() alibabaCrOptions {
+	return alibabaCrOptions{
+		defaultImplementationOptions: o.defaultOptions(),
+		alibabaCrCredentials: alibabaCrCredentials{
+			accessKeyId:     o.AlibabaAccessKeyId,
+			accessKeySecret: o.AlibabaAccessKeySecret,
+		},
+	}
+}
+
+func (o *DockerRegistryOptions) artifactoryOptions() artifactoryOptions {
+	return artifactoryOptions{
+		defaultImplementationOptions: o.defaultOptions(),
+		artifactoryCredentials: artifactoryCredentials{
+			username: o.ArtifactoryUsername,
+			password: o.ArtifactoryPassword,
+		},
+	}
 }
 
 func (o *DockerRegistryOptions) awsEcrOptions() awsEcrOptions {
@@ -87,6 +119,12 @@ func (o *DockerRegistryOptions) gitLabRegistryOptions() gitLabRegistryOptions {
 	}
 }
 
+func (o *DockerRegistryOptions) googleArtifactRegistryOptions() googleArtifactRegistryOptions {
+	return googleArtifactRegistryOptions{
+		defaultImplementationOptions: o.defaultOptions(),
+	}
+}
+
 func (o *DockerRegistryOptions) harborOptions() harborOptions {
 	return harborOptions{
 		defaultImplementationOptions: o.defaultOptions(),
@@ -97,6 +135,18 @@ func (o *DockerRegistryOptions) harborOptions() harborOptions {
 	}
 }
 
+func (o *DockerRegistryOptions) nexusOptions() nexusOptions {
+	return nexusOptions{
+		defaultImplementationOptions: o.defaultOptions(),
+		nexusCredentials: nexusCredentials{
+			username: o.NexusUsername,
+			password: o.NexusPassword,
+		},
+		apiUrl:     o.NexusApiUrl,
+		repository: o.NexusRepository,
+	}
+}
+
 func (o *DockerRegistryOptions) quayOptions() quayOptions {
 	return quayOptions{
 		defaultImplementationOptions: o.defaultOptions(),
@@ -115,6 +165,10 @@ func (o *DockerRegistryOptions) defaultOptions() defaultImplementationOptions {
 
 func NewDockerRegistry(repositoryAddress string, implementation string, options DockerRegistryOptions) (DockerRegistry, error) {
 	switch implementation {
+	case AlibabaCrImplementationName:
+		return newAlibabaCr(options.alibabaCrOptions())
+	case ArtifactoryImplementationName:
+		return newArtifactory(options.artifactoryOptions())
 	case AwsEcrImplementationName:
 		return newAwsEcr(options.awsEcrOptions())
 	case AzureCrImplementationName:
@@ -127,8 +181,12 @@ func NewDockerRegistry(repositoryAddress string, implementation string, options
 		return newGitHubPackages(options.gitHubPackagesOptions())
 	case GitLabRegistryImplementationName:
 		return newGitLabRegistry(options.gitLabRegistryOptions())
+	case GoogleArtifactRegistryImplementationName:
+		return newGoogleArtifactRegistry(options.googleArtifactRegistryOptions())
 	case HarborImplementationName:
 		return newHarbor(options.harborOptions())
+	case NexusImplementationName:
+		return newNexus(options.nexusOptions())
 	case QuayImplementationName:
 		return newQuay(options.quayOptions())
 	case DefaultImplementationName:
@@ -139,6 +197,11 @@ func NewDockerRegistry(repositoryAddress string, implementation string, options
 			return nil, err
 		}
 
+		// Nexus has no well-known hosts, the registry with the Nexus api url option is considered as Nexus
+		if resolvedImplementation == DefaultImplementationName && options.NexusApiUrl != "" {
+			resolvedImplementation = NexusImplementationName
+		}
+
 		return NewDockerRegistry(repositoryAddress, resolvedImplementation, options)
 	}
 }
@@ -178,6 +241,14 @@ func detectImplementation(accountOrRepositoryAddress string) (string, error) {
 		name     string
 		patterns []string
 	}{
+		{
+			name:     AlibabaCrImplementationName,
+			patterns: alibabaCrPatterns,
+		},
+		{
+			name:     ArtifactoryImplementationName,
+			patterns: artifactoryPatterns,
+		},
 		{
 			name:     AwsEcrImplementationName,
 			patterns: awsEcrPatterns,
@@ -198,10 +269,18 @@ func detectImplementation(accountOrRepositoryAddress string) (string, error) {
 			name:     GitHubPackagesImplementationName,
 			patterns: gitHubPackagesPatterns,
 		},
+		{
+			name:     GoogleArtifactRegistryImplementationName,
+			patterns: googleArtifactRegistryPatterns,
+		},
 		{
 			name:     HarborImplementationName,
 			patterns: harborPatterns,
 		},
+		{
+			name:     NexusImplementationName,
+			patterns: nexusPatterns,
+		},
 		{
 			name:     QuayImplementationName,
 			patterns: quayPatterns,
@@ -224,6 +303,8 @@ func detectImplementation(accountOrRepositoryAddress string) (string, error) {
 
 func ImplementationList() []string {
 	return []string{
+		AlibabaCrImplementationName,
+		ArtifactoryImplementationName,
 		AwsEcrImplementationName,
 		AzureCrImplementationName,
 		DefaultImplementationName,
@@ -231,7 +312,9 @@ func ImplementationList() []string {
 		GcrImplementationName,
 		GitHubPackagesImplementationName,
 		GitLabRegistryImplementationName,
+		GoogleArtifactRegistryImplementationName,
 		HarborImplementationName,
+		NexusImplementationName,
 		QuayImplementationName,
 	}
 }
@@ -0,0 +1,266 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/image"
+)
+
+const GoogleArtifactRegistryImplementationName = "gar"
+
+type GoogleArtifactRegistryNotFoundError apiError
+
+var (
+	googleArtifactRegistryPatternRegexp = regexp.MustCompile(`^([a-z0-9-]+)-docker\.pkg\.dev$`)
+	googleArtifactRegistryPatterns      = []string{googleArtifactRegistryPatternRegexp.String()}
+)
+
+// the username of the docker config credentials for the access token (gcloud credential helper)
+const googleOAuth2AccessTokenUsername = "oauth2accesstoken"
+
+type googleArtifactRegistry struct {
+	*defaultImplementation
+	googleArtifactRegistryApi
+
+	gcloudToken    string
+	gcloudTokenMux sync.Mutex
+}
+
+type googleArtifactRegistryOptions struct {
+	defaultImplementationOptions
+}
+
+func newGoogleArtifactRegistry(options googleArtifactRegistryOptions) (*googleArtifactRegistry, error) {
+	d, err := newDefaultImplementation(options.defaultImplementationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	googleArtifactRegistry := &googleArtifactRegistry{
+		defaultImplementation:     d,
+		googleArtifactRegistryApi: newGoogleArtifactRegistryApi(),
+	}
+
+	return googleArtifactRegistry, nil
+}
+
+func (r *googleArtifactRegistry) ResolveRepoMode(registryOrRepositoryAddress, repoMode string) (string, error) {
+	_, imageName, err := r.parseReference(registryOrRepositoryAddress)
+	if err != nil {
+		return "", err
+	}
+
+	switch repoMode {
+	case MonorepoRepoMode:
+		if imageName != "" {
+			return MonorepoRepoMode, nil
+		}
+
+		return "", fmt.Errorf("docker registry implementation %[1]s and repo mode %[2]s cannot be used with %[4]s (add image name to address or use %[3]s repo mode)", r.String(), MonorepoRepoMode, MultirepoRepoMode, registryOrRepositoryAddress)
+	case MultirepoRepoMode:
+		return MultirepoRepoMode, nil
+	case "auto", "":
+		if imageName == "" {
+			return MultirepoRepoMode, nil
+		} else {
+			return MonorepoRepoMode, nil
+		}
+	default:
+		return "", fmt.Errorf("docker registry implementation %s does not support repo mode %s", r.String(), repoMode)
+	}
+}
+
+func (r *googleArtifactRegistry) Tags(reference string) ([]string, error) {
+	repo, imageName, err := r.parseImageReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := r.getToken(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, resp, err := r.googleArtifactRegistryApi.listTags(repo, imageName, token)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	return tags, err
+}
+
+func (r *googleArtifactRegistry) SelectRepoImageList(reference string, f func(string, *image.Info, error) (bool, error)) ([]*image.Info, error) {
+	tags, err := r.Tags(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.defaultImplementation.selectRepoImageListByTags(reference, tags, f)
+}
+
+func (r *googleArtifactRegistry) GetRepoImageList(reference string) ([]*image.Info, error) {
+	return r.SelectRepoImageList(reference, nil)
+}
+
+// CreateRepo creates the Artifact Registry repository if it does not exist, the image is created on push
+func (r *googleArtifactRegistry) CreateRepo(reference string) error {
+	repo, _, err := r.parseReference(reference)
+	if err != nil {
+		return err
+	}
+
+	token, err := r.getToken(reference)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.googleArtifactRegistryApi.createDockerRepository(repo, token)
+	if resp != nil && resp.StatusCode == http.StatusConflict {
+		return nil
+	}
+
+	return err
+}
+
+func (r *googleArtifactRegistry) DeleteRepo(reference string) error {
+	repo, imageName, err := r.parseImageReference(reference)
+	if err != nil {
+		return err
+	}
+
+	token, err := r.getToken(reference)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.googleArtifactRegistryApi.deletePackage(repo, imageName, token)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return GoogleArtifactRegistryNotFoundError{error: err}
+	}
+
+	return err
+}
+
+func (r *googleArtifactRegistry) DeleteRepoImage(repoImageList ...*image.Info) error {
+	for _, repoImage := range repoImageList {
+		if err := r.deleteRepoImage(repoImage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *googleArtifactRegistry) deleteRepoImage(repoImage *image.Info) error {
+	repo, imageName, err := r.parseImageReference(repoImage.Repository)
+	if err != nil {
+		return err
+	}
+
+	token, err := r.getToken(repoImage.Repository)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.googleArtifactRegistryApi.deleteVersion(repo, imageName, repoImage.RepoDigest, token)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return GoogleArtifactRegistryNotFoundError{error: err}
+	}
+
+	return err
+}
+
+func (r *googleArtifactRegistry) String() string {
+	return GoogleArtifactRegistryImplementationName
+}
+
+func (r *googleArtifactRegistry) parseImageReference(reference string) (googleArtifactRegistryRepository, string, error) {
+	repo, imageName, err := r.parseReference(reference)
+	if err != nil {
+		return googleArtifactRegistryRepository{}, "", err
+	}
+
+	if imageName == "" {
+		return googleArtifactRegistryRepository{}, "", fmt.Errorf("unexpected reference %s: image name expected after repository", reference)
+	}
+
+	return repo, imageName, nil
+}
+
+// parseReference parses LOCATION-docker.pkg.dev/PROJECT/REPOSITORY/IMAGE, the image can be empty
+func (r *googleArtifactRegistry) parseReference(reference string) (googleArtifactRegistryRepository, string, error) {
+	parsedReference, err := name.NewRepository(reference)
+	if err != nil {
+		return googleArtifactRegistryRepository{}, "", err
+	}
+
+	match := googleArtifactRegistryPatternRegexp.FindStringSubmatch(parsedReference.RegistryStr())
+	if len(match) == 0 {
+		return googleArtifactRegistryRepository{}, "", fmt.Errorf("reference %s is not compatible with %s docker registry implementation", reference, r.String())
+	}
+
+	parts := strings.SplitN(parsedReference.RepositoryStr(), "/", 3)
+	if len(parts) < 2 {
+		return googleArtifactRegistryRepository{}, "", fmt.Errorf("unexpected reference %s: LOCATION-docker.pkg.dev/PROJECT/REPOSITORY[/IMAGE] expected", reference)
+	}
+
+	repo := googleArtifactRegistryRepository{
+		project:    parts[0],
+		location:   match[1],
+		repository: parts[1],
+	}
+
+	var imageName string
+	if len(parts) == 3 {
+		imageName = parts[2]
+	}
+
+	return repo, imageName, nil
+}
+
+// getToken returns the access token from the docker config (gcloud credential helper) or from gcloud
+func (r *googleArtifactRegistry) getToken(reference string) (string, error) {
+	parsedReference, err := name.NewRepository(reference)
+	if err != nil {
+		return "", err
+	}
+
+	username, password, err := getKeychainCredentials(parsedReference.Registry)
+	if err != nil {
+		return "", err
+	}
+
+	if username == googleOAuth2AccessTokenUsername && password != "" {
+		return password, nil
+	}
+
+	r.gcloudTokenMux.Lock()
+	defer r.gcloudTokenMux.Unlock()
+
+	if r.gcloudToken != "" {
+		return r.gcloudToken, nil
+	}
+
+	if _, err := exec.LookPath("gcloud"); err != nil {
+		return "", fmt.Errorf("access token for %s not found: configure docker with gcloud credential helper or install gcloud: %s", parsedReference.RegistryStr(), err)
+	}
+
+	logboek.Debug.LogLn("gcloud auth print-access-token")
+	output, err := exec.Command("gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("command: gcloud auth print-access-token\nerror: %s", err)
+	}
+
+	r.gcloudToken = strings.TrimSpace(string(output))
+
+	return r.gcloudToken, nil
+}
@@ -0,0 +1,108 @@
+package docker_registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+const googleArtifactRegistryAPIUrl = "https://artifactregistry.googleapis.com/v1"
+
+type googleArtifactRegistryApi struct {
+	apiUrl string
+}
+
+func newGoogleArtifactRegistryApi() googleArtifactRegistryApi {
+	return googleArtifactRegistryApi{apiUrl: googleArtifactRegistryAPIUrl}
+}
+
+type googleArtifactRegistryRepository struct {
+	project    string
+	location   string
+	repository string
+}
+
+func (repo googleArtifactRegistryRepository) resourceName() string {
+	return fmt.Sprintf("projects/%s/locations/%s/repositories/%s", repo.project, repo.location, repo.repository)
+}
+
+// packageResourceName returns the name of the package, the slashes of the image name are escaped
+func (repo googleArtifactRegistryRepository) packageResourceName(imageName string) string {
+	return fmt.Sprintf("%s/packages/%s", repo.resourceName(), url.PathEscape(imageName))
+}
+
+func (api *googleArtifactRegistryApi) listTags(repo googleArtifactRegistryRepository, imageName, token string) ([]string, *http.Response, error) {
+	var tags []string
+	var pageToken string
+
+	for {
+		query := url.Values{}
+		query.Set("pageSize", "1000")
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		reqUrl := fmt.Sprintf("%s/%s/tags?%s", api.apiUrl, repo.packageResourceName(imageName), query.Encode())
+		resp, respBody, err := doRequest(http.MethodGet, reqUrl, nil, api.requestOptions(token, http.StatusOK))
+		if err != nil {
+			return nil, resp, err
+		}
+
+		respJson := &struct {
+			Tags []struct {
+				Name string `json:"name"`
+			} `json:"tags"`
+			NextPageToken string `json:"nextPageToken"`
+		}{}
+
+		if err := json.Unmarshal(respBody, respJson); err != nil {
+			return nil, resp, fmt.Errorf("unexpected body %s", string(respBody))
+		}
+
+		for _, tag := range respJson.Tags {
+			tags = append(tags, path.Base(tag.Name))
+		}
+
+		if respJson.NextPageToken == "" {
+			return tags, resp, nil
+		}
+		pageToken = respJson.NextPageToken
+	}
+}
+
+// deleteVersion deletes the manifest by digest with all tags
+func (api *googleArtifactRegistryApi) deleteVersion(repo googleArtifactRegistryRepository, imageName, digest, token string) (*http.Response, error) {
+	reqUrl := fmt.Sprintf("%s/%s/versions/%s?force=true", api.apiUrl, repo.packageResourceName(imageName), url.PathEscape(digest))
+	resp, _, err := doRequest(http.MethodDelete, reqUrl, nil, api.requestOptions(token, http.StatusOK))
+	return resp, err
+}
+
+func (api *googleArtifactRegistryApi) deletePackage(repo googleArtifactRegistryRepository, imageName, token string) (*http.Response, error) {
+	reqUrl := fmt.Sprintf("%s/%s", api.apiUrl, repo.packageResourceName(imageName))
+	resp, _, err := doRequest(http.MethodDelete, reqUrl, nil, api.requestOptions(token, http.StatusOK))
+	return resp, err
+}
+
+func (api *googleArtifactRegistryApi) createDockerRepository(repo googleArtifactRegistryRepository, token string) (*http.Response, error) {
+	reqUrl := fmt.Sprintf("%s/projects/%s/locations/%s/repositories?repositoryId=%s", api.apiUrl, repo.project, repo.location, url.QueryEscape(repo.repository))
+	body := []byte(`{"format":"DOCKER"}`)
+
+	options := api.requestOptions(token, http.StatusOK)
+	options.Headers["Content-Type"] = "application/json"
+
+	resp, _, err := doRequest(http.MethodPost, reqUrl, bytes.NewBuffer(body), options)
+	return resp, err
+}
+
+func (api *googleArtifactRegistryApi) requestOptions(token string, acceptedCodes ...int) doRequestOptions {
+	return doRequestOptions{
+		Headers: map[string]string{
+			"Accept":        "application/json",
+			"Authorization": fmt.Sprintf("Bearer %s", token),
+		},
+		AcceptedCodes: acceptedCodes,
+	}
+}
@@ -0,0 +1,80 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/werf/pkg/image"
+)
+
+var _ = Describe("google artifact registry", func() {
+	const packagePath = "/projects/project/locations/europe-west1/repositories/repo/packages/group%2Fapp"
+
+	var server *httptest.Server
+	var registry *googleArtifactRegistry
+	var requests []string
+
+	BeforeEach(func() {
+		requests = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+
+			Ω(req.Header.Get("Authorization")).Should(Equal("Bearer token"))
+			requests = append(requests, fmt.Sprintf("%s %s?%s", req.Method, req.URL.EscapedPath(), req.URL.RawQuery))
+
+			switch {
+			case req.Method == http.MethodGet && req.URL.Query().Get("pageToken") == "":
+				fmt.Fprint(w, `{"tags":[{"name":"projects/project/locations/europe-west1/repositories/repo/packages/group%2Fapp/tags/v1"}],"nextPageToken":"next"}`)
+			case req.Method == http.MethodGet:
+				fmt.Fprint(w, `{"tags":[{"name":"projects/project/locations/europe-west1/repositories/repo/packages/group%2Fapp/tags/v2"}]}`)
+			case req.Method == http.MethodPost:
+				w.WriteHeader(http.StatusConflict)
+			default:
+				fmt.Fprint(w, `{"name":"operation"}`)
+			}
+		}))
+
+		var err error
+		registry, err = newGoogleArtifactRegistry(googleArtifactRegistryOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+		registry.googleArtifactRegistryApi.apiUrl = server.URL
+		registry.gcloudToken = "token"
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists tags of the package with all pages", func() {
+		tags, err := registry.Tags("europe-west1-docker.pkg.dev/project/repo/group/app")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"v1", "v2"}))
+
+		Ω(requests).Should(Equal([]string{
+			"GET " + packagePath + "/tags?pageSize=1000",
+			"GET " + packagePath + "/tags?pageSize=1000&pageToken=next",
+		}))
+	})
+
+	It("deletes the version by digest, the package and creates the repository", func() {
+		Ω(registry.DeleteRepoImage(&image.Info{Repository: "europe-west1-docker.pkg.dev/project/repo/group/app", Tag: "v1", RepoDigest: "sha256:aaa"})).Should(Succeed())
+		Ω(registry.DeleteRepo("europe-west1-docker.pkg.dev/project/repo/group/app")).Should(Succeed())
+		Ω(registry.CreateRepo("europe-west1-docker.pkg.dev/project/repo/group/app")).Should(Succeed())
+
+		Ω(requests).Should(Equal([]string{
+			"DELETE " + packagePath + "/versions/sha256:aaa?force=true",
+			"DELETE " + packagePath + "?",
+			"POST /projects/project/locations/europe-west1/repositories?repositoryId=repo",
+		}))
+	})
+
+	It("rejects the reference without repository", func() {
+		_, err := registry.Tags("europe-west1-docker.pkg.dev/project")
+		Ω(err).Should(HaveOccurred())
+	})
+})
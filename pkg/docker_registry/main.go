@@ -7,17 +7,23 @@ import (
 	"github.com/google/go-containerregistry/pkg/logs"
 
 	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/logging"
 )
 
 var generic *api
 
-func Init(insecureRegistry, skipTlsVerifyRegistry bool) error {
+func Init(insecureRegistry, skipTlsVerifyRegistry bool, registryConcurrency int) error {
+	if registryConcurrency > 0 {
+		concurrency = registryConcurrency
+	}
+
 	if logboek.Debug.IsAccepted() {
-		logs.Progress.SetOutput(logboek.GetOutStream())
-		logs.Warn.SetOutput(logboek.GetErrStream())
+		logs.Progress.SetOutput(logging.GetOutStream())
+		logs.Warn.SetOutput(logging.GetErrStream())
 
 		if debugDockerRegistryAPI() {
-			logs.Debug.SetOutput(logboek.GetOutStream())
+			logs.Debug.SetOutput(logging.GetOutStream())
 		} else {
 			logs.Debug.SetOutput(ioutil.Discard)
 		}
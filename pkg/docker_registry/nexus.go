@@ -0,0 +1,196 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/flant/werf/pkg/image"
+)
+
+const NexusImplementationName = "nexus"
+
+type NexusNotFoundError apiError
+
+var nexusPatterns = []string{"^nexus\\..*"}
+
+type nexus struct {
+	*defaultImplementation
+	nexusApi
+	nexusCredentials
+
+	apiUrl     string
+	repository string
+}
+
+type nexusOptions struct {
+	defaultImplementationOptions
+	nexusCredentials
+
+	// apiUrl is the Nexus REST API url, the docker connector host and port are used by default
+	apiUrl string
+	// repository is the Nexus repository the docker connector serves, the search is limited by this repository
+	repository string
+}
+
+type nexusCredentials struct {
+	username string
+	password string
+}
+
+func newNexus(options nexusOptions) (*nexus, error) {
+	d, err := newDefaultImplementation(options.defaultImplementationOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	nexus := &nexus{
+		defaultImplementation: d,
+		nexusApi:              newNexusApi(),
+		nexusCredentials:      options.nexusCredentials,
+		apiUrl:                strings.TrimSuffix(options.apiUrl, "/"),
+		repository:            options.repository,
+	}
+
+	return nexus, nil
+}
+
+func (r *nexus) Tags(reference string) ([]string, error) {
+	apiUrl, imageName, username, password, err := r.parseReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	components, _, err := r.nexusApi.searchDockerComponents(apiUrl, r.repository, imageName, "", username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	tagsSet := map[string]bool{}
+	for _, component := range components {
+		if !tagsSet[component.Version] {
+			tagsSet[component.Version] = true
+			tags = append(tags, component.Version)
+		}
+	}
+
+	return tags, nil
+}
+
+func (r *nexus) SelectRepoImageList(reference string, f func(string, *image.Info, error) (bool, error)) ([]*image.Info, error) {
+	tags, err := r.Tags(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.defaultImplementation.selectRepoImageListByTags(reference, tags, f)
+}
+
+func (r *nexus) GetRepoImageList(reference string) ([]*image.Info, error) {
+	return r.SelectRepoImageList(reference, nil)
+}
+
+// CreateRepo does nothing: the image is created in the Nexus repository on push
+func (r *nexus) CreateRepo(_ string) error {
+	return nil
+}
+
+func (r *nexus) DeleteRepo(reference string) error {
+	apiUrl, imageName, username, password, err := r.parseReference(reference)
+	if err != nil {
+		return err
+	}
+
+	components, _, err := r.nexusApi.searchDockerComponents(apiUrl, r.repository, imageName, "", username, password)
+	if err != nil {
+		return err
+	}
+
+	if len(components) == 0 {
+		return NexusNotFoundError{error: fmt.Errorf("image %s not found in Nexus repository %s", reference, r.repository)}
+	}
+
+	for _, component := range components {
+		if err := r.deleteComponent(apiUrl, component, username, password); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *nexus) DeleteRepoImage(repoImageList ...*image.Info) error {
+	for _, repoImage := range repoImageList {
+		if err := r.deleteRepoImage(repoImage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *nexus) deleteRepoImage(repoImage *image.Info) error {
+	apiUrl, imageName, username, password, err := r.parseReference(repoImage.Repository)
+	if err != nil {
+		return err
+	}
+
+	components, _, err := r.nexusApi.searchDockerComponents(apiUrl, r.repository, imageName, repoImage.Tag, username, password)
+	if err != nil {
+		return err
+	}
+
+	// the tag could be moved to another manifest since the image info has been received
+	for _, component := range components {
+		if "sha256:"+component.manifestSha256() == repoImage.RepoDigest {
+			return r.deleteComponent(apiUrl, component, username, password)
+		}
+	}
+
+	return NexusNotFoundError{error: fmt.Errorf("image %s:%s@%s not found in Nexus repository %s", repoImage.Repository, repoImage.Tag, repoImage.RepoDigest, r.repository)}
+}
+
+func (r *nexus) deleteComponent(apiUrl string, component nexusComponent, username, password string) error {
+	resp, err := r.nexusApi.deleteComponent(apiUrl, component.Id, username, password)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return NexusNotFoundError{error: err}
+	}
+
+	return err
+}
+
+func (r *nexus) String() string {
+	return NexusImplementationName
+}
+
+// parseReference returns the Nexus API url, the image name and the credentials (the docker config credentials are used by default).
+// The docker repository could be served by the docker connector on the separate port or host, so the API url is configurable
+func (r *nexus) parseReference(reference string) (string, string, string, string, error) {
+	// the same image name can exist in other repositories of the Nexus server, the search without the repository is not safe
+	if r.repository == "" {
+		return "", "", "", "", fmt.Errorf("Nexus repository of %s should be specified with --repo-nexus-repository option (or $WERF_REPO_NEXUS_REPOSITORY) to list and delete images", reference)
+	}
+
+	parsedReference, err := name.NewRepository(reference, r.api.parseReferenceOptions()...)
+	if err != nil {
+		return "", "", "", "", err
+	}
+
+	username, password := r.nexusCredentials.username, r.nexusCredentials.password
+	if username == "" && password == "" {
+		username, password, err = getKeychainCredentials(parsedReference.Registry)
+		if err != nil {
+			return "", "", "", "", err
+		}
+	}
+
+	apiUrl := r.apiUrl
+	if apiUrl == "" {
+		apiUrl = fmt.Sprintf("%s://%s", parsedReference.Registry.Scheme(), parsedReference.RegistryStr())
+	}
+
+	return apiUrl, parsedReference.RepositoryStr(), username, password, nil
+}
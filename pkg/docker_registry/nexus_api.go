@@ -0,0 +1,106 @@
+package docker_registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type nexusApi struct{}
+
+func newNexusApi() nexusApi {
+	return nexusApi{}
+}
+
+type nexusComponent struct {
+	Id         string `json:"id"`
+	Repository string `json:"repository"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Assets     []struct {
+		Path     string `json:"path"`
+		Checksum struct {
+			Sha256 string `json:"sha256"`
+		} `json:"checksum"`
+	} `json:"assets"`
+}
+
+// manifestSha256 returns the checksum of the manifest asset, that is the manifest digest without the algorithm
+func (c nexusComponent) manifestSha256() string {
+	for _, asset := range c.Assets {
+		if asset.Path == fmt.Sprintf("v2/%s/manifests/%s", c.Name, c.Version) {
+			return asset.Checksum.Sha256
+		}
+	}
+
+	return ""
+}
+
+// searchDockerComponents returns the components of the image in the Nexus repository, all tags are returned if the version is not specified
+func (api *nexusApi) searchDockerComponents(apiUrl, repository, imageName, version, username, password string) ([]nexusComponent, *http.Response, error) {
+	var components []nexusComponent
+	var continuationToken string
+
+	for {
+		query := url.Values{}
+		query.Set("format", "docker")
+		query.Set("repository", repository)
+		query.Set("name", imageName)
+		if version != "" {
+			query.Set("version", version)
+		}
+		if continuationToken != "" {
+			query.Set("continuationToken", continuationToken)
+		}
+
+		reqUrl := fmt.Sprintf("%s/service/rest/v1/search?%s", apiUrl, query.Encode())
+		resp, respBody, err := doRequest(http.MethodGet, reqUrl, nil, doRequestOptions{
+			Headers: map[string]string{
+				"Accept": "application/json",
+			},
+			BasicAuth: doRequestBasicAuth{
+				username: username,
+				password: password,
+			},
+			AcceptedCodes: []int{http.StatusOK},
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+
+		respJson := &struct {
+			Items             []nexusComponent `json:"items"`
+			ContinuationToken string           `json:"continuationToken"`
+		}{}
+
+		if err := json.Unmarshal(respBody, respJson); err != nil {
+			return nil, resp, fmt.Errorf("unexpected body %s", string(respBody))
+		}
+
+		for _, component := range respJson.Items {
+			// search by name is not strict in some versions of Nexus
+			if component.Repository == repository && component.Name == imageName && (version == "" || component.Version == version) {
+				components = append(components, component)
+			}
+		}
+
+		if respJson.ContinuationToken == "" {
+			return components, resp, nil
+		}
+		continuationToken = respJson.ContinuationToken
+	}
+}
+
+func (api *nexusApi) deleteComponent(apiUrl, id, username, password string) (*http.Response, error) {
+	reqUrl := fmt.Sprintf("%s/service/rest/v1/components/%s", apiUrl, url.PathEscape(id))
+	resp, _, err := doRequest(http.MethodDelete, reqUrl, nil, doRequestOptions{
+		BasicAuth: doRequestBasicAuth{
+			username: username,
+			password: password,
+		},
+		AcceptedCodes: []int{http.StatusOK, http.StatusNoContent},
+	})
+
+	return resp, err
+}
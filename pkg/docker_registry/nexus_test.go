@@ -0,0 +1,143 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/flant/werf/pkg/image"
+)
+
+var _ = Describe("nexus", func() {
+	var server *httptest.Server
+	var registry *nexus
+	var deletedComponents []string
+
+	BeforeEach(func() {
+		deletedComponents = nil
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/service/rest/v1/search", func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+
+			username, password, _ := req.BasicAuth()
+			Ω(username).Should(Equal("user"))
+			Ω(password).Should(Equal("pass"))
+			Ω(req.URL.Query().Get("format")).Should(Equal("docker"))
+			Ω(req.URL.Query().Get("repository")).Should(Equal("docker-hosted"))
+			Ω(req.URL.Query().Get("name")).Should(Equal("group/app"))
+
+			// search by name and repository is not strict in some versions of Nexus
+			switch {
+			case req.URL.Query().Get("version") == "v1":
+				fmt.Fprint(w, `{"items":[
+					{"id":"hosted-v1","repository":"docker-hosted","name":"group/app","version":"v1","assets":[{"path":"v2/group/app/manifests/v1","checksum":{"sha256":"aaa"}}]},
+					{"id":"other-v1","repository":"docker-other","name":"group/app","version":"v1","assets":[{"path":"v2/group/app/manifests/v1","checksum":{"sha256":"bbb"}}]}
+				],"continuationToken":null}`)
+			case req.URL.Query().Get("continuationToken") == "":
+				fmt.Fprint(w, `{"items":[
+					{"id":"hosted-v1","repository":"docker-hosted","name":"group/app","version":"v1"},
+					{"id":"hosted-app-v2","repository":"docker-hosted","name":"group/app-other","version":"v2"},
+					{"id":"other-v4","repository":"docker-other","name":"group/app","version":"v4"}
+				],"continuationToken":"next"}`)
+			default:
+				fmt.Fprint(w, `{"items":[{"id":"hosted-v3","repository":"docker-hosted","name":"group/app","version":"v3"}],"continuationToken":null}`)
+			}
+		})
+		mux.HandleFunc("/service/rest/v1/components/", func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+
+			Ω(req.Method).Should(Equal(http.MethodDelete))
+			deletedComponents = append(deletedComponents, strings.TrimPrefix(req.URL.Path, "/service/rest/v1/components/"))
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		server = httptest.NewServer(mux)
+
+		var err error
+		registry, err = newNexus(nexusOptions{nexusCredentials: nexusCredentials{username: "user", password: "pass"}, repository: "docker-hosted"})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists tags of the image in the Nexus repository with all pages of search results", func() {
+		tags, err := registry.Tags(registryHost(server) + "/group/app")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"v1", "v3"}))
+	})
+
+	It("deletes the component of the Nexus repository with the same manifest", func() {
+		err := registry.DeleteRepoImage(&image.Info{Repository: registryHost(server) + "/group/app", Tag: "v1", RepoDigest: "sha256:aaa"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(deletedComponents).Should(Equal([]string{"hosted-v1"}))
+	})
+
+	It("does not delete the component of another Nexus repository", func() {
+		err := registry.DeleteRepoImage(&image.Info{Repository: registryHost(server) + "/group/app", Tag: "v1", RepoDigest: "sha256:bbb"})
+		Ω(err).Should(BeAssignableToTypeOf(NexusNotFoundError{}))
+		Ω(deletedComponents).Should(BeEmpty())
+	})
+
+	It("deletes the components of the image only in the Nexus repository", func() {
+		Ω(registry.DeleteRepo(registryHost(server) + "/group/app")).Should(Succeed())
+		Ω(deletedComponents).Should(Equal([]string{"hosted-v1", "hosted-v3"}))
+	})
+
+	It("requires the Nexus repository to list and delete images", func() {
+		registry, err := newNexus(nexusOptions{nexusCredentials: nexusCredentials{username: "user", password: "pass"}})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = registry.Tags(registryHost(server) + "/group/app")
+		Ω(err).Should(MatchError(ContainSubstring("Nexus repository of")))
+
+		Ω(registry.DeleteRepo(registryHost(server) + "/group/app")).ShouldNot(Succeed())
+		Ω(deletedComponents).Should(BeEmpty())
+	})
+
+	It("uses the API url option if the docker connector is served on the separate host or port", func() {
+		registry, err := newNexus(nexusOptions{nexusCredentials: nexusCredentials{username: "user", password: "pass"}, apiUrl: server.URL + "/", repository: "docker-hosted"})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		tags, err := registry.Tags("docker.example.com:8443/group/app")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"v1", "v3"}))
+	})
+
+	It("returns not found error if the manifest is not found in the Nexus repository", func() {
+		err := registry.DeleteRepoImage(&image.Info{Repository: registryHost(server) + "/group/app", Tag: "v1", RepoDigest: "sha256:ccc"})
+		Ω(err).Should(BeAssignableToTypeOf(NexusNotFoundError{}))
+		Ω(deletedComponents).Should(BeEmpty())
+	})
+})
+
+var _ = Describe("nexus detection", func() {
+	It("detects Nexus by the host", func() {
+		dockerRegistry, err := NewDockerRegistry("nexus.example.com/group/app", "", DockerRegistryOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(dockerRegistry.String()).Should(Equal(NexusImplementationName))
+	})
+
+	It("detects Nexus on the custom host by the API url option", func() {
+		dockerRegistry, err := NewDockerRegistry("registry.example.com:8443/group/app", "", DockerRegistryOptions{NexusApiUrl: "https://repo.example.com"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(dockerRegistry.String()).Should(Equal(NexusImplementationName))
+		Ω(dockerRegistry.(*nexus).apiUrl).Should(Equal("https://repo.example.com"))
+	})
+
+	It("does not override the well-known registry by the API url option", func() {
+		dockerRegistry, err := NewDockerRegistry("quay.io/group/app", "", DockerRegistryOptions{NexusApiUrl: "https://repo.example.com"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(dockerRegistry.String()).Should(Equal(QuayImplementationName))
+	})
+})
+
+func registryHost(server *httptest.Server) string {
+	return strings.TrimPrefix(server.URL, "http://")
+}
@@ -104,6 +104,45 @@ var _ = DescribeTable("resolve implementation name and repo mode", func(entry en
 		expectedRepoMode:           "multirepo",
 	}),
 
+	Entry("[nexus] registry -> multirepo", entry{
+		imagesRepoAddress:          "nexus.company.com/group",
+		expectedImplementationName: "nexus",
+		expectedRepoMode:           "multirepo",
+	}),
+
+	Entry("[artifactory] registry -> multirepo", entry{
+		imagesRepoAddress:          "company.jfrog.io/docker-local",
+		expectedImplementationName: "artifactory",
+		expectedRepoMode:           "multirepo",
+	}),
+	Entry("[artifactory] repository -> monorepo", entry{
+		imagesRepoAddress:          "artifactory.company.com/docker-local/app",
+		expectedImplementationName: "artifactory",
+		expectedRepoMode:           "monorepo",
+	}),
+
+	Entry("[gar] registry -> multirepo", entry{
+		imagesRepoAddress:          "europe-west1-docker.pkg.dev/project/repo",
+		expectedImplementationName: "gar",
+		expectedRepoMode:           "multirepo",
+	}),
+	Entry("[gar] repository -> monorepo", entry{
+		imagesRepoAddress:          "europe-west1-docker.pkg.dev/project/repo/app",
+		expectedImplementationName: "gar",
+		expectedRepoMode:           "monorepo",
+	}),
+
+	Entry("[alibaba-acr] registry -> multirepo", entry{
+		imagesRepoAddress:          "registry.cn-hangzhou.aliyuncs.com/namespace",
+		expectedImplementationName: "alibaba-acr",
+		expectedRepoMode:           "multirepo",
+	}),
+	Entry("[alibaba-acr] repository -> monorepo", entry{
+		imagesRepoAddress:          "registry-intl-vpc.ap-southeast-1.aliyuncs.com/namespace/app",
+		expectedImplementationName: "alibaba-acr",
+		expectedRepoMode:           "monorepo",
+	}),
+
 	Entry("[quay] registry -> multirepo", entry{
 		imagesRepoAddress:          "quay.io/account",
 		expectedImplementationName: "quay",
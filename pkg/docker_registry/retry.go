@@ -0,0 +1,102 @@
+package docker_registry
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/tracing"
+)
+
+const (
+	retryMaxAttempts  = 5
+	retryInitialDelay = time.Second
+	retryMaxDelay     = 30 * time.Second
+)
+
+// retryTransport retries idempotent requests rejected by the registry rate limit (429) or failed with 5xx,
+// the delay grows exponentially unless the registry specifies Retry-After
+type retryTransport struct {
+	inner http.RoundTripper
+}
+
+func newRetryTransport(inner http.RoundTripper) http.RoundTripper {
+	return &retryTransport{inner: inner}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	span := tracing.StartConcurrentSpan(fmt.Sprintf("registry %s", req.Method), map[string]string{
+		"http.method": req.Method,
+		"http.host":   req.URL.Host,
+		"http.path":   req.URL.Path,
+	})
+	defer func() {
+		if resp != nil {
+			span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
+		}
+		span.End(err)
+	}()
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.inner.RoundTrip(req)
+	}
+
+	delay := retryInitialDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := t.inner.RoundTrip(req)
+		if err != nil || attempt == retryMaxAttempts || !isRetryableStatusCode(resp.StatusCode) {
+			span.SetAttribute("http.attempts", strconv.Itoa(attempt))
+			return resp, err
+		}
+
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+			delay = retryAfter
+		}
+		if delay > retryMaxDelay {
+			delay = retryMaxDelay
+		}
+
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		logboek.Debug.LogF("Retrying %s %s in %s: registry responded %s (attempt %d/%d)\n", req.Method, req.URL, delay, resp.Status, attempt, retryMaxAttempts)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
+
+func isRetryableStatusCode(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+
+	return 0
+}
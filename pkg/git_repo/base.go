@@ -5,13 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/flant/logboek"
 	"github.com/flant/werf/pkg/path_matcher"
 	"github.com/flant/werf/pkg/true_git"
 	"github.com/flant/werf/pkg/true_git/ls_tree"
+	"github.com/flant/werf/pkg/util"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -149,6 +152,7 @@ func (repo *Base) createPatch(repoPath, gitDir, workTreeCacheDir string, opts Pa
 		),
 		WithEntireFileContext: opts.WithEntireFileContext,
 		WithBinary:            opts.WithBinary,
+		LFS:                   opts.LFS,
 	}
 
 	var desc *true_git.PatchDescriptor
@@ -263,6 +267,7 @@ func (repo *Base) createArchive(repoPath, gitDir, workTreeCacheDir string, opts
 			opts.ExcludePaths,
 			true,
 		),
+		LFS: opts.LFS,
 	}
 
 	var desc *true_git.ArchiveDescriptor
@@ -447,6 +452,14 @@ func (repo *Base) checksumWithLsTree(repoPath, gitDir, workTreeCacheDir string,
 				})
 			}
 
+			if pathChecksum != "" && opts.LFS {
+				lfsChecksum, err := lfsObjectsChecksum(repositoryWithPreparedWorktree, pathLsTreeResult)
+				if err != nil {
+					return err
+				}
+				pathChecksum += lfsChecksum
+			}
+
 			if pathChecksum != "" {
 				checksum.Hash.Write([]byte(pathChecksum))
 			} else {
@@ -463,3 +476,51 @@ func (repo *Base) checksumWithLsTree(repoPath, gitDir, workTreeCacheDir string,
 
 	return checksum, nil
 }
+
+// lfsObjectsChecksum returns the checksum of LFS objects ids of the pointer files,
+// files of the submodules are not resolved
+func lfsObjectsChecksum(repository *git.Repository, lsTreeResult *ls_tree.Result) (string, error) {
+	var oids []string
+
+	if err := lsTreeResult.Walk(func(lsTreeEntry *ls_tree.LsTreeEntry) error {
+		if !lsTreeEntry.Mode.IsFile() {
+			return nil
+		}
+
+		blob, err := repository.BlobObject(lsTreeEntry.Hash)
+		if err == plumbing.ErrObjectNotFound {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to get blob %s of file %s: %s", lsTreeEntry.Hash, lsTreeEntry.FullFilepath, err)
+		}
+
+		if blob.Size > true_git.LFSPointerMaxSize {
+			return nil
+		}
+
+		reader, err := blob.Reader()
+		if err != nil {
+			return fmt.Errorf("unable to read blob %s of file %s: %s", lsTreeEntry.Hash, lsTreeEntry.FullFilepath, err)
+		}
+		defer reader.Close()
+
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("unable to read blob %s of file %s: %s", lsTreeEntry.Hash, lsTreeEntry.FullFilepath, err)
+		}
+
+		if pointer := true_git.ParseLFSPointer(data); pointer != nil {
+			oids = append(oids, fmt.Sprintf("%s:%s", filepath.ToSlash(lsTreeEntry.FullFilepath), pointer.Oid))
+		}
+
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	if len(oids) == 0 {
+		return "", nil
+	}
+
+	return util.Sha256Hash(oids...), nil
+}
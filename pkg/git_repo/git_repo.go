@@ -14,17 +14,20 @@ type PatchOptions struct {
 
 	WithEntireFileContext bool
 	WithBinary            bool
+	LFS                   bool
 }
 
 type ArchiveOptions struct {
 	FilterOptions
 	Commit string
+	LFS    bool
 }
 
 type ChecksumOptions struct {
 	FilterOptions
 	Paths  []string
 	Commit string
+	LFS    bool
 }
 
 type FilterOptions struct {
@@ -20,6 +20,8 @@ type Local struct {
 	Base
 	Path   string
 	GitDir string
+
+	devHeadCommit string
 }
 
 func OpenLocalRepo(name string, path string) (*Local, error) {
@@ -110,6 +112,69 @@ func (repo *Local) HeadCommit() (string, error) {
 	return repo.getHeadCommit(repo.Path)
 }
 
+// DevHeadCommit returns an unreferenced commit on top of the head commit which contains all tracked and untracked changes of the work tree.
+// The head commit is returned if the work tree is clean.
+func (repo *Local) DevHeadCommit() (string, error) {
+	if repo.devHeadCommit != "" {
+		return repo.devHeadCommit, nil
+	}
+
+	headCommit, err := repo.HeadCommit()
+	if err != nil {
+		return "", err
+	}
+
+	statusResult, err := repo.Status(path_matcher.NewSimplePathMatcher("", []string{}, false))
+	if err != nil {
+		return "", fmt.Errorf("unable to get status of repo %s: %s", repo.Path, err)
+	}
+
+	absRepoPath, err := filepath.Abs(repo.Path)
+	if err != nil {
+		return "", fmt.Errorf("unable to get absolute path for repo %s: %s", repo.Path, err)
+	}
+
+	var absPaths []string
+	for _, path := range statusResult.FilePathList() {
+		absPaths = append(absPaths, filepath.Join(absRepoPath, filepath.FromSlash(path)))
+	}
+
+	checkIgnoreResult, err := repo.CheckIgnore(absPaths)
+	if err != nil {
+		return "", fmt.Errorf("unable to check ignored files of repo %s: %s", repo.Path, err)
+	}
+
+	ignoredAbsPaths := map[string]bool{}
+	for _, absPath := range checkIgnoreResult.IgnoredFilesPaths() {
+		ignoredAbsPaths[absPath] = true
+	}
+
+	var paths []string
+	for _, absPath := range absPaths {
+		if ignoredAbsPaths[absPath] {
+			continue
+		}
+
+		relPath, err := filepath.Rel(absRepoPath, absPath)
+		if err != nil {
+			return "", err
+		}
+
+		paths = append(paths, filepath.ToSlash(relPath))
+	}
+
+	devHeadCommit, err := true_git.CreateDevCommit(repo.GitDir, repo.Path, headCommit, true_git.CreateDevCommitOptions{
+		Paths: paths,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to create dev commit for repo %s: %s", repo.Path, err)
+	}
+
+	repo.devHeadCommit = devHeadCommit
+
+	return devHeadCommit, nil
+}
+
 func (repo *Local) IsHeadReferenceExist() (bool, error) {
 	_, err := repo.getHeadCommit(repo.Path)
 	if err == errHeadNotFound {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"gopkg.in/ini.v1"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 
@@ -280,6 +282,15 @@ func (repo *Remote) CreatePatch(opts PatchOptions) (Patch, error) {
 }
 
 func (repo *Remote) CreateArchive(opts ArchiveOptions) (Archive, error) {
+	if opts.LFS && !repo.IsDryRun {
+		if err := repo.withRemoteRepoLock(func() error {
+			logboek.Default.LogFDetails("Fetch LFS objects of commit %s of %s\n", opts.Commit, repo.Url)
+			return true_git.LFSFetch(repo.GetClonePath(), opts.Commit)
+		}); err != nil {
+			return nil, fmt.Errorf("unable to fetch LFS objects of repo %s: %s", repo.String(), err)
+		}
+	}
+
 	return repo.createArchive(repo.GetClonePath(), repo.GetClonePath(), repo.getWorkTreeCacheDir(), opts)
 }
 
@@ -300,6 +311,54 @@ func (repo *Remote) IsCommitExists(commit string) (bool, error) {
 	return repo.isCommitExists(repo.GetClonePath(), repo.GetClonePath(), commit)
 }
 
+// ReadCommitFiles returns contents of the regular files from the commit tree located in the basePath,
+// file paths are relative to the basePath
+func (repo *Remote) ReadCommitFiles(commit, basePath string) (map[string][]byte, error) {
+	rawRepo, err := git.PlainOpenWithOptions(repo.GetClonePath(), &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open repo: %s", err)
+	}
+
+	commitObj, err := rawRepo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("bad commit '%s' of repo %s: %s", commit, repo.String(), err)
+	}
+
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get commit '%s' tree of repo %s: %s", commit, repo.String(), err)
+	}
+
+	basePath = strings.Trim(filepath.ToSlash(basePath), "/")
+
+	res := map[string][]byte{}
+	if err := tree.Files().ForEach(func(f *object.File) error {
+		if !f.Mode.IsFile() {
+			return nil
+		}
+
+		relPath := f.Name
+		if basePath != "" {
+			if !strings.HasPrefix(f.Name, basePath+"/") {
+				return nil
+			}
+			relPath = strings.TrimPrefix(f.Name, basePath+"/")
+		}
+
+		contents, err := f.Contents()
+		if err != nil {
+			return fmt.Errorf("cannot read file '%s': %s", f.Name, err)
+		}
+		res[relPath] = []byte(contents)
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("cannot read commit '%s' files of repo %s: %s", commit, repo.String(), err)
+	}
+
+	return res, nil
+}
+
 func (repo *Remote) getWorkTreeCacheDir() string {
 	return filepath.Join(GetWorkTreeCacheDir(), repo.getFilesystemRelativePathByEndpoint())
 }
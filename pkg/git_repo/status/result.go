@@ -307,6 +307,22 @@ func (r *Result) Checksum() (string, error) {
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
+// FilePathList returns changed and untracked file paths of the main repository relative to its root.
+// Submodule changes are not included.
+func (r *Result) FilePathList() []string {
+	var paths []string
+	for fileStatusPath, fileStatus := range r.fileStatusList {
+		paths = append(paths, fileStatusPath)
+		if fileStatus.Staging == git.Renamed && fileStatus.Extra != "" {
+			paths = append(paths, fileStatus.Extra)
+		}
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
 func (r *Result) IsEmpty() bool {
 	return len(r.fileStatusList) == 0 && len(r.submoduleResults) == 0
 }
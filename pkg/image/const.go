@@ -9,6 +9,8 @@ const (
 	WerfImageTagLabel       = "werf-image-tag"
 	WerfDockerImageName     = "werf-docker-image-name"
 	WerfStageSignatureLabel = "werf-stage-signature"
+	WerfStagePlatformLabel  = "werf-stage-platform"
+	WerfStageNameLabel      = "werf-stage-name"
 
 	WerfMountTmpDirLabel          = "werf-mount-type-tmp-dir"
 	WerfMountBuildDirLabel        = "werf-mount-type-build-dir"
@@ -16,7 +16,7 @@ import (
 )
 
 const (
-	ManifestCacheVersion = "1"
+	ManifestCacheVersion = "2"
 )
 
 type ManifestCache struct {
@@ -26,6 +26,8 @@ type ManifestCache struct {
 type ManifestCacheRecord struct {
 	AccessTimestamp int64
 	Info            *Info
+	// ManifestDigest is the digest of the manifest (or the manifest list) of the image name at the moment the info was stored
+	ManifestDigest string `json:",omitempty"`
 }
 
 func NewManifestCache(cacheDir string) *ManifestCache {
@@ -33,6 +35,15 @@ func NewManifestCache(cacheDir string) *ManifestCache {
 }
 
 func (cache *ManifestCache) GetImageInfo(imageName string) (*Info, error) {
+	return cache.getImageInfo(imageName, "")
+}
+
+// GetImageInfoByManifestDigest returns the image info only if it was stored for the same manifest digest
+func (cache *ManifestCache) GetImageInfoByManifestDigest(imageName, manifestDigest string) (*Info, error) {
+	return cache.getImageInfo(imageName, manifestDigest)
+}
+
+func (cache *ManifestCache) getImageInfo(imageName, manifestDigest string) (*Info, error) {
 	if lock, err := cache.lock(imageName); err != nil {
 		return nil, err
 	} else {
@@ -43,7 +54,7 @@ func (cache *ManifestCache) GetImageInfo(imageName string) (*Info, error) {
 
 	if record, err := cache.readRecord(imageName); err != nil {
 		return nil, err
-	} else if record != nil {
+	} else if record != nil && (manifestDigest == "" || record.ManifestDigest == manifestDigest) {
 		record.AccessTimestamp = now.Unix()
 		if err := cache.writeRecord(record); err != nil {
 			return nil, err
@@ -55,6 +66,10 @@ func (cache *ManifestCache) GetImageInfo(imageName string) (*Info, error) {
 }
 
 func (cache *ManifestCache) StoreImageInfo(imgInfo *Info) error {
+	return cache.StoreImageInfoWithManifestDigest(imgInfo, "")
+}
+
+func (cache *ManifestCache) StoreImageInfoWithManifestDigest(imgInfo *Info, manifestDigest string) error {
 	if lock, err := cache.lock(imgInfo.Name); err != nil {
 		return err
 	} else {
@@ -64,6 +79,7 @@ func (cache *ManifestCache) StoreImageInfo(imgInfo *Info) error {
 	record := &ManifestCacheRecord{
 		AccessTimestamp: time.Now().Unix(),
 		Info:            imgInfo,
+		ManifestDigest:  manifestDigest,
 	}
 	return cache.writeRecord(record)
 }
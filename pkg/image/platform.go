@@ -0,0 +1,59 @@
+package image
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+const platformSignatureSeparator = "_"
+
+// PlatformSignature returns the signature of the platform variant of the stage.
+// All platform variants share the logical signature of the stage and differ only by the platform suffix,
+// the signature is not changed for the stage of the image without platforms.
+func PlatformSignature(logicalSignature, platform string) string {
+	if platform == "" {
+		return logicalSignature
+	}
+
+	return strings.Join([]string{logicalSignature, strings.ReplaceAll(platform, "/", platformSignatureSeparator)}, platformSignatureSeparator)
+}
+
+// ParsePlatformSignature returns the logical signature and the platform of the stage signature
+func ParsePlatformSignature(signature string) (string, string) {
+	parts := strings.SplitN(signature, platformSignatureSeparator, 2)
+	if len(parts) != 2 {
+		return signature, ""
+	}
+
+	return parts[0], strings.ReplaceAll(parts[1], platformSignatureSeparator, "/")
+}
+
+func LogicalSignature(signature string) string {
+	logicalSignature, _ := ParsePlatformSignature(signature)
+	return logicalSignature
+}
+
+// ParsePlatform parses platform in the os/arch[/variant] format
+func ParsePlatform(platform string) (os, arch, variant string, err error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", "", "", fmt.Errorf("invalid platform %q: expected os/arch[/variant]", platform)
+	}
+
+	for _, part := range parts {
+		if part == "" || strings.ContainsAny(part, platformSignatureSeparator+"-:@ ") {
+			return "", "", "", fmt.Errorf("invalid platform %q: expected os/arch[/variant]", platform)
+		}
+	}
+
+	if len(parts) == 3 {
+		variant = parts[2]
+	}
+
+	return parts[0], parts[1], variant, nil
+}
+
+func HostPlatform() string {
+	return fmt.Sprintf("linux/%s", runtime.GOARCH)
+}
@@ -0,0 +1,37 @@
+package image
+
+import "testing"
+
+func TestPlatformSignature(t *testing.T) {
+	for _, test := range []struct {
+		logicalSignature, platform, expected string
+	}{
+		{"1c2d3e", "", "1c2d3e"},
+		{"1c2d3e", "linux/amd64", "1c2d3e_linux_amd64"},
+		{"1c2d3e", "linux/arm/v7", "1c2d3e_linux_arm_v7"},
+	} {
+		signature := PlatformSignature(test.logicalSignature, test.platform)
+		if signature != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, signature)
+		}
+
+		logicalSignature, platform := ParsePlatformSignature(signature)
+		if logicalSignature != test.logicalSignature || platform != test.platform {
+			t.Errorf("expected %q and %q, got %q and %q", test.logicalSignature, test.platform, logicalSignature, platform)
+		}
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	for _, platform := range []string{"linux", "linux/", "linux/arm/v7/extra", "linux/arm_64", "linux/arm-64"} {
+		if _, _, _, err := ParsePlatform(platform); err == nil {
+			t.Errorf("expected error for platform %q", platform)
+		}
+	}
+
+	if os, arch, variant, err := ParsePlatform("linux/arm64/v8"); err != nil {
+		t.Error(err)
+	} else if os != "linux" || arch != "arm64" || variant != "v8" {
+		t.Errorf("unexpected result %q %q %q", os, arch, variant)
+	}
+}
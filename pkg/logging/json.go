@@ -0,0 +1,444 @@
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/tracing"
+	"github.com/flant/werf/pkg/util/secretvalues"
+)
+
+const (
+	LogEventTypeLog          = "log"
+	LogEventTypeProcessStart = "process_start"
+	LogEventTypeProcessEnd   = "process_end"
+	LogEventTypeProcessFail  = "process_fail"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// LogEvent is the single line of the json log format
+type LogEvent struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Type    string    `json:"type"`
+	Message string    `json:"message,omitempty"`
+	Process string    `json:"process,omitempty"`
+	Image   string    `json:"image,omitempty"`
+	Stage   string    `json:"stage,omitempty"`
+	Phase   string    `json:"phase,omitempty"`
+	// Duration of the process in seconds
+	Duration *float64 `json:"duration,omitempty"`
+}
+
+var jsonLog *jsonLogOutput
+
+type jsonLogOutput struct {
+	mutex     sync.Mutex
+	handler   func(event *LogEvent)
+	writers   map[logboek.Level]*logEventsWriter
+	processes []*logEventsProcess
+
+	// logboek level streams are parsed separately from the raw output to track logboek processes by their borders
+	logboekWriters map[logboek.Level]*logEventsWriter
+
+	// logboek writes some output (e.g. kubedog status tables) into its own out stream, which can only be os.Stdout
+	logboekOutPipe       *os.File
+	logboekOutPipeClosed chan struct{}
+}
+
+type logEventsProcess struct {
+	name      string
+	startTime time.Time
+	image     string
+	stage     string
+	phase     string
+
+	// span is set for the trace span process, otherwise the process is the logboek process or block
+	span     *tracing.Span
+	isInline bool
+}
+
+// EnableJSONLogFormat replaces the human readable output with the stream of json log events (one event per line):
+// every log line and every start, end or fail of the process.
+// Processes are the trace spans (command, conveyor phases, stage builds, helm deploy etc.) and the logboek processes and blocks.
+// Logboek process borders are kept in the logboek output to track logboek processes and are stripped from the log lines,
+// the raw output is not parsed and only gets the context of the innermost process.
+func EnableJSONLogFormat() error {
+	if jsonLog != nil {
+		return nil
+	}
+
+	imageNameFormat = "image %s"
+	artifactNameFormat = "artifact %s"
+
+	logboek.DisableLogColor()
+	logboek.RawStreamsOutputModeOn()
+	logboek.DisableFitMode()
+	logboek.ResetPrefix()
+	// do not truncate process messages
+	logboek.SetWidth(1 << 20)
+
+	output := newJSONLogOutput(newJSONLogEventsHandler(os.Stdout))
+
+	if !isOutMuted {
+		if err := output.captureLogboekOut(); err != nil {
+			return err
+		}
+	}
+
+	jsonLog = output
+	tracing.SetSpanHook(output.handleSpan)
+
+	return nil
+}
+
+func newJSONLogOutput(handler func(event *LogEvent)) *jsonLogOutput {
+	output := &jsonLogOutput{
+		handler:        handler,
+		writers:        map[logboek.Level]*logEventsWriter{},
+		logboekWriters: map[logboek.Level]*logEventsWriter{},
+	}
+
+	for _, level := range logLevels {
+		output.writers[level] = &logEventsWriter{output: output, level: level.String()}
+		output.logboekWriters[level] = &logEventsWriter{output: output, level: level.String(), isLogboekOutput: true}
+	}
+
+	return output
+}
+
+// captureLogboekOut points the logboek out stream to the pipe: os.Stdout is replaced only for the logboek.UnmuteOut call
+func (output *jsonLogOutput) captureLogboekOut() error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	logboek.UnmuteOut()
+	os.Stdout = stdout
+
+	output.logboekOutPipe = w
+	output.logboekOutPipeClosed = make(chan struct{})
+
+	writer := output.writers[logboek.Default]
+	go func() {
+		defer close(output.logboekOutPipeClosed)
+		_, _ = io.Copy(writer, r)
+		_ = r.Close()
+	}()
+
+	return nil
+}
+
+func shutdownJSONLogFormat() {
+	if jsonLog == nil {
+		return
+	}
+
+	output := jsonLog
+
+	tracing.SetSpanHook(nil)
+
+	if output.logboekOutPipe != nil {
+		logboek.UnmuteOut()
+		_ = output.logboekOutPipe.Close()
+
+		select {
+		case <-output.logboekOutPipeClosed:
+		case <-time.After(shutdownTimeout):
+		}
+	}
+
+	for _, w := range output.writers {
+		w.flush()
+	}
+
+	for _, w := range output.logboekWriters {
+		w.flush()
+	}
+
+	jsonLog = nil
+}
+
+func (output *jsonLogOutput) writer(level logboek.Level, isLogboekOutput bool) io.Writer {
+	if isLogboekOutput {
+		return output.logboekWriters[level]
+	}
+
+	return output.writers[level]
+}
+
+func (output *jsonLogOutput) handleSpan(span *tracing.Span, isEnd bool) {
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+
+	if !isEnd {
+		process := output.newProcess(span.Name)
+		process.span = span
+		process.startTime = span.StartTime
+
+		if value := span.Attributes["image"]; value != "" {
+			process.image = value
+		}
+		if value := span.Attributes["stage"]; value != "" {
+			process.stage = value
+		}
+		if value := span.Attributes["phase"]; value != "" {
+			process.phase = value
+		}
+
+		output.startProcess(process, logboek.Default.String())
+
+		return
+	}
+
+	for ind := len(output.processes) - 1; ind >= 0; ind-- {
+		if output.processes[ind].span != span {
+			continue
+		}
+
+		output.endProcess(ind, logboek.Default.String(), span.Err != nil, span.EndTime)
+
+		return
+	}
+}
+
+// newProcess creates the process which inherits the context of the innermost process
+func (output *jsonLogOutput) newProcess(name string) *logEventsProcess {
+	process := &logEventsProcess{name: name, startTime: time.Now()}
+	if len(output.processes) > 0 {
+		parent := output.processes[len(output.processes)-1]
+		process.image, process.stage, process.phase = parent.image, parent.stage, parent.phase
+	}
+
+	return process
+}
+
+func (output *jsonLogOutput) startProcess(process *logEventsProcess, level string) {
+	output.processes = append(output.processes, process)
+	output.handler(output.newEvent(level, LogEventTypeProcessStart))
+}
+
+// endProcess ends the process with the index ind with all nested processes which have not been ended yet
+func (output *jsonLogOutput) endProcess(ind int, level string, isFailed bool, endTime time.Time) {
+	process := output.processes[ind]
+	output.processes = output.processes[:ind+1]
+
+	eventType := LogEventTypeProcessEnd
+	if isFailed {
+		eventType = LogEventTypeProcessFail
+		level = logboek.Error.String()
+	}
+
+	event := output.newEvent(level, eventType)
+	duration := endTime.Sub(process.startTime).Seconds()
+	event.Duration = &duration
+
+	output.processes = output.processes[:ind]
+	output.handler(event)
+}
+
+// logboekProcessIndex returns the index of the innermost logboek process with the borders or -1
+func (output *jsonLogOutput) logboekProcessIndex() int {
+	for ind := len(output.processes) - 1; ind >= 0; ind-- {
+		if process := output.processes[ind]; process.span == nil && !process.isInline {
+			return ind
+		}
+	}
+
+	return -1
+}
+
+// logboekProcessesDepth returns the number of the logboek process borders
+func (output *jsonLogOutput) logboekProcessesDepth() int {
+	var depth int
+	for _, process := range output.processes {
+		if process.span == nil && !process.isInline {
+			depth++
+		}
+	}
+
+	return depth
+}
+
+func (output *jsonLogOutput) newEvent(level, eventType string) *LogEvent {
+	event := &LogEvent{
+		Time:  time.Now(),
+		Level: level,
+		Type:  eventType,
+	}
+
+	if len(output.processes) > 0 {
+		process := output.processes[len(output.processes)-1]
+		event.Process = process.name
+		event.Image = process.image
+		event.Stage = process.stage
+		event.Phase = process.phase
+	}
+
+	return event
+}
+
+func newJSONLogEventsHandler(w io.Writer) func(event *LogEvent) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return func(event *LogEvent) {
+		event.Message = secretvalues.MaskRegisteredSecretValues(event.Message)
+		event.Process = secretvalues.MaskRegisteredSecretValues(event.Process)
+		_ = encoder.Encode(event)
+	}
+}
+
+const (
+	logboekProcessStartBorder = "┌ "
+	logboekProcessEndBorder   = "└ "
+	logboekProcessStepBorder  = "├ "
+	logboekVerticalBorder     = "│"
+
+	logboekProcessFailedSuffix     = " FAILED"
+	logboekInlineProcessDotsSuffix = " ..."
+)
+
+var (
+	ansiEscapeRegexp = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
+
+	// logboek inline process result, e.g. " (1.25 seconds)" or " (1.25 seconds) FAILED"
+	logboekInlineProcessResultRegexp = regexp.MustCompile(`^\([0-9.]+ seconds\)( FAILED)?$`)
+)
+
+// logEventsWriter produces the log event for every written line
+type logEventsWriter struct {
+	output *jsonLogOutput
+	level  string
+	buf    []byte
+
+	// logboek output lines contain process borders
+	isLogboekOutput bool
+	// logboek inline process which header has been written without the new line
+	inlineProcess *logEventsProcess
+}
+
+func (w *logEventsWriter) Write(data []byte) (int, error) {
+	w.output.mutex.Lock()
+	defer w.output.mutex.Unlock()
+
+	w.buf = append(w.buf, data...)
+	for {
+		ind := strings.IndexAny(string(w.buf), "\r\n")
+		if ind == -1 {
+			break
+		}
+
+		w.logLine(string(w.buf[:ind]))
+		w.buf = w.buf[ind+1:]
+	}
+
+	if w.isLogboekOutput && w.inlineProcess == nil && strings.HasSuffix(string(w.buf), logboekInlineProcessDotsSuffix) {
+		w.startInlineProcess(string(w.buf))
+		w.buf = nil
+	}
+
+	return len(data), nil
+}
+
+func (w *logEventsWriter) flush() {
+	w.output.mutex.Lock()
+	defer w.output.mutex.Unlock()
+
+	if len(w.buf) > 0 {
+		w.logLine(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *logEventsWriter) logLine(line string) {
+	line = ansiEscapeRegexp.ReplaceAllString(line, "")
+	if w.isLogboekOutput {
+		var isProcessBorder bool
+		if line, isProcessBorder = w.handleLogboekLine(line); isProcessBorder {
+			return
+		}
+	}
+
+	text := strings.TrimSpace(line)
+	if text == "" {
+		return
+	}
+
+	event := w.output.newEvent(w.level, LogEventTypeLog)
+	event.Message = text
+	w.output.handler(event)
+}
+
+// handleLogboekLine starts or ends the logboek process if the line is the process header or footer,
+// otherwise returns the line without the process borders
+func (w *logEventsWriter) handleLogboekLine(line string) (string, bool) {
+	depth := w.output.logboekProcessesDepth()
+	rest, bordersNumber := trimLogboekVerticalBorders(line, depth)
+
+	switch {
+	case bordersNumber == depth && strings.HasPrefix(rest, logboekProcessStartBorder):
+		process := w.output.newProcess(strings.TrimSpace(strings.TrimPrefix(rest, logboekProcessStartBorder)))
+		w.output.startProcess(process, w.level)
+		return "", true
+	case bordersNumber == depth-1 && strings.HasPrefix(rest, logboekProcessEndBorder):
+		ind := w.output.logboekProcessIndex()
+		footer := strings.TrimSpace(strings.TrimPrefix(rest, logboekProcessEndBorder))
+		isFailed := strings.HasSuffix(strings.TrimPrefix(footer, w.output.processes[ind].name), logboekProcessFailedSuffix)
+		w.output.endProcess(ind, w.level, isFailed, time.Now())
+		return "", true
+	case bordersNumber == depth-1 && strings.HasPrefix(rest, logboekProcessStepBorder):
+		return strings.TrimPrefix(rest, logboekProcessStepBorder), false
+	case w.inlineProcess != nil && logboekInlineProcessResultRegexp.MatchString(strings.TrimSpace(rest)):
+		w.endInlineProcess(strings.HasSuffix(strings.TrimSpace(rest), logboekProcessFailedSuffix))
+		return "", true
+	}
+
+	return rest, false
+}
+
+// startInlineProcess handles the header of the logboek inline process, e.g. "Getting chart templates ...",
+// the result with the elapsed time is written into the same line when the process is done
+func (w *logEventsWriter) startInlineProcess(header string) {
+	header = ansiEscapeRegexp.ReplaceAllString(header, "")
+	rest, _ := trimLogboekVerticalBorders(header, w.output.logboekProcessesDepth())
+
+	process := w.output.newProcess(strings.TrimSpace(strings.TrimSuffix(rest, logboekInlineProcessDotsSuffix)))
+	process.isInline = true
+	w.output.startProcess(process, w.level)
+	w.inlineProcess = process
+}
+
+func (w *logEventsWriter) endInlineProcess(isFailed bool) {
+	process := w.inlineProcess
+	w.inlineProcess = nil
+
+	for ind := len(w.output.processes) - 1; ind >= 0; ind-- {
+		if w.output.processes[ind] == process {
+			w.output.endProcess(ind, w.level, isFailed, time.Now())
+			return
+		}
+	}
+}
+
+// trimLogboekVerticalBorders trims up to maxNumber leading logboek vertical borders ("│ │ ") of the nested processes
+func trimLogboekVerticalBorders(line string, maxNumber int) (string, int) {
+	var number int
+	for number < maxNumber && strings.HasPrefix(line, logboekVerticalBorder) {
+		line = strings.TrimPrefix(strings.TrimPrefix(line, logboekVerticalBorder), " ")
+		number++
+	}
+
+	return line, number
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/tracing"
+)
+
+func TestJSONLogEvents(t *testing.T) {
+	var events []*LogEvent
+	output := newJSONLogOutput(func(event *LogEvent) { events = append(events, event) })
+
+	jsonLog = output
+	defer func() { jsonLog = nil }()
+
+	setupLevelStreams()
+	defer func() {
+		for _, level := range logLevels {
+			level.ResetStream()
+		}
+	}()
+
+	tracing.SetSpanHook(output.handleSpan)
+	defer tracing.SetSpanHook(nil)
+
+	if err := tracing.Init(tracing.InitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	defer tracing.Shutdown(nil)
+
+	_ = tracing.Trace("phase build", map[string]string{"phase": "build", "image": "backend"}, func() error {
+		return tracing.Trace("stage backend/install", map[string]string{"stage": "install"}, func() error {
+			_ = logboek.Default.LogProcess("Running install", logboek.LevelLogProcessOptions{}, func() error {
+				logboek.Default.LogLn("Running command")
+				// raw output which looks like the logboek process border must not affect the processes
+				_, _ = fmt.Fprintf(streamWriter(logboek.Default, false), "┌ not a process\n└ not a process end\n")
+				return nil
+			})
+
+			_ = logboek.Default.LogProcessInline("Getting image", logboek.LevelLogProcessInlineOptions{}, func() error {
+				return nil
+			})
+
+			return logboek.Default.LogProcess("Running setup", logboek.LevelLogProcessOptions{}, func() error {
+				_, _ = fmt.Fprintf(streamWriter(logboek.Error, false), "  \x1b[31mcommand failed\x1b[0m")
+				output.writers[logboek.Error].flush()
+				return errors.New("error")
+			})
+		})
+	})
+
+	expected := []LogEvent{
+		{Type: LogEventTypeProcessStart, Level: "default", Process: "phase build", Image: "backend", Phase: "build"},
+		{Type: LogEventTypeProcessStart, Level: "default", Process: "stage backend/install", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeProcessStart, Level: "default", Process: "Running install", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeLog, Level: "default", Message: "Running command", Process: "Running install", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeLog, Level: "default", Message: "┌ not a process", Process: "Running install", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeLog, Level: "default", Message: "└ not a process end", Process: "Running install", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeProcessEnd, Level: "default", Process: "Running install", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeProcessStart, Level: "default", Process: "Getting image", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeProcessEnd, Level: "default", Process: "Getting image", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeProcessStart, Level: "default", Process: "Running setup", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeLog, Level: "error", Message: "command failed", Process: "Running setup", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeProcessFail, Level: "error", Process: "Running setup", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeProcessFail, Level: "error", Process: "stage backend/install", Image: "backend", Stage: "install", Phase: "build"},
+		{Type: LogEventTypeProcessFail, Level: "error", Process: "phase build", Image: "backend", Phase: "build"},
+	}
+
+	if len(events) != len(expected) {
+		for _, event := range events {
+			t.Logf("%+v", *event)
+		}
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+
+	for ind, e := range expected {
+		event := events[ind]
+		if event.Type != e.Type || event.Level != e.Level || event.Message != e.Message || event.Process != e.Process || event.Image != e.Image || event.Stage != e.Stage || event.Phase != e.Phase {
+			t.Errorf("event #%d: expected %+v, got %+v", ind, e, *event)
+		}
+
+		if isEnd := event.Type == LogEventTypeProcessEnd || event.Type == LogEventTypeProcessFail; isEnd != (event.Duration != nil) {
+			t.Errorf("event #%d: duration expected only for the process end, got %v", ind, event.Duration)
+		}
+	}
+}
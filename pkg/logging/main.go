@@ -27,14 +27,21 @@ func Init() error {
 
 	logboek.EnableFitMode()
 
-	log.SetOutput(logboek.GetOutStream())
+	setupLevelStreams()
+	log.SetOutput(GetOutStream())
 
 	return nil
 }
 
+// Shutdown flushes the log output, it should be called before the process exit
+func Shutdown() {
+	shutdownJSONLogFormat()
+	flushStreams()
+}
+
 func EnableLogQuiet() {
 	logboek.SetLevel(logboek.Error)
-	logboek.MuteOut()
+	MuteOut()
 }
 
 func EnableLogDebug() {
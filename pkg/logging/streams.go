@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/util/secretvalues"
+)
+
+// werf output goes through the logboek level streams and the streams returned by GetOutStream and GetErrStream,
+// where the secret values are masked. os.Stdout and os.Stderr are never replaced:
+// child processes keep the terminal and the data printed by commands stays raw.
+
+var (
+	isOutMuted bool
+
+	maskedStdout = secretvalues.NewMaskingWriter(os.Stdout)
+	maskedStderr = secretvalues.NewMaskingWriter(os.Stderr)
+
+	logLevels = []logboek.Level{logboek.Error, logboek.Warn, logboek.Default, logboek.Info, logboek.Debug}
+)
+
+type levelStream struct {
+	level logboek.Level
+	// the output formatted by logboek itself (log lines and process borders) rather than the raw output
+	isLogboekOutput bool
+}
+
+func (s levelStream) Write(data []byte) (int, error) {
+	return streamWriter(s.level, s.isLogboekOutput).Write(data)
+}
+
+func streamWriter(level logboek.Level, isLogboekOutput bool) io.Writer {
+	isErrLevel := level == logboek.Error || level == logboek.Warn
+
+	switch {
+	case !isErrLevel && isOutMuted:
+		return ioutil.Discard
+	case jsonLog != nil:
+		return jsonLog.writer(level, isLogboekOutput)
+	case isErrLevel:
+		return maskedStderr
+	default:
+		return maskedStdout
+	}
+}
+
+func setupLevelStreams() {
+	for _, level := range logLevels {
+		level.SetStream(levelStream{level: level, isLogboekOutput: true})
+	}
+}
+
+// GetOutStream returns the stream for the raw output of docker, git, helm etc.
+func GetOutStream() io.Writer {
+	return logboek.WriterProxy{Writer: levelStream{level: logboek.Default}}
+}
+
+func GetErrStream() io.Writer {
+	return logboek.WriterProxy{Writer: levelStream{level: logboek.Error}}
+}
+
+// MuteOut disables all output except errors and warnings, e.g. for the command which prints the data
+func MuteOut() {
+	isOutMuted = true
+	logboek.MuteOut()
+}
+
+func flushStreams() {
+	_ = maskedStdout.Flush()
+	_ = maskedStderr.Flush()
+}
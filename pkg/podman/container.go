@@ -0,0 +1,133 @@
+package podman
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// maxSymlinksToFollow limits resolving of the symlinks chain in ContainerStatPath
+const maxSymlinksToFollow = 40
+
+func ContainerExist(ref string) (bool, error) {
+	return callCliCheck("container", "exists", ref)
+}
+
+// ContainerCommit commits container with the dockerfile instructions changes and returns the image id
+func ContainerCommit(ref string, changes []string) (string, error) {
+	args := []string{"commit", "--quiet", "--format=docker"}
+	for _, change := range changes {
+		args = append(args, "--change", change)
+	}
+	args = append(args, ref)
+
+	return callCliWithStdoutAndInput(nil, args...)
+}
+
+func ContainerRemove(ref string) error {
+	_, err := callCliWithStdoutAndInput(nil, "rm", ref)
+	return err
+}
+
+// ContainerIPAddress returns ip address of the container in the default network,
+// address is empty for the rootless containers which network is not reachable from other containers
+func ContainerIPAddress(ref string) (string, error) {
+	return callCliWithStdoutAndInput(nil, "container", "inspect", "--format={{.NetworkSettings.IPAddress}}", ref)
+}
+
+// CopyFromContainer returns tar archive of the path in the docker format, the archive should be closed by the caller
+func CopyFromContainer(ref, srcPath string) (io.ReadCloser, error) {
+	r := &cliOutputReader{}
+	r.cmd = newCliCmd("cp", fmt.Sprintf("%s:%s", ref, srcPath), "-")
+	r.cmd.Stderr = &r.stderr
+
+	stdout, err := r.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %s", strings.Join(r.cmd.Args, " "), err)
+	}
+	r.stdout = stdout
+
+	if err := r.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s failed: %s", strings.Join(r.cmd.Args, " "), err)
+	}
+
+	return r, nil
+}
+
+// ContainerStatPath reads the path info from the first entry of the path archive,
+// symlinks are resolved to the absolute link target the same way docker server does
+func ContainerStatPath(ref, srcPath string) (types.ContainerPathStat, error) {
+	var stat types.ContainerPathStat
+
+	statPath := srcPath
+	for i := 0; i <= maxSymlinksToFollow; i++ {
+		hdr, err := readFirstArchiveHeader(ref, statPath)
+		if err != nil {
+			return types.ContainerPathStat{}, err
+		}
+
+		if i == 0 {
+			stat = types.ContainerPathStat{
+				Name:  path.Base(srcPath),
+				Size:  hdr.Size,
+				Mode:  hdr.FileInfo().Mode(),
+				Mtime: hdr.ModTime,
+			}
+		}
+
+		if hdr.Typeflag != tar.TypeSymlink {
+			if i != 0 {
+				stat.LinkTarget = statPath
+			}
+			return stat, nil
+		}
+
+		if path.IsAbs(hdr.Linkname) {
+			statPath = path.Clean(hdr.Linkname)
+		} else {
+			statPath = path.Join(path.Dir(statPath), hdr.Linkname)
+		}
+	}
+
+	return types.ContainerPathStat{}, fmt.Errorf("unable to stat %s in container %s: too many levels of symbolic links", srcPath, ref)
+}
+
+func readFirstArchiveHeader(ref, srcPath string) (*tar.Header, error) {
+	reader, err := CopyFromContainer(ref, srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, readErr := tar.NewReader(reader).Next()
+	// the rest of the archive is not needed, cp fails on the closed output
+	closeErr := reader.Close()
+
+	if readErr != nil {
+		if closeErr != nil {
+			return nil, closeErr
+		}
+		return nil, fmt.Errorf("unable to read %s archive from container %s: %s", srcPath, ref, readErr)
+	}
+
+	return hdr, nil
+}
+
+func CliCreate(args ...string) error {
+	return callCliWithAutoOutput(append([]string{"create"}, args...)...)
+}
+
+func CliRun(args ...string) error {
+	return callCliWithAutoOutput(append([]string{"run"}, args...)...)
+}
+
+func CliRun_LiveOutput(args ...string) error {
+	return callCliWithLiveOutput(append([]string{"run"}, args...)...)
+}
+
+func CliRm(args ...string) error {
+	return callCliWithAutoOutput(append([]string{"rm"}, args...)...)
+}
@@ -0,0 +1,84 @@
+package podman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakePodmanCp emulates `podman cp CONTAINER:PATH -` by archiving the path of the root dir
+const fakePodmanCp = `#!/bin/sh
+p="${2#*:}"
+cd "$FAKE_PODMAN_ROOT$(dirname "$p")" && exec tar -cf - "$(basename "$p")"
+`
+
+func TestContainerStatPath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-podman-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	root := filepath.Join(tmpDir, "root")
+	for _, dir := range []string{"app/data", "etc"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "etc/config"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{"app/current": "data", "app/config": "/etc/config", "app/config-link": "config", "app/loop": "loop"} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	binPath := filepath.Join(tmpDir, "podman")
+	if err := ioutil.WriteFile(binPath, []byte(fakePodmanCp), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldBinPath := podmanBinPath
+	podmanBinPath = binPath
+	defer func() { podmanBinPath = oldBinPath }()
+
+	if err := os.Setenv("FAKE_PODMAN_ROOT", root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("FAKE_PODMAN_ROOT")
+
+	tests := []struct {
+		path               string
+		expectedIsDir      bool
+		expectedLinkTarget string
+	}{
+		{path: "/app/data", expectedIsDir: true},
+		{path: "/etc/config"},
+		{path: "/app/current", expectedLinkTarget: "/app/data"},
+		{path: "/app/config", expectedLinkTarget: "/etc/config"},
+		{path: "/app/config-link", expectedLinkTarget: "/etc/config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			stat, err := ContainerStatPath("container", tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if stat.Name != filepath.Base(tt.path) || stat.Mode.IsDir() != tt.expectedIsDir || stat.LinkTarget != tt.expectedLinkTarget {
+				t.Errorf("unexpected stat %+v", stat)
+			}
+		})
+	}
+
+	if _, err := ContainerStatPath("container", "/app/loop"); err == nil {
+		t.Errorf("expected error for symlinks loop")
+	}
+
+	if _, err := ContainerStatPath("container", "/app/missing"); err == nil {
+		t.Errorf("expected error for missing path")
+	}
+}
@@ -0,0 +1,126 @@
+package podman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/flant/logboek"
+)
+
+// emptyTarArchive is the end-of-archive marker of the tar format
+var emptyTarArchive = make([]byte, 1024)
+
+func CreateImage(ref string) error {
+	_, err := callCliWithStdoutAndInput(bytes.NewReader(emptyTarArchive), "image", "import", "--quiet", "-", ref)
+	return err
+}
+
+func ImageExist(ref string) (bool, error) {
+	return callCliCheck("image", "exists", ref)
+}
+
+// ImageInspect returns docker compatible image inspect or nil if image does not exist
+func ImageInspect(ref string) (*types.ImageInspect, error) {
+	if exist, err := ImageExist(ref); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, nil
+	}
+
+	output, err := callCliWithStdoutAndInput(nil, "image", "inspect", "--format=json", ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var inspects []*types.ImageInspect
+	if err := json.Unmarshal([]byte(output), &inspects); err != nil {
+		return nil, fmt.Errorf("unable to parse image %s inspect: %s", ref, err)
+	}
+
+	if len(inspects) == 0 {
+		return nil, nil
+	}
+
+	inspect := inspects[0]
+	// podman reports image id without algorithm prefix
+	if inspect.ID != "" && !strings.Contains(inspect.ID, ":") {
+		inspect.ID = fmt.Sprintf("sha256:%s", inspect.ID)
+	}
+
+	return inspect, nil
+}
+
+func CliTag(args ...string) error {
+	return callCliWithAutoOutput(append([]string{"tag"}, args...)...)
+}
+
+func CliRmi(args ...string) error {
+	return callCliWithAutoOutput(append([]string{"rmi"}, args...)...)
+}
+
+func CliSave(args ...string) error {
+	return callCliWithAutoOutput(append([]string{"save"}, args...)...)
+}
+
+func CliBuild_LiveOutput(args ...string) error {
+	return callCliWithLiveOutput(append([]string{"build"}, args...)...)
+}
+
+const cliPullMaxAttempts = 5
+
+func CliPullWithRetries(args ...string) error {
+	return callWithRetries("pull", cliPullMaxAttempts, args...)
+}
+
+const cliPushMaxAttempts = 10
+
+func CliPushWithRetries(args ...string) error {
+	return callWithRetries("push", cliPushMaxAttempts, args...)
+}
+
+var retryableErrors = []string{
+	"Client.Timeout exceeded while awaiting headers",
+	"TLS handshake timeout",
+	"i/o timeout",
+	"504 Gateway Time-out",
+	"504 Gateway Timeout",
+	"Internal Server Error",
+}
+
+func callWithRetries(command string, maxAttempts int, args ...string) error {
+	for attempt := 1; ; attempt++ {
+		output, err := callCliWithRecordedOutput(append([]string{command}, args...)...)
+		if err == nil {
+			if liveCliOutputEnabled {
+				logboek.LogF("%s", output)
+			}
+			return nil
+		}
+
+		if attempt < maxAttempts && isRetryableOutput(output) {
+			seconds := rand.Intn(30-15) + 15 // from 15 to 30 seconds
+
+			logboek.LogWarnF("Retrying podman %s in %d seconds (%d/%d) ...\n", command, seconds, attempt, maxAttempts)
+			time.Sleep(time.Duration(seconds) * time.Second)
+			continue
+		}
+
+		logboek.LogErrorF("%s", output)
+		return err
+	}
+}
+
+func isRetryableOutput(output string) bool {
+	for _, specificError := range retryableErrors {
+		if strings.Contains(output, specificError) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package podman
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/logging"
+)
+
+var (
+	podmanBinPath string
+
+	liveCliOutputEnabled bool
+	isDebug              bool
+)
+
+// Init prepares podman cli: podman works without daemon and keeps images and containers in the local containers storage of the user
+func Init(dockerConfigDir string, verbose, debug bool) error {
+	binPath := os.Getenv("WERF_PODMAN_BIN")
+	if binPath == "" {
+		binPath = "podman"
+	}
+
+	if path, err := exec.LookPath(binPath); err != nil {
+		return fmt.Errorf("podman is required for the podman container runtime: %s", err)
+	} else {
+		podmanBinPath = path
+	}
+
+	if dockerConfigDir != "" {
+		// registry api client of werf reads the same docker config
+		if err := os.Setenv("DOCKER_CONFIG", dockerConfigDir); err != nil {
+			return fmt.Errorf("cannot set DOCKER_CONFIG to %s: %s", dockerConfigDir, err)
+		}
+
+		// podman uses auth file with the docker config format
+		authFile := filepath.Join(dockerConfigDir, "config.json")
+		if err := os.Setenv("REGISTRY_AUTH_FILE", authFile); err != nil {
+			return fmt.Errorf("cannot set REGISTRY_AUTH_FILE to %s: %s", authFile, err)
+		}
+	}
+
+	isDebug = debug
+	liveCliOutputEnabled = verbose || debug
+
+	return nil
+}
+
+type ExitError struct {
+	Command  string
+	ExitCode int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("Code: %d", e.ExitCode)
+}
+
+func newCliCmd(args ...string) *exec.Cmd {
+	if isDebug {
+		args = append([]string{"--log-level=debug"}, args...)
+	}
+	return exec.Command(podmanBinPath, args...)
+}
+
+func runCliCmd(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%s failed: %s", strings.Join(cmd.Args, " "), err)
+	}
+	return runCliCmdWait(cmd)
+}
+
+func runCliCmdWait(cmd *exec.Cmd) error {
+	if err := cmd.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return &ExitError{Command: strings.Join(cmd.Args, " "), ExitCode: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("%s failed: %s", strings.Join(cmd.Args, " "), err)
+	}
+	return nil
+}
+
+func callCliWithLiveOutput(args ...string) error {
+	cmd := newCliCmd(args...)
+	cmd.Stdout = logging.GetOutStream()
+	cmd.Stderr = logging.GetErrStream()
+	return runCliCmd(cmd)
+}
+
+func callCliWithRecordedOutput(args ...string) (string, error) {
+	var output bytes.Buffer
+
+	cmd := newCliCmd(args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := runCliCmd(cmd)
+
+	return output.String(), err
+}
+
+func callCliWithStdoutAndInput(stdin io.Reader, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := newCliCmd(args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := runCliCmd(cmd); err != nil {
+		return "", fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func callCliWithAutoOutput(args ...string) error {
+	if liveCliOutputEnabled {
+		return callCliWithLiveOutput(args...)
+	} else {
+		output, err := callCliWithRecordedOutput(args...)
+		if err != nil {
+			logboek.LogErrorF("%s", output)
+		}
+		return err
+	}
+}
+
+// callCliCheck runs podman `exists` subcommands which report the result by the exit code
+func callCliCheck(args ...string) (bool, error) {
+	var stderr bytes.Buffer
+
+	cmd := newCliCmd(args...)
+	cmd.Stderr = &stderr
+	if err := runCliCmd(cmd); err != nil {
+		if exitErr, ok := err.(*ExitError); ok && exitErr.ExitCode == 1 {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return true, nil
+}
+
+// cliOutputReader streams the output of the running cli command, Close waits for the command
+type cliOutputReader struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+
+	closed   bool
+	closeErr error
+}
+
+func (r *cliOutputReader) Read(p []byte) (int, error) {
+	return r.stdout.Read(p)
+}
+
+func (r *cliOutputReader) Close() error {
+	if r.closed {
+		return r.closeErr
+	}
+	r.closed = true
+
+	_ = r.stdout.Close()
+	if err := runCliCmdWait(r.cmd); err != nil {
+		r.closeErr = fmt.Errorf("%s: %s", err, strings.TrimSpace(r.stderr.String()))
+	}
+
+	return r.closeErr
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/flant/lockgate"
 
+	"github.com/flant/werf/pkg/docker_registry"
 	"github.com/flant/werf/pkg/image"
 
 	"github.com/flant/werf/pkg/werf"
@@ -154,16 +155,19 @@ func (m *StagesManager) GetAllStages() ([]*image.StageDescription, error) {
 	if stageIDs, err := m.StagesStorage.GetAllStages(m.ProjectName); err != nil {
 		return nil, err
 	} else {
-		var stages []*image.StageDescription
+		stages := make([]*image.StageDescription, len(stageIDs))
 
-		for _, stageID := range stageIDs {
-			if stageDesc, err := m.getStageDescription(stageID); err != nil {
-				return nil, err
+		if err := docker_registry.ParallelFetch(len(stageIDs), func(ind int) error {
+			if stageDesc, err := m.getStageDescription(stageIDs[ind]); err != nil {
+				return err
 			} else if stageDesc == nil {
-				return nil, fmt.Errorf("invalid stage %s: stage does not exists in the %s", stageID.String(), m.StagesStorage.String())
+				return fmt.Errorf("invalid stage %s: stage does not exists in the %s", stageIDs[ind].String(), m.StagesStorage.String())
 			} else {
-				stages = append(stages, stageDesc)
+				stages[ind] = stageDesc
+				return nil
 			}
+		}); err != nil {
+			return nil, err
 		}
 
 		return stages, nil
@@ -86,42 +86,45 @@ func SyncStages(projectName string, fromStagesStorage storage.StagesStorage, toS
 		}
 	}
 
-	logboek.Default.LogFDetails("Stages to sync: %d\n", len(stagesToSync))
+	// platform variants of the stage are synced together as one logical stage
+	stageGroupsToSync := groupStagesByLogicalSignature(stagesToSync)
+
+	logboek.Default.LogFDetails("Stages to sync: %d\n", len(stageGroupsToSync))
 
 	maxWorkers := 10
 	resultsChan := make(chan struct {
 		error
-		image.StageID
+		stageIDs []image.StageID
 	}, 1000)
-	jobsChan := make(chan image.StageID, 1000)
+	jobsChan := make(chan []image.StageID, 1000)
 
 	for w := 0; w < maxWorkers; w++ {
 		go runSyncWorker(projectName, fromStagesStorage, toStagesStorage, containerRuntime, opts, w, jobsChan, resultsChan)
 	}
 
-	for _, stageDesc := range stagesToSync {
-		jobsChan <- stageDesc
+	for _, stageGroup := range stageGroupsToSync {
+		jobsChan <- stageGroup
 	}
 	close(jobsChan)
 
 	failedCounter := 0
 	succeededCounter := 0
-	for i := 0; i < len(stagesToSync); i++ {
+	for i := 0; i < len(stageGroupsToSync); i++ {
 		desc := <-resultsChan
 
 		if desc.error != nil {
 			failedCounter++
-			logboek.LogErrorF("%5d/%d failed: %s\n", failedCounter, len(stagesToSync), desc.error)
+			logboek.LogErrorF("%5d/%d failed: %s\n", failedCounter, len(stageGroupsToSync), desc.error)
 			errors = append(errors, desc.error)
 		} else {
 			succeededCounter++
-			logboek.Default.LogF("%5d/%d synced\n", succeededCounter, len(stagesToSync))
+			logboek.Default.LogF("%5d/%d synced\n", succeededCounter, len(stageGroupsToSync))
 		}
 	}
 
 	if len(errors) > 0 {
 		logboek.Default.LogLn()
-		logboek.Default.LogFHighlight("synced %d/%d, failed %d/%d\n", succeededCounter, len(stagesToSync), failedCounter, len(stagesToSync))
+		logboek.Default.LogFHighlight("synced %d/%d, failed %d/%d\n", succeededCounter, len(stageGroupsToSync), failedCounter, len(stageGroupsToSync))
 
 		errorMsg := fmt.Sprintf("following errors occured:\n")
 		for _, err := range errors {
@@ -134,18 +137,44 @@ func SyncStages(projectName string, fromStagesStorage storage.StagesStorage, toS
 	return nil
 }
 
-func runSyncWorker(projectName string, fromStagesStorage storage.StagesStorage, toStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, opts SyncStagesOptions, workerId int, jobs chan image.StageID, results chan struct {
+func groupStagesByLogicalSignature(stages []image.StageID) [][]image.StageID {
+	var groups [][]image.StageID
+	groupIndexByKey := map[string]int{}
+
+	for _, stageID := range stages {
+		logicalSignature, platform := image.ParsePlatformSignature(stageID.Signature)
+		if platform == "" {
+			groups = append(groups, []image.StageID{stageID})
+			continue
+		}
+
+		if ind, hasKey := groupIndexByKey[logicalSignature]; hasKey {
+			groups[ind] = append(groups[ind], stageID)
+		} else {
+			groupIndexByKey[logicalSignature] = len(groups)
+			groups = append(groups, []image.StageID{stageID})
+		}
+	}
+
+	return groups
+}
+
+func runSyncWorker(projectName string, fromStagesStorage storage.StagesStorage, toStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, opts SyncStagesOptions, workerId int, jobs chan []image.StageID, results chan struct {
 	error
-	image.StageID
+	stageIDs []image.StageID
 }) {
-	for stageID := range jobs {
+	for stageIDs := range jobs {
+		var err error
+		for _, stageID := range stageIDs {
+			if err = syncStage(projectName, stageID, fromStagesStorage, toStagesStorage, containerRuntime, opts); err != nil {
+				break
+			}
+		}
+
 		results <- struct {
 			error
-			image.StageID
-		}{
-			syncStage(projectName, stageID, fromStagesStorage, toStagesStorage, containerRuntime, opts),
-			stageID,
-		}
+			stageIDs []image.StageID
+		}{err, stageIDs}
 	}
 }
 
@@ -165,7 +194,7 @@ func syncStage(projectName string, stageID image.StageID, fromStagesStorage stor
 	if destStageDesc, err := toStagesStorage.GetStageDescription(projectName, stageID.Signature, stageID.UniqueID); err != nil {
 		return fmt.Errorf("error getting stage %s description from %s: %s", stageID.String(), toStagesStorage.String(), err)
 	} else if destStageDesc == nil {
-		img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime.(*container_runtime.LocalDockerServerRuntime))
+		img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime.(container_runtime.LocalRuntime))
 
 		logboek.Info.LogF("Fetching %s\n", img.Name())
 		if err := fromStagesStorage.FetchImage(&container_runtime.DockerImage{Image: img}); err != nil {
@@ -0,0 +1,263 @@
+package stages_manager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/storage"
+)
+
+type VerifyStagesOptions struct {
+	Repair bool
+}
+
+type brokenStage struct {
+	StageID   image.StageID
+	StageDesc *image.StageDescription
+	Reason    string
+}
+
+// VerifyStages cross-checks stages storage, stages storage cache and managed images records of the project.
+// Found problems are reported and fixed in the repair mode: cache records are rebuilt from the stages storage,
+// stages with invalid werf labels and dangling managed images records are deleted.
+func VerifyStages(projectName string, stagesStorage storage.StagesStorage, stagesStorageCache storage.StagesStorageCache, storageLockManager storage.LockManager, opts VerifyStagesOptions) error {
+	if lock, err := storageLockManager.LockStagesAndImages(projectName, storage.LockStagesAndImagesOptions{GetOrCreateImagesOnly: !opts.Repair}); err != nil {
+		return fmt.Errorf("unable to lock stages and images of project %q: %s", projectName, err)
+	} else {
+		defer storageLockManager.Unlock(lock)
+	}
+
+	logboek.Default.LogFDetails("Stages storage       — %s\n", stagesStorage.String())
+	logboek.Default.LogFDetails("Stages storage cache — %s\n", stagesStorageCache.String())
+	logboek.Default.LogOptionalLn()
+
+	var storageStages []image.StageID
+	if err := logboek.Default.LogProcess("Getting all stages from stages storage", logboek.LevelLogProcessOptions{}, func() error {
+		var err error
+		storageStages, err = stagesStorage.GetAllStages(projectName)
+		if err != nil {
+			return fmt.Errorf("unable to get stages from %s: %s", stagesStorage.String(), err)
+		}
+		logboek.Default.LogFDetails("Stages count: %d\n", len(storageStages))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var brokenStages []*brokenStage
+	validStagesBySignature := map[string][]image.StageID{}
+	if err := logboek.Default.LogProcess("Verifying stages manifests and labels", logboek.LevelLogProcessOptions{}, func() error {
+		for _, stageID := range storageStages {
+			// errors of the stages storage are not a breakage of the stage, verification is aborted and nothing is repaired
+			if broken, err := verifyStage(projectName, stagesStorage, stageID); err != nil {
+				return fmt.Errorf("unable to verify stage %s: %s", stagesStorage.ConstructStageImageName(projectName, stageID.Signature, stageID.UniqueID), err)
+			} else if broken != nil {
+				brokenStages = append(brokenStages, broken)
+			} else {
+				validStagesBySignature[stageID.Signature] = append(validStagesBySignature[stageID.Signature], stageID)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var signaturesToFixInCache []string
+	var cacheProblems []string
+	if err := logboek.Default.LogProcess("Verifying stages storage cache", logboek.LevelLogProcessOptions{}, func() error {
+		cacheExists, cacheStages, err := stagesStorageCache.GetAllStages(projectName)
+		if err != nil {
+			return fmt.Errorf("unable to get stages from %s: %s", stagesStorageCache.String(), err)
+		}
+		if !cacheExists {
+			logboek.Default.LogFDetails("Stages storage cache for project %q does not exist\n", projectName)
+			return nil
+		}
+
+		cacheStagesBySignature := map[string][]image.StageID{}
+		for _, stageID := range cacheStages {
+			cacheStagesBySignature[stageID.Signature] = append(cacheStagesBySignature[stageID.Signature], stageID)
+		}
+
+		var signatures []string
+		for signature := range cacheStagesBySignature {
+			signatures = append(signatures, signature)
+		}
+		for signature := range validStagesBySignature {
+			if _, hasKey := cacheStagesBySignature[signature]; !hasKey {
+				signatures = append(signatures, signature)
+			}
+		}
+
+		for _, signature := range signatures {
+			cachedIDs := cacheStagesBySignature[signature]
+			validIDs := validStagesBySignature[signature]
+			isConsistent := true
+
+			for _, cachedID := range cachedIDs {
+				if !hasStageID(validIDs, cachedID) {
+					cacheProblems = append(cacheProblems, fmt.Sprintf("orphan cache record %s", stagesStorage.ConstructStageImageName(projectName, cachedID.Signature, cachedID.UniqueID)))
+					isConsistent = false
+				}
+			}
+
+			for _, validID := range validIDs {
+				if !hasStageID(cachedIDs, validID) {
+					cacheProblems = append(cacheProblems, fmt.Sprintf("missing cache record %s", stagesStorage.ConstructStageImageName(projectName, validID.Signature, validID.UniqueID)))
+					isConsistent = false
+				}
+			}
+
+			if !isConsistent {
+				signaturesToFixInCache = append(signaturesToFixInCache, signature)
+			}
+		}
+
+		sort.Strings(cacheProblems)
+		sort.Strings(signaturesToFixInCache)
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var danglingManagedImages []string
+	if err := logboek.Default.LogProcess("Verifying managed images", logboek.LevelLogProcessOptions{}, func() error {
+		managedImages, err := stagesStorage.GetManagedImages(projectName)
+		if err != nil {
+			return fmt.Errorf("unable to get managed images from %s: %s", stagesStorage.String(), err)
+		}
+
+		// Stages are not labeled with the image name, so a managed image record is considered as dangling
+		// only when no stages of the project are left in the stages storage
+		if len(validStagesBySignature) == 0 {
+			danglingManagedImages = append(danglingManagedImages, managedImages...)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	problemsCount := len(brokenStages) + len(cacheProblems) + len(danglingManagedImages)
+	if problemsCount == 0 {
+		logboek.Default.LogLnHighlight("No problems found")
+		return nil
+	}
+
+	logboek.Default.LogOptionalLn()
+	_ = logboek.Default.LogBlock("Found problems", logboek.LevelLogBlockOptions{}, func() error {
+		for _, broken := range brokenStages {
+			logboek.LogWarnF("broken stage %s: %s\n", stagesStorage.ConstructStageImageName(projectName, broken.StageID.Signature, broken.StageID.UniqueID), broken.Reason)
+		}
+		for _, problem := range cacheProblems {
+			logboek.LogWarnF("%s\n", problem)
+		}
+		for _, managedImage := range danglingManagedImages {
+			logboek.LogWarnF("dangling managed image %q: stages of the image do not exist in the stages storage\n", logImageName(managedImage))
+		}
+		return nil
+	})
+
+	if !opts.Repair {
+		return fmt.Errorf("stages storage verification failed: %d problems found, use --repair option to fix them", problemsCount)
+	}
+
+	return repairStages(projectName, stagesStorage, stagesStorageCache, brokenStages, validStagesBySignature, signaturesToFixInCache, danglingManagedImages)
+}
+
+// verifyStage returns the broken stage if the manifest of the stage does not exist or has invalid werf labels,
+// the error is returned only if the manifest cannot be read from the stages storage
+func verifyStage(projectName string, stagesStorage storage.StagesStorage, stageID image.StageID) (*brokenStage, error) {
+	// manifest cache is skipped deliberately: the manifest could be deleted from the registry after caching
+	stageDesc, err := stagesStorage.GetStageDescription(projectName, stageID.Signature, stageID.UniqueID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stage description: %s", err)
+	} else if stageDesc == nil || stageDesc.Info == nil {
+		return &brokenStage{StageID: stageID, Reason: "manifest does not exist"}, nil
+	}
+
+	labels := stageDesc.Info.Labels
+	switch {
+	case labels[image.WerfLabel] != projectName:
+		return &brokenStage{StageID: stageID, StageDesc: stageDesc, Reason: fmt.Sprintf("unexpected %s label value %q", image.WerfLabel, labels[image.WerfLabel])}, nil
+	case labels[image.WerfStageSignatureLabel] != stageID.Signature:
+		return &brokenStage{StageID: stageID, StageDesc: stageDesc, Reason: fmt.Sprintf("unexpected %s label value %q", image.WerfStageSignatureLabel, labels[image.WerfStageSignatureLabel])}, nil
+	case labels[image.WerfCacheVersionLabel] == "":
+		return &brokenStage{StageID: stageID, StageDesc: stageDesc, Reason: fmt.Sprintf("%s label is not set", image.WerfCacheVersionLabel)}, nil
+	}
+
+	return nil, nil
+}
+
+func repairStages(projectName string, stagesStorage storage.StagesStorage, stagesStorageCache storage.StagesStorageCache, brokenStages []*brokenStage, validStagesBySignature map[string][]image.StageID, signaturesToFixInCache []string, danglingManagedImages []string) error {
+	return logboek.Default.LogProcess("Repairing", logboek.LevelLogProcessOptions{}, func() error {
+		for _, broken := range brokenStages {
+			stageImageName := stagesStorage.ConstructStageImageName(projectName, broken.StageID.Signature, broken.StageID.UniqueID)
+
+			if broken.StageDesc == nil {
+				logboek.LogWarnF("Stage %s cannot be deleted: manifest does not exist, it will be excluded from the stages storage cache\n", stageImageName)
+			} else {
+				logboek.Default.LogFDetails("Deleting broken stage %s\n", stageImageName)
+				if err := stagesStorage.DeleteStages(storage.DeleteImageOptions{}, broken.StageDesc); err != nil {
+					return fmt.Errorf("unable to delete stage %s: %s", stageImageName, err)
+				}
+			}
+
+			if !hasString(signaturesToFixInCache, broken.StageID.Signature) {
+				signaturesToFixInCache = append(signaturesToFixInCache, broken.StageID.Signature)
+			}
+		}
+
+		for _, signature := range signaturesToFixInCache {
+			if validIDs := validStagesBySignature[signature]; len(validIDs) == 0 {
+				logboek.Default.LogFDetails("Deleting stages storage cache records by signature %s\n", signature)
+				if err := stagesStorageCache.DeleteStagesBySignature(projectName, signature); err != nil {
+					return fmt.Errorf("unable to delete stages storage cache records by signature %s: %s", signature, err)
+				}
+			} else {
+				logboek.Default.LogFDetails("Storing stages storage cache records by signature %s\n", signature)
+				if err := stagesStorageCache.StoreStagesBySignature(projectName, signature, validIDs); err != nil {
+					return fmt.Errorf("unable to store stages storage cache records by signature %s: %s", signature, err)
+				}
+			}
+		}
+
+		for _, managedImage := range danglingManagedImages {
+			logboek.Default.LogFDetails("Deleting managed image %q\n", logImageName(managedImage))
+			if err := stagesStorage.RmManagedImage(projectName, managedImage); err != nil {
+				return fmt.Errorf("unable to delete managed image %q: %s", logImageName(managedImage), err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func hasStageID(stageIDs []image.StageID, stageID image.StageID) bool {
+	for _, id := range stageIDs {
+		if id.Signature == stageID.Signature && id.UniqueID == stageID.UniqueID {
+			return true
+		}
+	}
+	return false
+}
+
+func hasString(list []string, value string) bool {
+	for _, elm := range list {
+		if elm == value {
+			return true
+		}
+	}
+	return false
+}
+
+func logImageName(imageName string) string {
+	if imageName == "" {
+		return storage.NamelessImageRecordTag
+	}
+	return imageName
+}
@@ -0,0 +1,200 @@
+package stages_manager
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flant/werf/pkg/image"
+	"github.com/flant/werf/pkg/storage"
+)
+
+type stagesStorageStub struct {
+	storage.StagesStorage
+
+	stages        []image.StageID
+	labels        map[image.StageID]map[string]string
+	descErr       error
+	managedImages []string
+
+	deletedStages        []image.StageID
+	deletedManagedImages []string
+}
+
+func (s *stagesStorageStub) GetAllStages(_ string) ([]image.StageID, error) {
+	return s.stages, nil
+}
+
+func (s *stagesStorageStub) GetStageDescription(_, signature string, uniqueID int64) (*image.StageDescription, error) {
+	if s.descErr != nil {
+		return nil, s.descErr
+	}
+
+	stageID := image.StageID{Signature: signature, UniqueID: uniqueID}
+	labels, hasKey := s.labels[stageID]
+	if !hasKey {
+		return nil, nil
+	}
+	return &image.StageDescription{StageID: &stageID, Info: &image.Info{Labels: labels}}, nil
+}
+
+func (s *stagesStorageStub) DeleteStages(_ storage.DeleteImageOptions, stages ...*image.StageDescription) error {
+	for _, stageDesc := range stages {
+		s.deletedStages = append(s.deletedStages, *stageDesc.StageID)
+	}
+	return nil
+}
+
+func (s *stagesStorageStub) ConstructStageImageName(projectName, signature string, uniqueID int64) string {
+	return fmt.Sprintf("%s:%s-%d", projectName, signature, uniqueID)
+}
+
+func (s *stagesStorageStub) GetManagedImages(_ string) ([]string, error) {
+	return s.managedImages, nil
+}
+
+func (s *stagesStorageStub) RmManagedImage(_, imageName string) error {
+	s.deletedManagedImages = append(s.deletedManagedImages, imageName)
+	return nil
+}
+
+func (s *stagesStorageStub) String() string {
+	return "stub"
+}
+
+type stagesStorageCacheStub struct {
+	storage.StagesStorageCache
+
+	stages []image.StageID
+
+	storedSignatures  map[string][]image.StageID
+	deletedSignatures []string
+}
+
+func (c *stagesStorageCacheStub) GetAllStages(_ string) (bool, []image.StageID, error) {
+	return true, c.stages, nil
+}
+
+func (c *stagesStorageCacheStub) StoreStagesBySignature(_, signature string, stages []image.StageID) error {
+	if c.storedSignatures == nil {
+		c.storedSignatures = map[string][]image.StageID{}
+	}
+	c.storedSignatures[signature] = stages
+	return nil
+}
+
+func (c *stagesStorageCacheStub) DeleteStagesBySignature(_, signature string) error {
+	c.deletedSignatures = append(c.deletedSignatures, signature)
+	return nil
+}
+
+func (c *stagesStorageCacheStub) String() string {
+	return "stub"
+}
+
+type lockManagerStub struct {
+	storage.LockManager
+}
+
+func (m lockManagerStub) LockStagesAndImages(projectName string, _ storage.LockStagesAndImagesOptions) (storage.LockHandle, error) {
+	return storage.LockHandle{ProjectName: projectName}, nil
+}
+
+func (m lockManagerStub) Unlock(_ storage.LockHandle) error {
+	return nil
+}
+
+func validStageLabels(signature string) map[string]string {
+	return map[string]string{
+		image.WerfLabel:               "project",
+		image.WerfStageSignatureLabel: signature,
+		image.WerfCacheVersionLabel:   "1",
+	}
+}
+
+func TestVerifyStages(t *testing.T) {
+	validStage := image.StageID{Signature: "valid", UniqueID: 1}
+	uncachedStage := image.StageID{Signature: "uncached", UniqueID: 2}
+	brokenStage := image.StageID{Signature: "broken", UniqueID: 3}
+	orphanStage := image.StageID{Signature: "orphan", UniqueID: 4}
+
+	newStubs := func() (*stagesStorageStub, *stagesStorageCacheStub) {
+		stagesStorage := &stagesStorageStub{
+			stages: []image.StageID{validStage, uncachedStage, brokenStage},
+			labels: map[image.StageID]map[string]string{
+				validStage:    validStageLabels(validStage.Signature),
+				uncachedStage: validStageLabels(uncachedStage.Signature),
+				brokenStage:   {image.WerfLabel: "project", image.WerfStageSignatureLabel: brokenStage.Signature},
+			},
+			managedImages: []string{"app", "removed"},
+		}
+		stagesStorageCache := &stagesStorageCacheStub{stages: []image.StageID{validStage, brokenStage, orphanStage}}
+		return stagesStorage, stagesStorageCache
+	}
+
+	t.Run("report", func(t *testing.T) {
+		stagesStorage, stagesStorageCache := newStubs()
+
+		err := VerifyStages("project", stagesStorage, stagesStorageCache, lockManagerStub{}, VerifyStagesOptions{})
+		if err == nil || !strings.Contains(err.Error(), "4 problems found") {
+			t.Fatalf("expected 4 problems to be found, got %v", err)
+		}
+
+		if len(stagesStorage.deletedStages) != 0 || len(stagesStorage.deletedManagedImages) != 0 || len(stagesStorageCache.storedSignatures) != 0 || len(stagesStorageCache.deletedSignatures) != 0 {
+			t.Errorf("nothing should be repaired without repair option")
+		}
+	})
+
+	t.Run("repair", func(t *testing.T) {
+		stagesStorage, stagesStorageCache := newStubs()
+
+		if err := VerifyStages("project", stagesStorage, stagesStorageCache, lockManagerStub{}, VerifyStagesOptions{Repair: true}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if expected := []image.StageID{brokenStage}; !reflect.DeepEqual(stagesStorage.deletedStages, expected) {
+			t.Errorf("expected deleted stages %v, got %v", expected, stagesStorage.deletedStages)
+		}
+
+		if len(stagesStorage.deletedManagedImages) != 0 {
+			t.Errorf("managed images records should not be deleted while project stages exist, got %v", stagesStorage.deletedManagedImages)
+		}
+
+		if expected := map[string][]image.StageID{uncachedStage.Signature: {uncachedStage}}; !reflect.DeepEqual(stagesStorageCache.storedSignatures, expected) {
+			t.Errorf("expected stored cache records %v, got %v", expected, stagesStorageCache.storedSignatures)
+		}
+
+		if expected := []string{brokenStage.Signature, orphanStage.Signature}; !reflect.DeepEqual(stagesStorageCache.deletedSignatures, expected) {
+			t.Errorf("expected deleted cache records %v, got %v", expected, stagesStorageCache.deletedSignatures)
+		}
+	})
+
+	t.Run("repair without stages", func(t *testing.T) {
+		stagesStorage, stagesStorageCache := newStubs()
+		stagesStorage.stages = []image.StageID{brokenStage}
+
+		if err := VerifyStages("project", stagesStorage, stagesStorageCache, lockManagerStub{}, VerifyStagesOptions{Repair: true}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if expected := []string{"app", "removed"}; !reflect.DeepEqual(stagesStorage.deletedManagedImages, expected) {
+			t.Errorf("expected deleted managed images %v, got %v", expected, stagesStorage.deletedManagedImages)
+		}
+	})
+
+	t.Run("stages storage error", func(t *testing.T) {
+		stagesStorage, stagesStorageCache := newStubs()
+		stagesStorage.descErr = errors.New("connection reset by peer")
+
+		err := VerifyStages("project", stagesStorage, stagesStorageCache, lockManagerStub{}, VerifyStagesOptions{Repair: true})
+		if err == nil || !strings.Contains(err.Error(), "connection reset by peer") {
+			t.Fatalf("expected stages storage error, got %v", err)
+		}
+
+		if len(stagesStorage.deletedStages) != 0 || len(stagesStorage.deletedManagedImages) != 0 || len(stagesStorageCache.storedSignatures) != 0 || len(stagesStorageCache.deletedSignatures) != 0 {
+			t.Errorf("nothing should be repaired on stages storage error")
+		}
+	})
+}
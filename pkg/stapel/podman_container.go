@@ -0,0 +1,49 @@
+package stapel
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/flant/lockgate"
+	"github.com/flant/logboek"
+
+	"github.com/flant/werf/pkg/podman"
+	"github.com/flant/werf/pkg/werf"
+)
+
+// GetOrCreatePodmanContainer creates stapel container in the local containers storage of podman,
+// stapel volume of this container is used by the stage containers the same way as with docker server
+func GetOrCreatePodmanContainer() (string, error) {
+	c := getContainer()
+
+	if exist, err := podman.ContainerExist(c.Name); err != nil {
+		return "", err
+	} else if exist {
+		return c.Name, nil
+	}
+
+	err := werf.WithHostLock(fmt.Sprintf("stapel.podman_container.%s", c.Name), lockgate.AcquireOptions{Timeout: time.Second * 600}, func() error {
+		return logboek.LogProcess(fmt.Sprintf("Creating podman container %s from image %s", c.Name, c.ImageName), logboek.LogProcessOptions{}, func() error {
+			if exist, err := podman.ContainerExist(c.Name); err != nil {
+				return err
+			} else if exist {
+				return nil
+			}
+
+			if exist, err := podman.ImageExist(c.ImageName); err != nil {
+				return err
+			} else if !exist {
+				if err := podman.CliPullWithRetries(c.ImageName); err != nil {
+					return err
+				}
+			}
+
+			return podman.CliCreate(fmt.Sprintf("--name=%s", c.Name), fmt.Sprintf("--volume=%s", c.Volume), c.ImageName)
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return c.Name, nil
+}
@@ -3,6 +3,8 @@ package storage
 import (
 	"fmt"
 
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
 	"github.com/flant/logboek"
 
 	"github.com/flant/werf/pkg/container_runtime"
@@ -65,6 +67,10 @@ func (repo *DockerImagesRepo) GetRepoImage(imageName, tag string) (*image.Info,
 	return repo.DockerRegistry.GetRepoImage(repo.ImageRepositoryNameWithTag(imageName, tag))
 }
 
+func (repo *DockerImagesRepo) GetRepoImageByPlatform(imageName, tag, platform string) (*image.Info, error) {
+	return repo.DockerRegistry.GetRepoImageByPlatform(repo.ImageRepositoryNameWithTag(imageName, tag), platform)
+}
+
 func (repo *DockerImagesRepo) GetRepoImages(imageNames []string) (map[string][]*image.Info, error) {
 	return repo.SelectRepoImages(imageNames, nil)
 }
@@ -95,6 +101,14 @@ func (repo *DockerImagesRepo) PublishImage(publishImage *container_runtime.WerfI
 	return publishImage.Export()
 }
 
+func (repo *DockerImagesRepo) PublishImageByDigest(imageName string, img v1.Image) (string, error) {
+	return repo.DockerRegistry.PutImageByDigest(repo.ImageRepositoryName(imageName), img)
+}
+
+func (repo *DockerImagesRepo) PublishManifestList(imageName, tag string, entries []docker_registry.ManifestListEntry) error {
+	return repo.DockerRegistry.PutManifestList(repo.ImageRepositoryNameWithTag(imageName, tag), entries)
+}
+
 func (repo *DockerImagesRepo) ImageRepositoryName(imageName string) string {
 	return repo.imagesRepoManager.ImageRepo(imageName)
 }
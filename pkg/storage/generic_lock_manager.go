@@ -1,20 +1,28 @@
 package storage
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/flant/lockgate"
 	"github.com/flant/logboek"
+	"github.com/flant/werf/pkg/util"
 	"github.com/flant/werf/pkg/werf"
 )
 
 func NewGenericLockManager(locker lockgate.Locker) *GenericLockManager {
-	return &GenericLockManager{Locker: locker}
+	return &GenericLockManager{Locker: locker, OwnersDir: filepath.Join(werf.GetServiceDir(), "lock_owners")}
 }
 
 type GenericLockManager struct {
 	// Single Locker for all projects
 	Locker lockgate.Locker
+	// OwnersDir contains LockOwner records of the acquired locks: OwnersDir/<lock name hash>/<owner id>.json
+	OwnersDir string
 }
 
 type LockStagesAndImagesOptions struct {
@@ -22,31 +30,32 @@ type LockStagesAndImagesOptions struct {
 }
 
 func (manager *GenericLockManager) LockStage(projectName, signature string) (LockHandle, error) {
-	_, lock, err := manager.Locker.Acquire(genericStageLockName(projectName, signature), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{}))
-	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+	return manager.acquire(projectName, genericStageLockName(projectName, signature), lockgate.AcquireOptions{})
 }
 
 func (manager *GenericLockManager) LockStageCache(projectName, signature string) (LockHandle, error) {
-	_, lock, err := manager.Locker.Acquire(genericStageCacheLockName(projectName, signature), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{}))
-	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+	return manager.acquire(projectName, genericStageCacheLockName(projectName, signature), lockgate.AcquireOptions{})
 }
 
 func (manager *GenericLockManager) LockImage(projectName, imageName string) (LockHandle, error) {
-	_, lock, err := manager.Locker.Acquire(genericImageLockName(imageName), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{}))
-	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+	return manager.acquire(projectName, genericImageLockName(imageName), lockgate.AcquireOptions{})
 }
 
 func (manager *GenericLockManager) LockStagesAndImages(projectName string, opts LockStagesAndImagesOptions) (LockHandle, error) {
-	_, lock, err := manager.Locker.Acquire(genericStagesAndImagesLockName(projectName), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{Shared: opts.GetOrCreateImagesOnly}))
-	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+	return manager.acquire(projectName, genericStagesAndImagesLockName(projectName), lockgate.AcquireOptions{Shared: opts.GetOrCreateImagesOnly})
 }
 
 func (manager *GenericLockManager) LockDeployProcess(projectName string, releaseName string, kubeContextName string) (LockHandle, error) {
-	_, lock, err := manager.Locker.Acquire(genericDeployReleaseLockName(projectName, releaseName, kubeContextName), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{}))
-	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+	return manager.acquire(projectName, genericDeployReleaseLockName(projectName, releaseName, kubeContextName), lockgate.AcquireOptions{})
 }
 
 func (manager *GenericLockManager) Unlock(lock LockHandle) error {
+	if lock.OwnerID != "" {
+		if err := os.Remove(manager.ownerPath(lock.LockgateHandle.LockName, lock.OwnerID)); err != nil && !os.IsNotExist(err) {
+			logboek.LogWarnF("WARNING: unable to remove owner of the lock %q: %s\n", lock.LockgateHandle.LockName, err)
+		}
+	}
+
 	err := manager.Locker.Release(lock.LockgateHandle)
 	if err != nil {
 		logboek.ErrF("ERROR: unable to release lock for %q: %s", lock.LockgateHandle.LockName, err)
@@ -54,6 +63,214 @@ func (manager *GenericLockManager) Unlock(lock LockHandle) error {
 	return err
 }
 
+func (manager *GenericLockManager) ListLocks(projectName string) ([]*LockInfo, error) {
+	owners, err := manager.readOwners("")
+	if err != nil {
+		return nil, err
+	}
+
+	locksByName := map[string]*LockInfo{}
+	var locks []*LockInfo
+	for _, owner := range owners {
+		if owner.ProjectName != projectName {
+			continue
+		}
+
+		lock, hasKey := locksByName[owner.LockName]
+		if !hasKey {
+			lock = &LockInfo{Name: owner.LockName, Stale: true}
+			locksByName[owner.LockName] = lock
+			locks = append(locks, lock)
+		}
+
+		lock.Owners = append(lock.Owners, owner)
+		if owner.Shared {
+			lock.Shared = true
+			lock.HoldersCount = len(lock.Owners)
+		}
+		if !owner.isLocalProcessDead() {
+			lock.Stale = false
+		}
+	}
+
+	sort.Slice(locks, func(i, j int) bool { return locks[i].Name < locks[j].Name })
+
+	return locks, nil
+}
+
+// ReleaseLock removes records of the dead lock owners: host lock itself is released by the OS when the owner process exits
+// and could not be taken away from the running process
+func (manager *GenericLockManager) ReleaseLock(projectName, lockName string, force bool) error {
+	owners, err := manager.readOwners(lockName)
+	if err != nil {
+		return err
+	}
+
+	var found bool
+	for _, owner := range owners {
+		if owner.ProjectName != projectName {
+			continue
+		}
+		found = true
+
+		if !owner.isLocalProcessDead() {
+			host, _ := os.Hostname()
+			if owner.Host == host {
+				return fmt.Errorf("lock %q is held by the running process %d, host lock could be released only by the owner process", lockName, owner.PID)
+			} else if !force {
+				return fmt.Errorf("lock %q is held by the process %d on the host %s, which cannot be checked: use --force option to remove the owner record anyway", lockName, owner.PID, owner.Host)
+			}
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("lock %q not found", lockName)
+	}
+
+	for _, owner := range owners {
+		if owner.ProjectName != projectName {
+			continue
+		}
+
+		if err := os.Remove(manager.ownerPath(lockName, owner.ID)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to remove owner %s of the lock %q: %s", owner.ID, lockName, err)
+		}
+	}
+
+	return nil
+}
+
+func (manager *GenericLockManager) acquire(projectName, lockName string, opts lockgate.AcquireOptions) (LockHandle, error) {
+	opts = werf.SetupLockerOptionsWithHoldersInfo(opts, func() ([]string, error) {
+		owners, err := manager.readOwners(lockName)
+		if err != nil {
+			return nil, err
+		}
+
+		var liveOwners []*LockOwner
+		for _, owner := range owners {
+			if !owner.isLocalProcessDead() {
+				liveOwners = append(liveOwners, owner)
+			}
+		}
+
+		return lockHoldersInfo(liveOwners), nil
+	})
+
+	_, lock, err := manager.Locker.Acquire(lockName, opts)
+	handle := LockHandle{LockgateHandle: lock, ProjectName: projectName}
+	if err != nil || manager.OwnersDir == "" {
+		return handle, err
+	}
+
+	manager.removeDeadOwners(lockName)
+
+	owner := newLockOwner(projectName, lockName, lock.UUID, opts.Shared)
+	if err := manager.writeOwner(owner); err != nil {
+		logboek.LogWarnF("WARNING: unable to save owner of the lock %q: %s\n", lockName, err)
+	} else {
+		handle.OwnerID = owner.ID
+	}
+
+	return handle, nil
+}
+
+// removeDeadOwners cleans up records left by the killed processes
+func (manager *GenericLockManager) removeDeadOwners(lockName string) {
+	owners, err := manager.readOwners(lockName)
+	if err != nil {
+		logboek.LogWarnF("WARNING: unable to read owners of the lock %q: %s\n", lockName, err)
+		return
+	}
+
+	for _, owner := range owners {
+		if owner.isLocalProcessDead() {
+			_ = os.Remove(manager.ownerPath(lockName, owner.ID))
+		}
+	}
+}
+
+func (manager *GenericLockManager) ownerPath(lockName, ownerID string) string {
+	return filepath.Join(manager.OwnersDir, util.Sha256Hash(lockName), fmt.Sprintf("%s.json", ownerID))
+}
+
+func (manager *GenericLockManager) writeOwner(owner *LockOwner) error {
+	path := manager.ownerPath(owner.LockName, owner.ID)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(owner)
+	if err != nil {
+		return err
+	}
+
+	// owners are read concurrently by other processes, so the record is written into the temporary file first
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf("%s.*.tmp", owner.ID))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmpFile.Write(append(data, '\n')); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+
+	return nil
+}
+
+// readOwners returns owners of the lock or owners of all locks if lockName is empty
+func (manager *GenericLockManager) readOwners(lockName string) ([]*LockOwner, error) {
+	if manager.OwnersDir == "" {
+		return nil, nil
+	}
+
+	pattern := filepath.Join(manager.OwnersDir, "*", "*.json")
+	if lockName != "" {
+		pattern = manager.ownerPath(lockName, "*")
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []*LockOwner
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			// owner has released the lock
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %s", path, err)
+		}
+
+		owner := &LockOwner{}
+		if err := json.Unmarshal(data, owner); err != nil {
+			// broken record should not prevent locking
+			logboek.Debug.LogF("Skipping invalid lock owner record %s: %s\n", path, err)
+			continue
+		}
+
+		owners = append(owners, owner)
+	}
+
+	sort.Slice(owners, func(i, j int) bool { return owners[i].AcquiredAt.Before(owners[j].AcquiredAt) })
+
+	return owners, nil
+}
+
 func genericStageLockName(projectName, signature string) string {
 	return fmt.Sprintf("%s.%s", projectName, signature)
 }
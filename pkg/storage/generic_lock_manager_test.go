@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flant/lockgate"
+)
+
+func TestGenericLockManagerOwners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-generic-lock-manager-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	locker, err := lockgate.NewFileLocker(filepath.Join(dir, "locks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := &GenericLockManager{Locker: locker, OwnersDir: filepath.Join(dir, "lock_owners")}
+
+	lock, err := manager.LockStagesAndImages("demo", LockStagesAndImagesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the record left by the killed process
+	deadOwner := newLockOwner("demo", genericDeployReleaseLockName("demo", "demo-stage", "prod"), "", false)
+	deadOwner.PID = 1 << 30
+	if err := manager.writeOwner(deadOwner); err != nil {
+		t.Fatal(err)
+	}
+
+	// invalid record should be skipped
+	if err := ioutil.WriteFile(manager.ownerPath(deadOwner.LockName, "broken"), []byte("{\"id\": "), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if tmpFiles, err := filepath.Glob(filepath.Join(manager.OwnersDir, "*", "*.tmp")); err != nil {
+		t.Fatal(err)
+	} else if len(tmpFiles) != 0 {
+		t.Errorf("expected no temporary owner files, got %v", tmpFiles)
+	}
+
+	locks, err := manager.ListLocks("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(locks) != 2 {
+		t.Fatalf("expected 2 locks, got %d", len(locks))
+	}
+
+	if locks[0].Name != "demo.stages_and_images" || locks[0].Stale || len(locks[0].Owners) != 1 || locks[0].Owners[0].PID != os.Getpid() {
+		t.Errorf("expected lock of the current process, got %+v", *locks[0])
+	}
+
+	if locks[1].Name != deadOwner.LockName || !locks[1].Stale {
+		t.Errorf("expected stale lock %q, got %+v", deadOwner.LockName, *locks[1])
+	}
+
+	if err := manager.ReleaseLock("demo", locks[0].Name, true); err == nil {
+		t.Errorf("expected error releasing the lock held by the running process")
+	}
+
+	if err := manager.ReleaseLock("demo", deadOwner.LockName, false); err != nil {
+		t.Errorf("unexpected error releasing the stale lock: %s", err)
+	}
+
+	if err := manager.Unlock(lock); err != nil {
+		t.Fatal(err)
+	}
+
+	if locks, err := manager.ListLocks("demo"); err != nil {
+		t.Fatal(err)
+	} else if len(locks) != 0 {
+		t.Errorf("expected no locks, got %d", len(locks))
+	}
+}
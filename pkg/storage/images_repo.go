@@ -1,18 +1,26 @@
 package storage
 
 import (
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
 	"github.com/flant/werf/pkg/container_runtime"
+	"github.com/flant/werf/pkg/docker_registry"
 	"github.com/flant/werf/pkg/image"
 )
 
 type ImagesRepo interface {
 	GetRepoImage(imageName, tag string) (*image.Info, error)
+	GetRepoImageByPlatform(imageName, tag, platform string) (*image.Info, error)
 	GetRepoImages(imageNames []string) (map[string][]*image.Info, error)
 	SelectRepoImages(imageNames []string, f func(string, *image.Info, error) (bool, error)) (map[string][]*image.Info, error)
 	DeleteRepoImage(_ DeleteImageOptions, repoImageList ...*image.Info) error
 
 	GetAllImageRepoTags(imageName string) ([]string, error)
 	PublishImage(publishImage *container_runtime.WerfImage) error
+	// PublishImageByDigest will push the image without a tag, returns the digest of the published image
+	PublishImageByDigest(imageName string, img v1.Image) (string, error)
+	// PublishManifestList will create the manifest list by tag from the images published by digests before
+	PublishManifestList(imageName, tag string, entries []docker_registry.ManifestListEntry) error
 
 	CreateImageRepo(imageName string) error
 	DeleteImageRepo(imageName string) error
@@ -1,17 +1,26 @@
 package storage
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/flant/kubedog/pkg/kube"
 	"github.com/flant/lockgate"
 	"github.com/flant/logboek"
+	"github.com/flant/werf/pkg/util"
 	"github.com/flant/werf/pkg/werf"
 )
 
+const lockOwnersDataKeyPrefix = "lock-owners."
+
 func NewKubernetesLockManager(namespace string) *KuberntesLockManager {
 	return &KuberntesLockManager{
 		Namespace:        namespace,
@@ -52,48 +61,23 @@ func (manager *KuberntesLockManager) getLockerForProject(projectName string) (lo
 }
 
 func (manager *KuberntesLockManager) LockStage(projectName, signature string) (LockHandle, error) {
-	if locker, err := manager.getLockerForProject(projectName); err != nil {
-		return LockHandle{}, err
-	} else {
-		_, lock, err := locker.Acquire(kubernetesStageLockName(projectName, signature), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{}))
-		return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
-	}
+	return manager.acquire(projectName, kubernetesStageLockName(projectName, signature), lockgate.AcquireOptions{}, false)
 }
 
 func (manager *KuberntesLockManager) LockStageCache(projectName, signature string) (LockHandle, error) {
-	if locker, err := manager.getLockerForProject(projectName); err != nil {
-		return LockHandle{}, err
-	} else {
-		_, lock, err := locker.Acquire(kubernetesStageCacheLockName(projectName, signature), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{}))
-		return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
-	}
+	return manager.acquire(projectName, kubernetesStageCacheLockName(projectName, signature), lockgate.AcquireOptions{}, false)
 }
 
 func (manager *KuberntesLockManager) LockImage(projectName, imageName string) (LockHandle, error) {
-	if locker, err := manager.getLockerForProject(projectName); err != nil {
-		return LockHandle{}, err
-	} else {
-		_, lock, err := locker.Acquire(kuberntesImageLockName(projectName, imageName), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{}))
-		return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
-	}
+	return manager.acquire(projectName, kuberntesImageLockName(projectName, imageName), lockgate.AcquireOptions{}, false)
 }
 
 func (manager *KuberntesLockManager) LockStagesAndImages(projectName string, opts LockStagesAndImagesOptions) (LockHandle, error) {
-	if locker, err := manager.getLockerForProject(projectName); err != nil {
-		return LockHandle{}, err
-	} else {
-		_, lock, err := locker.Acquire(kuberntesStagesAndImagesLockName(projectName), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{Shared: opts.GetOrCreateImagesOnly}))
-		return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
-	}
+	return manager.acquire(projectName, kuberntesStagesAndImagesLockName(projectName), lockgate.AcquireOptions{Shared: opts.GetOrCreateImagesOnly}, true)
 }
 
 func (manager *KuberntesLockManager) LockDeployProcess(projectName string, releaseName string, kubeContextName string) (LockHandle, error) {
-	if locker, err := manager.getLockerForProject(projectName); err != nil {
-		return LockHandle{}, err
-	} else {
-		_, lock, err := locker.Acquire(kubernetesDeployReleaseLockName(projectName, releaseName, kubeContextName), werf.SetupLockerDefaultOptions(lockgate.AcquireOptions{}))
-		return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
-	}
+	return manager.acquire(projectName, kubernetesDeployReleaseLockName(projectName, releaseName, kubeContextName), lockgate.AcquireOptions{}, true)
 }
 
 func (manager *KuberntesLockManager) Unlock(lock LockHandle) error {
@@ -104,8 +88,260 @@ func (manager *KuberntesLockManager) Unlock(lock LockHandle) error {
 		if err != nil {
 			logboek.ErrF("ERROR: unable to release lock for %q: %s", lock.LockgateHandle.LockName, err)
 		}
+
+		if lock.OwnerID != "" {
+			if err := manager.changeLockOwners(lock.ProjectName, lock.LockgateHandle.LockName, func(owners []*LockOwner) []*LockOwner {
+				var res []*LockOwner
+				for _, owner := range owners {
+					if owner.ID != lock.OwnerID {
+						res = append(res, owner)
+					}
+				}
+				return res
+			}); err != nil {
+				logboek.LogWarnF("WARNING: unable to remove owner of the lock %q: %s\n", lock.LockgateHandle.LockName, err)
+			}
+		}
+
+		return err
+	}
+}
+
+func (manager *KuberntesLockManager) ListLocks(projectName string) ([]*LockInfo, error) {
+	obj, err := kube.Kubernetes.CoreV1().ConfigMaps(manager.Namespace).Get(configMapName(projectName), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get cm/%s error: %s", configMapName(projectName), err)
+	}
+
+	var locks []*LockInfo
+	for _, leaseAnnotation := range extractLockLeaseAnnotations(obj) {
+		lease := leaseAnnotation.Lease
+
+		owners, err := extractLockOwners(obj, lease.LockName)
+		if err != nil {
+			return nil, err
+		}
+
+		expireAt := time.Unix(lease.ExpireAtTimestamp, 0)
+		lock := &LockInfo{
+			Name:     lease.LockName,
+			Shared:   lease.IsShared,
+			Owners:   filterLeaseOwners(owners, lease),
+			ExpireAt: expireAt,
+			Stale:    time.Now().After(expireAt),
+		}
+		if lease.IsShared {
+			lock.HoldersCount = int(lease.SharedHoldersCount)
+		}
+
+		locks = append(locks, lock)
+	}
+
+	sort.Slice(locks, func(i, j int) bool { return locks[i].Name < locks[j].Name })
+
+	return locks, nil
+}
+
+// ReleaseLock removes the lease of the lock, lease which is still renewed by the running owner could be removed only with force:
+// the owner process will crash on the lost lease
+func (manager *KuberntesLockManager) ReleaseLock(projectName, lockName string, force bool) error {
+RETRY_RELEASE:
+
+	obj, err := kube.Kubernetes.CoreV1().ConfigMaps(manager.Namespace).Get(configMapName(projectName), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("lock %q not found", lockName)
+	} else if err != nil {
+		return fmt.Errorf("get cm/%s error: %s", configMapName(projectName), err)
+	}
+
+	leaseAnnotation := extractLockLeaseAnnotation(obj, lockName)
+	_, hasOwners := obj.Data[lockOwnersDataKey(lockName)]
+	if leaseAnnotation == nil && !hasOwners {
+		return fmt.Errorf("lock %q not found", lockName)
+	}
+
+	if leaseAnnotation != nil {
+		if expireAt := time.Unix(leaseAnnotation.Lease.ExpireAtTimestamp, 0); time.Now().Before(expireAt) && !force {
+			return fmt.Errorf("lock %q lease is renewed by the running owner until %s: use --force option to release it anyway, the owner process will crash", lockName, expireAt.Format(time.RFC3339))
+		}
+
+		delete(obj.Annotations, leaseAnnotation.Name)
+	}
+
+	delete(obj.Data, lockOwnersDataKey(lockName))
+
+	if _, err := kube.Kubernetes.CoreV1().ConfigMaps(manager.Namespace).Update(obj); errors.IsConflict(err) {
+		goto RETRY_RELEASE
+	} else if err != nil {
+		return fmt.Errorf("update cm/%s error: %s", obj.Name, err)
+	}
+
+	return nil
+}
+
+// acquire saves the owner only for the long-held locks when recordOwner is set:
+// stage, stage cache and image locks are acquired for each stage and the owner record would cost an additional cm update for each lock and unlock
+func (manager *KuberntesLockManager) acquire(projectName, lockName string, opts lockgate.AcquireOptions, recordOwner bool) (LockHandle, error) {
+	locker, err := manager.getLockerForProject(projectName)
+	if err != nil {
+		return LockHandle{}, err
+	}
+
+	opts = werf.SetupLockerOptionsWithHoldersInfo(opts, func() ([]string, error) {
+		obj, err := kube.Kubernetes.CoreV1().ConfigMaps(manager.Namespace).Get(configMapName(projectName), metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get cm/%s error: %s", configMapName(projectName), err)
+		}
+
+		owners, err := extractLockOwners(obj, lockName)
+		if err != nil {
+			return nil, err
+		}
+
+		return lockHoldersInfo(filterLeaseOwners(owners, extractLockLease(obj, lockName))), nil
+	})
+
+	// KubernetesLocker polls the lease without calling OnWaitFunc, so try to acquire the lock without waiting first
+	nonBlockingOpts := opts
+	nonBlockingOpts.NonBlocking = true
+
+	acquired, lock, err := locker.Acquire(lockName, nonBlockingOpts)
+	if err != nil {
+		return LockHandle{ProjectName: projectName}, err
+	}
+
+	if !acquired {
+		if err := opts.OnWaitFunc(lockgate.LockHandle{LockName: lockName}, func() error {
+			_, lock, err = locker.Acquire(lockName, opts)
+			return err
+		}); err != nil {
+			return LockHandle{ProjectName: projectName}, err
+		}
+	}
+
+	handle := LockHandle{LockgateHandle: lock, ProjectName: projectName}
+	if !recordOwner {
+		return handle, nil
+	}
+
+	owner := newLockOwner(projectName, lockName, lock.UUID, opts.Shared)
+	if err := manager.changeLockOwners(projectName, lockName, func(owners []*LockOwner) []*LockOwner {
+		return append(owners, owner)
+	}); err != nil {
+		logboek.LogWarnF("WARNING: unable to save owner of the lock %q: %s\n", lockName, err)
+	} else {
+		handle.OwnerID = owner.ID
+	}
+
+	return handle, nil
+}
+
+// changeLockOwners changes owners of the lock, owners of the previous leases are dropped
+func (manager *KuberntesLockManager) changeLockOwners(projectName, lockName string, changeFunc func(owners []*LockOwner) []*LockOwner) error {
+RETRY_CHANGE:
+
+	obj, err := getOrCreateConfigMapWithNamespaceIfNotExists(manager.Namespace, configMapName(projectName))
+	if err != nil {
+		return err
+	}
+
+	owners, err := extractLockOwners(obj, lockName)
+	if err != nil {
+		return err
+	}
+
+	owners = filterLeaseOwners(changeFunc(owners), extractLockLease(obj, lockName))
+	if len(owners) == 0 {
+		delete(obj.Data, lockOwnersDataKey(lockName))
+	} else if data, err := json.Marshal(owners); err != nil {
 		return err
+	} else {
+		if obj.Data == nil {
+			obj.Data = make(map[string]string)
+		}
+		obj.Data[lockOwnersDataKey(lockName)] = string(data)
+	}
+
+	if _, err := kube.Kubernetes.CoreV1().ConfigMaps(manager.Namespace).Update(obj); errors.IsConflict(err) {
+		goto RETRY_CHANGE
+	} else if err != nil {
+		return fmt.Errorf("update cm/%s error: %s", obj.Name, err)
+	}
+
+	return nil
+}
+
+// lockLeaseAnnotation is the lease record of the lockgate KubernetesLocker.
+// The annotation name is an implementation detail of lockgate, so leases are recognized by the annotation value
+type lockLeaseAnnotation struct {
+	Name  string
+	Lease *lockgate.LockLeaseRecord
+}
+
+func extractLockLeaseAnnotations(obj *v1.ConfigMap) []*lockLeaseAnnotation {
+	var res []*lockLeaseAnnotation
+	for name, value := range obj.Annotations {
+		var lease *lockgate.LockLeaseRecord
+		if err := json.Unmarshal([]byte(value), &lease); err != nil || lease == nil {
+			continue
+		}
+
+		if lease.LockName == "" || lease.UUID == "" || lease.ExpireAtTimestamp == 0 {
+			continue
+		}
+
+		res = append(res, &lockLeaseAnnotation{Name: name, Lease: lease})
 	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res
+}
+
+func extractLockLeaseAnnotation(obj *v1.ConfigMap, lockName string) *lockLeaseAnnotation {
+	for _, leaseAnnotation := range extractLockLeaseAnnotations(obj) {
+		if leaseAnnotation.Lease.LockName == lockName {
+			return leaseAnnotation
+		}
+	}
+	return nil
+}
+
+func extractLockLease(obj *v1.ConfigMap, lockName string) *lockgate.LockLeaseRecord {
+	if leaseAnnotation := extractLockLeaseAnnotation(obj, lockName); leaseAnnotation != nil {
+		return leaseAnnotation.Lease
+	}
+	return nil
+}
+
+func extractLockOwners(obj *v1.ConfigMap, lockName string) ([]*LockOwner, error) {
+	var owners []*LockOwner
+	if value, hasKey := obj.Data[lockOwnersDataKey(lockName)]; hasKey {
+		if err := json.Unmarshal([]byte(value), &owners); err != nil {
+			return nil, fmt.Errorf("invalid %s data in cm/%s: %s", lockOwnersDataKey(lockName), obj.Name, err)
+		}
+	}
+	return owners, nil
+}
+
+func filterLeaseOwners(owners []*LockOwner, lease *lockgate.LockLeaseRecord) []*LockOwner {
+	if lease == nil {
+		return nil
+	}
+
+	var res []*LockOwner
+	for _, owner := range owners {
+		if owner.LeaseUUID == lease.UUID {
+			res = append(res, owner)
+		}
+	}
+	return res
+}
+
+func lockOwnersDataKey(lockName string) string {
+	return fmt.Sprintf("%s%s", lockOwnersDataKeyPrefix, util.Sha3_224Hash(lockName))
 }
 
 func kubernetesStageLockName(_, signature string) string {
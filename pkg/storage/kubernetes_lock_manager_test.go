@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+
+	"github.com/flant/lockgate"
+)
+
+func TestExtractLockLease(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	cm := &v1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "werf-demo",
+			Namespace: "werf-synchronization",
+			Annotations: map[string]string{
+				"kubectl.kubernetes.io/last-applied-configuration": `{"apiVersion":"v1","kind":"ConfigMap"}`,
+				"description": "not a json",
+			},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	client := fake.NewSimpleDynamicClient(scheme, cm)
+	locker := lockgate.NewKubernetesLocker(client, gvr, cm.Name, cm.Namespace)
+
+	// the lease is created by lockgate itself to check that werf recognizes its format
+	_, handle, err := locker.Acquire("stages_and_images", lockgate.AcquireOptions{Shared: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer locker.Release(handle)
+
+	obj, err := client.Resource(gvr).Namespace(cm.Namespace).Get(cm.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var leasedCM v1.ConfigMap
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), &leasedCM); err != nil {
+		t.Fatal(err)
+	}
+
+	leaseAnnotations := extractLockLeaseAnnotations(&leasedCM)
+	if len(leaseAnnotations) != 1 {
+		t.Fatalf("expected 1 lease annotation, got %d", len(leaseAnnotations))
+	}
+
+	lease := extractLockLease(&leasedCM, "stages_and_images")
+	if lease == nil {
+		t.Fatalf("expected lease of the lock to be found")
+	}
+
+	if lease.UUID != handle.UUID || !lease.IsShared {
+		t.Errorf("unexpected lease %+v of the lock handle %+v", lease, handle)
+	}
+
+	if lease := extractLockLease(&leasedCM, "image/app"); lease != nil {
+		t.Errorf("unexpected lease %+v of the lock which is not acquired", lease)
+	}
+}
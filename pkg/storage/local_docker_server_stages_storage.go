@@ -176,6 +176,27 @@ func (storage *LocalDockerServerStagesStorage) GetManagedImages(projectName stri
 	return res, nil
 }
 
+// FetchDockerfileCache does nothing: docker server keeps the layers cache of built dockerfile images locally
+func (storage *LocalDockerServerStagesStorage) FetchDockerfileCache(_, _ string) (string, error) {
+	return "", nil
+}
+
+func (storage *LocalDockerServerStagesStorage) StoreDockerfileCache(_, _ string, _ container_runtime.Image) error {
+	return nil
+}
+
+func (storage *LocalDockerServerStagesStorage) RmDockerfileCache(_, _ string) error {
+	return nil
+}
+
+func (storage *LocalDockerServerStagesStorage) GetDockerfileCaches(_ string) ([]string, error) {
+	return nil, nil
+}
+
+func (storage *LocalDockerServerStagesStorage) GetDockerfileCacheStageSignature(_, _ string) (string, error) {
+	return "", nil
+}
+
 func (storage *LocalDockerServerStagesStorage) GetStagesBySignature(projectName, signature string) ([]image.StageID, error) {
 	filterSet := filters.NewArgs()
 	filterSet.Add("reference", fmt.Sprintf(LocalStage_ImageRepoFormat, projectName))
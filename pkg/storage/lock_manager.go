@@ -9,9 +9,14 @@ type LockManager interface {
 	LockStagesAndImages(projectName string, opts LockStagesAndImagesOptions) (LockHandle, error)
 	LockDeployProcess(projectName string, releaseName string, kubeContextName string) (LockHandle, error)
 	Unlock(lock LockHandle) error
+
+	ListLocks(projectName string) ([]*LockInfo, error)
+	ReleaseLock(projectName, lockName string, force bool) error
 }
 
 type LockHandle struct {
 	ProjectName    string
 	LockgateHandle lockgate.LockHandle
+	// OwnerID is the id of the LockOwner record saved for the acquired lock
+	OwnerID string
 }
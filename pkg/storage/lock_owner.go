@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LockOwner describes the werf process which holds the lock
+type LockOwner struct {
+	ID          string    `json:"id"`
+	LeaseUUID   string    `json:"leaseUUID,omitempty"`
+	ProjectName string    `json:"projectName"`
+	LockName    string    `json:"lockName"`
+	Host        string    `json:"host"`
+	PID         int       `json:"pid"`
+	CIJob       string    `json:"ciJob,omitempty"`
+	AcquiredAt  time.Time `json:"acquiredAt"`
+	Shared      bool      `json:"shared,omitempty"`
+}
+
+// LockInfo describes the lock of the project in the synchronization backend
+type LockInfo struct {
+	Name   string
+	Shared bool
+	// HoldersCount is the number of lease holders for the shared lock, 0 when the backend does not track it
+	HoldersCount int
+	Owners       []*LockOwner
+	// ExpireAt is the time when the lease expires unless the owner renews it, zero when the backend does not use leases
+	ExpireAt time.Time
+	// Stale lock has no live owners and could be released with the werf locks release command
+	Stale bool
+}
+
+func newLockOwner(projectName, lockName, leaseUUID string, shared bool) *LockOwner {
+	host, _ := os.Hostname()
+
+	return &LockOwner{
+		ID:          uuid.New().String(),
+		LeaseUUID:   leaseUUID,
+		ProjectName: projectName,
+		LockName:    lockName,
+		Host:        host,
+		PID:         os.Getpid(),
+		CIJob:       getCIJob(),
+		AcquiredAt:  time.Now(),
+		Shared:      shared,
+	}
+}
+
+func (owner *LockOwner) String() string {
+	desc := fmt.Sprintf("host %s pid %d", owner.Host, owner.PID)
+	if owner.CIJob != "" {
+		desc += fmt.Sprintf(" (CI job %s)", owner.CIJob)
+	}
+	return fmt.Sprintf("%s since %s", desc, owner.AcquiredAt.Format(time.RFC3339))
+}
+
+// isLocalProcessDead returns true only when the owner process is known to be finished
+func (owner *LockOwner) isLocalProcessDead() bool {
+	host, _ := os.Hostname()
+	return owner.Host == host && !isProcessAlive(owner.PID)
+}
+
+func getCIJob() string {
+	if jobUrl := os.Getenv("CI_JOB_URL"); jobUrl != "" {
+		return jobUrl
+	}
+
+	if runId := os.Getenv("GITHUB_RUN_ID"); runId != "" {
+		return fmt.Sprintf("%s/%s/actions/runs/%s", os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), runId)
+	}
+
+	if buildUrl := os.Getenv("BUILD_URL"); buildUrl != "" {
+		return buildUrl
+	}
+
+	return ""
+}
+
+func lockHoldersInfo(owners []*LockOwner) []string {
+	var res []string
+	for _, owner := range owners {
+		res = append(res, owner.String())
+	}
+	return res
+}
@@ -0,0 +1,13 @@
+// +build linux darwin
+
+package storage
+
+import "syscall"
+
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	if err == syscall.EPERM || err == nil {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,7 @@
+// +build windows
+
+package storage
+
+func isProcessAlive(_ int) bool {
+	return true
+}
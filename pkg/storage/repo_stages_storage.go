@@ -11,7 +11,6 @@ import (
 	"github.com/flant/werf/pkg/image"
 
 	"github.com/flant/werf/pkg/container_runtime"
-	"github.com/flant/werf/pkg/docker"
 
 	"github.com/flant/logboek"
 	"github.com/flant/werf/pkg/docker_registry"
@@ -23,6 +22,9 @@ const (
 	RepoManagedImageRecord_ImageTagPrefix  = "managed-image-"
 	RepoManagedImageRecord_ImageNameFormat = "%s:managed-image-%s"
 
+	RepoDockerfileCache_ImageTagPrefix  = "dockerfile-cache-"
+	RepoDockerfileCache_ImageNameFormat = "%s:dockerfile-cache-%s"
+
 	UnexpectedTagFormatErrorPrefix = "unexpected tag format"
 )
 
@@ -83,7 +85,7 @@ func (storage *RepoStagesStorage) GetAllStages(projectName string) ([]image.Stag
 		logboek.Debug.LogF("-- RepoStagesStorage.GetRepoImagesBySignature fetched tags for %q: %#v\n", storage.RepoAddress, tags)
 
 		for _, tag := range tags {
-			if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) {
+			if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoDockerfileCache_ImageTagPrefix) {
 				continue
 			}
 
@@ -132,12 +134,16 @@ func (storage *RepoStagesStorage) GetStagesBySignature(projectName, signature st
 				logboek.Debug.LogF("Discard tag %q: should have prefix %q\n", tag, signature)
 				continue
 			}
-			if _, uniqueID, err := getSignatureAndUniqueIDFromRepoStageImageTag(tag); err != nil {
+			if tagSignature, uniqueID, err := getSignatureAndUniqueIDFromRepoStageImageTag(tag); err != nil {
 				if isUnexpectedTagFormatError(err) {
 					logboek.Debug.LogLn(strings.Title(err.Error()))
 					continue
 				}
 				return nil, err
+			} else if tagSignature != signature {
+				// platform variant of the stage has the same signature prefix
+				logboek.Debug.LogF("Discard tag %q: should have signature %q\n", tag, signature)
+				continue
 			} else {
 				logboek.Debug.LogF("Tag %q is suitable for signature %q\n", tag, signature)
 				res = append(res, image.StageID{Signature: signature, UniqueID: uniqueID})
@@ -187,19 +193,21 @@ func (storage *RepoStagesStorage) AddManagedImage(projectName, imageName string)
 
 	logboek.Debug.LogF("-- RepoStagesStorage.AddManagedImage record %q does not exist => creating record\n", fullImageName)
 
-	switch storage.ContainerRuntime.(type) {
-	case *container_runtime.LocalDockerServerRuntime:
-		if err := docker.CreateImage(fullImageName); err != nil {
+	switch containerRuntime := storage.ContainerRuntime.(type) {
+	case container_runtime.LocalRuntime:
+		if err := containerRuntime.CreateEmptyImage(fullImageName); err != nil {
 			return fmt.Errorf("unable to create image %q: %s", fullImageName, err)
 		}
+
+		img := &container_runtime.DockerImage{Image: container_runtime.NewStageImage(nil, fullImageName, containerRuntime)}
 		defer func() {
-			if err := docker.CliRmi("--force", fullImageName); err != nil {
+			if err := containerRuntime.RemoveImage(img); err != nil {
 				// TODO: errored repo state
 				logboek.Error.LogF("unable to remove temporary image %q: %s", fullImageName, err)
 			}
 		}()
 
-		if err := docker.CliPushWithRetries(fullImageName); err != nil {
+		if err := containerRuntime.PushImage(img); err != nil {
 			return fmt.Errorf("unable to push image %q: %s", fullImageName, err)
 		}
 
@@ -241,24 +249,123 @@ func (storage *RepoStagesStorage) GetManagedImages(projectName string) ([]string
 				continue
 			}
 
-			tag = strings.ReplaceAll(tag, "__slash__", "/")
-			tag = strings.ReplaceAll(tag, "__plus__", "+")
+			res = append(res, imageNameFromRecordTagSuffix(strings.TrimPrefix(tag, RepoManagedImageRecord_ImageTagPrefix)))
+		}
+	}
 
-			managedImageName := strings.TrimPrefix(tag, RepoManagedImageRecord_ImageTagPrefix)
-			if managedImageName == NamelessImageRecordTag {
-				res = append(res, "")
-			} else {
-				res = append(res, managedImageName)
+	return res, nil
+}
+
+func (storage *RepoStagesStorage) FetchDockerfileCache(projectName, imageName string) (string, error) {
+	logboek.Debug.LogF("-- RepoStagesStorage.FetchDockerfileCache %s %s\n", projectName, imageName)
+
+	cacheImageName := makeRepoDockerfileCacheRecord(storage.RepoAddress, imageName)
+
+	if isExists, err := storage.DockerRegistry.IsRepoImageExists(cacheImageName); err != nil {
+		return "", err
+	} else if !isExists {
+		logboek.Debug.LogF("-- RepoStagesStorage.FetchDockerfileCache cache %q does not exist => exiting\n", cacheImageName)
+		return "", nil
+	}
+
+	switch containerRuntime := storage.ContainerRuntime.(type) {
+	case container_runtime.LocalRuntime:
+		img := &container_runtime.DockerImage{Image: container_runtime.NewStageImage(nil, cacheImageName, containerRuntime)}
+		if err := containerRuntime.PullImageFromRegistry(img); err != nil {
+			return "", err
+		}
+		return cacheImageName, nil
+	default: // TODO: case *container_runtime.LocalHostRuntime:
+		panic("not implemented")
+	}
+}
+
+func (storage *RepoStagesStorage) StoreDockerfileCache(projectName, imageName string, img container_runtime.Image) error {
+	logboek.Debug.LogF("-- RepoStagesStorage.StoreDockerfileCache %s %s\n", projectName, imageName)
+
+	cacheImageName := makeRepoDockerfileCacheRecord(storage.RepoAddress, imageName)
+
+	switch containerRuntime := storage.ContainerRuntime.(type) {
+	case container_runtime.LocalRuntime:
+		dockerImage := img.(*container_runtime.DockerImage)
+		cacheImg := &container_runtime.DockerImage{Image: container_runtime.NewStageImage(nil, dockerImage.Image.Name(), containerRuntime)}
+
+		if err := containerRuntime.RenameImage(cacheImg, cacheImageName, false); err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := containerRuntime.RemoveImage(cacheImg); err != nil {
+				logboek.Error.LogF("unable to remove temporary image %q: %s", cacheImageName, err)
+			}
+		}()
+
+		if err := containerRuntime.PushImage(cacheImg); err != nil {
+			return fmt.Errorf("unable to push image %q: %s", cacheImageName, err)
+		}
+
+		return nil
+	default: // TODO: case *container_runtime.LocalHostRuntime:
+		panic("not implemented")
+	}
+}
+
+func (storage *RepoStagesStorage) RmDockerfileCache(projectName, imageName string) error {
+	logboek.Debug.LogF("-- RepoStagesStorage.RmDockerfileCache %s %s\n", projectName, imageName)
+
+	cacheImageName := makeRepoDockerfileCacheRecord(storage.RepoAddress, imageName)
+
+	if imgInfo, err := storage.DockerRegistry.TryGetRepoImage(cacheImageName); err != nil {
+		return fmt.Errorf("unable to get repo image %q info: %s", cacheImageName, err)
+	} else if imgInfo == nil {
+		logboek.Debug.LogF("-- RepoStagesStorage.RmDockerfileCache cache %q does not exist => exiting\n", cacheImageName)
+		return nil
+	} else {
+		if err := storage.DockerRegistry.DeleteRepoImage(imgInfo); err != nil {
+			return fmt.Errorf("unable to delete image %q from repo: %s", cacheImageName, err)
+		}
+	}
+
+	return nil
+}
+
+func (storage *RepoStagesStorage) GetDockerfileCaches(projectName string) ([]string, error) {
+	logboek.Debug.LogF("-- RepoStagesStorage.GetDockerfileCaches %s\n", projectName)
+
+	var res []string
+
+	if tags, err := storage.DockerRegistry.Tags(storage.RepoAddress); err != nil {
+		return nil, fmt.Errorf("unable to get repo %q tags: %s", storage.RepoAddress, err)
+	} else {
+		for _, tag := range tags {
+			if !strings.HasPrefix(tag, RepoDockerfileCache_ImageTagPrefix) {
+				continue
 			}
+
+			res = append(res, imageNameFromRecordTagSuffix(strings.TrimPrefix(tag, RepoDockerfileCache_ImageTagPrefix)))
 		}
 	}
 
 	return res, nil
 }
 
+func (storage *RepoStagesStorage) GetDockerfileCacheStageSignature(projectName, imageName string) (string, error) {
+	logboek.Debug.LogF("-- RepoStagesStorage.GetDockerfileCacheStageSignature %s %s\n", projectName, imageName)
+
+	cacheImageName := makeRepoDockerfileCacheRecord(storage.RepoAddress, imageName)
+
+	if imgInfo, err := storage.DockerRegistry.TryGetRepoImage(cacheImageName); err != nil {
+		return "", fmt.Errorf("unable to get repo image %q info: %s", cacheImageName, err)
+	} else if imgInfo == nil {
+		return "", nil
+	} else {
+		return imgInfo.Labels[image.WerfStageSignatureLabel], nil
+	}
+}
+
 func (storage *RepoStagesStorage) FetchImage(img container_runtime.Image) error {
 	switch containerRuntime := storage.ContainerRuntime.(type) {
-	case *container_runtime.LocalDockerServerRuntime:
+	case container_runtime.LocalRuntime:
 		return containerRuntime.PullImageFromRegistry(img)
 	default:
 		// TODO: case *container_runtime.LocalHostRuntime:
@@ -268,7 +375,7 @@ func (storage *RepoStagesStorage) FetchImage(img container_runtime.Image) error
 
 func (storage *RepoStagesStorage) StoreImage(img container_runtime.Image) error {
 	switch containerRuntime := storage.ContainerRuntime.(type) {
-	case *container_runtime.LocalDockerServerRuntime:
+	case container_runtime.LocalRuntime:
 		dockerImage := img.(*container_runtime.DockerImage)
 
 		if dockerImage.Image.GetBuiltId() != "" {
@@ -285,7 +392,7 @@ func (storage *RepoStagesStorage) StoreImage(img container_runtime.Image) error
 
 func (storage *RepoStagesStorage) ShouldFetchImage(img container_runtime.Image) (bool, error) {
 	switch storage.ContainerRuntime.(type) {
-	case *container_runtime.LocalDockerServerRuntime:
+	case container_runtime.LocalRuntime:
 		dockerImage := img.(*container_runtime.DockerImage)
 		return !dockerImage.Image.IsExistsLocally(), nil
 	default:
@@ -302,6 +409,14 @@ func (storage *RepoStagesStorage) Address() string {
 }
 
 func makeRepoManagedImageRecord(repoAddress, imageName string) string {
+	return fmt.Sprintf(RepoManagedImageRecord_ImageNameFormat, repoAddress, imageNameToRecordTagSuffix(imageName))
+}
+
+func makeRepoDockerfileCacheRecord(repoAddress, imageName string) string {
+	return fmt.Sprintf(RepoDockerfileCache_ImageNameFormat, repoAddress, imageNameToRecordTagSuffix(imageName))
+}
+
+func imageNameToRecordTagSuffix(imageName string) string {
 	tagSuffix := imageName
 	if imageName == "" {
 		tagSuffix = NamelessImageRecordTag
@@ -310,5 +425,16 @@ func makeRepoManagedImageRecord(repoAddress, imageName string) string {
 	tagSuffix = strings.ReplaceAll(tagSuffix, "/", "__slash__")
 	tagSuffix = strings.ReplaceAll(tagSuffix, "+", "__plus__")
 
-	return fmt.Sprintf(RepoManagedImageRecord_ImageNameFormat, repoAddress, tagSuffix)
+	return tagSuffix
+}
+
+func imageNameFromRecordTagSuffix(tagSuffix string) string {
+	if tagSuffix == NamelessImageRecordTag {
+		return ""
+	}
+
+	imageName := strings.ReplaceAll(tagSuffix, "__slash__", "/")
+	imageName = strings.ReplaceAll(imageName, "__plus__", "+")
+
+	return imageName
 }
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestRepoDockerfileCacheRecord(t *testing.T) {
+	for imageName, expected := range map[string]string{
+		"":           "repo:dockerfile-cache-__nameless__",
+		"backend":    "repo:dockerfile-cache-backend",
+		"app/worker": "repo:dockerfile-cache-app__slash__worker",
+		"c++":        "repo:dockerfile-cache-c__plus____plus__",
+	} {
+		t.Run(imageName, func(t *testing.T) {
+			record := makeRepoDockerfileCacheRecord("repo", imageName)
+			if record != expected {
+				t.Errorf("expected %q, got %q", expected, record)
+			}
+
+			tagSuffix := record[len("repo:"+RepoDockerfileCache_ImageTagPrefix):]
+			if name := imageNameFromRecordTagSuffix(tagSuffix); name != imageName {
+				t.Errorf("expected image name %q, got %q", imageName, name)
+			}
+		})
+	}
+}
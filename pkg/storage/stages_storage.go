@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"fmt"
+
 	"github.com/flant/werf/pkg/container_runtime"
 	"github.com/flant/werf/pkg/image"
 )
@@ -9,6 +11,9 @@ const (
 	LocalStorageAddress             = ":local"
 	DefaultKubernetesStorageAddress = "kubernetes://werf-synchronization"
 	NamelessImageRecordTag          = "__nameless__"
+
+	// DevNamespaceSuffix separates the stages built in the development mode from the regular ones
+	DevNamespaceSuffix = "-dev"
 )
 
 type StagesStorage interface {
@@ -32,6 +37,15 @@ type StagesStorage interface {
 	RmManagedImage(projectName, imageName string) error
 	GetManagedImages(projectName string) ([]string, error)
 
+	// FetchDockerfileCache will create a local image with the layers cache of the dockerfile image in the container-runtime, returns empty name if there is no cache
+	FetchDockerfileCache(projectName, imageName string) (string, error)
+	// StoreDockerfileCache will store a local built image as the layers cache of the dockerfile image
+	StoreDockerfileCache(projectName, imageName string, img container_runtime.Image) error
+	RmDockerfileCache(projectName, imageName string) error
+	GetDockerfileCaches(projectName string) ([]string, error)
+	// GetDockerfileCacheStageSignature returns the signature of the dockerfile stage which the cache has been stored by, empty if there is no cache
+	GetDockerfileCacheStageSignature(projectName, imageName string) (string, error)
+
 	String() string
 	Address() string
 }
@@ -42,8 +56,27 @@ type StagesStorageOptions struct {
 
 func NewStagesStorage(stagesStorageAddress string, containerRuntime container_runtime.ContainerRuntime, options StagesStorageOptions) (StagesStorage, error) {
 	if stagesStorageAddress == LocalStorageAddress {
-		return NewLocalDockerServerStagesStorage(containerRuntime.(*container_runtime.LocalDockerServerRuntime)), nil
+		localDockerServerRuntime, ok := containerRuntime.(*container_runtime.LocalDockerServerRuntime)
+		if !ok {
+			return nil, fmt.Errorf("%s stages storage is not supported by %s container runtime: use docker registry as stages storage", LocalStorageAddress, containerRuntime.String())
+		}
+		return NewLocalDockerServerStagesStorage(localDockerServerRuntime), nil
 	} else { // Docker registry based stages storage
 		return NewRepoStagesStorage(stagesStorageAddress, containerRuntime, options.RepoStagesStorageOptions)
 	}
 }
+
+// DevStagesStorageAddress returns the separate repo for the stages built in the development mode,
+// the local stages storage is shared and the stages are separated by the project name (see DevProjectName)
+func DevStagesStorageAddress(stagesStorageAddress string) string {
+	if stagesStorageAddress == LocalStorageAddress {
+		return stagesStorageAddress
+	}
+
+	return stagesStorageAddress + DevNamespaceSuffix
+}
+
+// DevProjectName returns the project name under which the stages built in the development mode are stored and cached
+func DevProjectName(projectName string) string {
+	return projectName + DevNamespaceSuffix
+}
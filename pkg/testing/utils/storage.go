@@ -30,7 +30,7 @@ func NewImagesRepo(imagesRepoAddress, imageRepoMode, implementationName string,
 func NewStagesStorage(stagesStorageAddress string, implementationName string, dockerRegistryOptions docker_registry.DockerRegistryOptions) storage.StagesStorage {
 	s, err := storage.NewStagesStorage(
 		stagesStorageAddress,
-		&container_runtime.LocalDockerServerRuntime{},
+		container_runtime.NewLocalDockerServerRuntime(),
 		storage.StagesStorageOptions{
 			RepoStagesStorageOptions: storage.RepoStagesStorageOptions{
 				DockerRegistryOptions: dockerRegistryOptions,
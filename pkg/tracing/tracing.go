@@ -0,0 +1,306 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]string
+	Err          error
+
+	active bool
+}
+
+// SpanHook is called on every start and end of the sequential span
+type SpanHook func(span *Span, isEnd bool)
+
+type InitOptions struct {
+	// File to append the spans in the OTLP/JSON format
+	File string
+	// Endpoint of the collector accepting the OTLP/HTTP JSON requests
+	Endpoint string
+	// ServiceVersion is the werf version reported in the resource attributes
+	ServiceVersion string
+}
+
+// maxBatchSize limits the number of finished spans kept in memory, the batch is exported when it is full
+const maxBatchSize = 512
+
+var (
+	enabled     bool
+	isExporting bool
+	options     InitOptions
+	traceID     string
+	rootID      string
+	hook        SpanHook
+
+	mutex         sync.Mutex
+	activeSpans   []*Span
+	finishedSpans []*Span
+
+	exportMutex sync.Mutex
+	exportsDone sync.WaitGroup
+	exportErr   error
+)
+
+// traceparentRegexp matches W3C Trace Context header passed through the TRACEPARENT environment variable,
+// which allows to attach werf spans to the trace of the CI pipeline
+var traceparentRegexp = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+func Init(opts InitOptions) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	options = opts
+	isExporting = opts.File != "" || opts.Endpoint != ""
+	enabled = isExporting || hook != nil
+	activeSpans = nil
+	finishedSpans = nil
+
+	if match := traceparentRegexp.FindStringSubmatch(os.Getenv("TRACEPARENT")); match != nil {
+		traceID, rootID = match[1], match[2]
+	} else {
+		traceID, rootID = newID(16), ""
+	}
+
+	return nil
+}
+
+// SetSpanHook sets the hook for the sequential spans, spans are created even if the export is not configured
+func SetSpanHook(h SpanHook) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	hook = h
+	enabled = isExporting || hook != nil
+}
+
+func IsEnabled() bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return enabled
+}
+
+// StartSpan starts the span of the sequential operation: spans started later become its children until End is called.
+// Returns nil when tracing is not enabled, all Span methods are nil-safe.
+func StartSpan(name string, attributes map[string]string) *Span {
+	return startSpan(name, attributes, true)
+}
+
+// StartConcurrentSpan starts the span which may run in parallel with other operations (registry requests, lock waits),
+// such span never becomes the parent of other spans
+func StartConcurrentSpan(name string, attributes map[string]string) *Span {
+	return startSpan(name, attributes, false)
+}
+
+// Trace wraps f into the sequential operation span
+func Trace(name string, attributes map[string]string, f func() error) error {
+	span := StartSpan(name, attributes)
+	err := f()
+	span.End(err)
+	return err
+}
+
+func startSpan(name string, attributes map[string]string, active bool) *Span {
+	mutex.Lock()
+
+	if !enabled {
+		mutex.Unlock()
+		return nil
+	}
+
+	span := &Span{
+		TraceID:      traceID,
+		SpanID:       newID(8),
+		ParentSpanID: rootID,
+		Name:         name,
+		StartTime:    time.Now(),
+		Attributes:   map[string]string{},
+		active:       active,
+	}
+
+	for k, v := range attributes {
+		span.Attributes[k] = v
+	}
+
+	if len(activeSpans) > 0 {
+		span.ParentSpanID = activeSpans[len(activeSpans)-1].SpanID
+	}
+
+	if active {
+		activeSpans = append(activeSpans, span)
+	}
+
+	h := hook
+	mutex.Unlock()
+
+	if active && h != nil {
+		h(span, false)
+	}
+
+	return span
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	s.Attributes[key] = value
+}
+
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	mutex.Lock()
+
+	if !s.EndTime.IsZero() {
+		mutex.Unlock()
+		return
+	}
+
+	s.EndTime = time.Now()
+	s.Err = err
+
+	if s.active {
+		for ind := len(activeSpans) - 1; ind >= 0; ind-- {
+			if activeSpans[ind] == s {
+				activeSpans = append(activeSpans[:ind], activeSpans[ind+1:]...)
+				break
+			}
+		}
+	}
+
+	var batch []*Span
+	if enabled && isExporting {
+		finishedSpans = append(finishedSpans, s)
+		if len(finishedSpans) >= maxBatchSize {
+			batch = finishedSpans
+			finishedSpans = nil
+		}
+	}
+
+	opts := options
+	h := hook
+	mutex.Unlock()
+
+	if s.active && h != nil {
+		h(s, true)
+	}
+
+	if batch != nil {
+		exportsDone.Add(1)
+		go func() {
+			defer exportsDone.Done()
+			exportBatch(batch, opts)
+		}()
+	}
+}
+
+// Shutdown ends the spans which are still active and exports all spans which have not been exported yet
+func Shutdown(err error) error {
+	mutex.Lock()
+	if !enabled {
+		mutex.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	var endedSpans []*Span
+	for ind := len(activeSpans) - 1; ind >= 0; ind-- {
+		span := activeSpans[ind]
+		span.EndTime = now
+		span.Err = err
+		endedSpans = append(endedSpans, span)
+
+		if isExporting {
+			finishedSpans = append(finishedSpans, span)
+		}
+	}
+
+	spans := finishedSpans
+	opts := options
+	exporting := isExporting
+	h := hook
+
+	enabled = false
+	isExporting = false
+	activeSpans = nil
+	finishedSpans = nil
+	mutex.Unlock()
+
+	if h != nil {
+		for _, span := range endedSpans {
+			h(span, true)
+		}
+	}
+
+	if exporting && len(spans) > 0 {
+		exportBatch(spans, opts)
+	}
+
+	exportsDone.Wait()
+
+	exportMutex.Lock()
+	defer exportMutex.Unlock()
+
+	resErr := exportErr
+	exportErr = nil
+
+	return resErr
+}
+
+// exportBatch exports spans, batches are exported one by one and only the first error is kept
+func exportBatch(spans []*Span, opts InitOptions) {
+	exportMutex.Lock()
+	defer exportMutex.Unlock()
+
+	if err := doExportBatch(spans, opts); err != nil && exportErr == nil {
+		exportErr = err
+	}
+}
+
+func doExportBatch(spans []*Span, opts InitOptions) error {
+	data, err := marshalOTLP(spans, opts.ServiceVersion)
+	if err != nil {
+		return fmt.Errorf("unable to marshal trace spans: %s", err)
+	}
+
+	if opts.File != "" {
+		if err := exportToFile(opts.File, data); err != nil {
+			return fmt.Errorf("unable to export trace spans into file %s: %s", opts.File, err)
+		}
+	}
+
+	if opts.Endpoint != "" {
+		if err := exportToEndpoint(opts.Endpoint, data); err != nil {
+			return fmt.Errorf("unable to export trace spans to %s: %s", opts.Endpoint, err)
+		}
+	}
+
+	return nil
+}
+
+func newID(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("unable to generate random id: %s", err))
+	}
+	return hex.EncodeToString(b)
+}
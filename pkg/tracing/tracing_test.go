@@ -0,0 +1,198 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpansHierarchyExportedToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-tracing-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	traceFile := filepath.Join(dir, "trace.json")
+	if err := Init(InitOptions{File: traceFile, ServiceVersion: "v1.1.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = Trace("phase build", map[string]string{"image": "backend"}, func() error {
+		registrySpan := StartConcurrentSpan("registry GET", nil)
+		_ = Trace("stage install", nil, func() error { return nil })
+		registrySpan.End(nil)
+		return errors.New("build failed")
+	})
+
+	if err := Shutdown(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if span := StartSpan("after shutdown", nil); span != nil {
+		t.Fatalf("expected no spans after shutdown")
+	}
+
+	data, err := ioutil.ReadFile(traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var traces otlpTraces
+	if err := json.Unmarshal(data, &traces); err != nil {
+		t.Fatalf("unable to unmarshal %s: %s", data, err)
+	}
+
+	spans := map[string]otlpSpan{}
+	for _, s := range traces.ResourceSpans[0].ScopeSpans[0].Spans {
+		spans[s.Name] = s
+	}
+
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	phase := spans["phase build"]
+	if phase.ParentSpanID != "" {
+		t.Errorf("expected root span, got parent %q", phase.ParentSpanID)
+	}
+	if phase.Status.Code != otlpStatusCodeError || phase.Status.Message != "build failed" {
+		t.Errorf("unexpected phase span status %+v", phase.Status)
+	}
+	if len(phase.Attributes) != 1 || phase.Attributes[0].Key != "image" || phase.Attributes[0].Value.StringValue != "backend" {
+		t.Errorf("unexpected phase span attributes %+v", phase.Attributes)
+	}
+
+	// concurrent span must not become the parent of the sequential one
+	for _, name := range []string{"registry GET", "stage install"} {
+		if spans[name].ParentSpanID != phase.SpanID {
+			t.Errorf("expected span %q to be the child of the phase span", name)
+		}
+		if spans[name].TraceID != phase.TraceID {
+			t.Errorf("expected span %q to have the same trace id", name)
+		}
+	}
+}
+
+func TestSpansExportedToEndpoint(t *testing.T) {
+	var requestPath string
+	var traces otlpTraces
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&traces); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	defer os.Unsetenv("TRACEPARENT")
+
+	if err := Init(InitOptions{Endpoint: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	StartSpan("werf converge", nil)
+
+	if err := Shutdown(errors.New("interrupted")); err != nil {
+		t.Fatal(err)
+	}
+
+	if requestPath != "/v1/traces" {
+		t.Errorf("unexpected request path %q", requestPath)
+	}
+
+	span := traces.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if span.TraceID != "0af7651916cd43dd8448eb211c80319c" || span.ParentSpanID != "b7ad6b7169203331" {
+		t.Errorf("expected span to be attached to TRACEPARENT, got trace %q parent %q", span.TraceID, span.ParentSpanID)
+	}
+	if span.Status.Code != otlpStatusCodeError {
+		t.Errorf("expected active span to be ended with the shutdown error")
+	}
+}
+
+func TestSpansExportedInBatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-tracing-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	traceFile := filepath.Join(dir, "trace.json")
+	if err := Init(InitOptions{File: traceFile}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < maxBatchSize+10; i++ {
+		StartConcurrentSpan("registry GET", nil).End(nil)
+	}
+
+	mutex.Lock()
+	keptSpans := len(finishedSpans)
+	mutex.Unlock()
+
+	if keptSpans != 10 {
+		t.Errorf("expected full batch to be exported before the shutdown, %d spans kept", keptSpans)
+	}
+
+	if err := Shutdown(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spansCount int
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, line := range lines {
+		var traces otlpTraces
+		if err := json.Unmarshal([]byte(line), &traces); err != nil {
+			t.Fatalf("unable to unmarshal %s: %s", line, err)
+		}
+		spansCount += len(traces.ResourceSpans[0].ScopeSpans[0].Spans)
+	}
+
+	if len(lines) != 2 || spansCount != maxBatchSize+10 {
+		t.Errorf("expected 2 batches with %d spans, got %d batches with %d spans", maxBatchSize+10, len(lines), spansCount)
+	}
+}
+
+func TestSpanHook(t *testing.T) {
+	var events []string
+	SetSpanHook(func(span *Span, isEnd bool) {
+		if isEnd {
+			events = append(events, "end "+span.Name)
+		} else {
+			events = append(events, "start "+span.Name)
+		}
+	})
+	defer SetSpanHook(nil)
+
+	if err := Init(InitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	StartSpan("werf build", nil)
+	_ = Trace("phase build", nil, func() error {
+		StartConcurrentSpan("registry GET", nil).End(nil)
+		return nil
+	})
+
+	if err := Shutdown(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"start werf build", "start phase build", "end phase build", "end werf build"}
+	if strings.Join(events, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+}
@@ -21,6 +21,8 @@ import (
 type ArchiveOptions struct {
 	Commit      string
 	PathMatcher path_matcher.PathMatcher
+	// LFS enables replacing of the LFS pointer files with the objects from the local LFS store
+	LFS bool
 }
 
 type ArchiveDescriptor struct {
@@ -155,24 +157,25 @@ func writeArchive(out io.Writer, gitDir, workTreeCacheDir string, withSubmodules
 
 		switch gitFileMode {
 		case filemode.Regular, filemode.Executable, filemode.Deprecated:
+			f, size, err := openArchiveFile(gitDir, absFilepath, info, opts.LFS)
+			if err != nil {
+				return err
+			}
+
 			err = tw.WriteHeader(&tar.Header{
 				Format:     tar.FormatGNU,
 				Name:       tarEntryName,
 				Mode:       int64(gitFileMode),
-				Size:       info.Size(),
+				Size:       size,
 				ModTime:    info.ModTime(),
 				AccessTime: info.ModTime(),
 				ChangeTime: info.ModTime(),
 			})
 			if err != nil {
+				_ = f.Close()
 				return fmt.Errorf("unable to write tar header for file %s: %s", tarEntryName, err)
 			}
 
-			f, err := os.Open(absFilepath)
-			if err != nil {
-				return fmt.Errorf("unable to open file %s: %s", absFilepath, err)
-			}
-
 			_, err = io.Copy(tw, f)
 			if err != nil {
 				return fmt.Errorf("unable to write data to tar archive from file %s: %s", absFilepath, err)
@@ -242,3 +245,33 @@ func writeArchive(out io.Writer, gitDir, workTreeCacheDir string, withSubmodules
 
 	return desc, nil
 }
+
+// openArchiveFile opens the work tree file or the LFS object if the file is the LFS pointer
+func openArchiveFile(gitDir, absFilepath string, info os.FileInfo, lfs bool) (*os.File, int64, error) {
+	if lfs && info.Size() <= LFSPointerMaxSize {
+		data, err := ioutil.ReadFile(absFilepath)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to read file %s: %s", absFilepath, err)
+		}
+
+		if pointer := ParseLFSPointer(data); pointer != nil {
+			f, err := OpenLFSObject(gitDir, pointer)
+			if err != nil {
+				return nil, 0, fmt.Errorf("unable to get LFS object of file %s: %s", absFilepath, err)
+			}
+
+			if debugArchive() {
+				logboek.Debug.LogF("Using LFS object %s for file %s\n", pointer.Oid, absFilepath)
+			}
+
+			return f, pointer.Size, nil
+		}
+	}
+
+	f, err := os.Open(absFilepath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to open file %s: %s", absFilepath, err)
+	}
+
+	return f, info.Size(), nil
+}
@@ -0,0 +1,114 @@
+package true_git
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DevCommitMessage = "werf dev commit"
+
+	devCommitAuthorName  = "werf"
+	devCommitAuthorEmail = "werf@werf.io"
+	devCommitDate        = "1970-01-01T00:00:00+0000"
+)
+
+type CreateDevCommitOptions struct {
+	// Paths relative to the work tree root which should be taken from the work tree as is:
+	// modified and untracked files are added, deleted files are removed
+	Paths []string
+}
+
+// CreateDevCommit creates an unreferenced commit on top of parentCommit with the tree of parentCommit
+// updated by the specified work tree paths. Author, committer and dates are fixed, so the resulting commit
+// depends only on the parent commit and the actual content of the files.
+func CreateDevCommit(gitDir, workTreeDir, parentCommit string, opts CreateDevCommitOptions) (string, error) {
+	if len(opts.Paths) == 0 {
+		return parentCommit, nil
+	}
+
+	var err error
+
+	gitDir, err = filepath.Abs(gitDir)
+	if err != nil {
+		return "", fmt.Errorf("bad git dir %s: %s", gitDir, err)
+	}
+
+	workTreeDir, err = filepath.Abs(workTreeDir)
+	if err != nil {
+		return "", fmt.Errorf("bad work tree dir %s: %s", workTreeDir, err)
+	}
+
+	indexFile, err := ioutil.TempFile("", "werf-dev-commit-index-")
+	if err != nil {
+		return "", fmt.Errorf("unable to create temporary index file: %s", err)
+	}
+	indexFilePath := indexFile.Name()
+	_ = indexFile.Close()
+	_ = os.Remove(indexFilePath)
+	defer os.Remove(indexFilePath)
+
+	env := append(os.Environ(),
+		fmt.Sprintf("GIT_INDEX_FILE=%s", indexFilePath),
+		fmt.Sprintf("GIT_AUTHOR_NAME=%s", devCommitAuthorName),
+		fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", devCommitAuthorEmail),
+		fmt.Sprintf("GIT_AUTHOR_DATE=%s", devCommitDate),
+		fmt.Sprintf("GIT_COMMITTER_NAME=%s", devCommitAuthorName),
+		fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", devCommitAuthorEmail),
+		fmt.Sprintf("GIT_COMMITTER_DATE=%s", devCommitDate),
+	)
+
+	runGit := func(stdin []byte, args ...string) (string, error) {
+		cmd := exec.Command("git", append([]string{"--git-dir", gitDir, "--work-tree", workTreeDir}, args...)...)
+		cmd.Dir = workTreeDir
+		cmd.Env = env
+		if stdin != nil {
+			cmd.Stdin = bytes.NewReader(stdin)
+		}
+
+		output := setCommandRecordingLiveOutput(cmd)
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("git command %q failed: %s\n%s", strings.Join(append([]string{cmd.Path}, cmd.Args[1:]...), " "), err, output.String())
+		}
+
+		if debugDevCommit() {
+			fmt.Printf("[DEBUG DEV COMMIT] %s\n%s\n", strings.Join(append([]string{cmd.Path}, cmd.Args[1:]...), " "), output)
+		}
+
+		return strings.TrimSpace(output.String()), nil
+	}
+
+	if _, err := runGit(nil, "read-tree", parentCommit); err != nil {
+		return "", err
+	}
+
+	stdin := []byte(strings.Join(opts.Paths, "\000") + "\000")
+	if _, err := runGit(stdin, "update-index", "--add", "--remove", "-z", "--stdin"); err != nil {
+		return "", err
+	}
+
+	tree, err := runGit(nil, "write-tree")
+	if err != nil {
+		return "", err
+	}
+
+	parentTree, err := runGit(nil, "rev-parse", fmt.Sprintf("%s^{tree}", parentCommit))
+	if err != nil {
+		return "", err
+	}
+
+	if tree == parentTree {
+		return parentCommit, nil
+	}
+
+	return runGit(nil, "commit-tree", tree, "-p", parentCommit, "-m", DevCommitMessage)
+}
+
+func debugDevCommit() bool {
+	return os.Getenv("WERF_TRUE_GIT_DEV_COMMIT_DEBUG") == "1"
+}
@@ -38,6 +38,7 @@ const (
 
 type diffParser struct {
 	PathMatcher path_matcher.PathMatcher
+	LFS         bool
 
 	Out                 io.Writer
 	OutLines            uint
@@ -170,7 +171,7 @@ func (p *diffParser) handleDiffLine(line string) error {
 		if strings.HasPrefix(line, "Submodule ") {
 			return p.handleSubmoduleLine(line)
 		}
-		return p.writeOutLine(line)
+		return p.handleBodyLine(line)
 
 	case newFileDiff:
 		if strings.HasPrefix(line, "+++ ") {
@@ -191,7 +192,7 @@ func (p *diffParser) handleDiffLine(line string) error {
 		if strings.HasPrefix(line, "Submodule ") {
 			return p.handleSubmoduleLine(line)
 		}
-		return p.writeOutLine(line)
+		return p.handleBodyLine(line)
 
 	case deleteFileDiff:
 		if strings.HasPrefix(line, "--- ") {
@@ -212,7 +213,7 @@ func (p *diffParser) handleDiffLine(line string) error {
 		if strings.HasPrefix(line, "Submodule ") {
 			return p.handleSubmoduleLine(line)
 		}
-		return p.writeOutLine(line)
+		return p.handleBodyLine(line)
 
 	case diffBody:
 		if strings.HasPrefix(line, "diff --git ") {
@@ -221,12 +222,22 @@ func (p *diffParser) handleDiffLine(line string) error {
 		if strings.HasPrefix(line, "Submodule ") {
 			return p.handleSubmoduleLine(line)
 		}
-		return p.writeOutLine(line)
+		return p.handleBodyLine(line)
 	}
 
 	return nil
 }
 
+func (p *diffParser) handleBodyLine(line string) error {
+	if p.LFS && isLFSPointerDiffLine(line) {
+		for _, path := range p.LastSeenPaths {
+			p.BinaryPaths = appendUnique(p.BinaryPaths, path)
+		}
+	}
+
+	return p.writeOutLine(line)
+}
+
 func (p *diffParser) handleDiffBegin(line string) error {
 	var lineParts []string
 	var aAndBParts []string
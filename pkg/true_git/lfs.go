@@ -0,0 +1,129 @@
+package true_git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/flant/werf/pkg/util"
+)
+
+const (
+	lfsPointerVersionLine = "version https://git-lfs.github.com/spec/v1"
+
+	// LFSPointerMaxSize is the max size of the LFS pointer file, bigger files are never considered as pointers
+	LFSPointerMaxSize = 1024
+)
+
+var lfsOidRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type LFSPointer struct {
+	Oid  string
+	Size int64
+}
+
+// ParseLFSPointer returns nil if data is not a valid LFS pointer
+func ParseLFSPointer(data []byte) *LFSPointer {
+	if len(data) > LFSPointerMaxSize || !bytes.HasPrefix(data, []byte(lfsPointerVersionLine+"\n")) {
+		return nil
+	}
+
+	pointer := &LFSPointer{Size: -1}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), " ", 2)
+		if len(parts) != 2 {
+			return nil
+		}
+
+		switch parts[0] {
+		case "oid":
+			oid := strings.TrimPrefix(parts[1], "sha256:")
+			if !lfsOidRegexp.MatchString(oid) {
+				return nil
+			}
+			pointer.Oid = oid
+		case "size":
+			size, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil || size < 0 {
+				return nil
+			}
+			pointer.Size = size
+		}
+	}
+
+	if pointer.Oid == "" || pointer.Size < 0 {
+		return nil
+	}
+
+	return pointer
+}
+
+func isLFSPointerDiffLine(line string) bool {
+	return len(line) > 0 && line[1:] == lfsPointerVersionLine
+}
+
+// LFSObjectPath returns the path of the object in the LFS store of the repository, the store is shared between work trees
+func LFSObjectPath(gitDir, oid string) (string, error) {
+	commonDir := gitDir
+
+	if data, err := ioutil.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("unable to read commondir of %s: %s", gitDir, err)
+	}
+
+	return filepath.Join(commonDir, "lfs", "objects", oid[0:2], oid[2:4], oid), nil
+}
+
+// OpenLFSObject opens the object from the local LFS store, the object size should match the pointer
+func OpenLFSObject(gitDir string, pointer *LFSPointer) (*os.File, error) {
+	objectPath, err := LFSObjectPath(gitDir, pointer.Oid)
+	if err != nil {
+		return nil, err
+	}
+
+	if exist, err := util.FileExists(objectPath); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, fmt.Errorf("LFS object %s not found in the local LFS store: run `git lfs fetch` to download LFS objects", pointer.Oid)
+	}
+
+	f, err := os.Open(objectPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open LFS object %s: %s", objectPath, err)
+	}
+
+	if info, err := f.Stat(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("unable to stat LFS object %s: %s", objectPath, err)
+	} else if info.Size() != pointer.Size {
+		_ = f.Close()
+		return nil, fmt.Errorf("LFS object %s is corrupted: expected size %d, got %d", pointer.Oid, pointer.Size, info.Size())
+	}
+
+	return f, nil
+}
+
+// LFSFetch downloads LFS objects of the commit from the origin into the local LFS store of the repository
+func LFSFetch(gitDir, commit string) error {
+	cmd := exec.Command("git", "-C", gitDir, "lfs", "fetch", "origin", commit)
+	output := setCommandRecordingLiveOutput(cmd)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("`git lfs fetch` failed (git-lfs is required for git mappings with lfs enabled): %s\n%s", err, output.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package true_git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testLFSOid = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+func TestParseLFSPointer(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected *LFSPointer
+	}{
+		{
+			name:     "valid pointer",
+			data:     "version https://git-lfs.github.com/spec/v1\noid sha256:" + testLFSOid + "\nsize 12345\n",
+			expected: &LFSPointer{Oid: testLFSOid, Size: 12345},
+		},
+		{
+			name:     "valid pointer with extension",
+			data:     "version https://git-lfs.github.com/spec/v1\next-0-foo sha256:" + testLFSOid + "\noid sha256:" + testLFSOid + "\nsize 0\n",
+			expected: &LFSPointer{Oid: testLFSOid, Size: 0},
+		},
+		{
+			name: "malformed oid",
+			data: "version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 12345\n",
+		},
+		{
+			name: "malformed size",
+			data: "version https://git-lfs.github.com/spec/v1\noid sha256:" + testLFSOid + "\nsize -1\n",
+		},
+		{
+			name: "missing size",
+			data: "version https://git-lfs.github.com/spec/v1\noid sha256:" + testLFSOid + "\n",
+		},
+		{
+			name: "malformed line",
+			data: "version https://git-lfs.github.com/spec/v1\noid sha256:" + testLFSOid + "\nsize 12345\ngarbage\n",
+		},
+		{
+			name: "not a pointer",
+			data: "package main\n\nfunc main() {}\n",
+		},
+		{
+			name: "too big to be a pointer",
+			data: "version https://git-lfs.github.com/spec/v1\noid sha256:" + testLFSOid + "\nsize 12345\n" + strings.Repeat("#", LFSPointerMaxSize),
+		},
+		{
+			name: "empty file",
+			data: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if pointer := ParseLFSPointer([]byte(tt.data)); !reflect.DeepEqual(pointer, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, pointer)
+			}
+		})
+	}
+}
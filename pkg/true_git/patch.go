@@ -18,6 +18,8 @@ type PatchOptions struct {
 
 	WithEntireFileContext bool
 	WithBinary            bool
+	// LFS makes changed LFS pointer files to be reported as binary paths, so that the files are taken from the archive
+	LFS bool
 }
 
 type PatchDescriptor struct {
@@ -184,6 +186,7 @@ func writePatch(out io.Writer, gitDir, workTreeCacheDir string, withSubmodules b
 	}
 
 	p := makeDiffParser(out, opts.PathMatcher)
+	p.LFS = opts.LFS
 
 WaitForData:
 	for {
@@ -0,0 +1,192 @@
+package secretvalues
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MinSecretValueLength is the minimal length of the registered value, shorter values would mask too much of the regular output
+const MinSecretValueLength = 4
+
+var (
+	registryMutex sync.RWMutex
+	registry      []string
+
+	keyWordRegexp = regexp.MustCompile(`[A-Z]*[a-z0-9]+|[A-Z]+`)
+
+	sensitiveKeyLastWords = []string{
+		"password", "passwords", "passwd", "secret", "secrets", "token", "tokens", "credential", "credentials",
+		"privatekey", "apikey", "accesskey", "secretkey",
+	}
+	sensitiveKeyLastWordPairs = []string{"private key", "api key", "access key", "secret key"}
+
+	// environSecretValueNames are the environment variables which carry credentials,
+	// other variables with sensitive names (e.g. WERF_IGNORE_SECRET_KEY, WERF_SECRET_VALUES_DB) contain flags or paths
+	environSecretValueNames = []string{"WERF_SECRET_KEY", "WERF_OLD_SECRET_KEY", "CI_JOB_TOKEN", "CI_REGISTRY_PASSWORD", "CI_DEPLOY_PASSWORD", "GITHUB_TOKEN"}
+
+	environRepoPrefixes        = []string{"WERF_REPO_", "WERF_IMAGES_REPO_", "WERF_STAGES_STORAGE_REPO_", "WERF_FROM_REPO_", "WERF_TO_REPO_"}
+	environRepoCredentialNames = []string{
+		"DOCKER_HUB_PASSWORD", "DOCKER_HUB_TOKEN", "GITHUB_TOKEN", "HARBOR_PASSWORD", "QUAY_TOKEN",
+		"NEXUS_PASSWORD", "ARTIFACTORY_PASSWORD", "ALIBABA_ACCESS_KEY_SECRET",
+	}
+)
+
+// RegisterSecretValues adds values to the global registry of secret values masked in all werf output.
+// Every line of the multiline value is registered as well.
+func RegisterSecretValues(values ...string) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	for _, value := range values {
+		registerSecretValue(value)
+		if strings.Contains(value, "\n") {
+			for _, line := range strings.Split(value, "\n") {
+				registerSecretValue(strings.TrimSpace(line))
+			}
+		}
+	}
+
+	// longer values first, so the value which contains another one is masked entirely
+	sort.SliceStable(registry, func(i, j int) bool {
+		return len(registry[i]) > len(registry[j])
+	})
+}
+
+func registerSecretValue(value string) {
+	if len(value) < MinSecretValueLength {
+		return
+	}
+
+	for _, v := range registry {
+		if v == value {
+			return
+		}
+	}
+
+	registry = append(registry, value)
+}
+
+func GetRegisteredSecretValues() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	return append([]string{}, registry...)
+}
+
+func MaskRegisteredSecretValues(targetStr string) string {
+	return MaskSecretValuesInString(GetRegisteredSecretValues(), targetStr)
+}
+
+// IsSensitiveKey reports whether the name of the value looks like the name of the secret:
+// the last word of the name should be password, token etc. (e.g. dbPassword, api_key but not secretEnabled)
+func IsSensitiveKey(name string) bool {
+	words := keyWordRegexp.FindAllString(name, -1)
+	if len(words) == 0 {
+		return false
+	}
+
+	for ind := range words {
+		words[ind] = strings.ToLower(words[ind])
+	}
+
+	lastWord := words[len(words)-1]
+	for _, w := range sensitiveKeyLastWords {
+		if lastWord == w {
+			return true
+		}
+	}
+
+	if len(words) > 1 {
+		lastWordPair := strings.Join(words[len(words)-2:], " ")
+		for _, pair := range sensitiveKeyLastWordPairs {
+			if lastWordPair == pair {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// IsMaskableValue reports whether the value found by the name heuristics could be the secret:
+// booleans, numbers and file paths are not masked, otherwise the regular output would be corrupted
+func IsMaskableValue(value string) bool {
+	if _, err := strconv.ParseBool(value); err == nil {
+		return false
+	}
+
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return false
+	}
+
+	for _, prefix := range []string{"/", "./", "../", "~/"} {
+		if strings.HasPrefix(value, prefix) {
+			return false
+		}
+	}
+
+	if _, err := os.Stat(value); err == nil {
+		return false
+	}
+
+	return true
+}
+
+// RegisterEnvironSecretValues registers values of the environment variables which carry credentials (e.g. WERF_SECRET_KEY, CI_JOB_TOKEN)
+func RegisterEnvironSecretValues() {
+	for _, name := range environSecretValueNames {
+		registerEnvironSecretValue(name)
+	}
+
+	for _, prefix := range environRepoPrefixes {
+		for _, name := range environRepoCredentialNames {
+			registerEnvironSecretValue(prefix + name)
+		}
+	}
+}
+
+func registerEnvironSecretValue(name string) {
+	if value := os.Getenv(name); value != "" && IsMaskableValue(value) {
+		RegisterSecretValues(value)
+	}
+}
+
+// RegisterSensitiveValuesFromMap registers values of the sensitive keys (and all nested values of such keys) of the helm values map
+func RegisterSensitiveValuesFromMap(data map[string]interface{}) {
+	for key, value := range data {
+		if value == nil {
+			continue
+		}
+
+		if IsSensitiveKey(key) {
+			for _, v := range ExtractSecretValuesFromMap(map[string]interface{}{key: value}) {
+				if IsMaskableValue(v) {
+					RegisterSecretValues(v)
+				}
+			}
+			continue
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			RegisterSensitiveValuesFromMap(v)
+		case []interface{}:
+			for _, elem := range v {
+				if m, ok := elem.(map[string]interface{}); ok {
+					RegisterSensitiveValuesFromMap(m)
+				}
+			}
+		}
+	}
+}
+
+func resetRegistry() {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	registry = nil
+}
@@ -0,0 +1,126 @@
+package secretvalues
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRegisterSecretValues(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	RegisterSecretValues("abc", "password", "-----BEGIN KEY-----\n  line-of-key\n-----END KEY-----", "password", "longpassword")
+
+	expected := []string{
+		"-----BEGIN KEY-----\n  line-of-key\n-----END KEY-----",
+		"-----BEGIN KEY-----",
+		"-----END KEY-----",
+		"longpassword",
+		"line-of-key",
+		"password",
+	}
+	if values := GetRegisteredSecretValues(); !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %q, got %q", expected, values)
+	}
+
+	if res := MaskRegisteredSecretValues("login abc with longpassword and password"); res != "login abc with *** and ***" {
+		t.Errorf("unexpected masked string %q", res)
+	}
+}
+
+func TestIsSensitiveKey(t *testing.T) {
+	for _, key := range []string{"password", "DB_PASSWORD", "dbPassword", "CI_JOB_TOKEN", "privateKey", "private_key", "aws_secret_access_key", "apiKey", "APIKey", "secrets"} {
+		if !IsSensitiveKey(key) {
+			t.Errorf("expected %q to be sensitive", key)
+		}
+	}
+
+	for _, key := range []string{"HOME", "replicas", "image", "WERF_LOG_COLOR_MODE", "WERF_SECRET_VALUES", "fooSecretEnabled", "aws_access_key_id", "tokenTTL"} {
+		if IsSensitiveKey(key) {
+			t.Errorf("expected %q not to be sensitive", key)
+		}
+	}
+}
+
+func TestIsMaskableValue(t *testing.T) {
+	for _, value := range []string{"qwerty", "0a1b2c3d4e", "mail-pass"} {
+		if !IsMaskableValue(value) {
+			t.Errorf("expected %q to be maskable", value)
+		}
+	}
+
+	for _, value := range []string{"true", "false", "TRUE", "1000", "3.14", "/etc/ssl/key.pem", "./secret-values.yaml", "../values.yaml", "~/.ssh/id_rsa", "registry_test.go"} {
+		if IsMaskableValue(value) {
+			t.Errorf("expected %q not to be maskable", value)
+		}
+	}
+}
+
+func TestRegisterSensitiveValuesFromMap(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	RegisterSensitiveValuesFromMap(map[string]interface{}{
+		"replicas": "1000",
+		"db": map[string]interface{}{
+			"host":     "postgres",
+			"password": "qwerty",
+			"token":    nil,
+		},
+		"users": []interface{}{
+			map[string]interface{}{"name": "admin", "apiKey": "0a1b2c3d4e"},
+		},
+		"secrets":          map[string]interface{}{"mail": "mail-pass", "enabled": true, "port": "8025"},
+		"fooSecretEnabled": "true",
+		"tls":              map[string]interface{}{"secretKey": "/etc/ssl/key.pem"},
+	})
+
+	values := GetRegisteredSecretValues()
+	sort.Strings(values)
+
+	expected := []string{"0a1b2c3d4e", "mail-pass", "qwerty"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %q, got %q", expected, values)
+	}
+
+	if res := MaskRegisteredSecretValues("enabled: true\nport: 8025"); res != "enabled: true\nport: 8025" {
+		t.Errorf("unexpected masked string %q", res)
+	}
+}
+
+func TestRegisterEnvironSecretValues(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	for name, value := range map[string]string{
+		"WERF_SECRET_KEY":                "0a1b2c3d4e5f",
+		"WERF_REPO_GITHUB_TOKEN":         "github-token",
+		"WERF_IGNORE_SECRET_KEY":         "true",
+		"WERF_SECRET_VALUES_DB":          ".helm/secret-values-db.yaml",
+		"WERF_TEST_DB_PASSWORD":          "env-password",
+		"WERF_STAGES_STORAGE_REPO_TOKEN": "false",
+	} {
+		if oldValue, ok := os.LookupEnv(name); ok {
+			defer os.Setenv(name, oldValue)
+		} else {
+			defer os.Unsetenv(name)
+		}
+		_ = os.Setenv(name, value)
+	}
+
+	RegisterEnvironSecretValues()
+
+	values := GetRegisteredSecretValues()
+	sort.Strings(values)
+
+	expected := []string{"0a1b2c3d4e5f", "github-token"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %q, got %q", expected, values)
+	}
+
+	if res := MaskRegisteredSecretValues("enabled: true"); res != "enabled: true" {
+		t.Errorf("unexpected masked string %q", res)
+	}
+}
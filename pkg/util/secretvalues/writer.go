@@ -0,0 +1,73 @@
+package secretvalues
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// MaskingWriter replaces registered secret values with *** in the data written to the underlying writer.
+// The tail of the current line which could be the beginning of a secret value is held till the next write or Flush.
+type MaskingWriter struct {
+	w     io.Writer
+	mutex sync.Mutex
+	buf   string
+}
+
+func NewMaskingWriter(w io.Writer) *MaskingWriter {
+	return &MaskingWriter{w: w}
+}
+
+func (mw *MaskingWriter) Write(data []byte) (int, error) {
+	mw.mutex.Lock()
+	defer mw.mutex.Unlock()
+
+	values := GetRegisteredSecretValues()
+	text := MaskSecretValuesInString(values, mw.buf+string(data))
+
+	held := pendingSecretValueLen(values, text)
+	mw.buf = text[len(text)-held:]
+
+	if _, err := io.WriteString(mw.w, text[:len(text)-held]); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+func (mw *MaskingWriter) Flush() error {
+	mw.mutex.Lock()
+	defer mw.mutex.Unlock()
+
+	if mw.buf == "" {
+		return nil
+	}
+
+	text := mw.buf
+	mw.buf = ""
+
+	_, err := io.WriteString(mw.w, text)
+	return err
+}
+
+// pendingSecretValueLen returns the length of the longest suffix of the last line of the text which is the beginning of some secret value
+func pendingSecretValueLen(values []string, text string) int {
+	lastLine := text[strings.LastIndex(text, "\n")+1:]
+
+	var res int
+	for _, value := range values {
+		maxLen := len(value) - 1
+		if maxLen > len(lastLine) {
+			maxLen = len(lastLine)
+		}
+
+		for l := maxLen; l > res; l-- {
+			if strings.HasPrefix(value, lastLine[len(lastLine)-l:]) {
+				res = l
+				break
+			}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,34 @@
+package secretvalues
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMaskingWriter(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	RegisterSecretValues("supersecret")
+
+	buf := bytes.NewBuffer(nil)
+	w := NewMaskingWriter(buf)
+
+	for _, data := range []string{"token: sup", "ers", "ecret\n", "prefix sup", "\nsuper"} {
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if buf.String() != "token: ***\nprefix sup\n" {
+		t.Errorf("unexpected output before flush %q", buf.String())
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "token: ***\nprefix sup\nsuper" {
+		t.Errorf("unexpected output after flush %q", buf.String())
+	}
+}
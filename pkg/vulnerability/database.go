@@ -0,0 +1,207 @@
+package vulnerability
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+type Ecosystem string
+
+const (
+	Dpkg     Ecosystem = "dpkg"
+	Apk      Ecosystem = "apk"
+	Rpm      Ecosystem = "rpm"
+	Npm      Ecosystem = "npm"
+	PyPI     Ecosystem = "pypi"
+	RubyGems Ecosystem = "rubygems"
+	Composer Ecosystem = "composer"
+)
+
+type Severity string
+
+const (
+	SeverityUnknown  Severity = "UNKNOWN"
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+var severitiesInOrder = []Severity{SeverityUnknown, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+func ParseSeverity(value string) (Severity, error) {
+	for _, severity := range severitiesInOrder {
+		if strings.EqualFold(value, string(severity)) {
+			return severity, nil
+		}
+	}
+
+	var severities []string
+	for _, severity := range severitiesInOrder {
+		severities = append(severities, string(severity))
+	}
+
+	return "", fmt.Errorf("unknown severity %q: expected one of %s", value, strings.Join(severities, ", "))
+}
+
+// AtLeast returns true if the severity is the same or higher than the specified one, unknown severity is the lowest
+func (s Severity) AtLeast(severity Severity) bool {
+	return s.rank() >= severity.rank()
+}
+
+func (s Severity) rank() int {
+	for ind, severity := range severitiesInOrder {
+		if strings.EqualFold(string(s), string(severity)) {
+			return ind
+		}
+	}
+	return 0
+}
+
+type Package struct {
+	Ecosystem Ecosystem
+	Name      string
+	Version   string
+	// Path of the database or the lockfile in the image filesystem
+	Path string
+}
+
+type VersionRange struct {
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+}
+
+type Vulnerability struct {
+	ID          string         `json:"id"`
+	Ecosystem   Ecosystem      `json:"ecosystem"`
+	Package     string         `json:"package"`
+	Severity    Severity       `json:"severity"`
+	Affected    []VersionRange `json:"affected,omitempty"`
+	Description string         `json:"description,omitempty"`
+}
+
+func (v *Vulnerability) isAffected(version string) bool {
+	if len(v.Affected) == 0 {
+		return true
+	}
+
+	for _, r := range v.Affected {
+		if r.Introduced != "" && CompareVersions(v.Ecosystem, version, r.Introduced) < 0 {
+			continue
+		}
+		if r.Fixed != "" && CompareVersions(v.Ecosystem, version, r.Fixed) >= 0 {
+			continue
+		}
+		return true
+	}
+
+	return false
+}
+
+func (v *Vulnerability) fixedVersion(version string) string {
+	for _, r := range v.Affected {
+		if r.Fixed != "" && CompareVersions(v.Ecosystem, version, r.Fixed) < 0 {
+			return r.Fixed
+		}
+	}
+	return ""
+}
+
+type Finding struct {
+	VulnerabilityID  string
+	Severity         Severity
+	Ecosystem        Ecosystem
+	Package          string
+	InstalledVersion string
+	FixedVersion     string `json:",omitempty"`
+	Path             string
+}
+
+// Database is the list of known vulnerabilities loaded from the local file (JSON or YAML):
+//
+//	vulnerabilities:
+//	- id: CVE-2020-1967
+//	  ecosystem: apk
+//	  package: openssl
+//	  severity: HIGH
+//	  affected:
+//	  - introduced: 1.1.1d-r0
+//	    fixed: 1.1.1g-r0
+type Database struct {
+	Vulnerabilities []*Vulnerability `json:"vulnerabilities"`
+
+	byPackage map[string][]*Vulnerability
+}
+
+func LoadDatabase(path string) (*Database, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read vulnerability database %s: %s", path, err)
+	}
+
+	db, err := ParseDatabase(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse vulnerability database %s: %s", path, err)
+	}
+
+	return db, nil
+}
+
+func ParseDatabase(data []byte) (*Database, error) {
+	db := &Database{}
+	if err := yaml.Unmarshal(data, db); err != nil {
+		return nil, err
+	}
+
+	db.byPackage = map[string][]*Vulnerability{}
+	for ind, v := range db.Vulnerabilities {
+		if v.ID == "" || v.Ecosystem == "" || v.Package == "" {
+			return nil, fmt.Errorf("vulnerability #%d: id, ecosystem and package required", ind+1)
+		}
+
+		if v.Severity == "" {
+			v.Severity = SeverityUnknown
+		} else if severity, err := ParseSeverity(string(v.Severity)); err != nil {
+			return nil, fmt.Errorf("vulnerability %s: %s", v.ID, err)
+		} else {
+			v.Severity = severity
+		}
+
+		key := packageKey(v.Ecosystem, v.Package)
+		db.byPackage[key] = append(db.byPackage[key], v)
+	}
+
+	return db, nil
+}
+
+func (db *Database) Match(pkg Package) []Finding {
+	var findings []Finding
+	for _, v := range db.byPackage[packageKey(pkg.Ecosystem, pkg.Name)] {
+		if !v.isAffected(pkg.Version) {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			VulnerabilityID:  v.ID,
+			Severity:         v.Severity,
+			Ecosystem:        pkg.Ecosystem,
+			Package:          pkg.Name,
+			InstalledVersion: pkg.Version,
+			FixedVersion:     v.fixedVersion(pkg.Version),
+			Path:             pkg.Path,
+		})
+	}
+
+	return findings
+}
+
+func packageKey(ecosystem Ecosystem, name string) string {
+	// python package names are case insensitive and - and _ are equivalent
+	if ecosystem == PyPI {
+		name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	}
+	return fmt.Sprintf("%s/%s", ecosystem, name)
+}
@@ -0,0 +1,147 @@
+package vulnerability
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var lockfileParsers = map[string]func(r io.Reader, path string) ([]Package, error){
+	"package-lock.json": parsePackageLock,
+	"Gemfile.lock":      parseGemfileLock,
+	"requirements.txt":  parseRequirements,
+	"composer.lock":     parseComposerLock,
+}
+
+type packageLockDependency struct {
+	Version      string                           `json:"version"`
+	Dependencies map[string]packageLockDependency `json:"dependencies"`
+}
+
+// parsePackageLock supports both lockfileVersion 1 (dependencies tree) and 2+ (packages map)
+func parsePackageLock(r io.Reader, lockfilePath string) ([]Package, error) {
+	var lock struct {
+		Packages map[string]struct {
+			Version string `json:"version"`
+			Link    bool   `json:"link"`
+		} `json:"packages"`
+		Dependencies map[string]packageLockDependency `json:"dependencies"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&lock); err != nil {
+		return nil, err
+	}
+
+	var packages []Package
+	if len(lock.Packages) > 0 {
+		for pkgPath, pkg := range lock.Packages {
+			ind := strings.LastIndex(pkgPath, "node_modules/")
+			if ind == -1 || pkg.Link || pkg.Version == "" {
+				continue
+			}
+
+			name := pkgPath[ind+len("node_modules/"):]
+			packages = append(packages, Package{Ecosystem: Npm, Name: name, Version: pkg.Version, Path: lockfilePath})
+		}
+
+		return packages, nil
+	}
+
+	var walk func(deps map[string]packageLockDependency)
+	walk = func(deps map[string]packageLockDependency) {
+		for name, dep := range deps {
+			if dep.Version != "" {
+				packages = append(packages, Package{Ecosystem: Npm, Name: name, Version: dep.Version, Path: lockfilePath})
+			}
+			walk(dep.Dependencies)
+		}
+	}
+	walk(lock.Dependencies)
+
+	return packages, nil
+}
+
+var gemfileLockSpecRegexp = regexp.MustCompile(`^    ([^\s(]+) \(([^)]+)\)$`)
+
+// parseGemfileLock parses gems from the specs sections
+func parseGemfileLock(r io.Reader, path string) ([]Package, error) {
+	var packages []Package
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if match := gemfileLockSpecRegexp.FindStringSubmatch(scanner.Text()); match != nil {
+			// platform specific gems have the version like 1.10.9-x86_64-linux
+			version := strings.SplitN(match[2], "-", 2)[0]
+			packages = append(packages, Package{Ecosystem: RubyGems, Name: match[1], Version: version, Path: path})
+		}
+	}
+
+	return packages, scanner.Err()
+}
+
+// parseRequirements parses pinned requirements only (NAME==VERSION)
+func parseRequirements(r io.Reader, path string) ([]Package, error) {
+	var packages []Package
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if ind := strings.Index(line, "#"); ind != -1 {
+			line = line[:ind]
+		}
+		if ind := strings.Index(line, ";"); ind != -1 {
+			line = line[:ind]
+		}
+
+		parts := strings.SplitN(line, "==", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		name := strings.TrimSpace(parts[0])
+		if ind := strings.Index(name, "["); ind != -1 {
+			name = name[:ind]
+		}
+		version := strings.TrimSpace(strings.Fields(parts[1] + " ")[0])
+		if name == "" || version == "" {
+			continue
+		}
+
+		packages = append(packages, Package{Ecosystem: PyPI, Name: name, Version: version, Path: path})
+	}
+
+	return packages, scanner.Err()
+}
+
+func parseComposerLock(r io.Reader, path string) ([]Package, error) {
+	type composerPackage struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	var lock struct {
+		Packages    []composerPackage `json:"packages"`
+		PackagesDev []composerPackage `json:"packages-dev"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&lock); err != nil {
+		return nil, err
+	}
+
+	var packages []Package
+	for _, pkg := range append(lock.Packages, lock.PackagesDev...) {
+		if pkg.Name == "" || pkg.Version == "" {
+			continue
+		}
+		packages = append(packages, Package{Ecosystem: Composer, Name: pkg.Name, Version: strings.TrimPrefix(pkg.Version, "v"), Path: path})
+	}
+
+	return packages, nil
+}
+
+func getLockfileParser(filePath string) func(r io.Reader, path string) ([]Package, error) {
+	return lockfileParsers[path.Base(filePath)]
+}
@@ -0,0 +1,589 @@
+package vulnerability
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	dpkgStatusPath   = "var/lib/dpkg/status"
+	apkInstalledPath = "lib/apk/db/installed"
+	rpmPackagesPath  = "var/lib/rpm/Packages"
+	rpmSqlitePath    = "var/lib/rpm/rpmdb.sqlite"
+)
+
+// parseDpkgStatus parses installed packages from the dpkg status file
+func parseDpkgStatus(r io.Reader, path string) ([]Package, error) {
+	var packages []Package
+	var name, version, status string
+
+	flush := func() {
+		if name != "" && version != "" && strings.HasSuffix(status, " installed") {
+			packages = append(packages, Package{Ecosystem: Dpkg, Name: name, Version: version, Path: path})
+		}
+		name, version, status = "", "", ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "Package:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "Package:"))
+		case strings.HasPrefix(line, "Version:"):
+			version = strings.TrimSpace(strings.TrimPrefix(line, "Version:"))
+		case strings.HasPrefix(line, "Status:"):
+			status = strings.TrimSpace(strings.TrimPrefix(line, "Status:"))
+		}
+	}
+	flush()
+
+	return packages, scanner.Err()
+}
+
+// parseApkInstalled parses the apk installed database
+func parseApkInstalled(r io.Reader, path string) ([]Package, error) {
+	var packages []Package
+	var name, version string
+
+	flush := func() {
+		if name != "" && version != "" {
+			packages = append(packages, Package{Ecosystem: Apk, Name: name, Version: version, Path: path})
+		}
+		name, version = "", ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "P:"):
+			name = line[2:]
+		case strings.HasPrefix(line, "V:"):
+			version = line[2:]
+		}
+	}
+	flush()
+
+	return packages, scanner.Err()
+}
+
+// Berkeley DB hash database layout used by the rpm Packages file
+const (
+	bdbHashMagic         = 0x061561
+	bdbPageHeaderSize    = 26
+	bdbPageTypeHashUns   = 2
+	bdbPageTypeHash      = 13
+	bdbItemTypeOffPage   = 3
+	rpmTagName           = 1000
+	rpmTagVersion        = 1001
+	rpmTagRelease        = 1002
+	rpmTagEpoch          = 1003
+	rpmHeaderEntrySize   = 16
+	rpmHeaderTypeInt32   = 4
+	rpmHeaderTypeString  = 6
+	rpmHeaderMaxDataSize = 256 * 1024 * 1024
+)
+
+// parseRpmPackages reads package headers from the rpm Packages database in the Berkeley DB hash format
+func parseRpmPackages(data []byte, path string) ([]Package, error) {
+	if len(data) < 512 {
+		return nil, fmt.Errorf("unexpected rpm database size %d", len(data))
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(data[12:16]) != bdbHashMagic {
+		order = binary.BigEndian
+		if order.Uint32(data[12:16]) != bdbHashMagic {
+			return nil, fmt.Errorf("unsupported rpm database format: only Berkeley DB hash is supported")
+		}
+	}
+
+	pageSize := int(order.Uint32(data[20:24]))
+	lastPage := int(order.Uint32(data[32:36]))
+	if pageSize < 512 {
+		return nil, fmt.Errorf("unexpected rpm database page size %d", pageSize)
+	}
+
+	page := func(pageNo int) ([]byte, error) {
+		start := pageNo * pageSize
+		if pageNo < 0 || start+pageSize > len(data) {
+			return nil, fmt.Errorf("rpm database page %d is out of range", pageNo)
+		}
+		return data[start : start+pageSize], nil
+	}
+
+	var packages []Package
+	for pageNo := 1; pageNo <= lastPage; pageNo++ {
+		p, err := page(pageNo)
+		if err != nil {
+			return nil, err
+		}
+
+		if pageType := p[25]; pageType != bdbPageTypeHash && pageType != bdbPageTypeHashUns {
+			continue
+		}
+
+		entries := int(order.Uint16(p[20:22]))
+		if bdbPageHeaderSize+entries*2 > len(p) {
+			return nil, fmt.Errorf("unexpected rpm database page %d entries count %d", pageNo, entries)
+		}
+
+		// entries are key/value pairs, package headers are stored as values on the overflow pages
+		for ind := 1; ind < entries; ind += 2 {
+			offsetPos := bdbPageHeaderSize + ind*2
+			itemOffset := int(order.Uint16(p[offsetPos : offsetPos+2]))
+			if itemOffset < bdbPageHeaderSize || itemOffset+12 > len(p) {
+				return nil, fmt.Errorf("unexpected rpm database page %d item offset %d", pageNo, itemOffset)
+			}
+
+			if p[itemOffset] != bdbItemTypeOffPage {
+				continue
+			}
+
+			overflowPageNo := int(order.Uint32(p[itemOffset+4 : itemOffset+8]))
+			totalLen := int(order.Uint32(p[itemOffset+8 : itemOffset+12]))
+
+			blob, err := readBdbOverflow(page, order, overflowPageNo, totalLen)
+			if err != nil {
+				return nil, err
+			}
+
+			pkg, err := parseRpmHeader(blob)
+			if err != nil {
+				return nil, err
+			}
+			pkg.Path = path
+			packages = append(packages, *pkg)
+		}
+	}
+
+	return packages, nil
+}
+
+func readBdbOverflow(page func(int) ([]byte, error), order binary.ByteOrder, pageNo, totalLen int) ([]byte, error) {
+	if totalLen > rpmHeaderMaxDataSize {
+		return nil, fmt.Errorf("unexpected rpm header size %d", totalLen)
+	}
+
+	visited := map[int]bool{}
+	res := make([]byte, 0, totalLen)
+	for pageNo != 0 && len(res) < totalLen {
+		if visited[pageNo] {
+			return nil, fmt.Errorf("rpm database overflow page %d is referenced twice", pageNo)
+		}
+		visited[pageNo] = true
+
+		p, err := page(pageNo)
+		if err != nil {
+			return nil, err
+		}
+
+		// free area offset keeps the length of the data on the overflow page
+		dataLen := int(order.Uint16(p[22:24]))
+		if dataLen == 0 || bdbPageHeaderSize+dataLen > len(p) {
+			return nil, fmt.Errorf("unexpected rpm database overflow page %d data length %d", pageNo, dataLen)
+		}
+
+		res = append(res, p[bdbPageHeaderSize:bdbPageHeaderSize+dataLen]...)
+		pageNo = int(order.Uint32(p[16:20]))
+	}
+
+	if len(res) < totalLen {
+		return nil, fmt.Errorf("unexpected end of rpm header: %d of %d bytes", len(res), totalLen)
+	}
+
+	return res[:totalLen], nil
+}
+
+// parseRpmHeader parses the header blob: index entries count, data size, index entries and data (big-endian)
+func parseRpmHeader(blob []byte) (*Package, error) {
+	if len(blob) < 8 {
+		return nil, fmt.Errorf("unexpected rpm header size %d", len(blob))
+	}
+
+	entriesCount := int(binary.BigEndian.Uint32(blob[0:4]))
+	dataSize := int(binary.BigEndian.Uint32(blob[4:8]))
+	dataStart := 8 + entriesCount*rpmHeaderEntrySize
+	if entriesCount < 0 || dataSize < 0 || dataStart+dataSize > len(blob) {
+		return nil, fmt.Errorf("malformed rpm header")
+	}
+	dataStore := blob[dataStart : dataStart+dataSize]
+
+	var name, version, release, epoch string
+	for ind := 0; ind < entriesCount; ind++ {
+		entry := blob[8+ind*rpmHeaderEntrySize : 8+(ind+1)*rpmHeaderEntrySize]
+		tag := binary.BigEndian.Uint32(entry[0:4])
+		dataType := binary.BigEndian.Uint32(entry[4:8])
+		offset := int(binary.BigEndian.Uint32(entry[8:12]))
+		if offset < 0 || offset >= len(dataStore) {
+			continue
+		}
+
+		switch {
+		case dataType == rpmHeaderTypeString && (tag == rpmTagName || tag == rpmTagVersion || tag == rpmTagRelease):
+			value := dataStore[offset:]
+			if end := strings.IndexByte(string(value), 0); end != -1 {
+				value = value[:end]
+			}
+
+			switch tag {
+			case rpmTagName:
+				name = string(value)
+			case rpmTagVersion:
+				version = string(value)
+			case rpmTagRelease:
+				release = string(value)
+			}
+		case dataType == rpmHeaderTypeInt32 && tag == rpmTagEpoch && offset+4 <= len(dataStore):
+			epoch = fmt.Sprintf("%d", binary.BigEndian.Uint32(dataStore[offset:offset+4]))
+		}
+	}
+
+	if name == "" || version == "" {
+		return nil, fmt.Errorf("malformed rpm header: name and version required")
+	}
+
+	fullVersion := version
+	if release != "" {
+		fullVersion = fmt.Sprintf("%s-%s", fullVersion, release)
+	}
+	if epoch != "" && epoch != "0" {
+		fullVersion = fmt.Sprintf("%s:%s", epoch, fullVersion)
+	}
+
+	return &Package{Ecosystem: Rpm, Name: name, Version: fullVersion}, nil
+}
+
+// SQLite database layout used by the rpm rpmdb.sqlite file: the Packages table keeps package headers in the blob column
+const (
+	sqliteHeaderSize         = 100
+	sqliteMagic              = "SQLite format 3\x00"
+	sqlitePageTypeTableLeaf  = 13
+	sqlitePageTypeTableInner = 5
+	sqliteMasterRootPage     = 1
+	rpmSqlitePackagesTable   = "Packages"
+)
+
+// parseRpmSqlite reads package headers from the rpm database in the SQLite format
+func parseRpmSqlite(data []byte, path string) ([]Package, error) {
+	db, err := newSqliteReader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var packagesRootPage int
+	if err := db.walkTable(sqliteMasterRootPage, func(record []interface{}) error {
+		// sqlite_master columns: type, name, tbl_name, rootpage, sql
+		if len(record) < 4 || record[0] != "table" || record[1] != rpmSqlitePackagesTable {
+			return nil
+		}
+		if rootPage, ok := record[3].(int64); ok {
+			packagesRootPage = int(rootPage)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if packagesRootPage == 0 {
+		return nil, fmt.Errorf("table %s not found in rpm database", rpmSqlitePackagesTable)
+	}
+
+	var packages []Package
+	if err := db.walkTable(packagesRootPage, func(record []interface{}) error {
+		// Packages columns: hnum, blob
+		if len(record) < 2 {
+			return fmt.Errorf("unexpected rpm database record")
+		}
+
+		blob, ok := record[1].([]byte)
+		if !ok {
+			return fmt.Errorf("unexpected rpm database record: header blob expected")
+		}
+
+		pkg, err := parseRpmHeader(blob)
+		if err != nil {
+			return err
+		}
+		pkg.Path = path
+		packages = append(packages, *pkg)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return packages, nil
+}
+
+type sqliteReader struct {
+	data       []byte
+	pageSize   int
+	usableSize int
+}
+
+func newSqliteReader(data []byte) (*sqliteReader, error) {
+	if len(data) < sqliteHeaderSize || string(data[:len(sqliteMagic)]) != sqliteMagic {
+		return nil, fmt.Errorf("unsupported rpm database format: SQLite database expected")
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("unexpected rpm database page size %d", pageSize)
+	}
+
+	return &sqliteReader{data: data, pageSize: pageSize, usableSize: pageSize - int(data[20])}, nil
+}
+
+func (db *sqliteReader) page(pageNo int) ([]byte, error) {
+	start := (pageNo - 1) * db.pageSize
+	if pageNo < 1 || start+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("rpm database page %d is out of range", pageNo)
+	}
+	return db.data[start : start+db.pageSize], nil
+}
+
+// walkTable calls f for each record of the table b-tree in the rowid order
+func (db *sqliteReader) walkTable(rootPage int, f func(record []interface{}) error) error {
+	visited := map[int]bool{}
+
+	var walk func(pageNo int) error
+	walk = func(pageNo int) error {
+		if visited[pageNo] {
+			return fmt.Errorf("rpm database page %d is referenced twice", pageNo)
+		}
+		visited[pageNo] = true
+
+		p, err := db.page(pageNo)
+		if err != nil {
+			return err
+		}
+
+		headerStart := 0
+		if pageNo == 1 {
+			headerStart = sqliteHeaderSize
+		}
+
+		pageType := p[headerStart]
+		cellsCount := int(binary.BigEndian.Uint16(p[headerStart+3 : headerStart+5]))
+
+		cellPointersStart := headerStart + 8
+		if pageType == sqlitePageTypeTableInner {
+			cellPointersStart = headerStart + 12
+		} else if pageType != sqlitePageTypeTableLeaf {
+			return fmt.Errorf("unexpected rpm database page %d type %d", pageNo, pageType)
+		}
+
+		if cellPointersStart+cellsCount*2 > len(p) {
+			return fmt.Errorf("unexpected rpm database page %d cells count %d", pageNo, cellsCount)
+		}
+
+		for ind := 0; ind < cellsCount; ind++ {
+			cellOffset := int(binary.BigEndian.Uint16(p[cellPointersStart+ind*2 : cellPointersStart+ind*2+2]))
+			if cellOffset >= len(p) {
+				return fmt.Errorf("unexpected rpm database page %d cell offset %d", pageNo, cellOffset)
+			}
+
+			if pageType == sqlitePageTypeTableInner {
+				if cellOffset+4 > len(p) {
+					return fmt.Errorf("unexpected rpm database page %d cell offset %d", pageNo, cellOffset)
+				}
+				if err := walk(int(binary.BigEndian.Uint32(p[cellOffset : cellOffset+4]))); err != nil {
+					return err
+				}
+				continue
+			}
+
+			payload, err := db.readLeafCellPayload(p, cellOffset)
+			if err != nil {
+				return fmt.Errorf("unable to read rpm database page %d cell %d: %s", pageNo, ind, err)
+			}
+
+			record, err := parseSqliteRecord(payload)
+			if err != nil {
+				return fmt.Errorf("unable to parse rpm database page %d cell %d: %s", pageNo, ind, err)
+			}
+
+			if err := f(record); err != nil {
+				return err
+			}
+		}
+
+		if pageType == sqlitePageTypeTableInner {
+			return walk(int(binary.BigEndian.Uint32(p[headerStart+8 : headerStart+12])))
+		}
+
+		return nil
+	}
+
+	return walk(rootPage)
+}
+
+// readLeafCellPayload reads the payload of the table leaf cell: payload size, rowid, local payload and the first overflow page number
+func (db *sqliteReader) readLeafCellPayload(p []byte, offset int) ([]byte, error) {
+	payloadSize, n := readSqliteVarint(p[offset:])
+	if n == 0 {
+		return nil, fmt.Errorf("bad payload size")
+	}
+	offset += n
+
+	if _, n = readSqliteVarint(p[offset:]); n == 0 {
+		return nil, fmt.Errorf("bad rowid")
+	}
+	offset += n
+
+	if payloadSize > rpmHeaderMaxDataSize {
+		return nil, fmt.Errorf("unexpected payload size %d", payloadSize)
+	}
+	totalLen := int(payloadSize)
+
+	localLen := totalLen
+	if maxLocal := db.usableSize - 35; totalLen > maxLocal {
+		minLocal := (db.usableSize-12)*32/255 - 23
+		localLen = minLocal + (totalLen-minLocal)%(db.usableSize-4)
+		if localLen > maxLocal {
+			localLen = minLocal
+		}
+	}
+
+	if offset+localLen > len(p) {
+		return nil, fmt.Errorf("payload is out of page")
+	}
+
+	res := make([]byte, 0, totalLen)
+	res = append(res, p[offset:offset+localLen]...)
+	if localLen == totalLen {
+		return res, nil
+	}
+
+	if offset+localLen+4 > len(p) {
+		return nil, fmt.Errorf("overflow page number is out of page")
+	}
+
+	visited := map[int]bool{}
+	overflowPageNo := int(binary.BigEndian.Uint32(p[offset+localLen : offset+localLen+4]))
+	for len(res) < totalLen {
+		if overflowPageNo == 0 || visited[overflowPageNo] {
+			return nil, fmt.Errorf("unexpected end of payload: %d of %d bytes", len(res), totalLen)
+		}
+		visited[overflowPageNo] = true
+
+		overflowPage, err := db.page(overflowPageNo)
+		if err != nil {
+			return nil, err
+		}
+
+		chunkLen := db.usableSize - 4
+		if rest := totalLen - len(res); rest < chunkLen {
+			chunkLen = rest
+		}
+		res = append(res, overflowPage[4:4+chunkLen]...)
+		overflowPageNo = int(binary.BigEndian.Uint32(overflowPage[0:4]))
+	}
+
+	return res, nil
+}
+
+// parseSqliteRecord parses the record header with serial types of the columns and the column values,
+// integers are returned as int64, texts as string and blobs as []byte
+func parseSqliteRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := readSqliteVarint(payload)
+	if n == 0 || headerSize > uint64(len(payload)) {
+		return nil, fmt.Errorf("bad record header size")
+	}
+
+	var serialTypes []uint64
+	for pos := n; pos < int(headerSize); {
+		serialType, n := readSqliteVarint(payload[pos:int(headerSize)])
+		if n == 0 {
+			return nil, fmt.Errorf("bad record serial type")
+		}
+		serialTypes = append(serialTypes, serialType)
+		pos += n
+	}
+
+	var record []interface{}
+	pos := int(headerSize)
+	for _, serialType := range serialTypes {
+		var size int
+		switch {
+		case serialType == 0 || serialType == 8 || serialType == 9:
+			size = 0
+		case serialType <= 4:
+			size = int(serialType)
+		case serialType == 5:
+			size = 6
+		case serialType == 6 || serialType == 7:
+			size = 8
+		case serialType >= 12:
+			size = int((serialType - 12) / 2)
+		default:
+			return nil, fmt.Errorf("unexpected record serial type %d", serialType)
+		}
+
+		if size < 0 || pos+size > len(payload) {
+			return nil, fmt.Errorf("record value is out of payload")
+		}
+		value := payload[pos : pos+size]
+		pos += size
+
+		switch {
+		case serialType == 0:
+			record = append(record, nil)
+		case serialType == 8:
+			record = append(record, int64(0))
+		case serialType == 9:
+			record = append(record, int64(1))
+		case serialType <= 6:
+			var res int64
+			if value[0]&0x80 != 0 {
+				res = -1
+			}
+			for _, b := range value {
+				res = res<<8 | int64(b)
+			}
+			record = append(record, res)
+		case serialType == 7:
+			record = append(record, value)
+		case serialType%2 == 0:
+			record = append(record, value)
+		default:
+			record = append(record, string(value))
+		}
+	}
+
+	return record, nil
+}
+
+// readSqliteVarint reads the big-endian variable-length integer of 1-9 bytes, returns 0 length if the data is too short
+func readSqliteVarint(data []byte) (uint64, int) {
+	var res uint64
+	for ind := 0; ind < 9; ind++ {
+		if ind >= len(data) {
+			return 0, 0
+		}
+
+		if ind == 8 {
+			return res<<8 | uint64(data[ind]), 9
+		}
+
+		res = res<<7 | uint64(data[ind]&0x7f)
+		if data[ind]&0x80 == 0 {
+			return res, ind + 1
+		}
+	}
+	return res, 9
+}
@@ -0,0 +1,103 @@
+package vulnerability
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/flant/logboek"
+)
+
+type ScanFilesystemOptions struct {
+	// FailOnPackageDatabaseError makes the OS packages database that cannot be parsed an error instead of the warning,
+	// otherwise the vulnerabilities of the packages from this database are silently missed
+	FailOnPackageDatabaseError bool
+}
+
+// ScanFilesystem finds packages in the OS packages databases and the language lockfiles of the flattened image filesystem tarball
+func ScanFilesystem(r io.Reader, opts ScanFilesystemOptions) ([]Package, error) {
+	var packages []Package
+	seen := map[Package]bool{}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to read image filesystem: %s", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		filePath := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+
+		var found []Package
+		var parseErr error
+		isPackageDatabase := true
+		switch {
+		case filePath == dpkgStatusPath:
+			found, parseErr = parseDpkgStatus(tr, "/"+filePath)
+		case filePath == apkInstalledPath:
+			found, parseErr = parseApkInstalled(tr, "/"+filePath)
+		case filePath == rpmPackagesPath || filePath == rpmSqlitePath:
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read %s: %s", filePath, err)
+			}
+
+			if filePath == rpmPackagesPath {
+				found, parseErr = parseRpmPackages(data, "/"+filePath)
+			} else {
+				found, parseErr = parseRpmSqlite(data, "/"+filePath)
+			}
+		case isSystemPath(filePath):
+			continue
+		default:
+			isPackageDatabase = false
+			if parse := getLockfileParser(filePath); parse != nil {
+				found, parseErr = parse(tr, "/"+filePath)
+			}
+		}
+
+		if parseErr != nil {
+			if isPackageDatabase && opts.FailOnPackageDatabaseError {
+				return nil, fmt.Errorf("unable to parse packages database /%s: %s", filePath, parseErr)
+			}
+
+			logboek.LogWarnF("WARNING: unable to parse /%s: %s\n", filePath, parseErr)
+			continue
+		}
+
+		for _, pkg := range found {
+			if !seen[pkg] {
+				seen[pkg] = true
+				packages = append(packages, pkg)
+			}
+		}
+	}
+
+	sort.SliceStable(packages, func(i, j int) bool {
+		if packages[i].Path != packages[j].Path {
+			return packages[i].Path < packages[j].Path
+		}
+		return packages[i].Name < packages[j].Name
+	})
+
+	return packages, nil
+}
+
+func isSystemPath(filePath string) bool {
+	for _, prefix := range []string{"proc/", "sys/", "dev/"} {
+		if strings.HasPrefix(filePath, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,371 @@
+package vulnerability
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+const testDpkgStatus = `Package: openssl
+Status: install ok installed
+Version: 1.1.1d-0+deb10u2
+
+Package: removed
+Status: deinstall ok config-files
+Version: 1.0-1
+
+Package: zlib1g
+Status: install ok installed
+Version: 1:1.2.11.dfsg-1
+`
+
+const testApkInstalled = `C:Q1abc=
+P:musl
+V:1.1.24-r2
+
+P:busybox
+V:1.31.1-r9
+`
+
+const testPackageLock = `{
+  "lockfileVersion": 2,
+  "packages": {
+    "": {"name": "app"},
+    "node_modules/lodash": {"version": "4.17.15"},
+    "node_modules/a/node_modules/@scope/b": {"version": "1.0.0"}
+  }
+}`
+
+const testRequirements = `# comment
+requests==2.20.0
+Flask>=1.0
+PyYAML[extra] == 5.1 ; python_version > "3"
+`
+
+const testDatabase = `
+vulnerabilities:
+- id: CVE-1
+  ecosystem: dpkg
+  package: openssl
+  severity: high
+  affected:
+  - fixed: 1.1.1d-0+deb10u3
+- id: CVE-2
+  ecosystem: apk
+  package: busybox
+  severity: CRITICAL
+  affected:
+  - introduced: 1.31.0-r0
+    fixed: 1.31.1-r10
+- id: CVE-3
+  ecosystem: npm
+  package: lodash
+  severity: LOW
+  affected:
+  - fixed: 4.17.12
+- id: CVE-4
+  ecosystem: pypi
+  package: pyyaml
+  severity: MEDIUM
+- id: CVE-5
+  ecosystem: rpm
+  package: bash
+  affected:
+  - fixed: 4.2.46-35.el7
+`
+
+func TestScanFilesystem(t *testing.T) {
+	fs := map[string][]byte{
+		"var/lib/dpkg/status":          []byte(testDpkgStatus),
+		"./lib/apk/db/installed":       []byte(testApkInstalled),
+		"app/package-lock.json":        []byte(testPackageLock),
+		"app/requirements.txt":         []byte(testRequirements),
+		"/var/lib/rpm/Packages":        testRpmDatabase(t, "bash", "4.2.46", "34.el7"),
+		"/var/lib/rpm/rpmdb.sqlite":    testRpmSqliteDatabase(t, "openssl-libs", "1.1.1g", "15.el8"),
+		"proc/1/app/requirements.txt":  []byte("ignored==1.0"),
+		"app/other/requirements.txt.1": []byte("ignored==1.0"),
+	}
+
+	packages, err := ScanFilesystem(testFilesystem(t, fs), ScanFilesystemOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPackages := map[Package]bool{
+		{Ecosystem: Dpkg, Name: "openssl", Version: "1.1.1d-0+deb10u2", Path: "/var/lib/dpkg/status"}:       true,
+		{Ecosystem: Dpkg, Name: "zlib1g", Version: "1:1.2.11.dfsg-1", Path: "/var/lib/dpkg/status"}:         true,
+		{Ecosystem: Apk, Name: "musl", Version: "1.1.24-r2", Path: "/lib/apk/db/installed"}:                 true,
+		{Ecosystem: Apk, Name: "busybox", Version: "1.31.1-r9", Path: "/lib/apk/db/installed"}:              true,
+		{Ecosystem: Npm, Name: "lodash", Version: "4.17.15", Path: "/app/package-lock.json"}:                true,
+		{Ecosystem: Npm, Name: "@scope/b", Version: "1.0.0", Path: "/app/package-lock.json"}:                true,
+		{Ecosystem: PyPI, Name: "requests", Version: "2.20.0", Path: "/app/requirements.txt"}:               true,
+		{Ecosystem: PyPI, Name: "PyYAML", Version: "5.1", Path: "/app/requirements.txt"}:                    true,
+		{Ecosystem: Rpm, Name: "bash", Version: "4.2.46-34.el7", Path: "/var/lib/rpm/Packages"}:             true,
+		{Ecosystem: Rpm, Name: "openssl-libs", Version: "1.1.1g-15.el8", Path: "/var/lib/rpm/rpmdb.sqlite"}: true,
+	}
+
+	if len(packages) != len(expectedPackages) {
+		t.Errorf("got %d packages, expected %d: %+v", len(packages), len(expectedPackages), packages)
+	}
+	for _, pkg := range packages {
+		if !expectedPackages[pkg] {
+			t.Errorf("unexpected package %+v", pkg)
+		}
+	}
+
+	db, err := ParseDatabase([]byte(testDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := map[string]Finding{}
+	for _, pkg := range packages {
+		for _, finding := range db.Match(pkg) {
+			findings[finding.VulnerabilityID] = finding
+		}
+	}
+
+	for _, id := range []string{"CVE-1", "CVE-2", "CVE-4", "CVE-5"} {
+		if _, ok := findings[id]; !ok {
+			t.Errorf("expected finding %s", id)
+		}
+	}
+	if _, ok := findings["CVE-3"]; ok {
+		t.Errorf("unexpected finding CVE-3: lodash is already fixed")
+	}
+	if f := findings["CVE-2"]; f.FixedVersion != "1.31.1-r10" || f.Severity != SeverityCritical {
+		t.Errorf("unexpected finding %+v", f)
+	}
+	if f := findings["CVE-5"]; f.Severity != SeverityUnknown {
+		t.Errorf("unexpected finding %+v", f)
+	}
+}
+
+func TestScanFilesystemPackageDatabaseError(t *testing.T) {
+	fs := map[string][]byte{
+		"var/lib/rpm/Packages":  []byte("broken"),
+		"app/package-lock.json": []byte("broken"),
+	}
+
+	if _, err := ScanFilesystem(testFilesystem(t, fs), ScanFilesystemOptions{}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	_, err := ScanFilesystem(testFilesystem(t, fs), ScanFilesystemOptions{FailOnPackageDatabaseError: true})
+	if err == nil || !strings.Contains(err.Error(), "/var/lib/rpm/Packages") {
+		t.Errorf("expected packages database error, got %v", err)
+	}
+
+	delete(fs, "var/lib/rpm/Packages")
+	if _, err := ScanFilesystem(testFilesystem(t, fs), ScanFilesystemOptions{FailOnPackageDatabaseError: true}); err != nil {
+		t.Errorf("unexpected error for broken lockfile: %s", err)
+	}
+}
+
+func TestParseRpmPackagesMalformed(t *testing.T) {
+	const pageSize = 512
+	order := binary.LittleEndian
+
+	tests := []struct {
+		name   string
+		modify func(db []byte)
+	}{
+		{
+			name: "entries count out of page",
+			modify: func(db []byte) {
+				order.PutUint16(db[pageSize+20:pageSize+22], 0xffff)
+			},
+		},
+		{
+			name: "item offset out of page",
+			modify: func(db []byte) {
+				order.PutUint16(db[pageSize+bdbPageHeaderSize+2:], pageSize-4)
+			},
+		},
+		{
+			name: "self-linked overflow page without data",
+			modify: func(db []byte) {
+				order.PutUint32(db[2*pageSize+16:2*pageSize+20], 2)
+				order.PutUint16(db[2*pageSize+22:2*pageSize+24], 0)
+			},
+		},
+		{
+			name: "self-linked overflow page",
+			modify: func(db []byte) {
+				order.PutUint32(db[pageSize+pageSize-20+8:], pageSize)
+				order.PutUint32(db[2*pageSize+16:2*pageSize+20], 2)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testRpmDatabase(t, "bash", "4.4", "1")
+			tt.modify(db)
+
+			if _, err := parseRpmPackages(db, "/var/lib/rpm/Packages"); err == nil {
+				t.Errorf("expected malformed rpm database error")
+			}
+		})
+	}
+}
+
+func TestSeverityAtLeast(t *testing.T) {
+	if !SeverityCritical.AtLeast(SeverityHigh) || !SeverityHigh.AtLeast(SeverityHigh) || SeverityMedium.AtLeast(SeverityHigh) {
+		t.Errorf("unexpected severities order")
+	}
+
+	if _, err := ParseSeverity("severe"); err == nil {
+		t.Errorf("expected error for unknown severity")
+	}
+}
+
+func testFilesystem(t *testing.T, fs map[string][]byte) *bytes.Buffer {
+	buf := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(buf)
+	for name, data := range fs {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// testRpmSqliteDatabase builds the minimal SQLite file: sqlite_master leaf on the first page and the Packages table leaf with a single package header
+func testRpmSqliteDatabase(t *testing.T, name, version, release string) []byte {
+	const pageSize = 1024
+
+	db := make([]byte, 2*pageSize)
+	copy(db, sqliteMagic)
+	binary.BigEndian.PutUint16(db[16:18], pageSize)
+
+	testSqliteLeafPage(t, db[:pageSize], sqliteHeaderSize, testSqliteRecord("table", rpmSqlitePackagesTable, rpmSqlitePackagesTable, int64(2), "CREATE TABLE 'Packages' (hnum INTEGER PRIMARY KEY AUTOINCREMENT,blob BLOB NOT NULL)"))
+	testSqliteLeafPage(t, db[pageSize:], 0, testSqliteRecord(nil, testRpmHeader(name, version, release)))
+
+	return db
+}
+
+// testSqliteLeafPage writes the table leaf page with a single cell at the end of the page, payload should fit the page
+func testSqliteLeafPage(t *testing.T, page []byte, headerStart int, payload []byte) {
+	cell := append(testSqliteVarint(uint64(len(payload))), testSqliteVarint(1)...)
+	cell = append(cell, payload...)
+
+	cellOffset := len(page) - len(cell)
+	if len(payload) > len(page)-35 || cellOffset < headerStart+10 {
+		t.Fatalf("sqlite record is too big for the test database")
+	}
+	copy(page[cellOffset:], cell)
+
+	page[headerStart] = sqlitePageTypeTableLeaf
+	binary.BigEndian.PutUint16(page[headerStart+3:], 1)
+	binary.BigEndian.PutUint16(page[headerStart+5:], uint16(cellOffset))
+	binary.BigEndian.PutUint16(page[headerStart+8:], uint16(cellOffset))
+}
+
+// testSqliteRecord encodes nil, int64 (as 4-byte integer), string and []byte values
+func testSqliteRecord(values ...interface{}) []byte {
+	var serialTypes, body []byte
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			serialTypes = append(serialTypes, 0)
+		case int64:
+			serialTypes = append(serialTypes, 4)
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, uint32(v))
+			body = append(body, b...)
+		case string:
+			serialTypes = append(serialTypes, testSqliteVarint(uint64(len(v)*2+13))...)
+			body = append(body, v...)
+		case []byte:
+			serialTypes = append(serialTypes, testSqliteVarint(uint64(len(v)*2+12))...)
+			body = append(body, v...)
+		}
+	}
+
+	// header size includes its own varint, one byte is enough for the test records
+	header := append([]byte{byte(len(serialTypes) + 1)}, serialTypes...)
+	return append(header, body...)
+}
+
+func testSqliteVarint(v uint64) []byte {
+	res := []byte{byte(v & 0x7f)}
+	for v >>= 7; v > 0; v >>= 7 {
+		res = append([]byte{byte(v&0x7f) | 0x80}, res...)
+	}
+	return res
+}
+
+// testRpmDatabase builds the minimal Berkeley DB hash file with a single package header on the overflow page
+func testRpmDatabase(t *testing.T, name, version, release string) []byte {
+	header := testRpmHeader(name, version, release)
+
+	const pageSize = 512
+	if bdbPageHeaderSize+len(header) > pageSize {
+		t.Fatalf("rpm header is too big for the test database")
+	}
+
+	db := make([]byte, 3*pageSize)
+	order := binary.LittleEndian
+
+	// metadata page
+	order.PutUint32(db[12:16], bdbHashMagic)
+	order.PutUint32(db[20:24], pageSize)
+	order.PutUint32(db[32:36], 2)
+
+	// hash page with the key item and the off-page value item
+	hashPage := db[pageSize : 2*pageSize]
+	order.PutUint32(hashPage[8:12], 1)
+	order.PutUint16(hashPage[20:22], 2)
+	hashPage[25] = bdbPageTypeHash
+	keyOffset, valueOffset := pageSize-8, pageSize-20
+	order.PutUint16(hashPage[bdbPageHeaderSize:], uint16(keyOffset))
+	order.PutUint16(hashPage[bdbPageHeaderSize+2:], uint16(valueOffset))
+	hashPage[keyOffset] = 1
+	order.PutUint32(hashPage[keyOffset+1:], 1)
+	hashPage[valueOffset] = bdbItemTypeOffPage
+	order.PutUint32(hashPage[valueOffset+4:], 2)
+	order.PutUint32(hashPage[valueOffset+8:], uint32(len(header)))
+
+	// overflow page
+	overflowPage := db[2*pageSize:]
+	order.PutUint32(overflowPage[8:12], 2)
+	order.PutUint16(overflowPage[22:24], uint16(len(header)))
+	overflowPage[25] = 7
+	copy(overflowPage[bdbPageHeaderSize:], header)
+
+	return db
+}
+
+func testRpmHeader(name, version, release string) []byte {
+	var data []byte
+	var entries [][4]uint32
+	for _, e := range []struct {
+		tag   uint32
+		value string
+	}{{rpmTagName, name}, {rpmTagVersion, version}, {rpmTagRelease, release}} {
+		entries = append(entries, [4]uint32{e.tag, rpmHeaderTypeString, uint32(len(data)), 1})
+		data = append(data, append([]byte(e.value), 0)...)
+	}
+
+	blob := make([]byte, 8)
+	binary.BigEndian.PutUint32(blob[0:4], uint32(len(entries)))
+	binary.BigEndian.PutUint32(blob[4:8], uint32(len(data)))
+	for _, entry := range entries {
+		for _, v := range entry {
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, v)
+			blob = append(blob, b...)
+		}
+	}
+
+	return append(blob, data...)
+}
@@ -0,0 +1,620 @@
+package vulnerability
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+// CompareVersions compares package versions according to the rules of the ecosystem
+func CompareVersions(ecosystem Ecosystem, a, b string) int {
+	switch ecosystem {
+	case Dpkg:
+		return compareDebianVersions(a, b)
+	case Rpm:
+		return compareRpmVersions(a, b)
+	case Apk:
+		if res, ok := compareApkVersions(a, b); ok {
+			return res
+		}
+	case PyPI:
+		if res, ok := comparePypiVersions(a, b); ok {
+			return res
+		}
+	case RubyGems:
+		return compareGemVersions(a, b)
+	case Npm:
+		if va, err := semver.NewVersion(a); err == nil {
+			if vb, err := semver.NewVersion(b); err == nil {
+				return va.Compare(vb)
+			}
+		}
+	case Composer:
+		if res, ok := compareComposerVersions(a, b); ok {
+			return res
+		}
+	}
+
+	return compareGenericVersions(a, b)
+}
+
+// compareGenericVersions compares versions that do not follow the ecosystem rules: the release part is compared with rpmvercmp,
+// the version with the pre-release part after the hyphen is older than the release
+func compareGenericVersions(a, b string) int {
+	aRelease, aPrerelease := splitPrerelease(strings.TrimPrefix(a, "v"))
+	bRelease, bPrerelease := splitPrerelease(strings.TrimPrefix(b, "v"))
+
+	if res := rpmvercmp(aRelease, bRelease); res != 0 {
+		return res
+	}
+
+	switch {
+	case aPrerelease == bPrerelease:
+		return 0
+	case aPrerelease == "":
+		return 1
+	case bPrerelease == "":
+		return -1
+	default:
+		return rpmvercmp(aPrerelease, bPrerelease)
+	}
+}
+
+func splitPrerelease(version string) (string, string) {
+	if ind := strings.Index(version, "-"); ind != -1 {
+		return version[:ind], version[ind+1:]
+	}
+	return version, ""
+}
+
+var apkVersionRegexp = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)*)([a-z]?)((?:_(?:alpha|beta|pre|rc|cvs|svn|git|hg|p)[0-9]*)*)(?:-r([0-9]+))?$`)
+var apkSuffixRegexp = regexp.MustCompile(`_(alpha|beta|pre|rc|cvs|svn|git|hg|p)([0-9]*)`)
+
+// apkSuffixOrder lists apk version suffixes in the ascending order, the version without suffix is between rc and cvs
+var apkSuffixOrder = map[string]int{"alpha": -4, "beta": -3, "pre": -2, "rc": -1, "": 0, "cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5}
+
+// compareApkVersions compares number{.number}[letter]{_suffix[number]}[-rrevision] versions, ok is false if any version has another format
+func compareApkVersions(a, b string) (int, bool) {
+	am := apkVersionRegexp.FindStringSubmatch(a)
+	bm := apkVersionRegexp.FindStringSubmatch(b)
+	if am == nil || bm == nil {
+		return 0, false
+	}
+
+	if res := compareNumberLists(strings.Split(am[1], "."), strings.Split(bm[1], "."), false); res != 0 {
+		return res, true
+	}
+
+	if res := strings.Compare(am[2], bm[2]); res != 0 {
+		return res, true
+	}
+
+	aSuffixes := apkSuffixRegexp.FindAllStringSubmatch(am[3], -1)
+	bSuffixes := apkSuffixRegexp.FindAllStringSubmatch(bm[3], -1)
+	for ind := 0; ind < len(aSuffixes) || ind < len(bSuffixes); ind++ {
+		var aSuffix, aNumber, bSuffix, bNumber string
+		if ind < len(aSuffixes) {
+			aSuffix, aNumber = aSuffixes[ind][1], aSuffixes[ind][2]
+		}
+		if ind < len(bSuffixes) {
+			bSuffix, bNumber = bSuffixes[ind][1], bSuffixes[ind][2]
+		}
+
+		if aSuffix != bSuffix {
+			return sign(apkSuffixOrder[aSuffix] - apkSuffixOrder[bSuffix]), true
+		}
+
+		if res := compareNumbers(aNumber, bNumber); res != 0 {
+			return res, true
+		}
+	}
+
+	return compareNumbers(am[4], bm[4]), true
+}
+
+var pypiVersionRegexp = regexp.MustCompile(`^v?(?:([0-9]+)!)?([0-9]+(?:\.[0-9]+)*)(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?([0-9]*))?(?:-([0-9]+)|[-_.]?(post|rev|r)[-_.]?([0-9]*))?(?:[-_.]?(dev)[-_.]?([0-9]*))?(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+var pypiPrereleaseOrder = map[string]int{"a": 0, "alpha": 0, "b": 1, "beta": 1, "c": 2, "rc": 2, "pre": 2, "preview": 2}
+
+type pypiVersion struct {
+	epoch      string
+	release    []string
+	preRank    int // -1 for dev release without pre and post parts, 3 for version without pre-release part
+	preNumber  string
+	hasPost    bool
+	postNumber string
+	hasDev     bool
+	devNumber  string
+	local      []string
+}
+
+func parsePypiVersion(version string) (*pypiVersion, bool) {
+	m := pypiVersionRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(version)))
+	if m == nil {
+		return nil, false
+	}
+
+	v := &pypiVersion{
+		epoch:      m[1],
+		release:    strings.Split(m[2], "."),
+		preRank:    3,
+		preNumber:  m[4],
+		hasPost:    m[5] != "" || m[6] != "",
+		postNumber: m[5] + m[7],
+		hasDev:     m[8] != "",
+		devNumber:  m[9],
+	}
+
+	if m[3] != "" {
+		v.preRank = pypiPrereleaseOrder[m[3]]
+	} else if v.hasDev && !v.hasPost {
+		v.preRank = -1
+	}
+
+	if m[10] != "" {
+		v.local = strings.FieldsFunc(m[10], func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	}
+
+	return v, true
+}
+
+// comparePypiVersions implements PEP 440 ordering: dev releases, pre-releases (a, b, rc), final release, post releases, ok is false if any version is not PEP 440 compliant
+func comparePypiVersions(a, b string) (int, bool) {
+	va, okA := parsePypiVersion(a)
+	vb, okB := parsePypiVersion(b)
+	if !okA || !okB {
+		return 0, false
+	}
+
+	if res := compareNumbers(va.epoch, vb.epoch); res != 0 {
+		return res, true
+	}
+
+	if res := compareNumberLists(va.release, vb.release, true); res != 0 {
+		return res, true
+	}
+
+	if va.preRank != vb.preRank {
+		return sign(va.preRank - vb.preRank), true
+	}
+	if res := compareNumbers(va.preNumber, vb.preNumber); res != 0 {
+		return res, true
+	}
+
+	if va.hasPost != vb.hasPost {
+		return boolOrder(va.hasPost, vb.hasPost), true
+	}
+	if res := compareNumbers(va.postNumber, vb.postNumber); res != 0 {
+		return res, true
+	}
+
+	// the release without dev part is newer
+	if va.hasDev != vb.hasDev {
+		return -boolOrder(va.hasDev, vb.hasDev), true
+	}
+	if res := compareNumbers(va.devNumber, vb.devNumber); res != 0 {
+		return res, true
+	}
+
+	for ind := 0; ind < len(va.local) || ind < len(vb.local); ind++ {
+		if ind >= len(va.local) {
+			return -1, true
+		} else if ind >= len(vb.local) {
+			return 1, true
+		}
+
+		aSegment, bSegment := va.local[ind], vb.local[ind]
+		aIsNum, bIsNum := isNumber(aSegment), isNumber(bSegment)
+		switch {
+		case aIsNum && bIsNum:
+			if res := compareNumbers(aSegment, bSegment); res != 0 {
+				return res, true
+			}
+		case aIsNum != bIsNum:
+			// numeric segments are newer than alphanumeric ones
+			return boolOrder(aIsNum, bIsNum), true
+		default:
+			if res := strings.Compare(aSegment, bSegment); res != 0 {
+				return res, true
+			}
+		}
+	}
+
+	return 0, true
+}
+
+var gemSegmentRegexp = regexp.MustCompile(`[0-9]+|[a-zA-Z]+`)
+
+// compareGemVersions implements Gem::Version ordering: the version with letters is a pre-release, e.g. 1.0.0.rc1 < 1.0.0
+func compareGemVersions(a, b string) int {
+	aSegments := gemCanonicalSegments(a)
+	bSegments := gemCanonicalSegments(b)
+
+	for ind := 0; ind < len(aSegments) || ind < len(bSegments); ind++ {
+		aSegment, bSegment := "0", "0"
+		if ind < len(aSegments) {
+			aSegment = aSegments[ind]
+		}
+		if ind < len(bSegments) {
+			bSegment = bSegments[ind]
+		}
+
+		aIsNum, bIsNum := isNumber(aSegment), isNumber(bSegment)
+		switch {
+		case aIsNum && bIsNum:
+			if res := compareNumbers(aSegment, bSegment); res != 0 {
+				return res
+			}
+		case aIsNum != bIsNum:
+			return boolOrder(aIsNum, bIsNum)
+		default:
+			if res := strings.Compare(aSegment, bSegment); res != 0 {
+				return res
+			}
+		}
+	}
+
+	return 0
+}
+
+// gemCanonicalSegments splits the version into the release and pre-release segments and drops trailing zeros of both parts
+func gemCanonicalSegments(version string) []string {
+	segments := gemSegmentRegexp.FindAllString(strings.TrimSpace(version), -1)
+
+	prereleaseInd := len(segments)
+	for ind, segment := range segments {
+		if !isNumber(segment) {
+			prereleaseInd = ind
+			break
+		}
+	}
+
+	release := trimZeroSegments(segments[:prereleaseInd])
+	prerelease := trimZeroSegments(segments[prereleaseInd:])
+
+	return append(append([]string{}, release...), prerelease...)
+}
+
+func trimZeroSegments(segments []string) []string {
+	for len(segments) > 0 && isNumber(segments[len(segments)-1]) && strings.TrimLeft(segments[len(segments)-1], "0") == "" {
+		segments = segments[:len(segments)-1]
+	}
+	return segments
+}
+
+var composerVersionRegexp = regexp.MustCompile(`^v?([0-9]+(?:\.[0-9]+){0,3})(?:[-_.]?(stable|beta|b|rc|alpha|a|patch|pl|p)(?:[-_.]?([0-9]+))?)?(?:[-_.]?(dev))?$`)
+
+// composerStabilityOrder lists composer stabilities in the ascending order
+var composerStabilityOrder = map[string]int{"dev": 0, "alpha": 1, "a": 1, "beta": 2, "b": 2, "rc": 3, "": 4, "stable": 4, "patch": 5, "pl": 5, "p": 5}
+
+// compareComposerVersions compares versions with composer stability suffixes: dev < alpha < beta < RC < stable < patch,
+// ok is false if any version has another format (e.g. dev-master branch)
+func compareComposerVersions(a, b string) (int, bool) {
+	am := composerVersionRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(a)))
+	bm := composerVersionRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(b)))
+	if am == nil || bm == nil {
+		return 0, false
+	}
+
+	if res := compareNumberLists(strings.Split(am[1], "."), strings.Split(bm[1], "."), true); res != 0 {
+		return res, true
+	}
+
+	if am[2] != bm[2] {
+		aStability, bStability := composerStabilityOrder[am[2]], composerStabilityOrder[bm[2]]
+		if aStability != bStability {
+			return sign(aStability - bStability), true
+		}
+	}
+
+	if res := compareNumbers(am[3], bm[3]); res != 0 {
+		return res, true
+	}
+
+	// 1.0.0-beta1-dev is older than 1.0.0-beta1
+	if am[4] != bm[4] {
+		return -boolOrder(am[4] != "", bm[4] != ""), true
+	}
+
+	return 0, true
+}
+
+// compareNumberLists compares dot separated numbers one by one, missing numbers are zeros if padWithZeros is set,
+// otherwise the longer list is newer
+func compareNumberLists(a, b []string, padWithZeros bool) int {
+	for ind := 0; ind < len(a) || ind < len(b); ind++ {
+		if !padWithZeros {
+			if ind >= len(a) {
+				return -1
+			} else if ind >= len(b) {
+				return 1
+			}
+		}
+
+		var aNumber, bNumber string
+		if ind < len(a) {
+			aNumber = a[ind]
+		}
+		if ind < len(b) {
+			bNumber = b[ind]
+		}
+
+		if res := compareNumbers(aNumber, bNumber); res != 0 {
+			return res
+		}
+	}
+
+	return 0
+}
+
+// compareNumbers compares decimal numbers of any length, empty string is zero
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return sign(len(a) - len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+// boolOrder returns 1 if only a is set and -1 if only b is set
+func boolOrder(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for ind := 0; ind < len(s); ind++ {
+		if !isDigit(s[ind]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareDebianVersions implements dpkg comparison of [epoch:]upstream_version[-debian_revision]
+func compareDebianVersions(a, b string) int {
+	aEpoch, aUpstream, aRevision := splitVersion(a)
+	bEpoch, bUpstream, bRevision := splitVersion(b)
+
+	if aEpoch != bEpoch {
+		return sign(aEpoch - bEpoch)
+	}
+
+	if res := compareDebianFragments(aUpstream, bUpstream); res != 0 {
+		return res
+	}
+
+	return compareDebianFragments(aRevision, bRevision)
+}
+
+func compareDebianFragments(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := 0, 0
+			if i < len(a) {
+				ac = debianCharOrder(a[i])
+			}
+			if j < len(b) {
+				bc = debianCharOrder(b[j])
+			}
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+
+	return 0
+}
+
+func debianCharOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case isDigit(c):
+		return 0
+	case isLetter(c):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+// compareRpmVersions compares [epoch:]version[-release] using rpmvercmp for version and release
+func compareRpmVersions(a, b string) int {
+	aEpoch, aVersion, aRelease := splitVersion(a)
+	bEpoch, bVersion, bRelease := splitVersion(b)
+
+	if aEpoch != bEpoch {
+		return sign(aEpoch - bEpoch)
+	}
+
+	if res := rpmvercmp(aVersion, bVersion); res != 0 {
+		return res
+	}
+
+	if aRelease == "" || bRelease == "" {
+		return 0
+	}
+
+	return rpmvercmp(aRelease, bRelease)
+}
+
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isAlnum(a[i]) && a[i] != '~' && a[i] != '^' {
+			i++
+		}
+		for j < len(b) && !isAlnum(b[j]) && b[j] != '~' && b[j] != '^' {
+			j++
+		}
+
+		// tilde sorts before everything else
+		if (i < len(a) && a[i] == '~') || (j < len(b) && b[j] == '~') {
+			if i >= len(a) || a[i] != '~' {
+				return 1
+			}
+			if j >= len(b) || b[j] != '~' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		// caret sorts after the end of the version but before anything else
+		if (i < len(a) && a[i] == '^') || (j < len(b) && b[j] == '^') {
+			if i >= len(a) {
+				return -1
+			}
+			if j >= len(b) {
+				return 1
+			}
+			if a[i] != '^' {
+				return 1
+			}
+			if b[j] != '^' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		if i >= len(a) || j >= len(b) {
+			break
+		}
+
+		si, sj := i, j
+		isNum := isDigit(a[i])
+		if isNum {
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+		} else {
+			for i < len(a) && isLetter(a[i]) {
+				i++
+			}
+			for j < len(b) && isLetter(b[j]) {
+				j++
+			}
+		}
+
+		segA, segB := a[si:i], b[sj:j]
+		if segB == "" {
+			// numeric segment is newer than alpha one
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				return sign(len(segA) - len(segB))
+			}
+		}
+
+		if res := strings.Compare(segA, segB); res != 0 {
+			return res
+		}
+	}
+
+	if i >= len(a) && j >= len(b) {
+		return 0
+	} else if i >= len(a) {
+		return -1
+	}
+	return 1
+}
+
+func splitVersion(version string) (int, string, string) {
+	epoch := 0
+	if ind := strings.Index(version, ":"); ind != -1 {
+		if e, err := strconv.Atoi(version[:ind]); err == nil {
+			epoch = e
+			version = version[ind+1:]
+		}
+	}
+
+	var revision string
+	if ind := strings.LastIndex(version, "-"); ind != -1 {
+		revision = version[ind+1:]
+		version = version[:ind]
+	}
+
+	return epoch, version, revision
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || isLetter(c)
+}
@@ -0,0 +1,74 @@
+package vulnerability
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		ecosystem Ecosystem
+		a, b      string
+		expected  int
+	}{
+		{Dpkg, "1.1.1d-0+deb10u3", "1.1.1d-0+deb10u3", 0},
+		{Dpkg, "1.1.1d-0+deb10u2", "1.1.1d-0+deb10u3", -1},
+		{Dpkg, "1.0~rc1-1", "1.0-1", -1},
+		{Dpkg, "1:0.9-1", "2.0-1", 1},
+		{Dpkg, "2.30-1", "2.4-1", 1},
+		{Dpkg, "1.0a-1", "1.0-1", 1},
+		{Rpm, "1.0.2k-19.el7", "1.0.2k-21.el7_9", -1},
+		{Rpm, "1:1.0.2k-19.el7", "1.0.2k-21.el7", 1},
+		{Rpm, "2.0~beta", "2.0", -1},
+		{Rpm, "2.0^post1", "2.0", 1},
+		{Rpm, "1.10", "1.9", 1},
+		{Apk, "1.1.1g-r0", "1.1.1d-r2", 1},
+		{Apk, "1.31.1-r9", "1.31.1-r10", -1},
+		{Apk, "3.0.0_rc1-r0", "3.0.0-r0", -1},
+		{Apk, "3.0.0_rc2-r0", "3.0.0_rc10-r0", -1},
+		{Apk, "3.0.0_alpha1-r0", "3.0.0_beta1-r0", -1},
+		{Apk, "3.0.0_p1-r0", "3.0.0-r0", 1},
+		{Apk, "1.2-r0", "1.2.1-r0", -1},
+		{Apk, "1.2_rc1-r0", "1.2.1-r0", -1},
+		{Apk, "2.4.46-r0", "2.4.46", 0},
+		{Npm, "4.17.15", "4.17.19", -1},
+		{Npm, "1.0.0-beta.1", "1.0.0", -1},
+		{Npm, "1.0.0-alpha", "1.0.0-beta", -1},
+		{Npm, "1.0.0-rc.1+build.5", "1.0.0", -1},
+		{Npm, "1.2.3.4-beta", "1.2.3.4", -1},
+		{Npm, "1.2.3.4", "1.2.3.10", -1},
+		{PyPI, "2.22.0", "2.20.0", 1},
+		{PyPI, "2.0", "2.0.0", 0},
+		{PyPI, "1.0rc1", "1.0", -1},
+		{PyPI, "1.0a1", "1.0b1", -1},
+		{PyPI, "1.0b2", "1.0rc1", -1},
+		{PyPI, "1.0.dev1", "1.0a1", -1},
+		{PyPI, "1.0a1.dev1", "1.0a1", -1},
+		{PyPI, "1.0", "1.0.post1", -1},
+		{PyPI, "1.0-1", "1.0.post2", -1},
+		{PyPI, "1.0.post1.dev1", "1.0.post1", -1},
+		{PyPI, "1.0+local.1", "1.0", 1},
+		{PyPI, "1!0.1", "2.0", 1},
+		{PyPI, "1.0.0-Beta.2", "1.0.0b2", 0},
+		{RubyGems, "1.10.9", "1.10.10", -1},
+		{RubyGems, "1.0.0.rc1", "1.0.0", -1},
+		{RubyGems, "1.0.0.beta2", "1.0.0.rc1", -1},
+		{RubyGems, "1.0.0.pre", "1.0.0.pre.1", -1},
+		{RubyGems, "2.0", "2.0.0", 0},
+		{RubyGems, "2.0.a", "1.9", 1},
+		{Composer, "v5.4.2", "5.4.10", -1},
+		{Composer, "1.0.0-RC1", "1.0.0", -1},
+		{Composer, "1.0.0-beta2", "1.0.0-RC1", -1},
+		{Composer, "1.0.0-alpha3", "1.0.0-beta1", -1},
+		{Composer, "1.0.0-beta1-dev", "1.0.0-beta1", -1},
+		{Composer, "1.0.0-p1", "1.0.0", 1},
+		{Composer, "2.1.0.0", "2.1", 0},
+		{Composer, "dev-master", "dev-master", 0},
+	}
+
+	for _, tt := range tests {
+		if res := CompareVersions(tt.ecosystem, tt.a, tt.b); res != tt.expected {
+			t.Errorf("%s: compare %q and %q: got %d, expected %d", tt.ecosystem, tt.a, tt.b, res, tt.expected)
+		}
+		if res := CompareVersions(tt.ecosystem, tt.b, tt.a); res != -tt.expected {
+			t.Errorf("%s: compare %q and %q: got %d, expected %d", tt.ecosystem, tt.b, tt.a, res, -tt.expected)
+		}
+	}
+}
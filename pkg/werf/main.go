@@ -5,10 +5,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
+	"time"
 
 	"github.com/flant/lockgate/pkg/file_lock"
 	"github.com/flant/logboek"
 
+	"github.com/flant/werf/pkg/tracing"
+
 	"github.com/flant/lockgate"
 )
 
@@ -25,6 +29,9 @@ var (
 	hostLocker lockgate.Locker
 )
 
+// LockHoldersInfoInterval is the period of printing the lock holders while waiting for the lock
+const LockHoldersInfoInterval = 15 * time.Second
+
 func GetSharedContextDir() string {
 	if sharedContextDir == "" {
 		panic("bug: init required!")
@@ -83,6 +90,16 @@ func SetupLockerDefaultOptions(opts lockgate.AcquireOptions) lockgate.AcquireOpt
 	return opts
 }
 
+// SetupLockerOptionsWithHoldersInfo sets OnWaitFunc which prints the lock holders returned by getHoldersInfo while waiting
+func SetupLockerOptionsWithHoldersInfo(opts lockgate.AcquireOptions, getHoldersInfo func() ([]string, error)) lockgate.AcquireOptions {
+	if opts.OnWaitFunc == nil {
+		opts.OnWaitFunc = func(lock lockgate.LockHandle, doWait func() error) error {
+			return LockerOnWaitWithHoldersInfo(lock, doWait, getHoldersInfo)
+		}
+	}
+	return SetupLockerDefaultOptions(opts)
+}
+
 func WithHostLock(lockName string, opts lockgate.AcquireOptions, f func() error) error {
 	return lockgate.WithAcquire(GetHostLocker(), lockName, SetupLockerDefaultOptions(opts), func(_ bool) error {
 		return f()
@@ -98,12 +115,71 @@ func ReleaseHostLock(lock lockgate.LockHandle) error {
 }
 
 func DefaultLockerOnWait(lock lockgate.LockHandle, doWait func() error) error {
+	return LockerOnWaitWithHoldersInfo(lock, doWait, nil)
+}
+
+// LockerOnWaitWithHoldersInfo prints the lock holders right away and then every LockHoldersInfoInterval until the lock is acquired
+func LockerOnWaitWithHoldersInfo(lock lockgate.LockHandle, doWait func() error, getHoldersInfo func() ([]string, error)) error {
 	logProcessMsg := fmt.Sprintf("Waiting for locked %q", lock.LockName)
-	return logboek.LogProcessInline(logProcessMsg, logboek.LogProcessInlineOptions{}, func() error {
-		return doWait()
+
+	if getHoldersInfo == nil {
+		return logboek.LogProcessInline(logProcessMsg, logboek.LogProcessInlineOptions{}, func() error {
+			span := tracing.StartConcurrentSpan("lock wait", map[string]string{"lock": lock.LockName})
+			err := doWait()
+			span.End(err)
+			return err
+		})
+	}
+
+	return logboek.LogProcess(logProcessMsg, logboek.LogProcessOptions{}, func() error {
+		span := tracing.StartConcurrentSpan("lock wait", map[string]string{"lock": lock.LockName})
+
+		doneChan := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ticker := time.NewTicker(LockHoldersInfoInterval)
+			defer ticker.Stop()
+
+			for {
+				logLockHolders(lock.LockName, getHoldersInfo)
+
+				select {
+				case <-doneChan:
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+
+		err := doWait()
+		close(doneChan)
+		wg.Wait()
+
+		span.End(err)
+		return err
 	})
 }
 
+func logLockHolders(lockName string, getHoldersInfo func() ([]string, error)) {
+	holders, err := getHoldersInfo()
+	if err != nil {
+		logboek.LogWarnF("WARNING: unable to get holders of the lock %q: %s\n", lockName, err)
+		return
+	}
+
+	if len(holders) == 0 {
+		logboek.LogF("Lock holders are unknown\n")
+		return
+	}
+
+	for _, holder := range holders {
+		logboek.LogF("Locked by %s\n", holder)
+	}
+}
+
 func DefaultLockerOnLostLease(lock lockgate.LockHandle) error {
 	panic(fmt.Sprintf("Locker has lost lease for locked %q uuid %s. Will crash current process immediately!", lock.LockName, lock.UUID))
 }